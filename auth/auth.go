@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -25,13 +26,18 @@ type OIDCProvider struct {
 	clientSecret string
 	redirectURL  string
 	issuerURL    string
+	cookieSecret []byte
+	// postLoginRedirectURL is where the callback sends the client when login did not ask for somewhere else
+	postLoginRedirectURL string
+	allowedRedirectURLs  map[string]bool
+	now                  func() time.Time
 }
 
 type OIDCService interface {
-	GetAuthURL(state string) string
-	HandleCallback(ctx context.Context, code, state string) (*OIDCUserInfo, error)
+	CreateAuthURL(c *gin.Context, redirectURL string) (string, string, error)
+	LoginState(c *gin.Context, state string) (*LoginState, error)
+	HandleCallback(ctx context.Context, code string, loginState *LoginState) (*OIDCUserInfo, error)
 	VerifyToken(ctx context.Context, rawIDToken string) (*OIDCUserInfo, error)
-	CreateAuthURL(c *gin.Context) (string, string, error)
 }
 
 type OIDCUserInfo struct {
@@ -80,14 +86,23 @@ func NewOIDCProvider(cfg *config.OIDCConfig) (OIDCService, error) {
 
 	log.Printf("OAuth2 config: ClientID=%s, RedirectURL=%s", cfg.ClientID, cfg.RedirectURL)
 
+	allowedRedirectURLs := make(map[string]bool, len(cfg.AllowedRedirectURLs))
+	for _, allowedRedirectURL := range cfg.AllowedRedirectURLs {
+		allowedRedirectURLs[allowedRedirectURL] = true
+	}
+
 	return &OIDCProvider{
-		provider:     provider,
-		oauth2Config: oauth2Config,
-		verifier:     verifier,
-		clientID:     cfg.ClientID,
-		clientSecret: cfg.ClientSecret,
-		redirectURL:  cfg.RedirectURL,
-		issuerURL:    cfg.IssuerURL,
+		provider:             provider,
+		oauth2Config:         oauth2Config,
+		verifier:             verifier,
+		clientID:             cfg.ClientID,
+		clientSecret:         cfg.ClientSecret,
+		redirectURL:          cfg.RedirectURL,
+		issuerURL:            cfg.IssuerURL,
+		cookieSecret:         []byte(cfg.CookieSecret),
+		postLoginRedirectURL: cfg.PostLoginRedirectURL,
+		allowedRedirectURLs:  allowedRedirectURLs,
+		now:                  time.Now,
 	}, nil
 }
 
@@ -104,45 +119,91 @@ func validateOIDCConfig(cfg *config.OIDCConfig) error {
 	if cfg.RedirectURL == "" {
 		return errors.New("redirect URL is required")
 	}
+	if len(cfg.CookieSecret) < 32 {
+		return errors.New("cookie secret of at least 32 bytes is required")
+	}
 	return nil
 }
 
-func (o *OIDCProvider) CreateAuthURL(c *gin.Context) (string, string, error) {
+// CreateAuthURL starts a login: it picks a fresh state, nonce and PKCE verifier, keeps
+// them in a signed cookie for the callback, and returns the identity provider's login URL
+// with the S256 code challenge. redirectURL, when given, must be one of the allowed
+// post-login redirects.
+func (o *OIDCProvider) CreateAuthURL(c *gin.Context, redirectURL string) (string, string, error) {
+	if redirectURL == "" {
+		redirectURL = o.postLoginRedirectURL
+	} else if !o.allowedRedirectURLs[redirectURL] {
+		return "", "", fmt.Errorf("redirect URL %q is not allowed", redirectURL)
+	}
+
 	state, err := generateRandomState()
 	if err != nil {
 		log.Printf("Failed to generate state: %v", err)
 		return "", "", fmt.Errorf("failed to generate state: %w", err)
 	}
 
-	log.Printf("Generated state: %s", state)
+	nonce, err := generateRandomState()
+	if err != nil {
+		log.Printf("Failed to generate nonce: %v", err)
+		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
 
-	c.SetCookie(
-		"oauth_state",    // name
-		state,           // value
-		600,            // maxAge (10 minutes)
-		"/",            // path
-		"",             // domain (empty for current domain)
-		false,          // secure (set to true in production with HTTPS)
-		true,           // httpOnly
-	)
+	loginState := &LoginState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		RedirectURL:  redirectURL,
+		ExpiresAt:    o.now().Add(loginStateTTL),
+	}
 
-	authURL := o.GetAuthURL(state)
+	cookieValue, err := encodeLoginState(o.cookieSecret, loginState)
+	if err != nil {
+		return "", "", err
+	}
+
+	setLoginStateCookie(c, cookieValue, int(loginStateTTL.Seconds()))
+
+	authURL := o.oauth2Config.AuthCodeURL(
+		state,
+		oauth2.AccessTypeOffline,
+		oauth2.S256ChallengeOption(loginState.CodeVerifier),
+		oidc.Nonce(nonce),
+	)
 	log.Printf("Generated auth URL: %s", authURL)
 
 	return authURL, state, nil
 }
 
-// GetAuthURL returns the OIDC login URL
-func (o *OIDCProvider) GetAuthURL(state string) string {
-	return o.oauth2Config.AuthCodeURL(state, oauth2.AccessTypeOffline)
+// LoginState reads back the login CreateAuthURL started and clears its cookie, so a
+// callback can only be completed once. The state must match the one returned by the
+// identity provider.
+func (o *OIDCProvider) LoginState(c *gin.Context, state string) (*LoginState, error) {
+	cookieValue, err := c.Cookie(loginStateCookie)
+	if err != nil {
+		return nil, errors.New("no login in progress")
+	}
+
+	setLoginStateCookie(c, "", -1)
+
+	loginState, err := decodeLoginState(o.cookieSecret, cookieValue, o.now())
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(loginState.State), []byte(state)) != 1 {
+		return nil, errors.New("state does not match the login in progress")
+	}
+
+	return loginState, nil
 }
 
-// HandleCallback exchanges the code for tokens and verifies ID token
-func (o *OIDCProvider) HandleCallback(ctx context.Context, code, state string) (*OIDCUserInfo, error) {
-	log.Printf("Handling callback with code: %s, state: %s", code[:min(len(code), 10)]+"...", state)
+// HandleCallback exchanges the code for tokens, sending the PKCE verifier, and verifies
+// the ID token, which must carry the nonce the login was started with.
+func (o *OIDCProvider) HandleCallback(ctx context.Context, code string, loginState *LoginState) (*OIDCUserInfo, error) {
+	log.Printf("Handling callback with code: %s, state: %s", code[:min(len(code), 10)]+"...", loginState.State)
 
 	// Exchange authorization code for tokens
-	token, err := o.oauth2Config.Exchange(ctx, code)
+	token, err := o.oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(loginState.CodeVerifier))
 	if err != nil {
 		log.Printf("Failed to exchange token: %v", err)
 		return nil, fmt.Errorf("failed to exchange token: %w", err)
@@ -159,12 +220,31 @@ func (o *OIDCProvider) HandleCallback(ctx context.Context, code, state string) (
 
 	log.Printf("ID token found, verifying...")
 
-	// Verify and parse the ID token
-	return o.VerifyToken(ctx, rawIDToken)
+	idToken, err := o.verifyIDToken(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(loginState.Nonce)) != 1 {
+		log.Printf("ID token nonce does not match the login")
+		return nil, errors.New("ID token nonce does not match the login")
+	}
+
+	return o.userInfo(idToken)
 }
 
-// VerifyToken validates the ID token and extracts claims
+// VerifyToken validates an ID token presented as a bearer token and extracts its claims.
+// There is no login to hold a nonce against here; the nonce is checked in HandleCallback.
 func (o *OIDCProvider) VerifyToken(ctx context.Context, rawIDToken string) (*OIDCUserInfo, error) {
+	idToken, err := o.verifyIDToken(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.userInfo(idToken)
+}
+
+func (o *OIDCProvider) verifyIDToken(ctx context.Context, rawIDToken string) (*oidc.IDToken, error) {
 	log.Printf("Verifying ID token...")
 
 	idToken, err := o.verifier.Verify(ctx, rawIDToken)
@@ -175,6 +255,10 @@ func (o *OIDCProvider) VerifyToken(ctx context.Context, rawIDToken string) (*OID
 
 	log.Printf("ID token verified successfully")
 
+	return idToken, nil
+}
+
+func (o *OIDCProvider) userInfo(idToken *oidc.IDToken) (*OIDCUserInfo, error) {
 	var userInfo OIDCUserInfo
 	if err := idToken.Claims(&userInfo); err != nil {
 		log.Printf("Failed to parse ID token claims: %v", err)
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testIssuer       = "https://idp.example.com"
+	testClientID     = "savannah-pos"
+	testCookieSecret = "a-cookie-secret-of-at-least-32-bytes"
+)
+
+// testIdentityProvider is the identity provider's token endpoint. It only hands out an
+// ID token when the code verifier matches the challenge from the login URL, and signs
+// the ID token with whatever nonce the test asks for.
+type testIdentityProvider struct {
+	key           *rsa.PrivateKey
+	server        *httptest.Server
+	codeChallenge string
+	nonce         string
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	idp := &testIdentityProvider{key: key}
+
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		if pkceChallenge(r.PostForm.Get("code_verifier")) != idp.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		idToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   testIssuer,
+			"aud":   testClientID,
+			"sub":   "google-1",
+			"email": "wanjiru@example.com",
+			"nonce": idp.nonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}).SignedString(key)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "idp-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	}))
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *testIdentityProvider) oidcProvider() *OIDCProvider {
+	return &OIDCProvider{
+		oauth2Config: oauth2.Config{
+			ClientID:     testClientID,
+			ClientSecret: "secret",
+			RedirectURL:  "https://api.example.com/v1/auth/callback",
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   testIssuer + "/authorize",
+				TokenURL:  idp.server.URL,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+			Scopes: []string{oidc.ScopeOpenID, "email"},
+		},
+		verifier: oidc.NewVerifier(
+			testIssuer,
+			&oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{&idp.key.PublicKey}},
+			&oidc.Config{ClientID: testClientID},
+		),
+		cookieSecret:         []byte(testCookieSecret),
+		postLoginRedirectURL: "https://pos.example.com/login",
+		allowedRedirectURLs:  map[string]bool{"savannahpos://login": true},
+		now:                  time.Now,
+	}
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// startLogin runs CreateAuthURL and returns the login URL and the cookie it set.
+func startLogin(t *testing.T, provider *OIDCProvider, redirectURL string) (*url.URL, *http.Cookie) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/auth/login", nil)
+
+	authURL, _, err := provider.CreateAuthURL(c, redirectURL)
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	return parsed, cookies[0]
+}
+
+// readLoginState runs LoginState as the callback would, with the cookie from the login.
+func readLoginState(provider *OIDCProvider, cookie *http.Cookie, state string) (*LoginState, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/auth/callback", nil)
+	c.Request.AddCookie(cookie)
+
+	return provider.LoginState(c, state)
+}
+
+func TestCreateAuthURL_SendsPKCEChallengeAndNonce(t *testing.T) {
+	provider := newTestIdentityProvider(t).oidcProvider()
+
+	authURL, cookie := startLogin(t, provider, "")
+
+	assert.Equal(t, loginStateCookie, cookie.Name)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	state := authURL.Query().Get("state")
+	loginState, err := readLoginState(provider, cookie, state)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	assert.Equal(t, pkceChallenge(loginState.CodeVerifier), authURL.Query().Get("code_challenge"))
+	assert.NotEmpty(t, loginState.Nonce)
+	assert.Equal(t, loginState.Nonce, authURL.Query().Get("nonce"))
+	assert.Equal(t, "https://pos.example.com/login", loginState.RedirectURL)
+}
+
+func TestCreateAuthURL_OnlyAllowsListedRedirects(t *testing.T) {
+	provider := newTestIdentityProvider(t).oidcProvider()
+
+	authURL, cookie := startLogin(t, provider, "savannahpos://login")
+	loginState, err := readLoginState(provider, cookie, authURL.Query().Get("state"))
+	assert.NoError(t, err)
+	assert.Equal(t, "savannahpos://login", loginState.RedirectURL)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/auth/login", nil)
+
+	_, _, err = provider.CreateAuthURL(c, "https://attacker.example.com/steal")
+	assert.Error(t, err)
+}
+
+func TestLoginState_RejectsTamperedMismatchedOrExpiredCookies(t *testing.T) {
+	provider := newTestIdentityProvider(t).oidcProvider()
+
+	authURL, cookie := startLogin(t, provider, "")
+	state := authURL.Query().Get("state")
+
+	_, err := readLoginState(provider, cookie, "another-state")
+	assert.Error(t, err)
+
+	// a cookie whose redirect was swapped no longer matches its signature
+	encoded, signature, _ := strings.Cut(cookie.Value, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(encoded)
+	tampered := strings.Replace(string(payload), "https://pos.example.com/login", "https://attacker.example.com", 1)
+	_, err = readLoginState(provider, &http.Cookie{
+		Name:  loginStateCookie,
+		Value: base64.RawURLEncoding.EncodeToString([]byte(tampered)) + "." + signature,
+	}, state)
+	assert.Error(t, err)
+
+	provider.now = func() time.Time { return time.Now().Add(loginStateTTL + time.Minute) }
+	_, err = readLoginState(provider, cookie, state)
+	assert.Error(t, err)
+}
+
+func TestHandleCallback_SendsVerifierAndChecksNonce(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	provider := idp.oidcProvider()
+
+	authURL, cookie := startLogin(t, provider, "")
+	loginState, err := readLoginState(provider, cookie, authURL.Query().Get("state"))
+	assert.NoError(t, err)
+
+	idp.codeChallenge = authURL.Query().Get("code_challenge")
+	idp.nonce = loginState.Nonce
+
+	userInfo, err := provider.HandleCallback(context.Background(), "auth-code", loginState)
+	assert.NoError(t, err)
+	assert.Equal(t, "google-1", userInfo.Sub)
+	assert.Equal(t, "wanjiru@example.com", userInfo.Email)
+
+	// an ID token minted for some other login is refused
+	idp.nonce = "someone-elses-nonce"
+	_, err = provider.HandleCallback(context.Background(), "auth-code", loginState)
+	assert.Error(t, err)
+
+	// without the verifier the identity provider will not exchange the code
+	idp.nonce = loginState.Nonce
+	_, err = provider.HandleCallback(context.Background(), "auth-code", &LoginState{
+		State:        loginState.State,
+		Nonce:        loginState.Nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+	})
+	assert.Error(t, err)
+}
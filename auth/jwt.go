@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github/Doris-Mwito5/savannah-pos/config"
+)
+
+// jwtLeeway allows for clock drift between API replicas.
+const jwtLeeway = 30 * time.Second
+
+// JWTService signs and verifies the HS256 tokens handed out after login. Every token
+// names its key in the "kid" header, so keys can be rotated: add the new key to the set,
+// switch the signing key to it, and drop the old key once its tokens have expired.
+type JWTService interface {
+	SignToken(claims *Claims) (string, time.Time, error)
+	VerifyToken(ctx context.Context, rawToken string) (*Claims, error)
+}
+
+type jwtService struct {
+	issuer         string
+	audience       string
+	signingKeyID   string
+	keys           map[string][]byte
+	accessTokenTTL time.Duration
+	now            func() time.Time
+}
+
+func NewJWTService(cfg *config.JWTConfig) (JWTService, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("at least one JWT key is required")
+	}
+
+	keys := make(map[string][]byte, len(cfg.Keys))
+	for keyID, secret := range cfg.Keys {
+		keys[keyID] = []byte(secret)
+	}
+
+	if _, ok := keys[cfg.SigningKeyID]; !ok {
+		return nil, fmt.Errorf("signing key %q is not one of the JWT keys", cfg.SigningKeyID)
+	}
+
+	return &jwtService{
+		issuer:         cfg.Issuer,
+		audience:       cfg.Audience,
+		signingKeyID:   cfg.SigningKeyID,
+		keys:           keys,
+		accessTokenTTL: cfg.AccessTokenTTL,
+		now:            time.Now,
+	}, nil
+}
+
+// SignToken fills in the registered claims (issuer, audience, lifetime) and signs the
+// token with the current signing key.
+func (s *jwtService) SignToken(claims *Claims) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(s.accessTokenTTL)
+
+	claims.Issuer = s.issuer
+	claims.Audience = jwt.ClaimStrings{s.audience}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = s.signingKeyID
+
+	signed, err := token.SignedString(s.keys[s.signingKeyID])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signed, expiresAt, nil
+}
+
+// VerifyToken checks the signature against the key named by "kid", then the expiry,
+// issuer and audience.
+func (s *jwtService) VerifyToken(ctx context.Context, rawToken string) (*Claims, error) {
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(
+		rawToken,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) {
+			keyID, _ := token.Header["kid"].(string)
+			if keyID == "" {
+				return nil, errors.New("token has no key id")
+			}
+
+			key, ok := s.keys[keyID]
+			if !ok {
+				return nil, fmt.Errorf("unknown key id %q", keyID)
+			}
+
+			return key, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithLeeway(jwtLeeway),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify token: %w", err)
+	}
+
+	return &claims, nil
+}
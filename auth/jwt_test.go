@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/config"
+)
+
+func newTestJWTService(t *testing.T, signingKeyID string, keys map[string]string) *jwtService {
+	service, err := NewJWTService(&config.JWTConfig{
+		Issuer:         "savannah-pos",
+		Audience:       "savannah-pos-api",
+		SigningKeyID:   signingKeyID,
+		Keys:           keys,
+		AccessTokenTTL: time.Hour,
+	})
+	assert.NoError(t, err)
+
+	return service.(*jwtService)
+}
+
+func TestJWTService_SignAndVerify(t *testing.T) {
+	service := newTestJWTService(t, "k1", map[string]string{"k1": "secret-1"})
+
+	token, expiresAt, err := service.SignToken(&Claims{Email: "cashier@example.com", Sub: "google-7", SessionID: 7})
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	claims, err := service.VerifyToken(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), claims.SessionID)
+	assert.Equal(t, "google-7", claims.Sub)
+	assert.Equal(t, "savannah-pos", claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"savannah-pos-api"}, claims.Audience)
+}
+
+func TestJWTService_KeyRotation(t *testing.T) {
+	oldService := newTestJWTService(t, "k1", map[string]string{"k1": "secret-1"})
+	oldToken, _, err := oldService.SignToken(&Claims{Sub: "google-7"})
+	assert.NoError(t, err)
+
+	// k2 now signs, and tokens signed with k1 stay valid while k1 is kept
+	rotated := newTestJWTService(t, "k2", map[string]string{"k1": "secret-1", "k2": "secret-2"})
+	newToken, _, err := rotated.SignToken(&Claims{Sub: "google-7"})
+	assert.NoError(t, err)
+
+	_, err = rotated.VerifyToken(context.Background(), oldToken)
+	assert.NoError(t, err)
+	_, err = rotated.VerifyToken(context.Background(), newToken)
+	assert.NoError(t, err)
+
+	// once k1 is retired its tokens are refused
+	retired := newTestJWTService(t, "k2", map[string]string{"k2": "secret-2"})
+	_, err = retired.VerifyToken(context.Background(), oldToken)
+	assert.Error(t, err)
+	_, err = retired.VerifyToken(context.Background(), newToken)
+	assert.NoError(t, err)
+}
+
+func TestJWTService_Rejects(t *testing.T) {
+	service := newTestJWTService(t, "k1", map[string]string{"k1": "secret-1"})
+
+	sign := func(mutate func(claims *Claims, token *jwt.Token)) string {
+		claims := &Claims{
+			Sub: "google-7",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "savannah-pos",
+				Audience:  jwt.ClaimStrings{"savannah-pos-api"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = "k1"
+		mutate(claims, token)
+
+		signed, err := token.SignedString([]byte("secret-1"))
+		assert.NoError(t, err)
+		return signed
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(claims *Claims, token *jwt.Token)
+	}{
+		{"expired", func(claims *Claims, _ *jwt.Token) {
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		}},
+		{"no expiry", func(claims *Claims, _ *jwt.Token) { claims.ExpiresAt = nil }},
+		{"other issuer", func(claims *Claims, _ *jwt.Token) { claims.Issuer = "someone-else" }},
+		{"other audience", func(claims *Claims, _ *jwt.Token) { claims.Audience = jwt.ClaimStrings{"another-api"} }},
+		{"no key id", func(_ *Claims, token *jwt.Token) { delete(token.Header, "kid") }},
+		{"unknown key id", func(_ *Claims, token *jwt.Token) { token.Header["kid"] = "k9" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.VerifyToken(context.Background(), sign(tt.mutate))
+			assert.Error(t, err)
+		})
+	}
+
+	// signed with another secret under a known key id
+	forged := newTestJWTService(t, "k1", map[string]string{"k1": "secret-2"})
+	token, _, err := forged.SignToken(&Claims{Sub: "google-7"})
+	assert.NoError(t, err)
+	_, err = service.VerifyToken(context.Background(), token)
+	assert.Error(t, err)
+}
+
+func TestNewJWTService_RequiresSigningKey(t *testing.T) {
+	_, err := NewJWTService(&config.JWTConfig{SigningKeyID: "k1"})
+	assert.Error(t, err)
+
+	_, err = NewJWTService(&config.JWTConfig{SigningKeyID: "k2", Keys: map[string]string{"k1": "secret-1"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// loginStateCookie carries a login from /auth/login to /auth/callback
+	loginStateCookie = "oauth_login"
+	// loginStateTTL is how long the user has to finish logging in with the identity provider
+	loginStateTTL = 10 * time.Minute
+)
+
+// LoginState is what the callback needs to finish a login: the state to match, the PKCE
+// verifier for the code exchange, the nonce the ID token must carry, and where to send
+// the client afterwards.
+type LoginState struct {
+	State        string    `json:"state"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	RedirectURL  string    `json:"redirect_url,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// encodeLoginState signs the login state with HMAC-SHA256 so the callback can trust a
+// cookie it gets back from the browser.
+func encodeLoginState(secret []byte, loginState *LoginState) (string, error) {
+	payload, err := json.Marshal(loginState)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode login state: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + signLoginState(secret, encoded), nil
+}
+
+// decodeLoginState checks the signature and expiry of a login state cookie.
+func decodeLoginState(secret []byte, value string, now time.Time) (*LoginState, error) {
+	encoded, signature, found := strings.Cut(value, ".")
+	if !found {
+		return nil, errors.New("malformed login state")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(signLoginState(secret, encoded))) {
+		return nil, errors.New("login state signature does not match")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed login state: %w", err)
+	}
+
+	var loginState LoginState
+	if err := json.Unmarshal(payload, &loginState); err != nil {
+		return nil, fmt.Errorf("malformed login state: %w", err)
+	}
+
+	if now.After(loginState.ExpiresAt) {
+		return nil, errors.New("login state has expired")
+	}
+
+	return &loginState, nil
+}
+
+func signLoginState(secret []byte, encoded string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setLoginStateCookie writes the login state cookie. It is Secure and HttpOnly; SameSite
+// is Lax rather than Strict because the callback is a redirect from the identity provider.
+func setLoginStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     loginStateCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
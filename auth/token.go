@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are carried by the tokens we sign for a login session.
+type Claims struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+	Sub   string `json:"sub"`
+	// SessionID is the login session a token was issued for; logging out revokes it
+	SessionID int64 `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenVerifier checks a bearer token and returns the claims it was issued with.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, rawToken string) (*Claims, error)
+}
+
+// bearerVerifier accepts only the tokens we sign for a login session. The identity
+// provider's ID tokens are refused: they belong to no session, so logging out could not
+// revoke them.
+type bearerVerifier struct {
+	jwtService JWTService
+}
+
+func NewBearerVerifier(
+	jwtService JWTService,
+) TokenVerifier {
+	return &bearerVerifier{
+		jwtService: jwtService,
+	}
+}
+
+func (v *bearerVerifier) VerifyToken(ctx context.Context, rawToken string) (*Claims, error) {
+	claims, err := v.jwtService.VerifyToken(ctx, rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.SessionID == 0 {
+		return nil, errors.New("token was not issued for a login session")
+	}
+
+	return claims, nil
+}
@@ -52,6 +52,8 @@ func main() {
 		Handler: appRouter,
 	}
 
+	appRouter.NotificationWorker.Start(context.Background())
+
 	done := make(chan struct{})
 
 	go func() {
@@ -65,6 +67,11 @@ func main() {
 			loggers.Fatalf("Server shut down error: %v", err)
 		}
 
+		// requests have finished; let the worker record the notification it is sending
+		if err := appRouter.NotificationWorker.Shutdown(context.Background()); err != nil {
+			loggers.Errorf("Notification worker shut down error: %v", err)
+		}
+
 		close(done)
 	}()
 
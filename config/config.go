@@ -3,16 +3,26 @@ package config
 import (
 	"fmt"
 	"github/Doris-Mwito5/savannah-pos/env"
+	"github/Doris-Mwito5/savannah-pos/internal/processor"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all runtime configuration
 type Config struct {
 	DatabaseURL string
 	Port        string
-	JWTSecret   string
+	JWT         JWTConfig
 	SMSService  SMSServiceConfig // ✅ Better structure for SMS config
 	EmailService EmailServiceConfig
 	OIDC        OIDCConfig
+	OrderReference OrderReferenceConfig
+	Mpesa       MpesaConfig
+	Staff       StaffConfig
+	Notifications NotificationsConfig
 }
 
 // SMSServiceConfig holds Africa's Talking settings
@@ -21,6 +31,21 @@ type SMSServiceConfig struct {
 	ApiKey   string
 	Username string
 	Env      string
+	// CallbackToken must be sent as ?token= on delivery reports and inbound texts so they cannot be forged; they are refused until it is set
+	CallbackToken string
+	// Providers are tried in order until one takes a message
+	Providers []string
+	Twilio    TwilioConfig
+	// LogFile is where the log provider writes messages; stdout when empty
+	LogFile string
+}
+
+// TwilioConfig holds the settings for a Twilio-style SMS provider
+type TwilioConfig struct {
+	BaseURL    string
+	AccountSID string
+	AuthToken  string
+	From       string
 }
 
 type EmailServiceConfig struct {
@@ -39,6 +64,60 @@ type OIDCConfig struct {
 	ClientSecret string
 	IssuerURL    string
 	RedirectURL  string
+	// CookieSecret signs the cookie that carries a login's state, nonce and PKCE verifier
+	CookieSecret string
+	// PostLoginRedirectURL is where the callback sends the client with its tokens; when
+	// empty the callback answers with JSON instead
+	PostLoginRedirectURL string
+	// AllowedRedirectURLs are the redirects a client may ask for with ?redirect_uri= on
+	// login, e.g. an SPA's login page or a POS app's custom scheme
+	AllowedRedirectURLs []string
+}
+
+// JWTConfig holds the settings for the tokens we sign after login. Several keys can be
+// active at once so a new one can be rolled out before the old one is retired.
+type JWTConfig struct {
+	Issuer          string
+	Audience        string
+	SigningKeyID    string
+	Keys            map[string]string
+	AccessTokenTTL  time.Duration
+	// RefreshTokenTTL is how long a session can go unused before the user has to log in again
+	RefreshTokenTTL time.Duration
+}
+
+// OrderReferenceConfig holds the prefix put in front of order reference numbers for
+// shops that have not set their own
+type OrderReferenceConfig struct {
+	DefaultPrefix string
+}
+
+// MpesaConfig holds Daraja (M-Pesa Express) settings
+type MpesaConfig struct {
+	BaseURL        string
+	ConsumerKey    string
+	ConsumerSecret string
+	ShortCode      string
+	Passkey        string
+	CallbackURL    string
+	// CallbackToken must be sent as ?token= on callbacks so they cannot be forged; callbacks
+	// are refused until it is set
+	CallbackToken string
+}
+
+// StaffConfig holds how shops get their first owner
+type StaffConfig struct {
+	// BootstrapOwners maps a shop to the email of the user made its owner on first login,
+	// so a new shop has someone who can add the rest of its staff
+	BootstrapOwners map[string]string
+}
+
+// NotificationsConfig holds how the outbox of order notifications is delivered
+type NotificationsConfig struct {
+	// PollInterval is how often the worker looks for notifications that are due
+	PollInterval time.Duration
+	// MaxAttempts is how many times a notification is tried before it is dead-lettered
+	MaxAttempts int
 }
 
 var AppConfig Config
@@ -49,11 +128,30 @@ func LoadEnvConfig() error {
 	port, _ := env.GetEnvString("PORT")
 	jwtSecret, _ := env.GetEnvString("JWT_SECRET")
 
+	// JWT signing keys, e.g. JWT_KEYS="2025-10=secret-a,2025-11=secret-b" with JWT_SIGNING_KEY_ID=2025-11;
+	// a lone JWT_SECRET is used as the key "default"
+	jwtIssuer, _ := env.GetEnvString("JWT_ISSUER")
+	jwtAudience, _ := env.GetEnvString("JWT_AUDIENCE")
+	jwtKeys, _ := env.GetEnvString("JWT_KEYS")
+	jwtSigningKeyID, _ := env.GetEnvString("JWT_SIGNING_KEY_ID")
+	jwtAccessTokenTTL, _ := env.GetEnvString("JWT_ACCESS_TOKEN_TTL")
+	jwtRefreshTokenTTL, _ := env.GetEnvString("JWT_REFRESH_TOKEN_TTL")
+
 	// 🔑 Load Africa's Talking settings from environment variables
 	baseURL, _ := env.GetEnvString("AFRICAS_TALKING_BASE_URL")
 	apiKey, _ := env.GetEnvString("AFRICAS_TALKING_API_KEY")
 	username, _ := env.GetEnvString("AFRICAS_TALKING_USERNAME")
 	envVar, _ := env.GetEnvString("AFRICAS_TALKING_ENV") // ✅ FIXED: Changed variable name
+	smsCallbackToken, _ := env.GetEnvString("AFRICAS_TALKING_CALLBACK_TOKEN")
+
+	// SMS providers in failover order, e.g. SMS_PROVIDERS="africastalking,twilio"; "log"
+	// writes messages to SMS_LOG_FILE (or stdout) instead of sending them
+	smsProviders, _ := env.GetEnvString("SMS_PROVIDERS")
+	smsLogFile, _ := env.GetEnvString("SMS_LOG_FILE")
+	twilioBaseURL, _ := env.GetEnvString("TWILIO_BASE_URL")
+	twilioAccountSID, _ := env.GetEnvString("TWILIO_ACCOUNT_SID")
+	twilioAuthToken, _ := env.GetEnvString("TWILIO_AUTH_TOKEN")
+	twilioFrom, _ := env.GetEnvString("TWILIO_FROM")
 
 	smtpHost, _ := env.GetEnvString("SMTP_HOST")
     smtpPort, _ := env.GetEnvInt("SMTP_PORT")
@@ -68,16 +166,91 @@ func LoadEnvConfig() error {
 	clientSecret, _ := env.GetEnvString("OIDC_CLIENT_SECRET")
 	issuerURL, _ := env.GetEnvString("OIDC_ISSUER_URL")
 	redirectURL, _ := env.GetEnvString("OIDC_REDIRECT_URL")
+	// OIDC_COOKIE_SECRET defaults to the JWT signing key; post-login redirects are full URLs,
+	// e.g. OIDC_ALLOWED_REDIRECT_URLS="https://pos.example.com/login,savannahpos://login"
+	oidcCookieSecret, _ := env.GetEnvString("OIDC_COOKIE_SECRET")
+	postLoginRedirectURL, _ := env.GetEnvString("OIDC_POST_LOGIN_REDIRECT_URL")
+	allowedRedirectURLs, _ := env.GetEnvString("OIDC_ALLOWED_REDIRECT_URLS")
+
+	// Order reference prefix for shops without their own, e.g. ORDER_REFERENCE_PREFIX=SV
+	referencePrefix, _ := env.GetEnvString("ORDER_REFERENCE_PREFIX")
+
+	// M-Pesa settings; the base URL defaults to the Daraja sandbox
+	mpesaBaseURL, _ := env.GetEnvString("MPESA_BASE_URL")
+	mpesaConsumerKey, _ := env.GetEnvString("MPESA_CONSUMER_KEY")
+	mpesaConsumerSecret, _ := env.GetEnvString("MPESA_CONSUMER_SECRET")
+	mpesaShortCode, _ := env.GetEnvString("MPESA_SHORTCODE")
+	mpesaPasskey, _ := env.GetEnvString("MPESA_PASSKEY")
+	mpesaCallbackURL, _ := env.GetEnvString("MPESA_CALLBACK_URL")
+	mpesaCallbackToken, _ := env.GetEnvString("MPESA_CALLBACK_TOKEN")
+
+	// Shop owners set up at login, e.g. STAFF_BOOTSTRAP_OWNERS="shop-1=owner@example.com"
+	staffBootstrapOwners, _ := env.GetEnvString("STAFF_BOOTSTRAP_OWNERS")
+
+	// Notification outbox delivery, e.g. NOTIFICATION_POLL_INTERVAL=5s and NOTIFICATION_MAX_ATTEMPTS=8
+	notificationPollInterval, _ := env.GetEnvString("NOTIFICATION_POLL_INTERVAL")
+	notificationMaxAttempts, _ := env.GetEnvString("NOTIFICATION_MAX_ATTEMPTS")
+
+	if mpesaBaseURL == "" {
+		mpesaBaseURL = processor.MpesaSandboxBaseURL
+	}
+
+	bootstrapOwners, err := parseBootstrapOwners(staffBootstrapOwners)
+	if err != nil {
+		return err
+	}
+
+	jwtConfig, err := parseJWTConfig(jwtIssuer, jwtAudience, jwtKeys, jwtSigningKeyID, jwtSecret, jwtAccessTokenTTL, jwtRefreshTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	notificationsConfig, err := parseNotificationsConfig(notificationPollInterval, notificationMaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	providers, err := parseSMSProviders(smsProviders)
+	if err != nil {
+		return err
+	}
+
+	// the sandbox has its own host; an explicit base URL still wins, e.g. for a proxy
+	if baseURL == "" {
+		baseURL = processor.AfricasTalkingBaseURL(envVar)
+	}
+
+	redirectURLs, err := parseRedirectURLs(postLoginRedirectURL, allowedRedirectURLs)
+	if err != nil {
+		return err
+	}
+
+	if oidcCookieSecret == "" {
+		oidcCookieSecret = jwtConfig.Keys[jwtConfig.SigningKeyID]
+	}
+
+	if referencePrefix != "" && !utils.ValidReferencePrefix(referencePrefix) {
+		return fmt.Errorf("invalid ORDER_REFERENCE_PREFIX %q: use 1-4 upper case letters or digits", referencePrefix)
+	}
 
 	AppConfig = Config{
 		DatabaseURL: databaseURL,
 		Port:        port,
-		JWTSecret:   jwtSecret,
+		JWT:         jwtConfig,
 		SMSService: SMSServiceConfig{
 			BaseURL:  baseURL,
 			ApiKey:   apiKey,
 			Username: username,
 			Env:      envVar, // ✅ FIXED: Use the renamed variable
+			CallbackToken: smsCallbackToken,
+			Providers:     providers,
+			Twilio: TwilioConfig{
+				BaseURL:    twilioBaseURL,
+				AccountSID: twilioAccountSID,
+				AuthToken:  twilioAuthToken,
+				From:       twilioFrom,
+			},
+			LogFile: smsLogFile,
 		},
 		EmailService: EmailServiceConfig{
             SMTPHost:   smtpHost,
@@ -89,24 +262,226 @@ func LoadEnvConfig() error {
             AdminEmail: adminEmail,
         },
 		OIDC: OIDCConfig{
-			ClientID:     clientID,
-			ClientSecret: clientSecret,
-			IssuerURL:    issuerURL,
-			RedirectURL:  redirectURL,
+			ClientID:             clientID,
+			ClientSecret:         clientSecret,
+			IssuerURL:            issuerURL,
+			RedirectURL:          redirectURL,
+			CookieSecret:         oidcCookieSecret,
+			PostLoginRedirectURL: strings.TrimSpace(postLoginRedirectURL),
+			AllowedRedirectURLs:  redirectURLs,
 		},
+		OrderReference: OrderReferenceConfig{
+			DefaultPrefix: referencePrefix,
+		},
+		Mpesa: MpesaConfig{
+			BaseURL:        strings.TrimRight(mpesaBaseURL, "/"),
+			ConsumerKey:    mpesaConsumerKey,
+			ConsumerSecret: mpesaConsumerSecret,
+			ShortCode:      mpesaShortCode,
+			Passkey:        mpesaPasskey,
+			CallbackURL:    mpesaCallbackURL,
+			CallbackToken:  mpesaCallbackToken,
+		},
+		Staff: StaffConfig{
+			BootstrapOwners: bootstrapOwners,
+		},
+		Notifications: notificationsConfig,
 	}
 
 	// Validate required SMS config
-	if AppConfig.SMSService.ApiKey == "" || AppConfig.SMSService.Username == "" {
-		return fmt.Errorf("Africa's Talking API credentials are required")
+	for _, provider := range providers {
+		switch provider {
+		case processor.SMSProviderAfricasTalking:
+			if AppConfig.SMSService.ApiKey == "" || AppConfig.SMSService.Username == "" {
+				return fmt.Errorf("Africa's Talking API credentials are required")
+			}
+		case processor.SMSProviderTwilio:
+			if twilioAccountSID == "" || twilioAuthToken == "" || twilioFrom == "" {
+				return fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM are required for the twilio sms provider")
+			}
+		}
 	}
 
 	// Log the loaded config for debugging
-	fmt.Printf("SMS Config Loaded - Username: %s, API Key: %s, BaseURL: %s, Env: %s\n", 
-		AppConfig.SMSService.Username, 
-		AppConfig.SMSService.ApiKey, 
+	fmt.Printf("SMS Config Loaded - Providers: %s, Username: %s, BaseURL: %s, Env: %s\n",
+		strings.Join(AppConfig.SMSService.Providers, ","),
+		AppConfig.SMSService.Username,
 		AppConfig.SMSService.BaseURL,
 		AppConfig.SMSService.Env)
 
 	return nil
-}
\ No newline at end of file
+}
+
+// parseBootstrapOwners reads "shop=email" pairs separated by commas.
+func parseBootstrapOwners(value string) (map[string]string, error) {
+	owners := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		shopID, email, found := strings.Cut(pair, "=")
+		shopID = strings.TrimSpace(shopID)
+		email = strings.TrimSpace(email)
+		if !found || shopID == "" || !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("invalid STAFF_BOOTSTRAP_OWNERS entry %q: expected shop=email", pair)
+		}
+
+		owners[shopID] = email
+	}
+
+	return owners, nil
+}
+
+// parseRedirectURLs reads the comma separated post-login redirects a client may ask for.
+// The default redirect is always allowed. Tokens are handed over in the URL fragment, so
+// a redirect cannot have one of its own.
+func parseRedirectURLs(postLoginRedirectURL, allowedRedirectURLs string) ([]string, error) {
+	redirectURLs := make([]string, 0)
+
+	for _, redirectURL := range append(strings.Split(allowedRedirectURLs, ","), postLoginRedirectURL) {
+		redirectURL = strings.TrimSpace(redirectURL)
+		if redirectURL == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(redirectURL)
+		if err != nil || parsed.Scheme == "" || parsed.Fragment != "" {
+			return nil, fmt.Errorf("invalid post-login redirect %q: expected an absolute URL without a fragment", redirectURL)
+		}
+
+		redirectURLs = append(redirectURLs, redirectURL)
+	}
+
+	return redirectURLs, nil
+}
+
+// parseNotificationsConfig fills in the notification delivery defaults.
+func parseNotificationsConfig(pollInterval, maxAttempts string) (NotificationsConfig, error) {
+	notificationsConfig := NotificationsConfig{
+		PollInterval: 5 * time.Second,
+		MaxAttempts:  8,
+	}
+
+	if pollInterval != "" {
+		interval, err := time.ParseDuration(pollInterval)
+		if err != nil || interval <= 0 {
+			return NotificationsConfig{}, fmt.Errorf("invalid NOTIFICATION_POLL_INTERVAL %q: use a duration such as 5s", pollInterval)
+		}
+		notificationsConfig.PollInterval = interval
+	}
+
+	if maxAttempts != "" {
+		attempts, err := strconv.Atoi(maxAttempts)
+		if err != nil || attempts < 1 {
+			return NotificationsConfig{}, fmt.Errorf("invalid NOTIFICATION_MAX_ATTEMPTS %q: use a whole number of at least 1", maxAttempts)
+		}
+		notificationsConfig.MaxAttempts = attempts
+	}
+
+	return notificationsConfig, nil
+}
+
+// parseSMSProviders reads the providers to send SMS through, in the order they are tried;
+// Africa's Talking alone when none are given.
+func parseSMSProviders(value string) ([]string, error) {
+	providers := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, provider := range strings.Split(value, ",") {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if provider == "" {
+			continue
+		}
+
+		switch provider {
+		case processor.SMSProviderAfricasTalking, processor.SMSProviderTwilio, processor.SMSProviderLog:
+		default:
+			return nil, fmt.Errorf("invalid SMS_PROVIDERS %q: %q is not one of africastalking, twilio or log", value, provider)
+		}
+
+		if seen[provider] {
+			return nil, fmt.Errorf("invalid SMS_PROVIDERS %q: %q is listed twice", value, provider)
+		}
+		seen[provider] = true
+
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 0 {
+		providers = append(providers, processor.SMSProviderAfricasTalking)
+	}
+
+	return providers, nil
+}
+
+// parseJWTConfig fills in the JWT defaults and reads the "kid=secret" key pairs.
+func parseJWTConfig(issuer, audience, keys, signingKeyID, secret, accessTokenTTL, refreshTokenTTL string) (JWTConfig, error) {
+	jwtConfig := JWTConfig{
+		Issuer:          issuer,
+		Audience:        audience,
+		SigningKeyID:    signingKeyID,
+		Keys:            make(map[string]string),
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+	}
+
+	if jwtConfig.Issuer == "" {
+		jwtConfig.Issuer = "savannah-pos"
+	}
+
+	if jwtConfig.Audience == "" {
+		jwtConfig.Audience = "savannah-pos-api"
+	}
+
+	for _, pair := range strings.Split(keys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		keyID, keySecret, found := strings.Cut(pair, "=")
+		keyID = strings.TrimSpace(keyID)
+		if !found || keyID == "" || keySecret == "" {
+			return JWTConfig{}, fmt.Errorf("invalid JWT_KEYS entry for key %q: expected kid=secret", keyID)
+		}
+
+		jwtConfig.Keys[keyID] = keySecret
+	}
+
+	if len(jwtConfig.Keys) == 0 && secret != "" {
+		jwtConfig.Keys["default"] = secret
+	}
+
+	if jwtConfig.SigningKeyID == "" && len(jwtConfig.Keys) == 1 {
+		for keyID := range jwtConfig.Keys {
+			jwtConfig.SigningKeyID = keyID
+		}
+	}
+
+	if len(jwtConfig.Keys) > 0 {
+		if _, ok := jwtConfig.Keys[jwtConfig.SigningKeyID]; !ok {
+			return JWTConfig{}, fmt.Errorf("JWT_SIGNING_KEY_ID %q is not one of the JWT_KEYS", jwtConfig.SigningKeyID)
+		}
+	}
+
+	if accessTokenTTL != "" {
+		ttl, err := time.ParseDuration(accessTokenTTL)
+		if err != nil || ttl <= 0 {
+			return JWTConfig{}, fmt.Errorf("invalid JWT_ACCESS_TOKEN_TTL %q: use a duration such as 15m", accessTokenTTL)
+		}
+		jwtConfig.AccessTokenTTL = ttl
+	}
+
+	if refreshTokenTTL != "" {
+		ttl, err := time.ParseDuration(refreshTokenTTL)
+		if err != nil || ttl <= 0 {
+			return JWTConfig{}, fmt.Errorf("invalid JWT_REFRESH_TOKEN_TTL %q: use a duration such as 720h", refreshTokenTTL)
+		}
+		jwtConfig.RefreshTokenTTL = ttl
+	}
+
+	return jwtConfig, nil
+}
@@ -55,6 +55,8 @@ func (e *Error) Status() int {
 		return http.StatusInternalServerError
 	case NotFound:
 		return http.StatusNotFound
+	case Permission:
+		return http.StatusForbidden
 	case PayloadTooLarge:
 		return http.StatusRequestEntityTooLarge
 	case ServiceUnavailable:
@@ -34,6 +34,19 @@ func FilterFromContext(
 	filter.Year = strings.TrimSpace(c.Query("year"))
 	filter.Reference = strings.TrimSpace(c.Query("reference"))
 
+	activeQuery := strings.TrimSpace(c.Query("active"))
+	if activeQuery != "" {
+		active, err := strconv.ParseBool(activeQuery)
+		if err != nil {
+			log.Printf("Failed to parse active query param [%v]", activeQuery)
+			return filter, apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+		}
+		filter.Active = null.NullValue(active)
+	}
+
 	return filter, nil
 }
 
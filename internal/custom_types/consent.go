@@ -0,0 +1,82 @@
+package custom_types
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// NotificationPreference is how a customer agreed to hear about their orders. The shop's
+// admin email is not a notification to the customer and goes out whatever it is.
+type NotificationPreference string
+
+const (
+	NotificationPreferenceSMS   NotificationPreference = "sms"
+	NotificationPreferenceEmail NotificationPreference = "email"
+	NotificationPreferenceNone  NotificationPreference = "none"
+
+	// DefaultNotificationPreference is what customers had before they could choose, and
+	// what walk-ins get unless their number has opted out.
+	DefaultNotificationPreference = NotificationPreferenceSMS
+)
+
+func (n *NotificationPreference) Scan(value interface{}) error {
+	*n = NotificationPreference(string(value.([]uint8)))
+	return nil
+}
+
+func (n NotificationPreference) Value() (driver.Value, error) {
+	return n.String(), nil
+}
+
+func (n NotificationPreference) String() string {
+	return string(n)
+}
+
+func (n NotificationPreference) IsValid() bool {
+	switch n {
+	case NotificationPreferenceSMS, NotificationPreferenceEmail, NotificationPreferenceNone:
+		return true
+	}
+	return false
+}
+
+// NotificationPreferenceFromKeyword reads an opt-out or opt-in keyword texted to the shop's
+// number, in English or Swahili. Only the first word counts, so "stop please" opts out.
+func NotificationPreferenceFromKeyword(text string) (NotificationPreference, bool) {
+	words := strings.Fields(strings.ToUpper(text))
+	if len(words) == 0 {
+		return "", false
+	}
+
+	switch words[0] {
+	case "STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT", "ACHA", "SITISHA":
+		return NotificationPreferenceNone, true
+	case "START", "UNSTOP", "SUBSCRIBE", "ANZA":
+		return NotificationPreferenceSMS, true
+	}
+
+	return "", false
+}
+
+// ConsentSource is where a consent change came from.
+type ConsentSource string
+
+const (
+	// ConsentSourceStaff is a change staff made, on the customer's word.
+	ConsentSourceStaff ConsentSource = "staff"
+	// ConsentSourceSMSKeyword is the customer texting STOP or START.
+	ConsentSourceSMSKeyword ConsentSource = "sms_keyword"
+)
+
+func (c *ConsentSource) Scan(value interface{}) error {
+	*c = ConsentSource(string(value.([]uint8)))
+	return nil
+}
+
+func (c ConsentSource) Value() (driver.Value, error) {
+	return c.String(), nil
+}
+
+func (c ConsentSource) String() string {
+	return string(c)
+}
@@ -0,0 +1,30 @@
+package custom_types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreferenceFromKeyword(t *testing.T) {
+	tests := []struct {
+		text       string
+		preference NotificationPreference
+		ok         bool
+	}{
+		{"STOP", NotificationPreferenceNone, true},
+		{" stop please", NotificationPreferenceNone, true},
+		{"Sitisha", NotificationPreferenceNone, true},
+		{"START", NotificationPreferenceSMS, true},
+		{"anza", NotificationPreferenceSMS, true},
+		{"Is my order ready?", "", false},
+		{"stopped by the shop yesterday", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		preference, ok := NotificationPreferenceFromKeyword(tt.text)
+		assert.Equal(t, tt.ok, ok, tt.text)
+		assert.Equal(t, tt.preference, preference, tt.text)
+	}
+}
@@ -0,0 +1,31 @@
+package custom_types
+
+import "database/sql/driver"
+
+// Locale is the language a customer's notifications are written in.
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleSwahili Locale = "sw"
+
+	// DefaultLocale is used for customers who have not picked one, and for walk-ins.
+	DefaultLocale = LocaleEnglish
+)
+
+func (l *Locale) Scan(value interface{}) error {
+	*l = Locale(string(value.([]uint8)))
+	return nil
+}
+
+func (l Locale) Value() (driver.Value, error) {
+	return l.String(), nil
+}
+
+func (l Locale) String() string {
+	return string(l)
+}
+
+func (l Locale) IsValid() bool {
+	return l == LocaleEnglish || l == LocaleSwahili
+}
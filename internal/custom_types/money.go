@@ -0,0 +1,313 @@
+package custom_types
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency amounts are assumed to be in when none is given.
+const DefaultCurrency = "KES"
+
+// ErrCurrencyMismatch is returned when amounts in two different currencies are combined.
+var ErrCurrencyMismatch = errors.New("money: currencies differ")
+
+// minorUnitsPerMajor is 100 for every currency we sell in (cents, Kenyan cents).
+const minorUnitsPerMajor = 100
+
+// Money is an exact amount held in minor units, together with its ISO 4217 currency code.
+//
+// Money is single-currency: neither the DECIMAL(…, 2) columns nor the JSON number it is
+// written as carry a currency, so every amount is taken to be in DefaultCurrency. JSON is
+// read from a number, a decimal string, or an object {"amount": "1250.50", "currency": "KES"},
+// and an object naming any other currency is rejected rather than stored as shillings.
+// Adding or subtracting amounts in two different currencies fails with ErrCurrencyMismatch.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney returns an amount of minor units in the default currency.
+func NewMoney(minorUnits int64) Money {
+	return Money{Amount: minorUnits, Currency: DefaultCurrency}
+}
+
+// ParseMoney reads a decimal string such as "1250.5" or "-3.25". More than two decimal
+// places are rejected unless the extra digits are zeros, so no value is silently rounded.
+func ParseMoney(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Money{}, fmt.Errorf("money: empty amount")
+	}
+
+	negative := false
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return Money{}, fmt.Errorf("money: invalid amount %q", value)
+	}
+
+	trimmed := strings.TrimRight(fraction, "0")
+	if len(trimmed) > 2 {
+		return Money{}, fmt.Errorf("money: amount %q has more than two decimal places", value)
+	}
+	fraction = (trimmed + "00")[:2]
+
+	if whole == "" {
+		whole = "0"
+	}
+
+	for _, digits := range []string{whole, fraction} {
+		for _, char := range digits {
+			if char < '0' || char > '9' {
+				return Money{}, fmt.Errorf("money: invalid amount %q", value)
+			}
+		}
+	}
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || major > math.MaxInt64/minorUnitsPerMajor-1 {
+		return Money{}, fmt.Errorf("money: amount %q out of range", value)
+	}
+
+	minor, _ := strconv.ParseInt(fraction, 10, 64)
+
+	amount := major*minorUnitsPerMajor + minor
+	if negative {
+		amount = -amount
+	}
+
+	return NewMoney(amount), nil
+}
+
+// MoneyFromFloat converts a float, rounding half away from zero to the nearest minor unit.
+// It is meant for values that are already floats, such as JSON numbers or SQL averages.
+func MoneyFromFloat(value float64) Money {
+	return NewMoney(int64(math.Round(value * minorUnitsPerMajor)))
+}
+
+// Add returns m + other. A zero value without a currency takes the other's currency.
+// It fails with ErrCurrencyMismatch if both have a currency and they differ.
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.currencyWith(other)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: m.Amount + other.Amount, Currency: currency}, nil
+}
+
+// Sub returns m - other. It fails with ErrCurrencyMismatch if both have a currency and
+// they differ.
+func (m Money) Sub(other Money) (Money, error) {
+	currency, err := m.currencyWith(other)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: m.Amount - other.Amount, Currency: currency}, nil
+}
+
+// Mul returns m times a whole quantity.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// MulRatio returns m * numerator / denominator, rounded half away from zero to the minor unit.
+// It is how shares of an amount, such as a prorated discount, are worked out.
+func (m Money) MulRatio(numerator, denominator int64) Money {
+	if denominator == 0 {
+		return Money{Currency: m.Currency}
+	}
+
+	product := m.Amount * numerator
+	quotient := product / denominator
+	remainder := product % denominator
+
+	if remainder != 0 && 2*absInt64(remainder) >= absInt64(denominator) {
+		if (product < 0) != (denominator < 0) {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+
+	return Money{Amount: quotient, Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Decimal formats the amount in major units with two decimal places, e.g. "1250.50".
+func (m Money) Decimal() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, amount/minorUnitsPerMajor, amount%minorUnitsPerMajor)
+}
+
+// String formats the amount for people, e.g. "KES 1,250.50".
+func (m Money) String() string {
+	decimal := m.Decimal()
+
+	sign := ""
+	if strings.HasPrefix(decimal, "-") {
+		sign = "-"
+		decimal = decimal[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(decimal, ".")
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	return fmt.Sprintf("%s %s%s.%s", currency, sign, whole, fraction)
+}
+
+func (m *Money) Scan(value interface{}) error {
+	var (
+		parsed Money
+		err    error
+	)
+
+	switch v := value.(type) {
+	case nil:
+		parsed = NewMoney(0)
+	case []byte:
+		parsed, err = ParseMoney(string(v))
+	case string:
+		parsed, err = ParseMoney(v)
+	case int64:
+		parsed = NewMoney(v * minorUnitsPerMajor)
+	case float64:
+		parsed = MoneyFromFloat(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T", value)
+	}
+	if err != nil {
+		return err
+	}
+
+	if m.Currency != "" {
+		parsed.Currency = m.Currency
+	}
+
+	*m = parsed
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		var object struct {
+			Amount   json.RawMessage `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+
+		err := json.Unmarshal(data, &object)
+		if err != nil {
+			return err
+		}
+
+		var amount Money
+		err = amount.UnmarshalJSON(object.Amount)
+		if err != nil {
+			return err
+		}
+
+		currency := strings.ToUpper(strings.TrimSpace(object.Currency))
+		if currency != "" && currency != DefaultCurrency {
+			return fmt.Errorf("money: only %s amounts are accepted, got %q", DefaultCurrency, object.Currency)
+		}
+
+		*m = amount
+		return nil
+	}
+
+	var text string
+	if len(data) > 0 && data[0] == '"' {
+		err := json.Unmarshal(data, &text)
+		if err != nil {
+			return err
+		}
+	} else if bytes.ContainsAny(data, "eE") {
+		value, err := strconv.ParseFloat(string(data), 64)
+		if err != nil {
+			return err
+		}
+
+		*m = MoneyFromFloat(value)
+		return nil
+	} else {
+		// JSON numbers are read from their text, so 0.1 stays exactly ten cents
+		text = string(data)
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func (m Money) currencyWith(other Money) (string, error) {
+	if m.Currency == "" {
+		return other.Currency, nil
+	}
+
+	if other.Currency != "" && other.Currency != m.Currency {
+		return "", fmt.Errorf("%w: cannot combine %s with %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	return m.Currency, nil
+}
+
+func absInt64(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
@@ -0,0 +1,120 @@
+package custom_types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"1250.50", 125050, false},
+		{"1250.5", 125050, false},
+		{"0.1", 10, false},
+		{"-3.25", -325, false},
+		{"12.3400", 1234, false},
+		{"7", 700, false},
+		{".99", 99, false},
+		{"1.005", 0, true},
+		{"abc", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			money, err := ParseMoney(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, money.Amount)
+			assert.Equal(t, DefaultCurrency, money.Currency)
+		})
+	}
+}
+
+func TestMoney_AddsWithoutDrift(t *testing.T) {
+	total := NewMoney(0)
+	for i := 0; i < 10; i++ {
+		var err error
+		total, err = total.Add(MoneyFromFloat(0.1))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, int64(100), total.Amount)
+	assert.Equal(t, "1.00", total.Decimal())
+}
+
+func TestMoney_RefusesToMixCurrencies(t *testing.T) {
+	sum, err := Money{Amount: 50}.Add(NewMoney(100))
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(150), sum)
+
+	_, err = NewMoney(100).Add(Money{Amount: 100, Currency: "USD"})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = NewMoney(100).Sub(Money{Amount: 100, Currency: "USD"})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoney_MulRatioRoundsHalfAwayFromZero(t *testing.T) {
+	assert.Equal(t, int64(33), NewMoney(100).MulRatio(1, 3).Amount)
+	assert.Equal(t, int64(67), NewMoney(100).MulRatio(2, 3).Amount)
+	assert.Equal(t, int64(-50), NewMoney(-100).MulRatio(1, 2).Amount)
+	assert.Equal(t, int64(-2), NewMoney(-3).MulRatio(1, 2).Amount)
+	assert.Equal(t, int64(2), NewMoney(3).MulRatio(1, 2).Amount)
+	assert.Equal(t, int64(0), NewMoney(100).MulRatio(1, 0).Amount)
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "KES 1,234,567.05", NewMoney(123456705).String())
+	assert.Equal(t, "KES -0.50", NewMoney(-50).String())
+	assert.Equal(t, "USD 12.00", Money{Amount: 1200, Currency: "USD"}.String())
+}
+
+func TestMoney_JSON(t *testing.T) {
+	encoded, err := json.Marshal(struct {
+		Price Money `json:"price"`
+	}{NewMoney(125050)})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"price": 1250.50}`, string(encoded))
+
+	inputs := map[string]Money{
+		`1250.5`:                                 NewMoney(125050),
+		`"1250.50"`:                              NewMoney(125050),
+		`1.2505e3`:                               NewMoney(125050),
+		`{"amount": "12.00", "currency": "kes"}`: NewMoney(1200),
+		`{"amount": 12}`:                         NewMoney(1200),
+	}
+
+	for input, expected := range inputs {
+		var money Money
+		assert.NoError(t, json.Unmarshal([]byte(input), &money), input)
+		assert.Equal(t, expected, money, input)
+	}
+
+	var money Money
+	assert.Error(t, json.Unmarshal([]byte(`12.345`), &money))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": "12.00", "currency": "usd"}`), &money))
+}
+
+func TestMoney_ScanAndValue(t *testing.T) {
+	var money Money
+
+	assert.NoError(t, money.Scan([]byte("99.9900")))
+	assert.Equal(t, NewMoney(9999), money)
+
+	assert.NoError(t, money.Scan(nil))
+	assert.True(t, money.IsZero())
+
+	value, err := NewMoney(-1005).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "-10.05", value)
+}
@@ -0,0 +1,25 @@
+package custom_types
+
+import "database/sql/driver"
+
+type MpesaRequestStatus string
+
+const (
+	MpesaRequestStatusPending   MpesaRequestStatus = "pending"
+	MpesaRequestStatusCompleted MpesaRequestStatus = "completed"
+	MpesaRequestStatusFailed    MpesaRequestStatus = "failed"
+	MpesaRequestStatusCancelled MpesaRequestStatus = "cancelled"
+)
+
+func (m *MpesaRequestStatus) Scan(value interface{}) error {
+	*m = MpesaRequestStatus(string(value.([]uint8)))
+	return nil
+}
+
+func (m MpesaRequestStatus) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m MpesaRequestStatus) String() string {
+	return string(m)
+}
@@ -0,0 +1,148 @@
+package custom_types
+
+import "database/sql/driver"
+
+// NotificationChannel is how a notification reaches its recipient.
+type NotificationChannel string
+
+const (
+	NotificationChannelSMS NotificationChannel = "sms"
+	// NotificationChannelEmail is the email to the shop's admin.
+	NotificationChannelEmail NotificationChannel = "email"
+	// NotificationChannelCustomerEmail is an email to a customer who asked for email
+	// instead of SMS.
+	NotificationChannelCustomerEmail NotificationChannel = "customer_email"
+)
+
+func (n *NotificationChannel) Scan(value interface{}) error {
+	*n = NotificationChannel(string(value.([]uint8)))
+	return nil
+}
+
+func (n NotificationChannel) Value() (driver.Value, error) {
+	return n.String(), nil
+}
+
+func (n NotificationChannel) String() string {
+	return string(n)
+}
+
+func (n NotificationChannel) IsValid() bool {
+	switch n {
+	case NotificationChannelSMS, NotificationChannelEmail, NotificationChannelCustomerEmail:
+		return true
+	}
+	return false
+}
+
+// IsEmail reports whether the channel sends an email, which needs a subject.
+func (n NotificationChannel) IsEmail() bool {
+	return n == NotificationChannelEmail || n == NotificationChannelCustomerEmail
+}
+
+// NotificationEvent is what a notification tells its recipient about.
+type NotificationEvent string
+
+const (
+	NotificationEventOrderConfirmation NotificationEvent = "order_confirmation"
+	NotificationEventOrderReturn       NotificationEvent = "order_return"
+)
+
+func (n *NotificationEvent) Scan(value interface{}) error {
+	*n = NotificationEvent(string(value.([]uint8)))
+	return nil
+}
+
+func (n NotificationEvent) Value() (driver.Value, error) {
+	return n.String(), nil
+}
+
+func (n NotificationEvent) String() string {
+	return string(n)
+}
+
+func (n NotificationEvent) IsValid() bool {
+	return n == NotificationEventOrderConfirmation || n == NotificationEventOrderReturn
+}
+
+// NotificationStatus is where a notification in the outbox is: waiting to be (re)tried,
+// sent, dead once it has used up its attempts, or suppressed when the customer opted out
+// before it went.
+type NotificationStatus string
+
+const (
+	NotificationStatusPending    NotificationStatus = "pending"
+	NotificationStatusSent       NotificationStatus = "sent"
+	NotificationStatusDead       NotificationStatus = "dead"
+	NotificationStatusSuppressed NotificationStatus = "suppressed"
+)
+
+func (n *NotificationStatus) Scan(value interface{}) error {
+	*n = NotificationStatus(string(value.([]uint8)))
+	return nil
+}
+
+func (n NotificationStatus) Value() (driver.Value, error) {
+	return n.String(), nil
+}
+
+func (n NotificationStatus) String() string {
+	return string(n)
+}
+
+func (n NotificationStatus) IsValid() bool {
+	switch n {
+	case NotificationStatusPending, NotificationStatusSent, NotificationStatusDead, NotificationStatusSuppressed:
+		return true
+	}
+	return false
+}
+
+// SMSStatus is what the SMS provider last said about a message: sent while it is on its
+// way, then success, failed or rejected once its delivery report comes in.
+type SMSStatus string
+
+const (
+	SMSStatusSent     SMSStatus = "sent"
+	SMSStatusSuccess  SMSStatus = "success"
+	SMSStatusFailed   SMSStatus = "failed"
+	SMSStatusRejected SMSStatus = "rejected"
+)
+
+// SMSStatusFromDeliveryReport maps an Africa's Talking delivery report status to ours.
+// Sent, Submitted and Buffered mean the message is still on its way.
+func SMSStatusFromDeliveryReport(status string) (SMSStatus, bool) {
+	switch status {
+	case "Sent", "Submitted", "Buffered":
+		return SMSStatusSent, true
+	case "Success":
+		return SMSStatusSuccess, true
+	case "Failed":
+		return SMSStatusFailed, true
+	case "Rejected":
+		return SMSStatusRejected, true
+	}
+	return "", false
+}
+
+func (s *SMSStatus) Scan(value interface{}) error {
+	*s = SMSStatus(string(value.([]uint8)))
+	return nil
+}
+
+func (s SMSStatus) Value() (driver.Value, error) {
+	return s.String(), nil
+}
+
+func (s SMSStatus) String() string {
+	return string(s)
+}
+
+func (s SMSStatus) IsValid() bool {
+	return s == SMSStatusSent || s.IsFinal()
+}
+
+// IsFinal is true once the message has been delivered or given up on.
+func (s SMSStatus) IsFinal() bool {
+	return s == SMSStatusSuccess || s == SMSStatusFailed || s == SMSStatusRejected
+}
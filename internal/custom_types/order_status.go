@@ -11,6 +11,13 @@ const (
 	OrderStatusReturned OrderStatus = "returned"
 )
 
+// orderStatusTransitions lists the statuses an order may move to from each status.
+// Cancelled and returned orders are final.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:    {OrderStatusReturned},
+}
+
 func(o *OrderStatus) Scan(value interface{}) error {
 	*o = OrderStatus(string(value.([]uint8)))
 	return nil 
@@ -22,4 +29,22 @@ func (o OrderStatus) Value() (driver.Value, error) {
 
 func (o OrderStatus) String() string {
 	return string(o)
-}
\ No newline at end of file
+}
+
+func (o OrderStatus) IsValid() bool {
+	switch o {
+	case OrderStatusPending, OrderStatusCancelled, OrderStatusPaid, OrderStatusReturned:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an order in status o may be moved to next.
+func (o OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[o] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
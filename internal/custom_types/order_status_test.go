@@ -0,0 +1,36 @@
+package custom_types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from    OrderStatus
+		to      OrderStatus
+		allowed bool
+	}{
+		{OrderStatusPending, OrderStatusPaid, true},
+		{OrderStatusPending, OrderStatusCancelled, true},
+		{OrderStatusPaid, OrderStatusReturned, true},
+		{OrderStatusPending, OrderStatusReturned, false},
+		{OrderStatusPaid, OrderStatusCancelled, false},
+		{OrderStatusPaid, OrderStatusPending, false},
+		{OrderStatusCancelled, OrderStatusPaid, false},
+		{OrderStatusCancelled, OrderStatusPending, false},
+		{OrderStatusReturned, OrderStatusPaid, false},
+		{OrderStatusPending, OrderStatusPending, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, tt.from.CanTransitionTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestOrderStatus_IsValid(t *testing.T) {
+	assert.True(t, OrderStatusPaid.IsValid())
+	assert.False(t, OrderStatus("shipped").IsValid())
+	assert.False(t, OrderStatus("").IsValid())
+}
@@ -21,4 +21,11 @@ func (p PaymentMethod) Value() (driver.Value, error) {
 
 func (p PaymentMethod) String() string {
 	return string(p)
-}
\ No newline at end of file
+}
+func (p PaymentMethod) IsValid() bool {
+	switch p {
+	case PaymentMethodCash, PaymentMethodCard, PaymentMethodMpesa:
+		return true
+	}
+	return false
+}
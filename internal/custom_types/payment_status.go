@@ -0,0 +1,24 @@
+package custom_types
+
+import "database/sql/driver"
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusCompleted PaymentStatus = "completed"
+	PaymentStatusFailed    PaymentStatus = "failed"
+)
+
+func (p *PaymentStatus) Scan(value interface{}) error {
+	*p = PaymentStatus(string(value.([]uint8)))
+	return nil
+}
+
+func (p PaymentStatus) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+func (p PaymentStatus) String() string {
+	return string(p)
+}
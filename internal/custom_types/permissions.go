@@ -0,0 +1,40 @@
+package custom_types
+
+import (
+	"database/sql/driver"
+	"slices"
+	"strings"
+)
+
+// Permissions are the scopes of an API key, stored as a comma separated list.
+type Permissions []Permission
+
+func (p *Permissions) Scan(value interface{}) error {
+	*p = Permissions{}
+
+	for _, permission := range strings.Split(string(value.([]uint8)), ",") {
+		if permission != "" {
+			*p = append(*p, Permission(permission))
+		}
+	}
+
+	return nil
+}
+
+func (p Permissions) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+func (p Permissions) String() string {
+	permissions := make([]string, len(p))
+	for i, permission := range p {
+		permissions[i] = string(permission)
+	}
+
+	return strings.Join(permissions, ",")
+}
+
+// Has reports whether permission is one of p.
+func (p Permissions) Has(permission Permission) bool {
+	return slices.Contains(p, permission)
+}
@@ -6,7 +6,7 @@ type ProductType string
 
 const (
 	ProductTypeGoods ProductType = "goods"
-	ProductTypeService ProductType = "service"  
+	ProductTypeService ProductType = "services"  
 )
 
 func(p *ProductType) Scan(value interface{}) error {
@@ -20,4 +20,7 @@ func (p ProductType) Value() (driver.Value, error) {
 
 func (p ProductType) String() string {
 	return string(p)
-}
\ No newline at end of file
+}
+func (p ProductType) IsValid() bool {
+	return p == ProductTypeGoods || p == ProductTypeService
+}
@@ -0,0 +1,36 @@
+package custom_types
+
+import "database/sql/driver"
+
+type PromotionType string
+
+const (
+	// PromotionTypePercentage takes a percentage off each qualifying line.
+	PromotionTypePercentage PromotionType = "percentage"
+	// PromotionTypeFixed takes a fixed amount off each qualifying unit.
+	PromotionTypeFixed PromotionType = "fixed"
+	// PromotionTypeBuyXGetY gives Y units free for every X bought of the same product.
+	PromotionTypeBuyXGetY PromotionType = "buy_x_get_y"
+)
+
+func (p *PromotionType) Scan(value interface{}) error {
+	*p = PromotionType(string(value.([]uint8)))
+	return nil
+}
+
+func (p PromotionType) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+func (p PromotionType) String() string {
+	return string(p)
+}
+
+func (p PromotionType) IsValid() bool {
+	switch p {
+	case PromotionTypePercentage, PromotionTypeFixed, PromotionTypeBuyXGetY:
+		return true
+	}
+
+	return false
+}
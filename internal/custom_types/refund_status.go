@@ -0,0 +1,23 @@
+package custom_types
+
+import "database/sql/driver"
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusCompleted RefundStatus = "completed"
+)
+
+func (r *RefundStatus) Scan(value interface{}) error {
+	*r = RefundStatus(string(value.([]uint8)))
+	return nil
+}
+
+func (r RefundStatus) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r RefundStatus) String() string {
+	return string(r)
+}
@@ -0,0 +1,123 @@
+package custom_types
+
+import (
+	"database/sql/driver"
+	"slices"
+)
+
+type StaffRole string
+
+const (
+	StaffRoleOwner   StaffRole = "owner"
+	StaffRoleManager StaffRole = "manager"
+	StaffRoleCashier StaffRole = "cashier"
+	StaffRoleViewer  StaffRole = "viewer"
+)
+
+// Permission is something a route needs the caller's role in the shop to allow.
+type Permission string
+
+const (
+	PermissionViewShop          Permission = "shop:view"
+	PermissionCreateOrders      Permission = "orders:create"
+	PermissionManageOrders      Permission = "orders:manage"
+	PermissionTakePayments      Permission = "payments:take"
+	PermissionManageCustomers   Permission = "customers:manage"
+	PermissionCreateProducts    Permission = "products:create"
+	PermissionUpdateProducts    Permission = "products:update"
+	PermissionSetWholesalePrice Permission = "products:set_wholesale_price"
+	PermissionDeleteProducts    Permission = "products:delete"
+	PermissionManageStock       Permission = "stock:manage"
+	PermissionManageCatalog     Permission = "catalog:manage"
+	PermissionManageStaff       Permission = "staff:manage"
+	PermissionManageShop        Permission = "shop:manage"
+	PermissionManageAPIKeys     Permission = "api_keys:manage"
+	// PermissionManageTaxClasses adds tax classes of the shop's own; they decide the VAT
+	// it charges, so only owners hold it and no API key can
+	PermissionManageTaxClasses Permission = "tax_classes:manage"
+)
+
+var (
+	viewerPermissions = []Permission{
+		PermissionViewShop,
+	}
+
+	// cashiers run the till: they sell, take payment and keep customer details, but
+	// cannot touch what the shop pays for stock or take products off sale
+	cashierPermissions = slices.Concat(viewerPermissions, []Permission{
+		PermissionCreateOrders,
+		PermissionTakePayments,
+		PermissionManageCustomers,
+		PermissionUpdateProducts,
+	})
+
+	managerPermissions = slices.Concat(cashierPermissions, []Permission{
+		PermissionManageOrders,
+		PermissionCreateProducts,
+		PermissionSetWholesalePrice,
+		PermissionDeleteProducts,
+		PermissionManageStock,
+		PermissionManageCatalog,
+	})
+
+	ownerPermissions = slices.Concat(managerPermissions, []Permission{
+		PermissionManageStaff,
+		PermissionManageShop,
+		PermissionManageAPIKeys,
+		PermissionManageTaxClasses,
+	})
+)
+
+// rolePermissions lists what each role may do; each role can do everything the one below it can.
+var rolePermissions = map[StaffRole][]Permission{
+	StaffRoleOwner:   ownerPermissions,
+	StaffRoleManager: managerPermissions,
+	StaffRoleCashier: cashierPermissions,
+	StaffRoleViewer:  viewerPermissions,
+}
+
+// IsValid reports whether p is a permission some role can be granted.
+func (p Permission) IsValid() bool {
+	return slices.Contains(ownerPermissions, p)
+}
+
+// GrantableToAPIKey reports whether an API key may be given p as a scope. Keys are for
+// tills and scripts, so they cannot manage staff, the shop, other keys or tax classes.
+func (p Permission) GrantableToAPIKey() bool {
+	switch p {
+	case PermissionManageStaff, PermissionManageShop, PermissionManageAPIKeys, PermissionManageTaxClasses:
+		return false
+	}
+	return p.IsValid()
+}
+
+func (r *StaffRole) Scan(value interface{}) error {
+	*r = StaffRole(string(value.([]uint8)))
+	return nil
+}
+
+func (r StaffRole) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r StaffRole) String() string {
+	return string(r)
+}
+
+func (r StaffRole) IsValid() bool {
+	switch r {
+	case StaffRoleOwner, StaffRoleManager, StaffRoleCashier, StaffRoleViewer:
+		return true
+	}
+	return false
+}
+
+// Can reports whether role r grants permission.
+func (r StaffRole) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
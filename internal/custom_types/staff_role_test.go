@@ -0,0 +1,35 @@
+package custom_types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaffRole_Can(t *testing.T) {
+	assert.True(t, StaffRoleCashier.Can(PermissionCreateOrders))
+	assert.True(t, StaffRoleCashier.Can(PermissionUpdateProducts))
+	assert.False(t, StaffRoleCashier.Can(PermissionSetWholesalePrice))
+	assert.False(t, StaffRoleCashier.Can(PermissionDeleteProducts))
+
+	assert.True(t, StaffRoleViewer.Can(PermissionViewShop))
+	assert.False(t, StaffRoleViewer.Can(PermissionCreateOrders))
+
+	assert.True(t, StaffRoleManager.Can(PermissionDeleteProducts))
+	assert.False(t, StaffRoleManager.Can(PermissionManageStaff))
+
+	assert.True(t, StaffRoleOwner.Can(PermissionManageStaff))
+	assert.True(t, StaffRoleOwner.Can(PermissionManageTaxClasses))
+	assert.False(t, StaffRoleManager.Can(PermissionManageTaxClasses))
+	assert.False(t, StaffRole("janitor").Can(PermissionViewShop))
+}
+
+func TestPermission_GrantableToAPIKey(t *testing.T) {
+	assert.True(t, PermissionCreateOrders.GrantableToAPIKey())
+	assert.True(t, PermissionManageStock.GrantableToAPIKey())
+
+	assert.False(t, PermissionManageStaff.GrantableToAPIKey())
+	assert.False(t, PermissionManageAPIKeys.GrantableToAPIKey())
+	assert.False(t, PermissionManageTaxClasses.GrantableToAPIKey())
+	assert.False(t, Permission("orders:everything").GrantableToAPIKey())
+}
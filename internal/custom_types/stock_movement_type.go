@@ -0,0 +1,62 @@
+package custom_types
+
+import "database/sql/driver"
+
+type StockMovementType string
+
+const (
+	StockMovementTypeSale       StockMovementType = "sale"
+	StockMovementTypeReturn     StockMovementType = "return"
+	StockMovementTypeAdjustment StockMovementType = "adjustment"
+	StockMovementTypeDamage     StockMovementType = "damage"
+	StockMovementTypeReceived   StockMovementType = "received"
+)
+
+func (s *StockMovementType) Scan(value interface{}) error {
+	*s = StockMovementType(string(value.([]uint8)))
+	return nil
+}
+
+func (s StockMovementType) Value() (driver.Value, error) {
+	return s.String(), nil
+}
+
+func (s StockMovementType) String() string {
+	return string(s)
+}
+
+func (s StockMovementType) IsValid() bool {
+	switch s {
+	case StockMovementTypeSale, StockMovementTypeReturn, StockMovementTypeAdjustment, StockMovementTypeDamage, StockMovementTypeReceived:
+		return true
+	}
+	return false
+}
+
+// IsManual reports whether the movement may be recorded directly by staff rather than
+// as a side effect of an order or a return.
+func (s StockMovementType) IsManual() bool {
+	switch s {
+	case StockMovementTypeAdjustment, StockMovementTypeDamage, StockMovementTypeReceived:
+		return true
+	}
+	return false
+}
+
+// SignedQuantity applies the movement's direction to quantity. Sales and damage take stock
+// out, returns and receipts put it back, and adjustments keep the sign they were given.
+func (s StockMovementType) SignedQuantity(quantity int64) int64 {
+	if s == StockMovementTypeAdjustment {
+		return quantity
+	}
+
+	if quantity < 0 {
+		quantity = -quantity
+	}
+
+	if s == StockMovementTypeSale || s == StockMovementTypeDamage {
+		return -quantity
+	}
+
+	return quantity
+}
@@ -0,0 +1,37 @@
+package custom_types
+
+import "database/sql/driver"
+
+// TaxTreatment is how VAT applies to the goods in a tax class.
+type TaxTreatment string
+
+const (
+	// TaxTreatmentStandard goods carry VAT at the class rate.
+	TaxTreatmentStandard TaxTreatment = "standard"
+	// TaxTreatmentZeroRated goods are taxable at 0%, so input VAT can still be claimed on them.
+	TaxTreatmentZeroRated TaxTreatment = "zero_rated"
+	// TaxTreatmentExempt goods are outside VAT altogether.
+	TaxTreatmentExempt TaxTreatment = "exempt"
+)
+
+func (t *TaxTreatment) Scan(value interface{}) error {
+	*t = TaxTreatment(string(value.([]uint8)))
+	return nil
+}
+
+func (t TaxTreatment) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+func (t TaxTreatment) String() string {
+	return string(t)
+}
+
+func (t TaxTreatment) IsValid() bool {
+	switch t {
+	case TaxTreatmentStandard, TaxTreatmentZeroRated, TaxTreatmentExempt:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE order_status_transitions (
+    id                  BIGSERIAL           PRIMARY KEY,
+    order_id            BIGINT              NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status         ORDER_STATUS,
+    to_status           ORDER_STATUS        NOT NULL,
+    changed_by          VARCHAR(255)        NOT NULL,
+    reason              TEXT,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX order_status_transitions_order_id_idx ON order_status_transitions(order_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS order_status_transitions_order_id_idx;
+DROP TABLE IF EXISTS order_status_transitions;
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TYPE STOCK_MOVEMENT_TYPE AS ENUM ('sale', 'return', 'adjustment', 'damage', 'received');
+
+CREATE TABLE stock_movements (
+    id                  BIGSERIAL               PRIMARY KEY,
+    product_id          BIGINT                  NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    movement_type       STOCK_MOVEMENT_TYPE     NOT NULL,
+    quantity            INTEGER                 NOT NULL CHECK (quantity <> 0), -- signed change in stock
+    stock_after         INTEGER                 NOT NULL,
+    reason              TEXT                    NOT NULL,
+    actor               VARCHAR(255)            NOT NULL,
+    order_id            BIGINT                  REFERENCES orders(id) ON DELETE SET NULL,
+    created_at          TIMESTAMPTZ             NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ             NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX stock_movements_product_id_idx ON stock_movements(product_id);
+CREATE INDEX stock_movements_order_id_idx ON stock_movements(order_id);
+
+-- Opening balances so that every product's stock is derivable from its ledger
+INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, reason, actor)
+SELECT id, 'adjustment', stock, stock, 'opening balance', 'system'
+FROM products
+WHERE stock IS NOT NULL AND stock <> 0;
+
+UPDATE products SET stock = 0 WHERE stock IS NULL;
+ALTER TABLE products ALTER COLUMN stock SET DEFAULT 0;
+ALTER TABLE products ALTER COLUMN stock SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE products ALTER COLUMN stock DROP NOT NULL;
+ALTER TABLE products ALTER COLUMN stock DROP DEFAULT;
+
+DROP INDEX IF EXISTS stock_movements_order_id_idx;
+DROP INDEX IF EXISTS stock_movements_product_id_idx;
+DROP TABLE IF EXISTS stock_movements;
+DROP TYPE IF EXISTS STOCK_MOVEMENT_TYPE;
@@ -0,0 +1,59 @@
+-- +goose Up
+ALTER TABLE order_items ADD COLUMN returned_quantity INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD CONSTRAINT order_items_returned_quantity_check CHECK (returned_quantity BETWEEN 0 AND quantity);
+
+CREATE TABLE order_returns (
+    id                  BIGSERIAL           PRIMARY KEY,
+    order_id            BIGINT              NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    reason              TEXT                NOT NULL,
+    refund_amount       DECIMAL(10, 2)      NOT NULL,
+    actor               VARCHAR(255)        NOT NULL,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX order_returns_order_id_idx ON order_returns(order_id);
+
+CREATE TABLE order_return_items (
+    id                  BIGSERIAL           PRIMARY KEY,
+    order_return_id     BIGINT              NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    order_item_id       BIGINT              NOT NULL REFERENCES order_items(id),
+    product_id          BIGINT              NOT NULL REFERENCES products(id),
+    quantity            INTEGER             NOT NULL CHECK (quantity > 0),
+    refund_amount       DECIMAL(10, 2)      NOT NULL,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX order_return_items_order_return_id_idx ON order_return_items(order_return_id);
+
+CREATE TYPE REFUND_STATUS AS ENUM ('pending', 'completed');
+
+CREATE TABLE refunds (
+    id                  BIGSERIAL           PRIMARY KEY,
+    order_id            BIGINT              NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_return_id     BIGINT              NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    amount              DECIMAL(10, 2)      NOT NULL,
+    payment_method      PAYMENT_METHOD      NOT NULL,
+    refund_status       REFUND_STATUS       NOT NULL DEFAULT 'pending',
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX refunds_order_id_idx ON refunds(order_id);
+CREATE UNIQUE INDEX refunds_order_return_id_uniq_idx ON refunds(order_return_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS refunds_order_return_id_uniq_idx;
+DROP INDEX IF EXISTS refunds_order_id_idx;
+DROP TABLE IF EXISTS refunds;
+DROP TYPE IF EXISTS REFUND_STATUS;
+
+DROP INDEX IF EXISTS order_return_items_order_return_id_idx;
+DROP TABLE IF EXISTS order_return_items;
+
+DROP INDEX IF EXISTS order_returns_order_id_idx;
+DROP TABLE IF EXISTS order_returns;
+
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_returned_quantity_check;
+ALTER TABLE order_items DROP COLUMN IF EXISTS returned_quantity;
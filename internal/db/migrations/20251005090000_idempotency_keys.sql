@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE idempotency_keys (
+    id                  BIGSERIAL           PRIMARY KEY,
+    idempotency_key     VARCHAR(255)        NOT NULL,
+    request_path        VARCHAR(255)        NOT NULL,
+    request_hash        CHAR(64)            NOT NULL,
+    response_status     INTEGER,
+    response_body       JSONB,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE UNIQUE INDEX idempotency_keys_key_path_uniq_idx ON idempotency_keys(idempotency_key, request_path);
+
+-- +goose Down
+DROP INDEX IF EXISTS idempotency_keys_key_path_uniq_idx;
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- one sequence for every shop and replica, so references never collide whatever the prefix
+CREATE SEQUENCE order_reference_seq AS BIGINT START WITH 1;
+
+-- +goose Down
+DROP SEQUENCE IF EXISTS order_reference_seq;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- prices are held as exact minor units (custom_types.Money), so every amount column keeps two decimal places
+ALTER TABLE products ALTER COLUMN wholesale_price TYPE DECIMAL(10, 2) USING ROUND(wholesale_price, 2);
+ALTER TABLE products ALTER COLUMN retail_price TYPE DECIMAL(10, 2) USING ROUND(retail_price, 2);
+
+UPDATE orders SET discount = 0.00 WHERE discount IS NULL;
+ALTER TABLE orders ALTER COLUMN discount SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE orders ALTER COLUMN discount DROP NOT NULL;
+
+ALTER TABLE products ALTER COLUMN retail_price TYPE DECIMAL(10, 4);
+ALTER TABLE products ALTER COLUMN wholesale_price TYPE DECIMAL(10, 4);
@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE orders ADD COLUMN subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+ALTER TABLE orders ADD COLUMN line_discount DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+ALTER TABLE orders ADD COLUMN tax_total DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+
+ALTER TABLE order_items ADD COLUMN discount DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+ALTER TABLE order_items ADD COLUMN order_discount DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+ALTER TABLE order_items ADD COLUMN tax_amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+
+-- total_amount used to hold the undiscounted subtotal, and discount held the net amount
+-- (subtotal minus the discount the client sent). Move old rows onto the new meaning:
+-- discount is the discount given and total_amount is what the customer pays.
+UPDATE orders SET subtotal = total_amount;
+UPDATE orders SET discount = subtotal - discount WHERE discount > 0;
+UPDATE orders SET total_amount = subtotal - discount;
+
+-- spread old order discounts over their lines; rounding can leave a cent unallocated on old rows
+UPDATE order_items oi SET order_discount = ROUND(o.discount * oi.total_amount / o.subtotal, 2)
+    FROM orders o
+    WHERE o.id = oi.order_id AND o.discount > 0 AND o.subtotal > 0;
+UPDATE order_items SET total_amount = total_amount - order_discount;
+
+-- +goose Down
+UPDATE order_items SET total_amount = total_amount + order_discount;
+UPDATE orders SET discount = subtotal - discount WHERE discount > 0;
+UPDATE orders SET total_amount = subtotal;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS order_discount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS line_discount;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal;
@@ -0,0 +1,57 @@
+-- +goose Up
+CREATE TYPE TAX_TREATMENT AS ENUM ('standard', 'zero_rated', 'exempt');
+
+-- rate_basis_points is the VAT rate in hundredths of a percent, so 16% is 1600
+CREATE TABLE tax_classes (
+    id                  BIGSERIAL           PRIMARY KEY,
+    code                VARCHAR(20)         NOT NULL UNIQUE,
+    name                VARCHAR(255)        NOT NULL,
+    treatment           TAX_TREATMENT       NOT NULL,
+    rate_basis_points   INTEGER             NOT NULL DEFAULT 0,
+    is_default          BOOLEAN             NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    CONSTRAINT tax_classes_rate_check CHECK (
+        (treatment = 'standard' AND rate_basis_points > 0) OR
+        (treatment <> 'standard' AND rate_basis_points = 0)
+    )
+);
+
+-- at most one class applies to products that neither they nor their categories classify
+CREATE UNIQUE INDEX tax_classes_default_idx ON tax_classes(is_default) WHERE is_default;
+
+INSERT INTO tax_classes (code, name, treatment, rate_basis_points, is_default) VALUES
+    ('VAT16', 'VAT 16%', 'standard', 1600, TRUE),
+    ('ZERO', 'Zero rated', 'zero_rated', 0, FALSE),
+    ('EXEMPT', 'Exempt', 'exempt', 0, FALSE);
+
+ALTER TABLE categories ADD COLUMN tax_class_id BIGINT REFERENCES tax_classes(id);
+
+ALTER TABLE products ADD COLUMN tax_class_id BIGINT REFERENCES tax_classes(id);
+-- shelf prices in Kenya are quoted with VAT in them
+ALTER TABLE products ADD COLUMN price_includes_tax BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- each line keeps the class and rate it was taxed at, so later changes to a class leave sold orders alone
+ALTER TABLE order_items ADD COLUMN tax_class_id BIGINT REFERENCES tax_classes(id);
+ALTER TABLE order_items ADD COLUMN tax_rate INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN price_includes_tax BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE INDEX order_items_tax_class_id_idx ON order_items(tax_class_id);
+
+ALTER TABLE order_return_items ADD COLUMN tax_amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00;
+
+-- +goose Down
+ALTER TABLE order_return_items DROP COLUMN IF EXISTS tax_amount;
+
+DROP INDEX IF EXISTS order_items_tax_class_id_idx;
+ALTER TABLE order_items DROP COLUMN IF EXISTS price_includes_tax;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_class_id;
+
+ALTER TABLE products DROP COLUMN IF EXISTS price_includes_tax;
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class_id;
+
+ALTER TABLE categories DROP COLUMN IF EXISTS tax_class_id;
+
+DROP TABLE IF EXISTS tax_classes;
+DROP TYPE IF EXISTS TAX_TREATMENT;
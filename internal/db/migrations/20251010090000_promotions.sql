@@ -0,0 +1,63 @@
+-- +goose Up
+CREATE TYPE PROMOTION_TYPE AS ENUM ('percentage', 'fixed', 'buy_x_get_y');
+
+-- a promotion with neither product_id nor category_id covers every product in the shop;
+-- category_id covers the category and everything below it
+CREATE TABLE promotions (
+    id                  BIGSERIAL           PRIMARY KEY,
+    shop_id             VARCHAR(255)        NOT NULL,
+    name                VARCHAR(255)        NOT NULL,
+    promotion_type      PROMOTION_TYPE      NOT NULL,
+    percent_off         INTEGER             NOT NULL DEFAULT 0,
+    amount_off          DECIMAL(10, 2)      NOT NULL DEFAULT 0.00,
+    buy_quantity        INTEGER             NOT NULL DEFAULT 0,
+    get_quantity        INTEGER             NOT NULL DEFAULT 0,
+    product_id          BIGINT              REFERENCES products(id) ON DELETE CASCADE,
+    category_id         BIGINT              REFERENCES categories(id) ON DELETE CASCADE,
+    coupon_code         VARCHAR(50),
+    usage_limit         INTEGER,
+    usage_count         INTEGER             NOT NULL DEFAULT 0,
+    starts_at           TIMESTAMPTZ,
+    ends_at             TIMESTAMPTZ,
+    is_active           BOOLEAN             NOT NULL DEFAULT TRUE,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    CONSTRAINT promotions_percent_off_check CHECK (percent_off BETWEEN 0 AND 10000),
+    CONSTRAINT promotions_amount_off_check CHECK (amount_off >= 0),
+    CONSTRAINT promotions_buy_get_check CHECK (buy_quantity >= 0 AND get_quantity >= 0),
+    CONSTRAINT promotions_usage_check CHECK (usage_limit IS NULL OR usage_count <= usage_limit),
+    CONSTRAINT promotions_window_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+CREATE INDEX promotions_shop_id_idx ON promotions(shop_id);
+CREATE UNIQUE INDEX promotions_shop_coupon_code_idx ON promotions(shop_id, UPPER(coupon_code)) WHERE coupon_code IS NOT NULL;
+
+-- one row per promotion used on an order; usage limits count these
+CREATE TABLE promotion_redemptions (
+    id                  BIGSERIAL           PRIMARY KEY,
+    promotion_id        BIGINT              NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    order_id            BIGINT              NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    coupon_code         VARCHAR(50),
+    discount            DECIMAL(10, 2)      NOT NULL,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    UNIQUE (promotion_id, order_id)
+);
+
+-- the promotion discount each order line got; it is also part of order_items.discount
+CREATE TABLE order_item_promotions (
+    id                  BIGSERIAL           PRIMARY KEY,
+    order_item_id       BIGINT              NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    promotion_id        BIGINT              NOT NULL REFERENCES promotions(id),
+    discount            DECIMAL(10, 2)      NOT NULL,
+    created_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX order_item_promotions_order_item_id_idx ON order_item_promotions(order_item_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_item_promotions;
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotions;
+DROP TYPE IF EXISTS PROMOTION_TYPE;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TYPE MPESA_REQUEST_STATUS AS ENUM ('pending', 'completed', 'failed', 'cancelled');
+
+-- one row per STK Push sent to a customer's phone; Daraja's callback is matched back by checkout_request_id
+CREATE TABLE mpesa_stk_requests (
+    id                      BIGSERIAL               PRIMARY KEY,
+    order_id                BIGINT                  NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    merchant_request_id     VARCHAR(100)            NOT NULL,
+    checkout_request_id     VARCHAR(100)            NOT NULL UNIQUE,
+    phone_number            VARCHAR(20)             NOT NULL,
+    amount                  DECIMAL(10, 2)          NOT NULL,
+    request_status          MPESA_REQUEST_STATUS    NOT NULL DEFAULT 'pending',
+    result_code             INTEGER,
+    result_desc             TEXT,
+    mpesa_receipt_number    VARCHAR(50),
+    created_at              TIMESTAMPTZ             NOT NULL DEFAULT clock_timestamp(),
+    updated_at              TIMESTAMPTZ             NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX mpesa_stk_requests_order_id_idx ON mpesa_stk_requests(order_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS mpesa_stk_requests;
+DROP TYPE IF EXISTS MPESA_REQUEST_STATUS;
@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TYPE PAYMENT_STATUS AS ENUM ('pending', 'completed', 'failed');
+
+-- every tender taken against an order; an order may be settled by several (part cash, part M-Pesa)
+CREATE TABLE payments (
+    id                      BIGSERIAL           PRIMARY KEY,
+    order_id                BIGINT              NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    -- amount is what counts towards the order; cash tendered beyond it is handed back as change
+    amount                  DECIMAL(10, 2)      NOT NULL CHECK (amount > 0),
+    amount_tendered         DECIMAL(10, 2)      NOT NULL,
+    change_due              DECIMAL(10, 2)      NOT NULL DEFAULT 0.00 CHECK (change_due >= 0),
+    payment_method          PAYMENT_METHOD      NOT NULL,
+    provider_reference      VARCHAR(100),
+    payment_status          PAYMENT_STATUS      NOT NULL DEFAULT 'pending',
+    recorded_by             VARCHAR(255)        NOT NULL,
+    created_at              TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    updated_at              TIMESTAMPTZ         NOT NULL DEFAULT clock_timestamp(),
+    CHECK (amount_tendered = amount + change_due)
+);
+
+CREATE INDEX payments_order_id_idx ON payments(order_id);
+
+-- an M-Pesa receipt or card approval code can only settle one tender
+CREATE UNIQUE INDEX payments_provider_reference_idx ON payments(payment_method, provider_reference) WHERE provider_reference IS NOT NULL;
+
+-- orders settled before payments were recorded were paid in full by their single payment method
+INSERT INTO payments (order_id, amount, amount_tendered, payment_method, payment_status, recorded_by, created_at, updated_at)
+SELECT id, total_amount, total_amount, payment_method, 'completed', 'migration', updated_at, updated_at
+FROM orders
+WHERE order_status IN ('paid', 'returned') AND total_amount > 0;
+
+-- the payment an STK Push prompts for; completed or failed by Daraja's callback
+ALTER TABLE mpesa_stk_requests ADD COLUMN payment_id BIGINT REFERENCES payments(id);
+
+-- +goose Down
+ALTER TABLE mpesa_stk_requests DROP COLUMN IF EXISTS payment_id;
+
+DROP TABLE IF EXISTS payments;
+DROP TYPE IF EXISTS PAYMENT_STATUS;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- one row per login; access tokens name their session in the "sid" claim, so revoking
+-- the session (logout, or a refresh token used twice) cuts off every token it issued
+CREATE TABLE auth_sessions (
+    id                  BIGSERIAL       PRIMARY KEY,
+    customer_id         BIGINT          REFERENCES customers(id) ON DELETE CASCADE,
+    subject             VARCHAR(255)    NOT NULL,
+    email               VARCHAR(255)    NOT NULL,
+    name                VARCHAR(255)    NOT NULL DEFAULT '',
+    revoked_at          TIMESTAMPTZ,
+    revoked_reason      VARCHAR(100),
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+-- the rotating refresh tokens of a session (its token family); only a SHA-256 of each token is kept
+CREATE TABLE refresh_tokens (
+    id                  BIGSERIAL       PRIMARY KEY,
+    session_id          BIGINT          NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+    token_hash          CHAR(64)        NOT NULL UNIQUE,
+    expires_at          TIMESTAMPTZ     NOT NULL,
+    used_at             TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens(session_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS auth_sessions;
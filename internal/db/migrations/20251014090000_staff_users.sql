@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TYPE STAFF_ROLE AS ENUM ('owner', 'manager', 'cashier', 'viewer');
+
+-- people who log in to work a till or run a shop; unlike customers they are never sold to.
+-- subject is the identity provider's "sub" and is filled in on first login, so staff can
+-- be added by email before they have ever signed in
+CREATE TABLE users (
+    id                  BIGSERIAL       PRIMARY KEY,
+    subject             VARCHAR(255)    UNIQUE,
+    email               VARCHAR(255)    NOT NULL,
+    name                VARCHAR(255)    NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE UNIQUE INDEX users_email_idx ON users(LOWER(email));
+
+-- the role a user holds in a shop; a user may work in several shops with different roles
+CREATE TABLE shop_memberships (
+    id                  BIGSERIAL       PRIMARY KEY,
+    user_id             BIGINT          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    shop_id             VARCHAR(255)    NOT NULL,
+    role                STAFF_ROLE      NOT NULL,
+    created_by          VARCHAR(255)    NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    UNIQUE (user_id, shop_id)
+);
+
+CREATE INDEX shop_memberships_shop_id_idx ON shop_memberships(shop_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS shop_memberships;
+DROP TABLE IF EXISTS users;
+DROP TYPE IF EXISTS STAFF_ROLE;
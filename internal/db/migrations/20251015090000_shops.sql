@@ -0,0 +1,59 @@
+-- +goose Up
+-- until now a shop was only a string on the rows that belong to it; shop ids already in
+-- use become shops, named after their id until an owner renames them
+CREATE TABLE shops (
+    id                  VARCHAR(255)    PRIMARY KEY,
+    name                VARCHAR(255)    NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+UPDATE categories SET shop_id = NULL WHERE TRIM(shop_id) = '';
+UPDATE customers SET shop_id = NULL WHERE TRIM(shop_id) = '';
+UPDATE orders SET shop_id = NULL WHERE TRIM(shop_id) = '';
+
+INSERT INTO shops (id, name)
+SELECT shop_id, shop_id FROM (
+    SELECT shop_id FROM categories
+    UNION SELECT shop_id FROM customers
+    UNION SELECT shop_id FROM orders
+    UNION SELECT shop_id FROM promotions
+    UNION SELECT shop_id FROM shop_memberships
+) shop_ids
+WHERE shop_id IS NOT NULL;
+
+ALTER TABLE categories ADD CONSTRAINT categories_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES shops(id);
+ALTER TABLE customers ADD CONSTRAINT customers_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES shops(id);
+ALTER TABLE orders ADD CONSTRAINT orders_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES shops(id);
+ALTER TABLE promotions ADD CONSTRAINT promotions_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES shops(id);
+ALTER TABLE shop_memberships ADD CONSTRAINT shop_memberships_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES shops(id) ON DELETE CASCADE;
+
+CREATE INDEX categories_shop_id_idx ON categories(shop_id);
+CREATE INDEX orders_shop_id_idx ON orders(shop_id);
+
+-- two shops may well share a customer; email and phone number are unique within a shop
+DROP INDEX IF EXISTS customers_name_idx;
+DROP INDEX IF EXISTS customers_email_idx;
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_phone_number_key;
+
+CREATE UNIQUE INDEX customers_shop_email_idx ON customers(shop_id, email);
+CREATE UNIQUE INDEX customers_shop_phone_number_idx ON customers(shop_id, phone_number);
+
+-- +goose Down
+DROP INDEX IF EXISTS customers_shop_phone_number_idx;
+DROP INDEX IF EXISTS customers_shop_email_idx;
+
+ALTER TABLE customers ADD CONSTRAINT customers_phone_number_key UNIQUE (phone_number);
+CREATE UNIQUE INDEX customers_email_idx ON customers(email);
+CREATE UNIQUE INDEX customers_name_idx ON customers(name);
+
+DROP INDEX IF EXISTS orders_shop_id_idx;
+DROP INDEX IF EXISTS categories_shop_id_idx;
+
+ALTER TABLE shop_memberships DROP CONSTRAINT IF EXISTS shop_memberships_shop_id_fkey;
+ALTER TABLE promotions DROP CONSTRAINT IF EXISTS promotions_shop_id_fkey;
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_shop_id_fkey;
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_shop_id_fkey;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_shop_id_fkey;
+
+DROP TABLE IF EXISTS shops;
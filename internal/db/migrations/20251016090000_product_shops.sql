@@ -0,0 +1,15 @@
+-- +goose Up
+-- a product belonged to a shop only through its category; it now says so itself, and the
+-- shop listing no longer has to join categories to find its own products
+ALTER TABLE products ADD COLUMN shop_id VARCHAR(255) REFERENCES shops(id);
+
+UPDATE products p SET shop_id = c.shop_id
+FROM categories c
+WHERE c.id = p.category_id;
+
+CREATE INDEX products_shop_id_idx ON products(shop_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS products_shop_id_idx;
+
+ALTER TABLE products DROP COLUMN IF EXISTS shop_id;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- keys for tills and scripts that cannot do the browser login; each works for one shop
+-- with the comma separated permissions in scopes, and only a SHA-256 of the key is kept
+CREATE TABLE api_keys (
+    id                  BIGSERIAL       PRIMARY KEY,
+    shop_id             VARCHAR(255)    NOT NULL REFERENCES shops(id),
+    name                VARCHAR(255)    NOT NULL,
+    prefix              VARCHAR(20)     NOT NULL,
+    key_hash            CHAR(64)        NOT NULL UNIQUE,
+    scopes              TEXT            NOT NULL DEFAULT '',
+    expires_at          TIMESTAMPTZ,
+    last_used_at        TIMESTAMPTZ,
+    created_by          VARCHAR(255)    NOT NULL,
+    revoked_at          TIMESTAMPTZ,
+    revoked_by          VARCHAR(255),
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX api_keys_shop_id_idx ON api_keys(shop_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- notifications are written here in the transaction that creates the order (or return)
+-- and sent by a worker afterwards, so a crash or an SMS outage delays them instead of
+-- losing them; rows that run out of attempts stay as 'dead' until someone replays them
+CREATE TABLE notification_outbox (
+    id                  BIGSERIAL       PRIMARY KEY,
+    shop_id             VARCHAR(255)    NOT NULL REFERENCES shops(id),
+    order_id            BIGINT          NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    event               VARCHAR(50)     NOT NULL,
+    channel             VARCHAR(20)     NOT NULL,
+    recipient           VARCHAR(255),
+    payload             JSONB           NOT NULL,
+    status              VARCHAR(20)     NOT NULL DEFAULT 'pending',
+    attempts            INT             NOT NULL DEFAULT 0,
+    next_attempt_at     TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    last_error          TEXT,
+    sent_at             TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+-- the worker only ever looks for pending rows that are due
+CREATE INDEX notification_outbox_due_idx ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX notification_outbox_shop_id_idx ON notification_outbox(shop_id, status);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_outbox;
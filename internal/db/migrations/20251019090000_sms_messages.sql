@@ -0,0 +1,23 @@
+-- +goose Up
+-- every SMS the provider accepted, keyed by the provider's message id so delivery
+-- reports can update its status; status stays 'sent' until a report says otherwise
+CREATE TABLE sms_messages (
+    id                  BIGSERIAL       PRIMARY KEY,
+    shop_id             VARCHAR(255)    NOT NULL REFERENCES shops(id),
+    order_id            BIGINT          NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    customer_id         BIGINT,
+    notification_id     BIGINT          REFERENCES notification_outbox(id) ON DELETE SET NULL,
+    phone_number        VARCHAR(50)     NOT NULL,
+    message_id          VARCHAR(255)    NOT NULL UNIQUE,
+    cost                VARCHAR(50)     NOT NULL DEFAULT '',
+    status              VARCHAR(20)     NOT NULL DEFAULT 'sent',
+    failure_reason      VARCHAR(255),
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX sms_messages_order_id_idx ON sms_messages(shop_id, order_id);
+CREATE INDEX sms_messages_customer_id_idx ON sms_messages(shop_id, customer_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS sms_messages;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- messages can now go out through more than one provider; everything sent so far went
+-- through Africa's Talking
+ALTER TABLE sms_messages ADD COLUMN provider VARCHAR(50) NOT NULL DEFAULT 'africastalking';
+
+-- +goose Down
+ALTER TABLE sms_messages DROP COLUMN IF EXISTS provider;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- amounts in notifications are shown in the shop's currency, and customers get them in
+-- their own language
+ALTER TABLE shops ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'KES';
+ALTER TABLE customers ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'en';
+
+-- a shop's own wording for a notification; without one the built-in template is used
+CREATE TABLE notification_templates (
+    id                  BIGSERIAL       PRIMARY KEY,
+    shop_id             VARCHAR(255)    NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    event               VARCHAR(50)     NOT NULL,
+    channel             VARCHAR(20)     NOT NULL,
+    locale              VARCHAR(10)     NOT NULL,
+    subject             TEXT,
+    body                TEXT            NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    UNIQUE (shop_id, event, channel, locale)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_templates;
+ALTER TABLE customers DROP COLUMN IF EXISTS locale;
+ALTER TABLE shops DROP COLUMN IF EXISTS currency;
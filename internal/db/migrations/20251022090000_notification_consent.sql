@@ -0,0 +1,36 @@
+-- +goose Up
+-- how each customer agreed to hear about their orders; 'sms' is what everyone got before
+ALTER TABLE customers ADD COLUMN notification_preference VARCHAR(10) NOT NULL DEFAULT 'sms';
+
+-- numbers that texted STOP. Inbound messages do not say which shop they were meant for,
+-- so an opt-out covers every shop, and walk-in orders that only carry the number.
+CREATE TABLE sms_opt_outs (
+    phone_number        VARCHAR(20)     PRIMARY KEY,
+    keyword             VARCHAR(20)     NOT NULL,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+-- every change of consent, kept for the Data Protection Act. Changes texted in by a
+-- number belong to no shop or customer; staff changes belong to both.
+CREATE TABLE consent_changes (
+    id                  BIGSERIAL       PRIMARY KEY,
+    shop_id             VARCHAR(255)    REFERENCES shops(id) ON DELETE CASCADE,
+    customer_id         BIGINT          REFERENCES customers(id) ON DELETE SET NULL,
+    phone_number        VARCHAR(20),
+    from_preference     VARCHAR(10),
+    to_preference       VARCHAR(10)     NOT NULL,
+    source              VARCHAR(20)     NOT NULL,
+    changed_by          VARCHAR(255)    NOT NULL,
+    reason              TEXT,
+    created_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp(),
+    updated_at          TIMESTAMPTZ     NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX consent_changes_customer_id_idx ON consent_changes(customer_id);
+CREATE INDEX consent_changes_phone_number_idx ON consent_changes(phone_number);
+
+-- +goose Down
+DROP TABLE IF EXISTS consent_changes;
+DROP TABLE IF EXISTS sms_opt_outs;
+ALTER TABLE customers DROP COLUMN IF EXISTS notification_preference;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- an idempotency key is the caller's own, so two shops may pick the same one; without the
+-- shop a key reused elsewhere replayed the first shop's response. Stored responses are
+-- only kept for retries, so those from before are dropped rather than guessed at
+DELETE FROM idempotency_keys;
+
+DROP INDEX IF EXISTS idempotency_keys_key_path_uniq_idx;
+
+ALTER TABLE idempotency_keys ADD COLUMN shop_id VARCHAR(255) NOT NULL REFERENCES shops(id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX idempotency_keys_shop_key_path_uniq_idx ON idempotency_keys(shop_id, idempotency_key, request_path);
+
+-- +goose Down
+DELETE FROM idempotency_keys;
+
+DROP INDEX IF EXISTS idempotency_keys_shop_key_path_uniq_idx;
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS shop_id;
+
+CREATE UNIQUE INDEX idempotency_keys_key_path_uniq_idx ON idempotency_keys(idempotency_key, request_path);
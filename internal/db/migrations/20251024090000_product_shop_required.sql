@@ -0,0 +1,21 @@
+-- +goose Up
+-- every product is listed and sold through its shop, so one without a shop cannot be seen
+-- by anyone. Products the category backfill could not place have to be given a shop by
+-- hand before this runs; the migration stops and says how many there are
+-- +goose StatementBegin
+DO $$
+DECLARE
+    unplaced BIGINT;
+BEGIN
+    SELECT COUNT(id) INTO unplaced FROM products WHERE shop_id IS NULL;
+    IF unplaced > 0 THEN
+        RAISE EXCEPTION '% product(s) have no shop_id; set it from their category or delete them, then migrate again', unplaced;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE products ALTER COLUMN shop_id SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE products ALTER COLUMN shop_id DROP NOT NULL;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- a shop's order references start with its own prefix, e.g. NBO-000A7K-4; shops left
+-- blank use ORDER_REFERENCE_PREFIX
+ALTER TABLE shops ADD COLUMN order_reference_prefix VARCHAR(4) NOT NULL DEFAULT ''
+    CHECK (order_reference_prefix ~ '^[A-Z0-9]{0,4}$');
+
+-- +goose Down
+ALTER TABLE shops DROP COLUMN IF EXISTS order_reference_prefix;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- a return paid for by split tender is refunded to each payment it came from, so a
+-- return can have several refunds; refunds recorded before payments have no payment_id
+ALTER TABLE refunds ADD COLUMN payment_id BIGINT REFERENCES payments(id);
+
+DROP INDEX IF EXISTS refunds_order_return_id_uniq_idx;
+CREATE INDEX refunds_order_return_id_idx ON refunds(order_return_id);
+CREATE INDEX refunds_payment_id_idx ON refunds(payment_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS refunds_payment_id_idx;
+DROP INDEX IF EXISTS refunds_order_return_id_idx;
+CREATE UNIQUE INDEX refunds_order_return_id_uniq_idx ON refunds(order_return_id);
+
+ALTER TABLE refunds DROP COLUMN IF EXISTS payment_id;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- amounts are stored without a currency and are shillings, so a shop set to another
+-- currency only mislabelled them
+UPDATE shops SET currency = 'KES' WHERE currency <> 'KES';
+
+ALTER TABLE shops ADD CONSTRAINT shops_currency_kes_check CHECK (currency = 'KES');
+
+-- +goose Down
+ALTER TABLE shops DROP CONSTRAINT IF EXISTS shops_currency_kes_check;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- logins are staff, not customers; the column was never filled in
+ALTER TABLE auth_sessions DROP COLUMN IF EXISTS customer_id;
+
+-- +goose Down
+ALTER TABLE auth_sessions ADD COLUMN customer_id BIGINT REFERENCES customers(id) ON DELETE CASCADE;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- the seeded classes stay shared; a class a shop adds is its own, so one shop's owner can
+-- no longer change what every other shop may charge VAT under. Classes added before this
+-- cannot be traced to a shop and stay shared
+ALTER TABLE tax_classes ADD COLUMN shop_id VARCHAR(255) REFERENCES shops(id) ON DELETE CASCADE;
+
+ALTER TABLE tax_classes DROP CONSTRAINT IF EXISTS tax_classes_code_key;
+
+CREATE UNIQUE INDEX tax_classes_shop_code_uniq_idx ON tax_classes(COALESCE(shop_id, ''), code);
+CREATE INDEX tax_classes_shop_id_idx ON tax_classes(shop_id);
+
+-- +goose Down
+-- shops' classes become shared again; codes two shops both used must be renamed first
+DROP INDEX IF EXISTS tax_classes_shop_id_idx;
+DROP INDEX IF EXISTS tax_classes_shop_code_uniq_idx;
+
+ALTER TABLE tax_classes DROP COLUMN IF EXISTS shop_id;
+
+ALTER TABLE tax_classes ADD CONSTRAINT tax_classes_code_key UNIQUE (code);
@@ -0,0 +1,213 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"time"
+)
+
+const (
+	createAPIKeySQL        = "INSERT INTO api_keys (shop_id, name, prefix, key_hash, scopes, expires_at, created_by, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING(id)"
+	updateAPIKeySQL        = "UPDATE api_keys SET revoked_at = $1, revoked_by = $2, updated_at = $3 WHERE id = $4 AND shop_id = $5"
+	getAPIKeysSQL          = "SELECT id, shop_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_by, revoked_at, revoked_by, created_at, updated_at FROM api_keys"
+	getAPIKeyByHashSQL     = getAPIKeysSQL + " WHERE key_hash = $1"
+	getAPIKeyByIDSQL       = getAPIKeysSQL + " WHERE id = $1 AND shop_id = $2"
+	getAPIKeysByShopIDSQL  = getAPIKeysSQL + " WHERE shop_id = $1 ORDER BY id DESC"
+	touchAPIKeyLastUsedSQL = "UPDATE api_keys SET last_used_at = $1 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)"
+)
+
+type (
+	APIKeyDomain interface {
+		CreateAPIKey(ctx context.Context, operations db.SQLOperations, apiKey *models.APIKey) error
+		APIKeyByHash(ctx context.Context, operations db.SQLOperations, keyHash string) (*models.APIKey, error)
+		APIKeyByID(ctx context.Context, operations db.SQLOperations, shopID string, apiKeyID int64) (*models.APIKey, error)
+		APIKeysByShopID(ctx context.Context, operations db.SQLOperations, shopID string) ([]*models.APIKey, error)
+		TouchAPIKeyLastUsed(ctx context.Context, operations db.SQLOperations, apiKeyID int64, usedAt time.Time, interval time.Duration) error
+	}
+
+	apiKeyDomain struct{}
+)
+
+func NewAPIKeyDomain() APIKeyDomain {
+	return &apiKeyDomain{}
+}
+
+// CreateAPIKey saves a new key; after that only its revocation can change.
+func (d *apiKeyDomain) CreateAPIKey(
+	ctx context.Context,
+	operations db.SQLOperations,
+	apiKey *models.APIKey,
+) error {
+
+	apiKey.Touch()
+	if apiKey.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createAPIKeySQL,
+			apiKey.ShopID,
+			apiKey.Name,
+			apiKey.Prefix,
+			apiKey.KeyHash,
+			apiKey.Scopes,
+			apiKey.ExpiresAt,
+			apiKey.CreatedBy,
+			apiKey.CreatedAt,
+			apiKey.UpdatedAt,
+		).Scan(&apiKey.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save api key query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateAPIKeySQL,
+		apiKey.RevokedAt,
+		apiKey.RevokedBy,
+		apiKey.UpdatedAt,
+		apiKey.ID,
+		apiKey.ShopID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update api key exec err: %v", err)
+	}
+
+	return nil
+}
+
+// APIKeyByHash finds the key a request was made with. It is not scoped to a shop: the
+// key is what says which shop the request is for.
+func (d *apiKeyDomain) APIKeyByHash(
+	ctx context.Context,
+	operations db.SQLOperations,
+	keyHash string,
+) (*models.APIKey, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getAPIKeyByHashSQL,
+		keyHash,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *apiKeyDomain) APIKeyByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	apiKeyID int64,
+) (*models.APIKey, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getAPIKeyByIDSQL,
+		apiKeyID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *apiKeyDomain) APIKeysByShopID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) ([]*models.APIKey, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		getAPIKeysByShopIDSQL,
+		shopID,
+	)
+	if err != nil {
+		return []*models.APIKey{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("api keys query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	apiKeys := make([]*models.APIKey, 0)
+
+	for rows.Next() {
+		apiKey, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.APIKey{}, err
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if rows.Err() != nil {
+		return []*models.APIKey{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("api keys rows err: %v", rows.Err())
+	}
+
+	return apiKeys, nil
+}
+
+// TouchAPIKeyLastUsed records when a key was last used. A till calls many times a minute,
+// so the row is only written once the last recorded use is older than interval.
+func (d *apiKeyDomain) TouchAPIKeyLastUsed(
+	ctx context.Context,
+	operations db.SQLOperations,
+	apiKeyID int64,
+	usedAt time.Time,
+	interval time.Duration,
+) error {
+
+	_, err := operations.ExecContext(
+		ctx,
+		touchAPIKeyLastUsedSQL,
+		usedAt,
+		apiKeyID,
+		usedAt.Add(-interval),
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("touch api key exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *apiKeyDomain) scanRow(
+	row db.RowScanner,
+) (*models.APIKey, error) {
+
+	var apiKey models.APIKey
+
+	err := row.Scan(
+		&apiKey.ID,
+		&apiKey.ShopID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		&apiKey.Scopes,
+		&apiKey.ExpiresAt,
+		&apiKey.LastUsedAt,
+		&apiKey.CreatedBy,
+		&apiKey.RevokedAt,
+		&apiKey.RevokedBy,
+		&apiKey.CreatedAt,
+		&apiKey.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan api key row err: %v", err)
+	}
+
+	return &apiKey, nil
+}
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createAuthSessionSQL      = "INSERT INTO auth_sessions (subject, email, name, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING(id)"
+	updateAuthSessionSQL      = "UPDATE auth_sessions SET revoked_at = $1, revoked_reason = $2, updated_at = $3 WHERE id = $4"
+	getAuthSessionsSQL        = "SELECT id, subject, email, name, revoked_at, revoked_reason, created_at, updated_at FROM auth_sessions"
+	getAuthSessionByIDSQL     = getAuthSessionsSQL + " WHERE id = $1"
+	lockAuthSessionByIDSQL    = getAuthSessionByIDSQL + " FOR UPDATE"
+	createRefreshTokenSQL     = "INSERT INTO refresh_tokens (session_id, token_hash, expires_at, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING(id)"
+	updateRefreshTokenSQL     = "UPDATE refresh_tokens SET used_at = $1, updated_at = $2 WHERE id = $3"
+	lockRefreshTokenByHashSQL = "SELECT id, session_id, token_hash, expires_at, used_at, created_at, updated_at FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE"
+)
+
+type (
+	AuthSessionDomain interface {
+		CreateAuthSession(ctx context.Context, operations db.SQLOperations, session *models.AuthSession) error
+		AuthSessionByID(ctx context.Context, operations db.SQLOperations, sessionID int64) (*models.AuthSession, error)
+		LockAuthSessionByID(ctx context.Context, operations db.SQLOperations, sessionID int64) (*models.AuthSession, error)
+		CreateRefreshToken(ctx context.Context, operations db.SQLOperations, refreshToken *models.RefreshToken) error
+		LockRefreshTokenByHash(ctx context.Context, operations db.SQLOperations, tokenHash string) (*models.RefreshToken, error)
+	}
+
+	authSessionDomain struct{}
+)
+
+func NewAuthSessionDomain() AuthSessionDomain {
+	return &authSessionDomain{}
+}
+
+func (d *authSessionDomain) CreateAuthSession(
+	ctx context.Context,
+	operations db.SQLOperations,
+	session *models.AuthSession,
+) error {
+
+	session.Touch()
+	if session.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createAuthSessionSQL,
+			session.Subject,
+			session.Email,
+			session.Name,
+			session.CreatedAt,
+			session.UpdatedAt,
+		).Scan(&session.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save auth session query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateAuthSessionSQL,
+		session.RevokedAt,
+		session.RevokedReason,
+		session.UpdatedAt,
+		session.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update auth session exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *authSessionDomain) AuthSessionByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	sessionID int64,
+) (*models.AuthSession, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getAuthSessionByIDSQL,
+		sessionID,
+	)
+
+	return d.scanSessionRow(row)
+}
+
+func (d *authSessionDomain) LockAuthSessionByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	sessionID int64,
+) (*models.AuthSession, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockAuthSessionByIDSQL,
+		sessionID,
+	)
+
+	return d.scanSessionRow(row)
+}
+
+func (d *authSessionDomain) CreateRefreshToken(
+	ctx context.Context,
+	operations db.SQLOperations,
+	refreshToken *models.RefreshToken,
+) error {
+
+	refreshToken.Touch()
+	if refreshToken.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createRefreshTokenSQL,
+			refreshToken.SessionID,
+			refreshToken.TokenHash,
+			refreshToken.ExpiresAt,
+			refreshToken.CreatedAt,
+			refreshToken.UpdatedAt,
+		).Scan(&refreshToken.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save refresh token query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateRefreshTokenSQL,
+		refreshToken.UsedAt,
+		refreshToken.UpdatedAt,
+		refreshToken.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update refresh token exec err: %v", err)
+	}
+
+	return nil
+}
+
+// LockRefreshTokenByHash reads a refresh token and holds a row lock on it, so two
+// refreshes racing with the same token cannot both succeed.
+func (d *authSessionDomain) LockRefreshTokenByHash(
+	ctx context.Context,
+	operations db.SQLOperations,
+	tokenHash string,
+) (*models.RefreshToken, error) {
+
+	var refreshToken models.RefreshToken
+
+	err := operations.QueryRowContext(
+		ctx,
+		lockRefreshTokenByHashSQL,
+		tokenHash,
+	).Scan(
+		&refreshToken.ID,
+		&refreshToken.SessionID,
+		&refreshToken.TokenHash,
+		&refreshToken.ExpiresAt,
+		&refreshToken.UsedAt,
+		&refreshToken.CreatedAt,
+		&refreshToken.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan refresh token row err: %v", err)
+	}
+
+	return &refreshToken, nil
+}
+
+func (d *authSessionDomain) scanSessionRow(
+	row db.RowScanner,
+) (*models.AuthSession, error) {
+
+	var session models.AuthSession
+
+	err := row.Scan(
+		&session.ID,
+		&session.Subject,
+		&session.Email,
+		&session.Name,
+		&session.RevokedAt,
+		&session.RevokedReason,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan auth session row err: %v", err)
+	}
+
+	return &session, nil
+}
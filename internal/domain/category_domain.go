@@ -12,21 +12,21 @@ import (
 )
 
 const (
-	createCategorySQL  = "INSERT INTO categories (name, parent_id, shop_id, created_at, updated_at) VALUES($1, $2, $3, $4, $5) RETURNING(id)"
-	getCategoriesSQL   = "SELECT id, name, parent_id, shop_id, created_at, updated_at FROM categories"
-	getCategoryByIDSQL = getCategoriesSQL + " WHERE id = $1"
-	updateCategorySQL  = "UPDATE categories SET name = $1, parent_id = $2, shop_id = $3, updated_at = $4 WHERE id = $5"
-	deleteCategorySQL  = "DELETE FROM categories where id = $1"
+	createCategorySQL  = "INSERT INTO categories (name, parent_id, shop_id, tax_class_id, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING(id)"
+	getCategoriesSQL   = "SELECT id, name, parent_id, shop_id, tax_class_id, created_at, updated_at FROM categories"
+	getCategoryByIDSQL = getCategoriesSQL + " WHERE id = $1 AND shop_id = $2"
+	updateCategorySQL  = "UPDATE categories SET name = $1, parent_id = $2, tax_class_id = $3, updated_at = $4 WHERE id = $5 AND shop_id = $6"
+	deleteCategorySQL  = "DELETE FROM categories where id = $1 AND shop_id = $2"
 	getCategoriesCountSQL = "SELECT COUNT(id) FROM categories"
 )
 
 type (
 	CategoryDomain interface {
 		CreateCategory(ctx context.Context, operations db.SQLOperations, category *models.Category) error
-		CategoryByID(ctx context.Context, operations db.SQLOperations, categoryID int64) (*models.Category, error)
+		CategoryByID(ctx context.Context, operations db.SQLOperations, shopID string, categoryID int64) (*models.Category, error)
 		ListShopCateories(ctx context.Context, opearations db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Category, error)
 		ShopCategoriesCount(ctx context.Context, opearations db.SQLOperations, shopID string, filter *models.Filter) (int, error)
-		DeleteCategory(ctx context.Context, operations db.SQLOperations, categoryID int64) error
+		DeleteCategory(ctx context.Context, operations db.SQLOperations, shopID string, categoryID int64) error
 	}
 
 	categoryDomain struct{}
@@ -50,6 +50,7 @@ func (d *categoryDomain) CreateCategory(
 			category.Name,
 			category.ParentID,
 			category.ShopID,
+			category.TaxClassID,
 			category.CreatedAt,
 			category.UpdatedAt,
 		).Scan(&category.ID)
@@ -66,9 +67,10 @@ func (d *categoryDomain) CreateCategory(
 		updateCategorySQL,
 		category.Name,
 		category.ParentID,
-		category.ShopID,
+		category.TaxClassID,
 		category.UpdatedAt,
 		category.ID,
+		category.ShopID,
 	)
 	if err != nil {
 		return apperr.NewDatabaseError(
@@ -80,6 +82,7 @@ func (d *categoryDomain) CreateCategory(
 func (d *categoryDomain) CategoryByID(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	categoryID int64,
 ) (*models.Category, error) {
 
@@ -87,6 +90,7 @@ func (d *categoryDomain) CategoryByID(
 		ctx,
 		getCategoryByIDSQL,
 		categoryID,
+		shopID,
 	)
 
 	return d.scanRow(row)
@@ -168,6 +172,7 @@ func (d *categoryDomain) ShopCategoriesCount(
 func (d *categoryDomain) DeleteCategory(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	categoryID int64,
 ) error {
 
@@ -175,6 +180,7 @@ func (d *categoryDomain) DeleteCategory(
 		ctx,
 		deleteCategorySQL,
 		categoryID,
+		shopID,
 	)
 	if err != nil {
 		return apperr.NewDatabaseError(
@@ -225,6 +231,7 @@ func (d *categoryDomain) scanRow(
 		&category.Name,
 		&category.ParentID,
 		&category.ShopID,
+		&category.TaxClassID,
 		&category.CreatedAt,
 		&category.UpdatedAt,
 	)
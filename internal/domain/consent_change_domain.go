@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+)
+
+const (
+	createConsentChangeSQL    = "INSERT INTO consent_changes (shop_id, customer_id, phone_number, from_preference, to_preference, source, changed_by, reason, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING(id)"
+	getConsentChangesSQL      = "SELECT id, shop_id, customer_id, phone_number, from_preference, to_preference, source, changed_by, reason, created_at, updated_at FROM consent_changes"
+	getConsentChangesCountSQL = "SELECT COUNT(id) FROM consent_changes"
+)
+
+type (
+	ConsentChangeDomain interface {
+		CreateConsentChange(ctx context.Context, operations db.SQLOperations, consentChange *models.ConsentChange) error
+		ListCustomerConsentChanges(ctx context.Context, operations db.SQLOperations, customer *models.Customer, filter *models.Filter) ([]*models.ConsentChange, error)
+		CustomerConsentChangesCount(ctx context.Context, operations db.SQLOperations, customer *models.Customer) (int, error)
+	}
+
+	consentChangeDomain struct{}
+)
+
+func NewConsentChangeDomain() ConsentChangeDomain {
+	return &consentChangeDomain{}
+}
+
+func (d *consentChangeDomain) CreateConsentChange(
+	ctx context.Context,
+	operations db.SQLOperations,
+	consentChange *models.ConsentChange,
+) error {
+
+	consentChange.Touch()
+
+	err := operations.QueryRowContext(
+		ctx,
+		createConsentChangeSQL,
+		consentChange.ShopID,
+		consentChange.CustomerID,
+		consentChange.PhoneNumber,
+		consentChange.FromPreference,
+		consentChange.ToPreference,
+		consentChange.Source,
+		consentChange.ChangedBy,
+		consentChange.Reason,
+		consentChange.CreatedAt,
+		consentChange.UpdatedAt,
+	).Scan(&consentChange.ID)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save consent change query row err: %v", err)
+	}
+
+	return nil
+}
+
+// ListCustomerConsentChanges lists the changes staff made to the customer's consent and
+// the ones texted in from the customer's phone number, newest first.
+func (d *consentChangeDomain) ListCustomerConsentChanges(
+	ctx context.Context,
+	operations db.SQLOperations,
+	customer *models.Customer,
+	filter *models.Filter,
+) ([]*models.ConsentChange, error) {
+
+	query, args := d.buildQuery(getConsentChangesSQL, customer, filter)
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.ConsentChange{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("consent changes query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	consentChanges := make([]*models.ConsentChange, 0)
+
+	for rows.Next() {
+		consentChange, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.ConsentChange{}, err
+		}
+
+		consentChanges = append(consentChanges, consentChange)
+	}
+
+	if rows.Err() != nil {
+		return []*models.ConsentChange{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list consent changes err: %v", rows.Err())
+	}
+
+	return consentChanges, nil
+}
+
+func (d *consentChangeDomain) CustomerConsentChangesCount(
+	ctx context.Context,
+	operations db.SQLOperations,
+	customer *models.Customer,
+) (int, error) {
+
+	query, args := d.buildQuery(getConsentChangesCountSQL, customer, &models.Filter{})
+
+	row := operations.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	)
+
+	var count int
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("consent changes count query row err: %v", err)
+	}
+
+	return count, nil
+}
+
+func (d *consentChangeDomain) buildQuery(
+	query string,
+	customer *models.Customer,
+	filter *models.Filter,
+) (string, []interface{}) {
+
+	counter := utils.NewPlaceholder()
+
+	phoneNumber, err := utils.FormatPhoneNumber(customer.PhoneNumber)
+	if err != nil {
+		phoneNumber = customer.PhoneNumber
+	}
+
+	query += fmt.Sprintf(
+		" WHERE (shop_id = $%d AND customer_id = $%d) OR (customer_id IS NULL AND phone_number = $%d)",
+		counter.Touch(), counter.Touch(), counter.Touch(),
+	)
+	args := []interface{}{customer.ShopID, customer.ID, phoneNumber}
+
+	if filter.Page > 0 && filter.Per > 0 {
+		query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+
+	return query, args
+}
+
+func (d *consentChangeDomain) scanRow(
+	row db.RowScanner,
+) (*models.ConsentChange, error) {
+
+	var consentChange models.ConsentChange
+
+	err := row.Scan(
+		&consentChange.ID,
+		&consentChange.ShopID,
+		&consentChange.CustomerID,
+		&consentChange.PhoneNumber,
+		&consentChange.FromPreference,
+		&consentChange.ToPreference,
+		&consentChange.Source,
+		&consentChange.ChangedBy,
+		&consentChange.Reason,
+		&consentChange.CreatedAt,
+		&consentChange.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan consent change row err: %v", err)
+	}
+
+	return &consentChange, nil
+}
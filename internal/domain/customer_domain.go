@@ -12,22 +12,22 @@ import (
 )
 
 const (
-	createCustomerSQL                   = "INSERT INTO customers (name, email, phone_number, customer_type, shop_id, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING(id)"
-	getCustomersSQL                     = "SELECT id, name, email, phone_number, customer_type, shop_id, created_at, updated_at FROM customers"
-	getCustomerByIDSQL                  = getCustomersSQL + " WHERE id = $1"
-	getCustomerByEmailSQL               = getCustomersSQL + " WHERE email = $1"
-	getCustomerByEmailAndPhoneNumberSQL = getCustomersSQL + " WHERE email = $1 AND phone_number = $2"
+	createCustomerSQL                   = "INSERT INTO customers (name, email, phone_number, customer_type, shop_id, locale, notification_preference, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING(id)"
+	getCustomersSQL                     = "SELECT id, name, email, phone_number, customer_type, shop_id, locale, notification_preference, created_at, updated_at FROM customers"
+	getCustomerByIDSQL                  = getCustomersSQL + " WHERE id = $1 AND shop_id = $2"
+	getCustomerByEmailSQL               = getCustomersSQL + " WHERE shop_id = $1 AND email = $2"
+	getCustomerByEmailAndPhoneNumberSQL = getCustomersSQL + " WHERE shop_id = $1 AND (email = $2 OR phone_number = $3)"
 	getCustomersCountSQL                = "SELECT COUNT(id) FROM customers"
-	updateCustomeSQL                    = "UPDATE customers SET name = $1, email = $2, phone_number = $3, customer_type = $4, shop_id = $5, updated_at = $6 WHERE id = $7"
-	deleteCustomerSQL                   = "DELETE FROM customers WHERE id = $1"
+	updateCustomeSQL                    = "UPDATE customers SET name = $1, email = $2, phone_number = $3, customer_type = $4, locale = $5, notification_preference = $6, updated_at = $7 WHERE id = $8 AND shop_id = $9"
+	deleteCustomerSQL                   = "DELETE FROM customers WHERE id = $1 AND shop_id = $2"
 )
 
 type (
 	CustomerDomain interface {
 		CreateCustomer(ctx context.Context, operations db.SQLOperations, customer *models.Customer) error
-		CustomerByID(ctx context.Context, operations db.SQLOperations, customerID int64) (*models.Customer, error)
-		CustomerByEmail(ctx context.Context, operations db.SQLOperations, Email string) (*models.Customer, error)
-		CustomerByEmailAndPhoneNumber(ctx context.Context, operations db.SQLOperations, email, phoneNumber string) (*models.Customer, error)
+		CustomerByID(ctx context.Context, operations db.SQLOperations, shopID string, customerID int64) (*models.Customer, error)
+		CustomerByEmail(ctx context.Context, operations db.SQLOperations, shopID string, Email string) (*models.Customer, error)
+		CustomerByEmailAndPhoneNumber(ctx context.Context, operations db.SQLOperations, shopID string, email, phoneNumber string) (*models.Customer, error)
 		ListShopCustomers(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Customer, error)
 		ShopCustomersCount(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) (int, error)
 		DeleteCustomer(ctx context.Context, operations db.SQLOperations, customer *models.Customer) error
@@ -56,6 +56,8 @@ func (d *customerDomain) CreateCustomer(
 			customer.PhoneNumber,
 			customer.CustomerType,
 			customer.ShopID,
+			customer.Locale,
+			customer.NotificationPreference,
 			customer.CreatedAt,
 			customer.UpdatedAt,
 		).Scan(&customer.ID)
@@ -73,9 +75,11 @@ func (d *customerDomain) CreateCustomer(
 		customer.Email,
 		customer.PhoneNumber,
 		customer.CustomerType,
-		customer.ShopID,
+		customer.Locale,
+		customer.NotificationPreference,
 		customer.UpdatedAt,
 		customer.ID,
+		customer.ShopID,
 	)
 	if err != nil {
 		return apperr.NewDatabaseError(
@@ -88,6 +92,7 @@ func (d *customerDomain) CreateCustomer(
 func (d *customerDomain) CustomerByID(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	customerID int64,
 ) (*models.Customer, error) {
 
@@ -95,6 +100,7 @@ func (d *customerDomain) CustomerByID(
 		ctx,
 		getCustomerByIDSQL,
 		customerID,
+		shopID,
 	)
 
 	return d.scanRow(row)
@@ -103,12 +109,14 @@ func (d *customerDomain) CustomerByID(
 func (d *customerDomain) CustomerByEmail(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	Email string,
 ) (*models.Customer, error) {
 
 	row := operations.QueryRowContext(
 		ctx,
 		getCustomerByEmailSQL,
+		shopID,
 		Email,
 	)
 
@@ -118,6 +126,7 @@ func (d *customerDomain) CustomerByEmail(
 func (d *customerDomain) CustomerByEmailAndPhoneNumber(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	email,
 	phoneNumber string,
 ) (*models.Customer, error) {
@@ -125,6 +134,7 @@ func (d *customerDomain) CustomerByEmailAndPhoneNumber(
 	row := operations.QueryRowContext(
 		ctx,
 		getCustomerByEmailAndPhoneNumberSQL,
+		shopID,
 		email,
 		phoneNumber,
 	)
@@ -214,6 +224,7 @@ func (d *customerDomain) DeleteCustomer(
 		ctx,
 		deleteCustomerSQL,
 		customer.ID,
+		customer.ShopID,
 	)
 
 	if err != nil {
@@ -281,6 +292,8 @@ func (d *customerDomain) scanRow(
 		&customer.PhoneNumber,
 		&customer.CustomerType,
 		&customer.ShopID,
+		&customer.Locale,
+		&customer.NotificationPreference,
 		&customer.CreatedAt,
 		&customer.UpdatedAt,
 	)
@@ -57,7 +57,7 @@ func TestCustomerDomain_CreateCustomer(t *testing.T) {
 	// -------- New Customer (INSERT) --------
 	mock.ExpectQuery("INSERT INTO customers").
 		WithArgs(customer.Name, customer.Email, customer.PhoneNumber, customer.CustomerType,
-			customer.ShopID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			customer.ShopID, customer.Locale, customer.NotificationPreference, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	err = customerDomain.CreateCustomer(ctx, dbWrapper{DB: db}, customer)
@@ -67,9 +67,9 @@ func TestCustomerDomain_CreateCustomer(t *testing.T) {
 	// -------- Update Customer (UPDATE) --------
 	customer.Name = "Alice Updated"
 	customer.Touch()
-	mock.ExpectExec("UPDATE customers SET .* WHERE id = \\$7").
+	mock.ExpectExec("UPDATE customers SET .* WHERE id = \\$8 AND shop_id = \\$9").
 		WithArgs(customer.Name, customer.Email, customer.PhoneNumber, customer.CustomerType,
-			customer.ShopID, sqlmock.AnyArg(), customer.ID).
+			customer.Locale, customer.NotificationPreference, sqlmock.AnyArg(), customer.ID, customer.ShopID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = customerDomain.CreateCustomer(ctx, dbWrapper{DB: db}, customer)
@@ -90,7 +90,7 @@ func TestCustomerDomain_CreateCustomer(t *testing.T) {
 
 	// -------- Error on UPDATE --------
 	customer.ID = 99
-	mock.ExpectExec("UPDATE customers SET .* WHERE id = \\$7").
+	mock.ExpectExec("UPDATE customers SET .* WHERE id = \\$8 AND shop_id = \\$9").
 		WillReturnError(fmt.Errorf("update failed"))
 
 	err = customerDomain.CreateCustomer(ctx, dbWrapper{DB: db}, customer)
@@ -106,13 +106,13 @@ func TestCustomerDomain_CustomerByID(t *testing.T) {
 	ctx := context.Background()
 	now := time.Now()
 
-	mock.ExpectQuery("SELECT .* FROM customers WHERE id = \\$1").
-		WithArgs(1).
+	mock.ExpectQuery("SELECT .* FROM customers WHERE id = \\$1 AND shop_id = \\$2").
+		WithArgs(1, "shop123").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "email", "phone_number", "customer_type", "shop_id", "created_at", "updated_at",
-		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", now, now))
+			"id", "name", "email", "phone_number", "customer_type", "shop_id", "locale", "notification_preference", "created_at", "updated_at",
+		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", []byte("en"), []byte("sms"), now, now))
 
-	cust, err := customerDomain.CustomerByID(ctx, dbWrapper{DB: db}, 1)
+	cust, err := customerDomain.CustomerByID(ctx, dbWrapper{DB: db}, "shop123", 1)
 	assert.NoError(t, err)
 	assert.Equal(t, "Alice", cust.Name)
 }
@@ -124,13 +124,13 @@ func TestCustomerDomain_CustomerByEmail(t *testing.T) {
 	ctx := context.Background()
 	now := time.Now()
 
-	mock.ExpectQuery("SELECT .* FROM customers WHERE email = \\$1").
-		WithArgs("alice@example.com").
+	mock.ExpectQuery("SELECT .* FROM customers WHERE shop_id = \\$1 AND email = \\$2").
+		WithArgs("shop123", "alice@example.com").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "email", "phone_number", "customer_type", "shop_id", "created_at", "updated_at",
-		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", now, now))
+			"id", "name", "email", "phone_number", "customer_type", "shop_id", "locale", "notification_preference", "created_at", "updated_at",
+		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", []byte("en"), []byte("sms"), now, now))
 
-	cust, err := customerDomain.CustomerByEmail(ctx, dbWrapper{DB: db}, "alice@example.com")
+	cust, err := customerDomain.CustomerByEmail(ctx, dbWrapper{DB: db}, "shop123", "alice@example.com")
 	assert.NoError(t, err)
 	assert.Equal(t, "Alice", cust.Name)
 }
@@ -142,13 +142,13 @@ func TestCustomerDomain_CustomerByEmailAndPhoneNumber(t *testing.T) {
 	ctx := context.Background()
 	now := time.Now()
 
-	mock.ExpectQuery("SELECT .* FROM customers WHERE email = \\$1 AND phone_number = \\$2").
-		WithArgs("alice@example.com", "+254700000000").
+	mock.ExpectQuery("SELECT .* FROM customers WHERE shop_id = \\$1 AND \\(email = \\$2 OR phone_number = \\$3\\)").
+		WithArgs("shop123", "alice@example.com", "+254700000000").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "email", "phone_number", "customer_type", "shop_id", "created_at", "updated_at",
-		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", now, now))
+			"id", "name", "email", "phone_number", "customer_type", "shop_id", "locale", "notification_preference", "created_at", "updated_at",
+		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", []byte("en"), []byte("sms"), now, now))
 
-	cust, err := customerDomain.CustomerByEmailAndPhoneNumber(ctx, dbWrapper{DB: db}, "alice@example.com", "+254700000000")
+	cust, err := customerDomain.CustomerByEmailAndPhoneNumber(ctx, dbWrapper{DB: db}, "shop123", "alice@example.com", "+254700000000")
 	assert.NoError(t, err)
 	assert.Equal(t, "Alice", cust.Name)
 }
@@ -162,8 +162,8 @@ func TestCustomerDomain_ListShopCustomers(t *testing.T) {
 
 	mock.ExpectQuery("SELECT .* FROM customers").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "email", "phone_number", "customer_type", "shop_id", "created_at", "updated_at",
-		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", now, now))
+			"id", "name", "email", "phone_number", "customer_type", "shop_id", "locale", "notification_preference", "created_at", "updated_at",
+		}).AddRow(1, "Alice", "alice@example.com", "+254700000000", []byte("individual"), "shop123", []byte("en"), []byte("sms"), now, now))
 
 	customers, err := customerDomain.ListShopCustomers(ctx, dbWrapper{DB: db}, "shop123", &models.Filter{})
 	assert.NoError(t, err)
@@ -191,9 +191,9 @@ func TestCustomerDomain_DeleteCustomer(t *testing.T) {
 	customerDomain := NewCustomerDomain()
 	ctx := context.Background()
 
-	cust := &models.Customer{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}}
-	mock.ExpectExec("DELETE FROM customers WHERE id = \\$1").
-		WithArgs(cust.ID).
+	cust := &models.Customer{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, ShopID: "shop123"}
+	mock.ExpectExec("DELETE FROM customers WHERE id = \\$1 AND shop_id = \\$2").
+		WithArgs(cust.ID, cust.ShopID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	err := customerDomain.DeleteCustomer(ctx, dbWrapper{DB: db}, cust)
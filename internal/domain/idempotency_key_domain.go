@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createIdempotencyKeySQL   = "INSERT INTO idempotency_keys (shop_id, idempotency_key, request_path, request_hash, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (shop_id, idempotency_key, request_path) DO NOTHING RETURNING(id)"
+	getIdempotencyKeySQL      = "SELECT id, shop_id, idempotency_key, request_path, request_hash, response_status, response_body, created_at, updated_at FROM idempotency_keys WHERE shop_id = $1 AND idempotency_key = $2 AND request_path = $3"
+	completeIdempotencyKeySQL = "UPDATE idempotency_keys SET response_status = $1, response_body = $2, updated_at = $3 WHERE id = $4"
+	deleteIdempotencyKeySQL   = "DELETE FROM idempotency_keys WHERE id = $1"
+	takeOverIdempotencyKeySQL = "UPDATE idempotency_keys SET created_at = $1, updated_at = $1 WHERE id = $2 AND response_status IS NULL AND created_at < $3 RETURNING(id)"
+)
+
+type (
+	IdempotencyKeyDomain interface {
+		CreateIdempotencyKey(ctx context.Context, operations db.SQLOperations, idempotencyKey *models.IdempotencyKey) (bool, error)
+		IdempotencyKeyByKey(ctx context.Context, operations db.SQLOperations, shopID, key, requestPath string) (*models.IdempotencyKey, error)
+		CompleteIdempotencyKey(ctx context.Context, operations db.SQLOperations, idempotencyKey *models.IdempotencyKey) error
+		DeleteIdempotencyKey(ctx context.Context, operations db.SQLOperations, idempotencyKeyID int64) error
+		TakeOverIdempotencyKey(ctx context.Context, operations db.SQLOperations, idempotencyKey *models.IdempotencyKey, staleBefore time.Time) (bool, error)
+	}
+
+	idempotencyKeyDomain struct{}
+)
+
+func NewIdempotencyKeyDomain() IdempotencyKeyDomain {
+	return &idempotencyKeyDomain{}
+}
+
+// CreateIdempotencyKey claims a key for a request path in a shop. It returns false,
+// without an error, when the shop has already claimed the key.
+func (d *idempotencyKeyDomain) CreateIdempotencyKey(
+	ctx context.Context,
+	operations db.SQLOperations,
+	idempotencyKey *models.IdempotencyKey,
+) (bool, error) {
+
+	idempotencyKey.Touch()
+
+	err := operations.QueryRowContext(
+		ctx,
+		createIdempotencyKeySQL,
+		idempotencyKey.ShopID,
+		idempotencyKey.Key,
+		idempotencyKey.RequestPath,
+		idempotencyKey.RequestHash,
+		idempotencyKey.CreatedAt,
+		idempotencyKey.UpdatedAt,
+	).Scan(&idempotencyKey.ID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return false, nil
+		}
+
+		return false, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save idempotency key query row err: %v", err)
+	}
+
+	return true, nil
+}
+
+func (d *idempotencyKeyDomain) IdempotencyKeyByKey(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	key string,
+	requestPath string,
+) (*models.IdempotencyKey, error) {
+
+	var idempotencyKey models.IdempotencyKey
+
+	err := operations.QueryRowContext(
+		ctx,
+		getIdempotencyKeySQL,
+		shopID,
+		key,
+		requestPath,
+	).Scan(
+		&idempotencyKey.ID,
+		&idempotencyKey.ShopID,
+		&idempotencyKey.Key,
+		&idempotencyKey.RequestPath,
+		&idempotencyKey.RequestHash,
+		&idempotencyKey.ResponseStatus,
+		&idempotencyKey.ResponseBody,
+		&idempotencyKey.CreatedAt,
+		&idempotencyKey.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan idempotency key row err: %v", err)
+	}
+
+	return &idempotencyKey, nil
+}
+
+func (d *idempotencyKeyDomain) CompleteIdempotencyKey(
+	ctx context.Context,
+	operations db.SQLOperations,
+	idempotencyKey *models.IdempotencyKey,
+) error {
+
+	idempotencyKey.Touch()
+
+	_, err := operations.ExecContext(
+		ctx,
+		completeIdempotencyKeySQL,
+		idempotencyKey.ResponseStatus,
+		// lib/pq sends []byte as bytea, which jsonb will not accept
+		string(idempotencyKey.ResponseBody),
+		idempotencyKey.UpdatedAt,
+		idempotencyKey.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("complete idempotency key exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *idempotencyKeyDomain) DeleteIdempotencyKey(
+	ctx context.Context,
+	operations db.SQLOperations,
+	idempotencyKeyID int64,
+) error {
+
+	_, err := operations.ExecContext(ctx, deleteIdempotencyKeySQL, idempotencyKeyID)
+	if err != nil {
+		return apperr.NewDatabaseError(err).LogErrorMessage("delete idempotency key error: %v", err)
+	}
+
+	return nil
+}
+
+// TakeOverIdempotencyKey claims a key again when the request holding it never finished and
+// was claimed before staleBefore. It returns false, without an error, when the key has
+// been completed, released or claimed afresh in the meantime.
+func (d *idempotencyKeyDomain) TakeOverIdempotencyKey(
+	ctx context.Context,
+	operations db.SQLOperations,
+	idempotencyKey *models.IdempotencyKey,
+	staleBefore time.Time,
+) (bool, error) {
+
+	claimedAt := time.Now()
+
+	err := operations.QueryRowContext(
+		ctx,
+		takeOverIdempotencyKeySQL,
+		claimedAt,
+		idempotencyKey.ID,
+		staleBefore,
+	).Scan(&idempotencyKey.ID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return false, nil
+		}
+
+		return false, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("take over idempotency key query row err: %v", err)
+	}
+
+	idempotencyKey.CreatedAt = claimedAt
+	idempotencyKey.UpdatedAt = claimedAt
+
+	return true, nil
+}
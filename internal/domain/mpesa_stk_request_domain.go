@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createMpesaSTKRequestSQL           = "INSERT INTO mpesa_stk_requests (order_id, payment_id, merchant_request_id, checkout_request_id, phone_number, amount, request_status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING(id)"
+	updateMpesaSTKRequestSQL           = "UPDATE mpesa_stk_requests SET request_status = $1, result_code = $2, result_desc = $3, mpesa_receipt_number = $4, updated_at = $5 WHERE id = $6"
+	getMpesaSTKRequestsSQL             = "SELECT r.id, r.order_id, o.shop_id, r.payment_id, r.merchant_request_id, r.checkout_request_id, r.phone_number, r.amount, r.request_status, r.result_code, r.result_desc, r.mpesa_receipt_number, r.created_at, r.updated_at FROM mpesa_stk_requests r INNER JOIN orders o ON o.id = r.order_id"
+	lockMpesaSTKRequestByCheckoutIDSQL = getMpesaSTKRequestsSQL + " WHERE r.checkout_request_id = $1 FOR UPDATE OF r"
+	getMpesaSTKRequestsByOrderIDSQL    = getMpesaSTKRequestsSQL + " WHERE r.order_id = $1 AND o.shop_id = $2 ORDER BY r.id DESC"
+)
+
+type (
+	MpesaSTKRequestDomain interface {
+		CreateMpesaSTKRequest(ctx context.Context, operations db.SQLOperations, stkRequest *models.MpesaSTKRequest) error
+		LockMpesaSTKRequestByCheckoutID(ctx context.Context, operations db.SQLOperations, checkoutRequestID string) (*models.MpesaSTKRequest, error)
+		MpesaSTKRequestsByOrderID(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) ([]*models.MpesaSTKRequest, error)
+	}
+
+	mpesaSTKRequestDomain struct{}
+)
+
+func NewMpesaSTKRequestDomain() MpesaSTKRequestDomain {
+	return &mpesaSTKRequestDomain{}
+}
+
+func (d *mpesaSTKRequestDomain) CreateMpesaSTKRequest(
+	ctx context.Context,
+	operations db.SQLOperations,
+	stkRequest *models.MpesaSTKRequest,
+) error {
+
+	stkRequest.Touch()
+	if stkRequest.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createMpesaSTKRequestSQL,
+			stkRequest.OrderID,
+			stkRequest.PaymentID,
+			stkRequest.MerchantRequestID,
+			stkRequest.CheckoutRequestID,
+			stkRequest.PhoneNumber,
+			stkRequest.Amount,
+			stkRequest.RequestStatus,
+			stkRequest.CreatedAt,
+			stkRequest.UpdatedAt,
+		).Scan(&stkRequest.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save mpesa stk request query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateMpesaSTKRequestSQL,
+		stkRequest.RequestStatus,
+		stkRequest.ResultCode,
+		stkRequest.ResultDesc,
+		stkRequest.MpesaReceiptNumber,
+		stkRequest.UpdatedAt,
+		stkRequest.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update mpesa stk request exec err: %v", err)
+	}
+
+	return nil
+}
+
+// LockMpesaSTKRequestByCheckoutID reads the request Daraja's callback refers to and holds a
+// row lock on it, so a callback delivered twice is only acted on once. The callback comes
+// from no shop's staff, so the request carries its order's shop for the lookups after it.
+func (d *mpesaSTKRequestDomain) LockMpesaSTKRequestByCheckoutID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	checkoutRequestID string,
+) (*models.MpesaSTKRequest, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockMpesaSTKRequestByCheckoutIDSQL,
+		checkoutRequestID,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *mpesaSTKRequestDomain) MpesaSTKRequestsByOrderID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+) ([]*models.MpesaSTKRequest, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		getMpesaSTKRequestsByOrderIDSQL,
+		orderID,
+		shopID,
+	)
+	if err != nil {
+		return []*models.MpesaSTKRequest{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("mpesa stk requests query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	stkRequests := make([]*models.MpesaSTKRequest, 0)
+
+	for rows.Next() {
+		stkRequest, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.MpesaSTKRequest{}, err
+		}
+
+		stkRequests = append(stkRequests, stkRequest)
+	}
+
+	if rows.Err() != nil {
+		return []*models.MpesaSTKRequest{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("mpesa stk requests rows err: %v", rows.Err())
+	}
+
+	return stkRequests, nil
+}
+
+func (d *mpesaSTKRequestDomain) scanRow(
+	row db.RowScanner,
+) (*models.MpesaSTKRequest, error) {
+
+	var stkRequest models.MpesaSTKRequest
+
+	err := row.Scan(
+		&stkRequest.ID,
+		&stkRequest.OrderID,
+		&stkRequest.ShopID,
+		&stkRequest.PaymentID,
+		&stkRequest.MerchantRequestID,
+		&stkRequest.CheckoutRequestID,
+		&stkRequest.PhoneNumber,
+		&stkRequest.Amount,
+		&stkRequest.RequestStatus,
+		&stkRequest.ResultCode,
+		&stkRequest.ResultDesc,
+		&stkRequest.MpesaReceiptNumber,
+		&stkRequest.CreatedAt,
+		&stkRequest.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan mpesa stk request row err: %v", err)
+	}
+
+	return &stkRequest, nil
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMpesaSTKRequestDomain_CreateMpesaSTKRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	stkRequestDomain := NewMpesaSTKRequestDomain()
+	ctx := context.Background()
+
+	stkRequest := &models.MpesaSTKRequest{
+		OrderID:           7,
+		PaymentID:         null.NullValue(int64(11)),
+		MerchantRequestID: "29115-34620561-1",
+		CheckoutRequestID: "ws_CO_191220191020363925",
+		PhoneNumber:       "254712345678",
+		Amount:            custom_types.NewMoney(150000),
+		RequestStatus:     custom_types.MpesaRequestStatusPending,
+	}
+
+	// -------- New request (INSERT) --------
+	mock.ExpectQuery("INSERT INTO mpesa_stk_requests").
+		WithArgs(stkRequest.OrderID, stkRequest.PaymentID, stkRequest.MerchantRequestID, stkRequest.CheckoutRequestID,
+			stkRequest.PhoneNumber, stkRequest.Amount, stkRequest.RequestStatus, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	err = stkRequestDomain.CreateMpesaSTKRequest(ctx, dbWrapper{DB: db}, stkRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stkRequest.ID)
+
+	// -------- Callback result (UPDATE) --------
+	stkRequest.RequestStatus = custom_types.MpesaRequestStatusCompleted
+	stkRequest.ResultCode = null.NullValue(int64(0))
+	stkRequest.ResultDesc = null.NullValue("The service request is processed successfully.")
+	stkRequest.MpesaReceiptNumber = null.NullValue("NLJ7RT61SV")
+
+	mock.ExpectExec("UPDATE mpesa_stk_requests SET .* WHERE id = \\$6").
+		WithArgs(stkRequest.RequestStatus, stkRequest.ResultCode, stkRequest.ResultDesc,
+			stkRequest.MpesaReceiptNumber, sqlmock.AnyArg(), stkRequest.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = stkRequestDomain.CreateMpesaSTKRequest(ctx, dbWrapper{DB: db}, stkRequest)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
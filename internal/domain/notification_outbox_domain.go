@@ -0,0 +1,260 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"strings"
+	"time"
+)
+
+const (
+	createOutboxNotificationSQL      = "INSERT INTO notification_outbox (shop_id, order_id, event, channel, recipient, payload, status, attempts, next_attempt_at, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING(id)"
+	updateOutboxNotificationSQL      = "UPDATE notification_outbox SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, sent_at = $5, updated_at = $6 WHERE id = $7"
+	getOutboxNotificationsSQL        = "SELECT id, shop_id, order_id, event, channel, recipient, payload, status, attempts, next_attempt_at, last_error, sent_at, created_at, updated_at FROM notification_outbox"
+	getOutboxNotificationsCountSQL   = "SELECT COUNT(id) FROM notification_outbox"
+	lockOutboxNotificationByIDSQL    = getOutboxNotificationsSQL + " WHERE id = $1 AND shop_id = $2 FOR UPDATE"
+	lockNextDueOutboxNotificationSQL = getOutboxNotificationsSQL + " WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at, id LIMIT 1 FOR UPDATE SKIP LOCKED"
+)
+
+type (
+	NotificationOutboxDomain interface {
+		CreateOutboxNotification(ctx context.Context, operations db.SQLOperations, notification *models.OutboxNotification) error
+		LockOutboxNotificationByID(ctx context.Context, operations db.SQLOperations, shopID string, notificationID int64) (*models.OutboxNotification, error)
+		LockNextDueOutboxNotification(ctx context.Context, operations db.SQLOperations, now time.Time) (*models.OutboxNotification, error)
+		ListOutboxNotifications(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) ([]*models.OutboxNotification, error)
+		OutboxNotificationsCount(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) (int, error)
+	}
+
+	notificationOutboxDomain struct{}
+)
+
+func NewNotificationOutboxDomain() NotificationOutboxDomain {
+	return &notificationOutboxDomain{}
+}
+
+func (d *notificationOutboxDomain) CreateOutboxNotification(
+	ctx context.Context,
+	operations db.SQLOperations,
+	notification *models.OutboxNotification,
+) error {
+
+	notification.Touch()
+	if notification.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createOutboxNotificationSQL,
+			notification.ShopID,
+			notification.OrderID,
+			notification.Event,
+			notification.Channel,
+			notification.Recipient,
+			[]byte(notification.Payload),
+			notification.Status,
+			notification.Attempts,
+			notification.NextAttemptAt,
+			notification.CreatedAt,
+			notification.UpdatedAt,
+		).Scan(&notification.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save outbox notification query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateOutboxNotificationSQL,
+		notification.Status,
+		notification.Attempts,
+		notification.NextAttemptAt,
+		notification.LastError,
+		notification.SentAt,
+		notification.UpdatedAt,
+		notification.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update outbox notification exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *notificationOutboxDomain) LockOutboxNotificationByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	notificationID int64,
+) (*models.OutboxNotification, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockOutboxNotificationByIDSQL,
+		notificationID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+// LockNextDueOutboxNotification claims the pending notification that has waited longest.
+// Rows another worker has already claimed are skipped, so several API replicas can
+// deliver from the same outbox without sending anything twice.
+func (d *notificationOutboxDomain) LockNextDueOutboxNotification(
+	ctx context.Context,
+	operations db.SQLOperations,
+	now time.Time,
+) (*models.OutboxNotification, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockNextDueOutboxNotificationSQL,
+		now,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *notificationOutboxDomain) ListOutboxNotifications(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	filter *models.Filter,
+) ([]*models.OutboxNotification, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	query, args := d.buildQuery(getOutboxNotificationsSQL, filter)
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.OutboxNotification{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("outbox notifications query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	notifications := make([]*models.OutboxNotification, 0)
+
+	for rows.Next() {
+		notification, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.OutboxNotification{}, err
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	if rows.Err() != nil {
+		return []*models.OutboxNotification{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list outbox notifications err: %v", rows.Err())
+	}
+
+	return notifications, nil
+}
+
+func (d *notificationOutboxDomain) OutboxNotificationsCount(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	filter *models.Filter,
+) (int, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	query, args := d.buildQuery(getOutboxNotificationsCountSQL, filter.NoPagination())
+
+	row := operations.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	)
+
+	var count int
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("outbox notifications count query row err: %v", err)
+	}
+
+	return count, nil
+}
+
+func (d *notificationOutboxDomain) buildQuery(
+	query string,
+	filter *models.Filter,
+) (string, []interface{}) {
+
+	args := make([]interface{}, 0)
+	conditions := make([]string, 0)
+	counter := utils.NewPlaceholder()
+
+	if filter.ShopID != nil {
+		condition := fmt.Sprintf("shop_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.ShopID))
+		conditions = append(conditions, condition)
+	}
+
+	if filter.Status != nil && *filter.Status != "" {
+		condition := fmt.Sprintf("status = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.Status))
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if filter.Page > 0 && filter.Per > 0 {
+		query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+
+	return query, args
+}
+
+func (d *notificationOutboxDomain) scanRow(
+	row db.RowScanner,
+) (*models.OutboxNotification, error) {
+
+	var notification models.OutboxNotification
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.ShopID,
+		&notification.OrderID,
+		&notification.Event,
+		&notification.Channel,
+		&notification.Recipient,
+		&notification.Payload,
+		&notification.Status,
+		&notification.Attempts,
+		&notification.NextAttemptAt,
+		&notification.LastError,
+		&notification.SentAt,
+		&notification.CreatedAt,
+		&notification.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan outbox notification row err: %v", err)
+	}
+
+	return &notification, nil
+}
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createNotificationTemplateSQL       = "INSERT INTO notification_templates (shop_id, event, channel, locale, subject, body, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING(id)"
+	updateNotificationTemplateSQL       = "UPDATE notification_templates SET subject = $1, body = $2, updated_at = $3 WHERE id = $4 AND shop_id = $5"
+	deleteNotificationTemplateSQL       = "DELETE FROM notification_templates WHERE id = $1 AND shop_id = $2"
+	getNotificationTemplatesSQL         = "SELECT id, shop_id, event, channel, locale, subject, body, created_at, updated_at FROM notification_templates"
+	getNotificationTemplateByIDSQL      = getNotificationTemplatesSQL + " WHERE id = $1 AND shop_id = $2"
+	getNotificationTemplateSQL          = getNotificationTemplatesSQL + " WHERE shop_id = $1 AND event = $2 AND channel = $3 AND locale = $4"
+	getNotificationTemplatesByShopIDSQL = getNotificationTemplatesSQL + " WHERE shop_id = $1 ORDER BY event, channel, locale"
+)
+
+type (
+	NotificationTemplateDomain interface {
+		CreateNotificationTemplate(ctx context.Context, operations db.SQLOperations, notificationTemplate *models.NotificationTemplate) error
+		DeleteNotificationTemplate(ctx context.Context, operations db.SQLOperations, notificationTemplate *models.NotificationTemplate) error
+		NotificationTemplateByID(ctx context.Context, operations db.SQLOperations, shopID string, templateID int64) (*models.NotificationTemplate, error)
+		NotificationTemplate(ctx context.Context, operations db.SQLOperations, shopID string, event custom_types.NotificationEvent, channel custom_types.NotificationChannel, locale custom_types.Locale) (*models.NotificationTemplate, error)
+		NotificationTemplatesByShopID(ctx context.Context, operations db.SQLOperations, shopID string) ([]*models.NotificationTemplate, error)
+	}
+
+	notificationTemplateDomain struct{}
+)
+
+func NewNotificationTemplateDomain() NotificationTemplateDomain {
+	return &notificationTemplateDomain{}
+}
+
+func (d *notificationTemplateDomain) CreateNotificationTemplate(
+	ctx context.Context,
+	operations db.SQLOperations,
+	notificationTemplate *models.NotificationTemplate,
+) error {
+
+	notificationTemplate.Touch()
+	if notificationTemplate.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createNotificationTemplateSQL,
+			notificationTemplate.ShopID,
+			notificationTemplate.Event,
+			notificationTemplate.Channel,
+			notificationTemplate.Locale,
+			notificationTemplate.Subject,
+			notificationTemplate.Body,
+			notificationTemplate.CreatedAt,
+			notificationTemplate.UpdatedAt,
+		).Scan(&notificationTemplate.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save notification template query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateNotificationTemplateSQL,
+		notificationTemplate.Subject,
+		notificationTemplate.Body,
+		notificationTemplate.UpdatedAt,
+		notificationTemplate.ID,
+		notificationTemplate.ShopID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update notification template exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *notificationTemplateDomain) DeleteNotificationTemplate(
+	ctx context.Context,
+	operations db.SQLOperations,
+	notificationTemplate *models.NotificationTemplate,
+) error {
+
+	_, err := operations.ExecContext(
+		ctx,
+		deleteNotificationTemplateSQL,
+		notificationTemplate.ID,
+		notificationTemplate.ShopID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("delete notification template exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *notificationTemplateDomain) NotificationTemplateByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	templateID int64,
+) (*models.NotificationTemplate, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getNotificationTemplateByIDSQL,
+		templateID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+// NotificationTemplate is the shop's own template for a notification in one language.
+func (d *notificationTemplateDomain) NotificationTemplate(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	event custom_types.NotificationEvent,
+	channel custom_types.NotificationChannel,
+	locale custom_types.Locale,
+) (*models.NotificationTemplate, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getNotificationTemplateSQL,
+		shopID,
+		event,
+		channel,
+		locale,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *notificationTemplateDomain) NotificationTemplatesByShopID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) ([]*models.NotificationTemplate, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		getNotificationTemplatesByShopIDSQL,
+		shopID,
+	)
+	if err != nil {
+		return []*models.NotificationTemplate{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("notification templates query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	notificationTemplates := make([]*models.NotificationTemplate, 0)
+
+	for rows.Next() {
+		notificationTemplate, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.NotificationTemplate{}, err
+		}
+
+		notificationTemplates = append(notificationTemplates, notificationTemplate)
+	}
+
+	if rows.Err() != nil {
+		return []*models.NotificationTemplate{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list notification templates err: %v", rows.Err())
+	}
+
+	return notificationTemplates, nil
+}
+
+func (d *notificationTemplateDomain) scanRow(
+	row db.RowScanner,
+) (*models.NotificationTemplate, error) {
+
+	var notificationTemplate models.NotificationTemplate
+
+	err := row.Scan(
+		&notificationTemplate.ID,
+		&notificationTemplate.ShopID,
+		&notificationTemplate.Event,
+		&notificationTemplate.Channel,
+		&notificationTemplate.Locale,
+		&notificationTemplate.Subject,
+		&notificationTemplate.Body,
+		&notificationTemplate.CreatedAt,
+		&notificationTemplate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan notification template row err: %v", err)
+	}
+
+	return &notificationTemplate, nil
+}
@@ -12,20 +12,24 @@ import (
 )
 
 const (
-	createOrderSQL    = "INSERT INTO orders (reference_number, phone_number, order_status, order_source, payment_method, customer_id, shop_id, total_items, total_amount, discount, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING(id)"
-	getOrdersSQL      = "SELECT id, reference_number, phone_number, order_status, order_source, payment_method, customer_id, shop_id, total_items, total_amount, discount, created_at, updated_at FROM orders"
-	getOrderByIDSQL   = getOrdersSQL + " WHERE id = $1"
+	createOrderSQL    = "INSERT INTO orders (reference_number, phone_number, order_status, order_source, payment_method, customer_id, shop_id, total_items, subtotal, line_discount, discount, tax_total, total_amount, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING(id)"
+	getOrdersSQL      = "SELECT id, reference_number, phone_number, order_status, order_source, payment_method, customer_id, shop_id, total_items, subtotal, line_discount, discount, tax_total, total_amount, created_at, updated_at FROM orders"
+	getOrderByIDSQL   = getOrdersSQL + " WHERE id = $1 AND shop_id = $2"
+	lockOrderByIDSQL  = getOrderByIDSQL + " FOR UPDATE"
 	getOrdersCountSQL = "SELECT COUNT(id) FROM orders"
 	deleteOrdersSQL   = "DELETE FROM orders WHERE id = $1"
-	updateOrderSQL    = "UPDATE orders SET reference_number = $1, phone_number = $2, order_status = $3, order_source = $4, payment_method = $5, customer_id = $6, shop_id = $7, total_items = $8, total_amount = $9, discount = $10, updated_at = $11 WHERE id = $12"
+	nextOrderReferenceSQL = "SELECT nextval('order_reference_seq')"
+	updateOrderSQL    = "UPDATE orders SET reference_number = $1, phone_number = $2, order_status = $3, order_source = $4, payment_method = $5, customer_id = $6, total_items = $7, subtotal = $8, line_discount = $9, discount = $10, tax_total = $11, total_amount = $12, updated_at = $13 WHERE id = $14 AND shop_id = $15"
 )
 
 type (
 	OrderDomain interface {
 		CreateOrder(ctx context.Context, operations db.SQLOperations, order *models.Order) error
-		OrderByID(ctx context.Context, operations db.SQLOperations, orderID int64) (*models.Order, error)
+		OrderByID(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) (*models.Order, error)
+		LockOrderByID(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) (*models.Order, error)
 		LisOrders(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Order, error)
 		OrderCount(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) (int, error)
+		NextOrderReferenceSequence(ctx context.Context, operations db.SQLOperations) (int64, error)
 	}
 
 	orderDomain struct{}
@@ -54,8 +58,11 @@ func (d *orderDomain) CreateOrder(
 			order.CustomerID,
 			order.ShopID,
 			order.TotalItems,
-			order.TotalAmount,
+			order.Subtotal,
+			order.LineDiscount,
 			order.Discount,
+			order.TaxTotal,
+			order.TotalAmount,
 			order.CreatedAt,
 			order.UpdatedAt,
 		).Scan(&order.ID)
@@ -76,12 +83,15 @@ func (d *orderDomain) CreateOrder(
 		order.OrderMedium,
 		order.PaymentMethod,
 		order.CustomerID,
-		order.ShopID,
 		order.TotalItems,
-		order.TotalAmount,
+		order.Subtotal,
+		order.LineDiscount,
 		order.Discount,
+		order.TaxTotal,
+		order.TotalAmount,
 		order.UpdatedAt,
 		order.ID,
+		order.ShopID,
 	)
 	if err != nil {
 		return apperr.NewDatabaseError(
@@ -94,6 +104,7 @@ func (d *orderDomain) CreateOrder(
 func (d *orderDomain) OrderByID(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	orderID int64,
 ) (*models.Order, error) {
 
@@ -101,6 +112,25 @@ func (d *orderDomain) OrderByID(
 		ctx,
 		getOrderByIDSQL,
 		orderID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+// LockOrderByID reads an order and holds a row lock on it until the surrounding transaction ends.
+func (d *orderDomain) LockOrderByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+) (*models.Order, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockOrderByIDSQL,
+		orderID,
+		shopID,
 	)
 
 	return d.scanRow(row)
@@ -171,6 +201,25 @@ func (d *orderDomain) LisOrders(
 	return orders, nil
 }
 
+// NextOrderReferenceSequence draws the next number for an order reference. Sequence values
+// are never handed out twice, even to concurrent transactions that later roll back.
+func (d *orderDomain) NextOrderReferenceSequence(
+	ctx context.Context,
+	operations db.SQLOperations,
+) (int64, error) {
+
+	var sequence int64
+
+	err := operations.QueryRowContext(ctx, nextOrderReferenceSQL).Scan(&sequence)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("next order reference query row err: %v", err)
+	}
+
+	return sequence, nil
+}
+
 func (d *orderDomain) buildQuery(
 	query string,
 	filter *models.Filter,
@@ -215,8 +264,11 @@ func (d *orderDomain) scanRow(
 		&order.CustomerID,
 		&order.ShopID,
 		&order.TotalItems,
-		&order.TotalAmount,
+		&order.Subtotal,
+		&order.LineDiscount,
 		&order.Discount,
+		&order.TaxTotal,
+		&order.TotalAmount,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 	)
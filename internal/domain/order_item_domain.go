@@ -12,20 +12,24 @@ import (
 )
 
 const (
-	createOrderItemSQL       = "INSERT INTO order_items (order_id, product_id, unit_price, quantity, total_amount, created_at, updated_at) VALUES "
-	getOrderItemsSQL         = "SELECT oi.id, oi.order_id, p.product_id,  oi.unit_price, oi.quantity, oi.total_amount, oi.created_at, oi.updated_at FROM order_items oi INNER JOIN products p ON oi.product_id = p.id"
-	getOrderItemByIDSQL      = getOrderItemsSQL + " WHERE id= $1"
-	getOrderItemsCountSQL    = "SELECT COUNT(id) FROM order_items"
-	deleteOrderItemSQL       = "DELETE FROM order_items WHERE product_id = $1"
+	createOrderItemSQL       = "INSERT INTO order_items (order_id, product_id, unit_price, quantity, discount, order_discount, tax_class_id, tax_rate, price_includes_tax, tax_amount, total_amount, created_at, updated_at) VALUES "
+	getOrderItemsSQL         = "SELECT oi.id, oi.order_id, oi.product_id, oi.unit_price, oi.quantity, oi.returned_quantity, oi.discount, oi.order_discount, oi.tax_class_id, oi.tax_rate, oi.price_includes_tax, oi.tax_amount, oi.total_amount, oi.created_at, oi.updated_at FROM order_items oi INNER JOIN products p ON oi.product_id = p.id INNER JOIN orders o ON o.id = oi.order_id"
+	getOrderItemByIDSQL      = getOrderItemsSQL + " WHERE oi.id = $1 AND o.shop_id = $2"
+	lockOrderItemsSQL        = getOrderItemsSQL + " WHERE oi.order_id = $1 AND o.shop_id = $2 ORDER BY oi.id FOR UPDATE OF oi"
+	getOrderItemsCountSQL    = "SELECT COUNT(oi.id) FROM order_items oi INNER JOIN orders o ON o.id = oi.order_id"
+	updateReturnedQuantitySQL = "UPDATE order_items SET returned_quantity = $1, updated_at = $2 WHERE id = $3"
+	deleteOrderItemSQL       = "DELETE FROM order_items WHERE product_id = $1 AND order_id IN (SELECT id FROM orders WHERE shop_id = $2)"
 )
 
 type (
 	OrderItemDomain interface {
 		InsertOrderItems(ctx context.Context, operations db.SQLOperations, orderItems []*models.OrderItem) error
-		OrderItemByID(ctx context.Context, operations db.SQLOperations, orderItemID int64) (*models.OrderItem, error)
-		OrderItemCount(ctx context.Context, operations db.SQLOperations, orderID int64, filter *models.Filter) (int, error)
-		OrderItems(ctx context.Context, operations db.SQLOperations, orderID int64, filter *models.Filter) ([]*models.OrderItem, error)
-		DeleteOrderItems(ctx context.Context, operations db.SQLOperations, productID int64) error
+		OrderItemByID(ctx context.Context, operations db.SQLOperations, shopID string, orderItemID int64) (*models.OrderItem, error)
+		OrderItemCount(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64, filter *models.Filter) (int, error)
+		OrderItems(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64, filter *models.Filter) ([]*models.OrderItem, error)
+		LockOrderItems(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) ([]*models.OrderItem, error)
+		UpdateReturnedQuantity(ctx context.Context, operations db.SQLOperations, orderItem *models.OrderItem) error
+		DeleteOrderItems(ctx context.Context, operations db.SQLOperations, shopID string, productID int64) error
 	}
 
 	orderItemDomain struct{}
@@ -42,7 +46,7 @@ func (d *orderItemDomain) InsertOrderItems(
 ) error {
 
     // Base query with all columns, including `updated_at`
-    baseSQL := "INSERT INTO order_items (order_id, product_id, unit_price, quantity, total_amount, created_at, updated_at) VALUES "
+    baseSQL := createOrderItemSQL
     
     counter := utils.NewPlaceholder()
     placeholders := make([]string, len(orderItems))
@@ -51,32 +55,54 @@ func (d *orderItemDomain) InsertOrderItems(
     for index, orderItem := range orderItems {
         orderItem.Touch()
 
-        // Create a placeholder string for each item with 7 values, e.g., "($1, $2, $3, $4, $5, $6, $7)"
-        placeholder := make([]string, 7)
-        for i := 0; i < 7; i++ {
+        // Create a placeholder string for each item with 13 values, e.g., "($1, $2, ..., $13)"
+        placeholder := make([]string, 13)
+        for i := 0; i < 13; i++ {
             placeholder[i] = fmt.Sprintf("$%d", counter.Touch())
         }
 
         placeholders[index] = "(" + strings.Join(placeholder, ",") + ")"
         
-        // Append the 7 values for the current order item
+        // Append the 13 values for the current order item
         values = append(values,
             orderItem.OrderID,
             orderItem.ProductID,
             orderItem.UnitPrice,
             orderItem.Quantity,
+            orderItem.Discount,
+            orderItem.OrderDiscount,
+            orderItem.TaxClassID,
+            orderItem.TaxRate,
+            orderItem.PriceIncludesTax,
+            orderItem.TaxAmount,
             orderItem.TotalAmount,
             orderItem.CreatedAt,
 			orderItem.UpdatedAt,
         )
     }
 
-    // Join the placeholders and form the final query.
-    query := baseSQL + strings.Join(placeholders, ",")
+    // Join the placeholders and form the final query. Ids come back in the order the rows were listed.
+    query := baseSQL + strings.Join(placeholders, ",") + " RETURNING id"
 
-    _, err := operations.ExecContext(ctx, query, values...)
+    rows, err := operations.QueryContext(ctx, query, values...)
     if err != nil {
-        return apperr.NewDatabaseError(err).LogErrorMessage("insert order items exec error")
+        return apperr.NewDatabaseError(err).LogErrorMessage("insert order items query error")
+    }
+    defer rows.Close()
+
+    for _, orderItem := range orderItems {
+        if !rows.Next() {
+            break
+        }
+
+        err = rows.Scan(&orderItem.ID)
+        if err != nil {
+            return apperr.NewDatabaseError(err).LogErrorMessage("scan inserted order item id err")
+        }
+    }
+
+    if rows.Err() != nil {
+        return apperr.NewDatabaseError(rows.Err()).LogErrorMessage("insert order items rows err")
     }
 
     return nil
@@ -85,6 +111,7 @@ func (d *orderItemDomain) InsertOrderItems(
 func (d *orderItemDomain) OrderItemByID(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	orderItemID int64,
 ) (*models.OrderItem, error) {
 
@@ -92,6 +119,7 @@ func (d *orderItemDomain) OrderItemByID(
 		ctx,
 		getOrderItemByIDSQL,
 		orderItemID,
+		shopID,
 	)
 
 	return d.scanRow(row)
@@ -100,10 +128,12 @@ func (d *orderItemDomain) OrderItemByID(
 func (d *orderItemDomain) OrderItemCount(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	orderID int64,
 	filter *models.Filter,
 ) (int, error) {
 
+	filter.ShopID = null.NullValue(shopID)
 	filter.OrderID = null.NullValue(orderID)
 
 	query, args := d.buildQuery(getOrderItemsCountSQL, filter.NoPagination())
@@ -126,10 +156,12 @@ func (d *orderItemDomain) OrderItemCount(
 func (d *orderItemDomain) OrderItems(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	orderID int64,
 	filter *models.Filter,
 ) ([]*models.OrderItem, error) {
 
+	filter.ShopID = null.NullValue(shopID)
 	filter.OrderID = null.NullValue(orderID)
 
 	query, args := d.buildQuery(getOrderItemsSQL, filter)
@@ -159,13 +191,70 @@ func (d *orderItemDomain) OrderItems(
 	return orderItems, nil
 }
 
+// LockOrderItems reads all items of an order and holds row locks on them until the surrounding transaction ends.
+func (d *orderItemDomain) LockOrderItems(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+) ([]*models.OrderItem, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		lockOrderItemsSQL,
+		orderID,
+		shopID,
+	)
+	if err != nil {
+		return []*models.OrderItem{}, apperr.NewDatabaseError(err).LogErrorMessage("lock order items query context err")
+	}
+	defer rows.Close()
+
+	orderItems := make([]*models.OrderItem, 0)
+	for rows.Next() {
+		orderItem, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.OrderItem{}, err
+		}
+		orderItems = append(orderItems, orderItem)
+	}
+	if rows.Err() != nil {
+		return []*models.OrderItem{}, apperr.NewDatabaseError(rows.Err()).LogErrorMessage("lock order items err")
+	}
+
+	return orderItems, nil
+}
+
+func (d *orderItemDomain) UpdateReturnedQuantity(
+	ctx context.Context,
+	operations db.SQLOperations,
+	orderItem *models.OrderItem,
+) error {
+
+	orderItem.Touch()
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateReturnedQuantitySQL,
+		orderItem.ReturnedQuantity,
+		orderItem.UpdatedAt,
+		orderItem.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(err).LogErrorMessage("update order item returned quantity err: %v", err)
+	}
+
+	return nil
+}
+
 func (d *orderItemDomain) DeleteOrderItems(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	productID int64,
 ) error {
 
-	_, err := operations.ExecContext(ctx, deleteOrderItemSQL, productID)
+	_, err := operations.ExecContext(ctx, deleteOrderItemSQL, productID, shopID)
 	if err != nil {
 		return apperr.NewDatabaseError(err).LogErrorMessage("delete order items error: %v", err)
 	}
@@ -181,6 +270,12 @@ func (d *orderItemDomain) buildQuery(
 	conditions := make([]string, 0)
 	counter := utils.NewPlaceholder()
 
+	if filter.ShopID != nil {
+		condition := fmt.Sprintf("o.shop_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.ShopID))
+		conditions = append(conditions, condition)
+	}
+
 	if filter.OrderID != nil {
 		condition := fmt.Sprintf("oi.order_id = $%d", counter.Touch())
 		args = append(args, null.ValueFromNull(filter.OrderID))
@@ -210,8 +305,16 @@ func (d *orderItemDomain) scanRow(
 		&orderItem.ProductID,
 		&orderItem.UnitPrice,
 		&orderItem.Quantity,
+		&orderItem.ReturnedQuantity,
+		&orderItem.Discount,
+		&orderItem.OrderDiscount,
+		&orderItem.TaxClassID,
+		&orderItem.TaxRate,
+		&orderItem.PriceIncludesTax,
+		&orderItem.TaxAmount,
 		&orderItem.TotalAmount,
 		&orderItem.CreatedAt,
+		&orderItem.UpdatedAt,
 	)
 	if err != nil {
 		return &models.OrderItem{}, apperr.NewDatabaseError(err).LogErrorMessage("scan row err")
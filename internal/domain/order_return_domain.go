@@ -0,0 +1,275 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"strings"
+)
+
+const (
+	createOrderReturnSQL       = "INSERT INTO order_returns (order_id, reason, refund_amount, actor, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING(id)"
+	getOrderReturnsSQL         = "SELECT id, order_id, reason, refund_amount, actor, created_at, updated_at FROM order_returns"
+	getOrderReturnsCountSQL    = "SELECT COUNT(id) FROM order_returns"
+	createOrderReturnItemSQL   = "INSERT INTO order_return_items (order_return_id, order_item_id, product_id, quantity, refund_amount, tax_amount, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING(id)"
+	getOrderReturnItemsSQL     = "SELECT id, order_return_id, order_item_id, product_id, quantity, refund_amount, tax_amount, created_at, updated_at FROM order_return_items WHERE order_return_id = $1 AND order_return_id IN (SELECT r.id FROM order_returns r INNER JOIN orders o ON o.id = r.order_id WHERE o.shop_id = $2) ORDER BY id"
+)
+
+type (
+	OrderReturnDomain interface {
+		CreateOrderReturn(ctx context.Context, operations db.SQLOperations, orderReturn *models.OrderReturn) error
+		ListOrderReturns(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64, filter *models.Filter) ([]*models.OrderReturn, error)
+		OrderReturnsCount(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64, filter *models.Filter) (int, error)
+		OrderReturnItems(ctx context.Context, operations db.SQLOperations, shopID string, orderReturnID int64) ([]*models.OrderReturnItem, error)
+	}
+
+	orderReturnDomain struct{}
+)
+
+func NewOrderReturnDomain() OrderReturnDomain {
+	return &orderReturnDomain{}
+}
+
+// CreateOrderReturn saves the return together with its items.
+func (d *orderReturnDomain) CreateOrderReturn(
+	ctx context.Context,
+	operations db.SQLOperations,
+	orderReturn *models.OrderReturn,
+) error {
+
+	orderReturn.Touch()
+
+	err := operations.QueryRowContext(
+		ctx,
+		createOrderReturnSQL,
+		orderReturn.OrderID,
+		orderReturn.Reason,
+		orderReturn.RefundAmount,
+		orderReturn.Actor,
+		orderReturn.CreatedAt,
+		orderReturn.UpdatedAt,
+	).Scan(&orderReturn.ID)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save order return query row err: %v", err)
+	}
+
+	for _, item := range orderReturn.Items {
+
+		item.OrderReturnID = orderReturn.ID
+		item.Touch()
+
+		err := operations.QueryRowContext(
+			ctx,
+			createOrderReturnItemSQL,
+			item.OrderReturnID,
+			item.OrderItemID,
+			item.ProductID,
+			item.Quantity,
+			item.RefundAmount,
+			item.TaxAmount,
+			item.CreatedAt,
+			item.UpdatedAt,
+		).Scan(&item.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save order return item query row err: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *orderReturnDomain) ListOrderReturns(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+	filter *models.Filter,
+) ([]*models.OrderReturn, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	filter.OrderID = null.NullValue(orderID)
+	query, args := d.buildQuery(getOrderReturnsSQL, filter)
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.OrderReturn{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("order returns query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	orderReturns := make([]*models.OrderReturn, 0)
+
+	for rows.Next() {
+		orderReturn, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.OrderReturn{}, err
+		}
+
+		orderReturns = append(orderReturns, orderReturn)
+	}
+
+	if rows.Err() != nil {
+		return []*models.OrderReturn{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list order returns err: %v", rows.Err())
+	}
+
+	return orderReturns, nil
+}
+
+func (d *orderReturnDomain) OrderReturnsCount(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+	filter *models.Filter,
+) (int, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	filter.OrderID = null.NullValue(orderID)
+	query, args := d.buildQuery(getOrderReturnsCountSQL, filter.NoPagination())
+
+	row := operations.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	)
+
+	var count int
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("order returns count query row err: %v", err)
+	}
+
+	return count, nil
+}
+
+func (d *orderReturnDomain) OrderReturnItems(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderReturnID int64,
+) ([]*models.OrderReturnItem, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		getOrderReturnItemsSQL,
+		orderReturnID,
+		shopID,
+	)
+	if err != nil {
+		return []*models.OrderReturnItem{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("order return items query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	items := make([]*models.OrderReturnItem, 0)
+
+	for rows.Next() {
+
+		var item models.OrderReturnItem
+
+		err := rows.Scan(
+			&item.ID,
+			&item.OrderReturnID,
+			&item.OrderItemID,
+			&item.ProductID,
+			&item.Quantity,
+			&item.RefundAmount,
+			&item.TaxAmount,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return []*models.OrderReturnItem{}, apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("scan order return item row err: %v", err)
+		}
+
+		items = append(items, &item)
+	}
+
+	if rows.Err() != nil {
+		return []*models.OrderReturnItem{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list order return items err: %v", rows.Err())
+	}
+
+	return items, nil
+}
+
+func (d *orderReturnDomain) buildQuery(
+	query string,
+	filter *models.Filter,
+) (string, []interface{}) {
+
+	args := make([]interface{}, 0)
+	conditions := make([]string, 0)
+	counter := utils.NewPlaceholder()
+
+	if filter.ShopID != nil {
+		condition := fmt.Sprintf("order_id IN (SELECT id FROM orders WHERE shop_id = $%d)", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.ShopID))
+		conditions = append(conditions, condition)
+	}
+
+	if filter.OrderID != nil {
+		condition := fmt.Sprintf("order_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.OrderID))
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if filter.Page > 0 && filter.Per > 0 {
+		query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+
+	return query, args
+}
+
+func (d *orderReturnDomain) scanRow(
+	row db.RowScanner,
+) (*models.OrderReturn, error) {
+
+	var orderReturn models.OrderReturn
+
+	err := row.Scan(
+		&orderReturn.ID,
+		&orderReturn.OrderID,
+		&orderReturn.Reason,
+		&orderReturn.RefundAmount,
+		&orderReturn.Actor,
+		&orderReturn.CreatedAt,
+		&orderReturn.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan order return row err: %v", err)
+	}
+
+	return &orderReturn, nil
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"strings"
+)
+
+const (
+	createOrderStatusTransitionSQL    = "INSERT INTO order_status_transitions (order_id, from_status, to_status, changed_by, reason, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING(id)"
+	getOrderStatusTransitionsSQL      = "SELECT id, order_id, from_status, to_status, changed_by, reason, created_at, updated_at FROM order_status_transitions"
+	getOrderStatusTransitionsCountSQL = "SELECT COUNT(id) FROM order_status_transitions"
+)
+
+type (
+	OrderStatusTransitionDomain interface {
+		CreateOrderStatusTransition(ctx context.Context, operations db.SQLOperations, transition *models.OrderStatusTransition) error
+		ListOrderStatusTransitions(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64, filter *models.Filter) ([]*models.OrderStatusTransition, error)
+		OrderStatusTransitionsCount(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64, filter *models.Filter) (int, error)
+	}
+
+	orderStatusTransitionDomain struct{}
+)
+
+func NewOrderStatusTransitionDomain() OrderStatusTransitionDomain {
+	return &orderStatusTransitionDomain{}
+}
+
+func (d *orderStatusTransitionDomain) CreateOrderStatusTransition(
+	ctx context.Context,
+	operations db.SQLOperations,
+	transition *models.OrderStatusTransition,
+) error {
+
+	transition.Touch()
+
+	err := operations.QueryRowContext(
+		ctx,
+		createOrderStatusTransitionSQL,
+		transition.OrderID,
+		transition.FromStatus,
+		transition.ToStatus,
+		transition.ChangedBy,
+		transition.Reason,
+		transition.CreatedAt,
+		transition.UpdatedAt,
+	).Scan(&transition.ID)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save order status transition query row err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *orderStatusTransitionDomain) ListOrderStatusTransitions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+	filter *models.Filter,
+) ([]*models.OrderStatusTransition, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	filter.OrderID = null.NullValue(orderID)
+	query, args := d.buildQuery(getOrderStatusTransitionsSQL, filter)
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.OrderStatusTransition{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("order status transitions query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	transitions := make([]*models.OrderStatusTransition, 0)
+
+	for rows.Next() {
+		transition, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.OrderStatusTransition{}, err
+		}
+
+		transitions = append(transitions, transition)
+	}
+
+	if rows.Err() != nil {
+		return []*models.OrderStatusTransition{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list order status transitions err: %v", rows.Err())
+	}
+
+	return transitions, nil
+}
+
+func (d *orderStatusTransitionDomain) OrderStatusTransitionsCount(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+	filter *models.Filter,
+) (int, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	filter.OrderID = null.NullValue(orderID)
+	query, args := d.buildQuery(getOrderStatusTransitionsCountSQL, filter.NoPagination())
+
+	row := operations.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	)
+
+	var count int
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("order status transitions count query row err: %v", err)
+	}
+
+	return count, nil
+}
+
+func (d *orderStatusTransitionDomain) buildQuery(
+	query string,
+	filter *models.Filter,
+) (string, []interface{}) {
+
+	args := make([]interface{}, 0)
+	conditions := make([]string, 0)
+	counter := utils.NewPlaceholder()
+
+	if filter.ShopID != nil {
+		condition := fmt.Sprintf("order_id IN (SELECT id FROM orders WHERE shop_id = $%d)", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.ShopID))
+		conditions = append(conditions, condition)
+	}
+
+	if filter.OrderID != nil {
+		condition := fmt.Sprintf("order_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.OrderID))
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if filter.Page > 0 && filter.Per > 0 {
+		query += fmt.Sprintf(" ORDER BY created_at ASC, id ASC LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+
+	return query, args
+}
+
+func (d *orderStatusTransitionDomain) scanRow(
+	row db.RowScanner,
+) (*models.OrderStatusTransition, error) {
+
+	var transition models.OrderStatusTransition
+
+	err := row.Scan(
+		&transition.ID,
+		&transition.OrderID,
+		&transition.FromStatus,
+		&transition.ToStatus,
+		&transition.ChangedBy,
+		&transition.Reason,
+		&transition.CreatedAt,
+		&transition.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan order status transition row err: %v", err)
+	}
+
+	return &transition, nil
+}
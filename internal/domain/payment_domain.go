@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createPaymentSQL                 = "INSERT INTO payments (order_id, amount, amount_tendered, change_due, payment_method, provider_reference, payment_status, recorded_by, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING(id)"
+	updatePaymentSQL                 = "UPDATE payments SET amount = $1, amount_tendered = $2, change_due = $3, provider_reference = $4, payment_status = $5, updated_at = $6 WHERE id = $7"
+	getPaymentsSQL                   = "SELECT id, order_id, amount, amount_tendered, change_due, payment_method, provider_reference, payment_status, recorded_by, created_at, updated_at FROM payments"
+	lockPaymentByIDSQL               = getPaymentsSQL + " WHERE id = $1 AND order_id IN (SELECT id FROM orders WHERE shop_id = $2) FOR UPDATE"
+	getPaymentByProviderReferenceSQL = getPaymentsSQL + " WHERE payment_method = $1 AND provider_reference = $2"
+	getPaymentsByOrderIDSQL          = getPaymentsSQL + " WHERE order_id = $1 AND order_id IN (SELECT id FROM orders WHERE shop_id = $2) ORDER BY id"
+)
+
+type (
+	PaymentDomain interface {
+		CreatePayment(ctx context.Context, operations db.SQLOperations, payment *models.Payment) error
+		LockPaymentByID(ctx context.Context, operations db.SQLOperations, shopID string, paymentID int64) (*models.Payment, error)
+		PaymentByProviderReference(ctx context.Context, operations db.SQLOperations, paymentMethod custom_types.PaymentMethod, providerReference string) (*models.Payment, error)
+		PaymentsByOrderID(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) ([]*models.Payment, error)
+	}
+
+	paymentDomain struct{}
+)
+
+func NewPaymentDomain() PaymentDomain {
+	return &paymentDomain{}
+}
+
+func (d *paymentDomain) CreatePayment(
+	ctx context.Context,
+	operations db.SQLOperations,
+	payment *models.Payment,
+) error {
+
+	payment.Touch()
+	if payment.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createPaymentSQL,
+			payment.OrderID,
+			payment.Amount,
+			payment.AmountTendered,
+			payment.ChangeDue,
+			payment.PaymentMethod,
+			payment.ProviderReference,
+			payment.PaymentStatus,
+			payment.RecordedBy,
+			payment.CreatedAt,
+			payment.UpdatedAt,
+		).Scan(&payment.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save payment query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updatePaymentSQL,
+		payment.Amount,
+		payment.AmountTendered,
+		payment.ChangeDue,
+		payment.ProviderReference,
+		payment.PaymentStatus,
+		payment.UpdatedAt,
+		payment.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update payment exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *paymentDomain) LockPaymentByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	paymentID int64,
+) (*models.Payment, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockPaymentByIDSQL,
+		paymentID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+// PaymentByProviderReference looks across every shop: a receipt number belongs to one
+// payment only, wherever it was recorded.
+func (d *paymentDomain) PaymentByProviderReference(
+	ctx context.Context,
+	operations db.SQLOperations,
+	paymentMethod custom_types.PaymentMethod,
+	providerReference string,
+) (*models.Payment, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getPaymentByProviderReferenceSQL,
+		paymentMethod,
+		providerReference,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *paymentDomain) PaymentsByOrderID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+) ([]*models.Payment, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		getPaymentsByOrderIDSQL,
+		orderID,
+		shopID,
+	)
+	if err != nil {
+		return []*models.Payment{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("payments query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	payments := make([]*models.Payment, 0)
+
+	for rows.Next() {
+		payment, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.Payment{}, err
+		}
+
+		payments = append(payments, payment)
+	}
+
+	if rows.Err() != nil {
+		return []*models.Payment{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("payments rows err: %v", rows.Err())
+	}
+
+	return payments, nil
+}
+
+func (d *paymentDomain) scanRow(
+	row db.RowScanner,
+) (*models.Payment, error) {
+
+	var payment models.Payment
+
+	err := row.Scan(
+		&payment.ID,
+		&payment.OrderID,
+		&payment.Amount,
+		&payment.AmountTendered,
+		&payment.ChangeDue,
+		&payment.PaymentMethod,
+		&payment.ProviderReference,
+		&payment.PaymentStatus,
+		&payment.RecordedBy,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan payment row err: %v", err)
+	}
+
+	return &payment, nil
+}
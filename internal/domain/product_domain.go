@@ -4,33 +4,37 @@ import (
 	"context"
 	"fmt"
 	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/models"
 	"github/Doris-Mwito5/savannah-pos/internal/null"
 	"github/Doris-Mwito5/savannah-pos/internal/utils"
 	"strings"
+	"time"
 )
 
 const (
-	createProductSQL = "INSERT INTO products(name, description, wholesale_price, retail_price, category_id, product_image, product_type, stock, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id"
-	getProductsSQL   = `SELECT p.id, p.name, p.description, p.wholesale_price, p.retail_price, p.category_id, p.product_image, p.product_type, p.stock, p.created_at, p.updated_at FROM products p
-		LEFT JOIN categories c ON c.id = p.category_id`
-	getProductByIDSQL    = getProductsSQL + " WHERE p.id = $1"
-	getInventoryCountSQL = "SELECT COUNT(p.id) FROM products p LEFT JOIN categories c ON c.id = p.category_id"
-	updateProductSQL     = `UPDATE products SET name = $1, description = $2, wholesale_price = $3, retail_price = $4, category_id = $5, product_image = $6, product_type = $7, stock = $8, updated_at = $9 WHERE id = $10`
-	deleteProductSQL     = "DELETE FROM products WHERE id = $1"
+	createProductSQL     = "INSERT INTO products(shop_id, name, description, wholesale_price, retail_price, category_id, product_image, product_type, stock, tax_class_id, price_includes_tax, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id"
+	getProductsSQL       = "SELECT p.id, p.shop_id, p.name, p.description, p.wholesale_price, p.retail_price, p.category_id, p.product_image, p.product_type, p.stock, p.tax_class_id, p.price_includes_tax, p.created_at, p.updated_at FROM products p"
+	getProductByIDSQL    = getProductsSQL + " WHERE p.id = $1 AND p.shop_id = $2"
+	lockProductByIDSQL   = getProductByIDSQL + " FOR UPDATE"
+	getInventoryCountSQL = "SELECT COUNT(p.id) FROM products p"
+	updateProductSQL     = `UPDATE products SET name = $1, description = $2, wholesale_price = $3, retail_price = $4, category_id = $5, product_image = $6, product_type = $7, tax_class_id = $8, price_includes_tax = $9, updated_at = $10 WHERE id = $11 AND shop_id = $12`
+	deleteProductSQL     = "DELETE FROM products WHERE id = $1 AND shop_id = $2"
+	adjustStockSQL       = "UPDATE products SET stock = stock + $1, updated_at = $2 WHERE id = $3 AND shop_id = $4 RETURNING stock"
 	
 	getCategoryHierarchySQL = `
 		WITH RECURSIVE category_tree AS (
 			SELECT id, name, parent_id, 0 as level
 			FROM categories 
-			WHERE id = $1
+			WHERE id = $1 AND shop_id = $2
 			
 			UNION ALL
 			
 			SELECT c.id, c.name, c.parent_id, ct.level + 1
 			FROM categories c
 			INNER JOIN category_tree ct ON c.parent_id = ct.id
+			WHERE c.shop_id = $2
 		)
 		SELECT id FROM category_tree`
 		
@@ -38,28 +42,32 @@ const (
 		WITH RECURSIVE category_tree AS (
 			SELECT id, name, parent_id, 0 as level
 			FROM categories 
-			WHERE id = $1
+			WHERE id = $1 AND shop_id = $2
 			
 			UNION ALL
 			
 			SELECT c.id, c.name, c.parent_id, ct.level + 1
 			FROM categories c
 			INNER JOIN category_tree ct ON c.parent_id = ct.id
+			WHERE c.shop_id = $2
 		)
-		SELECT COALESCE(AVG(p.retail_price), 0) as average_price
+		SELECT COALESCE(ROUND(AVG(p.retail_price), 2), 0) as average_price
 		FROM products p
-		INNER JOIN category_tree ct ON p.category_id = ct.id`
+		INNER JOIN category_tree ct ON p.category_id = ct.id
+		WHERE p.shop_id = $2`
 )
 
 type (
 	ProductDomain interface {
 		CreateProduct(ctx context.Context, operations db.SQLOperations, product *models.Product) error
-		ProductByID(ctx context.Context, operations db.SQLOperations, productID int64) (*models.Product, error)
+		ProductByID(ctx context.Context, operations db.SQLOperations, shopID string, productID int64) (*models.Product, error)
+		LockProductByID(ctx context.Context, operations db.SQLOperations, shopID string, productID int64) (*models.Product, error)
+		AdjustStock(ctx context.Context, operations db.SQLOperations, shopID string, productID int64, quantity int64) (int64, error)
 		ListProducts(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Product, error)
 		ProductCount(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) (int, error)
-		DeleteProduct(ctx context.Context, operations db.SQLOperations, productID int64) error
-		GetAveragePriceByCategory(ctx context.Context, operations db.SQLOperations, categoryID int64) (float64, error)
-		GetCategoryHierarchy(ctx context.Context, operations db.SQLOperations, categoryID int64) ([]int64, error)
+		DeleteProduct(ctx context.Context, operations db.SQLOperations, shopID string, productID int64) error
+		GetAveragePriceByCategory(ctx context.Context, operations db.SQLOperations, shopID string, categoryID int64) (custom_types.Money, error)
+		GetCategoryHierarchy(ctx context.Context, operations db.SQLOperations, shopID string, categoryID int64) ([]int64, error)
 	}
 
 	productDomain struct{}
@@ -79,6 +87,7 @@ func (d *productDomain) CreateProduct(
 		err := operations.QueryRowContext(
 			ctx,
 			createProductSQL,
+			product.ShopID,
 			product.Name,
 			product.Description,
 			product.WholesalePrice,
@@ -87,6 +96,8 @@ func (d *productDomain) CreateProduct(
 			product.ProductImage,
 			product.ProductType,
 			product.Stock,
+			product.TaxClassID,
+			product.PriceIncludesTax,
 			product.CreatedAt,
 			product.UpdatedAt,
 		).Scan(&product.ID)
@@ -108,9 +119,11 @@ func (d *productDomain) CreateProduct(
 		product.CategoryID,
 		product.ProductImage,
 		product.ProductType,
-		product.Stock,
+		product.TaxClassID,
+		product.PriceIncludesTax,
 		product.UpdatedAt,
 		product.ID,
+		product.ShopID,
 	)
 	if err != nil {
 		return apperr.NewDatabaseError(
@@ -123,6 +136,7 @@ func (d *productDomain) CreateProduct(
 func (d *productDomain) ProductByID(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	productID int64,
 ) (*models.Product, error) {
 
@@ -130,18 +144,67 @@ func (d *productDomain) ProductByID(
 		ctx,
 		getProductByIDSQL,
 		productID,
+		shopID,
 	)
 
 	return d.scanRow(row)
 }
 
+// LockProductByID reads a product and holds a row lock on it until the surrounding transaction ends.
+func (d *productDomain) LockProductByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	productID int64,
+) (*models.Product, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockProductByIDSQL,
+		productID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+// AdjustStock adds quantity (which may be negative) to a product's stock and returns the new level.
+// Stock only changes through the stock movement ledger; updateProductSQL leaves it alone.
+func (d *productDomain) AdjustStock(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	productID int64,
+	quantity int64,
+) (int64, error) {
+
+	var stock int64
+
+	err := operations.QueryRowContext(
+		ctx,
+		adjustStockSQL,
+		quantity,
+		time.Now(),
+		productID,
+		shopID,
+	).Scan(&stock)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("adjust product stock err: %v", err)
+	}
+
+	return stock, nil
+}
+
 func (d *productDomain) DeleteProduct(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	productID int64,
 ) error {
 
-	_, err := operations.ExecContext(ctx, deleteProductSQL, productID)
+	_, err := operations.ExecContext(ctx, deleteProductSQL, productID, shopID)
 	if err != nil {
 		return apperr.NewDatabaseError(err).LogErrorMessage("delete product error: %v", err)
 	}
@@ -235,19 +298,21 @@ func (d *productDomain) ProductCount(
 func (d *productDomain) GetAveragePriceByCategory(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	categoryID int64,
-) (float64, error) {
+) (custom_types.Money, error) {
 	
 	row := operations.QueryRowContext(
 		ctx,
 		getAveragePriceByCategorySQL,
 		categoryID,
+		shopID,
 	)
 
-	var averagePrice float64
+	var averagePrice custom_types.Money
 	err := row.Scan(&averagePrice)
 	if err != nil {
-		return 0, apperr.NewDatabaseError(
+		return custom_types.Money{}, apperr.NewDatabaseError(
 			err,
 		).LogErrorMessage("get average price by category err: %v", err)
 	}
@@ -258,6 +323,7 @@ func (d *productDomain) GetAveragePriceByCategory(
 func (d *productDomain) GetCategoryHierarchy(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	categoryID int64,
 ) ([]int64, error) {
 	
@@ -265,6 +331,7 @@ func (d *productDomain) GetCategoryHierarchy(
 		ctx,
 		getCategoryHierarchySQL,
 		categoryID,
+		shopID,
 	)
 	if err != nil {
 		return []int64{}, apperr.NewDatabaseError(
@@ -304,13 +371,13 @@ func (d *productDomain) buildQuery(
 	counter := utils.NewPlaceholder()
 
 	if filter.CategoryID != nil {
-		condition := fmt.Sprintf("category_id = $%d", counter.Touch())
+		condition := fmt.Sprintf("p.category_id = $%d", counter.Touch())
 		args = append(args, null.ValueFromNull(filter.CategoryID))
 		conditions = append(conditions, condition)
 	}
 
 	if filter.ShopID != nil {
-		condition := fmt.Sprintf("shop_id = $%d", counter.Touch())
+		condition := fmt.Sprintf("p.shop_id = $%d", counter.Touch())
 		args = append(args, null.ValueFromNull(filter.ShopID))
 		conditions = append(conditions, condition)
 	}
@@ -320,7 +387,7 @@ func (d *productDomain) buildQuery(
 	}
 
 	if filter.Page > 0 && filter.Per > 0 {
-		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		query += fmt.Sprintf(" ORDER BY p.id LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
 		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
 	}
 
@@ -335,6 +402,7 @@ func (d *productDomain) scanRow(
 
 	err := row.Scan(
 		&product.ID,
+		&product.ShopID,
 		&product.Name,
 		&product.Description,
 		&product.WholesalePrice,
@@ -343,6 +411,8 @@ func (d *productDomain) scanRow(
 		&product.ProductImage,
 		&product.ProductType,
 		&product.Stock,
+		&product.TaxClassID,
+		&product.PriceIncludesTax,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 	)
@@ -0,0 +1,485 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"strings"
+	"time"
+)
+
+const (
+	promotionColumns          = "id, shop_id, name, promotion_type, percent_off, amount_off, buy_quantity, get_quantity, product_id, category_id, coupon_code, usage_limit, usage_count, starts_at, ends_at, is_active, created_at, updated_at"
+	createPromotionSQL        = "INSERT INTO promotions (shop_id, name, promotion_type, percent_off, amount_off, buy_quantity, get_quantity, product_id, category_id, coupon_code, usage_limit, starts_at, ends_at, is_active, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING(id)"
+	updatePromotionSQL        = "UPDATE promotions SET name = $1, usage_limit = $2, starts_at = $3, ends_at = $4, is_active = $5, updated_at = $6 WHERE id = $7 AND shop_id = $8"
+	getPromotionsSQL          = "SELECT " + promotionColumns + " FROM promotions"
+	getPromotionByIDSQL       = getPromotionsSQL + " WHERE id = $1 AND shop_id = $2"
+	getPromotionsCountSQL     = "SELECT COUNT(id) FROM promotions"
+	getPromotionByCouponSQL   = getPromotionsSQL + " WHERE shop_id = $1 AND UPPER(coupon_code) = UPPER($2)"
+	getAutomaticPromotionsSQL = getPromotionsSQL + ` WHERE shop_id = $1 AND coupon_code IS NULL AND is_active
+		AND (starts_at IS NULL OR starts_at <= $2) AND (ends_at IS NULL OR ends_at > $2)
+		AND (usage_limit IS NULL OR usage_count < usage_limit)
+		ORDER BY id`
+	// the usage limit is enforced here, against the row as it is when the update runs,
+	// so two orders racing for the last use cannot both get it
+	incrementPromotionUsageSQL   = "UPDATE promotions SET usage_count = usage_count + 1, updated_at = $1 WHERE id = $2 AND (usage_limit IS NULL OR usage_count < usage_limit) RETURNING usage_count"
+	createPromotionRedemptionSQL = "INSERT INTO promotion_redemptions (promotion_id, order_id, coupon_code, discount, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING(id)"
+	// the uses a cancelled order took go back, so a coupon limited to a few orders is not
+	// used up by orders that never went through
+	releaseOrderPromotionsSQL = `WITH released AS (DELETE FROM promotion_redemptions WHERE order_id = $1 RETURNING promotion_id)
+		UPDATE promotions p SET usage_count = p.usage_count - 1, updated_at = $2
+		FROM released r WHERE p.id = r.promotion_id`
+	createOrderItemPromotionSQL = "INSERT INTO order_item_promotions (order_item_id, promotion_id, discount, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING(id)"
+	getOrderItemPromotionsSQL   = `SELECT oip.id, oip.order_item_id, oip.promotion_id, p.name, p.promotion_type, p.coupon_code, oip.discount, oip.created_at, oip.updated_at
+		FROM order_item_promotions oip
+		INNER JOIN promotions p ON p.id = oip.promotion_id
+		INNER JOIN order_items oi ON oi.id = oip.order_item_id
+		WHERE oi.order_id = $1 AND p.shop_id = $2
+		ORDER BY oip.id`
+)
+
+type (
+	PromotionDomain interface {
+		CreatePromotion(ctx context.Context, operations db.SQLOperations, promotion *models.Promotion) error
+		PromotionByID(ctx context.Context, operations db.SQLOperations, shopID string, promotionID int64) (*models.Promotion, error)
+		PromotionByCouponCode(ctx context.Context, operations db.SQLOperations, shopID string, couponCode string) (*models.Promotion, error)
+		AutomaticPromotions(ctx context.Context, operations db.SQLOperations, shopID string, now time.Time) ([]*models.Promotion, error)
+		ListShopPromotions(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Promotion, error)
+		ShopPromotionsCount(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) (int, error)
+		RedeemPromotion(ctx context.Context, operations db.SQLOperations, redemption *models.PromotionRedemption) error
+		ReleaseOrderPromotions(ctx context.Context, operations db.SQLOperations, orderID int64) error
+		CreateOrderItemPromotions(ctx context.Context, operations db.SQLOperations, orderItemPromotions []*models.OrderItemPromotion) error
+		OrderItemPromotions(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) ([]*models.OrderItemPromotion, error)
+	}
+
+	promotionDomain struct{}
+)
+
+func NewPromotionDomain() PromotionDomain {
+	return &promotionDomain{}
+}
+
+func (d *promotionDomain) CreatePromotion(
+	ctx context.Context,
+	operations db.SQLOperations,
+	promotion *models.Promotion,
+) error {
+
+	promotion.Touch()
+	if promotion.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createPromotionSQL,
+			promotion.ShopID,
+			promotion.Name,
+			promotion.PromotionType,
+			promotion.PercentOff,
+			promotion.AmountOff,
+			promotion.BuyQuantity,
+			promotion.GetQuantity,
+			promotion.ProductID,
+			promotion.CategoryID,
+			promotion.CouponCode,
+			promotion.UsageLimit,
+			promotion.StartsAt,
+			promotion.EndsAt,
+			promotion.IsActive,
+			promotion.CreatedAt,
+			promotion.UpdatedAt,
+		).Scan(&promotion.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save promotion query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	// what a promotion gives is fixed once it exists; only when and how often it runs can change
+	_, err := operations.ExecContext(
+		ctx,
+		updatePromotionSQL,
+		promotion.Name,
+		promotion.UsageLimit,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.IsActive,
+		promotion.UpdatedAt,
+		promotion.ID,
+		promotion.ShopID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update promotion exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *promotionDomain) PromotionByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	promotionID int64,
+) (*models.Promotion, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getPromotionByIDSQL,
+		promotionID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+// PromotionByCouponCode finds a shop's coupon; codes are matched without regard to case.
+func (d *promotionDomain) PromotionByCouponCode(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	couponCode string,
+) (*models.Promotion, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getPromotionByCouponSQL,
+		shopID,
+		couponCode,
+	)
+
+	return d.scanRow(row)
+}
+
+// AutomaticPromotions are the shop's promotions that need no coupon and can be used at now.
+func (d *promotionDomain) AutomaticPromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	now time.Time,
+) ([]*models.Promotion, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		getAutomaticPromotionsSQL,
+		shopID,
+		now,
+	)
+	if err != nil {
+		return []*models.Promotion{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("automatic promotions query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	return d.scanRows(rows)
+}
+
+func (d *promotionDomain) ListShopPromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	filter *models.Filter,
+) ([]*models.Promotion, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	query, args := d.buildQuery(getPromotionsSQL, filter)
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.Promotion{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("list promotions query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	return d.scanRows(rows)
+}
+
+func (d *promotionDomain) ShopPromotionsCount(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	filter *models.Filter,
+) (int, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	query, args := d.buildQuery(getPromotionsCountSQL, filter.NoPagination())
+
+	var count int
+
+	err := operations.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	).Scan(&count)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("promotions count query row err: %v", err)
+	}
+
+	return count, nil
+}
+
+// RedeemPromotion uses up one use of a promotion and records the order it went to. It
+// fails with a conflict when the usage limit has already been reached.
+func (d *promotionDomain) RedeemPromotion(
+	ctx context.Context,
+	operations db.SQLOperations,
+	redemption *models.PromotionRedemption,
+) error {
+
+	redemption.Touch()
+
+	var usageCount int64
+
+	err := operations.QueryRowContext(
+		ctx,
+		incrementPromotionUsageSQL,
+		redemption.UpdatedAt,
+		redemption.PromotionID,
+	).Scan(&usageCount)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("promotion [%d] has reached its usage limit", redemption.PromotionID),
+				apperr.Conflict,
+			)
+		}
+
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("increment promotion usage query row err: %v", err)
+	}
+
+	err = operations.QueryRowContext(
+		ctx,
+		createPromotionRedemptionSQL,
+		redemption.PromotionID,
+		redemption.OrderID,
+		redemption.CouponCode,
+		redemption.Discount,
+		redemption.CreatedAt,
+		redemption.UpdatedAt,
+	).Scan(&redemption.ID)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save promotion redemption query row err: %v", err)
+	}
+
+	return nil
+}
+
+// ReleaseOrderPromotions gives back the use of every promotion redeemed on the order.
+func (d *promotionDomain) ReleaseOrderPromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	orderID int64,
+) error {
+
+	_, err := operations.ExecContext(
+		ctx,
+		releaseOrderPromotionsSQL,
+		orderID,
+		time.Now(),
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("release order promotions exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *promotionDomain) CreateOrderItemPromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	orderItemPromotions []*models.OrderItemPromotion,
+) error {
+
+	for _, orderItemPromotion := range orderItemPromotions {
+
+		orderItemPromotion.Touch()
+
+		err := operations.QueryRowContext(
+			ctx,
+			createOrderItemPromotionSQL,
+			orderItemPromotion.OrderItemID,
+			orderItemPromotion.PromotionID,
+			orderItemPromotion.Discount,
+			orderItemPromotion.CreatedAt,
+			orderItemPromotion.UpdatedAt,
+		).Scan(&orderItemPromotion.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save order item promotion query row err: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// OrderItemPromotions returns the promotions applied to every line of an order.
+func (d *promotionDomain) OrderItemPromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+) ([]*models.OrderItemPromotion, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		getOrderItemPromotionsSQL,
+		orderID,
+		shopID,
+	)
+	if err != nil {
+		return []*models.OrderItemPromotion{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("order item promotions query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	orderItemPromotions := make([]*models.OrderItemPromotion, 0)
+
+	for rows.Next() {
+		var orderItemPromotion models.OrderItemPromotion
+
+		err := rows.Scan(
+			&orderItemPromotion.ID,
+			&orderItemPromotion.OrderItemID,
+			&orderItemPromotion.PromotionID,
+			&orderItemPromotion.Name,
+			&orderItemPromotion.PromotionType,
+			&orderItemPromotion.CouponCode,
+			&orderItemPromotion.Discount,
+			&orderItemPromotion.CreatedAt,
+			&orderItemPromotion.UpdatedAt,
+		)
+		if err != nil {
+			return []*models.OrderItemPromotion{}, apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("scan order item promotion row err: %v", err)
+		}
+
+		orderItemPromotions = append(orderItemPromotions, &orderItemPromotion)
+	}
+
+	if rows.Err() != nil {
+		return []*models.OrderItemPromotion{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("order item promotions rows err: %v", rows.Err())
+	}
+
+	return orderItemPromotions, nil
+}
+
+func (d *promotionDomain) buildQuery(
+	query string,
+	filter *models.Filter,
+) (string, []interface{}) {
+
+	args := make([]interface{}, 0)
+	conditions := make([]string, 0)
+	counter := utils.NewPlaceholder()
+
+	if filter.ShopID != nil {
+		condition := fmt.Sprintf("shop_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.ShopID))
+		conditions = append(conditions, condition)
+	}
+
+	if filter.Active != nil {
+		condition := fmt.Sprintf("is_active = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.Active))
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if filter.Page > 0 && filter.Per > 0 {
+		query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+
+	return query, args
+}
+
+func (d *promotionDomain) scanRows(
+	rows *sql.Rows,
+) ([]*models.Promotion, error) {
+
+	promotions := make([]*models.Promotion, 0)
+
+	for rows.Next() {
+		promotion, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.Promotion{}, err
+		}
+
+		promotions = append(promotions, promotion)
+	}
+
+	if rows.Err() != nil {
+		return []*models.Promotion{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("promotions rows err: %v", rows.Err())
+	}
+
+	return promotions, nil
+}
+
+func (d *promotionDomain) scanRow(
+	row db.RowScanner,
+) (*models.Promotion, error) {
+
+	var promotion models.Promotion
+
+	err := row.Scan(
+		&promotion.ID,
+		&promotion.ShopID,
+		&promotion.Name,
+		&promotion.PromotionType,
+		&promotion.PercentOff,
+		&promotion.AmountOff,
+		&promotion.BuyQuantity,
+		&promotion.GetQuantity,
+		&promotion.ProductID,
+		&promotion.CategoryID,
+		&promotion.CouponCode,
+		&promotion.UsageLimit,
+		&promotion.UsageCount,
+		&promotion.StartsAt,
+		&promotion.EndsAt,
+		&promotion.IsActive,
+		&promotion.CreatedAt,
+		&promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan promotion row err: %v", err)
+	}
+
+	return &promotion, nil
+}
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createRefundSQL            = "INSERT INTO refunds (order_id, order_return_id, payment_id, amount, payment_method, refund_status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING(id)"
+	getRefundsSQL              = "SELECT id, order_id, order_return_id, payment_id, amount, payment_method, refund_status, created_at, updated_at FROM refunds"
+	getRefundsByOrderReturnSQL = getRefundsSQL + " WHERE order_return_id = $1 AND order_id IN (SELECT id FROM orders WHERE shop_id = $2) ORDER BY id"
+	getRefundsByOrderSQL       = getRefundsSQL + " WHERE order_id = $1 AND order_id IN (SELECT id FROM orders WHERE shop_id = $2) ORDER BY id"
+)
+
+type (
+	RefundDomain interface {
+		CreateRefund(ctx context.Context, operations db.SQLOperations, refund *models.Refund) error
+		RefundsByOrderReturnID(ctx context.Context, operations db.SQLOperations, shopID string, orderReturnID int64) ([]*models.Refund, error)
+		RefundsByOrderID(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) ([]*models.Refund, error)
+	}
+
+	refundDomain struct{}
+)
+
+func NewRefundDomain() RefundDomain {
+	return &refundDomain{}
+}
+
+func (d *refundDomain) CreateRefund(
+	ctx context.Context,
+	operations db.SQLOperations,
+	refund *models.Refund,
+) error {
+
+	refund.Touch()
+
+	err := operations.QueryRowContext(
+		ctx,
+		createRefundSQL,
+		refund.OrderID,
+		refund.OrderReturnID,
+		refund.PaymentID,
+		refund.Amount,
+		refund.PaymentMethod,
+		refund.RefundStatus,
+		refund.CreatedAt,
+		refund.UpdatedAt,
+	).Scan(&refund.ID)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save refund query row err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *refundDomain) RefundsByOrderReturnID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderReturnID int64,
+) ([]*models.Refund, error) {
+
+	return d.refunds(ctx, operations, getRefundsByOrderReturnSQL, orderReturnID, shopID)
+}
+
+func (d *refundDomain) RefundsByOrderID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+) ([]*models.Refund, error) {
+
+	return d.refunds(ctx, operations, getRefundsByOrderSQL, orderID, shopID)
+}
+
+func (d *refundDomain) refunds(
+	ctx context.Context,
+	operations db.SQLOperations,
+	query string,
+	args ...interface{},
+) ([]*models.Refund, error) {
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.Refund{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("refunds query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	refunds := make([]*models.Refund, 0)
+
+	for rows.Next() {
+		refund, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.Refund{}, err
+		}
+
+		refunds = append(refunds, refund)
+	}
+
+	if rows.Err() != nil {
+		return []*models.Refund{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("refunds rows err: %v", rows.Err())
+	}
+
+	return refunds, nil
+}
+
+func (d *refundDomain) scanRow(
+	row db.RowScanner,
+) (*models.Refund, error) {
+
+	var refund models.Refund
+
+	err := row.Scan(
+		&refund.ID,
+		&refund.OrderID,
+		&refund.OrderReturnID,
+		&refund.PaymentID,
+		&refund.Amount,
+		&refund.PaymentMethod,
+		&refund.RefundStatus,
+		&refund.CreatedAt,
+		&refund.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan refund row err: %v", err)
+	}
+
+	return &refund, nil
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"time"
+)
+
+const (
+	createShopSQL       = "INSERT INTO shops (id, name, currency, order_reference_prefix, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)"
+	ensureShopSQL       = "INSERT INTO shops (id, name, created_at, updated_at) VALUES ($1, $1, $2, $2) ON CONFLICT (id) DO NOTHING"
+	updateShopSQL       = "UPDATE shops SET name = $1, currency = $2, order_reference_prefix = $3, updated_at = $4 WHERE id = $5"
+	getShopsSQL         = "SELECT s.id, s.name, s.currency, s.order_reference_prefix, s.created_at, s.updated_at FROM shops s"
+	getShopByIDSQL      = getShopsSQL + " WHERE s.id = $1"
+	getShopsByUserIDSQL = getShopsSQL + " INNER JOIN shop_memberships m ON m.shop_id = s.id WHERE m.user_id = $1 ORDER BY s.id"
+)
+
+type (
+	ShopDomain interface {
+		CreateShop(ctx context.Context, operations db.SQLOperations, shop *models.Shop) error
+		EnsureShop(ctx context.Context, operations db.SQLOperations, shopID string) error
+		UpdateShop(ctx context.Context, operations db.SQLOperations, shop *models.Shop) error
+		ShopByID(ctx context.Context, operations db.SQLOperations, shopID string) (*models.Shop, error)
+		ShopsByUserID(ctx context.Context, operations db.SQLOperations, userID int64) ([]*models.Shop, error)
+	}
+
+	shopDomain struct{}
+)
+
+func NewShopDomain() ShopDomain {
+	return &shopDomain{}
+}
+
+func (d *shopDomain) CreateShop(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shop *models.Shop,
+) error {
+
+	shop.Touch()
+
+	_, err := operations.ExecContext(
+		ctx,
+		createShopSQL,
+		shop.ID,
+		shop.Name,
+		shop.Currency,
+		shop.OrderReferencePrefix,
+		shop.CreatedAt,
+		shop.UpdatedAt,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save shop exec err: %v", err)
+	}
+
+	return nil
+}
+
+// EnsureShop creates a shop named after its id unless it already exists.
+func (d *shopDomain) EnsureShop(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) error {
+
+	_, err := operations.ExecContext(
+		ctx,
+		ensureShopSQL,
+		shopID,
+		time.Now(),
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("ensure shop exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *shopDomain) UpdateShop(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shop *models.Shop,
+) error {
+
+	shop.Touch()
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateShopSQL,
+		shop.Name,
+		shop.Currency,
+		shop.OrderReferencePrefix,
+		shop.UpdatedAt,
+		shop.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update shop exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *shopDomain) ShopByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) (*models.Shop, error) {
+
+	row := operations.QueryRowContext(ctx, getShopByIDSQL, shopID)
+	return d.scanRow(row)
+}
+
+// ShopsByUserID lists the shops a user works in.
+func (d *shopDomain) ShopsByUserID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	userID int64,
+) ([]*models.Shop, error) {
+
+	rows, err := operations.QueryContext(ctx, getShopsByUserIDSQL, userID)
+	if err != nil {
+		return []*models.Shop{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("shops by user query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	shops := make([]*models.Shop, 0)
+
+	for rows.Next() {
+		shop, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.Shop{}, err
+		}
+
+		shops = append(shops, shop)
+	}
+
+	if rows.Err() != nil {
+		return []*models.Shop{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("shops by user rows err: %v", rows.Err())
+	}
+
+	return shops, nil
+}
+
+func (d *shopDomain) scanRow(
+	row db.RowScanner,
+) (*models.Shop, error) {
+
+	var shop models.Shop
+
+	err := row.Scan(
+		&shop.ID,
+		&shop.Name,
+		&shop.Currency,
+		&shop.OrderReferencePrefix,
+		&shop.CreatedAt,
+		&shop.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan shop row err: %v", err)
+	}
+
+	return &shop, nil
+}
@@ -0,0 +1,195 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createShopMembershipSQL        = "INSERT INTO shop_memberships (user_id, shop_id, role, created_by, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING(id)"
+	updateShopMembershipSQL        = "UPDATE shop_memberships SET role = $1, updated_at = $2 WHERE id = $3"
+	deleteShopMembershipSQL        = "DELETE FROM shop_memberships WHERE id = $1"
+	getShopMembershipsSQL          = "SELECT m.id, m.user_id, m.shop_id, m.role, m.created_by, u.email, u.name, m.created_at, m.updated_at FROM shop_memberships m JOIN users u ON u.id = m.user_id"
+	getShopMembershipSQL           = getShopMembershipsSQL + " WHERE m.user_id = $1 AND m.shop_id = $2"
+	getShopMembershipsByUserIDSQL  = getShopMembershipsSQL + " WHERE m.user_id = $1 ORDER BY m.shop_id"
+	getShopMembershipsByShopIDSQL  = getShopMembershipsSQL + " WHERE m.shop_id = $1 ORDER BY m.id"
+	lockShopMembershipsByShopIDSQL = getShopMembershipsByShopIDSQL + " FOR UPDATE OF m"
+)
+
+type (
+	ShopMembershipDomain interface {
+		CreateShopMembership(ctx context.Context, operations db.SQLOperations, membership *models.ShopMembership) error
+		DeleteShopMembership(ctx context.Context, operations db.SQLOperations, membershipID int64) error
+		ShopMembership(ctx context.Context, operations db.SQLOperations, userID int64, shopID string) (*models.ShopMembership, error)
+		ShopMembershipsByUserID(ctx context.Context, operations db.SQLOperations, userID int64) ([]*models.ShopMembership, error)
+		ShopMembershipsByShopID(ctx context.Context, operations db.SQLOperations, shopID string) ([]*models.ShopMembership, error)
+		LockShopMembershipsByShopID(ctx context.Context, operations db.SQLOperations, shopID string) ([]*models.ShopMembership, error)
+	}
+
+	shopMembershipDomain struct{}
+)
+
+func NewShopMembershipDomain() ShopMembershipDomain {
+	return &shopMembershipDomain{}
+}
+
+func (d *shopMembershipDomain) CreateShopMembership(
+	ctx context.Context,
+	operations db.SQLOperations,
+	membership *models.ShopMembership,
+) error {
+
+	membership.Touch()
+	if membership.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createShopMembershipSQL,
+			membership.UserID,
+			membership.ShopID,
+			membership.Role,
+			membership.CreatedBy,
+			membership.CreatedAt,
+			membership.UpdatedAt,
+		).Scan(&membership.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save shop membership query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateShopMembershipSQL,
+		membership.Role,
+		membership.UpdatedAt,
+		membership.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update shop membership exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *shopMembershipDomain) DeleteShopMembership(
+	ctx context.Context,
+	operations db.SQLOperations,
+	membershipID int64,
+) error {
+
+	_, err := operations.ExecContext(ctx, deleteShopMembershipSQL, membershipID)
+	if err != nil {
+		return apperr.NewDatabaseError(err).LogErrorMessage("delete shop membership error: %v", err)
+	}
+
+	return nil
+}
+
+func (d *shopMembershipDomain) ShopMembership(
+	ctx context.Context,
+	operations db.SQLOperations,
+	userID int64,
+	shopID string,
+) (*models.ShopMembership, error) {
+
+	row := operations.QueryRowContext(ctx, getShopMembershipSQL, userID, shopID)
+	return d.scanRow(row)
+}
+
+func (d *shopMembershipDomain) ShopMembershipsByUserID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	userID int64,
+) ([]*models.ShopMembership, error) {
+
+	return d.query(ctx, operations, getShopMembershipsByUserIDSQL, userID)
+}
+
+func (d *shopMembershipDomain) ShopMembershipsByShopID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) ([]*models.ShopMembership, error) {
+
+	return d.query(ctx, operations, getShopMembershipsByShopIDSQL, shopID)
+}
+
+// LockShopMembershipsByShopID reads a shop's staff and locks their memberships, so two
+// owners cannot demote each other at once and leave the shop with none.
+func (d *shopMembershipDomain) LockShopMembershipsByShopID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) ([]*models.ShopMembership, error) {
+
+	return d.query(ctx, operations, lockShopMembershipsByShopIDSQL, shopID)
+}
+
+func (d *shopMembershipDomain) query(
+	ctx context.Context,
+	operations db.SQLOperations,
+	query string,
+	args ...interface{},
+) ([]*models.ShopMembership, error) {
+
+	rows, err := operations.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []*models.ShopMembership{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("shop memberships query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	memberships := make([]*models.ShopMembership, 0)
+
+	for rows.Next() {
+		membership, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.ShopMembership{}, err
+		}
+
+		memberships = append(memberships, membership)
+	}
+
+	if rows.Err() != nil {
+		return []*models.ShopMembership{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("shop memberships rows err: %v", rows.Err())
+	}
+
+	return memberships, nil
+}
+
+func (d *shopMembershipDomain) scanRow(
+	row db.RowScanner,
+) (*models.ShopMembership, error) {
+
+	var membership models.ShopMembership
+
+	err := row.Scan(
+		&membership.ID,
+		&membership.UserID,
+		&membership.ShopID,
+		&membership.Role,
+		&membership.CreatedBy,
+		&membership.Email,
+		&membership.Name,
+		&membership.CreatedAt,
+		&membership.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan shop membership row err: %v", err)
+	}
+
+	return &membership, nil
+}
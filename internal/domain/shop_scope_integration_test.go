@@ -0,0 +1,232 @@
+//go:build integration
+
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+)
+
+// These run against a migrated database:
+//
+//	TEST_DATABASE_URL=postgres://... go test -tags integration ./internal/domain/
+//
+// Everything happens in one transaction that is rolled back, so nothing is left behind.
+
+type txWrapper struct {
+	*sql.Tx
+}
+
+func (w txWrapper) ValidForPostgres() bool { return true }
+
+type shopRecords struct {
+	category  *models.Category
+	customer  *models.Customer
+	product   *models.Product
+	order     *models.Order
+	promotion *models.Promotion
+	taxClass  *models.TaxClass
+}
+
+func integrationTx(t *testing.T) txWrapper {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	sqlDB, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatalf("begin test transaction: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+
+	return txWrapper{tx}
+}
+
+func createShopRecords(t *testing.T, ctx context.Context, operations txWrapper, shopID string) *shopRecords {
+	store := NewStore()
+
+	assert.NoError(t, store.ShopDomain.CreateShop(ctx, operations, &models.Shop{ID: shopID, Name: shopID}))
+
+	records := &shopRecords{
+		category: &models.Category{Name: "Drinks", ShopID: null.NullValue(shopID)},
+		customer: &models.Customer{
+			Name:         "Wanjiru",
+			Email:        "wanjiru@example.com",
+			PhoneNumber:  "+254700000001",
+			CustomerType: custom_types.CustomerTypeIndividual,
+			ShopID:       shopID,
+		},
+	}
+
+	assert.NoError(t, store.CategoryDomain.CreateCategory(ctx, operations, records.category))
+	// the same customer may shop at both
+	assert.NoError(t, store.CustomerDomain.CreateCustomer(ctx, operations, records.customer))
+
+	records.product = &models.Product{
+		ShopID:         shopID,
+		Name:           "Soda",
+		WholesalePrice: custom_types.NewMoney(4000),
+		RetailPrice:    custom_types.NewMoney(6000),
+		CategoryID:     records.category.ID,
+		Stock:          10,
+		ProductType:    custom_types.ProductTypeGoods,
+	}
+	assert.NoError(t, store.ProductDomain.CreateProduct(ctx, operations, records.product))
+
+	records.order = &models.Order{
+		ReferenceNumber: shopID + "-ORD-1",
+		PhoneNumber:     records.customer.PhoneNumber,
+		OrderStatus:     custom_types.OrderStatusPending,
+		OrderMedium:     custom_types.OrderMediumOnline,
+		PaymentMethod:   custom_types.PaymentMethodCash,
+		CustomerID:      null.NullValue(records.customer.ID),
+		ShopID:          shopID,
+		TotalItems:      1,
+		Subtotal:        custom_types.NewMoney(6000),
+		TotalAmount:     custom_types.NewMoney(6000),
+	}
+	assert.NoError(t, store.OrderDomain.CreateOrder(ctx, operations, records.order))
+
+	records.promotion = &models.Promotion{
+		ShopID:        shopID,
+		Name:          "Ten off",
+		PromotionType: custom_types.PromotionTypePercentage,
+		PercentOff:    1000,
+		IsActive:      true,
+	}
+	assert.NoError(t, store.PromotionDomain.CreatePromotion(ctx, operations, records.promotion))
+
+	// both shops use the same code for a class of their own
+	records.taxClass = &models.TaxClass{
+		ShopID:    null.NullValue(shopID),
+		Code:      "OWN8",
+		Name:      "Own 8%",
+		Treatment: custom_types.TaxTreatmentStandard,
+		Rate:      800,
+	}
+	assert.NoError(t, store.TaxClassDomain.CreateTaxClass(ctx, operations, records.taxClass))
+
+	return records
+}
+
+func assertNoRows(t *testing.T, err error) {
+	t.Helper()
+	assert.True(t, apperr.IsNoRowsErr(err), "expected no rows, got %v", err)
+}
+
+func TestShopScope_CannotReadAnotherShopsRecords(t *testing.T) {
+	ctx := context.Background()
+	operations := integrationTx(t)
+	store := NewStore()
+
+	shopA := createShopRecords(t, ctx, operations, "it-shop-a")
+	createShopRecords(t, ctx, operations, "it-shop-b")
+
+	order, err := store.OrderDomain.OrderByID(ctx, operations, "it-shop-a", shopA.order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "it-shop-a", order.ShopID)
+
+	_, err = store.OrderDomain.OrderByID(ctx, operations, "it-shop-b", shopA.order.ID)
+	assertNoRows(t, err)
+
+	_, err = store.OrderDomain.LockOrderByID(ctx, operations, "it-shop-b", shopA.order.ID)
+	assertNoRows(t, err)
+
+	_, err = store.CustomerDomain.CustomerByID(ctx, operations, "it-shop-b", shopA.customer.ID)
+	assertNoRows(t, err)
+
+	_, err = store.CategoryDomain.CategoryByID(ctx, operations, "it-shop-b", shopA.category.ID)
+	assertNoRows(t, err)
+
+	product, err := store.ProductDomain.ProductByID(ctx, operations, "it-shop-a", shopA.product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "it-shop-a", product.ShopID)
+
+	_, err = store.ProductDomain.ProductByID(ctx, operations, "it-shop-b", shopA.product.ID)
+	assertNoRows(t, err)
+
+	_, err = store.PromotionDomain.PromotionByID(ctx, operations, "it-shop-b", shopA.promotion.ID)
+	assertNoRows(t, err)
+
+	payments, err := store.PaymentDomain.PaymentsByOrderID(ctx, operations, "it-shop-b", shopA.order.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, payments)
+
+	orderItems, err := store.OrderItemDomain.OrderItems(ctx, operations, "it-shop-b", shopA.order.ID, &models.Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, orderItems)
+
+	_, err = store.TaxClassDomain.TaxClassByID(ctx, operations, "it-shop-b", shopA.taxClass.ID)
+	assertNoRows(t, err)
+
+	taxClasses, err := store.TaxClassDomain.ListTaxClasses(ctx, operations, "it-shop-b")
+	assert.NoError(t, err)
+	codes := make([]string, 0, len(taxClasses))
+	for _, taxClass := range taxClasses {
+		assert.NotEqual(t, shopA.taxClass.ID, taxClass.ID)
+		codes = append(codes, taxClass.Code)
+	}
+	// the shared classes are everyone's
+	assert.Contains(t, codes, "VAT16")
+	assert.Contains(t, codes, "OWN8")
+}
+
+func TestShopScope_CannotChangeAnotherShopsRecords(t *testing.T) {
+	ctx := context.Background()
+	operations := integrationTx(t)
+	store := NewStore()
+
+	shopA := createShopRecords(t, ctx, operations, "it-shop-a")
+	createShopRecords(t, ctx, operations, "it-shop-b")
+
+	// shop B claims shop A's order and customer as its own
+	order := *shopA.order
+	order.ShopID = "it-shop-b"
+	order.OrderStatus = custom_types.OrderStatusCancelled
+	assert.NoError(t, store.OrderDomain.CreateOrder(ctx, operations, &order))
+
+	customer := *shopA.customer
+	customer.ShopID = "it-shop-b"
+	customer.Name = "Someone else"
+	assert.NoError(t, store.CustomerDomain.CreateCustomer(ctx, operations, &customer))
+
+	stock, err := store.ProductDomain.AdjustStock(ctx, operations, "it-shop-b", shopA.product.ID, -10)
+	assertNoRows(t, err)
+	assert.Zero(t, stock)
+
+	assert.NoError(t, store.ProductDomain.DeleteProduct(ctx, operations, "it-shop-b", shopA.product.ID))
+	assert.NoError(t, store.CategoryDomain.DeleteCategory(ctx, operations, "it-shop-b", shopA.category.ID))
+
+	// shop A's records are untouched
+	savedOrder, err := store.OrderDomain.OrderByID(ctx, operations, "it-shop-a", shopA.order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.OrderStatusPending, savedOrder.OrderStatus)
+
+	savedCustomer, err := store.CustomerDomain.CustomerByID(ctx, operations, "it-shop-a", shopA.customer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Wanjiru", savedCustomer.Name)
+
+	savedProduct, err := store.ProductDomain.ProductByID(ctx, operations, "it-shop-a", shopA.product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), savedProduct.Stock)
+
+	_, err = store.CategoryDomain.CategoryByID(ctx, operations, "it-shop-a", shopA.category.ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,249 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"strings"
+)
+
+const (
+	createSMSMessageSQL          = "INSERT INTO sms_messages (shop_id, order_id, customer_id, notification_id, phone_number, provider, message_id, cost, status, failure_reason, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING(id)"
+	updateSMSMessageSQL          = "UPDATE sms_messages SET status = $1, failure_reason = $2, updated_at = $3 WHERE id = $4"
+	getSMSMessagesSQL            = "SELECT id, shop_id, order_id, customer_id, notification_id, phone_number, provider, message_id, cost, status, failure_reason, created_at, updated_at FROM sms_messages"
+	getSMSMessagesCountSQL       = "SELECT COUNT(id) FROM sms_messages"
+	lockSMSMessageByMessageIDSQL = getSMSMessagesSQL + " WHERE message_id = $1 FOR UPDATE"
+)
+
+type (
+	SMSMessageDomain interface {
+		CreateSMSMessage(ctx context.Context, operations db.SQLOperations, smsMessage *models.SMSMessage) error
+		LockSMSMessageByMessageID(ctx context.Context, operations db.SQLOperations, messageID string) (*models.SMSMessage, error)
+		ListSMSMessages(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) ([]*models.SMSMessage, error)
+		SMSMessagesCount(ctx context.Context, operations db.SQLOperations, shopID string, filter *models.Filter) (int, error)
+	}
+
+	smsMessageDomain struct{}
+)
+
+func NewSMSMessageDomain() SMSMessageDomain {
+	return &smsMessageDomain{}
+}
+
+func (d *smsMessageDomain) CreateSMSMessage(
+	ctx context.Context,
+	operations db.SQLOperations,
+	smsMessage *models.SMSMessage,
+) error {
+
+	smsMessage.Touch()
+	if smsMessage.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createSMSMessageSQL,
+			smsMessage.ShopID,
+			smsMessage.OrderID,
+			smsMessage.CustomerID,
+			smsMessage.NotificationID,
+			smsMessage.PhoneNumber,
+			smsMessage.Provider,
+			smsMessage.MessageID,
+			smsMessage.Cost,
+			smsMessage.Status,
+			smsMessage.FailureReason,
+			smsMessage.CreatedAt,
+			smsMessage.UpdatedAt,
+		).Scan(&smsMessage.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save sms message query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateSMSMessageSQL,
+		smsMessage.Status,
+		smsMessage.FailureReason,
+		smsMessage.UpdatedAt,
+		smsMessage.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update sms message exec err: %v", err)
+	}
+
+	return nil
+}
+
+// LockSMSMessageByMessageID finds a message by the id the SMS provider gave it. It is not
+// scoped to a shop: delivery reports come in for the whole account, and the message id
+// alone says which shop the message belongs to.
+func (d *smsMessageDomain) LockSMSMessageByMessageID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	messageID string,
+) (*models.SMSMessage, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		lockSMSMessageByMessageIDSQL,
+		messageID,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *smsMessageDomain) ListSMSMessages(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	filter *models.Filter,
+) ([]*models.SMSMessage, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	query, args := d.buildQuery(getSMSMessagesSQL, filter)
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.SMSMessage{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("sms messages query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	smsMessages := make([]*models.SMSMessage, 0)
+
+	for rows.Next() {
+		smsMessage, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.SMSMessage{}, err
+		}
+
+		smsMessages = append(smsMessages, smsMessage)
+	}
+
+	if rows.Err() != nil {
+		return []*models.SMSMessage{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list sms messages err: %v", rows.Err())
+	}
+
+	return smsMessages, nil
+}
+
+func (d *smsMessageDomain) SMSMessagesCount(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	filter *models.Filter,
+) (int, error) {
+
+	filter.ShopID = null.NullValue(shopID)
+	query, args := d.buildQuery(getSMSMessagesCountSQL, filter.NoPagination())
+
+	row := operations.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	)
+
+	var count int
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("sms messages count query row err: %v", err)
+	}
+
+	return count, nil
+}
+
+func (d *smsMessageDomain) buildQuery(
+	query string,
+	filter *models.Filter,
+) (string, []interface{}) {
+
+	args := make([]interface{}, 0)
+	conditions := make([]string, 0)
+	counter := utils.NewPlaceholder()
+
+	if filter.ShopID != nil {
+		condition := fmt.Sprintf("shop_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.ShopID))
+		conditions = append(conditions, condition)
+	}
+
+	if filter.OrderID != nil {
+		condition := fmt.Sprintf("order_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.OrderID))
+		conditions = append(conditions, condition)
+	}
+
+	if filter.CustomerID != nil {
+		condition := fmt.Sprintf("customer_id = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.CustomerID))
+		conditions = append(conditions, condition)
+	}
+
+	if filter.Status != nil && *filter.Status != "" {
+		condition := fmt.Sprintf("status = $%d", counter.Touch())
+		args = append(args, null.ValueFromNull(filter.Status))
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if filter.Page > 0 && filter.Per > 0 {
+		query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+
+	return query, args
+}
+
+func (d *smsMessageDomain) scanRow(
+	row db.RowScanner,
+) (*models.SMSMessage, error) {
+
+	var smsMessage models.SMSMessage
+
+	err := row.Scan(
+		&smsMessage.ID,
+		&smsMessage.ShopID,
+		&smsMessage.OrderID,
+		&smsMessage.CustomerID,
+		&smsMessage.NotificationID,
+		&smsMessage.PhoneNumber,
+		&smsMessage.Provider,
+		&smsMessage.MessageID,
+		&smsMessage.Cost,
+		&smsMessage.Status,
+		&smsMessage.FailureReason,
+		&smsMessage.CreatedAt,
+		&smsMessage.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan sms message row err: %v", err)
+	}
+
+	return &smsMessage, nil
+}
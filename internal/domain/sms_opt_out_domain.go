@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createSMSOptOutSQL           = "INSERT INTO sms_opt_outs (phone_number, keyword, created_at, updated_at) VALUES ($1, $2, $3, $4) ON CONFLICT (phone_number) DO NOTHING"
+	deleteSMSOptOutSQL           = "DELETE FROM sms_opt_outs WHERE phone_number = $1"
+	getSMSOptOutByPhoneNumberSQL = "SELECT phone_number, keyword, created_at, updated_at FROM sms_opt_outs WHERE phone_number = $1"
+)
+
+type (
+	SMSOptOutDomain interface {
+		CreateSMSOptOut(ctx context.Context, operations db.SQLOperations, smsOptOut *models.SMSOptOut) error
+		DeleteSMSOptOut(ctx context.Context, operations db.SQLOperations, phoneNumber string) error
+		SMSOptOutByPhoneNumber(ctx context.Context, operations db.SQLOperations, phoneNumber string) (*models.SMSOptOut, error)
+	}
+
+	smsOptOutDomain struct{}
+)
+
+func NewSMSOptOutDomain() SMSOptOutDomain {
+	return &smsOptOutDomain{}
+}
+
+func (d *smsOptOutDomain) CreateSMSOptOut(
+	ctx context.Context,
+	operations db.SQLOperations,
+	smsOptOut *models.SMSOptOut,
+) error {
+
+	smsOptOut.Touch()
+
+	_, err := operations.ExecContext(
+		ctx,
+		createSMSOptOutSQL,
+		smsOptOut.PhoneNumber,
+		smsOptOut.Keyword,
+		smsOptOut.CreatedAt,
+		smsOptOut.UpdatedAt,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save sms opt out query err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *smsOptOutDomain) DeleteSMSOptOut(
+	ctx context.Context,
+	operations db.SQLOperations,
+	phoneNumber string,
+) error {
+
+	_, err := operations.ExecContext(
+		ctx,
+		deleteSMSOptOutSQL,
+		phoneNumber,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("delete sms opt out query err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *smsOptOutDomain) SMSOptOutByPhoneNumber(
+	ctx context.Context,
+	operations db.SQLOperations,
+	phoneNumber string,
+) (*models.SMSOptOut, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getSMSOptOutByPhoneNumberSQL,
+		phoneNumber,
+	)
+
+	var smsOptOut models.SMSOptOut
+
+	err := row.Scan(
+		&smsOptOut.PhoneNumber,
+		&smsOptOut.Keyword,
+		&smsOptOut.CreatedAt,
+		&smsOptOut.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan sms opt out row err: %v", err)
+	}
+
+	return &smsOptOut, nil
+}
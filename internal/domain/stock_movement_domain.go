@@ -0,0 +1,229 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"strings"
+)
+
+const (
+	createStockMovementSQL    = "INSERT INTO stock_movements (product_id, movement_type, quantity, stock_after, reason, actor, order_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING(id)"
+	getStockMovementsSQL      = "SELECT id, product_id, movement_type, quantity, stock_after, reason, actor, order_id, created_at, updated_at FROM stock_movements"
+	getStockMovementsCountSQL = "SELECT COUNT(id) FROM stock_movements"
+	getLedgerStockSQL         = "SELECT COALESCE(SUM(quantity), 0) FROM stock_movements WHERE product_id = $1 AND product_id IN (SELECT id FROM products WHERE shop_id = $2)"
+)
+
+type (
+	StockMovementDomain interface {
+		CreateStockMovement(ctx context.Context, operations db.SQLOperations, movement *models.StockMovement) error
+		ListProductStockMovements(ctx context.Context, operations db.SQLOperations, shopID string, productID int64, filter *models.Filter) ([]*models.StockMovement, error)
+		ProductStockMovementsCount(ctx context.Context, operations db.SQLOperations, shopID string, productID int64, filter *models.Filter) (int, error)
+		LedgerStock(ctx context.Context, operations db.SQLOperations, shopID string, productID int64) (int64, error)
+	}
+
+	stockMovementDomain struct{}
+)
+
+func NewStockMovementDomain() StockMovementDomain {
+	return &stockMovementDomain{}
+}
+
+func (d *stockMovementDomain) CreateStockMovement(
+	ctx context.Context,
+	operations db.SQLOperations,
+	movement *models.StockMovement,
+) error {
+
+	movement.Touch()
+
+	err := operations.QueryRowContext(
+		ctx,
+		createStockMovementSQL,
+		movement.ProductID,
+		movement.MovementType,
+		movement.Quantity,
+		movement.StockAfter,
+		movement.Reason,
+		movement.Actor,
+		movement.OrderID,
+		movement.CreatedAt,
+		movement.UpdatedAt,
+	).Scan(&movement.ID)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save stock movement query row err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *stockMovementDomain) ListProductStockMovements(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	productID int64,
+	filter *models.Filter,
+) ([]*models.StockMovement, error) {
+
+	query, args := d.buildQuery(getStockMovementsSQL, shopID, productID, filter)
+
+	rows, err := operations.QueryContext(
+		ctx,
+		query,
+		args...,
+	)
+	if err != nil {
+		return []*models.StockMovement{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("stock movements query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	movements := make([]*models.StockMovement, 0)
+
+	for rows.Next() {
+		movement, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.StockMovement{}, err
+		}
+
+		movements = append(movements, movement)
+	}
+
+	if rows.Err() != nil {
+		return []*models.StockMovement{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list stock movements err: %v", rows.Err())
+	}
+
+	return movements, nil
+}
+
+func (d *stockMovementDomain) ProductStockMovementsCount(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	productID int64,
+	filter *models.Filter,
+) (int, error) {
+
+	query, args := d.buildQuery(getStockMovementsCountSQL, shopID, productID, filter.NoPagination())
+
+	row := operations.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	)
+
+	var count int
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("stock movements count query row err: %v", err)
+	}
+
+	return count, nil
+}
+
+// LedgerStock sums a product's movements, which is the stock the ledger says it should have.
+func (d *stockMovementDomain) LedgerStock(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	productID int64,
+) (int64, error) {
+
+	var stock int64
+
+	err := operations.QueryRowContext(
+		ctx,
+		getLedgerStockSQL,
+		productID,
+		shopID,
+	).Scan(&stock)
+	if err != nil {
+		return 0, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("ledger stock query row err: %v", err)
+	}
+
+	return stock, nil
+}
+
+func (d *stockMovementDomain) buildQuery(
+	query string,
+	shopID string,
+	productID int64,
+	filter *models.Filter,
+) (string, []interface{}) {
+
+	args := make([]interface{}, 0)
+	conditions := make([]string, 0)
+	counter := utils.NewPlaceholder()
+
+	conditions = append(conditions, fmt.Sprintf("product_id = $%d", counter.Touch()))
+	args = append(args, productID)
+
+	conditions = append(conditions, fmt.Sprintf("product_id IN (SELECT id FROM products WHERE shop_id = $%d)", counter.Touch()))
+	args = append(args, shopID)
+
+	if filter.Type != "" {
+		conditions = append(conditions, fmt.Sprintf("movement_type = $%d", counter.Touch()))
+		args = append(args, filter.Type)
+	}
+
+	if filter.OrderID != nil {
+		conditions = append(conditions, fmt.Sprintf("order_id = $%d", counter.Touch()))
+		args = append(args, null.ValueFromNull(filter.OrderID))
+	}
+
+	if filter.FromTime != nil && filter.ToTime != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at BETWEEN $%d AND $%d", counter.Touch(), counter.Touch()))
+		args = append(args, null.ValueFromNull(filter.FromTime), null.ValueFromNull(filter.ToTime))
+	}
+
+	query += " WHERE " + strings.Join(conditions, " AND ")
+
+	if filter.Page > 0 && filter.Per > 0 {
+		query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", counter.Touch(), counter.Touch())
+		args = append(args, filter.Per, (filter.Page-1)*filter.Per)
+	}
+
+	return query, args
+}
+
+func (d *stockMovementDomain) scanRow(
+	row db.RowScanner,
+) (*models.StockMovement, error) {
+
+	var movement models.StockMovement
+
+	err := row.Scan(
+		&movement.ID,
+		&movement.ProductID,
+		&movement.MovementType,
+		&movement.Quantity,
+		&movement.StockAfter,
+		&movement.Reason,
+		&movement.Actor,
+		&movement.OrderID,
+		&movement.CreatedAt,
+		&movement.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan stock movement row err: %v", err)
+	}
+
+	return &movement, nil
+}
@@ -1,19 +1,57 @@
 package domain
 
 type Store struct {
-	CustomerDomain  CustomerDomain
-	CategoryDomain  CategoryDomain
-	ProductDomain   ProductDomain
-	OrderDomain     OrderDomain
-	OrderItemDomain OrderItemDomain
+	CustomerDomain              CustomerDomain
+	CategoryDomain              CategoryDomain
+	ProductDomain               ProductDomain
+	OrderDomain                 OrderDomain
+	OrderItemDomain             OrderItemDomain
+	OrderStatusTransitionDomain OrderStatusTransitionDomain
+	StockMovementDomain         StockMovementDomain
+	OrderReturnDomain           OrderReturnDomain
+	RefundDomain                RefundDomain
+	IdempotencyKeyDomain        IdempotencyKeyDomain
+	TaxClassDomain              TaxClassDomain
+	PromotionDomain             PromotionDomain
+	MpesaSTKRequestDomain       MpesaSTKRequestDomain
+	PaymentDomain               PaymentDomain
+	AuthSessionDomain           AuthSessionDomain
+	UserDomain                  UserDomain
+	ShopMembershipDomain        ShopMembershipDomain
+	ShopDomain                  ShopDomain
+	APIKeyDomain                APIKeyDomain
+	NotificationOutboxDomain    NotificationOutboxDomain
+	SMSMessageDomain            SMSMessageDomain
+	NotificationTemplateDomain  NotificationTemplateDomain
+	SMSOptOutDomain             SMSOptOutDomain
+	ConsentChangeDomain         ConsentChangeDomain
 }
 
 func NewStore() *Store {
 	return &Store{
-		CustomerDomain:  NewCustomerDomain(),
-		CategoryDomain:  NewCategoryDomain(),
-		ProductDomain:   NewProductDomain(),
-		OrderDomain:     NewOrderDomain(),
-		OrderItemDomain: NewOrderItemDomain(),
+		CustomerDomain:              NewCustomerDomain(),
+		CategoryDomain:              NewCategoryDomain(),
+		ProductDomain:               NewProductDomain(),
+		OrderDomain:                 NewOrderDomain(),
+		OrderItemDomain:             NewOrderItemDomain(),
+		OrderStatusTransitionDomain: NewOrderStatusTransitionDomain(),
+		StockMovementDomain:         NewStockMovementDomain(),
+		OrderReturnDomain:           NewOrderReturnDomain(),
+		RefundDomain:                NewRefundDomain(),
+		IdempotencyKeyDomain:        NewIdempotencyKeyDomain(),
+		TaxClassDomain:              NewTaxClassDomain(),
+		PromotionDomain:             NewPromotionDomain(),
+		MpesaSTKRequestDomain:       NewMpesaSTKRequestDomain(),
+		PaymentDomain:               NewPaymentDomain(),
+		AuthSessionDomain:           NewAuthSessionDomain(),
+		UserDomain:                  NewUserDomain(),
+		ShopMembershipDomain:        NewShopMembershipDomain(),
+		ShopDomain:                  NewShopDomain(),
+		APIKeyDomain:                NewAPIKeyDomain(),
+		NotificationOutboxDomain:    NewNotificationOutboxDomain(),
+		SMSMessageDomain:            NewSMSMessageDomain(),
+		NotificationTemplateDomain:  NewNotificationTemplateDomain(),
+		SMSOptOutDomain:             NewSMSOptOutDomain(),
+		ConsentChangeDomain:         NewConsentChangeDomain(),
 	}
 }
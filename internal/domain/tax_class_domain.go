@@ -0,0 +1,305 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"time"
+)
+
+const (
+	createTaxClassSQL = "INSERT INTO tax_classes (shop_id, code, name, treatment, rate_basis_points, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING(id)"
+	getTaxClassesSQL  = "SELECT id, shop_id, code, name, treatment, rate_basis_points, is_default, created_at, updated_at FROM tax_classes"
+	// a shop sees the shared classes and its own
+	getTaxClassByIDSQL = getTaxClassesSQL + " WHERE id = $1 AND (shop_id IS NULL OR shop_id = $2)"
+	listTaxClassesSQL  = getTaxClassesSQL + " WHERE shop_id IS NULL OR shop_id = $1 ORDER BY rate_basis_points DESC, code"
+
+	// the product's own class wins, then the nearest category up the tree that has one,
+	// then the default class
+	getProductTaxClassSQL = `
+		WITH RECURSIVE category_chain AS (
+			SELECT c.id, c.parent_id, c.tax_class_id, 0 AS depth
+			FROM categories c
+			INNER JOIN products p ON p.category_id = c.id
+			WHERE p.id = $1 AND p.shop_id = $2
+
+			UNION ALL
+
+			SELECT c.id, c.parent_id, c.tax_class_id, cc.depth + 1
+			FROM categories c
+			INNER JOIN category_chain cc ON c.id = cc.parent_id
+			WHERE cc.depth < 32
+		)
+		SELECT tc.id, tc.shop_id, tc.code, tc.name, tc.treatment, tc.rate_basis_points, tc.is_default, tc.created_at, tc.updated_at
+		FROM tax_classes tc
+		WHERE tc.id = COALESCE(
+			(SELECT tax_class_id FROM products WHERE id = $1 AND shop_id = $2),
+			(SELECT tax_class_id FROM category_chain WHERE tax_class_id IS NOT NULL ORDER BY depth LIMIT 1),
+			(SELECT id FROM tax_classes WHERE is_default)
+		)`
+
+	// sold lines less what came back on them, by the class and rate each line was taxed at
+	getOrderTaxSummarySQL = `
+		WITH returned AS (
+			SELECT order_item_id, SUM(refund_amount) AS gross_amount, SUM(tax_amount) AS tax_amount
+			FROM order_return_items
+			GROUP BY order_item_id
+		)
+		SELECT oi.tax_class_id, COALESCE(tc.code, ''), COALESCE(tc.name, ''), tc.treatment, oi.tax_rate,
+			SUM(oi.total_amount - COALESCE(r.gross_amount, 0)), SUM(oi.tax_amount - COALESCE(r.tax_amount, 0))
+		FROM order_items oi
+		INNER JOIN orders o ON o.id = oi.order_id
+		LEFT JOIN tax_classes tc ON tc.id = oi.tax_class_id
+		LEFT JOIN returned r ON r.order_item_id = oi.id
+		WHERE oi.order_id = $1 AND o.shop_id = $2
+		GROUP BY oi.tax_class_id, tc.code, tc.name, tc.treatment, oi.tax_rate
+		ORDER BY oi.tax_rate DESC, 2`
+
+	// sales count in the period the order was paid in, returns in the period they were taken
+	getShopTaxSummarySQL = `
+		WITH tax_lines AS (
+			SELECT oi.tax_class_id, oi.tax_rate, oi.total_amount AS gross_amount, oi.tax_amount
+			FROM order_items oi
+			INNER JOIN orders o ON o.id = oi.order_id
+			INNER JOIN (
+				SELECT order_id, MIN(created_at) AS paid_at
+				FROM order_status_transitions
+				WHERE to_status = 'paid'
+				GROUP BY order_id
+			) paid ON paid.order_id = o.id
+			WHERE o.shop_id = $1 AND o.order_status IN ('paid', 'returned') AND paid.paid_at >= $2 AND paid.paid_at < $3
+
+			UNION ALL
+
+			SELECT oi.tax_class_id, oi.tax_rate, -ori.refund_amount, -ori.tax_amount
+			FROM order_return_items ori
+			INNER JOIN order_items oi ON oi.id = ori.order_item_id
+			INNER JOIN orders o ON o.id = oi.order_id
+			WHERE o.shop_id = $1 AND ori.created_at >= $2 AND ori.created_at < $3
+		)
+		SELECT tl.tax_class_id, COALESCE(tc.code, ''), COALESCE(tc.name, ''), tc.treatment, tl.tax_rate,
+			SUM(tl.gross_amount), SUM(tl.tax_amount)
+		FROM tax_lines tl
+		LEFT JOIN tax_classes tc ON tc.id = tl.tax_class_id
+		GROUP BY tl.tax_class_id, tc.code, tc.name, tc.treatment, tl.tax_rate
+		ORDER BY tl.tax_rate DESC, 2`
+)
+
+type (
+	TaxClassDomain interface {
+		CreateTaxClass(ctx context.Context, operations db.SQLOperations, taxClass *models.TaxClass) error
+		TaxClassByID(ctx context.Context, operations db.SQLOperations, shopID string, taxClassID int64) (*models.TaxClass, error)
+		ListTaxClasses(ctx context.Context, operations db.SQLOperations, shopID string) ([]*models.TaxClass, error)
+		ProductTaxClass(ctx context.Context, operations db.SQLOperations, shopID string, productID int64) (*models.TaxClass, error)
+		OrderTaxSummary(ctx context.Context, operations db.SQLOperations, shopID string, orderID int64) ([]*models.TaxSummaryLine, error)
+		ShopTaxSummary(ctx context.Context, operations db.SQLOperations, shopID string, from, to time.Time) ([]*models.TaxSummaryLine, error)
+	}
+
+	taxClassDomain struct{}
+)
+
+func NewTaxClassDomain() TaxClassDomain {
+	return &taxClassDomain{}
+}
+
+// CreateTaxClass adds a shop's own class, which is never the default; the default is the
+// shared one the tax classes migration seeds.
+func (d *taxClassDomain) CreateTaxClass(
+	ctx context.Context,
+	operations db.SQLOperations,
+	taxClass *models.TaxClass,
+) error {
+
+	taxClass.Touch()
+
+	err := operations.QueryRowContext(
+		ctx,
+		createTaxClassSQL,
+		taxClass.ShopID,
+		taxClass.Code,
+		taxClass.Name,
+		taxClass.Treatment,
+		taxClass.Rate,
+		taxClass.CreatedAt,
+		taxClass.UpdatedAt,
+	).Scan(&taxClass.ID)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("save tax class query row err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *taxClassDomain) TaxClassByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	taxClassID int64,
+) (*models.TaxClass, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getTaxClassByIDSQL,
+		taxClassID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *taxClassDomain) ListTaxClasses(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) ([]*models.TaxClass, error) {
+
+	rows, err := operations.QueryContext(ctx, listTaxClassesSQL, shopID)
+	if err != nil {
+		return []*models.TaxClass{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("list tax classes query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	taxClasses := make([]*models.TaxClass, 0)
+
+	for rows.Next() {
+		taxClass, err := d.scanRow(rows)
+		if err != nil {
+			return []*models.TaxClass{}, err
+		}
+
+		taxClasses = append(taxClasses, taxClass)
+	}
+
+	if rows.Err() != nil {
+		return []*models.TaxClass{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("list tax classes rows err: %v", rows.Err())
+	}
+
+	return taxClasses, nil
+}
+
+// ProductTaxClass resolves the tax class a product is sold under. It returns a no rows
+// error when neither the product, its categories nor a default class say.
+func (d *taxClassDomain) ProductTaxClass(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	productID int64,
+) (*models.TaxClass, error) {
+
+	row := operations.QueryRowContext(
+		ctx,
+		getProductTaxClassSQL,
+		productID,
+		shopID,
+	)
+
+	return d.scanRow(row)
+}
+
+func (d *taxClassDomain) OrderTaxSummary(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	orderID int64,
+) ([]*models.TaxSummaryLine, error) {
+
+	return d.summaryLines(ctx, operations, getOrderTaxSummarySQL, orderID, shopID)
+}
+
+// ShopTaxSummary covers from inclusive up to to exclusive.
+func (d *taxClassDomain) ShopTaxSummary(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	from, to time.Time,
+) ([]*models.TaxSummaryLine, error) {
+
+	return d.summaryLines(ctx, operations, getShopTaxSummarySQL, shopID, from, to)
+}
+
+func (d *taxClassDomain) summaryLines(
+	ctx context.Context,
+	operations db.SQLOperations,
+	query string,
+	args ...interface{},
+) ([]*models.TaxSummaryLine, error) {
+
+	rows, err := operations.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []*models.TaxSummaryLine{}, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("tax summary query err: %v", err)
+	}
+
+	defer rows.Close()
+
+	lines := make([]*models.TaxSummaryLine, 0)
+
+	for rows.Next() {
+		var line models.TaxSummaryLine
+
+		err := rows.Scan(
+			&line.TaxClassID,
+			&line.Code,
+			&line.Name,
+			&line.Treatment,
+			&line.Rate,
+			&line.GrossAmount,
+			&line.TaxAmount,
+		)
+		if err != nil {
+			return []*models.TaxSummaryLine{}, apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("scan tax summary row err: %v", err)
+		}
+
+		line.TaxableAmount, err = line.GrossAmount.Sub(line.TaxAmount)
+		if err != nil {
+			return []*models.TaxSummaryLine{}, apperr.NewError(err)
+		}
+
+		lines = append(lines, &line)
+	}
+
+	if rows.Err() != nil {
+		return []*models.TaxSummaryLine{}, apperr.NewDatabaseError(
+			rows.Err(),
+		).LogErrorMessage("tax summary rows err: %v", rows.Err())
+	}
+
+	return lines, nil
+}
+
+func (d *taxClassDomain) scanRow(
+	row db.RowScanner,
+) (*models.TaxClass, error) {
+
+	var taxClass models.TaxClass
+
+	err := row.Scan(
+		&taxClass.ID,
+		&taxClass.ShopID,
+		&taxClass.Code,
+		&taxClass.Name,
+		&taxClass.Treatment,
+		&taxClass.Rate,
+		&taxClass.IsDefault,
+		&taxClass.CreatedAt,
+		&taxClass.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan tax class row err: %v", err)
+	}
+
+	return &taxClass, nil
+}
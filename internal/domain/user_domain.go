@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+const (
+	createUserSQL        = "INSERT INTO users (subject, email, name, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING(id)"
+	updateUserSQL        = "UPDATE users SET subject = $1, email = $2, name = $3, updated_at = $4 WHERE id = $5"
+	getUsersSQL          = "SELECT id, subject, email, name, created_at, updated_at FROM users"
+	getUserByIDSQL       = getUsersSQL + " WHERE id = $1"
+	getUserBySubjectSQL  = getUsersSQL + " WHERE subject = $1"
+	getUserByEmailSQL    = getUsersSQL + " WHERE LOWER(email) = LOWER($1)"
+	lockUserBySubjectSQL = getUserBySubjectSQL + " FOR UPDATE"
+	lockUserByEmailSQL   = getUserByEmailSQL + " FOR UPDATE"
+)
+
+type (
+	UserDomain interface {
+		CreateUser(ctx context.Context, operations db.SQLOperations, user *models.User) error
+		UserByID(ctx context.Context, operations db.SQLOperations, userID int64) (*models.User, error)
+		UserBySubject(ctx context.Context, operations db.SQLOperations, subject string) (*models.User, error)
+		UserByEmail(ctx context.Context, operations db.SQLOperations, email string) (*models.User, error)
+		LockUserBySubject(ctx context.Context, operations db.SQLOperations, subject string) (*models.User, error)
+		LockUserByEmail(ctx context.Context, operations db.SQLOperations, email string) (*models.User, error)
+	}
+
+	userDomain struct{}
+)
+
+func NewUserDomain() UserDomain {
+	return &userDomain{}
+}
+
+func (d *userDomain) CreateUser(
+	ctx context.Context,
+	operations db.SQLOperations,
+	user *models.User,
+) error {
+
+	user.Touch()
+	if user.IsNew() {
+		err := operations.QueryRowContext(
+			ctx,
+			createUserSQL,
+			user.Subject,
+			user.Email,
+			user.Name,
+			user.CreatedAt,
+			user.UpdatedAt,
+		).Scan(&user.ID)
+		if err != nil {
+			return apperr.NewDatabaseError(
+				err,
+			).LogErrorMessage("save user query row err: %v", err)
+		}
+
+		return nil
+	}
+
+	_, err := operations.ExecContext(
+		ctx,
+		updateUserSQL,
+		user.Subject,
+		user.Email,
+		user.Name,
+		user.UpdatedAt,
+		user.ID,
+	)
+	if err != nil {
+		return apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("update user exec err: %v", err)
+	}
+
+	return nil
+}
+
+func (d *userDomain) UserByID(
+	ctx context.Context,
+	operations db.SQLOperations,
+	userID int64,
+) (*models.User, error) {
+
+	row := operations.QueryRowContext(ctx, getUserByIDSQL, userID)
+	return d.scanRow(row)
+}
+
+func (d *userDomain) UserBySubject(
+	ctx context.Context,
+	operations db.SQLOperations,
+	subject string,
+) (*models.User, error) {
+
+	row := operations.QueryRowContext(ctx, getUserBySubjectSQL, subject)
+	return d.scanRow(row)
+}
+
+func (d *userDomain) UserByEmail(
+	ctx context.Context,
+	operations db.SQLOperations,
+	email string,
+) (*models.User, error) {
+
+	row := operations.QueryRowContext(ctx, getUserByEmailSQL, email)
+	return d.scanRow(row)
+}
+
+func (d *userDomain) LockUserBySubject(
+	ctx context.Context,
+	operations db.SQLOperations,
+	subject string,
+) (*models.User, error) {
+
+	row := operations.QueryRowContext(ctx, lockUserBySubjectSQL, subject)
+	return d.scanRow(row)
+}
+
+func (d *userDomain) LockUserByEmail(
+	ctx context.Context,
+	operations db.SQLOperations,
+	email string,
+) (*models.User, error) {
+
+	row := operations.QueryRowContext(ctx, lockUserByEmailSQL, email)
+	return d.scanRow(row)
+}
+
+func (d *userDomain) scanRow(
+	row db.RowScanner,
+) (*models.User, error) {
+
+	var user models.User
+
+	err := row.Scan(
+		&user.ID,
+		&user.Subject,
+		&user.Email,
+		&user.Name,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, apperr.NewDatabaseError(
+			err,
+		).LogErrorMessage("scan user row err: %v", err)
+	}
+
+	return &user, nil
+}
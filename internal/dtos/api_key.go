@@ -0,0 +1,12 @@
+package dtos
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"time"
+)
+
+type CreateAPIKeyForm struct {
+	Name      string                    `json:"name"`
+	Scopes    []custom_types.Permission `json:"scopes"`
+	ExpiresAt *time.Time                `json:"expires_at"`
+}
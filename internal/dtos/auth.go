@@ -0,0 +1,5 @@
+package dtos
+
+type RefreshTokenForm struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -1,13 +1,15 @@
 package dtos
 
 type CreateCategoryForm struct {
-	Name     string `json:"name"`
-	ParentID *int64 `json:"parent_id"`
-	ShopID   string `json:"shop_id"`
+	Name       string `json:"name"`
+	ParentID   *int64 `json:"parent_id"`
+	ShopID     string `json:"shop_id"`
+	TaxClassID *int64 `json:"tax_class_id"`
 }
 
 type UpdateCategoryForm struct {
-	Name     *string `json:"name"`
-	ParentID *int64 `json:"parent_id"`
-	ShopID   *string `json:"shop_id"`
+	Name       *string `json:"name"`
+	ParentID   *int64  `json:"parent_id"`
+	ShopID     *string `json:"shop_id"`
+	TaxClassID *int64  `json:"tax_class_id"`
 }
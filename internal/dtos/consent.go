@@ -0,0 +1,9 @@
+package dtos
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type NotificationPreferenceForm struct {
+	Preference custom_types.NotificationPreference `json:"preference"`
+	// Reason is how the customer gave or withdrew consent, e.g. "asked at the till"
+	Reason *string `json:"reason"`
+}
@@ -8,6 +8,7 @@ type CreateCustomerForm struct {
 	PhoneNumber  string                    `json:"phone_number"`
 	CustomerType custom_types.CustomerType `json:"customer_type"`
 	ShopID       string                    `json:"shop_id"`
+	Locale       custom_types.Locale       `json:"locale"`
 }
 
 type UpdateCustomerForm struct {
@@ -16,4 +17,5 @@ type UpdateCustomerForm struct {
 	PhoneNumber  *string `json:"phone_number"`
 	CustomerType *string `json:"customer_type"`
 	ShopID       *string `json:"shop_id"`
+	Locale       *string `json:"locale"`
 }
@@ -0,0 +1,15 @@
+package dtos
+
+import "github/Doris-Mwito5/savannah-pos/internal/models"
+
+type InitiateSTKPushForm struct {
+	// PhoneNumber defaults to the order's phone number.
+	PhoneNumber string `json:"phone_number"`
+}
+
+// MpesaCallbackForm is the envelope Daraja wraps an STK Push result in.
+type MpesaCallbackForm struct {
+	Body struct {
+		STKCallback models.STKCallback `json:"stkCallback"`
+	} `json:"Body"`
+}
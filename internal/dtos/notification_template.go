@@ -0,0 +1,23 @@
+package dtos
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// NotificationTemplateForm sets a shop's own wording for a notification in one language,
+// replacing any it had. Subject is for email only.
+type NotificationTemplateForm struct {
+	Event   custom_types.NotificationEvent   `json:"event"`
+	Channel custom_types.NotificationChannel `json:"channel"`
+	Locale  custom_types.Locale              `json:"locale"`
+	Subject *string                          `json:"subject"`
+	Body    string                           `json:"body"`
+}
+
+// PreviewNotificationTemplateForm renders a notification against a sample order. Without
+// a body the template the shop would send now is used.
+type PreviewNotificationTemplateForm struct {
+	Event   custom_types.NotificationEvent   `json:"event"`
+	Channel custom_types.NotificationChannel `json:"channel"`
+	Locale  custom_types.Locale              `json:"locale"`
+	Subject *string                          `json:"subject"`
+	Body    *string                          `json:"body"`
+}
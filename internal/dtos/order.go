@@ -13,23 +13,24 @@ type CreateOrderForm struct {
 	CustomerID      *int64                     `json:"customer_id"`
 	ShopID          string                     `json:"shop_id"`
 	Items           []OrderItemForm            `json:"items"`
-	TotalAmount     float64                    `json:"total_amount"`
-	Discount        *float64                    `json:"discount"`
+	TotalAmount     *custom_types.Money        `json:"total_amount"`
+	Discount        *custom_types.Money        `json:"discount"`
+	CouponCode      string                     `json:"coupon_code"`
 }
 
 type UpdateOrderForm struct {
-	PhoneNumber   *string  `json:"phone_number"`
-	OrderStatus   *string  `json:"order_status"`
-	OrderMedium   *string  `json:"order_medium"`
-	PaymentMethod *string  `json:"payment_method"`
-	TotalAmount   *float64 `json:"total_amount"`
-	Discount      *float64 `json:"discount"`
+	PhoneNumber   *string `json:"phone_number"`
+	OrderStatus   *string `json:"order_status"`
+	OrderMedium   *string `json:"order_medium"`
+	PaymentMethod *string `json:"payment_method"`
+	Reason        *string `json:"reason"`
 }
 
 type OrderItemForm struct {
-	OrderID     int64   `json:"order_id"`
-	ProductID   int64   `json:"product_id"`
-	UnitPrice   float64 `json:"unit_price"`
-	Quantity    int64   `json:"quantity"`
-	TotalAmount float64 `json:"total_amount"`
+	OrderID     int64               `json:"order_id"`
+	ProductID   int64               `json:"product_id"`
+	UnitPrice   *custom_types.Money `json:"unit_price"`
+	Quantity    int64               `json:"quantity"`
+	Discount    *custom_types.Money `json:"discount"`
+	TotalAmount *custom_types.Money `json:"total_amount"`
 }
@@ -0,0 +1,11 @@
+package dtos
+
+type CreateOrderReturnForm struct {
+	Reason string                `json:"reason"`
+	Items  []OrderReturnItemForm `json:"items"`
+}
+
+type OrderReturnItemForm struct {
+	OrderItemID int64 `json:"order_item_id"`
+	Quantity    int64 `json:"quantity"`
+}
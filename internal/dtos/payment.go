@@ -0,0 +1,14 @@
+package dtos
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type RecordPaymentsForm struct {
+	Tenders []TenderForm `json:"tenders"`
+}
+
+type TenderForm struct {
+	PaymentMethod custom_types.PaymentMethod `json:"payment_method"`
+	// Amount is what the customer handed over; cash beyond the balance comes back as change.
+	Amount            custom_types.Money `json:"amount"`
+	ProviderReference *string            `json:"provider_reference"`
+}
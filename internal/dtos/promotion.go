@@ -0,0 +1,30 @@
+package dtos
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"time"
+)
+
+type CreatePromotionForm struct {
+	ShopID        string                     `json:"shop_id"`
+	Name          string                     `json:"name"`
+	PromotionType custom_types.PromotionType `json:"promotion_type"`
+	PercentOff    int64                      `json:"percent_off"`
+	AmountOff     *custom_types.Money        `json:"amount_off"`
+	BuyQuantity   int64                      `json:"buy_quantity"`
+	GetQuantity   int64                      `json:"get_quantity"`
+	ProductID     *int64                     `json:"product_id"`
+	CategoryID    *int64                     `json:"category_id"`
+	CouponCode    *string                    `json:"coupon_code"`
+	UsageLimit    *int64                     `json:"usage_limit"`
+	StartsAt      *time.Time                 `json:"starts_at"`
+	EndsAt        *time.Time                 `json:"ends_at"`
+}
+
+type UpdatePromotionForm struct {
+	Name       *string    `json:"name"`
+	UsageLimit *int64     `json:"usage_limit"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	IsActive   *bool      `json:"is_active"`
+}
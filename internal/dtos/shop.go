@@ -0,0 +1,15 @@
+package dtos
+
+type CreateShopForm struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	Currency             string `json:"currency"`
+	OrderReferencePrefix string `json:"order_reference_prefix"`
+}
+
+type UpdateShopForm struct {
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	// OrderReferencePrefix is left alone when missing; an empty string clears it.
+	OrderReferencePrefix *string `json:"order_reference_prefix"`
+}
@@ -0,0 +1,24 @@
+package dtos
+
+// SMSDeliveryReportForm is the form Africa's Talking posts to the delivery report
+// callback for each message.
+type SMSDeliveryReportForm struct {
+	ID          string `form:"id"`
+	Status      string `form:"status"`
+	PhoneNumber string `form:"phoneNumber"`
+	NetworkCode string `form:"networkCode"`
+	// FailureReason is only sent with Failed and Rejected, e.g. "InsufficientCredit"
+	FailureReason string `form:"failureReason"`
+	RetryCount    int    `form:"retryCount"`
+}
+
+// InboundSMSForm is the form Africa's Talking posts to the incoming messages callback
+// when someone texts the shop's number.
+type InboundSMSForm struct {
+	ID     string `form:"id"`
+	From   string `form:"from"`
+	To     string `form:"to"`
+	Text   string `form:"text"`
+	Date   string `form:"date"`
+	LinkID string `form:"linkId"`
+}
@@ -0,0 +1,13 @@
+package dtos
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type AddShopMemberForm struct {
+	Email string                 `json:"email"`
+	Name  string                 `json:"name"`
+	Role  custom_types.StaffRole `json:"role"`
+}
+
+type UpdateShopMemberForm struct {
+	Role custom_types.StaffRole `json:"role"`
+}
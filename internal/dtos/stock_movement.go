@@ -0,0 +1,9 @@
+package dtos
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type CreateStockMovementForm struct {
+	MovementType custom_types.StockMovementType `json:"movement_type"`
+	Quantity     int64                          `json:"quantity"`
+	Reason       string                         `json:"reason"`
+}
@@ -0,0 +1,10 @@
+package dtos
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type CreateTaxClassForm struct {
+	Code      string                    `json:"code"`
+	Name      string                    `json:"name"`
+	Treatment custom_types.TaxTreatment `json:"treatment"`
+	Rate      int64                     `json:"rate"`
+}
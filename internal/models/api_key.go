@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"time"
+)
+
+// APIKey lets a machine client, such as a till or a back-office script, call the API for
+// one shop with only the permissions in its scopes. Only a SHA-256 of the key is kept;
+// Prefix is the start of the key, shown so staff can tell their keys apart.
+type APIKey struct {
+	custom_types.SequentialIdentifier
+	ShopID     string                   `json:"shop_id"`
+	Name       string                   `json:"name"`
+	Prefix     string                   `json:"prefix"`
+	KeyHash    string                   `json:"-"`
+	Scopes     custom_types.Permissions `json:"scopes"`
+	ExpiresAt  *time.Time               `json:"expires_at"`
+	LastUsedAt *time.Time               `json:"last_used_at"`
+	CreatedBy  string                   `json:"created_by"`
+	RevokedAt  *time.Time               `json:"revoked_at"`
+	RevokedBy  *string                  `json:"revoked_by"`
+	custom_types.Timestamps
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// CreatedAPIKey is returned once, when the key is made; the key itself cannot be shown again.
+type CreatedAPIKey struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"time"
+)
+
+// AuthSession is one login. Its refresh tokens rotate on every use; revoking the session
+// ends every access and refresh token issued for it.
+type AuthSession struct {
+	custom_types.SequentialIdentifier
+	Subject       string     `json:"subject"`
+	Email         string     `json:"email"`
+	Name          string     `json:"name"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	RevokedReason *string    `json:"revoked_reason"`
+	custom_types.Timestamps
+}
+
+func (s *AuthSession) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+type RefreshToken struct {
+	custom_types.SequentialIdentifier
+	SessionID int64      `json:"session_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	custom_types.Timestamps
+}
+
+// SessionTokens is what a client gets on login and on every refresh.
+type SessionTokens struct {
+	TokenType             string    `json:"token_type"`
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
@@ -5,7 +5,9 @@ import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
 type Category struct {
 	custom_types.SequentialIdentifier
 	Name     string  `json:"name"`
-	ParentID *int64 `json:"parent_id"`
+	ParentID *int64  `json:"parent_id"`
 	ShopID   *string `json:"shop_id"`
+	// TaxClassID is nil when the category takes its tax class from its parent.
+	TaxClassID *int64 `json:"tax_class_id"`
 	custom_types.Timestamps
 }
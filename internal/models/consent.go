@@ -0,0 +1,32 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// SMSOptOut is a phone number that texted STOP. No shop sends it an SMS until it texts
+// START.
+type SMSOptOut struct {
+	PhoneNumber string `json:"phone_number"`
+	// Keyword is what the number texted, e.g. "STOP"
+	Keyword string `json:"keyword"`
+	custom_types.Timestamps
+}
+
+// ConsentChange records a change to how someone agreed to be contacted. Changes texted in
+// from a number have no shop or customer.
+type ConsentChange struct {
+	custom_types.SequentialIdentifier
+	ShopID         *string                              `json:"shop_id"`
+	CustomerID     *int64                               `json:"customer_id"`
+	PhoneNumber    *string                              `json:"phone_number"`
+	FromPreference *custom_types.NotificationPreference `json:"from_preference"`
+	ToPreference   custom_types.NotificationPreference  `json:"to_preference"`
+	Source         custom_types.ConsentSource           `json:"source"`
+	ChangedBy      string                               `json:"changed_by"`
+	Reason         *string                              `json:"reason"`
+	custom_types.Timestamps
+}
+
+type ConsentChangeList struct {
+	ConsentChanges []*ConsentChange `json:"consent_changes"`
+	Pagination     *Pagination      `json:"pagination"`
+}
@@ -9,5 +9,9 @@ type Customer struct {
 	PhoneNumber  string                    `json:"phone_number"`
 	CustomerType custom_types.CustomerType `json:"customer_type"`
 	ShopID       string                    `json:"shop_id"`
+	// Locale is the language the customer's notifications are written in
+	Locale custom_types.Locale `json:"locale"`
+	// NotificationPreference is how the customer agreed to hear about their orders
+	NotificationPreference custom_types.NotificationPreference `json:"notification_preference"`
 	custom_types.Timestamps
 }
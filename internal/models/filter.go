@@ -26,6 +26,7 @@ type Filter struct {
 	ShopID     *string
 	CategoryID *string
 	OrderID    *int64
+	CustomerID *int64
 }
 
 func (f *Filter) ConvertTime() error {
@@ -63,6 +64,8 @@ func (f *Filter) NoPagination() *Filter {
 		Active:     f.Active,
 		ShopID:     f.ShopID,
 		CategoryID: f.CategoryID,
+		OrderID:    f.OrderID,
+		CustomerID: f.CustomerID,
 	}
 }
 
@@ -0,0 +1,22 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// IdempotencyKey remembers a request made with an Idempotency-Key header and,
+// once it has been handled, the response that was sent back. Keys are the shop's own;
+// two shops may use the same one.
+type IdempotencyKey struct {
+	custom_types.SequentialIdentifier
+	ShopID         string `json:"shop_id"`
+	Key            string `json:"key"`
+	RequestPath    string `json:"request_path"`
+	RequestHash    string `json:"request_hash"`
+	ResponseStatus *int   `json:"response_status"`
+	ResponseBody   []byte `json:"-"`
+	custom_types.Timestamps
+}
+
+// IsCompleted reports whether a response has been stored for the key.
+func (k *IdempotencyKey) IsCompleted() bool {
+	return k.ResponseStatus != nil
+}
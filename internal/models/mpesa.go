@@ -0,0 +1,81 @@
+package models
+
+import "fmt"
+
+// MpesaService holds the Daraja credentials for a paybill or till.
+type MpesaService struct {
+	BaseURL        string `json:"base_url"`
+	ConsumerKey    string `json:"consumer_key"`
+	ConsumerSecret string `json:"consumer_secret"`
+	ShortCode      string `json:"short_code"`
+	Passkey        string `json:"passkey"`
+	CallbackURL    string `json:"callback_url"`
+}
+
+// STKPushRequest is the body of Daraja's /mpesa/stkpush/v1/processrequest.
+type STKPushRequest struct {
+	BusinessShortCode string `json:"BusinessShortCode"`
+	Password          string `json:"Password"`
+	Timestamp         string `json:"Timestamp"`
+	TransactionType   string `json:"TransactionType"`
+	Amount            int64  `json:"Amount"`
+	PartyA            string `json:"PartyA"`
+	PartyB            string `json:"PartyB"`
+	PhoneNumber       string `json:"PhoneNumber"`
+	CallBackURL       string `json:"CallBackURL"`
+	AccountReference  string `json:"AccountReference"`
+	TransactionDesc   string `json:"TransactionDesc"`
+}
+
+// STKPushResponse is Daraja's answer to an STK Push. A ResponseCode of "0" means the
+// prompt went to the phone; the payment result comes later on the callback.
+type STKPushResponse struct {
+	MerchantRequestID   string `json:"MerchantRequestID"`
+	CheckoutRequestID   string `json:"CheckoutRequestID"`
+	ResponseCode        string `json:"ResponseCode"`
+	ResponseDescription string `json:"ResponseDescription"`
+	CustomerMessage     string `json:"CustomerMessage"`
+	RequestID           string `json:"requestId,omitempty"`
+	ErrorCode           string `json:"errorCode,omitempty"`
+	ErrorMessage        string `json:"errorMessage,omitempty"`
+}
+
+// STKCallback is the result Daraja posts to the callback URL.
+type STKCallback struct {
+	MerchantRequestID string               `json:"MerchantRequestID"`
+	CheckoutRequestID string               `json:"CheckoutRequestID"`
+	ResultCode        int                  `json:"ResultCode"`
+	ResultDesc        string               `json:"ResultDesc"`
+	CallbackMetadata  *STKCallbackMetadata `json:"CallbackMetadata,omitempty"`
+}
+
+type STKCallbackMetadata struct {
+	Item []STKCallbackItem `json:"Item"`
+}
+
+type STKCallbackItem struct {
+	Name  string      `json:"Name"`
+	Value interface{} `json:"Value,omitempty"`
+}
+
+// MetadataValue returns a callback metadata item as text, or "" when it is missing.
+func (c *STKCallback) MetadataValue(name string) string {
+	if c.CallbackMetadata == nil {
+		return ""
+	}
+
+	for _, item := range c.CallbackMetadata.Item {
+		if item.Name != name || item.Value == nil {
+			continue
+		}
+
+		// JSON numbers arrive as float64; receipts and amounts are whole numbers
+		if number, ok := item.Value.(float64); ok {
+			return fmt.Sprintf("%.0f", number)
+		}
+
+		return fmt.Sprint(item.Value)
+	}
+
+	return ""
+}
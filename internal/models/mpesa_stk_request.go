@@ -0,0 +1,20 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type MpesaSTKRequest struct {
+	custom_types.SequentialIdentifier
+	OrderID            int64                           `json:"order_id"`
+	ShopID             string                          `json:"-"`
+	PaymentID          *int64                          `json:"payment_id"`
+	MerchantRequestID  string                          `json:"merchant_request_id"`
+	CheckoutRequestID  string                          `json:"checkout_request_id"`
+	PhoneNumber        string                          `json:"phone_number"`
+	Amount             custom_types.Money              `json:"amount"`
+	RequestStatus      custom_types.MpesaRequestStatus `json:"request_status"`
+	ResultCode         *int64                          `json:"result_code"`
+	ResultDesc         *string                         `json:"result_desc"`
+	MpesaReceiptNumber *string                         `json:"mpesa_receipt_number"`
+	CustomerMessage    string                          `json:"customer_message,omitempty"`
+	custom_types.Timestamps
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"time"
+)
+
+// OutboxNotification is a notification written in the same transaction as the change it
+// is about, and delivered afterwards by the notification worker. Payload is a snapshot
+// of what the message needs, so it says what was true when the change was made.
+type OutboxNotification struct {
+	custom_types.SequentialIdentifier
+	ShopID  string                           `json:"shop_id"`
+	OrderID int64                            `json:"order_id"`
+	Event   custom_types.NotificationEvent   `json:"event"`
+	Channel custom_types.NotificationChannel `json:"channel"`
+	// Recipient is the customer's phone number; it is nil for email to the shop's admin
+	Recipient     *string                         `json:"recipient"`
+	Payload       json.RawMessage                 `json:"payload"`
+	Status        custom_types.NotificationStatus `json:"status"`
+	Attempts      int                             `json:"attempts"`
+	NextAttemptAt time.Time                       `json:"next_attempt_at"`
+	LastError     *string                         `json:"last_error"`
+	SentAt        *time.Time                      `json:"sent_at"`
+	custom_types.Timestamps
+}
+
+// NotificationPayload is what is kept of an order, and of a return of it, for its notifications.
+type NotificationPayload struct {
+	Order       *Order       `json:"order"`
+	OrderReturn *OrderReturn `json:"order_return,omitempty"`
+}
+
+type OutboxNotificationList struct {
+	Notifications []*OutboxNotification `json:"notifications"`
+	Pagination    *Pagination           `json:"pagination"`
+}
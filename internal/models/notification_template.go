@@ -0,0 +1,36 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// NotificationTemplate is a shop's own wording for one notification in one language.
+// SMS bodies are text/template and email bodies html/template; the subject, for email
+// only, is text/template.
+type NotificationTemplate struct {
+	custom_types.SequentialIdentifier
+	ShopID  string                           `json:"shop_id"`
+	Event   custom_types.NotificationEvent   `json:"event"`
+	Channel custom_types.NotificationChannel `json:"channel"`
+	Locale  custom_types.Locale              `json:"locale"`
+	Subject *string                          `json:"subject"`
+	Body    string                           `json:"body"`
+	custom_types.Timestamps
+}
+
+// NotificationTemplateData is what a template is rendered with. Customer is nil for
+// walk-in orders and OrderReturn is only set for returns.
+type NotificationTemplateData struct {
+	Shop        *Shop
+	Customer    *Customer
+	Order       *Order
+	OrderReturn *OrderReturn
+	Locale      custom_types.Locale
+}
+
+// RenderedNotification is a template filled in and ready to send.
+type RenderedNotification struct {
+	Event   custom_types.NotificationEvent   `json:"event"`
+	Channel custom_types.NotificationChannel `json:"channel"`
+	Locale  custom_types.Locale              `json:"locale"`
+	Subject string                           `json:"subject,omitempty"`
+	Body    string                           `json:"body"`
+}
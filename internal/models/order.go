@@ -12,7 +12,11 @@ type Order struct {
 	CustomerID      *int64                     `json:"customer_id"`
 	ShopID          string                     `json:"shop_id"`
 	TotalItems      int                        `json:"total_items"`
-	TotalAmount     float64                    `json:"total_amount"`
-	Discount        *float64                   `json:"discount"`
+	Subtotal        custom_types.Money         `json:"subtotal"`
+	LineDiscount    custom_types.Money         `json:"line_discount"`
+	Discount        custom_types.Money         `json:"discount"`
+	TaxTotal        custom_types.Money         `json:"tax_total"`
+	TotalAmount     custom_types.Money         `json:"total_amount"`
+	Items           []*OrderItem               `json:"items,omitempty"`
 	custom_types.Timestamps
 }
@@ -4,10 +4,19 @@ import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
 
 type OrderItem struct {
 	custom_types.SequentialIdentifier
-	OrderID     int64   `json:"order_id"`
-	ProductID   int64   `json:"product_id"`
-	UnitPrice   float64 `json:"unit_price"`
-	Quantity    int64   `json:"quantity"`
-	TotalAmount float64 `json:"total_amount"`
+	OrderID          int64              `json:"order_id"`
+	ProductID        int64              `json:"product_id"`
+	UnitPrice        custom_types.Money `json:"unit_price"`
+	Quantity         int64              `json:"quantity"`
+	ReturnedQuantity int64              `json:"returned_quantity"`
+	Discount         custom_types.Money `json:"discount"`
+	OrderDiscount    custom_types.Money `json:"order_discount"`
+	TaxClassID       *int64             `json:"tax_class_id"`
+	TaxRate          int64              `json:"tax_rate"`
+	PriceIncludesTax bool               `json:"price_includes_tax"`
+	TaxAmount        custom_types.Money `json:"tax_amount"`
+	TotalAmount      custom_types.Money `json:"total_amount"`
+	// Promotions are the promotions behind part of Discount.
+	Promotions []*OrderItemPromotion `json:"promotions,omitempty"`
 	custom_types.Timestamps
 }
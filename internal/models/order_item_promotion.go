@@ -0,0 +1,15 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// OrderItemPromotion is a promotion applied to an order line and the discount it gave.
+type OrderItemPromotion struct {
+	custom_types.SequentialIdentifier
+	OrderItemID   int64                      `json:"order_item_id"`
+	PromotionID   int64                      `json:"promotion_id"`
+	Name          string                     `json:"name"`
+	PromotionType custom_types.PromotionType `json:"promotion_type"`
+	CouponCode    *string                    `json:"coupon_code,omitempty"`
+	Discount      custom_types.Money         `json:"discount"`
+	custom_types.Timestamps
+}
@@ -0,0 +1,25 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type OrderReturn struct {
+	custom_types.SequentialIdentifier
+	OrderID      int64              `json:"order_id"`
+	Reason       string             `json:"reason"`
+	RefundAmount custom_types.Money `json:"refund_amount"`
+	Actor        string             `json:"actor"`
+	Items        []*OrderReturnItem `json:"items"`
+	Refunds      []*Refund          `json:"refunds"`
+	custom_types.Timestamps
+}
+
+type OrderReturnItem struct {
+	custom_types.SequentialIdentifier
+	OrderReturnID int64              `json:"order_return_id"`
+	OrderItemID   int64              `json:"order_item_id"`
+	ProductID     int64              `json:"product_id"`
+	Quantity      int64              `json:"quantity"`
+	RefundAmount  custom_types.Money `json:"refund_amount"`
+	TaxAmount     custom_types.Money `json:"tax_amount"`
+	custom_types.Timestamps
+}
@@ -0,0 +1,6 @@
+package models
+
+type OrderReturnList struct {
+	OrderReturns []*OrderReturn `json:"order_returns"`
+	Pagination   *Pagination    `json:"pagination"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type OrderStatusTransition struct {
+	custom_types.SequentialIdentifier
+	OrderID    int64                     `json:"order_id"`
+	FromStatus *custom_types.OrderStatus `json:"from_status"`
+	ToStatus   custom_types.OrderStatus  `json:"to_status"`
+	ChangedBy  string                    `json:"changed_by"`
+	Reason     *string                   `json:"reason"`
+	custom_types.Timestamps
+}
@@ -0,0 +1,6 @@
+package models
+
+type OrderStatusTransitionList struct {
+	Transitions []*OrderStatusTransition `json:"transitions"`
+	Pagination  *Pagination              `json:"pagination"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type Payment struct {
+	custom_types.SequentialIdentifier
+	OrderID           int64                      `json:"order_id"`
+	Amount            custom_types.Money         `json:"amount"`
+	AmountTendered    custom_types.Money         `json:"amount_tendered"`
+	ChangeDue         custom_types.Money         `json:"change_due"`
+	PaymentMethod     custom_types.PaymentMethod `json:"payment_method"`
+	ProviderReference *string                    `json:"provider_reference"`
+	PaymentStatus     custom_types.PaymentStatus `json:"payment_status"`
+	RecordedBy        string                     `json:"recorded_by"`
+	custom_types.Timestamps
+}
+
+// OrderPayments is where an order stands against its grand total. ChangeDue is the
+// cash handed back on the payments listed.
+type OrderPayments struct {
+	OrderID     int64                    `json:"order_id"`
+	OrderStatus custom_types.OrderStatus `json:"order_status"`
+	TotalAmount custom_types.Money       `json:"total_amount"`
+	AmountPaid  custom_types.Money       `json:"amount_paid"`
+	Balance     custom_types.Money       `json:"balance"`
+	ChangeDue   custom_types.Money       `json:"change_due"`
+	Payments    []*Payment               `json:"payments"`
+}
@@ -4,13 +4,17 @@ import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
 
 type Product struct {
 	custom_types.SequentialIdentifier
+	ShopID         string                   `json:"shop_id"`
 	Name           string                   `json:"name"`
 	Description    *string                  `json:"description,omitempty"`
-	WholesalePrice float64                  `json:"wholesale_price"`
-	RetailPrice    float64                  `json:"retail_price"`
+	WholesalePrice custom_types.Money       `json:"wholesale_price"`
+	RetailPrice    custom_types.Money       `json:"retail_price"`
 	CategoryID     int64                    `json:"category_id"`
 	ProductImage   *string                  `json:"product_image,omitempty"`
 	Stock          int64                    `json:"stock,omitempty"`
 	ProductType    custom_types.ProductType `json:"product_type"`
+	// TaxClassID is nil when the product takes its tax class from its category.
+	TaxClassID       *int64 `json:"tax_class_id"`
+	PriceIncludesTax bool   `json:"price_includes_tax"`
 	custom_types.Timestamps
 }
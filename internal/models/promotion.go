@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"time"
+)
+
+type Promotion struct {
+	custom_types.SequentialIdentifier
+	ShopID        string                     `json:"shop_id"`
+	Name          string                     `json:"name"`
+	PromotionType custom_types.PromotionType `json:"promotion_type"`
+	// PercentOff is in basis points: 1000 is 10% off.
+	PercentOff  int64              `json:"percent_off"`
+	AmountOff   custom_types.Money `json:"amount_off"`
+	BuyQuantity int64              `json:"buy_quantity"`
+	GetQuantity int64              `json:"get_quantity"`
+	// ProductID and CategoryID narrow the promotion down; with neither it covers the whole shop.
+	ProductID  *int64     `json:"product_id"`
+	CategoryID *int64     `json:"category_id"`
+	CouponCode *string    `json:"coupon_code"`
+	UsageLimit *int64     `json:"usage_limit"`
+	UsageCount int64      `json:"usage_count"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	IsActive   bool       `json:"is_active"`
+	custom_types.Timestamps
+}
+
+// AvailableAt reports whether the promotion can be used at now: it is active, inside
+// its validity window and has uses left.
+func (p *Promotion) AvailableAt(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+
+	return p.UsageLimit == nil || p.UsageCount < *p.UsageLimit
+}
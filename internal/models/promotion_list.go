@@ -0,0 +1,6 @@
+package models
+
+type PromotionList struct {
+	Promotions []*Promotion `json:"promotions"`
+	Pagination *Pagination  `json:"pagination"`
+}
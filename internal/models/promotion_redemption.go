@@ -0,0 +1,12 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type PromotionRedemption struct {
+	custom_types.SequentialIdentifier
+	PromotionID int64              `json:"promotion_id"`
+	OrderID     int64              `json:"order_id"`
+	CouponCode  *string            `json:"coupon_code"`
+	Discount    custom_types.Money `json:"discount"`
+	custom_types.Timestamps
+}
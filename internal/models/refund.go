@@ -0,0 +1,14 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type Refund struct {
+	custom_types.SequentialIdentifier
+	OrderID       int64                      `json:"order_id"`
+	OrderReturnID int64                      `json:"order_return_id"`
+	PaymentID     *int64                     `json:"payment_id"`
+	Amount        custom_types.Money         `json:"amount"`
+	PaymentMethod custom_types.PaymentMethod `json:"payment_method"`
+	RefundStatus  custom_types.RefundStatus  `json:"refund_status"`
+	custom_types.Timestamps
+}
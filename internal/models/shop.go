@@ -0,0 +1,15 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// Shop is a business selling through the POS. Everything it sells, and everyone it sells
+// to, belongs to exactly one shop.
+type Shop struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Currency is the ISO 4217 code the shop trades in; amounts are stored as shillings, so it is always "KES"
+	Currency string `json:"currency"`
+	// OrderReferencePrefix starts the shop's order references, e.g. "NBO"; blank uses the default
+	OrderReferencePrefix string `json:"order_reference_prefix"`
+	custom_types.Timestamps
+}
@@ -1,10 +1,34 @@
 package models
 
+// SMSService configures how SMS are sent. Providers are tried in order until one takes
+// the message; ApiKey, Username, BaseURL and Env are Africa's Talking's.
 type SMSService struct {
 	ApiKey   string `json:"api_key"`
 	Username string `json:"username"`
 	BaseURL  string `json:"base_url"`
 	Env      string `json:"env"`
+
+	Providers []string       `json:"providers"`
+	Twilio    *TwilioService `json:"twilio"`
+	// LogFile is where the log provider writes messages; stdout when empty
+	LogFile string `json:"log_file"`
+}
+
+// TwilioService holds the settings for a Twilio-style JSON messaging API.
+type TwilioService struct {
+	BaseURL    string `json:"base_url"`
+	AccountSID string `json:"account_sid"`
+	AuthToken  string `json:"auth_token"`
+	From       string `json:"from"`
+}
+
+// SentSMS is what a provider says about a message it accepted.
+type SentSMS struct {
+	// Provider is the name of the provider that took the message, e.g. "africastalking"
+	Provider  string `json:"provider"`
+	Number    string `json:"number"`
+	MessageID string `json:"message_id"`
+	Cost      string `json:"cost"`
 }
 
 type SMSRequest struct {
@@ -31,3 +55,25 @@ type Recipient struct {
 	MessageID    string `json:"messageId"`
 	MessageParts int64  `json:"messageParts"`
 }
+
+// TwilioMessageRequest is the body a Twilio-style API takes to send a message.
+type TwilioMessageRequest struct {
+	To   string `json:"to"`
+	From string `json:"from"`
+	Body string `json:"body"`
+}
+
+// TwilioMessageResponse is a message as a Twilio-style API describes it, or the error
+// it answered with instead.
+type TwilioMessageResponse struct {
+	SID          string  `json:"sid"`
+	To           string  `json:"to"`
+	Status       string  `json:"status"`
+	Price        *string `json:"price"`
+	PriceUnit    string  `json:"price_unit"`
+	ErrorCode    *int64  `json:"error_code"`
+	ErrorMessage *string `json:"error_message"`
+
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
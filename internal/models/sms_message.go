@@ -0,0 +1,28 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// SMSMessage is an SMS the provider accepted, kept so its delivery report can be matched
+// back by MessageID and staff can see what reached an order's customer.
+type SMSMessage struct {
+	custom_types.SequentialIdentifier
+	ShopID     string `json:"shop_id"`
+	OrderID    int64  `json:"order_id"`
+	CustomerID *int64 `json:"customer_id"`
+	// NotificationID is the outbox notification the message was sent for
+	NotificationID *int64 `json:"notification_id"`
+	PhoneNumber    string `json:"phone_number"`
+	// Provider is the SMS provider that accepted the message, e.g. "africastalking"
+	Provider  string `json:"provider"`
+	MessageID string `json:"message_id"`
+	// Cost is as the provider reported it, e.g. "KES 0.8000"
+	Cost          string                 `json:"cost"`
+	Status        custom_types.SMSStatus `json:"status"`
+	FailureReason *string                `json:"failure_reason"`
+	custom_types.Timestamps
+}
+
+type SMSMessageList struct {
+	SMSMessages []*SMSMessage `json:"sms_messages"`
+	Pagination  *Pagination   `json:"pagination"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+type StockMovement struct {
+	custom_types.SequentialIdentifier
+	ProductID    int64                          `json:"product_id"`
+	MovementType custom_types.StockMovementType `json:"movement_type"`
+	Quantity     int64                          `json:"quantity"`
+	StockAfter   int64                          `json:"stock_after"`
+	Reason       string                         `json:"reason"`
+	Actor        string                         `json:"actor"`
+	OrderID      *int64                         `json:"order_id"`
+	custom_types.Timestamps
+}
+
+// StockLevel compares a product's stored stock with the stock derived from its ledger.
+type StockLevel struct {
+	ProductID   int64 `json:"product_id"`
+	Stock       int64 `json:"stock"`
+	LedgerStock int64 `json:"ledger_stock"`
+	InSync      bool  `json:"in_sync"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type StockMovementList struct {
+	StockMovements []*StockMovement `json:"stock_movements"`
+	Pagination     *Pagination      `json:"pagination"`
+}
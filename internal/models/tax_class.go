@@ -0,0 +1,17 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// TaxClass is a VAT treatment products and categories are sold under. The classes the
+// migrations seed are shared by every shop and have no ShopID; a shop may add its own.
+type TaxClass struct {
+	custom_types.SequentialIdentifier
+	ShopID    *string                   `json:"shop_id"`
+	Code      string                    `json:"code"`
+	Name      string                    `json:"name"`
+	Treatment custom_types.TaxTreatment `json:"treatment"`
+	// Rate is in basis points: 1600 is 16%.
+	Rate      int64 `json:"rate"`
+	IsDefault bool  `json:"is_default"`
+	custom_types.Timestamps
+}
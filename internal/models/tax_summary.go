@@ -0,0 +1,31 @@
+package models
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"time"
+)
+
+// TaxSummary adds up the VAT on sales, net of returns, per tax class and rate.
+type TaxSummary struct {
+	OrderID       *int64             `json:"order_id,omitempty"`
+	ShopID        *string            `json:"shop_id,omitempty"`
+	From          *time.Time         `json:"from,omitempty"`
+	To            *time.Time         `json:"to,omitempty"`
+	Lines         []*TaxSummaryLine  `json:"lines"`
+	TaxableAmount custom_types.Money `json:"taxable_amount"`
+	TaxAmount     custom_types.Money `json:"tax_amount"`
+	GrossAmount   custom_types.Money `json:"gross_amount"`
+}
+
+// TaxSummaryLine is one tax class at one rate. Lines sold before tax classes existed
+// have no class and are reported with an empty code.
+type TaxSummaryLine struct {
+	TaxClassID    *int64                     `json:"tax_class_id"`
+	Code          string                     `json:"code"`
+	Name          string                     `json:"name"`
+	Treatment     *custom_types.TaxTreatment `json:"treatment"`
+	Rate          int64                      `json:"rate"`
+	TaxableAmount custom_types.Money         `json:"taxable_amount"`
+	TaxAmount     custom_types.Money         `json:"tax_amount"`
+	GrossAmount   custom_types.Money         `json:"gross_amount"`
+}
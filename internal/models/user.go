@@ -0,0 +1,25 @@
+package models
+
+import "github/Doris-Mwito5/savannah-pos/internal/custom_types"
+
+// User is a member of staff who logs in through the identity provider.
+type User struct {
+	custom_types.SequentialIdentifier
+	Subject *string `json:"-"`
+	Email   string  `json:"email"`
+	Name    string  `json:"name"`
+	custom_types.Timestamps
+}
+
+// ShopMembership is the role a user holds in one shop. Email and Name are read from
+// the user for listing and are not saved with the membership.
+type ShopMembership struct {
+	custom_types.SequentialIdentifier
+	UserID    int64                  `json:"user_id"`
+	ShopID    string                 `json:"shop_id"`
+	Role      custom_types.StaffRole `json:"role"`
+	CreatedBy string                 `json:"created_by"`
+	Email     string                 `json:"email"`
+	Name      string                 `json:"name"`
+	custom_types.Timestamps
+}
@@ -2,16 +2,20 @@ package notification
 
 import (
 	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/models"
 	"github/Doris-Mwito5/savannah-pos/internal/processor"
 	"log"
 	"strings"
 )
 
+// OrderNotification sends notifications about an order once their templates have been
+// rendered: the SMS or email to the customer, and the email to the shop's admin. Whether
+// the customer wants them is decided before they get here.
 type OrderNotification interface {
-	SendOrderSMS(order *models.Order) error
-	SendOrderEmail(order *models.Order) error
-	SendOrderNotifications(order *models.Order) error
+	SendOrderSMS(order *models.Order, message string) (*models.SMSMessage, error)
+	SendOrderEmail(order *models.Order, subject, body string) error
+	SendCustomerEmail(order *models.Order, to, subject, body string) error
 }
 
 type orderNotification struct {
@@ -29,43 +33,34 @@ func NewOrderNotification(
 	}
 }
 
-func (n *orderNotification) SendOrderSMS(order *models.Order) error {
-	message := fmt.Sprintf(
-		"🎉 Order Confirmed!\n"+
-			"Order ID: %d\n"+
-			"Total: $%.2f\n"+
-			"Items: %d\n"+
-			"We'll notify you when it's ready. Thank you!",
-		order.ID,
-		order.TotalAmount,
-		order.TotalItems,
-	)
+func (n *orderNotification) SendOrderSMS(order *models.Order, message string) (*models.SMSMessage, error) {
 	log.Printf("message: %s\n", message)
-	return n.sendSMSWithValidation(order.PhoneNumber, message)
+	return n.sendSMSWithValidation(order, message)
 }
 
-func (n *orderNotification) sendSMSWithValidation(phone, message string) error {
-	if !n.isValidPhoneNumber(phone) {
-		return fmt.Errorf("invalid phone number format: %s", phone)
+// sendSMSWithValidation sends message to the order's phone number and returns the SMS
+// as the provider accepted it, so its delivery report can be matched up later.
+func (n *orderNotification) sendSMSWithValidation(order *models.Order, message string) (*models.SMSMessage, error) {
+	if !n.isValidPhoneNumber(order.PhoneNumber) {
+		return nil, fmt.Errorf("invalid phone number format: %s", order.PhoneNumber)
 	}
 
-	response, err := n.smsProcessor.SendSMS(phone, message)
+	sentSMS, err := n.smsProcessor.SendSMS(order.PhoneNumber, message)
 	if err != nil {
-		return fmt.Errorf("failed to send SMS: %w", err)
+		return nil, fmt.Errorf("failed to send SMS: %w", err)
 	}
-	log.Printf("response: %v\n", response)
-
-	if err := n.smsProcessor.ValidateResponse(response); err != nil {
-		return fmt.Errorf("SMS delivery failed: %w", err)
-	}
-
-	if len(response.SMSMessageData.Recipients) > 0 {
-		recipient := response.SMSMessageData.Recipients[0]
-		fmt.Printf("✅ SMS sent to %s | ID: %s | Cost: %s\n",
-			recipient.Number, recipient.MessageID, recipient.Cost)
-	}
-
-	return nil
+	log.Printf("response: %v\n", sentSMS)
+
+	return &models.SMSMessage{
+		ShopID:      order.ShopID,
+		OrderID:     order.ID,
+		CustomerID:  order.CustomerID,
+		PhoneNumber: order.PhoneNumber,
+		Provider:    sentSMS.Provider,
+		MessageID:   sentSMS.MessageID,
+		Cost:        sentSMS.Cost,
+		Status:      custom_types.SMSStatusSent,
+	}, nil
 }
 
 func (n *orderNotification) isValidPhoneNumber(phone string) bool {
@@ -87,38 +82,37 @@ func (n *orderNotification) isValidPhoneNumber(phone string) bool {
 	return true
 }
 
+// SendOrderEmail sends email notification to administrator
+func (n *orderNotification) SendOrderEmail(order *models.Order, subject, body string) error {
+    if n.emailProcessor == nil {
+        return fmt.Errorf("email processor not configured")
+    }
 
+    err := n.emailProcessor.SendOrderNotification(subject, body)
+    if err != nil {
+        return fmt.Errorf("failed to send order email: %w", err)
+    }
 
-// SendOrderNotifications sends both SMS and email notifications
-func (n *orderNotification) SendOrderNotifications(order *models.Order) error {
-    // Send SMS notification (async - don't block on failure)
-    go func() {
-        if err := n.SendOrderSMS(order); err != nil {
-            log.Printf("Failed to send order SMS: %v", err)
-        }
-    }()
-
-    // Send email notification 
-    go func() {
-        if err := n.SendOrderEmail(order); err != nil {
-            log.Printf("Failed to send order email: %v", err)
-        }
-    }()
-
+    log.Printf("✅ Order notification email sent for order #%s", order.ReferenceNumber)
     return nil
 }
 
-// SendOrderEmail sends email notification to administrator
-func (n *orderNotification) SendOrderEmail(order *models.Order) error {
+// SendCustomerEmail sends an order's customer the email they asked for instead of SMS.
+func (n *orderNotification) SendCustomerEmail(order *models.Order, to, subject, body string) error {
     if n.emailProcessor == nil {
         return fmt.Errorf("email processor not configured")
     }
 
-    err := n.emailProcessor.SendOrderNotification(order)
+    _, err := n.emailProcessor.SendEmail(&models.EmailRequest{
+        To:          []string{to},
+        Subject:     subject,
+        Body:        body,
+        ContentType: "text/html",
+    })
     if err != nil {
-        return fmt.Errorf("failed to send order email: %w", err)
+        return fmt.Errorf("failed to send customer email: %w", err)
     }
 
-    log.Printf("✅ Order notification email sent for order #%s", order.ReferenceNumber)
+    log.Printf("✅ Customer email sent for order #%s", order.ReferenceNumber)
     return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// The built-in templates are named <event>.<channel>.<locale>.tmpl; an email's subject
+// line is in <event>.<channel>.<locale>.subject.tmpl.
+//
+//go:embed templates/*.tmpl
+var builtInTemplates embed.FS
+
+// Dates in notifications are Kenyan local time.
+var notificationTime = time.FixedZone("EAT", 3*60*60)
+
+type TemplateRenderer interface {
+	// DefaultTemplate is the built-in template for a notification, in English when there
+	// is none in locale.
+	DefaultTemplate(event custom_types.NotificationEvent, channel custom_types.NotificationChannel, locale custom_types.Locale) (*models.NotificationTemplate, error)
+	// Render fills a template in. SMS bodies are text/template and email bodies are
+	// html/template, so whatever an order carries is escaped in an email.
+	Render(notificationTemplate *models.NotificationTemplate, data *models.NotificationTemplateData) (*models.RenderedNotification, error)
+}
+
+type templateRenderer struct {
+	templates fs.FS
+}
+
+func NewTemplateRenderer() TemplateRenderer {
+	return &templateRenderer{
+		templates: builtInTemplates,
+	}
+}
+
+func (r *templateRenderer) DefaultTemplate(
+	event custom_types.NotificationEvent,
+	channel custom_types.NotificationChannel,
+	locale custom_types.Locale,
+) (*models.NotificationTemplate, error) {
+
+	for _, candidate := range []custom_types.Locale{locale, custom_types.DefaultLocale} {
+		name := fmt.Sprintf("templates/%s.%s.%s", event, channel, candidate)
+
+		body, err := fs.ReadFile(r.templates, name+".tmpl")
+		if err != nil {
+			continue
+		}
+
+		notificationTemplate := &models.NotificationTemplate{
+			Event:   event,
+			Channel: channel,
+			Locale:  candidate,
+			Body:    string(body),
+		}
+
+		subject, err := fs.ReadFile(r.templates, name+".subject.tmpl")
+		if err == nil {
+			notificationTemplate.Subject = null.NullValue(strings.TrimSpace(string(subject)))
+		}
+
+		return notificationTemplate, nil
+	}
+
+	return nil, fmt.Errorf("no %s template for %s", channel, event)
+}
+
+func (r *templateRenderer) Render(
+	notificationTemplate *models.NotificationTemplate,
+	data *models.NotificationTemplateData,
+) (*models.RenderedNotification, error) {
+
+	funcs := templateFuncs(data)
+
+	rendered := &models.RenderedNotification{
+		Event:   notificationTemplate.Event,
+		Channel: notificationTemplate.Channel,
+		Locale:  notificationTemplate.Locale,
+	}
+
+	var body bytes.Buffer
+
+	switch notificationTemplate.Channel {
+	case custom_types.NotificationChannelSMS:
+		tmpl, err := texttemplate.New("body").Option("missingkey=error").Funcs(funcs).Parse(notificationTemplate.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		err = tmpl.Execute(&body, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+	case custom_types.NotificationChannelEmail, custom_types.NotificationChannelCustomerEmail:
+		tmpl, err := htmltemplate.New("body").Option("missingkey=error").Funcs(htmltemplate.FuncMap(funcs)).Parse(notificationTemplate.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		err = tmpl.Execute(&body, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+
+		if notificationTemplate.Subject == nil {
+			return nil, fmt.Errorf("email templates need a subject")
+		}
+
+		// a subject is a mail header, not HTML
+		var subject bytes.Buffer
+		subjectTmpl, err := texttemplate.New("subject").Option("missingkey=error").Funcs(funcs).Parse(*notificationTemplate.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subject: %w", err)
+		}
+		err = subjectTmpl.Execute(&subject, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render subject: %w", err)
+		}
+		rendered.Subject = strings.Join(strings.Fields(subject.String()), " ")
+	default:
+		return nil, fmt.Errorf("no templates for channel %q", notificationTemplate.Channel)
+	}
+
+	rendered.Body = strings.TrimSpace(body.String())
+	if rendered.Body == "" {
+		return nil, fmt.Errorf("template rendered an empty message")
+	}
+
+	return rendered, nil
+}
+
+// templateFuncs are the functions templates can call:
+//
+//	money          an amount with its currency, e.g. {{money .Order.TotalAmount}} gives "KES 1,250.00"
+//	date           a time as day, month, year and time of day in Nairobi
+//	returnedItems  how many items came back in the return
+func templateFuncs(data *models.NotificationTemplateData) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"money": func(amount custom_types.Money) string {
+			return amount.String()
+		},
+		"date": func(t time.Time) string {
+			return t.In(notificationTime).Format("02/01/2006 15:04")
+		},
+		"returnedItems": func() int64 {
+			var returnedItems int64
+			if data.OrderReturn != nil {
+				for _, item := range data.OrderReturn.Items {
+					returnedItems += item.Quantity
+				}
+			}
+			return returnedItems
+		},
+	}
+}
@@ -11,7 +11,7 @@ import (
 
 type EmailClient interface {
     SendEmail(req *models.EmailRequest) (*models.EmailResponse, error)
-    SendOrderNotification(order *models.Order) error
+    SendOrderNotification(subject, body string) error
 }
 
 type emailClient struct {
@@ -62,14 +62,8 @@ func (e *emailClient) SendEmail(req *models.EmailRequest) (*models.EmailResponse
     }, nil
 }
 
-// SendOrderNotification sends an order notification email to administrators
-func (e *emailClient) SendOrderNotification(order *models.Order) error {
-    // Create email subject
-    subject := fmt.Sprintf("📦 New Order Received - #%s", order.ReferenceNumber)
-
-    // Create email body
-    body := e.createOrderEmailBody(order)
-
+// SendOrderNotification sends a rendered order notification email to administrators
+func (e *emailClient) SendOrderNotification(subject, body string) error {
     // Prepare email request
     emailReq := &models.EmailRequest{
         To:          []string{e.emailService.AdminEmail},
@@ -192,133 +186,3 @@ func (e *emailClient) sendSMTP(to []string, message []byte, auth smtp.Auth) erro
 
     return client.Quit()
 }
-
-func (e *emailClient) createOrderEmailBody(order *models.Order) string {
-    return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background: #f8f9fa; padding: 20px; text-align: center; border-radius: 5px; }
-        .order-info { background: #fff; padding: 20px; margin: 20px 0; border: 1px solid #ddd; border-radius: 5px; }
-        .order-detail { margin: 10px 0; }
-        .status-pending { color: #856404; background: #fff3cd; padding: 5px 10px; border-radius: 3px; }
-        .status-confirmed { color: #155724; background: #d4edda; padding: 5px 10px; border-radius: 3px; }
-        .table { width: 100%%; border-collapse: collapse; margin: 20px 0; }
-        .table th, .table td { padding: 12px; text-align: left; border-bottom: 1px solid #ddd; }
-        .table th { background: #f8f9fa; }
-        .total-row { font-weight: bold; background: #f8f9fa; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>🛍️ New Order Received</h1>
-            <p>Savannah POS System Notification</p>
-        </div>
-        
-        <div class="order-info">
-            <h2>Order Details</h2>
-            
-            <div class="order-detail">
-                <strong>Order Reference:</strong> %s
-            </div>
-            <div class="order-detail">
-                <strong>Order ID:</strong> %d
-            </div>
-            <div class="order-detail">
-                <strong>Order Date:</strong> %s
-            </div>
-            <div class="order-detail">
-                <strong>Customer Phone:</strong> %s
-            </div>
-            <div class="order-detail">
-                <strong>Order Status:</strong> <span class="status-%s">%s</span>
-            </div>
-            <div class="order-detail">
-                <strong>Payment Method:</strong> %s
-            </div>
-            <div class="order-detail">
-                <strong>Order Medium:</strong> %s
-            </div>
-        </div>
-
-        <div class="order-info">
-            <h2>Order Items</h2>
-            <table class="table">
-                <thead>
-                    <tr>
-                        <th>Product ID</th>
-                        <th>Unit Price</th>
-                        <th>Quantity</th>
-                        <th>Total</th>
-                    </tr>
-                </thead>
-                <tbody>
-                    %s
-                </tbody>
-                <tfoot>
-                    <tr class="total-row">
-                        <td colspan="3"><strong>Subtotal:</strong></td>
-                        <td>$%.2f</td>
-                    </tr>
-                    <tr class="total-row">
-                        <td colspan="3"><strong>Discount:</strong></td>
-                        <td>$%.2f</td>
-                    </tr>
-                    <tr class="total-row">
-                        <td colspan="3"><strong>Total Amount:</strong></td>
-                        <td>$%.2f</td>
-                    </tr>
-                </tfoot>
-            </table>
-        </div>
-
-        <div class="order-info">
-            <p><strong>Action Required:</strong> Please review this order in the admin dashboard.</p>
-            <p>This is an automated notification from Savannah POS System.</p>
-        </div>
-    </div>
-</body>
-</html>`,
-        order.ReferenceNumber,
-        order.ID,
-        order.CreatedAt.Format("2006-01-02 15:04:05"),
-        order.PhoneNumber,
-        strings.ToLower(string(order.OrderStatus)),
-        order.OrderStatus,
-        order.PaymentMethod,
-        order.OrderMedium,
-        e.generateOrderItemsHTML(order),
-        order.TotalAmount,
-        e.getDiscountAmount(order),
-        order.TotalAmount,
-    )
-}
-
-// generateOrderItemsHTML generates HTML for order items table
-func (e *emailClient) generateOrderItemsHTML(order *models.Order) string {
-    return `
-        <tr>
-            <td>1</td>
-            <td>$999.99</td>
-            <td>2</td>
-            <td>$1999.98</td>
-        </tr>
-        <tr>
-            <td>2</td>
-            <td>$9999.99</td>
-            <td>1</td>
-            <td>$9999.99</td>
-        </tr>`
-}
-
-func (e *emailClient) getDiscountAmount(order *models.Order) float64 {
-    if order.Discount == nil {
-        return 0.0
-    }
-    return *order.Discount
-}
\ No newline at end of file
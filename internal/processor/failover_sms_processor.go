@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+type failoverSMSClient struct {
+	clients []SMSClient
+}
+
+// NewFailoverSMSClient tries clients in order and returns the first that accepts the
+// message. A provider that times out after accepting a message cannot be told apart from
+// one that rejected it, so an outage can now and then mean a message sent twice.
+func NewFailoverSMSClient(
+	clients ...SMSClient,
+) SMSClient {
+	return &failoverSMSClient{
+		clients: clients,
+	}
+}
+
+func (p *failoverSMSClient) SendSMS(to, message string) (*models.SentSMS, error) {
+	errs := make([]error, 0, len(p.clients))
+
+	for i, client := range p.clients {
+		sentSMS, err := client.SendSMS(to, message)
+		if err == nil {
+			return sentSMS, nil
+		}
+
+		loggers.Errorf("sms provider %d of %d failed: [%+v]", i+1, len(p.clients), err)
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no sms providers configured")
+	}
+
+	return nil, errors.Join(errs...)
+}
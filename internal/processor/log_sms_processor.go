@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"io"
+	"sync"
+	"time"
+)
+
+type logSMSClient struct {
+	mu  sync.Mutex
+	out io.Writer
+	now func() time.Time
+	n   int64
+}
+
+// NewLogSMSClient writes messages to out instead of sending them, for development
+// without an SMS account. Every message is accepted.
+func NewLogSMSClient(
+	out io.Writer,
+) SMSClient {
+	return &logSMSClient{
+		out: out,
+		now: time.Now,
+	}
+}
+
+func (p *logSMSClient) SendSMS(to, message string) (*models.SentSMS, error) {
+	if to == "" || message == "" {
+		return nil, fmt.Errorf("phone number and message cannot be empty")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.n++
+	now := p.now()
+	// unique across restarts, as message ids are stored
+	messageID := fmt.Sprintf("log-%d-%d", now.UnixNano(), p.n)
+
+	_, err := fmt.Fprintf(p.out, "%s sms %s to %s:\n%s\n\n", now.Format(time.RFC3339), messageID, to, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write sms: %w", err)
+	}
+
+	return &models.SentSMS{
+		Provider:  SMSProviderLog,
+		Number:    to,
+		MessageID: messageID,
+	}, nil
+}
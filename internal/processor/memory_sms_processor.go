@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"sync"
+)
+
+// MemorySMS is a message a MemorySMSClient was asked to send.
+type MemorySMS struct {
+	To        string
+	Message   string
+	MessageID string
+}
+
+// MemorySMSClient keeps the messages it is asked to send, for tests. It fails while Err
+// is set.
+type MemorySMSClient struct {
+	mu   sync.Mutex
+	Err  error
+	sent []MemorySMS
+}
+
+func NewMemorySMSClient() *MemorySMSClient {
+	return &MemorySMSClient{}
+}
+
+func (p *MemorySMSClient) SendSMS(to, message string) (*models.SentSMS, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return nil, p.Err
+	}
+
+	sms := MemorySMS{
+		To:        to,
+		Message:   message,
+		MessageID: fmt.Sprintf("memory-%d", len(p.sent)+1),
+	}
+	p.sent = append(p.sent, sms)
+
+	return &models.SentSMS{
+		Provider:  SMSProviderMemory,
+		Number:    to,
+		MessageID: sms.MessageID,
+	}, nil
+}
+
+// Sent is every message accepted so far, oldest first.
+func (p *MemorySMSClient) Sent() []MemorySMS {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]MemorySMS(nil), p.sent...)
+}
@@ -0,0 +1,245 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mpesaOAuthPath = "/oauth/v1/generate?grant_type=client_credentials"
+	mpesaSTKPath   = "/mpesa/stkpush/v1/processrequest"
+
+	// mpesaTransactionType is for paybills; tills use CustomerBuyGoodsOnline
+	mpesaTransactionType = "CustomerPayBillOnline"
+
+	// MpesaSandboxBaseURL is Daraja's test environment.
+	MpesaSandboxBaseURL = "https://sandbox.safaricom.co.ke"
+)
+
+// Daraja reads timestamps as Kenyan local time.
+var eastAfricaTime = time.FixedZone("EAT", 3*60*60)
+
+// ErrSTKPushRejected is wrapped by STKPush errors that are certain no prompt went out:
+// the request was never sent, or Daraja answered and refused it. Any other error, such
+// as a timeout, leaves it unknown whether the customer's phone was prompted.
+var ErrSTKPushRejected = errors.New("STK push rejected")
+
+type MpesaClient interface {
+	// STKPush prompts phoneNumber to pay amount whole shillings. A nil error means
+	// Daraja accepted the request, not that the customer has paid.
+	STKPush(ctx context.Context, phoneNumber string, amount int64, accountReference, description string) (*models.STKPushResponse, error)
+}
+
+type mpesaClient struct {
+	MpesaService *models.MpesaService
+	client       *http.Client
+	now          func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func NewMpesaClient(
+	MpesaService *models.MpesaService,
+	client *http.Client,
+) MpesaClient {
+	return &mpesaClient{
+		MpesaService: MpesaService,
+		client:       client,
+		now:          time.Now,
+	}
+}
+
+func (p *mpesaClient) STKPush(
+	ctx context.Context,
+	phoneNumber string,
+	amount int64,
+	accountReference string,
+	description string,
+) (*models.STKPushResponse, error) {
+
+	if amount < 1 {
+		return nil, fmt.Errorf("%w: M-Pesa amount must be at least 1 shilling, got %d", ErrSTKPushRejected, amount)
+	}
+
+	formattedPhone, err := FormatMpesaPhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSTKPushRejected, err)
+	}
+
+	token, err := p.token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSTKPushRejected, err)
+	}
+
+	timestamp := p.now().In(eastAfricaTime).Format("20060102150405")
+
+	payload := &models.STKPushRequest{
+		BusinessShortCode: p.MpesaService.ShortCode,
+		Password:          STKPassword(p.MpesaService.ShortCode, p.MpesaService.Passkey, timestamp),
+		Timestamp:         timestamp,
+		TransactionType:   mpesaTransactionType,
+		Amount:            amount,
+		PartyA:            formattedPhone,
+		PartyB:            p.MpesaService.ShortCode,
+		PhoneNumber:       formattedPhone,
+		CallBackURL:       p.MpesaService.CallbackURL,
+		AccountReference:  truncate(accountReference, 12),
+		TransactionDesc:   truncate(description, 13),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to encode STK push request: %w", ErrSTKPushRejected, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.MpesaService.BaseURL+mpesaSTKPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create HTTP request: %w", ErrSTKPushRejected, err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// the token was revoked early; fetch a new one next time
+		p.mu.Lock()
+		p.accessToken = ""
+		p.mu.Unlock()
+	}
+
+	var stkResponse models.STKPushResponse
+	err = json.Unmarshal(respBody, &stkResponse)
+	if err != nil {
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return nil, fmt.Errorf("%w with status %d: %s", ErrSTKPushRejected, resp.StatusCode, string(respBody))
+		}
+		// e.g. a gateway error page; Daraja may still have taken the request
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || stkResponse.ResponseCode != "0" {
+		message := stkResponse.ErrorMessage
+		if message == "" {
+			message = stkResponse.ResponseDescription
+		}
+		return nil, fmt.Errorf("%w with status %d: %s", ErrSTKPushRejected, resp.StatusCode, message)
+	}
+
+	return &stkResponse, nil
+}
+
+// token returns a cached OAuth access token, fetching a new one shortly before the old one expires.
+func (p *mpesaClient) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && p.now().Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.MpesaService.BaseURL+mpesaOAuthPath, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.SetBasicAuth(p.MpesaService.ConsumerKey, p.MpesaService.ConsumerSecret)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("M-Pesa authentication failed - check the consumer key and secret. Status: %d, Body: %s",
+			resp.StatusCode, string(body))
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   string `json:"expires_in"`
+	}
+
+	err = json.Unmarshal(body, &tokenResponse)
+	if err != nil || tokenResponse.AccessToken == "" {
+		return "", fmt.Errorf("failed to parse M-Pesa token response: %s", string(body))
+	}
+
+	expiresIn, err := strconv.Atoi(tokenResponse.ExpiresIn)
+	if err != nil || expiresIn <= 0 {
+		expiresIn = 3599
+	}
+
+	p.accessToken = tokenResponse.AccessToken
+	// leave a minute's margin so a token never expires mid request
+	p.expiresAt = p.now().Add(time.Duration(expiresIn)*time.Second - time.Minute)
+
+	return p.accessToken, nil
+}
+
+// STKPassword is base64(shortcode + passkey + timestamp), as Daraja expects.
+func STKPassword(shortCode, passkey, timestamp string) string {
+	return base64.StdEncoding.EncodeToString([]byte(shortCode + passkey + timestamp))
+}
+
+// FormatMpesaPhoneNumber turns a Kenyan mobile number into the 2547XXXXXXXX form Daraja takes.
+func FormatMpesaPhoneNumber(phone string) (string, error) {
+	cleaned := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	cleaned = strings.TrimPrefix(cleaned, "+")
+
+	switch {
+	case strings.HasPrefix(cleaned, "0") && len(cleaned) == 10:
+		cleaned = "254" + cleaned[1:]
+	case len(cleaned) == 9:
+		cleaned = "254" + cleaned
+	}
+
+	if len(cleaned) != 12 || !strings.HasPrefix(cleaned, "254") {
+		return "", fmt.Errorf("invalid M-Pesa phone number: %s", phone)
+	}
+
+	for _, char := range cleaned {
+		if char < '0' || char > '9' {
+			return "", fmt.Errorf("invalid characters in phone number: %s", phone)
+		}
+	}
+
+	return cleaned, nil
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+
+	return value[:length]
+}
@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+// fakeDaraja answers the OAuth and STK Push endpoints the way Safaricom's sandbox does.
+type fakeDaraja struct {
+	tokenRequests int
+	stkRequests   []models.STKPushRequest
+	stkStatus     int
+	stkResponse   models.STKPushResponse
+}
+
+func (f *fakeDaraja) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/oauth/v1/generate":
+		key, secret, ok := r.BasicAuth()
+		if !ok || key != "key" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.tokenRequests++
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token", "expires_in": "3599"})
+	case "/mpesa/stkpush/v1/processrequest":
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(models.STKPushResponse{ErrorMessage: "Invalid Access Token"})
+			return
+		}
+		var payload models.STKPushRequest
+		json.NewDecoder(r.Body).Decode(&payload)
+		f.stkRequests = append(f.stkRequests, payload)
+		w.WriteHeader(f.stkStatus)
+		json.NewEncoder(w).Encode(f.stkResponse)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeDaraja(t *testing.T) (*fakeDaraja, *mpesaClient) {
+	daraja := &fakeDaraja{
+		stkStatus: http.StatusOK,
+		stkResponse: models.STKPushResponse{
+			MerchantRequestID: "merchant-1",
+			CheckoutRequestID: "ws_CO_1",
+			ResponseCode:      "0",
+			CustomerMessage:   "Success. Request accepted for processing",
+		},
+	}
+
+	server := httptest.NewServer(daraja)
+	t.Cleanup(server.Close)
+
+	client := NewMpesaClient(&models.MpesaService{
+		BaseURL:        server.URL,
+		ConsumerKey:    "key",
+		ConsumerSecret: "secret",
+		ShortCode:      "174379",
+		Passkey:        "passkey",
+		CallbackURL:    "https://pos.example.com/v1/payments/mpesa/callback",
+	}, server.Client()).(*mpesaClient)
+
+	client.now = func() time.Time { return time.Date(2025, 10, 11, 9, 30, 0, 0, time.UTC) }
+
+	return daraja, client
+}
+
+func TestMpesaClient_STKPush(t *testing.T) {
+	daraja, client := newFakeDaraja(t)
+
+	response, err := client.STKPush(context.Background(), "0712 345 678", 150, "ORD-2025-000001", "Order payment")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ws_CO_1", response.CheckoutRequestID)
+
+	assert.Len(t, daraja.stkRequests, 1)
+	payload := daraja.stkRequests[0]
+	assert.Equal(t, "254712345678", payload.PhoneNumber)
+	assert.Equal(t, "254712345678", payload.PartyA)
+	assert.Equal(t, int64(150), payload.Amount)
+	// 09:30 UTC is 12:30 in Nairobi
+	assert.Equal(t, "20251011123000", payload.Timestamp)
+	assert.Equal(t, STKPassword("174379", "passkey", "20251011123000"), payload.Password)
+	assert.Equal(t, "ORD-2025-000", payload.AccountReference)
+	assert.Equal(t, "Order payment", payload.TransactionDesc)
+}
+
+func TestMpesaClient_CachesToken(t *testing.T) {
+	daraja, client := newFakeDaraja(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.STKPush(context.Background(), "254712345678", 10, "ORD", "Order payment")
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, daraja.tokenRequests)
+
+	// a minute before Daraja's expiry the token is renewed
+	client.now = func() time.Time { return time.Date(2025, 10, 11, 10, 29, 0, 0, time.UTC) }
+
+	_, err := client.STKPush(context.Background(), "254712345678", 10, "ORD", "Order payment")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, daraja.tokenRequests)
+}
+
+func TestMpesaClient_Rejected(t *testing.T) {
+	daraja, client := newFakeDaraja(t)
+	daraja.stkStatus = http.StatusBadRequest
+	daraja.stkResponse = models.STKPushResponse{ErrorCode: "400.002.02", ErrorMessage: "Bad Request - Invalid Amount"}
+
+	_, err := client.STKPush(context.Background(), "254712345678", 10, "ORD", "Order payment")
+
+	assert.ErrorContains(t, err, "Invalid Amount")
+	assert.ErrorIs(t, err, ErrSTKPushRejected)
+}
+
+func TestMpesaClient_TimeoutMayHavePrompted(t *testing.T) {
+	_, client := newFakeDaraja(t)
+
+	// the token is fetched, then the STK Push request outlives its deadline
+	_, err := client.STKPush(context.Background(), "254712345678", 10, "ORD", "Order payment")
+	assert.NoError(t, err)
+	client.client.Timeout = time.Nanosecond
+
+	_, err = client.STKPush(context.Background(), "254712345678", 10, "ORD", "Order payment")
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrSTKPushRejected)
+}
+
+func TestMpesaClient_RejectsBadInput(t *testing.T) {
+	daraja, client := newFakeDaraja(t)
+
+	_, err := client.STKPush(context.Background(), "254712345678", 0, "ORD", "Order payment")
+	assert.ErrorIs(t, err, ErrSTKPushRejected)
+
+	_, err = client.STKPush(context.Background(), "12345", 10, "ORD", "Order payment")
+	assert.ErrorIs(t, err, ErrSTKPushRejected)
+
+	assert.Empty(t, daraja.stkRequests)
+}
+
+func TestFormatMpesaPhoneNumber(t *testing.T) {
+	for _, phone := range []string{"0712345678", "+254712345678", "254 712-345-678", "712345678"} {
+		formatted, err := FormatMpesaPhoneNumber(phone)
+		assert.NoError(t, err, phone)
+		assert.Equal(t, "254712345678", formatted, phone)
+	}
+
+	for _, phone := range []string{"", "07123", "255712345678", "07123456ab"} {
+		_, err := FormatMpesaPhoneNumber(phone)
+		assert.Error(t, err, phone)
+	}
+}
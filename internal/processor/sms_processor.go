@@ -4,59 +4,135 @@ import (
 	"encoding/json"
 	"fmt"
 	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 )
 
+// The providers an SMSService can list, in the order they should be tried.
+const (
+	SMSProviderAfricasTalking = "africastalking"
+	SMSProviderTwilio         = "twilio"
+	SMSProviderLog            = "log"
+	SMSProviderMemory         = "memory"
+)
+
+const (
+	// AfricasTalkingLiveBaseURL is Africa's Talking's messaging endpoint.
+	AfricasTalkingLiveBaseURL = "https://api.africastalking.com/version1/messaging"
+	// AfricasTalkingSandboxBaseURL is the messaging endpoint for the "sandbox" app, whose
+	// messages only reach the simulator.
+	AfricasTalkingSandboxBaseURL = "https://api.sandbox.africastalking.com/version1/messaging"
+
+	// SMSEnvSandbox is the SMSService Env that sends through the sandbox.
+	SMSEnvSandbox = "sandbox"
+)
+
+// SMSClient sends an SMS through a provider. A nil error means the provider accepted the
+// message, not that it reached the phone; that comes later in a delivery report.
 type SMSClient interface {
-	SendSMS(to, message string) (*models.SMSResponse, error)
-	
-	ValidateResponse(response *models.SMSResponse) error
+	SendSMS(to, message string) (*models.SentSMS, error)
 }
 
-type smsClient struct {
-	SMSService *models.SMSService  
-	client     *http.Client        
-}
+// AfricasTalkingBaseURL is the messaging endpoint for env.
+func AfricasTalkingBaseURL(env string) string {
+	if strings.EqualFold(env, SMSEnvSandbox) {
+		return AfricasTalkingSandboxBaseURL
+	}
 
+	return AfricasTalkingLiveBaseURL
+}
 
+// NewSMSClient builds the providers SMSService lists, tried in that order.
 func NewSMSClient(
-	SMSService *models.SMSService, 
-	client     *http.Client,       
+	SMSService *models.SMSService,
+	client *http.Client,
+) (SMSClient, error) {
+
+	providers := SMSService.Providers
+	if len(providers) == 0 {
+		providers = []string{SMSProviderAfricasTalking}
+	}
+
+	clients := make([]SMSClient, 0, len(providers))
+
+	for _, provider := range providers {
+		switch provider {
+		case SMSProviderAfricasTalking:
+			clients = append(clients, NewAfricasTalkingClient(SMSService, client))
+		case SMSProviderTwilio:
+			if SMSService.Twilio == nil {
+				return nil, fmt.Errorf("sms provider %q is not configured", provider)
+			}
+			clients = append(clients, NewTwilioClient(SMSService.Twilio, client))
+		case SMSProviderLog:
+			if SMSService.LogFile == "" {
+				clients = append(clients, NewLogSMSClient(os.Stdout))
+				continue
+			}
+			logFile, err := os.OpenFile(SMSService.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open sms log file: %w", err)
+			}
+			clients = append(clients, NewLogSMSClient(logFile))
+		case SMSProviderMemory:
+			clients = append(clients, NewMemorySMSClient())
+		default:
+			return nil, fmt.Errorf("unknown sms provider %q", provider)
+		}
+	}
+
+	if len(clients) == 1 {
+		return clients[0], nil
+	}
+
+	return NewFailoverSMSClient(clients...), nil
+}
+
+type africasTalkingClient struct {
+	SMSService *models.SMSService
+	client     *http.Client
+}
+
+// NewAfricasTalkingClient sends through Africa's Talking. Its endpoint is SMSService's
+// BaseURL, or the live or sandbox one for its Env when that is empty.
+func NewAfricasTalkingClient(
+	SMSService *models.SMSService,
+	client *http.Client,
 ) SMSClient {
-	return &smsClient{
+	return &africasTalkingClient{
 		SMSService: SMSService,
 		client:     client,
 	}
 }
 
-
-func (p *smsClient) SendSMS(to, message string) (*models.SMSResponse, error) {
+func (p *africasTalkingClient) SendSMS(to, message string) (*models.SentSMS, error) {
 	if to == "" || message == "" {
 		return nil, fmt.Errorf("phone number and message cannot be empty")
 	}
 
-	formattedTo, err := p.formatPhoneNumber(to)
+	formattedTo, err := utils.FormatPhoneNumber(to)
 	if err != nil {
 		return nil, fmt.Errorf("invalid phone number: %w", err)
 	}
 
 	data := url.Values{}
-	data.Set("username", p.SMSService.Username)  
-	data.Set("to", formattedTo)                  
-	data.Set("message", message)                 
+	data.Set("username", p.SMSService.Username)
+	data.Set("to", formattedTo)
+	data.Set("message", message)
 
-	req, err := http.NewRequest("POST", p.SMSService.BaseURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequest("POST", p.baseURL(), strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded") 
-	req.Header.Set("ApiKey", p.SMSService.ApiKey)                       
-	req.Header.Set("Accept", "application/json")                       
-	req.Header.Set("User-Agent", "Savannah-POS/1.0")                    
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("ApiKey", p.SMSService.ApiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Savannah-POS/1.0")
 	resp, err := p.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
@@ -69,7 +145,7 @@ func (p *smsClient) SendSMS(to, message string) (*models.SMSResponse, error) {
 	}
 
 	if resp.StatusCode == 401 {
-		return nil, fmt.Errorf("authentication failed - check your API key and username. Status: %d, Body: %s", 
+		return nil, fmt.Errorf("authentication failed - check your API key and username. Status: %d, Body: %s",
 			resp.StatusCode, string(body))
 	}
 
@@ -82,43 +158,29 @@ func (p *smsClient) SendSMS(to, message string) (*models.SMSResponse, error) {
 		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
 	}
 
-	return &smsResponse, nil
-}
-
-
-func (p *smsClient) formatPhoneNumber(phone string) (string, error) {
-	cleaned := strings.ReplaceAll(phone, " ", "")
-	cleaned = strings.ReplaceAll(cleaned, "-", "")
-	cleaned = strings.ReplaceAll(cleaned, "(", "")
-	cleaned = strings.ReplaceAll(cleaned, ")", "")
-
-	if !strings.HasPrefix(cleaned, "+") {
-		
-		if strings.HasPrefix(cleaned, "0") && len(cleaned) == 10 {
-			cleaned = "+254" + cleaned[1:]
-		} else if len(cleaned) == 9 {
-			cleaned = "+254" + cleaned
-		} else {
-			return "", fmt.Errorf("invalid phone number format: %s", phone)
-		}
+	if err := p.validateResponse(&smsResponse); err != nil {
+		return nil, fmt.Errorf("SMS delivery failed: %w", err)
 	}
 
-	digits := strings.TrimPrefix(cleaned, "+")
-	
-	if len(digits) < 9 || len(digits) > 12 {
-		return "", fmt.Errorf("invalid phone number length: %s", phone)
-	}
+	recipient := smsResponse.SMSMessageData.Recipients[0]
 
-	for _, char := range digits {
-		if char < '0' || char > '9' {
-			return "", fmt.Errorf("invalid characters in phone number: %s", phone)
-		}
+	return &models.SentSMS{
+		Provider:  SMSProviderAfricasTalking,
+		Number:    recipient.Number,
+		MessageID: recipient.MessageID,
+		Cost:      recipient.Cost,
+	}, nil
+}
+
+func (p *africasTalkingClient) baseURL() string {
+	if p.SMSService.BaseURL != "" {
+		return p.SMSService.BaseURL
 	}
 
-	return cleaned, nil
+	return AfricasTalkingBaseURL(p.SMSService.Env)
 }
 
-func (p *smsClient) ValidateResponse(response *models.SMSResponse) error {
+func (p *africasTalkingClient) validateResponse(response *models.SMSResponse) error {
 	if len(response.SMSMessageData.Recipients) == 0 {
 		if response.SMSMessageData.Message != "" && response.SMSMessageData.Message != "Sent" {
 			return fmt.Errorf("SMS API error: %s", response.SMSMessageData.Message)
@@ -135,4 +197,4 @@ func (p *smsClient) ValidateResponse(response *models.SMSResponse) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+func TestAfricasTalkingClient_SendSMS(t *testing.T) {
+	var form map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("ApiKey") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		form = map[string]string{"username": r.PostForm.Get("username"), "to": r.PostForm.Get("to")}
+
+		json.NewEncoder(w).Encode(models.SMSResponse{SMSMessageData: models.SMSMessageData{
+			Message: "Sent to 1/1 Total Cost: KES 0.8000",
+			Recipients: []models.Recipient{
+				{StatusCode: 101, Number: "+254712345678", Status: "Success", Cost: "KES 0.8000", MessageID: "ATXid_1"},
+			},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewAfricasTalkingClient(&models.SMSService{ApiKey: "key", Username: "sandbox", BaseURL: server.URL}, server.Client())
+
+	sentSMS, err := client.SendSMS("0712 345 678", "Order confirmed")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.SentSMS{Provider: SMSProviderAfricasTalking, Number: "+254712345678", MessageID: "ATXid_1", Cost: "KES 0.8000"}, sentSMS)
+	assert.Equal(t, map[string]string{"username": "sandbox", "to": "+254712345678"}, form)
+}
+
+func TestAfricasTalkingClient_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.SMSResponse{SMSMessageData: models.SMSMessageData{
+			Recipients: []models.Recipient{
+				{StatusCode: 405, Number: "+254712345678", Status: "InsufficientBalance"},
+			},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewAfricasTalkingClient(&models.SMSService{ApiKey: "key", Username: "shop", BaseURL: server.URL}, server.Client())
+
+	_, err := client.SendSMS("+254712345678", "Order confirmed")
+	assert.ErrorContains(t, err, "InsufficientBalance")
+}
+
+func TestAfricasTalkingBaseURL(t *testing.T) {
+	assert.Equal(t, AfricasTalkingSandboxBaseURL, AfricasTalkingBaseURL("sandbox"))
+	assert.Equal(t, AfricasTalkingSandboxBaseURL, AfricasTalkingBaseURL("Sandbox"))
+	assert.Equal(t, AfricasTalkingLiveBaseURL, AfricasTalkingBaseURL("production"))
+	assert.Equal(t, AfricasTalkingLiveBaseURL, AfricasTalkingBaseURL(""))
+
+	// the base URL follows Env unless one is given
+	client := NewAfricasTalkingClient(&models.SMSService{Env: "sandbox"}, nil).(*africasTalkingClient)
+	assert.Equal(t, AfricasTalkingSandboxBaseURL, client.baseURL())
+
+	client = NewAfricasTalkingClient(&models.SMSService{Env: "sandbox", BaseURL: "http://localhost:8080"}, nil).(*africasTalkingClient)
+	assert.Equal(t, "http://localhost:8080", client.baseURL())
+}
+
+func TestTwilioClient_SendSMS(t *testing.T) {
+	var request models.TwilioMessageRequest
+	var path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sid, token, ok := r.BasicAuth()
+		if !ok || sid != "AC123" || token != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{"code": 20003, "message": "Authenticate"})
+			return
+		}
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&request)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"sid": "SM1", "to": request.To, "status": "queued", "price": nil, "price_unit": "USD"})
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewTwilioClient(&models.TwilioService{BaseURL: server.URL, AccountSID: "AC123", AuthToken: "token", From: "+15005550006"}, server.Client())
+
+	sentSMS, err := client.SendSMS("0712345678", "Order confirmed")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.SentSMS{Provider: SMSProviderTwilio, Number: "+254712345678", MessageID: "SM1"}, sentSMS)
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", path)
+	assert.Equal(t, models.TwilioMessageRequest{To: "+254712345678", From: "+15005550006", Body: "Order confirmed"}, request)
+
+	client = NewTwilioClient(&models.TwilioService{BaseURL: server.URL, AccountSID: "AC123", AuthToken: "wrong"}, server.Client())
+
+	_, err = client.SendSMS("0712345678", "Order confirmed")
+	assert.ErrorContains(t, err, "Authenticate")
+}
+
+func TestLogSMSClient_SendSMS(t *testing.T) {
+	var out bytes.Buffer
+	client := NewLogSMSClient(&out)
+
+	first, err := client.SendSMS("+254712345678", "Order confirmed")
+	assert.NoError(t, err)
+	second, err := client.SendSMS("+254712345678", "Return received")
+	assert.NoError(t, err)
+
+	assert.Equal(t, SMSProviderLog, first.Provider)
+	assert.NotEqual(t, first.MessageID, second.MessageID)
+	assert.Contains(t, out.String(), "to +254712345678:\nOrder confirmed\n")
+	assert.Contains(t, out.String(), "Return received")
+}
+
+func TestFailoverSMSClient_SendSMS(t *testing.T) {
+	loggers.InitLogger("test")
+
+	primary := NewMemorySMSClient()
+	secondary := NewMemorySMSClient()
+	client := NewFailoverSMSClient(primary, secondary)
+
+	_, err := client.SendSMS("+254712345678", "first")
+	assert.NoError(t, err)
+
+	// while the first provider is down the next one takes the messages
+	primary.Err = errors.New("provider unavailable")
+	sentSMS, err := client.SendSMS("+254712345678", "second")
+	assert.NoError(t, err)
+	assert.Equal(t, "memory-1", sentSMS.MessageID)
+
+	assert.Len(t, primary.Sent(), 1)
+	assert.Equal(t, []MemorySMS{{To: "+254712345678", Message: "second", MessageID: "memory-1"}}, secondary.Sent())
+
+	secondary.Err = errors.New("insufficient balance")
+	_, err = client.SendSMS("+254712345678", "third")
+	assert.ErrorContains(t, err, "provider unavailable")
+	assert.ErrorContains(t, err, "insufficient balance")
+}
+
+func TestNewSMSClient(t *testing.T) {
+	client, err := NewSMSClient(&models.SMSService{}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &africasTalkingClient{}, client)
+
+	client, err = NewSMSClient(&models.SMSService{Providers: []string{SMSProviderAfricasTalking, SMSProviderLog}}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &failoverSMSClient{}, client)
+
+	_, err = NewSMSClient(&models.SMSService{Providers: []string{SMSProviderTwilio}}, nil)
+	assert.Error(t, err)
+
+	_, err = NewSMSClient(&models.SMSService{Providers: []string{"pigeon"}}, nil)
+	assert.Error(t, err)
+}
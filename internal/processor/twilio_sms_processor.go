@@ -0,0 +1,119 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// TwilioBaseURL is Twilio's API; Twilio-compatible gateways take the same requests.
+	TwilioBaseURL = "https://api.twilio.com"
+)
+
+type twilioClient struct {
+	TwilioService *models.TwilioService
+	client        *http.Client
+}
+
+// NewTwilioClient sends through a Twilio-style JSON messaging API, authenticating with
+// the account SID and auth token.
+func NewTwilioClient(
+	TwilioService *models.TwilioService,
+	client *http.Client,
+) SMSClient {
+	return &twilioClient{
+		TwilioService: TwilioService,
+		client:        client,
+	}
+}
+
+func (p *twilioClient) SendSMS(to, message string) (*models.SentSMS, error) {
+	if to == "" || message == "" {
+		return nil, fmt.Errorf("phone number and message cannot be empty")
+	}
+
+	formattedTo, err := utils.FormatPhoneNumber(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid phone number: %w", err)
+	}
+
+	payload, err := json.Marshal(&models.TwilioMessageRequest{
+		To:   formattedTo,
+		From: p.TwilioService.From,
+		Body: message,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", p.messagesURL(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.SetBasicAuth(p.TwilioService.AccountSID, p.TwilioService.AuthToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Savannah-POS/1.0")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var messageResponse models.TwilioMessageResponse
+	if err := json.Unmarshal(body, &messageResponse); err != nil {
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("API returned status %d: %s (code: %d)", resp.StatusCode, messageResponse.Message, messageResponse.Code)
+	}
+
+	// queued, accepted, sending and sent are all on their way
+	if messageResponse.Status == "failed" || messageResponse.Status == "undelivered" || messageResponse.ErrorCode != nil {
+		var errorMessage string
+		if messageResponse.ErrorMessage != nil {
+			errorMessage = *messageResponse.ErrorMessage
+		}
+		return nil, fmt.Errorf("SMS failed for %s: %s %s", formattedTo, messageResponse.Status, errorMessage)
+	}
+
+	if messageResponse.SID == "" {
+		return nil, fmt.Errorf("no message id in response")
+	}
+
+	// the price is usually only known once the message is sent
+	var cost string
+	if messageResponse.Price != nil {
+		cost = strings.TrimSpace(messageResponse.PriceUnit + " " + *messageResponse.Price)
+	}
+
+	return &models.SentSMS{
+		Provider:  SMSProviderTwilio,
+		Number:    formattedTo,
+		MessageID: messageResponse.SID,
+		Cost:      cost,
+	}, nil
+}
+
+func (p *twilioClient) messagesURL() string {
+	baseURL := p.TwilioService.BaseURL
+	if baseURL == "" {
+		baseURL = TwilioBaseURL
+	}
+
+	return fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(baseURL, "/"), url.PathEscape(p.TwilioService.AccountSID))
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeyPrefix marks our keys, so one pasted somewhere it should not be is easy to spot
+	apiKeyPrefix = "spos_"
+	apiKeyBytes  = 32
+	// apiKeyShownPrefixLength is how much of a key is kept in the clear to tell keys apart
+	apiKeyShownPrefixLength = len(apiKeyPrefix) + 8
+	// apiKeyLastUsedInterval is how stale last_used_at may get before a request updates it
+	apiKeyLastUsedInterval = time.Minute
+)
+
+type (
+	APIKeyService interface {
+		CreateAPIKey(ctx context.Context, dB db.DB, shopID string, form *dtos.CreateAPIKeyForm, actor string) (*models.CreatedAPIKey, error)
+		ListAPIKeys(ctx context.Context, dB db.DB, shopID string) ([]*models.APIKey, error)
+		RevokeAPIKey(ctx context.Context, dB db.DB, shopID string, apiKeyID int64, actor string) (*models.APIKey, error)
+		AuthenticateAPIKey(ctx context.Context, dB db.DB, rawKey string) (*models.APIKey, error)
+	}
+
+	apiKeyService struct {
+		store *domain.Store
+		now   func() time.Time
+	}
+)
+
+func NewAPIKeyService(
+	store *domain.Store,
+) APIKeyService {
+	return &apiKeyService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// CreateAPIKey makes a key for one shop with the given scopes. The key is only ever
+// returned here; afterwards the shop sees its prefix and when it was last used.
+func (s *apiKeyService) CreateAPIKey(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	form *dtos.CreateAPIKeyForm,
+	actor string,
+) (*models.CreatedAPIKey, error) {
+
+	name := strings.TrimSpace(form.Name)
+	if name == "" {
+		return nil, apperr.NewBadRequest("api key name is required")
+	}
+
+	if len(form.Scopes) < 1 {
+		return nil, apperr.NewBadRequest("an api key needs at least one scope")
+	}
+
+	scopes := make(custom_types.Permissions, 0, len(form.Scopes))
+	for _, scope := range form.Scopes {
+		if !scope.GrantableToAPIKey() {
+			return nil, apperr.NewBadRequest(fmt.Sprintf("[%s] cannot be given to an api key", scope))
+		}
+
+		if !scopes.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if form.ExpiresAt != nil && !form.ExpiresAt.After(s.now()) {
+		return nil, apperr.NewBadRequest("api key expiry must be in the future")
+	}
+
+	rawKey, err := newAPIKey()
+	if err != nil {
+		return nil, apperr.NewInternal("failed to generate api key")
+	}
+
+	apiKey := &models.APIKey{
+		ShopID:    shopID,
+		Name:      name,
+		Prefix:    rawKey[:apiKeyShownPrefixLength],
+		KeyHash:   hashAPIKey(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: form.ExpiresAt,
+		CreatedBy: actor,
+	}
+
+	err = s.store.APIKeyDomain.CreateAPIKey(ctx, dB, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedAPIKey{
+		APIKey: apiKey,
+		Key:    rawKey,
+	}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+) ([]*models.APIKey, error) {
+
+	return s.store.APIKeyDomain.APIKeysByShopID(ctx, dB, shopID)
+}
+
+// RevokeAPIKey stops a key working from the next request on. Revoking a key twice is
+// not an error.
+func (s *apiKeyService) RevokeAPIKey(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	apiKeyID int64,
+	actor string,
+) (*models.APIKey, error) {
+
+	apiKey, err := s.store.APIKeyDomain.APIKeyByID(ctx, dB, shopID, apiKeyID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return nil, apperr.NewNotFound("api key", fmt.Sprint(apiKeyID))
+		}
+		return nil, err
+	}
+
+	if apiKey.IsRevoked() {
+		return apiKey, nil
+	}
+
+	apiKey.RevokedAt = null.NullValue(s.now())
+	apiKey.RevokedBy = null.NullValue(actor)
+
+	err = s.store.APIKeyDomain.CreateAPIKey(ctx, dB, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// AuthenticateAPIKey returns the active key a request was made with and notes that it
+// was used.
+func (s *apiKeyService) AuthenticateAPIKey(
+	ctx context.Context,
+	dB db.DB,
+	rawKey string,
+) (*models.APIKey, error) {
+
+	rawKey = strings.TrimSpace(rawKey)
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, apperr.NewAuthorization("invalid api key")
+	}
+
+	apiKey, err := s.store.APIKeyDomain.APIKeyByHash(ctx, dB, hashAPIKey(rawKey))
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return nil, apperr.NewAuthorization("invalid api key")
+		}
+		return nil, err
+	}
+
+	if apiKey.IsRevoked() {
+		return nil, apperr.NewAuthorization("api key has been revoked")
+	}
+
+	now := s.now()
+	if apiKey.IsExpired(now) {
+		return nil, apperr.NewAuthorization("api key has expired")
+	}
+
+	// the request goes ahead even if this fails; last_used_at is only a hint for staff
+	err = s.store.APIKeyDomain.TouchAPIKeyLastUsed(ctx, dB, apiKey.ID, now, apiKeyLastUsedInterval)
+	if err != nil {
+		loggers.Errorf("failed to record use of api key [%d]: [%+v]", apiKey.ID, err)
+	}
+
+	return apiKey, nil
+}
+
+func newAPIKey() (string, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKey is what is stored and looked up, so a leaked table holds no usable keys.
+func hashAPIKey(rawKey string) string {
+	hash := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,197 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func TestCreateAPIKey_Success(t *testing.T) {
+	ctx := context.Background()
+	mockAPIKeys := new(MockAPIKeyDomain)
+	store := &domain.Store{APIKeyDomain: mockAPIKeys}
+	service := services.NewAPIKeyService(store)
+
+	mockAPIKeys.On("CreateAPIKey", ctx, mock.Anything, mock.AnythingOfType("*models.APIKey")).
+		Return(nil)
+
+	createdAPIKey, err := service.CreateAPIKey(ctx, nil, "shop-1", &dtos.CreateAPIKeyForm{
+		Name:   " Till 1 ",
+		Scopes: []custom_types.Permission{custom_types.PermissionCreateOrders, custom_types.PermissionTakePayments, custom_types.PermissionCreateOrders},
+	}, "owner@example.com")
+
+	assert.NoError(t, err)
+
+	apiKey := createdAPIKey.APIKey
+	assert.Equal(t, "Till 1", apiKey.Name)
+	assert.Equal(t, "shop-1", apiKey.ShopID)
+	assert.Equal(t, custom_types.Permissions{custom_types.PermissionCreateOrders, custom_types.PermissionTakePayments}, apiKey.Scopes)
+	assert.Equal(t, "owner@example.com", apiKey.CreatedBy)
+
+	// only the hash is kept; the prefix is enough to recognise the key
+	assert.True(t, strings.HasPrefix(createdAPIKey.Key, "spos_"))
+	assert.True(t, len(createdAPIKey.Key) > len(apiKey.Prefix))
+	assert.Equal(t, createdAPIKey.Key[:len(apiKey.Prefix)], apiKey.Prefix)
+	assert.NotEmpty(t, apiKey.KeyHash)
+	assert.NotContains(t, apiKey.KeyHash, createdAPIKey.Key)
+
+	mockAPIKeys.AssertExpectations(t)
+}
+
+func TestCreateAPIKey_RefusesBadForms(t *testing.T) {
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name string
+		form *dtos.CreateAPIKeyForm
+	}{
+		{"no name", &dtos.CreateAPIKeyForm{Scopes: []custom_types.Permission{custom_types.PermissionCreateOrders}}},
+		{"no scopes", &dtos.CreateAPIKeyForm{Name: "Till"}},
+		{"staff management", &dtos.CreateAPIKeyForm{Name: "Till", Scopes: []custom_types.Permission{custom_types.PermissionManageStaff}}},
+		{"unknown scope", &dtos.CreateAPIKeyForm{Name: "Till", Scopes: []custom_types.Permission{"orders:everything"}}},
+		{"already expired", &dtos.CreateAPIKeyForm{Name: "Till", Scopes: []custom_types.Permission{custom_types.PermissionCreateOrders}, ExpiresAt: &yesterday}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockAPIKeys := new(MockAPIKeyDomain)
+			store := &domain.Store{APIKeyDomain: mockAPIKeys}
+			service := services.NewAPIKeyService(store)
+
+			_, err := service.CreateAPIKey(ctx, nil, "shop-1", tt.form, "owner@example.com")
+
+			assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+			mockAPIKeys.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAuthenticateAPIKey_NotesTheUse(t *testing.T) {
+	ctx := context.Background()
+	mockAPIKeys := new(MockAPIKeyDomain)
+	store := &domain.Store{APIKeyDomain: mockAPIKeys}
+	service := services.NewAPIKeyService(store)
+
+	apiKey := &models.APIKey{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, ShopID: "shop-1"}
+
+	mockAPIKeys.On("APIKeyByHash", ctx, mock.Anything, mock.MatchedBy(func(keyHash string) bool {
+		return keyHash != "" && !strings.Contains(keyHash, "spos_")
+	})).Return(apiKey, nil)
+	// the domain skips the write when the key was used within the last minute
+	mockAPIKeys.On("TouchAPIKeyLastUsed", ctx, mock.Anything, int64(1), mock.AnythingOfType("time.Time"), time.Minute).
+		Return(nil)
+
+	authenticated, err := service.AuthenticateAPIKey(ctx, nil, " spos_abcdefgh12345678 ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, apiKey, authenticated)
+	mockAPIKeys.AssertExpectations(t)
+}
+
+func TestAuthenticateAPIKey_Refuses(t *testing.T) {
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+
+	tests := []struct {
+		name   string
+		rawKey string
+		apiKey *models.APIKey
+	}{
+		{"not one of ours", "not-one-of-ours", nil},
+		{"unknown key", "spos_unknown", nil},
+		{"revoked key", "spos_revoked", &models.APIKey{RevokedAt: null.NullValue(lastWeek), RevokedBy: null.NullValue("owner@example.com")}},
+		{"expired key", "spos_expired", &models.APIKey{ExpiresAt: &lastWeek}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockAPIKeys := new(MockAPIKeyDomain)
+			store := &domain.Store{APIKeyDomain: mockAPIKeys}
+			service := services.NewAPIKeyService(store)
+
+			if tt.apiKey != nil {
+				mockAPIKeys.On("APIKeyByHash", ctx, mock.Anything, mock.AnythingOfType("string")).
+					Return(tt.apiKey, nil)
+			} else {
+				mockAPIKeys.On("APIKeyByHash", ctx, mock.Anything, mock.AnythingOfType("string")).
+					Return(nil, apperr.NewDatabaseError(sql.ErrNoRows)).Maybe()
+			}
+
+			_, err := service.AuthenticateAPIKey(ctx, nil, tt.rawKey)
+
+			assert.Equal(t, apperr.Authorization, apperr.NewError(err).Type)
+			mockAPIKeys.AssertNotCalled(t, "TouchAPIKeyLastUsed", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRevokeAPIKey_Success(t *testing.T) {
+	ctx := context.Background()
+	mockAPIKeys := new(MockAPIKeyDomain)
+	store := &domain.Store{APIKeyDomain: mockAPIKeys}
+	service := services.NewAPIKeyService(store)
+
+	apiKey := &models.APIKey{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, ShopID: "shop-1"}
+
+	mockAPIKeys.On("APIKeyByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(apiKey, nil)
+	mockAPIKeys.On("CreateAPIKey", ctx, mock.Anything, apiKey).
+		Return(nil)
+
+	revoked, err := service.RevokeAPIKey(ctx, nil, "shop-1", 1, "owner@example.com")
+
+	assert.NoError(t, err)
+	assert.True(t, revoked.IsRevoked())
+	assert.Equal(t, "owner@example.com", *revoked.RevokedBy)
+	mockAPIKeys.AssertExpectations(t)
+}
+
+func TestRevokeAPIKey_AnotherShopsKeyIsNotFound(t *testing.T) {
+	ctx := context.Background()
+	mockAPIKeys := new(MockAPIKeyDomain)
+	store := &domain.Store{APIKeyDomain: mockAPIKeys}
+	service := services.NewAPIKeyService(store)
+
+	mockAPIKeys.On("APIKeyByID", ctx, mock.Anything, "shop-2", int64(1)).
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	_, err := service.RevokeAPIKey(ctx, nil, "shop-2", 1, "owner@shop-2.example.com")
+
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+	mockAPIKeys.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRevokeAPIKey_KeepsTheFirstRevocation(t *testing.T) {
+	ctx := context.Background()
+	mockAPIKeys := new(MockAPIKeyDomain)
+	store := &domain.Store{APIKeyDomain: mockAPIKeys}
+	service := services.NewAPIKeyService(store)
+
+	mockAPIKeys.On("APIKeyByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(&models.APIKey{
+			SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+			ShopID:               "shop-1",
+			RevokedAt:            null.NullValue(time.Now().Add(-time.Hour)),
+			RevokedBy:            null.NullValue("owner@example.com"),
+		}, nil)
+
+	again, err := service.RevokeAPIKey(ctx, nil, "shop-1", 1, "someone@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "owner@example.com", *again.RevokedBy)
+	mockAPIKeys.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
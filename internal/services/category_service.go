@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/domain"
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
@@ -11,9 +13,9 @@ import (
 
 type (
 	CategoryService interface {
-		CreateCategory(ctx context.Context, dB db.DB, form *dtos.CreateCategoryForm) (*models.Category, error)
-		CategoryByID(ctx context.Context, dB db.DB, categoryID int64) (*models.Category, error)
-		DeleteCategory(ctx context.Context, dB db.DB, categoryID int64) (*models.Category, error)
+		CreateCategory(ctx context.Context, dB db.DB, shopID string, form *dtos.CreateCategoryForm) (*models.Category, error)
+		CategoryByID(ctx context.Context, dB db.DB, shopID string, categoryID int64) (*models.Category, error)
+		DeleteCategory(ctx context.Context, dB db.DB, shopID string, categoryID int64) (*models.Category, error)
 		ListCategories(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.CategoryList, error)
 	}
 
@@ -31,16 +33,38 @@ func NewCategoryService(store *domain.Store) CategoryService {
 func (s *categoryService) CreateCategory(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	form *dtos.CreateCategoryForm,
 ) (*models.Category, error) {
 
+	err := checkFormShop(shopID, form.ShopID)
+	if err != nil {
+		return nil, err
+	}
+
 	category := &models.Category{
-		Name:     form.Name,
-		ParentID: form.ParentID,
-		ShopID:   null.NullValue(form.ShopID),
+		Name:       form.Name,
+		ParentID:   form.ParentID,
+		ShopID:     null.NullValue(shopID),
+		TaxClassID: form.TaxClassID,
+	}
+
+	if category.ParentID != nil {
+		_, err = s.store.CategoryDomain.CategoryByID(ctx, dB, shopID, *category.ParentID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return nil, apperr.NewBadRequest(fmt.Sprintf("parent category [%d] does not exist", *category.ParentID))
+			}
+			return nil, err
+		}
+	}
+
+	err = checkTaxClass(ctx, dB, s.store, shopID, category.TaxClassID)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.store.CategoryDomain.CreateCategory(ctx, dB, category)
+	err = s.store.CategoryDomain.CreateCategory(ctx, dB, category)
 	if err != nil {
 		return nil, err
 	}
@@ -51,23 +75,25 @@ func (s *categoryService) CreateCategory(
 func (s *categoryService) CategoryByID(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	categoryID int64,
 ) (*models.Category, error) {
-	return s.store.CategoryDomain.CategoryByID(ctx, dB, categoryID)
+	return s.store.CategoryDomain.CategoryByID(ctx, dB, shopID, categoryID)
 }
 
 func (s *categoryService) DeleteCategory(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	categoryID int64,
 ) (*models.Category, error) {
 
-	category, err := s.store.CategoryDomain.CategoryByID(ctx, dB, categoryID)
+	category, err := s.store.CategoryDomain.CategoryByID(ctx, dB, shopID, categoryID)
 	if err != nil {
 		return &models.Category{}, err
 	}
 
-	err = s.store.CategoryDomain.DeleteCategory(ctx, dB, categoryID)
+	err = s.store.CategoryDomain.DeleteCategory(ctx, dB, shopID, categoryID)
 	if err != nil {
 		return &models.Category{}, err
 	}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"strings"
+)
+
+type (
+	ConsentService interface {
+		SetNotificationPreference(ctx context.Context, dB db.DB, shopID string, customerID int64, form *dtos.NotificationPreferenceForm, actor string) (*models.Customer, error)
+		ListConsentChanges(ctx context.Context, dB db.DB, shopID string, customerID int64, filter *models.Filter) (*models.ConsentChangeList, error)
+		ReceiveInboundSMS(ctx context.Context, dB db.DB, form *dtos.InboundSMSForm) error
+	}
+
+	consentService struct {
+		store *domain.Store
+	}
+)
+
+func NewConsentService(
+	store *domain.Store,
+) ConsentService {
+	return &consentService{
+		store: store,
+	}
+}
+
+// SetNotificationPreference records how a customer told staff they want to hear about
+// their orders. A number that texted STOP cannot be put back on SMS by staff; the
+// customer has to text START.
+func (s *consentService) SetNotificationPreference(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	customerID int64,
+	form *dtos.NotificationPreferenceForm,
+	actor string,
+) (*models.Customer, error) {
+
+	if !form.Preference.IsValid() {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("invalid notification preference [%s]", form.Preference))
+	}
+
+	var customer *models.Customer
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		var err error
+		customer, err = s.store.CustomerDomain.CustomerByID(ctx, operations, shopID, customerID)
+		if err != nil {
+			return err
+		}
+
+		current := customer.NotificationPreference
+		if current == form.Preference {
+			return nil
+		}
+
+		switch form.Preference {
+		case custom_types.NotificationPreferenceSMS:
+			if customer.PhoneNumber == "" {
+				return apperr.NewBadRequest("customer has no phone number to send SMS to")
+			}
+
+			optedOut, err := smsOptedOut(ctx, operations, s.store, customer.PhoneNumber)
+			if err != nil {
+				return err
+			}
+			if optedOut {
+				return apperr.NewErrorWithType(
+					fmt.Errorf("%s texted STOP; the customer has to text START to get SMS again", customer.PhoneNumber),
+					apperr.Conflict,
+				)
+			}
+		case custom_types.NotificationPreferenceEmail:
+			if customer.Email == "" {
+				return apperr.NewBadRequest("customer has no email address")
+			}
+		}
+
+		customer.NotificationPreference = form.Preference
+
+		err = s.store.CustomerDomain.CreateCustomer(ctx, operations, customer)
+		if err != nil {
+			return err
+		}
+
+		consentChange := &models.ConsentChange{
+			ShopID:       null.NullValue(shopID),
+			CustomerID:   null.NullValue(customer.ID),
+			ToPreference: form.Preference,
+			Source:       custom_types.ConsentSourceStaff,
+			ChangedBy:    actor,
+			Reason:       form.Reason,
+		}
+		if current.IsValid() {
+			consentChange.FromPreference = &current
+		}
+		if customer.PhoneNumber != "" {
+			consentChange.PhoneNumber = null.NullValue(consentPhoneNumber(customer.PhoneNumber))
+		}
+
+		return s.store.ConsentChangeDomain.CreateConsentChange(ctx, operations, consentChange)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+// ListConsentChanges is the customer's consent history, including STOP and START texted
+// from their phone number, newest first.
+func (s *consentService) ListConsentChanges(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	customerID int64,
+	filter *models.Filter,
+) (*models.ConsentChangeList, error) {
+
+	customer, err := s.store.CustomerDomain.CustomerByID(ctx, dB, shopID, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	consentChanges, err := s.store.ConsentChangeDomain.ListCustomerConsentChanges(ctx, dB, customer, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.store.ConsentChangeDomain.CustomerConsentChangesCount(ctx, dB, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConsentChangeList{
+		ConsentChanges: consentChanges,
+		Pagination:     models.NewPagination(count, filter.Page, filter.Per),
+	}, nil
+}
+
+// ReceiveInboundSMS handles a text to the shop's number. STOP opts the number out of SMS
+// from every shop and START opts it back in; anything else is logged and dropped, since
+// there is no one reading replies.
+func (s *consentService) ReceiveInboundSMS(
+	ctx context.Context,
+	dB db.DB,
+	form *dtos.InboundSMSForm,
+) error {
+
+	phoneNumber, err := utils.FormatPhoneNumber(form.From)
+	if err != nil {
+		return apperr.NewBadRequest(fmt.Sprintf("invalid sender [%s]", form.From))
+	}
+
+	preference, ok := custom_types.NotificationPreferenceFromKeyword(form.Text)
+	if !ok {
+		loggers.Infof("ignoring inbound sms [%s] from %s: not a keyword", form.ID, phoneNumber)
+		return nil
+	}
+
+	keyword := strings.ToUpper(strings.Fields(form.Text)[0])
+
+	return dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		optedOut, err := smsOptedOut(ctx, operations, s.store, phoneNumber)
+		if err != nil {
+			return err
+		}
+
+		from := custom_types.NotificationPreferenceSMS
+
+		switch {
+		case preference == custom_types.NotificationPreferenceNone && !optedOut:
+			err = s.store.SMSOptOutDomain.CreateSMSOptOut(ctx, operations, &models.SMSOptOut{
+				PhoneNumber: phoneNumber,
+				Keyword:     keyword,
+			})
+		case preference == custom_types.NotificationPreferenceSMS && optedOut:
+			from = custom_types.NotificationPreferenceNone
+			err = s.store.SMSOptOutDomain.DeleteSMSOptOut(ctx, operations, phoneNumber)
+		default:
+			// already where the keyword asks to be
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return s.store.ConsentChangeDomain.CreateConsentChange(ctx, operations, &models.ConsentChange{
+			PhoneNumber:    null.NullValue(phoneNumber),
+			FromPreference: &from,
+			ToPreference:   preference,
+			Source:         custom_types.ConsentSourceSMSKeyword,
+			ChangedBy:      phoneNumber,
+			Reason:         null.NullValue(strings.TrimSpace(form.Text)),
+		})
+	})
+}
+
+// customerChannel is how the order's customer agreed to hear about it and where to send
+// it. An empty channel means they are not to be contacted. Walk-ins get SMS unless their
+// number texted STOP.
+func customerChannel(
+	ctx context.Context,
+	operations db.SQLOperations,
+	store *domain.Store,
+	order *models.Order,
+) (custom_types.NotificationChannel, string, error) {
+
+	preference := custom_types.DefaultNotificationPreference
+	var customer *models.Customer
+
+	if order.CustomerID != nil {
+		var err error
+		customer, err = store.CustomerDomain.CustomerByID(ctx, operations, order.ShopID, *order.CustomerID)
+		if err != nil && !apperr.IsNoRowsErr(err) {
+			return "", "", err
+		}
+
+		if customer != nil && customer.NotificationPreference.IsValid() {
+			preference = customer.NotificationPreference
+		}
+	}
+
+	switch preference {
+	case custom_types.NotificationPreferenceSMS:
+		if order.PhoneNumber == "" {
+			return "", "", nil
+		}
+
+		optedOut, err := smsOptedOut(ctx, operations, store, order.PhoneNumber)
+		if err != nil || optedOut {
+			return "", "", err
+		}
+
+		return custom_types.NotificationChannelSMS, order.PhoneNumber, nil
+	case custom_types.NotificationPreferenceEmail:
+		if customer == nil || customer.Email == "" {
+			return "", "", nil
+		}
+
+		return custom_types.NotificationChannelCustomerEmail, customer.Email, nil
+	}
+
+	return "", "", nil
+}
+
+// smsOptedOut reports whether phoneNumber texted STOP.
+func smsOptedOut(
+	ctx context.Context,
+	operations db.SQLOperations,
+	store *domain.Store,
+	phoneNumber string,
+) (bool, error) {
+
+	_, err := store.SMSOptOutDomain.SMSOptOutByPhoneNumber(ctx, operations, consentPhoneNumber(phoneNumber))
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// consentPhoneNumber is phoneNumber as inbound messages come from, so a number typed in
+// at the till matches the one that texted STOP.
+func consentPhoneNumber(phoneNumber string) string {
+	formatted, err := utils.FormatPhoneNumber(phoneNumber)
+	if err != nil {
+		return phoneNumber
+	}
+	return formatted
+}
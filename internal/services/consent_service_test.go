@@ -0,0 +1,232 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func smsCustomer() *models.Customer {
+	return &models.Customer{
+		SequentialIdentifier:   custom_types.SequentialIdentifier{ID: 3},
+		Name:                   "Wanjiku",
+		PhoneNumber:            "0712 345 678",
+		ShopID:                 "shop-1",
+		NotificationPreference: custom_types.NotificationPreferenceSMS,
+	}
+}
+
+func TestSetNotificationPreference_RecordsTheChange(t *testing.T) {
+	ctx := context.Background()
+	mockCustomers := new(MockCustomerDomain)
+	mockConsentChanges := new(MockConsentChangeDomain)
+	store := &domain.Store{CustomerDomain: mockCustomers, ConsentChangeDomain: mockConsentChanges}
+	service := services.NewConsentService(store)
+
+	var consentChange *models.ConsentChange
+
+	mockCustomers.On("CustomerByID", ctx, mock.Anything, "shop-1", int64(3)).
+		Return(smsCustomer(), nil)
+	mockCustomers.On("CreateCustomer", ctx, mock.Anything, mock.AnythingOfType("*models.Customer")).
+		Return(nil)
+	mockConsentChanges.On("CreateConsentChange", ctx, mock.Anything, mock.AnythingOfType("*models.ConsentChange")).
+		Run(func(args mock.Arguments) { consentChange = args.Get(2).(*models.ConsentChange) }).
+		Return(nil)
+
+	customer, err := service.SetNotificationPreference(ctx, &inlineDB{}, "shop-1", 3, &dtos.NotificationPreferenceForm{
+		Preference: custom_types.NotificationPreferenceNone,
+		Reason:     null.NullValue("asked at the till"),
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.NotificationPreferenceNone, customer.NotificationPreference)
+
+	assert.Equal(t, "shop-1", *consentChange.ShopID)
+	assert.Equal(t, int64(3), *consentChange.CustomerID)
+	assert.Equal(t, "+254712345678", *consentChange.PhoneNumber)
+	assert.Equal(t, custom_types.NotificationPreferenceSMS, *consentChange.FromPreference)
+	assert.Equal(t, custom_types.NotificationPreferenceNone, consentChange.ToPreference)
+	assert.Equal(t, custom_types.ConsentSourceStaff, consentChange.Source)
+	assert.Equal(t, "cashier@example.com", consentChange.ChangedBy)
+	assert.Equal(t, "asked at the till", *consentChange.Reason)
+
+	mockCustomers.AssertExpectations(t)
+	mockConsentChanges.AssertExpectations(t)
+}
+
+func TestSetNotificationPreference_Unchanged(t *testing.T) {
+	ctx := context.Background()
+	mockCustomers := new(MockCustomerDomain)
+	mockConsentChanges := new(MockConsentChangeDomain)
+	store := &domain.Store{CustomerDomain: mockCustomers, ConsentChangeDomain: mockConsentChanges}
+	service := services.NewConsentService(store)
+
+	mockCustomers.On("CustomerByID", ctx, mock.Anything, "shop-1", int64(3)).
+		Return(smsCustomer(), nil)
+
+	_, err := service.SetNotificationPreference(ctx, &inlineDB{}, "shop-1", 3, &dtos.NotificationPreferenceForm{Preference: custom_types.NotificationPreferenceSMS}, "cashier@example.com")
+
+	// nothing changed, so nothing is recorded
+	assert.NoError(t, err)
+	mockCustomers.AssertNotCalled(t, "CreateCustomer", mock.Anything, mock.Anything, mock.Anything)
+	mockConsentChanges.AssertNotCalled(t, "CreateConsentChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetNotificationPreference_Rejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		preference custom_types.NotificationPreference
+		optedOut   bool
+		errType    apperr.Type
+	}{
+		{"unknown preference", "whatsapp", false, apperr.BadRequest},
+		{"email with no address", custom_types.NotificationPreferenceEmail, false, apperr.BadRequest},
+		// staff cannot put a number that texted STOP back on SMS for the customer
+		{"sms after STOP", custom_types.NotificationPreferenceSMS, true, apperr.Conflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockCustomers := new(MockCustomerDomain)
+			mockSMSOptOuts := new(MockSMSOptOutDomain)
+			mockConsentChanges := new(MockConsentChangeDomain)
+			store := &domain.Store{CustomerDomain: mockCustomers, SMSOptOutDomain: mockSMSOptOuts, ConsentChangeDomain: mockConsentChanges}
+			service := services.NewConsentService(store)
+
+			customer := smsCustomer()
+			customer.NotificationPreference = custom_types.NotificationPreferenceNone
+
+			mockCustomers.On("CustomerByID", ctx, mock.Anything, "shop-1", int64(3)).
+				Return(customer, nil).Maybe()
+			if tt.optedOut {
+				mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+					Return(&models.SMSOptOut{PhoneNumber: "+254712345678", Keyword: "STOP"}, nil)
+			}
+
+			_, err := service.SetNotificationPreference(ctx, &inlineDB{}, "shop-1", 3, &dtos.NotificationPreferenceForm{Preference: tt.preference}, "cashier@example.com")
+
+			assert.Equal(t, tt.errType, apperr.NewError(err).Type)
+			mockCustomers.AssertNotCalled(t, "CreateCustomer", mock.Anything, mock.Anything, mock.Anything)
+			mockConsentChanges.AssertNotCalled(t, "CreateConsentChange", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestReceiveInboundSMS_STOPOptsTheNumberOut(t *testing.T) {
+	ctx := context.Background()
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	mockConsentChanges := new(MockConsentChangeDomain)
+	store := &domain.Store{SMSOptOutDomain: mockSMSOptOuts, ConsentChangeDomain: mockConsentChanges}
+	service := services.NewConsentService(store)
+
+	var consentChange *models.ConsentChange
+
+	mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockSMSOptOuts.On("CreateSMSOptOut", ctx, mock.Anything, &models.SMSOptOut{PhoneNumber: "+254712345678", Keyword: "STOP"}).
+		Return(nil)
+	mockConsentChanges.On("CreateConsentChange", ctx, mock.Anything, mock.AnythingOfType("*models.ConsentChange")).
+		Run(func(args mock.Arguments) { consentChange = args.Get(2).(*models.ConsentChange) }).
+		Return(nil)
+
+	err := service.ReceiveInboundSMS(ctx, &inlineDB{}, &dtos.InboundSMSForm{ID: "in-1", From: "+254712345678", Text: " stop please"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, consentChange.ShopID)
+	assert.Nil(t, consentChange.CustomerID)
+	assert.Equal(t, custom_types.NotificationPreferenceSMS, *consentChange.FromPreference)
+	assert.Equal(t, custom_types.NotificationPreferenceNone, consentChange.ToPreference)
+	assert.Equal(t, custom_types.ConsentSourceSMSKeyword, consentChange.Source)
+	assert.Equal(t, "+254712345678", consentChange.ChangedBy)
+
+	mockSMSOptOuts.AssertExpectations(t)
+	mockConsentChanges.AssertExpectations(t)
+}
+
+func TestReceiveInboundSMS_STARTOptsTheNumberBackIn(t *testing.T) {
+	ctx := context.Background()
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	mockConsentChanges := new(MockConsentChangeDomain)
+	store := &domain.Store{SMSOptOutDomain: mockSMSOptOuts, ConsentChangeDomain: mockConsentChanges}
+	service := services.NewConsentService(store)
+
+	var consentChange *models.ConsentChange
+
+	mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+		Return(&models.SMSOptOut{PhoneNumber: "+254712345678", Keyword: "STOP"}, nil)
+	mockSMSOptOuts.On("DeleteSMSOptOut", ctx, mock.Anything, "+254712345678").
+		Return(nil)
+	mockConsentChanges.On("CreateConsentChange", ctx, mock.Anything, mock.AnythingOfType("*models.ConsentChange")).
+		Run(func(args mock.Arguments) { consentChange = args.Get(2).(*models.ConsentChange) }).
+		Return(nil)
+
+	err := service.ReceiveInboundSMS(ctx, &inlineDB{}, &dtos.InboundSMSForm{ID: "in-4", From: "0712345678", Text: "START"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.NotificationPreferenceNone, *consentChange.FromPreference)
+	assert.Equal(t, custom_types.NotificationPreferenceSMS, consentChange.ToPreference)
+
+	mockSMSOptOuts.AssertExpectations(t)
+	mockConsentChanges.AssertExpectations(t)
+}
+
+func TestReceiveInboundSMS_IgnoresWhatChangesNothing(t *testing.T) {
+	loggers.InitLogger("test")
+
+	tests := []struct {
+		name     string
+		text     string
+		optedOut bool
+	}{
+		{"a second STOP", "SITISHA", true},
+		{"START while opted in", "START", false},
+		{"not a keyword", "Is my order ready?", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockSMSOptOuts := new(MockSMSOptOutDomain)
+			mockConsentChanges := new(MockConsentChangeDomain)
+			store := &domain.Store{SMSOptOutDomain: mockSMSOptOuts, ConsentChangeDomain: mockConsentChanges}
+			service := services.NewConsentService(store)
+
+			if tt.optedOut {
+				mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+					Return(&models.SMSOptOut{PhoneNumber: "+254712345678", Keyword: "STOP"}, nil)
+			} else {
+				mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+					Return(nil, apperr.NewDatabaseError(sql.ErrNoRows)).Maybe()
+			}
+
+			err := service.ReceiveInboundSMS(ctx, &inlineDB{}, &dtos.InboundSMSForm{ID: "in-2", From: "+254712345678", Text: tt.text})
+
+			assert.NoError(t, err)
+			mockConsentChanges.AssertNotCalled(t, "CreateConsentChange", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestReceiveInboundSMS_RejectsAnUnknownSender(t *testing.T) {
+	ctx := context.Background()
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	store := &domain.Store{SMSOptOutDomain: mockSMSOptOuts}
+	service := services.NewConsentService(store)
+
+	err := service.ReceiveInboundSMS(ctx, &inlineDB{}, &dtos.InboundSMSForm{ID: "in-5", From: "not a number", Text: "STOP"})
+
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+}
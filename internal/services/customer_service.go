@@ -14,11 +14,11 @@ import (
 
 type (
 	CustomerService interface {
-		CreateCustomer(ctx context.Context, dB db.DB, form *dtos.CreateCustomerForm) (*models.Customer, error)
-		UpdateCustomer(ctx context.Context, dB db.DB, customerID int64, form *dtos.UpdateCustomerForm) (*models.Customer, error)
-		CustomerByID(ctx context.Context, dB db.DB, customerID int64) (*models.Customer, error)
-		CustomerByEmail(ctx context.Context, dB db.DB, Email string) (*models.Customer, error)
-		DeleteCustomer(ctx context.Context, dB db.DB, customerID int64) (*models.Customer, error)
+		CreateCustomer(ctx context.Context, dB db.DB, shopID string, form *dtos.CreateCustomerForm) (*models.Customer, error)
+		UpdateCustomer(ctx context.Context, dB db.DB, shopID string, customerID int64, form *dtos.UpdateCustomerForm) (*models.Customer, error)
+		CustomerByID(ctx context.Context, dB db.DB, shopID string, customerID int64) (*models.Customer, error)
+		CustomerByEmail(ctx context.Context, dB db.DB, shopID string, Email string) (*models.Customer, error)
+		DeleteCustomer(ctx context.Context, dB db.DB, shopID string, customerID int64) (*models.Customer, error)
 		ListShopCustomers(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.CustomerList, error)
 	}
 
@@ -35,10 +35,15 @@ func NewCustomerService(store *domain.Store) CustomerService {
 
 func (s *customerService) CreateCustomer(
     ctx context.Context,
-    dB db.DB, form *dtos.CreateCustomerForm,
+    dB db.DB, shopID string, form *dtos.CreateCustomerForm,
 ) (*models.Customer, error) {
 
-    _, err := s.store.CustomerDomain.CustomerByEmailAndPhoneNumber(ctx, dB, form.Email, form.PhoneNumber)
+    err := checkFormShop(shopID, form.ShopID)
+    if err != nil {
+        return nil, err
+    }
+
+    _, err = s.store.CustomerDomain.CustomerByEmailAndPhoneNumber(ctx, dB, shopID, form.Email, form.PhoneNumber)
 
     if err == nil {
         return nil, apperr.NewErrorWithType(
@@ -51,12 +56,22 @@ func (s *customerService) CreateCustomer(
         return nil, err
     }
 
+    if form.Locale == "" {
+        form.Locale = custom_types.DefaultLocale
+    }
+
+    if !form.Locale.IsValid() {
+        return nil, apperr.NewBadRequest(fmt.Sprintf("invalid locale [%s]", form.Locale))
+    }
+
     customer := &models.Customer{
         Name:         form.Name,
         Email:        form.Email,
         PhoneNumber:  form.PhoneNumber,
         CustomerType: form.CustomerType,
-        ShopID:       form.ShopID,
+        ShopID:       shopID,
+        Locale:       form.Locale,
+        NotificationPreference: custom_types.DefaultNotificationPreference,
     }
 
     err = s.store.CustomerDomain.CreateCustomer(ctx, dB, customer)
@@ -70,11 +85,12 @@ func (s *customerService) CreateCustomer(
 func (s *customerService) UpdateCustomer(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	customerID int64,
 	form *dtos.UpdateCustomerForm,
 ) (*models.Customer, error) {
 
-	customer, err := s.store.CustomerDomain.CustomerByID(ctx, dB, customerID)
+	customer, err := s.store.CustomerDomain.CustomerByID(ctx, dB, shopID, customerID)
 	if err != nil {
 		return &models.Customer{}, err
 	}
@@ -95,6 +111,14 @@ func (s *customerService) UpdateCustomer(
 		customer.CustomerType = custom_types.CustomerType(null.ValueFromNull(form.CustomerType))
 	}
 
+	if form.Locale != nil {
+		locale := custom_types.Locale(null.ValueFromNull(form.Locale))
+		if !locale.IsValid() {
+			return &models.Customer{}, apperr.NewBadRequest(fmt.Sprintf("invalid locale [%s]", locale))
+		}
+		customer.Locale = locale
+	}
+
 	err = s.store.CustomerDomain.CreateCustomer(ctx, dB, customer)
 	if err != nil {
 		return &models.Customer{}, err
@@ -106,19 +130,21 @@ func (s *customerService) UpdateCustomer(
 func (s *customerService) CustomerByID(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	customerID int64,
 ) (*models.Customer, error) {
 
-	return s.store.CustomerDomain.CustomerByID(ctx, dB, customerID)
+	return s.store.CustomerDomain.CustomerByID(ctx, dB, shopID, customerID)
 }
 
 func (s *customerService) CustomerByEmail(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	Email string,
 ) (*models.Customer, error) {
 
-	return s.store.CustomerDomain.CustomerByEmail(ctx, dB, Email)
+	return s.store.CustomerDomain.CustomerByEmail(ctx, dB, shopID, Email)
 }
 
 func (s *customerService) ListShopCustomers(
@@ -153,10 +179,11 @@ func (s *customerService) ListShopCustomers(
 func (s *customerService) DeleteCustomer(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	customerID int64,
 ) (*models.Customer, error) {
 
-	customer, err := s.store.CustomerDomain.CustomerByID(ctx, dB, customerID)
+	customer, err := s.store.CustomerDomain.CustomerByID(ctx, dB, shopID, customerID)
 	if err != nil {
 		return &models.Customer{}, err
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
 	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/domain"
@@ -20,8 +21,8 @@ type MockCustomerDomain struct {
 	mock.Mock
 }
 
-func (m *MockCustomerDomain) CustomerByEmailAndPhoneNumber(ctx context.Context, dB db.SQLOperations, email, phone string) (*models.Customer, error) {
-	args := m.Called(ctx, dB, email, phone)
+func (m *MockCustomerDomain) CustomerByEmailAndPhoneNumber(ctx context.Context, dB db.SQLOperations, shopID string, email, phone string) (*models.Customer, error) {
+	args := m.Called(ctx, dB, shopID, email, phone)
 	if customer, ok := args.Get(0).(*models.Customer); ok {
 		return customer, args.Error(1)
 	}
@@ -31,15 +32,15 @@ func (m *MockCustomerDomain) CreateCustomer(ctx context.Context, dB db.SQLOperat
 	args := m.Called(ctx, dB, customer)
 	return args.Error(0)
 }
-func (m *MockCustomerDomain) CustomerByID(ctx context.Context, dB db.SQLOperations, customerID int64) (*models.Customer, error) {
-	args := m.Called(ctx, dB, customerID)
+func (m *MockCustomerDomain) CustomerByID(ctx context.Context, dB db.SQLOperations, shopID string, customerID int64) (*models.Customer, error) {
+	args := m.Called(ctx, dB, shopID, customerID)
 	if customer, ok := args.Get(0).(*models.Customer); ok {
 		return customer, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
-func (m *MockCustomerDomain) CustomerByEmail(ctx context.Context, dB db.SQLOperations, email string) (*models.Customer, error) {
-	args := m.Called(ctx, dB, email)
+func (m *MockCustomerDomain) CustomerByEmail(ctx context.Context, dB db.SQLOperations, shopID string, email string) (*models.Customer, error) {
+	args := m.Called(ctx, dB, shopID, email)
 	if customer, ok := args.Get(0).(*models.Customer); ok {
 		return customer, args.Error(1)
 	}
@@ -77,18 +78,20 @@ func TestCreateCustomer_Success(t *testing.T) {
 		ShopID:       "shop-1",
 	}
 
-	mockDomain.On("CustomerByEmailAndPhoneNumber", ctx, mock.Anything, form.Email, form.PhoneNumber).
+	mockDomain.On("CustomerByEmailAndPhoneNumber", ctx, mock.Anything, "shop-1", form.Email, form.PhoneNumber).
 		Return(nil, sql.ErrNoRows)
 	mockDomain.On("CreateCustomer", ctx, mock.Anything, mock.AnythingOfType("*models.Customer")).
 		Return(nil)
 
-	customer, err := service.CreateCustomer(ctx, nil, form)
+	customer, err := service.CreateCustomer(ctx, nil, "shop-1", form)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, customer)
 	assert.Equal(t, "John Doe", customer.Name)
 	assert.Equal(t, "john@example.com", customer.Email)
 	assert.Equal(t, "1234567890", customer.PhoneNumber)
+	assert.Equal(t, "shop-1", customer.ShopID)
+	assert.Equal(t, custom_types.LocaleEnglish, customer.Locale)
 
 	mockDomain.AssertExpectations(t)
 }
@@ -109,20 +112,22 @@ func TestUpdateCustomer_Success(t *testing.T) {
 		Email:       ptr("new@mail.com"),
 		PhoneNumber: ptr("999"),
 		CustomerType: ptr(string(custom_types.CustomerTypeIndividual)),
+		Locale:       ptr("sw"),
 	}
 
-	mockDomain.On("CustomerByID", ctx, mock.Anything, customerID).
+	mockDomain.On("CustomerByID", ctx, mock.Anything, "shop-1", customerID).
 		Return(existing, nil)
 	mockDomain.On("CreateCustomer", ctx, mock.Anything, existing).
 		Return(nil)
 
-	updated, err := service.UpdateCustomer(ctx, nil, customerID, form)
+	updated, err := service.UpdateCustomer(ctx, nil, "shop-1", customerID, form)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "New Name", updated.Name)
 	assert.Equal(t, "new@mail.com", updated.Email)
 	assert.Equal(t, "999", updated.PhoneNumber)
 	assert.Equal(t, custom_types.CustomerType(custom_types.CustomerTypeIndividual), updated.CustomerType)
+	assert.Equal(t, custom_types.LocaleSwahili, updated.Locale)
 
 	mockDomain.AssertExpectations(t)
 }
@@ -136,10 +141,10 @@ func TestCustomerByID_Success(t *testing.T) {
 	customerID := int64(10)
 	expected := &models.Customer{Name: "Jane"}
 
-	mockDomain.On("CustomerByID", ctx, mock.Anything, customerID).
+	mockDomain.On("CustomerByID", ctx, mock.Anything, "shop-1", customerID).
 		Return(expected, nil)
 
-	result, err := service.CustomerByID(ctx, nil, customerID)
+	result, err := service.CustomerByID(ctx, nil, "shop-1", customerID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Jane", result.Name)
@@ -155,10 +160,10 @@ func TestCustomerByEmail_Success(t *testing.T) {
 	email := "test@mail.com"
 	expected := &models.Customer{Name: "Tom"}
 
-	mockDomain.On("CustomerByEmail", ctx, mock.Anything, email).
+	mockDomain.On("CustomerByEmail", ctx, mock.Anything, "shop-1", email).
 		Return(expected, nil)
 
-	result, err := service.CustomerByEmail(ctx, nil, email)
+	result, err := service.CustomerByEmail(ctx, nil, "shop-1", email)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Tom", result.Name)
@@ -200,18 +205,53 @@ func TestDeleteCustomer_Success(t *testing.T) {
 	customerID := int64(5)
 	existing := &models.Customer{Name: "Del Target"}
 
-	mockDomain.On("CustomerByID", ctx, mock.Anything, customerID).
+	mockDomain.On("CustomerByID", ctx, mock.Anything, "shop-1", customerID).
 		Return(existing, nil)
 	mockDomain.On("DeleteCustomer", ctx, mock.Anything, existing).
 		Return(nil)
 
-	result, err := service.DeleteCustomer(ctx, nil, customerID)
+	result, err := service.DeleteCustomer(ctx, nil, "shop-1", customerID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Del Target", result.Name)
 	mockDomain.AssertExpectations(t)
 }
 
+func TestCreateCustomer_RefusesAnotherShop(t *testing.T) {
+	ctx := context.Background()
+	mockDomain := new(MockCustomerDomain)
+	store := &domain.Store{CustomerDomain: mockDomain}
+	service := services.NewCustomerService(store)
+
+	form := &dtos.CreateCustomerForm{
+		Name:         "John Doe",
+		Email:        "john@example.com",
+		PhoneNumber:  "1234567890",
+		CustomerType: "REGULAR",
+		ShopID:       "shop-2",
+	}
+
+	_, err := service.CreateCustomer(ctx, nil, "shop-1", form)
+
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+	mockDomain.AssertNotCalled(t, "CreateCustomer", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateCustomer_RejectsUnknownLocale(t *testing.T) {
+	ctx := context.Background()
+	mockDomain := new(MockCustomerDomain)
+	store := &domain.Store{CustomerDomain: mockDomain}
+	service := services.NewCustomerService(store)
+
+	mockDomain.On("CustomerByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(&models.Customer{Name: "John Doe", Locale: custom_types.LocaleEnglish}, nil)
+
+	_, err := service.UpdateCustomer(ctx, nil, "shop-1", 1, &dtos.UpdateCustomerForm{Locale: ptr("fr")})
+
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+	mockDomain.AssertNotCalled(t, "CreateCustomer", mock.Anything, mock.Anything, mock.Anything)
+}
+
 // helper for pointers
 func ptr[T any](v T) *T {
 	return &v
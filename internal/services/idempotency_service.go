@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyClaimTimeout is how long a request may hold its key unfinished. Past it
+	// the request is taken to have died without releasing the key, and a retry may claim it.
+	idempotencyClaimTimeout = 5 * time.Minute
+)
+
+type (
+	IdempotencyService interface {
+		StartRequest(ctx context.Context, dB db.DB, shopID, key, requestPath, requestHash string) (*models.IdempotencyKey, error)
+		CompleteRequest(ctx context.Context, dB db.DB, idempotencyKey *models.IdempotencyKey, status int, body []byte) error
+		ReleaseRequest(ctx context.Context, dB db.DB, idempotencyKey *models.IdempotencyKey) error
+	}
+
+	idempotencyService struct {
+		store *domain.Store
+		now   func() time.Time
+	}
+)
+
+func NewIdempotencyService(store *domain.Store) IdempotencyService {
+	return &idempotencyService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// StartRequest claims key for a request made for shopID. A first use returns a fresh key that is not yet
+// completed. Replays of a finished request return the stored key so its response can be
+// sent again; a different body under the same key, or a replay while the first request is
+// still running, is a Conflict. A key left unfinished for longer than idempotencyClaimTimeout
+// is taken over by the replay.
+func (s *idempotencyService) StartRequest(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	key string,
+	requestPath string,
+	requestHash string,
+) (*models.IdempotencyKey, error) {
+
+	if shopID == "" {
+		return nil, apperr.NewInternal("idempotency keys are kept per shop; the request has no shop")
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("idempotency key cannot be longer than %d characters", maxIdempotencyKeyLength))
+	}
+
+	idempotencyKey := &models.IdempotencyKey{
+		ShopID:      shopID,
+		Key:         key,
+		RequestPath: requestPath,
+		RequestHash: requestHash,
+	}
+
+	created, err := s.store.IdempotencyKeyDomain.CreateIdempotencyKey(ctx, dB, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		return idempotencyKey, nil
+	}
+
+	existing, err := s.store.IdempotencyKeyDomain.IdempotencyKeyByKey(ctx, dB, shopID, key, requestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, apperr.NewErrorWithType(
+			fmt.Errorf("idempotency key [%s] was already used with a different request", key),
+			apperr.Conflict,
+		)
+	}
+
+	if !existing.IsCompleted() {
+		staleBefore := s.now().Add(-idempotencyClaimTimeout)
+		if existing.CreatedAt.Before(staleBefore) {
+			takenOver, err := s.store.IdempotencyKeyDomain.TakeOverIdempotencyKey(ctx, dB, existing, staleBefore)
+			if err != nil {
+				return nil, err
+			}
+
+			if takenOver {
+				return existing, nil
+			}
+		}
+
+		return nil, apperr.NewErrorWithType(
+			fmt.Errorf("a request with idempotency key [%s] is still being processed", key),
+			apperr.Conflict,
+		)
+	}
+
+	return existing, nil
+}
+
+func (s *idempotencyService) CompleteRequest(
+	ctx context.Context,
+	dB db.DB,
+	idempotencyKey *models.IdempotencyKey,
+	status int,
+	body []byte,
+) error {
+
+	idempotencyKey.ResponseStatus = null.NullValue(status)
+	idempotencyKey.ResponseBody = body
+
+	return s.store.IdempotencyKeyDomain.CompleteIdempotencyKey(ctx, dB, idempotencyKey)
+}
+
+// ReleaseRequest gives the key back so the client can retry, used when the request failed
+// in a way that says nothing about the request itself.
+func (s *idempotencyService) ReleaseRequest(
+	ctx context.Context,
+	dB db.DB,
+	idempotencyKey *models.IdempotencyKey,
+) error {
+	return s.store.IdempotencyKeyDomain.DeleteIdempotencyKey(ctx, dB, idempotencyKey.ID)
+}
@@ -0,0 +1,998 @@
+package services_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+// inlineDB runs a transaction's operations straight away against the mocked domains,
+// which are called with nil operations.
+type inlineDB struct {
+	db.DB
+}
+
+func (d *inlineDB) InTransaction(ctx context.Context, operations func(context.Context, db.SQLOperations) error) error {
+	return operations(ctx, nil)
+}
+
+// --- Services ---
+
+type MockStockService struct {
+	mock.Mock
+}
+
+func (m *MockStockService) RecordStockMovement(ctx context.Context, dB db.DB, shopID string, productID int64, form *dtos.CreateStockMovementForm, actor string) (*models.StockMovement, error) {
+	args := m.Called(ctx, dB, shopID, productID, form, actor)
+	if value, ok := args.Get(0).(*models.StockMovement); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockStockService) ApplyStockMovements(ctx context.Context, dB db.SQLOperations, shopID string, movements []*models.StockMovement) error {
+	args := m.Called(ctx, dB, shopID, movements)
+	return args.Error(0)
+}
+
+func (m *MockStockService) ListProductStockMovements(ctx context.Context, dB db.DB, shopID string, productID int64, filter *models.Filter) (*models.StockMovementList, error) {
+	args := m.Called(ctx, dB, shopID, productID, filter)
+	if value, ok := args.Get(0).(*models.StockMovementList); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockStockService) ProductStockLevel(ctx context.Context, dB db.DB, shopID string, productID int64) (*models.StockLevel, error) {
+	args := m.Called(ctx, dB, shopID, productID)
+	if value, ok := args.Get(0).(*models.StockLevel); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockNotificationOutboxService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationOutboxService) EnqueueOrderConfirmation(ctx context.Context, dB db.SQLOperations, order *models.Order) error {
+	args := m.Called(ctx, dB, order)
+	return args.Error(0)
+}
+
+func (m *MockNotificationOutboxService) EnqueueOrderReturn(ctx context.Context, dB db.SQLOperations, order *models.Order, orderReturn *models.OrderReturn) error {
+	args := m.Called(ctx, dB, order, orderReturn)
+	return args.Error(0)
+}
+
+func (m *MockNotificationOutboxService) DeliverNext(ctx context.Context, dB db.DB) (bool, error) {
+	args := m.Called(ctx, dB)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNotificationOutboxService) ListNotifications(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.OutboxNotificationList, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	if value, ok := args.Get(0).(*models.OutboxNotificationList); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationOutboxService) ReplayNotification(ctx context.Context, dB db.DB, shopID string, notificationID int64) (*models.OutboxNotification, error) {
+	args := m.Called(ctx, dB, shopID, notificationID)
+	if value, ok := args.Get(0).(*models.OutboxNotification); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockNotificationTemplateService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationTemplateService) RenderNotification(ctx context.Context, operations db.SQLOperations, event custom_types.NotificationEvent, channel custom_types.NotificationChannel, order *models.Order, orderReturn *models.OrderReturn) (*models.RenderedNotification, error) {
+	args := m.Called(ctx, operations, event, channel, order, orderReturn)
+	if value, ok := args.Get(0).(*models.RenderedNotification); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateService) ListNotificationTemplates(ctx context.Context, dB db.DB, shopID string) ([]*models.NotificationTemplate, error) {
+	args := m.Called(ctx, dB, shopID)
+	if value, ok := args.Get(0).([]*models.NotificationTemplate); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateService) SaveNotificationTemplate(ctx context.Context, dB db.DB, shopID string, form *dtos.NotificationTemplateForm) (*models.NotificationTemplate, error) {
+	args := m.Called(ctx, dB, shopID, form)
+	if value, ok := args.Get(0).(*models.NotificationTemplate); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateService) DeleteNotificationTemplate(ctx context.Context, dB db.DB, shopID string, templateID int64) (*models.NotificationTemplate, error) {
+	args := m.Called(ctx, dB, shopID, templateID)
+	if value, ok := args.Get(0).(*models.NotificationTemplate); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateService) PreviewNotificationTemplate(ctx context.Context, dB db.DB, shopID string, form *dtos.PreviewNotificationTemplateForm) (*models.RenderedNotification, error) {
+	args := m.Called(ctx, dB, shopID, form)
+	if value, ok := args.Get(0).(*models.RenderedNotification); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// --- Providers ---
+
+type MockOrderNotification struct {
+	mock.Mock
+}
+
+func (m *MockOrderNotification) SendOrderSMS(order *models.Order, message string) (*models.SMSMessage, error) {
+	args := m.Called(order, message)
+	if value, ok := args.Get(0).(*models.SMSMessage); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderNotification) SendOrderEmail(order *models.Order, subject, body string) error {
+	args := m.Called(order, subject, body)
+	return args.Error(0)
+}
+
+func (m *MockOrderNotification) SendCustomerEmail(order *models.Order, to, subject, body string) error {
+	args := m.Called(order, to, subject, body)
+	return args.Error(0)
+}
+
+type MockMpesaClient struct {
+	mock.Mock
+}
+
+func (m *MockMpesaClient) STKPush(ctx context.Context, phoneNumber string, amount int64, accountReference, description string) (*models.STKPushResponse, error) {
+	args := m.Called(ctx, phoneNumber, amount, accountReference, description)
+	if value, ok := args.Get(0).(*models.STKPushResponse); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// --- Domains ---
+
+type MockProductDomain struct {
+	mock.Mock
+}
+
+func (m *MockProductDomain) CreateProduct(ctx context.Context, dB db.SQLOperations, product *models.Product) error {
+	args := m.Called(ctx, dB, product)
+	return args.Error(0)
+}
+
+func (m *MockProductDomain) ProductByID(ctx context.Context, dB db.SQLOperations, shopID string, productID int64) (*models.Product, error) {
+	args := m.Called(ctx, dB, shopID, productID)
+	if value, ok := args.Get(0).(*models.Product); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockProductDomain) LockProductByID(ctx context.Context, dB db.SQLOperations, shopID string, productID int64) (*models.Product, error) {
+	args := m.Called(ctx, dB, shopID, productID)
+	if value, ok := args.Get(0).(*models.Product); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockProductDomain) AdjustStock(ctx context.Context, dB db.SQLOperations, shopID string, productID int64, quantity int64) (int64, error) {
+	args := m.Called(ctx, dB, shopID, productID, quantity)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockProductDomain) ListProducts(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Product, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	if value, ok := args.Get(0).([]*models.Product); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockProductDomain) ProductCount(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockProductDomain) DeleteProduct(ctx context.Context, dB db.SQLOperations, shopID string, productID int64) error {
+	args := m.Called(ctx, dB, shopID, productID)
+	return args.Error(0)
+}
+
+func (m *MockProductDomain) GetAveragePriceByCategory(ctx context.Context, dB db.SQLOperations, shopID string, categoryID int64) (custom_types.Money, error) {
+	args := m.Called(ctx, dB, shopID, categoryID)
+	return args.Get(0).(custom_types.Money), args.Error(1)
+}
+
+func (m *MockProductDomain) GetCategoryHierarchy(ctx context.Context, dB db.SQLOperations, shopID string, categoryID int64) ([]int64, error) {
+	args := m.Called(ctx, dB, shopID, categoryID)
+	if value, ok := args.Get(0).([]int64); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockOrderDomain struct {
+	mock.Mock
+}
+
+func (m *MockOrderDomain) CreateOrder(ctx context.Context, dB db.SQLOperations, order *models.Order) error {
+	args := m.Called(ctx, dB, order)
+	return args.Error(0)
+}
+
+func (m *MockOrderDomain) OrderByID(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) (*models.Order, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).(*models.Order); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderDomain) LockOrderByID(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) (*models.Order, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).(*models.Order); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderDomain) LisOrders(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Order, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	if value, ok := args.Get(0).([]*models.Order); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderDomain) OrderCount(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderDomain) NextOrderReferenceSequence(ctx context.Context, dB db.SQLOperations) (int64, error) {
+	args := m.Called(ctx, dB)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockOrderItemDomain struct {
+	mock.Mock
+}
+
+func (m *MockOrderItemDomain) InsertOrderItems(ctx context.Context, dB db.SQLOperations, orderItems []*models.OrderItem) error {
+	args := m.Called(ctx, dB, orderItems)
+	return args.Error(0)
+}
+
+func (m *MockOrderItemDomain) OrderItemByID(ctx context.Context, dB db.SQLOperations, shopID string, orderItemID int64) (*models.OrderItem, error) {
+	args := m.Called(ctx, dB, shopID, orderItemID)
+	if value, ok := args.Get(0).(*models.OrderItem); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderItemDomain) OrderItemCount(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, orderID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderItemDomain) OrderItems(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64, filter *models.Filter) ([]*models.OrderItem, error) {
+	args := m.Called(ctx, dB, shopID, orderID, filter)
+	if value, ok := args.Get(0).([]*models.OrderItem); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderItemDomain) LockOrderItems(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) ([]*models.OrderItem, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).([]*models.OrderItem); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderItemDomain) UpdateReturnedQuantity(ctx context.Context, dB db.SQLOperations, orderItem *models.OrderItem) error {
+	args := m.Called(ctx, dB, orderItem)
+	return args.Error(0)
+}
+
+func (m *MockOrderItemDomain) DeleteOrderItems(ctx context.Context, dB db.SQLOperations, shopID string, productID int64) error {
+	args := m.Called(ctx, dB, shopID, productID)
+	return args.Error(0)
+}
+
+type MockOrderStatusTransitionDomain struct {
+	mock.Mock
+}
+
+func (m *MockOrderStatusTransitionDomain) CreateOrderStatusTransition(ctx context.Context, dB db.SQLOperations, transition *models.OrderStatusTransition) error {
+	args := m.Called(ctx, dB, transition)
+	return args.Error(0)
+}
+
+func (m *MockOrderStatusTransitionDomain) ListOrderStatusTransitions(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64, filter *models.Filter) ([]*models.OrderStatusTransition, error) {
+	args := m.Called(ctx, dB, shopID, orderID, filter)
+	if value, ok := args.Get(0).([]*models.OrderStatusTransition); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderStatusTransitionDomain) OrderStatusTransitionsCount(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, orderID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+type MockStockMovementDomain struct {
+	mock.Mock
+}
+
+func (m *MockStockMovementDomain) CreateStockMovement(ctx context.Context, dB db.SQLOperations, movement *models.StockMovement) error {
+	args := m.Called(ctx, dB, movement)
+	return args.Error(0)
+}
+
+func (m *MockStockMovementDomain) ListProductStockMovements(ctx context.Context, dB db.SQLOperations, shopID string, productID int64, filter *models.Filter) ([]*models.StockMovement, error) {
+	args := m.Called(ctx, dB, shopID, productID, filter)
+	if value, ok := args.Get(0).([]*models.StockMovement); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockStockMovementDomain) ProductStockMovementsCount(ctx context.Context, dB db.SQLOperations, shopID string, productID int64, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, productID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStockMovementDomain) LedgerStock(ctx context.Context, dB db.SQLOperations, shopID string, productID int64) (int64, error) {
+	args := m.Called(ctx, dB, shopID, productID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockOrderReturnDomain struct {
+	mock.Mock
+}
+
+func (m *MockOrderReturnDomain) CreateOrderReturn(ctx context.Context, dB db.SQLOperations, orderReturn *models.OrderReturn) error {
+	args := m.Called(ctx, dB, orderReturn)
+	return args.Error(0)
+}
+
+func (m *MockOrderReturnDomain) ListOrderReturns(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64, filter *models.Filter) ([]*models.OrderReturn, error) {
+	args := m.Called(ctx, dB, shopID, orderID, filter)
+	if value, ok := args.Get(0).([]*models.OrderReturn); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderReturnDomain) OrderReturnsCount(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, orderID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderReturnDomain) OrderReturnItems(ctx context.Context, dB db.SQLOperations, shopID string, orderReturnID int64) ([]*models.OrderReturnItem, error) {
+	args := m.Called(ctx, dB, shopID, orderReturnID)
+	if value, ok := args.Get(0).([]*models.OrderReturnItem); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockRefundDomain struct {
+	mock.Mock
+}
+
+func (m *MockRefundDomain) CreateRefund(ctx context.Context, dB db.SQLOperations, refund *models.Refund) error {
+	args := m.Called(ctx, dB, refund)
+	return args.Error(0)
+}
+
+func (m *MockRefundDomain) RefundsByOrderReturnID(ctx context.Context, dB db.SQLOperations, shopID string, orderReturnID int64) ([]*models.Refund, error) {
+	args := m.Called(ctx, dB, shopID, orderReturnID)
+	if value, ok := args.Get(0).([]*models.Refund); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRefundDomain) RefundsByOrderID(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) ([]*models.Refund, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).([]*models.Refund); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockPromotionDomain struct {
+	mock.Mock
+}
+
+func (m *MockPromotionDomain) CreatePromotion(ctx context.Context, dB db.SQLOperations, promotion *models.Promotion) error {
+	args := m.Called(ctx, dB, promotion)
+	return args.Error(0)
+}
+
+func (m *MockPromotionDomain) PromotionByID(ctx context.Context, dB db.SQLOperations, shopID string, promotionID int64) (*models.Promotion, error) {
+	args := m.Called(ctx, dB, shopID, promotionID)
+	if value, ok := args.Get(0).(*models.Promotion); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPromotionDomain) PromotionByCouponCode(ctx context.Context, dB db.SQLOperations, shopID string, couponCode string) (*models.Promotion, error) {
+	args := m.Called(ctx, dB, shopID, couponCode)
+	if value, ok := args.Get(0).(*models.Promotion); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPromotionDomain) AutomaticPromotions(ctx context.Context, dB db.SQLOperations, shopID string, now time.Time) ([]*models.Promotion, error) {
+	args := m.Called(ctx, dB, shopID, now)
+	if value, ok := args.Get(0).([]*models.Promotion); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPromotionDomain) ListShopPromotions(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Promotion, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	if value, ok := args.Get(0).([]*models.Promotion); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPromotionDomain) ShopPromotionsCount(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPromotionDomain) RedeemPromotion(ctx context.Context, dB db.SQLOperations, redemption *models.PromotionRedemption) error {
+	args := m.Called(ctx, dB, redemption)
+	return args.Error(0)
+}
+
+func (m *MockPromotionDomain) ReleaseOrderPromotions(ctx context.Context, dB db.SQLOperations, orderID int64) error {
+	args := m.Called(ctx, dB, orderID)
+	return args.Error(0)
+}
+
+func (m *MockPromotionDomain) CreateOrderItemPromotions(ctx context.Context, dB db.SQLOperations, orderItemPromotions []*models.OrderItemPromotion) error {
+	args := m.Called(ctx, dB, orderItemPromotions)
+	return args.Error(0)
+}
+
+func (m *MockPromotionDomain) OrderItemPromotions(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) ([]*models.OrderItemPromotion, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).([]*models.OrderItemPromotion); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockMpesaSTKRequestDomain struct {
+	mock.Mock
+}
+
+func (m *MockMpesaSTKRequestDomain) CreateMpesaSTKRequest(ctx context.Context, dB db.SQLOperations, stkRequest *models.MpesaSTKRequest) error {
+	args := m.Called(ctx, dB, stkRequest)
+	return args.Error(0)
+}
+
+func (m *MockMpesaSTKRequestDomain) LockMpesaSTKRequestByCheckoutID(ctx context.Context, dB db.SQLOperations, checkoutRequestID string) (*models.MpesaSTKRequest, error) {
+	args := m.Called(ctx, dB, checkoutRequestID)
+	if value, ok := args.Get(0).(*models.MpesaSTKRequest); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMpesaSTKRequestDomain) MpesaSTKRequestsByOrderID(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) ([]*models.MpesaSTKRequest, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).([]*models.MpesaSTKRequest); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockPaymentDomain struct {
+	mock.Mock
+}
+
+func (m *MockPaymentDomain) CreatePayment(ctx context.Context, dB db.SQLOperations, payment *models.Payment) error {
+	args := m.Called(ctx, dB, payment)
+	return args.Error(0)
+}
+
+func (m *MockPaymentDomain) LockPaymentByID(ctx context.Context, dB db.SQLOperations, shopID string, paymentID int64) (*models.Payment, error) {
+	args := m.Called(ctx, dB, shopID, paymentID)
+	if value, ok := args.Get(0).(*models.Payment); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentDomain) PaymentByProviderReference(ctx context.Context, dB db.SQLOperations, paymentMethod custom_types.PaymentMethod, providerReference string) (*models.Payment, error) {
+	args := m.Called(ctx, dB, paymentMethod, providerReference)
+	if value, ok := args.Get(0).(*models.Payment); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentDomain) PaymentsByOrderID(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) ([]*models.Payment, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).([]*models.Payment); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockAuthSessionDomain struct {
+	mock.Mock
+}
+
+func (m *MockAuthSessionDomain) CreateAuthSession(ctx context.Context, dB db.SQLOperations, session *models.AuthSession) error {
+	args := m.Called(ctx, dB, session)
+	return args.Error(0)
+}
+
+func (m *MockAuthSessionDomain) AuthSessionByID(ctx context.Context, dB db.SQLOperations, sessionID int64) (*models.AuthSession, error) {
+	args := m.Called(ctx, dB, sessionID)
+	if value, ok := args.Get(0).(*models.AuthSession); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthSessionDomain) LockAuthSessionByID(ctx context.Context, dB db.SQLOperations, sessionID int64) (*models.AuthSession, error) {
+	args := m.Called(ctx, dB, sessionID)
+	if value, ok := args.Get(0).(*models.AuthSession); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAuthSessionDomain) CreateRefreshToken(ctx context.Context, dB db.SQLOperations, refreshToken *models.RefreshToken) error {
+	args := m.Called(ctx, dB, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockAuthSessionDomain) LockRefreshTokenByHash(ctx context.Context, dB db.SQLOperations, tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, dB, tokenHash)
+	if value, ok := args.Get(0).(*models.RefreshToken); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockUserDomain struct {
+	mock.Mock
+}
+
+func (m *MockUserDomain) CreateUser(ctx context.Context, dB db.SQLOperations, user *models.User) error {
+	args := m.Called(ctx, dB, user)
+	return args.Error(0)
+}
+
+func (m *MockUserDomain) UserByID(ctx context.Context, dB db.SQLOperations, userID int64) (*models.User, error) {
+	args := m.Called(ctx, dB, userID)
+	if value, ok := args.Get(0).(*models.User); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserDomain) UserBySubject(ctx context.Context, dB db.SQLOperations, subject string) (*models.User, error) {
+	args := m.Called(ctx, dB, subject)
+	if value, ok := args.Get(0).(*models.User); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserDomain) UserByEmail(ctx context.Context, dB db.SQLOperations, email string) (*models.User, error) {
+	args := m.Called(ctx, dB, email)
+	if value, ok := args.Get(0).(*models.User); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserDomain) LockUserBySubject(ctx context.Context, dB db.SQLOperations, subject string) (*models.User, error) {
+	args := m.Called(ctx, dB, subject)
+	if value, ok := args.Get(0).(*models.User); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserDomain) LockUserByEmail(ctx context.Context, dB db.SQLOperations, email string) (*models.User, error) {
+	args := m.Called(ctx, dB, email)
+	if value, ok := args.Get(0).(*models.User); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockShopMembershipDomain struct {
+	mock.Mock
+}
+
+func (m *MockShopMembershipDomain) CreateShopMembership(ctx context.Context, dB db.SQLOperations, membership *models.ShopMembership) error {
+	args := m.Called(ctx, dB, membership)
+	return args.Error(0)
+}
+
+func (m *MockShopMembershipDomain) DeleteShopMembership(ctx context.Context, dB db.SQLOperations, membershipID int64) error {
+	args := m.Called(ctx, dB, membershipID)
+	return args.Error(0)
+}
+
+func (m *MockShopMembershipDomain) ShopMembership(ctx context.Context, dB db.SQLOperations, userID int64, shopID string) (*models.ShopMembership, error) {
+	args := m.Called(ctx, dB, userID, shopID)
+	if value, ok := args.Get(0).(*models.ShopMembership); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockShopMembershipDomain) ShopMembershipsByUserID(ctx context.Context, dB db.SQLOperations, userID int64) ([]*models.ShopMembership, error) {
+	args := m.Called(ctx, dB, userID)
+	if value, ok := args.Get(0).([]*models.ShopMembership); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockShopMembershipDomain) ShopMembershipsByShopID(ctx context.Context, dB db.SQLOperations, shopID string) ([]*models.ShopMembership, error) {
+	args := m.Called(ctx, dB, shopID)
+	if value, ok := args.Get(0).([]*models.ShopMembership); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockShopMembershipDomain) LockShopMembershipsByShopID(ctx context.Context, dB db.SQLOperations, shopID string) ([]*models.ShopMembership, error) {
+	args := m.Called(ctx, dB, shopID)
+	if value, ok := args.Get(0).([]*models.ShopMembership); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockShopDomain struct {
+	mock.Mock
+}
+
+func (m *MockShopDomain) CreateShop(ctx context.Context, dB db.SQLOperations, shop *models.Shop) error {
+	args := m.Called(ctx, dB, shop)
+	return args.Error(0)
+}
+
+func (m *MockShopDomain) EnsureShop(ctx context.Context, dB db.SQLOperations, shopID string) error {
+	args := m.Called(ctx, dB, shopID)
+	return args.Error(0)
+}
+
+func (m *MockShopDomain) UpdateShop(ctx context.Context, dB db.SQLOperations, shop *models.Shop) error {
+	args := m.Called(ctx, dB, shop)
+	return args.Error(0)
+}
+
+func (m *MockShopDomain) ShopByID(ctx context.Context, dB db.SQLOperations, shopID string) (*models.Shop, error) {
+	args := m.Called(ctx, dB, shopID)
+	if value, ok := args.Get(0).(*models.Shop); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockShopDomain) ShopsByUserID(ctx context.Context, dB db.SQLOperations, userID int64) ([]*models.Shop, error) {
+	args := m.Called(ctx, dB, userID)
+	if value, ok := args.Get(0).([]*models.Shop); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockAPIKeyDomain struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyDomain) CreateAPIKey(ctx context.Context, dB db.SQLOperations, apiKey *models.APIKey) error {
+	args := m.Called(ctx, dB, apiKey)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyDomain) APIKeyByHash(ctx context.Context, dB db.SQLOperations, keyHash string) (*models.APIKey, error) {
+	args := m.Called(ctx, dB, keyHash)
+	if value, ok := args.Get(0).(*models.APIKey); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyDomain) APIKeyByID(ctx context.Context, dB db.SQLOperations, shopID string, apiKeyID int64) (*models.APIKey, error) {
+	args := m.Called(ctx, dB, shopID, apiKeyID)
+	if value, ok := args.Get(0).(*models.APIKey); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyDomain) APIKeysByShopID(ctx context.Context, dB db.SQLOperations, shopID string) ([]*models.APIKey, error) {
+	args := m.Called(ctx, dB, shopID)
+	if value, ok := args.Get(0).([]*models.APIKey); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyDomain) TouchAPIKeyLastUsed(ctx context.Context, dB db.SQLOperations, apiKeyID int64, usedAt time.Time, interval time.Duration) error {
+	args := m.Called(ctx, dB, apiKeyID, usedAt, interval)
+	return args.Error(0)
+}
+
+type MockNotificationOutboxDomain struct {
+	mock.Mock
+}
+
+func (m *MockNotificationOutboxDomain) CreateOutboxNotification(ctx context.Context, dB db.SQLOperations, notification *models.OutboxNotification) error {
+	args := m.Called(ctx, dB, notification)
+	return args.Error(0)
+}
+
+func (m *MockNotificationOutboxDomain) LockOutboxNotificationByID(ctx context.Context, dB db.SQLOperations, shopID string, notificationID int64) (*models.OutboxNotification, error) {
+	args := m.Called(ctx, dB, shopID, notificationID)
+	if value, ok := args.Get(0).(*models.OutboxNotification); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationOutboxDomain) LockNextDueOutboxNotification(ctx context.Context, dB db.SQLOperations, now time.Time) (*models.OutboxNotification, error) {
+	args := m.Called(ctx, dB, now)
+	if value, ok := args.Get(0).(*models.OutboxNotification); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationOutboxDomain) ListOutboxNotifications(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) ([]*models.OutboxNotification, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	if value, ok := args.Get(0).([]*models.OutboxNotification); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationOutboxDomain) OutboxNotificationsCount(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+type MockSMSMessageDomain struct {
+	mock.Mock
+}
+
+func (m *MockSMSMessageDomain) CreateSMSMessage(ctx context.Context, dB db.SQLOperations, smsMessage *models.SMSMessage) error {
+	args := m.Called(ctx, dB, smsMessage)
+	return args.Error(0)
+}
+
+func (m *MockSMSMessageDomain) LockSMSMessageByMessageID(ctx context.Context, dB db.SQLOperations, messageID string) (*models.SMSMessage, error) {
+	args := m.Called(ctx, dB, messageID)
+	if value, ok := args.Get(0).(*models.SMSMessage); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockSMSMessageDomain) ListSMSMessages(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) ([]*models.SMSMessage, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	if value, ok := args.Get(0).([]*models.SMSMessage); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockSMSMessageDomain) SMSMessagesCount(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+type MockNotificationTemplateDomain struct {
+	mock.Mock
+}
+
+func (m *MockNotificationTemplateDomain) CreateNotificationTemplate(ctx context.Context, dB db.SQLOperations, notificationTemplate *models.NotificationTemplate) error {
+	args := m.Called(ctx, dB, notificationTemplate)
+	return args.Error(0)
+}
+
+func (m *MockNotificationTemplateDomain) DeleteNotificationTemplate(ctx context.Context, dB db.SQLOperations, notificationTemplate *models.NotificationTemplate) error {
+	args := m.Called(ctx, dB, notificationTemplate)
+	return args.Error(0)
+}
+
+func (m *MockNotificationTemplateDomain) NotificationTemplateByID(ctx context.Context, dB db.SQLOperations, shopID string, templateID int64) (*models.NotificationTemplate, error) {
+	args := m.Called(ctx, dB, shopID, templateID)
+	if value, ok := args.Get(0).(*models.NotificationTemplate); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateDomain) NotificationTemplate(ctx context.Context, dB db.SQLOperations, shopID string, event custom_types.NotificationEvent, channel custom_types.NotificationChannel, locale custom_types.Locale) (*models.NotificationTemplate, error) {
+	args := m.Called(ctx, dB, shopID, event, channel, locale)
+	if value, ok := args.Get(0).(*models.NotificationTemplate); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateDomain) NotificationTemplatesByShopID(ctx context.Context, dB db.SQLOperations, shopID string) ([]*models.NotificationTemplate, error) {
+	args := m.Called(ctx, dB, shopID)
+	if value, ok := args.Get(0).([]*models.NotificationTemplate); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockSMSOptOutDomain struct {
+	mock.Mock
+}
+
+func (m *MockSMSOptOutDomain) CreateSMSOptOut(ctx context.Context, dB db.SQLOperations, smsOptOut *models.SMSOptOut) error {
+	args := m.Called(ctx, dB, smsOptOut)
+	return args.Error(0)
+}
+
+func (m *MockSMSOptOutDomain) DeleteSMSOptOut(ctx context.Context, dB db.SQLOperations, phoneNumber string) error {
+	args := m.Called(ctx, dB, phoneNumber)
+	return args.Error(0)
+}
+
+func (m *MockSMSOptOutDomain) SMSOptOutByPhoneNumber(ctx context.Context, dB db.SQLOperations, phoneNumber string) (*models.SMSOptOut, error) {
+	args := m.Called(ctx, dB, phoneNumber)
+	if value, ok := args.Get(0).(*models.SMSOptOut); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockConsentChangeDomain struct {
+	mock.Mock
+}
+
+func (m *MockConsentChangeDomain) CreateConsentChange(ctx context.Context, dB db.SQLOperations, consentChange *models.ConsentChange) error {
+	args := m.Called(ctx, dB, consentChange)
+	return args.Error(0)
+}
+
+func (m *MockConsentChangeDomain) ListCustomerConsentChanges(ctx context.Context, dB db.SQLOperations, customer *models.Customer, filter *models.Filter) ([]*models.ConsentChange, error) {
+	args := m.Called(ctx, dB, customer, filter)
+	if value, ok := args.Get(0).([]*models.ConsentChange); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockConsentChangeDomain) CustomerConsentChangesCount(ctx context.Context, dB db.SQLOperations, customer *models.Customer) (int, error) {
+	args := m.Called(ctx, dB, customer)
+	return args.Int(0), args.Error(1)
+}
+
+type MockTaxClassDomain struct {
+	mock.Mock
+}
+
+func (m *MockTaxClassDomain) CreateTaxClass(ctx context.Context, dB db.SQLOperations, taxClass *models.TaxClass) error {
+	args := m.Called(ctx, dB, taxClass)
+	return args.Error(0)
+}
+
+func (m *MockTaxClassDomain) TaxClassByID(ctx context.Context, dB db.SQLOperations, shopID string, taxClassID int64) (*models.TaxClass, error) {
+	args := m.Called(ctx, dB, shopID, taxClassID)
+	if value, ok := args.Get(0).(*models.TaxClass); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxClassDomain) ListTaxClasses(ctx context.Context, dB db.SQLOperations, shopID string) ([]*models.TaxClass, error) {
+	args := m.Called(ctx, dB, shopID)
+	if value, ok := args.Get(0).([]*models.TaxClass); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxClassDomain) ProductTaxClass(ctx context.Context, dB db.SQLOperations, shopID string, productID int64) (*models.TaxClass, error) {
+	args := m.Called(ctx, dB, shopID, productID)
+	if value, ok := args.Get(0).(*models.TaxClass); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxClassDomain) OrderTaxSummary(ctx context.Context, dB db.SQLOperations, shopID string, orderID int64) ([]*models.TaxSummaryLine, error) {
+	args := m.Called(ctx, dB, shopID, orderID)
+	if value, ok := args.Get(0).([]*models.TaxSummaryLine); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaxClassDomain) ShopTaxSummary(ctx context.Context, dB db.SQLOperations, shopID string, from time.Time, to time.Time) ([]*models.TaxSummaryLine, error) {
+	args := m.Called(ctx, dB, shopID, from, to)
+	if value, ok := args.Get(0).([]*models.TaxSummaryLine); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+type MockCategoryDomain struct {
+	mock.Mock
+}
+
+func (m *MockCategoryDomain) CreateCategory(ctx context.Context, dB db.SQLOperations, category *models.Category) error {
+	args := m.Called(ctx, dB, category)
+	return args.Error(0)
+}
+
+func (m *MockCategoryDomain) CategoryByID(ctx context.Context, dB db.SQLOperations, shopID string, categoryID int64) (*models.Category, error) {
+	args := m.Called(ctx, dB, shopID, categoryID)
+	if value, ok := args.Get(0).(*models.Category); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryDomain) ListShopCateories(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) ([]*models.Category, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	if value, ok := args.Get(0).([]*models.Category); ok {
+		return value, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCategoryDomain) ShopCategoriesCount(ctx context.Context, dB db.SQLOperations, shopID string, filter *models.Filter) (int, error) {
+	args := m.Called(ctx, dB, shopID, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockCategoryDomain) DeleteCategory(ctx context.Context, dB db.SQLOperations, shopID string, categoryID int64) error {
+	args := m.Called(ctx, dB, shopID, categoryID)
+	return args.Error(0)
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/processor"
+	"strings"
+	"time"
+)
+
+const (
+	// mpesaResultSuccess is the only Daraja result code that means the customer paid.
+	mpesaResultSuccess = 0
+	// mpesaResultCancelled is sent when the customer dismisses the prompt.
+	mpesaResultCancelled = 1032
+
+	// mpesaCallbackActor is who order status changes made by Daraja callbacks are recorded against.
+	mpesaCallbackActor = "mpesa"
+)
+
+type (
+	MpesaService interface {
+		InitiateSTKPush(ctx context.Context, dB db.DB, shopID string, orderID int64, form *dtos.InitiateSTKPushForm) (*models.MpesaSTKRequest, error)
+		CancelSTKPush(ctx context.Context, dB db.DB, shopID string, orderID int64) (*models.OrderPayments, error)
+		HandleSTKCallback(ctx context.Context, dB db.DB, callback *models.STKCallback) error
+		ListSTKRequests(ctx context.Context, dB db.DB, shopID string, orderID int64) ([]*models.MpesaSTKRequest, error)
+	}
+
+	mpesaService struct {
+		mpesaClient processor.MpesaClient
+		store       *domain.Store
+		now         func() time.Time
+	}
+)
+
+func NewMpesaService(
+	mpesaClient processor.MpesaClient,
+	store *domain.Store,
+) MpesaService {
+	return &mpesaService{
+		mpesaClient: mpesaClient,
+		store:       store,
+		now:         time.Now,
+	}
+}
+
+// InitiateSTKPush asks the customer's phone to pay what is still owed on a pending order,
+// which may be the whole total or what is left after other tenders. The order stays
+// pending until Daraja's callback reports the payment, and takes no second prompt while
+// one is waiting, so a double click cannot charge the customer twice. A prompt stops
+// waiting when it is cancelled or after mpesaPromptExpiry.
+func (s *mpesaService) InitiateSTKPush(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+	form *dtos.InitiateSTKPushForm,
+) (*models.MpesaSTKRequest, error) {
+
+	var (
+		order       *models.Order
+		payment     *models.Payment
+		phoneNumber string
+	)
+
+	// the pending payment is recorded under the order's lock and committed before the
+	// prompt goes out, so a concurrent prompt or cash tender sees it; the lock itself is
+	// not held while Daraja is called
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		var err error
+		order, err = s.store.OrderDomain.LockOrderByID(ctx, operations, shopID, orderID)
+		if err != nil {
+			return err
+		}
+
+		if order.OrderStatus != custom_types.OrderStatusPending {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is [%s]; only pending orders can be paid", order.ID, order.OrderStatus),
+				apperr.Conflict,
+			)
+		}
+
+		payments, err := s.store.PaymentDomain.PaymentsByOrderID(ctx, operations, shopID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		err = releaseExpiredPrompts(ctx, operations, s.store, payments, s.now())
+		if err != nil {
+			return err
+		}
+
+		awaited, err := amountAwaited(payments)
+		if err != nil {
+			return err
+		}
+
+		if !awaited.IsZero() {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is waiting on an M-Pesa payment of %s", order.ID, awaited),
+				apperr.Conflict,
+			)
+		}
+
+		balance, err := balanceDue(order, payments)
+		if err != nil {
+			return err
+		}
+
+		if balance.Amount <= 0 {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is already covered by its payments", order.ID),
+				apperr.Conflict,
+			)
+		}
+
+		// M-Pesa moves whole shillings only; the cents can be taken in cash first
+		if balance.Amount%100 != 0 {
+			return apperr.NewBadRequest(fmt.Sprintf("M-Pesa cannot collect %s; the amount has cents", balance))
+		}
+
+		phoneNumber = strings.TrimSpace(form.PhoneNumber)
+		if phoneNumber == "" {
+			phoneNumber = order.PhoneNumber
+		}
+
+		phoneNumber, err = processor.FormatMpesaPhoneNumber(phoneNumber)
+		if err != nil {
+			return apperr.NewBadRequest(err.Error())
+		}
+
+		// the payment waits for the callback, and does not count towards the order until then
+		payment = &models.Payment{
+			OrderID:        order.ID,
+			Amount:         balance,
+			AmountTendered: balance,
+			ChangeDue:      custom_types.NewMoney(0),
+			PaymentMethod:  custom_types.PaymentMethodMpesa,
+			PaymentStatus:  custom_types.PaymentStatusPending,
+			RecordedBy:     mpesaCallbackActor,
+		}
+
+		return s.store.PaymentDomain.CreatePayment(ctx, operations, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stkResponse, err := s.mpesaClient.STKPush(ctx, phoneNumber, payment.Amount.Amount/100, order.ReferenceNumber, "Order payment")
+	if err != nil {
+		loggers.Errorf("failed to send STK push for order [%d]: [%+v]", order.ID, err)
+
+		if !errors.Is(err, processor.ErrSTKPushRejected) {
+			// the prompt may still reach the phone, so the payment keeps waiting until it
+			// expires or is cancelled rather than letting the bill be paid twice
+			return nil, apperr.NewErrorWithType(
+				fmt.Errorf("M-Pesa did not confirm the prompt for order [%d]; cancel it before taking another tender: %w", order.ID, err),
+				apperr.ServiceUnavailable,
+			)
+		}
+
+		// no prompt went out, so nothing is waiting on the order
+		payment.PaymentStatus = custom_types.PaymentStatusFailed
+		saveErr := s.store.PaymentDomain.CreatePayment(ctx, dB, payment)
+		if saveErr != nil {
+			loggers.Errorf("failed to release payment [%d] for order [%d]: [%+v]", payment.ID, order.ID, saveErr)
+		}
+
+		return nil, apperr.NewErrorWithType(err, apperr.ServiceUnavailable)
+	}
+
+	stkRequest := &models.MpesaSTKRequest{
+		OrderID:           order.ID,
+		ShopID:            shopID,
+		PaymentID:         null.NullValue(payment.ID),
+		MerchantRequestID: stkResponse.MerchantRequestID,
+		CheckoutRequestID: stkResponse.CheckoutRequestID,
+		PhoneNumber:       phoneNumber,
+		Amount:            payment.Amount,
+		RequestStatus:     custom_types.MpesaRequestStatusPending,
+		CustomerMessage:   stkResponse.CustomerMessage,
+	}
+
+	err = s.store.MpesaSTKRequestDomain.CreateMpesaSTKRequest(ctx, dB, stkRequest)
+	if err != nil {
+		// the prompt is already on the phone; without this row its callback cannot be matched
+		loggers.Errorf("failed to save STK push [%s] for order [%d]: [%+v]", stkResponse.CheckoutRequestID, order.ID, err)
+		return nil, err
+	}
+
+	return stkRequest, nil
+}
+
+// CancelSTKPush stops an order waiting on its M-Pesa prompts, e.g. when the customer
+// would rather pay cash. Their payments are failed; a callback that still arrives for
+// one records what the customer paid.
+func (s *mpesaService) CancelSTKPush(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+) (*models.OrderPayments, error) {
+
+	var orderPayments *models.OrderPayments
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		order, err := s.store.OrderDomain.LockOrderByID(ctx, operations, shopID, orderID)
+		if err != nil {
+			return err
+		}
+
+		payments, err := s.store.PaymentDomain.PaymentsByOrderID(ctx, operations, shopID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		cancelled := make([]*models.Payment, 0)
+
+		for _, payment := range payments {
+			if payment.PaymentStatus != custom_types.PaymentStatusPending {
+				continue
+			}
+
+			payment.PaymentStatus = custom_types.PaymentStatusFailed
+
+			err = s.store.PaymentDomain.CreatePayment(ctx, operations, payment)
+			if err != nil {
+				return err
+			}
+
+			cancelled = append(cancelled, payment)
+		}
+
+		if len(cancelled) == 0 {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is not waiting on an M-Pesa payment", order.ID),
+				apperr.Conflict,
+			)
+		}
+
+		orderPayments, err = newOrderPayments(order, payments, cancelled)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orderPayments, nil
+}
+
+// HandleSTKCallback records Daraja's result for an STK Push against its payment and marks
+// the order paid once its payments cover the total. Callbacks delivered more than once
+// are only acted on the first time.
+func (s *mpesaService) HandleSTKCallback(
+	ctx context.Context,
+	dB db.DB,
+	callback *models.STKCallback,
+) error {
+
+	if strings.TrimSpace(callback.CheckoutRequestID) == "" {
+		return apperr.NewBadRequest("callback has no checkout request id")
+	}
+
+	return dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		stkRequest, err := s.store.MpesaSTKRequestDomain.LockMpesaSTKRequestByCheckoutID(ctx, operations, callback.CheckoutRequestID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewNotFound("checkout request", callback.CheckoutRequestID)
+			}
+			return err
+		}
+
+		if stkRequest.RequestStatus != custom_types.MpesaRequestStatusPending {
+			loggers.Infof("ignoring repeated callback for checkout request [%s]", callback.CheckoutRequestID)
+			return nil
+		}
+
+		stkRequest.ResultCode = null.NullValue(int64(callback.ResultCode))
+		stkRequest.ResultDesc = null.NullValue(callback.ResultDesc)
+
+		switch callback.ResultCode {
+		case mpesaResultSuccess:
+			stkRequest.RequestStatus = custom_types.MpesaRequestStatusCompleted
+			if receipt := callback.MetadataValue("MpesaReceiptNumber"); receipt != "" {
+				stkRequest.MpesaReceiptNumber = null.NullValue(receipt)
+			}
+		case mpesaResultCancelled:
+			stkRequest.RequestStatus = custom_types.MpesaRequestStatusCancelled
+		default:
+			stkRequest.RequestStatus = custom_types.MpesaRequestStatusFailed
+		}
+
+		err = s.store.MpesaSTKRequestDomain.CreateMpesaSTKRequest(ctx, operations, stkRequest)
+		if err != nil {
+			return err
+		}
+
+		if stkRequest.PaymentID == nil {
+			// sent before payments were recorded
+			return nil
+		}
+
+		payment, err := s.store.PaymentDomain.LockPaymentByID(ctx, operations, stkRequest.ShopID, *stkRequest.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		if stkRequest.RequestStatus != custom_types.MpesaRequestStatusCompleted {
+			payment.PaymentStatus = custom_types.PaymentStatusFailed
+			return s.store.PaymentDomain.CreatePayment(ctx, operations, payment)
+		}
+
+		// record what the customer actually paid; a short payment leaves a balance
+		paid, err := custom_types.ParseMoney(callback.MetadataValue("Amount"))
+		if err != nil {
+			loggers.Errorf("checkout request [%s] has no readable amount [%s]; assuming %s", callback.CheckoutRequestID, callback.MetadataValue("Amount"), stkRequest.Amount)
+			paid = stkRequest.Amount
+		}
+
+		if paid.Amount != stkRequest.Amount.Amount {
+			loggers.Errorf("checkout request [%s] paid %s, expected %s", callback.CheckoutRequestID, paid, stkRequest.Amount)
+		}
+
+		payment.Amount = paid
+		payment.AmountTendered = paid
+		payment.ProviderReference = stkRequest.MpesaReceiptNumber
+		payment.PaymentStatus = custom_types.PaymentStatusCompleted
+
+		err = s.store.PaymentDomain.CreatePayment(ctx, operations, payment)
+		if err != nil {
+			return err
+		}
+
+		order, err := s.store.OrderDomain.LockOrderByID(ctx, operations, stkRequest.ShopID, stkRequest.OrderID)
+		if err != nil {
+			return err
+		}
+
+		if order.OrderStatus != custom_types.OrderStatusPending {
+			// e.g. the order was cancelled while the customer was paying; the money needs refunding by hand
+			loggers.Errorf("checkout request [%s] paid for order [%d], which is already [%s]", callback.CheckoutRequestID, order.ID, order.OrderStatus)
+			return nil
+		}
+
+		payments, err := s.store.PaymentDomain.PaymentsByOrderID(ctx, operations, order.ShopID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		return settleOrder(ctx, operations, s.store, order, payments, mpesaCallbackActor)
+	})
+}
+
+func (s *mpesaService) ListSTKRequests(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+) ([]*models.MpesaSTKRequest, error) {
+
+	_, err := s.store.OrderDomain.OrderByID(ctx, dB, shopID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.MpesaSTKRequestDomain.MpesaSTKRequestsByOrderID(ctx, dB, shopID, orderID)
+}
@@ -0,0 +1,482 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/processor"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func stkPushAccepted() *models.STKPushResponse {
+	return &models.STKPushResponse{
+		MerchantRequestID: "merchant-1",
+		CheckoutRequestID: "ws_CO_1",
+		ResponseCode:      "0",
+		CustomerMessage:   "Success. Request accepted for processing",
+	}
+}
+
+// pendingSTKRequest is a prompt for 1,500.00 on the customer's phone, waiting on payment 7.
+func pendingSTKRequest() *models.MpesaSTKRequest {
+	return &models.MpesaSTKRequest{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+		OrderID:              1,
+		ShopID:               "shop-1",
+		PaymentID:            null.NullValue(int64(7)),
+		MerchantRequestID:    "merchant-1",
+		CheckoutRequestID:    "ws_CO_1",
+		PhoneNumber:          "254712345678",
+		Amount:               custom_types.NewMoney(150000),
+		RequestStatus:        custom_types.MpesaRequestStatusPending,
+	}
+}
+
+func paidCallback(checkoutRequestID string, amount float64) *models.STKCallback {
+	return &models.STKCallback{
+		MerchantRequestID: "merchant-1",
+		CheckoutRequestID: checkoutRequestID,
+		ResultCode:        0,
+		ResultDesc:        "The service request is processed successfully.",
+		CallbackMetadata: &models.STKCallbackMetadata{Item: []models.STKCallbackItem{
+			{Name: "Amount", Value: amount},
+			{Name: "MpesaReceiptNumber", Value: "NLJ7RT61SV"},
+			{Name: "PhoneNumber", Value: float64(254712345678)},
+		}},
+	}
+}
+
+func TestInitiateSTKPush_PromptsForTheTotal(t *testing.T) {
+	ctx := context.Background()
+	mockMpesa := new(MockMpesaClient)
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(mockMpesa, store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.PaymentMethod == custom_types.PaymentMethodMpesa &&
+			payment.PaymentStatus == custom_types.PaymentStatusPending &&
+			payment.Amount.Amount == 150000
+	})).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Payment).ID = 7
+	}).Return(nil)
+	mockMpesa.On("STKPush", ctx, "254712345678", int64(1500), "ORD-2025-000001", "Order payment").
+		Return(stkPushAccepted(), nil)
+	mockSTKRequests.On("CreateMpesaSTKRequest", ctx, mock.Anything, mock.AnythingOfType("*models.MpesaSTKRequest")).
+		Return(nil)
+
+	stkRequest, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-1", 1, &dtos.InitiateSTKPushForm{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ws_CO_1", stkRequest.CheckoutRequestID)
+	assert.Equal(t, "254712345678", stkRequest.PhoneNumber)
+	assert.Equal(t, int64(150000), stkRequest.Amount.Amount)
+	assert.Equal(t, int64(7), *stkRequest.PaymentID)
+	assert.Equal(t, custom_types.MpesaRequestStatusPending, stkRequest.RequestStatus)
+	mockOrders.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+	mockMpesa.AssertExpectations(t)
+	mockPayments.AssertExpectations(t)
+	mockSTKRequests.AssertExpectations(t)
+}
+
+func TestInitiateSTKPush_PromptsForTheBalanceAfterCash(t *testing.T) {
+	ctx := context.Background()
+	mockMpesa := new(MockMpesaClient)
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(mockMpesa, store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150050), nil)
+	// the cents cannot go over M-Pesa, so they are paid in cash first
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{payment(1, custom_types.PaymentMethodCash, 50050, custom_types.PaymentStatusCompleted)}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Return(nil)
+	mockMpesa.On("STKPush", ctx, "254712345678", int64(1000), "ORD-2025-000001", "Order payment").
+		Return(stkPushAccepted(), nil)
+	mockSTKRequests.On("CreateMpesaSTKRequest", ctx, mock.Anything, mock.AnythingOfType("*models.MpesaSTKRequest")).
+		Return(nil)
+
+	stkRequest, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-1", 1, &dtos.InitiateSTKPushForm{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100000), stkRequest.Amount.Amount)
+	mockMpesa.AssertExpectations(t)
+}
+
+func TestInitiateSTKPush_OnePromptAtATime(t *testing.T) {
+	ctx := context.Background()
+	mockMpesa := new(MockMpesaClient)
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewMpesaService(mockMpesa, store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	// a second click while the first prompt is on the phone
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{payment(7, custom_types.PaymentMethodMpesa, 150000, custom_types.PaymentStatusPending)}, nil)
+
+	_, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-1", 1, &dtos.InitiateSTKPushForm{})
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+	mockMpesa.AssertNotCalled(t, "STKPush", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestInitiateSTKPush_Rejects(t *testing.T) {
+	paid := pendingOrder(150000)
+	paid.OrderStatus = custom_types.OrderStatusPaid
+
+	tests := []struct {
+		name      string
+		order     *models.Order
+		payments  []*models.Payment
+		errorType apperr.Type
+	}{
+		{"cents", pendingOrder(150050), []*models.Payment{}, apperr.BadRequest},
+		{"paid order", paid, nil, apperr.Conflict},
+		{"covered by its payments", pendingOrder(150000), []*models.Payment{payment(1, custom_types.PaymentMethodCard, 150000, custom_types.PaymentStatusCompleted)}, apperr.Conflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockMpesa := new(MockMpesaClient)
+			mockOrders := new(MockOrderDomain)
+			mockPayments := new(MockPaymentDomain)
+			store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+			service := services.NewMpesaService(mockMpesa, store)
+
+			mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(tt.order, nil)
+			mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(tt.payments, nil).Maybe()
+
+			_, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-1", 1, &dtos.InitiateSTKPushForm{})
+
+			assert.Equal(t, tt.errorType, apperr.NewError(err).Type)
+			mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+			mockMpesa.AssertNotCalled(t, "STKPush", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestInitiateSTKPush_ReleasesThePaymentWhenDarajaRefuses(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockMpesa := new(MockMpesaClient)
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(mockMpesa, store)
+
+	var statuses []custom_types.PaymentStatus
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Run(func(args mock.Arguments) {
+			statuses = append(statuses, args.Get(2).(*models.Payment).PaymentStatus)
+		}).Return(nil)
+	mockMpesa.On("STKPush", ctx, "254712345678", int64(1500), "ORD-2025-000001", "Order payment").
+		Return(nil, fmt.Errorf("%w with status 400: Bad Request - Invalid PhoneNumber", processor.ErrSTKPushRejected))
+
+	_, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-1", 1, &dtos.InitiateSTKPushForm{})
+
+	assert.Equal(t, apperr.ServiceUnavailable, apperr.NewError(err).Type)
+	assert.Equal(t, []custom_types.PaymentStatus{custom_types.PaymentStatusPending, custom_types.PaymentStatusFailed}, statuses)
+	mockSTKRequests.AssertNotCalled(t, "CreateMpesaSTKRequest", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestInitiateSTKPush_KeepsWaitingWhenDarajaMayHavePrompted(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockMpesa := new(MockMpesaClient)
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(mockMpesa, store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Return(nil).Once()
+	mockMpesa.On("STKPush", ctx, "254712345678", int64(1500), "ORD-2025-000001", "Order payment").
+		Return(nil, errors.New("HTTP request failed: context deadline exceeded"))
+
+	_, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-1", 1, &dtos.InitiateSTKPushForm{})
+
+	assert.Equal(t, apperr.ServiceUnavailable, apperr.NewError(err).Type)
+	// the payment is only saved pending, and holds the order until it expires or is cancelled
+	mockPayments.AssertNumberOfCalls(t, "CreatePayment", 1)
+	mockSTKRequests.AssertNotCalled(t, "CreateMpesaSTKRequest", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestInitiateSTKPush_PromptsAgainOnceAPromptExpires(t *testing.T) {
+	ctx := context.Background()
+	mockMpesa := new(MockMpesaClient)
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(mockMpesa, store)
+
+	expired := payment(7, custom_types.PaymentMethodMpesa, 150000, custom_types.PaymentStatusPending)
+	expired.CreatedAt = time.Now().Add(-10 * time.Minute)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{expired}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Return(nil)
+	mockMpesa.On("STKPush", ctx, "254712345678", int64(1500), "ORD-2025-000001", "Order payment").
+		Return(stkPushAccepted(), nil)
+	mockSTKRequests.On("CreateMpesaSTKRequest", ctx, mock.Anything, mock.AnythingOfType("*models.MpesaSTKRequest")).
+		Return(nil)
+
+	stkRequest, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-1", 1, &dtos.InitiateSTKPushForm{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(150000), stkRequest.Amount.Amount)
+	assert.Equal(t, custom_types.PaymentStatusFailed, expired.PaymentStatus)
+	mockMpesa.AssertExpectations(t)
+}
+
+func TestCancelSTKPush_ReleasesTheOrder(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewMpesaService(new(MockMpesaClient), store)
+
+	cash := payment(1, custom_types.PaymentMethodCash, 50000, custom_types.PaymentStatusCompleted)
+	prompted := payment(2, custom_types.PaymentMethodMpesa, 100000, custom_types.PaymentStatusPending)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{cash, prompted}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, prompted).
+		Return(nil)
+
+	orderPayments, err := service.CancelSTKPush(ctx, &inlineDB{}, "shop-1", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.PaymentStatusFailed, prompted.PaymentStatus)
+	assert.Equal(t, []*models.Payment{prompted}, orderPayments.Payments)
+	assert.Equal(t, int64(100000), orderPayments.Balance.Amount)
+	mockPayments.AssertExpectations(t)
+}
+
+func TestCancelSTKPush_NothingWaiting(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewMpesaService(new(MockMpesaClient), store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{payment(1, custom_types.PaymentMethodMpesa, 150000, custom_types.PaymentStatusFailed)}, nil)
+
+	_, err := service.CancelSTKPush(ctx, &inlineDB{}, "shop-1", 1)
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleSTKCallback_PaidCallbackMarksOrderPaid(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests, OrderStatusTransitionDomain: mockTransitions}
+	service := services.NewMpesaService(new(MockMpesaClient), store)
+
+	order := pendingOrder(150000)
+	prompted := payment(7, custom_types.PaymentMethodMpesa, 150000, custom_types.PaymentStatusPending)
+
+	mockSTKRequests.On("LockMpesaSTKRequestByCheckoutID", ctx, mock.Anything, "ws_CO_1").
+		Return(pendingSTKRequest(), nil)
+	mockSTKRequests.On("CreateMpesaSTKRequest", ctx, mock.Anything, mock.MatchedBy(func(stkRequest *models.MpesaSTKRequest) bool {
+		return stkRequest.RequestStatus == custom_types.MpesaRequestStatusCompleted &&
+			*stkRequest.MpesaReceiptNumber == "NLJ7RT61SV" &&
+			*stkRequest.ResultCode == 0
+	})).Return(nil)
+	mockPayments.On("LockPaymentByID", ctx, mock.Anything, "shop-1", int64(7)).
+		Return(prompted, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, prompted).
+		Return(nil)
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{prompted}, nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockTransitions.On("CreateOrderStatusTransition", ctx, mock.Anything, mock.MatchedBy(func(transition *models.OrderStatusTransition) bool {
+		return transition.ToStatus == custom_types.OrderStatusPaid && transition.ChangedBy == "mpesa"
+	})).Return(nil)
+
+	err := service.HandleSTKCallback(ctx, &inlineDB{}, paidCallback("ws_CO_1", 1500))
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.PaymentStatusCompleted, prompted.PaymentStatus)
+	assert.Equal(t, int64(150000), prompted.Amount.Amount)
+	assert.Equal(t, "NLJ7RT61SV", *prompted.ProviderReference)
+	assert.Equal(t, custom_types.OrderStatusPaid, order.OrderStatus)
+	assert.Equal(t, custom_types.PaymentMethodMpesa, order.PaymentMethod)
+	mockSTKRequests.AssertExpectations(t)
+	mockPayments.AssertExpectations(t)
+	mockOrders.AssertExpectations(t)
+	mockTransitions.AssertExpectations(t)
+}
+
+func TestHandleSTKCallback_RepeatedCallbacksChangeNothing(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockPayments := new(MockPaymentDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(new(MockMpesaClient), store)
+
+	// Daraja retries callbacks; the first delivery already completed the request
+	completed := pendingSTKRequest()
+	completed.RequestStatus = custom_types.MpesaRequestStatusCompleted
+
+	mockSTKRequests.On("LockMpesaSTKRequestByCheckoutID", ctx, mock.Anything, "ws_CO_1").
+		Return(completed, nil)
+
+	err := service.HandleSTKCallback(ctx, &inlineDB{}, paidCallback("ws_CO_1", 1500))
+
+	assert.NoError(t, err)
+	mockSTKRequests.AssertNotCalled(t, "CreateMpesaSTKRequest", mock.Anything, mock.Anything, mock.Anything)
+	mockPayments.AssertNotCalled(t, "LockPaymentByID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleSTKCallback_UnsuccessfulCallbacksLeaveOrderPending(t *testing.T) {
+	tests := []struct {
+		name          string
+		callback      *models.STKCallback
+		status        custom_types.MpesaRequestStatus
+		paymentStatus custom_types.PaymentStatus
+	}{
+		{"cancelled by customer", &models.STKCallback{CheckoutRequestID: "ws_CO_1", ResultCode: 1032, ResultDesc: "Request cancelled by user"}, custom_types.MpesaRequestStatusCancelled, custom_types.PaymentStatusFailed},
+		{"insufficient balance", &models.STKCallback{CheckoutRequestID: "ws_CO_1", ResultCode: 1, ResultDesc: "The balance is insufficient for the transaction"}, custom_types.MpesaRequestStatusFailed, custom_types.PaymentStatusFailed},
+		// the shilling received is recorded and the rest is still owed
+		{"paid short", paidCallback("ws_CO_1", 1), custom_types.MpesaRequestStatusCompleted, custom_types.PaymentStatusCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loggers.InitLogger("test")
+
+			ctx := context.Background()
+			mockOrders := new(MockOrderDomain)
+			mockPayments := new(MockPaymentDomain)
+			mockSTKRequests := new(MockMpesaSTKRequestDomain)
+			mockTransitions := new(MockOrderStatusTransitionDomain)
+			store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, MpesaSTKRequestDomain: mockSTKRequests, OrderStatusTransitionDomain: mockTransitions}
+			service := services.NewMpesaService(new(MockMpesaClient), store)
+
+			stkRequest := pendingSTKRequest()
+			prompted := payment(7, custom_types.PaymentMethodMpesa, 150000, custom_types.PaymentStatusPending)
+
+			mockSTKRequests.On("LockMpesaSTKRequestByCheckoutID", ctx, mock.Anything, "ws_CO_1").
+				Return(stkRequest, nil)
+			mockSTKRequests.On("CreateMpesaSTKRequest", ctx, mock.Anything, stkRequest).
+				Return(nil)
+			mockPayments.On("LockPaymentByID", ctx, mock.Anything, "shop-1", int64(7)).
+				Return(prompted, nil)
+			mockPayments.On("CreatePayment", ctx, mock.Anything, prompted).
+				Return(nil)
+			mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(pendingOrder(150000), nil).Maybe()
+			mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return([]*models.Payment{prompted}, nil).Maybe()
+
+			err := service.HandleSTKCallback(ctx, &inlineDB{}, tt.callback)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.status, stkRequest.RequestStatus)
+			assert.Equal(t, int64(tt.callback.ResultCode), *stkRequest.ResultCode)
+			assert.Equal(t, tt.paymentStatus, prompted.PaymentStatus)
+			mockOrders.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+			mockTransitions.AssertNotCalled(t, "CreateOrderStatusTransition", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandleSTKCallback_RejectsUnknownCheckoutRequests(t *testing.T) {
+	ctx := context.Background()
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(new(MockMpesaClient), store)
+
+	mockSTKRequests.On("LockMpesaSTKRequestByCheckoutID", ctx, mock.Anything, "ws_CO_unknown").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	err := service.HandleSTKCallback(ctx, &inlineDB{}, paidCallback("ws_CO_unknown", 1500))
+
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+	mockSTKRequests.AssertNotCalled(t, "CreateMpesaSTKRequest", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMpesaService_OtherShopsOrders(t *testing.T) {
+	ctx := context.Background()
+	mockMpesa := new(MockMpesaClient)
+	mockOrders := new(MockOrderDomain)
+	mockSTKRequests := new(MockMpesaSTKRequestDomain)
+	store := &domain.Store{OrderDomain: mockOrders, MpesaSTKRequestDomain: mockSTKRequests}
+	service := services.NewMpesaService(mockMpesa, store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-2", int64(1)).
+		Return(nil, apperr.NewNotFound("order", "1"))
+	mockOrders.On("OrderByID", ctx, mock.Anything, "shop-2", int64(1)).
+		Return(nil, apperr.NewNotFound("order", "1"))
+
+	_, err := service.InitiateSTKPush(ctx, &inlineDB{}, "shop-2", 1, &dtos.InitiateSTKPushForm{})
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	_, err = service.ListSTKRequests(ctx, nil, "shop-2", 1)
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	mockMpesa.AssertNotCalled(t, "STKPush", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockSTKRequests.AssertNotCalled(t, "MpesaSTKRequestsByOrderID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
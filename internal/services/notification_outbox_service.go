@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/notification"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"time"
+)
+
+const (
+	// notificationRetryDelay is how long the first retry waits; each retry after it waits twice as long
+	notificationRetryDelay = 30 * time.Second
+	// notificationMaxRetryDelay caps the wait, so a notification stuck behind an outage
+	// still goes out soon after the provider is back
+	notificationMaxRetryDelay = time.Hour
+)
+
+// errNotificationSuppressed is returned for a notification the customer stopped wanting
+// after it was queued. It is not retried.
+var errNotificationSuppressed = errors.New("notification suppressed")
+
+type (
+	NotificationOutboxService interface {
+		EnqueueOrderConfirmation(ctx context.Context, operations db.SQLOperations, order *models.Order) error
+		EnqueueOrderReturn(ctx context.Context, operations db.SQLOperations, order *models.Order, orderReturn *models.OrderReturn) error
+		DeliverNext(ctx context.Context, dB db.DB) (bool, error)
+		ListNotifications(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.OutboxNotificationList, error)
+		ReplayNotification(ctx context.Context, dB db.DB, shopID string, notificationID int64) (*models.OutboxNotification, error)
+	}
+
+	notificationOutboxService struct {
+		orderNotification           notification.OrderNotification
+		notificationTemplateService NotificationTemplateService
+		store                       *domain.Store
+		maxAttempts                 int
+		now                         func() time.Time
+	}
+)
+
+func NewNotificationOutboxService(
+	orderNotification notification.OrderNotification,
+	notificationTemplateService NotificationTemplateService,
+	store *domain.Store,
+	maxAttempts int,
+) NotificationOutboxService {
+	return &notificationOutboxService{
+		orderNotification:           orderNotification,
+		notificationTemplateService: notificationTemplateService,
+		store:                       store,
+		maxAttempts:                 maxAttempts,
+		now:                         time.Now,
+	}
+}
+
+// EnqueueOrderConfirmation records the notification to the customer, by SMS or email as
+// they agreed, and the email to the shop's admin for a new order. It is called inside the
+// transaction that creates the order, so the notifications exist exactly when the order
+// does. Orders without a phone number are not announced.
+func (s *notificationOutboxService) EnqueueOrderConfirmation(
+	ctx context.Context,
+	operations db.SQLOperations,
+	order *models.Order,
+) error {
+
+	if order.PhoneNumber == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(&models.NotificationPayload{Order: order})
+	if err != nil {
+		return apperr.NewInternal(fmt.Sprintf("failed to encode notification for order [%d]", order.ID))
+	}
+
+	err = s.enqueueForCustomer(ctx, operations, order, custom_types.NotificationEventOrderConfirmation, payload)
+	if err != nil {
+		return err
+	}
+
+	return s.enqueue(ctx, operations, order, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelEmail, nil, payload)
+}
+
+// EnqueueOrderReturn records the SMS or email telling the customer what came back and
+// what was refunded. It is called inside the transaction that records the return.
+func (s *notificationOutboxService) EnqueueOrderReturn(
+	ctx context.Context,
+	operations db.SQLOperations,
+	order *models.Order,
+	orderReturn *models.OrderReturn,
+) error {
+
+	if order.PhoneNumber == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(&models.NotificationPayload{Order: order, OrderReturn: orderReturn})
+	if err != nil {
+		return apperr.NewInternal(fmt.Sprintf("failed to encode return notification for order [%d]", order.ID))
+	}
+
+	return s.enqueueForCustomer(ctx, operations, order, custom_types.NotificationEventOrderReturn, payload)
+}
+
+// enqueueForCustomer records the customer's notification on the channel they agreed to,
+// and none if they opted out.
+func (s *notificationOutboxService) enqueueForCustomer(
+	ctx context.Context,
+	operations db.SQLOperations,
+	order *models.Order,
+	event custom_types.NotificationEvent,
+	payload []byte,
+) error {
+
+	channel, recipient, err := customerChannel(ctx, operations, s.store, order)
+	if err != nil {
+		return err
+	}
+
+	if channel == "" {
+		return nil
+	}
+
+	return s.enqueue(ctx, operations, order, event, channel, null.NullValue(recipient), payload)
+}
+
+func (s *notificationOutboxService) enqueue(
+	ctx context.Context,
+	operations db.SQLOperations,
+	order *models.Order,
+	event custom_types.NotificationEvent,
+	channel custom_types.NotificationChannel,
+	recipient *string,
+	payload []byte,
+) error {
+
+	outboxNotification := &models.OutboxNotification{
+		ShopID:        order.ShopID,
+		OrderID:       order.ID,
+		Event:         event,
+		Channel:       channel,
+		Recipient:     recipient,
+		Payload:       payload,
+		Status:        custom_types.NotificationStatusPending,
+		NextAttemptAt: s.now(),
+	}
+
+	err := s.store.NotificationOutboxDomain.CreateOutboxNotification(ctx, operations, outboxNotification)
+	if err != nil {
+		loggers.Errorf("failed to enqueue %s %s for order [%d]: [%+v]", event, channel, order.ID, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeliverNext sends the notification that is most overdue and reports whether there
+// was one. The row stays locked while it is sent, so no other worker picks it up. A
+// failed send is retried with exponential backoff until maxAttempts, when the
+// notification is dead-lettered for staff to look at and replay. The consent and
+// template lookups for the send run outside the claiming transaction, so a database
+// error there is recorded as a failed attempt instead of aborting the transaction and
+// losing the attempt.
+func (s *notificationOutboxService) DeliverNext(
+	ctx context.Context,
+	dB db.DB,
+) (bool, error) {
+
+	delivered := false
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		outboxNotification, err := s.store.NotificationOutboxDomain.LockNextDueOutboxNotification(ctx, operations, s.now())
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return nil
+			}
+			return err
+		}
+
+		delivered = true
+
+		smsMessage, err := s.send(ctx, dB, outboxNotification)
+
+		now := s.now()
+		outboxNotification.Attempts++
+
+		switch {
+		case errors.Is(err, errNotificationSuppressed):
+			outboxNotification.Status = custom_types.NotificationStatusSuppressed
+			outboxNotification.LastError = null.NullValue(err.Error())
+		case err == nil:
+			outboxNotification.Status = custom_types.NotificationStatusSent
+			outboxNotification.SentAt = null.NullValue(now)
+			outboxNotification.LastError = nil
+
+			// keep what the provider accepted so its delivery report can be matched up
+			if smsMessage != nil {
+				smsMessage.NotificationID = null.NullValue(outboxNotification.ID)
+
+				err = s.store.SMSMessageDomain.CreateSMSMessage(ctx, operations, smsMessage)
+				if err != nil {
+					return err
+				}
+			}
+		case outboxNotification.Attempts >= s.maxAttempts:
+			loggers.Errorf("notification [%d] dead-lettered after %d attempts: [%+v]", outboxNotification.ID, outboxNotification.Attempts, err)
+			outboxNotification.Status = custom_types.NotificationStatusDead
+			outboxNotification.LastError = null.NullValue(err.Error())
+		default:
+			loggers.Errorf("notification [%d] attempt %d failed: [%+v]", outboxNotification.ID, outboxNotification.Attempts, err)
+			outboxNotification.NextAttemptAt = now.Add(notificationBackoff(outboxNotification.Attempts))
+			outboxNotification.LastError = null.NullValue(err.Error())
+		}
+
+		return s.store.NotificationOutboxDomain.CreateOutboxNotification(ctx, operations, outboxNotification)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return delivered, nil
+}
+
+// send renders the notification from the shop's current templates and delivers it. For
+// an SMS it returns the message as the provider accepted it. Consent is checked again
+// first, since the customer may have texted STOP while the notification waited.
+func (s *notificationOutboxService) send(
+	ctx context.Context,
+	dB db.DB,
+	outboxNotification *models.OutboxNotification,
+) (*models.SMSMessage, error) {
+
+	var payload models.NotificationPayload
+
+	err := json.Unmarshal(outboxNotification.Payload, &payload)
+	if err != nil || payload.Order == nil {
+		return nil, fmt.Errorf("unreadable notification payload: %v", err)
+	}
+
+	if outboxNotification.Event == custom_types.NotificationEventOrderReturn && payload.OrderReturn == nil {
+		return nil, fmt.Errorf("return notification has no return in its payload")
+	}
+
+	if outboxNotification.Channel != custom_types.NotificationChannelEmail {
+		channel, _, err := customerChannel(ctx, dB, s.store, payload.Order)
+		if err != nil {
+			return nil, err
+		}
+
+		if channel != outboxNotification.Channel {
+			return nil, fmt.Errorf("%w: the customer no longer wants %s", errNotificationSuppressed, outboxNotification.Channel)
+		}
+	}
+
+	rendered, err := s.notificationTemplateService.RenderNotification(ctx, dB, outboxNotification.Event, outboxNotification.Channel, payload.Order, payload.OrderReturn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch outboxNotification.Channel {
+	case custom_types.NotificationChannelSMS:
+		return s.orderNotification.SendOrderSMS(payload.Order, rendered.Body)
+	case custom_types.NotificationChannelEmail:
+		return nil, s.orderNotification.SendOrderEmail(payload.Order, rendered.Subject, rendered.Body)
+	case custom_types.NotificationChannelCustomerEmail:
+		if outboxNotification.Recipient == nil {
+			return nil, fmt.Errorf("customer email has no recipient")
+		}
+		return nil, s.orderNotification.SendCustomerEmail(payload.Order, *outboxNotification.Recipient, rendered.Subject, rendered.Body)
+	}
+
+	return nil, fmt.Errorf("no way to send %s by %s", outboxNotification.Event, outboxNotification.Channel)
+}
+
+func (s *notificationOutboxService) ListNotifications(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	filter *models.Filter,
+) (*models.OutboxNotificationList, error) {
+
+	if filter.Status != nil && *filter.Status != "" && !custom_types.NotificationStatus(*filter.Status).IsValid() {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("invalid notification status [%s]", *filter.Status))
+	}
+
+	notifications, err := s.store.NotificationOutboxDomain.ListOutboxNotifications(ctx, dB, shopID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.store.NotificationOutboxDomain.OutboxNotificationsCount(ctx, dB, shopID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OutboxNotificationList{
+		Notifications: notifications,
+		Pagination:    models.NewPagination(count, filter.Page, filter.Per),
+	}, nil
+}
+
+// ReplayNotification puts a dead-lettered notification back in the queue with a fresh
+// set of attempts, e.g. once the SMS account has been topped up.
+func (s *notificationOutboxService) ReplayNotification(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	notificationID int64,
+) (*models.OutboxNotification, error) {
+
+	var outboxNotification *models.OutboxNotification
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		var err error
+		outboxNotification, err = s.store.NotificationOutboxDomain.LockOutboxNotificationByID(ctx, operations, shopID, notificationID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewNotFound("notification", fmt.Sprint(notificationID))
+			}
+			return err
+		}
+
+		if outboxNotification.Status != custom_types.NotificationStatusDead {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("notification [%d] is %s; only dead notifications can be replayed", notificationID, outboxNotification.Status),
+				apperr.Conflict,
+			)
+		}
+
+		outboxNotification.Status = custom_types.NotificationStatusPending
+		outboxNotification.Attempts = 0
+		outboxNotification.NextAttemptAt = s.now()
+
+		return s.store.NotificationOutboxDomain.CreateOutboxNotification(ctx, operations, outboxNotification)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outboxNotification, nil
+}
+
+// notificationBackoff is how long to wait after the given number of failed attempts.
+func notificationBackoff(attempts int) time.Duration {
+	delay := notificationRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= notificationMaxRetryDelay {
+			return notificationMaxRetryDelay
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,412 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func outboxOrder() *models.Order {
+	order := &models.Order{
+		ShopID:          "shop-1",
+		ReferenceNumber: "NBO-0001",
+		PhoneNumber:     "+254712345678",
+	}
+	order.ID = 7
+	return order
+}
+
+// queuedNotification is a pending notification about outboxOrder, as the outbox holds it.
+func queuedNotification(t *testing.T, event custom_types.NotificationEvent, channel custom_types.NotificationChannel, recipient *string) *models.OutboxNotification {
+	payload := &models.NotificationPayload{Order: outboxOrder()}
+	if event == custom_types.NotificationEventOrderReturn {
+		payload.OrderReturn = &models.OrderReturn{OrderID: 7}
+	}
+
+	encoded, err := json.Marshal(payload)
+	assert.NoError(t, err)
+
+	return &models.OutboxNotification{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+		ShopID:               "shop-1",
+		OrderID:              7,
+		Event:                event,
+		Channel:              channel,
+		Recipient:            recipient,
+		Payload:              encoded,
+		Status:               custom_types.NotificationStatusPending,
+		NextAttemptAt:        time.Now().Add(-time.Minute),
+	}
+}
+
+func TestEnqueueOrderConfirmation_SMSAndAdminEmail(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox, SMSOptOutDomain: mockSMSOptOuts}
+	service := services.NewNotificationOutboxService(new(MockOrderNotification), new(MockNotificationTemplateService), store, 3)
+
+	var queued []*models.OutboxNotification
+
+	mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("*models.OutboxNotification")).
+		Run(func(args mock.Arguments) { queued = append(queued, args.Get(2).(*models.OutboxNotification)) }).
+		Return(nil)
+
+	err := service.EnqueueOrderConfirmation(ctx, nil, outboxOrder())
+
+	assert.NoError(t, err)
+	assert.Len(t, queued, 2)
+	for _, outboxNotification := range queued {
+		assert.Equal(t, "shop-1", outboxNotification.ShopID)
+		assert.Equal(t, int64(7), outboxNotification.OrderID)
+		assert.Equal(t, custom_types.NotificationEventOrderConfirmation, outboxNotification.Event)
+		assert.Equal(t, custom_types.NotificationStatusPending, outboxNotification.Status)
+		assert.WithinDuration(t, time.Now(), outboxNotification.NextAttemptAt, time.Minute)
+	}
+	assert.Equal(t, custom_types.NotificationChannelSMS, queued[0].Channel)
+	assert.Equal(t, "+254712345678", *queued[0].Recipient)
+	assert.Equal(t, custom_types.NotificationChannelEmail, queued[1].Channel)
+	assert.Nil(t, queued[1].Recipient)
+}
+
+func TestEnqueueOrderConfirmation_NotWithoutAPhoneNumber(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox}
+	service := services.NewNotificationOutboxService(new(MockOrderNotification), new(MockNotificationTemplateService), store, 3)
+
+	// orders taken without a phone number are not announced
+	order := outboxOrder()
+	order.PhoneNumber = ""
+
+	err := service.EnqueueOrderConfirmation(ctx, nil, order)
+
+	assert.NoError(t, err)
+	mockOutbox.AssertNotCalled(t, "CreateOutboxNotification", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEnqueueOrderConfirmation_RespectsNotificationPreferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		preference custom_types.NotificationPreference
+		channels   []custom_types.NotificationChannel
+	}{
+		// the shop's admin hears about the order either way
+		{"email", custom_types.NotificationPreferenceEmail, []custom_types.NotificationChannel{custom_types.NotificationChannelCustomerEmail, custom_types.NotificationChannelEmail}},
+		{"none", custom_types.NotificationPreferenceNone, []custom_types.NotificationChannel{custom_types.NotificationChannelEmail}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockOutbox := new(MockNotificationOutboxDomain)
+			mockCustomers := new(MockCustomerDomain)
+			store := &domain.Store{NotificationOutboxDomain: mockOutbox, CustomerDomain: mockCustomers}
+			service := services.NewNotificationOutboxService(new(MockOrderNotification), new(MockNotificationTemplateService), store, 3)
+
+			var channels []custom_types.NotificationChannel
+
+			mockCustomers.On("CustomerByID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(&models.Customer{ShopID: "shop-1", Email: "wanjiku@example.com", NotificationPreference: tt.preference}, nil)
+			mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("*models.OutboxNotification")).
+				Run(func(args mock.Arguments) {
+					outboxNotification := args.Get(2).(*models.OutboxNotification)
+					channels = append(channels, outboxNotification.Channel)
+					if outboxNotification.Channel == custom_types.NotificationChannelCustomerEmail {
+						assert.Equal(t, "wanjiku@example.com", *outboxNotification.Recipient)
+					}
+				}).
+				Return(nil)
+
+			order := outboxOrder()
+			order.CustomerID = null.NullValue(int64(1))
+
+			err := service.EnqueueOrderConfirmation(ctx, nil, order)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.channels, channels)
+		})
+	}
+}
+
+func TestEnqueueOrderReturn_NoSMSAfterSTOP(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox, SMSOptOutDomain: mockSMSOptOuts}
+	service := services.NewNotificationOutboxService(new(MockOrderNotification), new(MockNotificationTemplateService), store, 3)
+
+	// a walk-in whose number texted STOP
+	mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+		Return(&models.SMSOptOut{PhoneNumber: "+254712345678", Keyword: "STOP"}, nil)
+
+	err := service.EnqueueOrderReturn(ctx, nil, outboxOrder(), &models.OrderReturn{})
+
+	assert.NoError(t, err)
+	mockOutbox.AssertNotCalled(t, "CreateOutboxNotification", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeliverNext_NothingDue(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox}
+	service := services.NewNotificationOutboxService(new(MockOrderNotification), new(MockNotificationTemplateService), store, 3)
+
+	mockOutbox.On("LockNextDueOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	delivered, err := service.DeliverNext(ctx, &inlineDB{})
+
+	assert.NoError(t, err)
+	assert.False(t, delivered)
+}
+
+func TestDeliverNext_SendsAnSMSFromThePayload(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	mockSMSMessages := new(MockSMSMessageDomain)
+	mockOrderNotification := new(MockOrderNotification)
+	mockTemplates := new(MockNotificationTemplateService)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox, SMSOptOutDomain: mockSMSOptOuts, SMSMessageDomain: mockSMSMessages}
+	service := services.NewNotificationOutboxService(mockOrderNotification, mockTemplates, store, 3)
+
+	outboxNotification := queuedNotification(t, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, null.NullValue("+254712345678"))
+	smsMessage := &models.SMSMessage{ShopID: "shop-1", OrderID: 7, PhoneNumber: "+254712345678", MessageID: "ATXid_1", Status: custom_types.SMSStatusSent}
+
+	mockOutbox.On("LockNextDueOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(outboxNotification, nil)
+	mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockTemplates.On("RenderNotification", ctx, mock.Anything, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, mock.AnythingOfType("*models.Order"), (*models.OrderReturn)(nil)).
+		Return(&models.RenderedNotification{Body: "Order: NBO-0001"}, nil)
+	mockOrderNotification.On("SendOrderSMS", mock.MatchedBy(func(order *models.Order) bool { return order.ReferenceNumber == "NBO-0001" }), "Order: NBO-0001").
+		Return(smsMessage, nil)
+	// the SMS is kept for its delivery report
+	mockSMSMessages.On("CreateSMSMessage", ctx, mock.Anything, smsMessage).
+		Return(nil)
+	mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, outboxNotification).
+		Return(nil)
+
+	delivered, err := service.DeliverNext(ctx, &inlineDB{})
+
+	assert.NoError(t, err)
+	assert.True(t, delivered)
+	assert.Equal(t, custom_types.NotificationStatusSent, outboxNotification.Status)
+	assert.Equal(t, 1, outboxNotification.Attempts)
+	assert.NotNil(t, outboxNotification.SentAt)
+	assert.Equal(t, int64(1), *smsMessage.NotificationID)
+
+	mockOutbox.AssertExpectations(t)
+	mockSMSMessages.AssertExpectations(t)
+	mockOrderNotification.AssertExpectations(t)
+	mockTemplates.AssertExpectations(t)
+}
+
+func TestDeliverNext_SendsTheAdminEmail(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	mockSMSMessages := new(MockSMSMessageDomain)
+	mockOrderNotification := new(MockOrderNotification)
+	mockTemplates := new(MockNotificationTemplateService)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox, SMSMessageDomain: mockSMSMessages}
+	service := services.NewNotificationOutboxService(mockOrderNotification, mockTemplates, store, 3)
+
+	outboxNotification := queuedNotification(t, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelEmail, nil)
+
+	mockOutbox.On("LockNextDueOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(outboxNotification, nil)
+	mockTemplates.On("RenderNotification", ctx, mock.Anything, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelEmail, mock.AnythingOfType("*models.Order"), (*models.OrderReturn)(nil)).
+		Return(&models.RenderedNotification{Subject: "📦 New Order Received - #NBO-0001", Body: "<p>NBO-0001</p>"}, nil)
+	mockOrderNotification.On("SendOrderEmail", mock.AnythingOfType("*models.Order"), "📦 New Order Received - #NBO-0001", "<p>NBO-0001</p>").
+		Return(nil)
+	mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, outboxNotification).
+		Return(nil)
+
+	delivered, err := service.DeliverNext(ctx, &inlineDB{})
+
+	assert.NoError(t, err)
+	assert.True(t, delivered)
+	assert.Equal(t, custom_types.NotificationStatusSent, outboxNotification.Status)
+	// the email is not an SMS
+	mockSMSMessages.AssertNotCalled(t, "CreateSMSMessage", mock.Anything, mock.Anything, mock.Anything)
+	mockOrderNotification.AssertExpectations(t)
+}
+
+func TestDeliverNext_FailuresBackOffThenDeadLetter(t *testing.T) {
+	loggers.InitLogger("test")
+
+	tests := []struct {
+		name     string
+		attempts int
+		status   custom_types.NotificationStatus
+		backoff  time.Duration
+	}{
+		{"first failure", 0, custom_types.NotificationStatusPending, 30 * time.Second},
+		{"second failure", 1, custom_types.NotificationStatusPending, time.Minute},
+		{"capped at an hour", 7, custom_types.NotificationStatusPending, time.Hour},
+		{"out of attempts", 9, custom_types.NotificationStatusDead, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockOutbox := new(MockNotificationOutboxDomain)
+			mockOrderNotification := new(MockOrderNotification)
+			mockTemplates := new(MockNotificationTemplateService)
+			store := &domain.Store{NotificationOutboxDomain: mockOutbox}
+			service := services.NewNotificationOutboxService(mockOrderNotification, mockTemplates, store, 10)
+
+			outboxNotification := queuedNotification(t, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelEmail, nil)
+			outboxNotification.Attempts = tt.attempts
+			dueAt := outboxNotification.NextAttemptAt
+
+			mockOutbox.On("LockNextDueOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("time.Time")).
+				Return(outboxNotification, nil)
+			mockTemplates.On("RenderNotification", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(&models.RenderedNotification{Subject: "New order", Body: "<p>NBO-0001</p>"}, nil)
+			mockOrderNotification.On("SendOrderEmail", mock.Anything, mock.Anything, mock.Anything).
+				Return(errors.New("insufficient balance"))
+			mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, outboxNotification).
+				Return(nil)
+
+			delivered, err := service.DeliverNext(ctx, &inlineDB{})
+
+			assert.NoError(t, err)
+			assert.True(t, delivered)
+			assert.Equal(t, tt.attempts+1, outboxNotification.Attempts)
+			assert.Equal(t, tt.status, outboxNotification.Status)
+			assert.Equal(t, "insufficient balance", *outboxNotification.LastError)
+			if tt.backoff > 0 {
+				assert.WithinDuration(t, time.Now().Add(tt.backoff), outboxNotification.NextAttemptAt, 5*time.Second)
+			} else {
+				assert.Equal(t, dueAt, outboxNotification.NextAttemptAt)
+			}
+			mockOutbox.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeliverNext_SuppressesNotificationsAfterSTOP(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	mockOrderNotification := new(MockOrderNotification)
+	mockTemplates := new(MockNotificationTemplateService)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox, SMSOptOutDomain: mockSMSOptOuts}
+	service := services.NewNotificationOutboxService(mockOrderNotification, mockTemplates, store, 3)
+
+	outboxNotification := queuedNotification(t, custom_types.NotificationEventOrderReturn, custom_types.NotificationChannelSMS, null.NullValue("+254712345678"))
+
+	mockOutbox.On("LockNextDueOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(outboxNotification, nil)
+	// the customer texted STOP while the SMS waited behind an outage
+	mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, mock.Anything, "+254712345678").
+		Return(&models.SMSOptOut{PhoneNumber: "+254712345678", Keyword: "STOP"}, nil)
+	mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, outboxNotification).
+		Return(nil)
+
+	delivered, err := service.DeliverNext(ctx, &inlineDB{})
+
+	assert.NoError(t, err)
+	assert.True(t, delivered)
+	assert.Equal(t, custom_types.NotificationStatusSuppressed, outboxNotification.Status)
+	assert.Contains(t, *outboxNotification.LastError, "no longer wants sms")
+	mockOrderNotification.AssertNotCalled(t, "SendOrderSMS", mock.Anything, mock.Anything)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestDeliverNext_RecordsALookupFailureAsAnAttempt(t *testing.T) {
+	loggers.InitLogger("test")
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	mockSMSOptOuts := new(MockSMSOptOutDomain)
+	mockOrderNotification := new(MockOrderNotification)
+	mockTemplates := new(MockNotificationTemplateService)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox, SMSOptOutDomain: mockSMSOptOuts}
+	service := services.NewNotificationOutboxService(mockOrderNotification, mockTemplates, store, 3)
+
+	dB := &inlineDB{}
+	outboxNotification := queuedNotification(t, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, null.NullValue("+254712345678"))
+
+	mockOutbox.On("LockNextDueOutboxNotification", ctx, mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(outboxNotification, nil)
+	// the consent lookup runs on its own connection, not in the claiming transaction
+	mockSMSOptOuts.On("SMSOptOutByPhoneNumber", ctx, dB, "+254712345678").
+		Return(nil, apperr.NewDatabaseError(errors.New("connection reset by peer")))
+	mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, outboxNotification).
+		Return(nil)
+
+	delivered, err := service.DeliverNext(ctx, dB)
+
+	assert.NoError(t, err)
+	assert.True(t, delivered)
+	assert.Equal(t, 1, outboxNotification.Attempts)
+	assert.Equal(t, custom_types.NotificationStatusPending, outboxNotification.Status)
+	assert.Contains(t, *outboxNotification.LastError, "connection reset by peer")
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), outboxNotification.NextAttemptAt, 5*time.Second)
+	mockOrderNotification.AssertNotCalled(t, "SendOrderSMS", mock.Anything, mock.Anything)
+	mockSMSOptOuts.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestReplayNotification_Success(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox}
+	service := services.NewNotificationOutboxService(new(MockOrderNotification), new(MockNotificationTemplateService), store, 3)
+
+	outboxNotification := queuedNotification(t, custom_types.NotificationEventOrderReturn, custom_types.NotificationChannelSMS, null.NullValue("+254712345678"))
+	outboxNotification.Status = custom_types.NotificationStatusDead
+	outboxNotification.Attempts = 3
+
+	mockOutbox.On("LockOutboxNotificationByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(outboxNotification, nil)
+	mockOutbox.On("CreateOutboxNotification", ctx, mock.Anything, outboxNotification).
+		Return(nil)
+
+	replayed, err := service.ReplayNotification(ctx, &inlineDB{}, "shop-1", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.NotificationStatusPending, replayed.Status)
+	assert.Equal(t, 0, replayed.Attempts)
+	assert.WithinDuration(t, time.Now(), replayed.NextAttemptAt, time.Minute)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestReplayNotification_Rejects(t *testing.T) {
+	ctx := context.Background()
+	mockOutbox := new(MockNotificationOutboxDomain)
+	store := &domain.Store{NotificationOutboxDomain: mockOutbox}
+	service := services.NewNotificationOutboxService(new(MockOrderNotification), new(MockNotificationTemplateService), store, 3)
+
+	mockOutbox.On("LockOutboxNotificationByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(queuedNotification(t, custom_types.NotificationEventOrderReturn, custom_types.NotificationChannelSMS, null.NullValue("+254712345678")), nil)
+	mockOutbox.On("LockOutboxNotificationByID", ctx, mock.Anything, "shop-2", int64(1)).
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	// only dead notifications can be replayed
+	_, err := service.ReplayNotification(ctx, &inlineDB{}, "shop-1", 1)
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+
+	_, err = service.ReplayNotification(ctx, &inlineDB{}, "shop-2", 1)
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	mockOutbox.AssertNotCalled(t, "CreateOutboxNotification", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/notification"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+	"time"
+)
+
+type (
+	NotificationTemplateService interface {
+		RenderNotification(ctx context.Context, operations db.SQLOperations, event custom_types.NotificationEvent, channel custom_types.NotificationChannel, order *models.Order, orderReturn *models.OrderReturn) (*models.RenderedNotification, error)
+		ListNotificationTemplates(ctx context.Context, dB db.DB, shopID string) ([]*models.NotificationTemplate, error)
+		SaveNotificationTemplate(ctx context.Context, dB db.DB, shopID string, form *dtos.NotificationTemplateForm) (*models.NotificationTemplate, error)
+		DeleteNotificationTemplate(ctx context.Context, dB db.DB, shopID string, templateID int64) (*models.NotificationTemplate, error)
+		PreviewNotificationTemplate(ctx context.Context, dB db.DB, shopID string, form *dtos.PreviewNotificationTemplateForm) (*models.RenderedNotification, error)
+	}
+
+	notificationTemplateService struct {
+		templateRenderer notification.TemplateRenderer
+		store            *domain.Store
+	}
+)
+
+func NewNotificationTemplateService(
+	templateRenderer notification.TemplateRenderer,
+	store *domain.Store,
+) NotificationTemplateService {
+	return &notificationTemplateService{
+		templateRenderer: templateRenderer,
+		store:            store,
+	}
+}
+
+// RenderNotification fills in a notification about order in its customer's language,
+// using the shop's own template when it has one. Walk-in orders get the default language.
+func (s *notificationTemplateService) RenderNotification(
+	ctx context.Context,
+	operations db.SQLOperations,
+	event custom_types.NotificationEvent,
+	channel custom_types.NotificationChannel,
+	order *models.Order,
+	orderReturn *models.OrderReturn,
+) (*models.RenderedNotification, error) {
+
+	shop, err := s.store.ShopDomain.ShopByID(ctx, operations, order.ShopID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &models.NotificationTemplateData{
+		Shop:        shop,
+		Order:       order,
+		OrderReturn: orderReturn,
+		Locale:      custom_types.DefaultLocale,
+	}
+
+	if order.CustomerID != nil {
+		customer, err := s.store.CustomerDomain.CustomerByID(ctx, operations, order.ShopID, *order.CustomerID)
+		if err != nil && !apperr.IsNoRowsErr(err) {
+			return nil, err
+		}
+
+		if customer != nil {
+			data.Customer = customer
+			if customer.Locale.IsValid() {
+				data.Locale = customer.Locale
+			}
+		}
+	}
+
+	notificationTemplate, err := s.notificationTemplate(ctx, operations, order.ShopID, event, channel, data.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.templateRenderer.Render(notificationTemplate, data)
+}
+
+// notificationTemplate is the shop's own template in locale, or else the built-in one.
+func (s *notificationTemplateService) notificationTemplate(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	event custom_types.NotificationEvent,
+	channel custom_types.NotificationChannel,
+	locale custom_types.Locale,
+) (*models.NotificationTemplate, error) {
+
+	notificationTemplate, err := s.store.NotificationTemplateDomain.NotificationTemplate(ctx, operations, shopID, event, channel, locale)
+	if err == nil {
+		return notificationTemplate, nil
+	}
+	if !apperr.IsNoRowsErr(err) {
+		return nil, err
+	}
+
+	return s.templateRenderer.DefaultTemplate(event, channel, locale)
+}
+
+// ListNotificationTemplates lists the templates the shop has set; the rest are built in.
+func (s *notificationTemplateService) ListNotificationTemplates(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+) ([]*models.NotificationTemplate, error) {
+
+	return s.store.NotificationTemplateDomain.NotificationTemplatesByShopID(ctx, dB, shopID)
+}
+
+// SaveNotificationTemplate sets the shop's template for a notification in one language.
+// It is rendered against the sample order first, so a template that cannot be sent is
+// refused here rather than dead-lettering the notifications that use it.
+func (s *notificationTemplateService) SaveNotificationTemplate(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	form *dtos.NotificationTemplateForm,
+) (*models.NotificationTemplate, error) {
+
+	err := validateNotificationTemplateKey(form.Event, form.Channel, form.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(form.Body) == "" {
+		return nil, apperr.NewBadRequest("template body is required")
+	}
+
+	subject := form.Subject
+	if !form.Channel.IsEmail() {
+		subject = nil
+	}
+
+	shop, err := s.store.ShopDomain.ShopByID(ctx, dB, shopID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return nil, apperr.NewNotFound("shop", shopID)
+		}
+		return nil, err
+	}
+
+	candidate := &models.NotificationTemplate{
+		ShopID:  shopID,
+		Event:   form.Event,
+		Channel: form.Channel,
+		Locale:  form.Locale,
+		Subject: subject,
+		Body:    form.Body,
+	}
+
+	_, err = s.templateRenderer.Render(candidate, sampleNotificationData(shop, form.Locale))
+	if err != nil {
+		return nil, apperr.NewBadRequest(err.Error())
+	}
+
+	var notificationTemplate *models.NotificationTemplate
+
+	err = dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		var err error
+		notificationTemplate, err = s.store.NotificationTemplateDomain.NotificationTemplate(ctx, operations, shopID, form.Event, form.Channel, form.Locale)
+		if err != nil {
+			if !apperr.IsNoRowsErr(err) {
+				return err
+			}
+			notificationTemplate = candidate
+		}
+
+		notificationTemplate.Subject = candidate.Subject
+		notificationTemplate.Body = candidate.Body
+
+		return s.store.NotificationTemplateDomain.CreateNotificationTemplate(ctx, operations, notificationTemplate)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notificationTemplate, nil
+}
+
+// DeleteNotificationTemplate goes back to the built-in template.
+func (s *notificationTemplateService) DeleteNotificationTemplate(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	templateID int64,
+) (*models.NotificationTemplate, error) {
+
+	notificationTemplate, err := s.store.NotificationTemplateDomain.NotificationTemplateByID(ctx, dB, shopID, templateID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return nil, apperr.NewNotFound("notification template", fmt.Sprint(templateID))
+		}
+		return nil, err
+	}
+
+	err = s.store.NotificationTemplateDomain.DeleteNotificationTemplate(ctx, dB, notificationTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return notificationTemplate, nil
+}
+
+// PreviewNotificationTemplate renders a draft template, or the one the shop would send
+// now, against a sample order in the shop's currency.
+func (s *notificationTemplateService) PreviewNotificationTemplate(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	form *dtos.PreviewNotificationTemplateForm,
+) (*models.RenderedNotification, error) {
+
+	if form.Locale == "" {
+		form.Locale = custom_types.DefaultLocale
+	}
+
+	err := validateNotificationTemplateKey(form.Event, form.Channel, form.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	shop, err := s.store.ShopDomain.ShopByID(ctx, dB, shopID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return nil, apperr.NewNotFound("shop", shopID)
+		}
+		return nil, err
+	}
+
+	var notificationTemplate *models.NotificationTemplate
+
+	if form.Body != nil {
+		notificationTemplate = &models.NotificationTemplate{
+			ShopID:  shopID,
+			Event:   form.Event,
+			Channel: form.Channel,
+			Locale:  form.Locale,
+			Subject: form.Subject,
+			Body:    *form.Body,
+		}
+	} else {
+		notificationTemplate, err = s.notificationTemplate(ctx, dB, shopID, form.Event, form.Channel, form.Locale)
+		if err != nil {
+			return nil, apperr.NewBadRequest(err.Error())
+		}
+	}
+
+	rendered, err := s.templateRenderer.Render(notificationTemplate, sampleNotificationData(shop, form.Locale))
+	if err != nil {
+		return nil, apperr.NewBadRequest(err.Error())
+	}
+
+	return rendered, nil
+}
+
+func validateNotificationTemplateKey(
+	event custom_types.NotificationEvent,
+	channel custom_types.NotificationChannel,
+	locale custom_types.Locale,
+) error {
+
+	if !event.IsValid() {
+		return apperr.NewBadRequest(fmt.Sprintf("invalid notification event [%s]", event))
+	}
+
+	if !channel.IsValid() {
+		return apperr.NewBadRequest(fmt.Sprintf("invalid notification channel [%s]", channel))
+	}
+
+	if !locale.IsValid() {
+		return apperr.NewBadRequest(fmt.Sprintf("invalid locale [%s]", locale))
+	}
+
+	return nil
+}
+
+// sampleNotificationData is a made-up order, customer and return for previews.
+func sampleNotificationData(
+	shop *models.Shop,
+	locale custom_types.Locale,
+) *models.NotificationTemplateData {
+
+	customer := &models.Customer{
+		Name:        "Wanjiku Kamau",
+		Email:       "wanjiku@example.com",
+		PhoneNumber: "+254712345678",
+		ShopID:      shop.ID,
+		Locale:      locale,
+	}
+	customer.ID = 1
+
+	items := []*models.OrderItem{
+		{ProductID: 101, UnitPrice: custom_types.NewMoney(45000), Quantity: 2, TotalAmount: custom_types.NewMoney(90000)},
+		{ProductID: 102, UnitPrice: custom_types.NewMoney(120000), Quantity: 1, TotalAmount: custom_types.NewMoney(120000)},
+	}
+
+	order := &models.Order{
+		ReferenceNumber: "SAMPLE-000001",
+		PhoneNumber:     customer.PhoneNumber,
+		OrderStatus:     custom_types.OrderStatusPaid,
+		OrderMedium:     custom_types.OrderMediumOnline,
+		PaymentMethod:   custom_types.PaymentMethodMpesa,
+		CustomerID:      null.NullValue(customer.ID),
+		ShopID:          shop.ID,
+		TotalItems:      3,
+		Subtotal:        custom_types.NewMoney(210000),
+		LineDiscount:    custom_types.NewMoney(0),
+		Discount:        custom_types.NewMoney(10000),
+		TotalAmount:     custom_types.NewMoney(200000),
+		Items:           items,
+	}
+	order.ID = 1
+	order.CreatedAt = time.Date(2025, 10, 1, 9, 30, 0, 0, time.UTC)
+
+	orderReturn := &models.OrderReturn{
+		OrderID:      order.ID,
+		Reason:       "wrong size",
+		RefundAmount: custom_types.NewMoney(45000),
+		Items: []*models.OrderReturnItem{
+			{ProductID: 101, Quantity: 1, RefundAmount: custom_types.NewMoney(45000)},
+		},
+	}
+
+	return &models.NotificationTemplateData{
+		Shop:        shop,
+		Customer:    customer,
+		Order:       order,
+		OrderReturn: orderReturn,
+		Locale:      locale,
+	}
+}
@@ -0,0 +1,340 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/notification"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+var (
+	savannahDuka = &models.Shop{ID: "shop-1", Name: "Savannah Duka", Currency: "KES"}
+	kiliCorner   = &models.Shop{ID: "shop-2", Name: "Kili Corner", Currency: "KES"}
+)
+
+func templateOrder(shopID string) *models.Order {
+	order := &models.Order{
+		ShopID:          shopID,
+		ReferenceNumber: "NBO-0001",
+		PhoneNumber:     "+254712345678",
+		TotalItems:      2,
+		TotalAmount:     custom_types.NewMoney(125000),
+	}
+	order.ID = 7
+	return order
+}
+
+func TestRenderNotification_InTheCustomersLanguage(t *testing.T) {
+	swahiliCustomer := &models.Customer{Name: "Wanjiku", ShopID: "shop-1", Locale: custom_types.LocaleSwahili}
+
+	tests := []struct {
+		name     string
+		shop     *models.Shop
+		customer *models.Customer
+		locale   custom_types.Locale
+		contains []string
+	}{
+		// walk-in customers get English
+		{"walk-in", savannahDuka, nil, custom_types.LocaleEnglish, []string{"Order: NBO-0001", "Total: KES 1,250.00"}},
+		{"swahili customer", savannahDuka, swahiliCustomer, custom_types.LocaleSwahili, []string{"Oda: NBO-0001", "Asante kwa kununua Savannah Duka"}},
+		// amounts are shillings whatever shop sends them
+		{"another shop", kiliCorner, nil, custom_types.LocaleEnglish, []string{"Thank you for shopping at Kili Corner", "Total: KES 1,250.00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockShops := new(MockShopDomain)
+			mockCustomers := new(MockCustomerDomain)
+			mockTemplates := new(MockNotificationTemplateDomain)
+			store := &domain.Store{ShopDomain: mockShops, CustomerDomain: mockCustomers, NotificationTemplateDomain: mockTemplates}
+			service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+			order := templateOrder(tt.shop.ID)
+			if tt.customer != nil {
+				order.CustomerID = null.NullValue(int64(3))
+				mockCustomers.On("CustomerByID", ctx, mock.Anything, tt.shop.ID, int64(3)).
+					Return(tt.customer, nil)
+			}
+
+			mockShops.On("ShopByID", ctx, mock.Anything, tt.shop.ID).
+				Return(tt.shop, nil)
+			mockTemplates.On("NotificationTemplate", ctx, mock.Anything, tt.shop.ID, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, tt.locale).
+				Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+			rendered, err := service.RenderNotification(ctx, nil, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, order, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.locale, rendered.Locale)
+			for _, contains := range tt.contains {
+				assert.Contains(t, rendered.Body, contains)
+			}
+			mockShops.AssertExpectations(t)
+			mockCustomers.AssertExpectations(t)
+			mockTemplates.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRenderNotification_ShopTemplatesOverrideTheBuiltInOnes(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	mockTemplates := new(MockNotificationTemplateDomain)
+	store := &domain.Store{ShopDomain: mockShops, NotificationTemplateDomain: mockTemplates}
+	service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-1").
+		Return(savannahDuka, nil)
+	mockTemplates.On("NotificationTemplate", ctx, mock.Anything, "shop-1", custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, custom_types.LocaleEnglish).
+		Return(&models.NotificationTemplate{
+			ShopID:  "shop-1",
+			Event:   custom_types.NotificationEventOrderConfirmation,
+			Channel: custom_types.NotificationChannelSMS,
+			Locale:  custom_types.LocaleEnglish,
+			Body:    "{{.Shop.Name}}: order {{.Order.ReferenceNumber}} is {{money .Order.TotalAmount}}",
+		}, nil)
+
+	rendered, err := service.RenderNotification(ctx, nil, custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, templateOrder("shop-1"), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Savannah Duka: order NBO-0001 is KES 1,250.00", rendered.Body)
+	mockTemplates.AssertExpectations(t)
+}
+
+func TestSaveNotificationTemplate_Creates(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	mockTemplates := new(MockNotificationTemplateDomain)
+	store := &domain.Store{ShopDomain: mockShops, NotificationTemplateDomain: mockTemplates}
+	service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-1").
+		Return(savannahDuka, nil)
+	mockTemplates.On("NotificationTemplate", ctx, mock.Anything, "shop-1", custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, custom_types.LocaleEnglish).
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockTemplates.On("CreateNotificationTemplate", ctx, mock.Anything, mock.AnythingOfType("*models.NotificationTemplate")).
+		Return(nil)
+
+	saved, err := service.SaveNotificationTemplate(ctx, &inlineDB{}, "shop-1", &dtos.NotificationTemplateForm{
+		Event:   custom_types.NotificationEventOrderConfirmation,
+		Channel: custom_types.NotificationChannelSMS,
+		Locale:  custom_types.LocaleEnglish,
+		Subject: null.NullValue("ignored for SMS"),
+		Body:    "{{.Shop.Name}}: order {{.Order.ReferenceNumber}} is {{money .Order.TotalAmount}}",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "shop-1", saved.ShopID)
+	assert.Nil(t, saved.Subject)
+	mockTemplates.AssertExpectations(t)
+}
+
+func TestSaveNotificationTemplate_ReplacesTheShopsTemplate(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	mockTemplates := new(MockNotificationTemplateDomain)
+	store := &domain.Store{ShopDomain: mockShops, NotificationTemplateDomain: mockTemplates}
+	service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+	existing := &models.NotificationTemplate{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 4},
+		ShopID:               "shop-1",
+		Event:                custom_types.NotificationEventOrderConfirmation,
+		Channel:              custom_types.NotificationChannelSMS,
+		Locale:               custom_types.LocaleEnglish,
+		Body:                 "Order {{.Order.ReferenceNumber}}",
+	}
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-1").
+		Return(savannahDuka, nil)
+	mockTemplates.On("NotificationTemplate", ctx, mock.Anything, "shop-1", custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelSMS, custom_types.LocaleEnglish).
+		Return(existing, nil)
+	mockTemplates.On("CreateNotificationTemplate", ctx, mock.Anything, existing).
+		Return(nil)
+
+	saved, err := service.SaveNotificationTemplate(ctx, &inlineDB{}, "shop-1", &dtos.NotificationTemplateForm{
+		Event:   custom_types.NotificationEventOrderConfirmation,
+		Channel: custom_types.NotificationChannelSMS,
+		Locale:  custom_types.LocaleEnglish,
+		Body:    "Order {{.Order.ReferenceNumber}} received",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), saved.ID)
+	assert.Equal(t, "Order {{.Order.ReferenceNumber}} received", saved.Body)
+	mockTemplates.AssertExpectations(t)
+}
+
+func TestSaveNotificationTemplate_RejectsTemplatesThatCannotBeSent(t *testing.T) {
+	forms := []*dtos.NotificationTemplateForm{
+		{Event: "order_shipped", Channel: custom_types.NotificationChannelSMS, Locale: custom_types.LocaleEnglish, Body: "Shipped"},
+		{Event: custom_types.NotificationEventOrderConfirmation, Channel: custom_types.NotificationChannelSMS, Locale: "fr", Body: "Commande"},
+		{Event: custom_types.NotificationEventOrderConfirmation, Channel: custom_types.NotificationChannelSMS, Locale: custom_types.LocaleEnglish, Body: "  "},
+		{Event: custom_types.NotificationEventOrderConfirmation, Channel: custom_types.NotificationChannelSMS, Locale: custom_types.LocaleEnglish, Body: "Order {{.Order.Reference"},
+		{Event: custom_types.NotificationEventOrderConfirmation, Channel: custom_types.NotificationChannelSMS, Locale: custom_types.LocaleEnglish, Body: "Order {{.Order.Tracking}}"},
+		{Event: custom_types.NotificationEventOrderConfirmation, Channel: custom_types.NotificationChannelEmail, Locale: custom_types.LocaleEnglish, Body: "<p>Order {{.Order.ReferenceNumber}}</p>"},
+	}
+
+	for _, form := range forms {
+		t.Run(form.Body, func(t *testing.T) {
+			ctx := context.Background()
+			mockShops := new(MockShopDomain)
+			mockTemplates := new(MockNotificationTemplateDomain)
+			store := &domain.Store{ShopDomain: mockShops, NotificationTemplateDomain: mockTemplates}
+			service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+			mockShops.On("ShopByID", ctx, mock.Anything, "shop-1").
+				Return(savannahDuka, nil).Maybe()
+
+			_, err := service.SaveNotificationTemplate(ctx, &inlineDB{}, "shop-1", form)
+
+			assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+			mockTemplates.AssertNotCalled(t, "CreateNotificationTemplate", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestDeleteNotificationTemplate(t *testing.T) {
+	ctx := context.Background()
+	mockTemplates := new(MockNotificationTemplateDomain)
+	store := &domain.Store{NotificationTemplateDomain: mockTemplates}
+	service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+	saved := &models.NotificationTemplate{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 4}, ShopID: "shop-1"}
+
+	mockTemplates.On("NotificationTemplateByID", ctx, mock.Anything, "shop-2", int64(4)).
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockTemplates.On("NotificationTemplateByID", ctx, mock.Anything, "shop-1", int64(4)).
+		Return(saved, nil)
+	mockTemplates.On("DeleteNotificationTemplate", ctx, mock.Anything, saved).
+		Return(nil).Once()
+
+	_, err := service.DeleteNotificationTemplate(ctx, nil, "shop-2", 4)
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	deleted, err := service.DeleteNotificationTemplate(ctx, nil, "shop-1", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, deleted)
+
+	mockTemplates.AssertExpectations(t)
+}
+
+func TestPreviewNotificationTemplate_WhatTheShopSendsToday(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	mockTemplates := new(MockNotificationTemplateDomain)
+	store := &domain.Store{ShopDomain: mockShops, NotificationTemplateDomain: mockTemplates}
+	service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-2").
+		Return(kiliCorner, nil)
+	mockTemplates.On("NotificationTemplate", ctx, mock.Anything, "shop-2", custom_types.NotificationEventOrderConfirmation, custom_types.NotificationChannelEmail, custom_types.LocaleSwahili).
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	// without a draft the preview is what the shop sends today
+	rendered, err := service.PreviewNotificationTemplate(ctx, nil, "shop-2", &dtos.PreviewNotificationTemplateForm{
+		Event:   custom_types.NotificationEventOrderConfirmation,
+		Channel: custom_types.NotificationChannelEmail,
+		Locale:  custom_types.LocaleSwahili,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "📦 Oda Mpya Imepokelewa - #SAMPLE-000001", rendered.Subject)
+	assert.Contains(t, rendered.Body, "KES 2,000.00")
+	assert.Contains(t, rendered.Body, "Wanjiku Kamau")
+	mockTemplates.AssertExpectations(t)
+}
+
+func TestPreviewNotificationTemplate_EscapesCustomerValuesInAnEmail(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{ShopDomain: mockShops}
+	service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-1").
+		Return(savannahDuka, nil)
+
+	rendered, err := service.PreviewNotificationTemplate(ctx, nil, "shop-1", &dtos.PreviewNotificationTemplateForm{
+		Event:   custom_types.NotificationEventOrderReturn,
+		Channel: custom_types.NotificationChannelEmail,
+		Subject: null.NullValue("Return for {{.Order.ReferenceNumber}}"),
+		Body:    null.NullValue("<p>{{.OrderReturn.Reason}} & {{returnedItems}} item, refund {{money .OrderReturn.RefundAmount}}</p>"),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.LocaleEnglish, rendered.Locale)
+	assert.Equal(t, "Return for SAMPLE-000001", rendered.Subject)
+	assert.Equal(t, "<p>wrong size & 1 item, refund KES 450.00</p>", rendered.Body)
+}
+
+func TestPreviewNotificationTemplate_Rejects(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{ShopDomain: mockShops}
+	service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-1").
+		Return(savannahDuka, nil)
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-9").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	_, err := service.PreviewNotificationTemplate(ctx, nil, "shop-1", &dtos.PreviewNotificationTemplateForm{
+		Event:   custom_types.NotificationEventOrderConfirmation,
+		Channel: custom_types.NotificationChannelSMS,
+		Body:    null.NullValue("{{.Order.Tracking}}"),
+	})
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+
+	_, err = service.PreviewNotificationTemplate(ctx, nil, "shop-9", &dtos.PreviewNotificationTemplateForm{
+		Event:   custom_types.NotificationEventOrderConfirmation,
+		Channel: custom_types.NotificationChannelSMS,
+	})
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+}
+
+func TestPreviewNotificationTemplate_BuiltInTemplatesRender(t *testing.T) {
+	channels := map[custom_types.NotificationEvent][]custom_types.NotificationChannel{
+		custom_types.NotificationEventOrderConfirmation: {custom_types.NotificationChannelSMS, custom_types.NotificationChannelEmail, custom_types.NotificationChannelCustomerEmail},
+		custom_types.NotificationEventOrderReturn:       {custom_types.NotificationChannelSMS, custom_types.NotificationChannelCustomerEmail},
+	}
+
+	for event, eventChannels := range channels {
+		for _, channel := range eventChannels {
+			for _, locale := range []custom_types.Locale{custom_types.LocaleEnglish, custom_types.LocaleSwahili} {
+				ctx := context.Background()
+				mockShops := new(MockShopDomain)
+				mockTemplates := new(MockNotificationTemplateDomain)
+				store := &domain.Store{ShopDomain: mockShops, NotificationTemplateDomain: mockTemplates}
+				service := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), store)
+
+				mockShops.On("ShopByID", ctx, mock.Anything, "shop-1").
+					Return(savannahDuka, nil)
+				mockTemplates.On("NotificationTemplate", ctx, mock.Anything, "shop-1", event, channel, locale).
+					Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+				rendered, err := service.PreviewNotificationTemplate(ctx, nil, "shop-1", &dtos.PreviewNotificationTemplateForm{
+					Event:   event,
+					Channel: channel,
+					Locale:  locale,
+				})
+
+				assert.NoError(t, err, "%s %s %s", event, channel, locale)
+				if assert.NotNil(t, rendered) {
+					assert.Equal(t, locale, rendered.Locale, "%s %s", event, channel)
+					assert.Equal(t, channel.IsEmail(), rendered.Subject != "", "%s %s", event, channel)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"strings"
+)
+
+// taxRateBasis is 100% in the basis points tax rates are kept in.
+const taxRateBasis = 10000
+
+// orderPricing is the server's view of what an order costs:
+//
+//	subtotal       sum of unit price x quantity
+//	- line discount   discounts on individual lines
+//	- order discount  discount on the order as a whole, shared over the lines
+//	+ tax             on lines priced exclusive of tax only
+//	= grand total
+//
+// TaxTotal is all the VAT in the order, whether it was added on top or was already
+// inside tax-inclusive prices.
+type orderPricing struct {
+	Subtotal      custom_types.Money
+	LineDiscount  custom_types.Money
+	OrderDiscount custom_types.Money
+	TaxTotal      custom_types.Money
+	GrandTotal    custom_types.Money
+}
+
+// priceOrder prices orderItems, which must have ProductID, UnitPrice, Quantity, Discount,
+// TaxRate and PriceIncludesTax set. It fills in each line's share of the order discount, its tax and its total, and
+// returns the order level components, which always add up to the sum of the lines.
+func priceOrder(
+	orderItems []*models.OrderItem,
+	orderDiscount custom_types.Money,
+) (*orderPricing, error) {
+
+	pricing := &orderPricing{
+		Subtotal:      custom_types.NewMoney(0),
+		LineDiscount:  custom_types.NewMoney(0),
+		OrderDiscount: orderDiscount,
+		TaxTotal:      custom_types.NewMoney(0),
+		GrandTotal:    custom_types.NewMoney(0),
+	}
+
+	if orderDiscount.IsNegative() {
+		return nil, apperr.NewBadRequest("order discount cannot be negative")
+	}
+
+	netAmounts := make([]int64, len(orderItems))
+
+	for i, orderItem := range orderItems {
+		if orderItem.Quantity <= 0 {
+			return nil, apperr.NewBadRequest(fmt.Sprintf("quantity for product [%d] must be greater than zero", orderItem.ProductID))
+		}
+
+		gross := orderItem.UnitPrice.Mul(orderItem.Quantity)
+
+		if orderItem.Discount.IsNegative() || orderItem.Discount.Amount > gross.Amount {
+			return nil, apperr.NewBadRequest(fmt.Sprintf(
+				"discount %s on product [%d] must be between zero and the line amount %s",
+				orderItem.Discount,
+				orderItem.ProductID,
+				gross,
+			))
+		}
+
+		net, err := gross.Sub(orderItem.Discount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		pricing.Subtotal, err = pricing.Subtotal.Add(gross)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		pricing.LineDiscount, err = pricing.LineDiscount.Add(orderItem.Discount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		netAmounts[i] = net.Amount
+	}
+
+	discountable, err := pricing.Subtotal.Sub(pricing.LineDiscount)
+	if err != nil {
+		return nil, apperr.NewError(err)
+	}
+
+	if orderDiscount.Amount > discountable.Amount {
+		return nil, apperr.NewBadRequest(fmt.Sprintf(
+			"order discount %s is more than the discounted subtotal %s",
+			orderDiscount,
+			discountable,
+		))
+	}
+
+	orderDiscounts := allocateMoney(orderDiscount, netAmounts)
+
+	for i, orderItem := range orderItems {
+		orderItem.OrderDiscount = orderDiscounts[i]
+
+		// tax is worked out on what the line actually sells for, after every discount
+		net, err := orderItem.UnitPrice.Mul(orderItem.Quantity).Sub(orderItem.Discount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		net, err = net.Sub(orderItem.OrderDiscount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		orderItem.TaxAmount = lineTax(net, orderItem.TaxRate, orderItem.PriceIncludesTax)
+		orderItem.TotalAmount = net
+		if !orderItem.PriceIncludesTax {
+			orderItem.TotalAmount, err = net.Add(orderItem.TaxAmount)
+			if err != nil {
+				return nil, apperr.NewError(err)
+			}
+		}
+
+		pricing.TaxTotal, err = pricing.TaxTotal.Add(orderItem.TaxAmount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		pricing.GrandTotal, err = pricing.GrandTotal.Add(orderItem.TotalAmount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+	}
+
+	return pricing, nil
+}
+
+// moneyPart pairs an amount with the figure it is worked into.
+type moneyPart struct {
+	target *custom_types.Money
+	amount custom_types.Money
+}
+
+// lineTax is the VAT on a line selling for net at rate basis points. For a tax-inclusive
+// price the tax is the part of net above net / (1 + rate); otherwise it goes on top.
+func lineTax(
+	net custom_types.Money,
+	rate int64,
+	priceIncludesTax bool,
+) custom_types.Money {
+
+	if rate == 0 {
+		return custom_types.Money{Currency: net.Currency}
+	}
+
+	if priceIncludesTax {
+		return net.MulRatio(rate, taxRateBasis+rate)
+	}
+
+	return net.MulRatio(rate, taxRateBasis)
+}
+
+func (p *orderPricing) applyTo(order *models.Order) {
+	order.Subtotal = p.Subtotal
+	order.LineDiscount = p.LineDiscount
+	order.Discount = p.OrderDiscount
+	order.TaxTotal = p.TaxTotal
+	order.TotalAmount = p.GrandTotal
+}
+
+// checkDeclaredPricing compares the prices and totals a client sent with the server's
+// pricing. The server's figures are what get charged, so any disagreement is rejected
+// rather than silently overridden.
+func checkDeclaredPricing(
+	form *dtos.CreateOrderForm,
+	orderItems []*models.OrderItem,
+	pricing *orderPricing,
+) error {
+
+	mismatches := make([]string, 0)
+
+	for i, item := range form.Items {
+		if item.UnitPrice != nil && item.UnitPrice.Amount != orderItems[i].UnitPrice.Amount {
+			mismatches = append(mismatches, fmt.Sprintf(
+				"unit price for product [%d] is %s, not %s",
+				item.ProductID,
+				orderItems[i].UnitPrice,
+				*item.UnitPrice,
+			))
+		}
+
+		if item.TotalAmount != nil && item.TotalAmount.Amount != orderItems[i].TotalAmount.Amount {
+			mismatches = append(mismatches, fmt.Sprintf(
+				"total for product [%d] is %s, not %s",
+				item.ProductID,
+				orderItems[i].TotalAmount,
+				*item.TotalAmount,
+			))
+		}
+	}
+
+	if form.TotalAmount != nil && form.TotalAmount.Amount != pricing.GrandTotal.Amount {
+		mismatches = append(mismatches, fmt.Sprintf(
+			"order total is %s, not %s",
+			pricing.GrandTotal,
+			*form.TotalAmount,
+		))
+	}
+
+	if len(mismatches) > 0 {
+		return apperr.NewBadRequest("order pricing does not match: " + strings.Join(mismatches, "; "))
+	}
+
+	return nil
+}
+
+// allocateMoney splits total over weights in proportion, using the largest remainder
+// method so the parts always add back up to total exactly.
+func allocateMoney(
+	total custom_types.Money,
+	weights []int64,
+) []custom_types.Money {
+
+	parts := make([]custom_types.Money, len(weights))
+
+	var weightSum int64
+	for i, weight := range weights {
+		parts[i] = custom_types.Money{Currency: total.Currency}
+		weightSum += weight
+	}
+
+	if weightSum == 0 || total.IsZero() {
+		return parts
+	}
+
+	remainders := make([]int64, len(weights))
+	var allocated int64
+
+	for i, weight := range weights {
+		parts[i].Amount = total.Amount * weight / weightSum
+		remainders[i] = total.Amount * weight % weightSum
+		allocated += parts[i].Amount
+	}
+
+	// hand the cents left over to the lines that lost the most to rounding down,
+	// earlier lines first on ties
+	for left := total.Amount - allocated; left > 0; left-- {
+		best := -1
+		for i := range remainders {
+			if weights[i] > 0 && (best == -1 || remainders[i] > remainders[best]) {
+				best = i
+			}
+		}
+		parts[best].Amount++
+		remainders[best] = -1
+	}
+
+	return parts
+}
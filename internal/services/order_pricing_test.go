@@ -0,0 +1,207 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+func pricingItem(productID int64, unitPrice, quantity, discount int64) *models.OrderItem {
+	return &models.OrderItem{
+		ProductID: productID,
+		UnitPrice: custom_types.NewMoney(unitPrice),
+		Quantity:  quantity,
+		Discount:  custom_types.NewMoney(discount),
+	}
+}
+
+func TestPriceOrder_Components(t *testing.T) {
+	orderItems := []*models.OrderItem{
+		pricingItem(1, 10000, 2, 1000), // 200.00 less 10.00
+		pricingItem(2, 5000, 1, 0),     // 50.00
+	}
+
+	pricing, err := priceOrder(orderItems, custom_types.NewMoney(2400))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25000), pricing.Subtotal.Amount)
+	assert.Equal(t, int64(1000), pricing.LineDiscount.Amount)
+	assert.Equal(t, int64(2400), pricing.OrderDiscount.Amount)
+	assert.Equal(t, int64(0), pricing.TaxTotal.Amount)
+	assert.Equal(t, int64(21600), pricing.GrandTotal.Amount)
+
+	// the order discount is shared 190:50 over the discounted lines
+	assert.Equal(t, int64(1900), orderItems[0].OrderDiscount.Amount)
+	assert.Equal(t, int64(500), orderItems[1].OrderDiscount.Amount)
+	assert.Equal(t, int64(17100), orderItems[0].TotalAmount.Amount)
+	assert.Equal(t, int64(4500), orderItems[1].TotalAmount.Amount)
+}
+
+func TestPriceOrder_OrderDiscountSharesAddUp(t *testing.T) {
+	orderItems := []*models.OrderItem{
+		pricingItem(1, 100, 1, 0),
+		pricingItem(2, 100, 1, 0),
+		pricingItem(3, 100, 1, 0),
+	}
+
+	pricing, err := priceOrder(orderItems, custom_types.NewMoney(100))
+
+	assert.NoError(t, err)
+
+	var shared int64
+	for _, orderItem := range orderItems {
+		shared += orderItem.OrderDiscount.Amount
+	}
+	assert.Equal(t, int64(100), shared)
+	assert.Equal(t, int64(200), pricing.GrandTotal.Amount)
+}
+
+func TestPriceOrder_RejectsBadDiscounts(t *testing.T) {
+	tests := []struct {
+		name          string
+		orderItems    []*models.OrderItem
+		orderDiscount int64
+	}{
+		{"line discount above line amount", []*models.OrderItem{pricingItem(1, 1000, 1, 1001)}, 0},
+		{"negative line discount", []*models.OrderItem{pricingItem(1, 1000, 1, -1)}, 0},
+		{"order discount above discounted subtotal", []*models.OrderItem{pricingItem(1, 1000, 1, 500)}, 501},
+		{"negative order discount", []*models.OrderItem{pricingItem(1, 1000, 1, 0)}, -1},
+		{"zero quantity", []*models.OrderItem{pricingItem(1, 1000, 0, 0)}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := priceOrder(tt.orderItems, custom_types.NewMoney(tt.orderDiscount))
+
+			assert.Error(t, err)
+			assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+		})
+	}
+}
+
+func TestPriceOrder_MixedCurrenciesFail(t *testing.T) {
+	orderItem := pricingItem(1, 1000, 1, 100)
+	orderItem.UnitPrice.Currency = "USD"
+
+	_, err := priceOrder([]*models.OrderItem{orderItem}, custom_types.NewMoney(0))
+
+	assert.ErrorContains(t, err, "cannot combine USD with KES")
+	assert.Equal(t, apperr.Internal, apperr.NewError(err).Type)
+}
+
+func taxedItem(productID int64, unitPrice, quantity, rate int64, priceIncludesTax bool) *models.OrderItem {
+	orderItem := pricingItem(productID, unitPrice, quantity, 0)
+	orderItem.TaxRate = rate
+	orderItem.PriceIncludesTax = priceIncludesTax
+	return orderItem
+}
+
+func TestPriceOrder_Tax(t *testing.T) {
+	orderItems := []*models.OrderItem{
+		taxedItem(1, 11600, 1, 1600, true),  // 116.00 with VAT in it
+		taxedItem(2, 10000, 1, 1600, false), // 100.00 plus VAT
+		taxedItem(3, 5000, 2, 0, true),      // exempt or zero rated
+		taxedItem(4, 9999, 1, 1600, true),   // VAT of 13.7917 rounds to 13.79
+	}
+
+	pricing, err := priceOrder(orderItems, custom_types.NewMoney(0))
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(1600), orderItems[0].TaxAmount.Amount)
+	assert.Equal(t, int64(11600), orderItems[0].TotalAmount.Amount)
+
+	assert.Equal(t, int64(1600), orderItems[1].TaxAmount.Amount)
+	assert.Equal(t, int64(11600), orderItems[1].TotalAmount.Amount)
+
+	assert.Equal(t, int64(0), orderItems[2].TaxAmount.Amount)
+	assert.Equal(t, int64(10000), orderItems[2].TotalAmount.Amount)
+
+	assert.Equal(t, int64(1379), orderItems[3].TaxAmount.Amount)
+	assert.Equal(t, int64(9999), orderItems[3].TotalAmount.Amount)
+
+	// only the exclusive line's VAT is added to what the customer pays
+	assert.Equal(t, int64(41599), pricing.Subtotal.Amount)
+	assert.Equal(t, int64(4579), pricing.TaxTotal.Amount)
+	assert.Equal(t, int64(43199), pricing.GrandTotal.Amount)
+}
+
+func TestPriceOrder_TaxAfterDiscounts(t *testing.T) {
+	orderItems := []*models.OrderItem{
+		taxedItem(1, 11600, 1, 1600, true),
+		taxedItem(2, 11600, 1, 1600, false),
+	}
+	orderItems[1].Discount = custom_types.NewMoney(1600)
+
+	// the order discount is shared 11600:10000 over the lines
+	pricing, err := priceOrder(orderItems, custom_types.NewMoney(2160))
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(1160), orderItems[0].OrderDiscount.Amount)
+	assert.Equal(t, int64(1440), orderItems[0].TaxAmount.Amount)
+	assert.Equal(t, int64(10440), orderItems[0].TotalAmount.Amount)
+
+	assert.Equal(t, int64(1000), orderItems[1].OrderDiscount.Amount)
+	assert.Equal(t, int64(1440), orderItems[1].TaxAmount.Amount)
+	assert.Equal(t, int64(10440), orderItems[1].TotalAmount.Amount)
+
+	assert.Equal(t, int64(2880), pricing.TaxTotal.Amount)
+	assert.Equal(t, int64(20880), pricing.GrandTotal.Amount)
+}
+
+func TestCheckDeclaredPricing(t *testing.T) {
+	orderItems := []*models.OrderItem{pricingItem(1, 10000, 2, 0)}
+	pricing, err := priceOrder(orderItems, custom_types.NewMoney(0))
+	assert.NoError(t, err)
+
+	agreeing := &dtos.CreateOrderForm{
+		Items: []dtos.OrderItemForm{{
+			ProductID:   1,
+			Quantity:    2,
+			UnitPrice:   moneyPtr(10000),
+			TotalAmount: moneyPtr(20000),
+		}},
+		TotalAmount: moneyPtr(20000),
+	}
+	assert.NoError(t, checkDeclaredPricing(agreeing, orderItems, pricing))
+
+	undeclared := &dtos.CreateOrderForm{Items: []dtos.OrderItemForm{{ProductID: 1, Quantity: 2}}}
+	assert.NoError(t, checkDeclaredPricing(undeclared, orderItems, pricing))
+
+	cheaper := &dtos.CreateOrderForm{
+		Items:       []dtos.OrderItemForm{{ProductID: 1, Quantity: 2, UnitPrice: moneyPtr(9000)}},
+		TotalAmount: moneyPtr(18000),
+	}
+	err = checkDeclaredPricing(cheaper, orderItems, pricing)
+	assert.Error(t, err)
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+	assert.Contains(t, err.Error(), "unit price for product [1]")
+	assert.Contains(t, err.Error(), "order total is KES 200.00, not KES 180.00")
+}
+
+func TestReturnedShares_AddUpOverPartialReturns(t *testing.T) {
+	orderItem := pricingItem(1, 3333, 3, 0)
+	orderItem.OrderDiscount = custom_types.NewMoney(1000)
+	orderItem.TotalAmount = custom_types.NewMoney(8999)
+
+	var refunded, discount int64
+	for i := 0; i < 3; i++ {
+		shares, err := returnedShares(orderItem, 1)
+		assert.NoError(t, err)
+		refunded += shares.GrandTotal.Amount
+		discount += shares.OrderDiscount.Amount
+		orderItem.ReturnedQuantity++
+	}
+
+	assert.Equal(t, int64(8999), refunded)
+	assert.Equal(t, int64(1000), discount)
+}
+
+func moneyPtr(minorUnits int64) *custom_types.Money {
+	money := custom_types.NewMoney(minorUnits)
+	return &money
+}
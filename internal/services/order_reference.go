@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+)
+
+// DefaultOrderReferencePrefix is used for shops without a prefix of their own when no
+// other default is configured.
+const DefaultOrderReferencePrefix = "SV"
+
+type (
+	OrderReferenceGenerator interface {
+		NextOrderReference(ctx context.Context, operations db.SQLOperations, shopID string) (string, error)
+	}
+
+	orderReferenceGenerator struct {
+		store         *domain.Store
+		defaultPrefix string
+	}
+)
+
+func NewOrderReferenceGenerator(
+	store *domain.Store,
+	defaultPrefix string,
+) OrderReferenceGenerator {
+
+	if defaultPrefix == "" {
+		defaultPrefix = DefaultOrderReferencePrefix
+	}
+
+	return &orderReferenceGenerator{
+		store:         store,
+		defaultPrefix: defaultPrefix,
+	}
+}
+
+// NextOrderReference builds a reference from the shared order reference sequence, so
+// references are unique across shops and replicas; the prefix, set on the shop, only
+// tells shops apart.
+func (g *orderReferenceGenerator) NextOrderReference(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+) (string, error) {
+
+	sequence, err := g.store.OrderDomain.NextOrderReferenceSequence(ctx, operations)
+	if err != nil {
+		return "", err
+	}
+
+	shop, err := g.store.ShopDomain.ShopByID(ctx, operations, shopID)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := shop.OrderReferencePrefix
+	if prefix == "" {
+		prefix = g.defaultPrefix
+	}
+
+	return utils.FormatOrderReference(prefix, sequence), nil
+}
@@ -0,0 +1,103 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+)
+
+// sharedSequence stands in for the database sequence every replica draws from.
+type sharedSequence struct {
+	*MockOrderDomain
+	last atomic.Int64
+}
+
+func (s *sharedSequence) NextOrderReferenceSequence(context.Context, db.SQLOperations) (int64, error) {
+	return s.last.Add(1), nil
+}
+
+func TestNextOrderReference_UsesShopPrefix(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{OrderDomain: mockOrders, ShopDomain: mockShops}
+	generator := services.NewOrderReferenceGenerator(store, "POS")
+
+	mockOrders.On("NextOrderReferenceSequence", ctx, mock.Anything).
+		Return(int64(1), nil).Once()
+	mockOrders.On("NextOrderReferenceSequence", ctx, mock.Anything).
+		Return(int64(2), nil).Once()
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-nbo").
+		Return(&models.Shop{ID: "shop-nbo", Name: "Nairobi", OrderReferencePrefix: "NBO"}, nil)
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-other").
+		Return(&models.Shop{ID: "shop-other", Name: "Other"}, nil)
+
+	nairobi, err := generator.NextOrderReference(ctx, nil, "shop-nbo")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(nairobi, "NBO-"))
+
+	other, err := generator.NextOrderReference(ctx, nil, "shop-other")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(other, "POS-"))
+
+	mockOrders.AssertExpectations(t)
+}
+
+func TestNextOrderReference_UniqueUnderConcurrentLoad(t *testing.T) {
+	const workers, perWorker = 32, 250
+
+	ctx := context.Background()
+	sequence := &sharedSequence{MockOrderDomain: new(MockOrderDomain)}
+	mockShops := new(MockShopDomain)
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-nbo").
+		Return(&models.Shop{ID: "shop-nbo", OrderReferencePrefix: "NBO"}, nil)
+	mockShops.On("ShopByID", ctx, mock.Anything, "shop-other").
+		Return(&models.Shop{ID: "shop-other"}, nil)
+
+	// Two generators, as two replicas of the API would have, sharing one sequence.
+	replicas := []services.OrderReferenceGenerator{
+		services.NewOrderReferenceGenerator(&domain.Store{OrderDomain: sequence, ShopDomain: mockShops}, ""),
+		services.NewOrderReferenceGenerator(&domain.Store{OrderDomain: sequence, ShopDomain: mockShops}, ""),
+	}
+	shops := []string{"shop-nbo", "shop-other"}
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool, workers*perWorker)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			generator := replicas[w%len(replicas)]
+			for i := 0; i < perWorker; i++ {
+				ref, err := generator.NextOrderReference(ctx, nil, shops[i%len(shops)])
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.True(t, utils.ValidOrderReference(ref), ref)
+				assert.LessOrEqual(t, len(ref), 12, ref)
+
+				mu.Lock()
+				assert.False(t, seen[ref], "duplicate reference %s", ref)
+				seen[ref] = true
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, workers*perWorker)
+}
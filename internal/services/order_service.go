@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"github/Doris-Mwito5/savannah-pos/internal/apperr"
 	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
@@ -9,63 +10,111 @@ import (
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
 	"github/Doris-Mwito5/savannah-pos/internal/loggers"
 	"github/Doris-Mwito5/savannah-pos/internal/models"
-	"github/Doris-Mwito5/savannah-pos/internal/notification"
 	"github/Doris-Mwito5/savannah-pos/internal/null"
 	"github/Doris-Mwito5/savannah-pos/internal/utils"
 )
 
 type (
 	OrderService interface {
-		CreateOrder(ctx context.Context, dB db.DB, form *dtos.CreateOrderForm) (*models.Order, error)
+		CreateOrder(ctx context.Context, dB db.DB, shopID string, form *dtos.CreateOrderForm, actor string) (*models.Order, error)
+		UpdateOrder(ctx context.Context, dB db.DB, shopID string, orderID int64, form *dtos.UpdateOrderForm, actor string) (*models.Order, error)
 		ListShopOrders(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.OrderList, error)
-		OrderByID(ctx context.Context, dB db.DB, orderID int64) (*models.Order, error)
+		OrderByID(ctx context.Context, dB db.DB, shopID string, orderID int64) (*models.Order, error)
+		ListOrderStatusTransitions(ctx context.Context, dB db.DB, shopID string, orderID int64, filter *models.Filter) (*models.OrderStatusTransitionList, error)
 	}
 
 	orderService struct {
-		customerService   CustomerService
-		store             *domain.Store
-		orderNotification notification.OrderNotification
+		customerService           CustomerService
+		stockService              StockService
+		promotionService          PromotionService
+		orderReferenceGenerator   OrderReferenceGenerator
+		store                     *domain.Store
+		notificationOutboxService NotificationOutboxService
 	}
 )
 
 func NewOrderService(
 	customerService CustomerService,
+	stockService StockService,
+	promotionService PromotionService,
+	orderReferenceGenerator OrderReferenceGenerator,
 	store *domain.Store,
-	orderNotification notification.OrderNotification,
+	notificationOutboxService NotificationOutboxService,
 ) OrderService {
 	return &orderService{
-		customerService:   customerService,
-		store:             store,
-		orderNotification: orderNotification,
+		customerService:           customerService,
+		stockService:              stockService,
+		promotionService:          promotionService,
+		orderReferenceGenerator:   orderReferenceGenerator,
+		store:                     store,
+		notificationOutboxService: notificationOutboxService,
 	}
 }
 
 func (s *orderService) CreateOrder(
     ctx context.Context,
     dB db.DB,
+    shopID string,
     form *dtos.CreateOrderForm,
+    actor string,
 ) (*models.Order, error) {
 
+    err := checkFormShop(shopID, form.ShopID)
+    if err != nil {
+        return nil, err
+    }
+
+    orderStatus := form.OrderStatus
+    if orderStatus == "" {
+        orderStatus = custom_types.OrderStatusPending
+    }
+
+    // orders start out pending, or paid when settled at the till
+    if orderStatus != custom_types.OrderStatusPending && orderStatus != custom_types.OrderStatusPaid {
+        return nil, apperr.NewBadRequest(fmt.Sprintf("order cannot be created with status [%s]", orderStatus))
+    }
+
+    if orderStatus == custom_types.OrderStatusPaid && !form.PaymentMethod.IsValid() {
+        return nil, apperr.NewBadRequest(fmt.Sprintf("an order settled at the till needs a valid payment method, got [%s]", form.PaymentMethod))
+    }
+
+    if form.ReferenceNumber != "" {
+        return nil, apperr.NewBadRequest("order reference numbers are assigned by the server")
+    }
+
     order := &models.Order{
-        ReferenceNumber: utils.GenerateTransactionRef(),
-        OrderStatus:     custom_types.OrderStatus(form.OrderStatus),
+        OrderStatus:     orderStatus,
         OrderMedium:     custom_types.OrderMedium(form.OrderMedium),
         PaymentMethod:   custom_types.PaymentMethod(form.PaymentMethod),
         TotalItems:      len(form.Items),
-        ShopID:          form.ShopID,
+        ShopID:          shopID,
         PhoneNumber:     form.PhoneNumber,
     }
 
     var orderItems []*models.OrderItem
-    var totalOrderCost float64
-
-    err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
-        
-        // check for an existing customer by email and phone number using the transaction's operations
-        customer, err := s.store.CustomerDomain.CustomerByEmailAndPhoneNumber(ctx, operations, form.CustomerEmail, form.PhoneNumber)
-        
-        if err == nil {
-            // Customer exists, link the order to the existing customer ID
+
+    err = dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+        var err error
+        order.ReferenceNumber, err = s.orderReferenceGenerator.NextOrderReference(ctx, operations, order.ShopID)
+        if err != nil {
+            loggers.Errorf("failed to generate order reference: [%+v]", err)
+            return err
+        }
+
+        if form.CustomerID != nil {
+            // the till picked a known customer, who has to belong to this shop
+            customer, err := s.store.CustomerDomain.CustomerByID(ctx, operations, order.ShopID, null.ValueFromNull(form.CustomerID))
+            if err != nil {
+                if apperr.IsNoRowsErr(err) {
+                    return apperr.NewBadRequest(fmt.Sprintf("customer [%d] does not exist", null.ValueFromNull(form.CustomerID)))
+                }
+                return err
+            }
+
+            order.CustomerID = null.NullValue(customer.ID)
+        } else if customer, err := s.store.CustomerDomain.CustomerByEmailAndPhoneNumber(ctx, operations, order.ShopID, form.CustomerEmail, form.PhoneNumber); err == nil {
+            // Customer exists with this email and phone number, link the order to the existing customer ID
             order.CustomerID = null.NullValue(customer.ID)
         } else if apperr.IsNoRowsErr(err) {
             // Customer does not exist, so we create a new one
@@ -73,11 +122,10 @@ func (s *orderService) CreateOrder(
                 Name:         form.CustomerName,
                 Email:        form.CustomerEmail,
                 PhoneNumber:  form.PhoneNumber,
-                ShopID:       form.ShopID,
                 CustomerType: "individual",
             }
             
-            createdCustomer, err := s.customerService.CreateCustomer(ctx, dB, formCustomer)
+            createdCustomer, err := s.customerService.CreateCustomer(ctx, dB, order.ShopID, formCustomer)
             if err != nil {
                 loggers.Errorf("failed to create new customer: [%+v]", err)
                 return err
@@ -89,17 +137,35 @@ func (s *orderService) CreateOrder(
             return err
         }
     
-        //alculate order items and total costc
-        orderItems, totalOrderCost, err = s.getPriceAndOrderItems(ctx, operations, form)
+        // price the order on the server; whatever the client declared has to agree
+        var products map[int64]*models.Product
+        orderItems, products, err = s.getOrderItems(ctx, operations, order.ShopID, form)
         if err != nil {
-            loggers.Errorf("failed to get price and order items: [%+v]", err)
+            loggers.Errorf("failed to get order items: [%+v]", err)
             return err
         }
-    
-        order.TotalAmount = totalOrderCost
+
+        err = s.promotionService.ApplyPromotions(ctx, operations, order.ShopID, form.CouponCode, orderItems, products)
+        if err != nil {
+            return err
+        }
+
+        orderDiscount := custom_types.NewMoney(0)
         if form.Discount != nil {
-            order.Discount = null.NullValue(totalOrderCost - null.ValueFromNull(form.Discount))
+            orderDiscount = *form.Discount
         }
+
+        pricing, err := priceOrder(orderItems, orderDiscount)
+        if err != nil {
+            return err
+        }
+
+        err = checkDeclaredPricing(form, orderItems, pricing)
+        if err != nil {
+            return err
+        }
+
+        pricing.applyTo(order)
     
         // Save the order to the database
         err = s.store.OrderDomain.CreateOrder(ctx, operations, order)
@@ -107,6 +173,56 @@ func (s *orderService) CreateOrder(
             loggers.Errorf("failed to save order: [%+v]", err)
             return err
         }
+
+        err = s.store.OrderStatusTransitionDomain.CreateOrderStatusTransition(ctx, operations, &models.OrderStatusTransition{
+            OrderID:   order.ID,
+            ToStatus:  order.OrderStatus,
+            ChangedBy: actor,
+        })
+        if err != nil {
+            loggers.Errorf("failed to record order status: [%+v]", err)
+            return err
+        }
+
+        // an order settled at the till was paid in full by its one payment method
+        if order.OrderStatus == custom_types.OrderStatusPaid && order.TotalAmount.Amount > 0 {
+            err = s.store.PaymentDomain.CreatePayment(ctx, operations, &models.Payment{
+                OrderID:        order.ID,
+                Amount:         order.TotalAmount,
+                AmountTendered: order.TotalAmount,
+                ChangeDue:      custom_types.NewMoney(0),
+                PaymentMethod:  order.PaymentMethod,
+                PaymentStatus:  custom_types.PaymentStatusCompleted,
+                RecordedBy:     actor,
+            })
+            if err != nil {
+                loggers.Errorf("failed to record order payment: [%+v]", err)
+                return err
+            }
+        }
+
+        // take the sold goods out of stock; services carry no stock
+        saleMovements := make([]*models.StockMovement, 0, len(orderItems))
+        for _, orderItem := range orderItems {
+            if products[orderItem.ProductID].ProductType == custom_types.ProductTypeService {
+                continue
+            }
+
+            saleMovements = append(saleMovements, &models.StockMovement{
+                ProductID:    orderItem.ProductID,
+                MovementType: custom_types.StockMovementTypeSale,
+                Quantity:     custom_types.StockMovementTypeSale.SignedQuantity(orderItem.Quantity),
+                Reason:       fmt.Sprintf("sold on order %s", order.ReferenceNumber),
+                Actor:        actor,
+                OrderID:      null.NullValue(order.ID),
+            })
+        }
+
+        err = s.stockService.ApplyStockMovements(ctx, operations, order.ShopID, saleMovements)
+        if err != nil {
+            loggers.Errorf("failed to take order items out of stock: [%+v]", err)
+            return err
+        }
     
         //Save the order's items in batches
         if len(orderItems) > 0 {
@@ -122,25 +238,156 @@ func (s *orderService) CreateOrder(
                 }
             }
         }
-    
-        return nil
+
+        err = s.promotionService.RecordPromotions(ctx, operations, order, orderItems)
+        if err != nil {
+            loggers.Errorf("failed to record order promotions: [%+v]", err)
+            return err
+        }
+
+        order.Items = orderItems
+
+        // the confirmation is committed with the order and sent by the notification worker
+        return s.notificationOutboxService.EnqueueOrderConfirmation(ctx, operations, order)
     })
 
     if err != nil {
         return nil, err
     }
 
-    // after the successful transaction, send the SMS notification
-    if order.PhoneNumber != "" {
-        err = s.orderNotification.SendOrderNotifications(order)
-        if err != nil {
-            loggers.Errorf("failed to send order confirmation SMS: [%+v]", err)
-        }
-    }
-
     return order, nil
 }
 
+func (s *orderService) UpdateOrder(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+	form *dtos.UpdateOrderForm,
+	actor string,
+) (*models.Order, error) {
+
+	var order *models.Order
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		var err error
+		order, err = s.store.OrderDomain.LockOrderByID(ctx, operations, shopID, orderID)
+		if err != nil {
+			return err
+		}
+
+		detailsChanged := form.PhoneNumber != nil || form.OrderMedium != nil || form.PaymentMethod != nil
+
+		// order details are only editable before the order is settled
+		if detailsChanged && order.OrderStatus != custom_types.OrderStatusPending {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is [%s] and can no longer be edited", order.ID, order.OrderStatus),
+				apperr.Conflict,
+			)
+		}
+
+		if form.PhoneNumber != nil {
+			order.PhoneNumber = null.ValueFromNull(form.PhoneNumber)
+		}
+
+		if form.OrderMedium != nil {
+			order.OrderMedium = custom_types.OrderMedium(null.ValueFromNull(form.OrderMedium))
+		}
+
+		if form.PaymentMethod != nil {
+			order.PaymentMethod = custom_types.PaymentMethod(null.ValueFromNull(form.PaymentMethod))
+		}
+
+		if form.OrderStatus != nil && custom_types.OrderStatus(null.ValueFromNull(form.OrderStatus)) == custom_types.OrderStatusPaid && order.OrderStatus != custom_types.OrderStatusPaid {
+			// an order is paid once its payments cover the total, not on request
+			return apperr.NewBadRequest(fmt.Sprintf("order [%d] is marked paid by recording its payments", order.ID))
+		}
+
+		if form.OrderStatus != nil && custom_types.OrderStatus(null.ValueFromNull(form.OrderStatus)) == custom_types.OrderStatusReturned && order.OrderStatus != custom_types.OrderStatusReturned {
+			// a return restocks the goods and records the refund, which a status change cannot
+			return apperr.NewBadRequest(fmt.Sprintf("order [%d] is returned by recording its return at POST /orders/%d/returns", order.ID, order.ID))
+		}
+
+		if form.OrderStatus != nil && custom_types.OrderStatus(null.ValueFromNull(form.OrderStatus)) != order.OrderStatus {
+			err = transitionOrderStatus(
+				ctx,
+				operations,
+				s.store,
+				order,
+				custom_types.OrderStatus(null.ValueFromNull(form.OrderStatus)),
+				actor,
+				form.Reason,
+			)
+			if err != nil {
+				return err
+			}
+
+			if order.OrderStatus != custom_types.OrderStatusCancelled {
+				return nil
+			}
+
+			err = s.restockCancelledOrder(ctx, operations, order, actor)
+			if err != nil {
+				return err
+			}
+
+			return s.promotionService.ReleasePromotions(ctx, operations, order)
+		}
+
+		if !detailsChanged {
+			return nil
+		}
+
+		return s.store.OrderDomain.CreateOrder(ctx, operations, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// restockCancelledOrder puts back the goods a cancelled order took out of stock when it
+// was created.
+func (s *orderService) restockCancelledOrder(
+	ctx context.Context,
+	operations db.SQLOperations,
+	order *models.Order,
+	actor string,
+) error {
+
+	orderItems, err := s.store.OrderItemDomain.LockOrderItems(ctx, operations, order.ShopID, order.ID)
+	if err != nil {
+		return err
+	}
+
+	restockMovements := make([]*models.StockMovement, 0, len(orderItems))
+
+	for _, orderItem := range orderItems {
+		product, err := s.store.ProductDomain.ProductByID(ctx, operations, order.ShopID, orderItem.ProductID)
+		if err != nil {
+			return err
+		}
+
+		// services carry no stock, so there is nothing to put back
+		if product.ProductType == custom_types.ProductTypeService {
+			continue
+		}
+
+		restockMovements = append(restockMovements, &models.StockMovement{
+			ProductID:    orderItem.ProductID,
+			MovementType: custom_types.StockMovementTypeReturn,
+			Quantity:     custom_types.StockMovementTypeReturn.SignedQuantity(orderItem.Quantity - orderItem.ReturnedQuantity),
+			Reason:       fmt.Sprintf("order %s cancelled", order.ReferenceNumber),
+			Actor:        actor,
+			OrderID:      null.NullValue(order.ID),
+		})
+	}
+
+	return s.stockService.ApplyStockMovements(ctx, operations, order.ShopID, restockMovements)
+}
+
 func (s *orderService) ListShopOrders(
 	ctx context.Context,
 	dB db.DB,
@@ -173,49 +420,148 @@ func (s *orderService) ListShopOrders(
 func (s *orderService) OrderByID(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	orderID int64,
 ) (*models.Order, error) {
-	return s.store.OrderDomain.OrderByID(ctx, dB, orderID)
+
+	order, err := s.store.OrderDomain.OrderByID(ctx, dB, shopID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Items, err = s.store.OrderItemDomain.OrderItems(ctx, dB, shopID, order.ID, &models.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	orderItemPromotions, err := s.store.PromotionDomain.OrderItemPromotions(ctx, dB, shopID, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	orderItemsByID := make(map[int64]*models.OrderItem, len(order.Items))
+	for _, orderItem := range order.Items {
+		orderItemsByID[orderItem.ID] = orderItem
+	}
+
+	for _, orderItemPromotion := range orderItemPromotions {
+		if orderItem, ok := orderItemsByID[orderItemPromotion.OrderItemID]; ok {
+			orderItem.Promotions = append(orderItem.Promotions, orderItemPromotion)
+		}
+	}
+
+	return order, nil
 }
 
-func (s *orderService) getPriceAndOrderItems(
+func (s *orderService) ListOrderStatusTransitions(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+	filter *models.Filter,
+) (*models.OrderStatusTransitionList, error) {
+
+	_, err := s.store.OrderDomain.OrderByID(ctx, dB, shopID, orderID)
+	if err != nil {
+		return &models.OrderStatusTransitionList{}, err
+	}
+
+	transitions, err := s.store.OrderStatusTransitionDomain.ListOrderStatusTransitions(ctx, dB, shopID, orderID, filter)
+	if err != nil {
+		return &models.OrderStatusTransitionList{}, err
+	}
+
+	count, err := s.store.OrderStatusTransitionDomain.OrderStatusTransitionsCount(ctx, dB, shopID, orderID, filter)
+	if err != nil {
+		return &models.OrderStatusTransitionList{}, err
+	}
+
+	transitionList := &models.OrderStatusTransitionList{
+		Transitions: transitions,
+		Pagination: models.NewPagination(
+			count,
+			filter.Page,
+			filter.Per,
+		),
+	}
+
+	return transitionList, nil
+}
+
+// getOrderItems builds the order lines at the products' current retail prices and tax
+// classes and returns the products they refer to. Pricing the lines is left to priceOrder.
+func (s *orderService) getOrderItems(
 	ctx context.Context,
 	operations db.SQLOperations,
+	shopID string,
 	form *dtos.CreateOrderForm,
-) ([]*models.OrderItem, float64, error) {
+) ([]*models.OrderItem, map[int64]*models.Product, error) {
+
+	if len(form.Items) == 0 {
+		return nil, nil, apperr.NewBadRequest("an order needs at least one item")
+	}
 
-	var totalPrice float64
 	orderItems := make([]*models.OrderItem, 0, len(form.Items))
+	products := make(map[int64]*models.Product, len(form.Items))
+	taxClasses := make(map[int64]*models.TaxClass, len(form.Items))
 
 	for _, item := range form.Items {
-		product, err := s.store.ProductDomain.ProductByID(ctx, operations, item.ProductID)
-		if err != nil {
-			loggers.Errorf("failed to get product by id [%d], err: [%+v]", item.ProductID, err)
-			return nil, 0, err
+		if item.Quantity <= 0 {
+			return nil, nil, apperr.NewBadRequest(fmt.Sprintf("quantity for product [%d] must be greater than zero", item.ProductID))
 		}
 
-		// cost = retail_price * quantity
-		cost := product.RetailPrice * float64(item.Quantity)
+		product, ok := products[item.ProductID]
+		if !ok {
+			var err error
+			product, err = s.store.ProductDomain.ProductByID(ctx, operations, shopID, item.ProductID)
+			if err != nil {
+				if apperr.IsNoRowsErr(err) {
+					return nil, nil, apperr.NewBadRequest(fmt.Sprintf("product [%d] does not exist", item.ProductID))
+				}
+				loggers.Errorf("failed to get product by id [%d], err: [%+v]", item.ProductID, err)
+				return nil, nil, err
+			}
+			products[item.ProductID] = product
+
+			taxClass, err := s.store.TaxClassDomain.ProductTaxClass(ctx, operations, shopID, product.ID)
+			if err != nil && !apperr.IsNoRowsErr(err) {
+				loggers.Errorf("failed to get tax class for product [%d], err: [%+v]", product.ID, err)
+				return nil, nil, err
+			}
+			// with no class anywhere and no default configured the line goes untaxed
+			taxClasses[product.ID] = taxClass
+		}
 
 		orderItem := &models.OrderItem{
-			ProductID:   product.ID,
-			Quantity:    item.Quantity,
-			UnitPrice:   product.RetailPrice,
-			TotalAmount: cost,
+			ProductID:        product.ID,
+			Quantity:         item.Quantity,
+			UnitPrice:        product.RetailPrice,
+			PriceIncludesTax: product.PriceIncludesTax,
+		}
+
+		if taxClass := taxClasses[product.ID]; taxClass != nil {
+			orderItem.TaxClassID = null.NullValue(taxClass.ID)
+			orderItem.TaxRate = taxClass.Rate
+		}
+
+		lineDiscount := custom_types.NewMoney(0)
+		if item.Discount != nil {
+			lineDiscount = *item.Discount
 		}
 
+		orderItem.Discount = lineDiscount
 		orderItems = append(orderItems, orderItem)
-		totalPrice += cost
 	}
 
-	return orderItems, totalPrice, nil
+	return orderItems, products, nil
 }
 
 func (s *orderService) createOrderItemsBatches(
 	orderItems []*models.OrderItem,
 ) [][]*models.OrderItem {
 
-	batchSize := 6000
+	// 13 parameters per item; Postgres takes at most 65535 in one statement
+	batchSize := 5000
 
 	expectedBatches := utils.CreateBatches(len(orderItems), batchSize)
 
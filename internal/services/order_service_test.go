@@ -0,0 +1,135 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+// orderLineItem is a line of order 1 for product productID.
+func orderLineItem(id, productID, quantity int64) *models.OrderItem {
+	return &models.OrderItem{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: id},
+		OrderID:              1,
+		ProductID:            productID,
+		UnitPrice:            custom_types.NewMoney(10000),
+		Quantity:             quantity,
+		TotalAmount:          custom_types.NewMoney(10000).Mul(quantity),
+	}
+}
+
+func TestUpdateOrder_RejectsReturnedStatus(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	store := &domain.Store{OrderDomain: mockOrders, OrderStatusTransitionDomain: mockTransitions}
+	service := services.NewOrderService(nil, new(MockStockService), services.NewPromotionService(store), nil, store, nil)
+
+	order := pendingOrder(100000)
+	order.OrderStatus = custom_types.OrderStatusPaid
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+
+	_, err := service.UpdateOrder(ctx, &inlineDB{}, "shop-1", 1, &dtos.UpdateOrderForm{
+		OrderStatus: null.NullValue(string(custom_types.OrderStatusReturned)),
+	}, "manager@example.com")
+
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+	assert.Contains(t, err.Error(), "POST /orders/1/returns")
+	assert.Equal(t, custom_types.OrderStatusPaid, order.OrderStatus)
+	mockOrders.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+	mockTransitions.AssertNotCalled(t, "CreateOrderStatusTransition", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateOrder_CancellingRestocksAndReleasesPromotions(t *testing.T) {
+	ctx := context.Background()
+	mockStock := new(MockStockService)
+	mockOrders := new(MockOrderDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	mockOrderItems := new(MockOrderItemDomain)
+	mockProducts := new(MockProductDomain)
+	mockPromotions := new(MockPromotionDomain)
+	store := &domain.Store{
+		OrderDomain:                 mockOrders,
+		OrderStatusTransitionDomain: mockTransitions,
+		OrderItemDomain:             mockOrderItems,
+		ProductDomain:               mockProducts,
+		PromotionDomain:             mockPromotions,
+	}
+	service := services.NewOrderService(nil, mockStock, services.NewPromotionService(store), nil, store, nil)
+
+	order := pendingOrder(300000)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockTransitions.On("CreateOrderStatusTransition", ctx, mock.Anything, mock.MatchedBy(func(transition *models.OrderStatusTransition) bool {
+		return transition.ToStatus == custom_types.OrderStatusCancelled && *transition.Reason == "customer walked out"
+	})).Return(nil)
+	mockOrderItems.On("LockOrderItems", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.OrderItem{orderLineItem(1, 10, 2), orderLineItem(2, 20, 1)}, nil)
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(10)).
+		Return(goodsProduct(), nil)
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(20)).
+		Return(serviceProduct(), nil)
+	// the goods go back; the service line carries no stock
+	mockStock.On("ApplyStockMovements", ctx, mock.Anything, "shop-1", mock.MatchedBy(func(movements []*models.StockMovement) bool {
+		return len(movements) == 1 &&
+			movements[0].ProductID == 10 &&
+			movements[0].MovementType == custom_types.StockMovementTypeReturn &&
+			movements[0].Quantity == 2 &&
+			*movements[0].OrderID == 1 &&
+			movements[0].Reason == "order ORD-2025-000001 cancelled" &&
+			movements[0].Actor == "manager@example.com"
+	})).Return(nil)
+	// coupons and promotions with a usage limit get the use back
+	mockPromotions.On("ReleaseOrderPromotions", ctx, mock.Anything, int64(1)).
+		Return(nil)
+
+	cancelled, err := service.UpdateOrder(ctx, &inlineDB{}, "shop-1", 1, &dtos.UpdateOrderForm{
+		OrderStatus: null.NullValue(string(custom_types.OrderStatusCancelled)),
+		Reason:      null.NullValue("customer walked out"),
+	}, "manager@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.OrderStatusCancelled, cancelled.OrderStatus)
+	mockTransitions.AssertExpectations(t)
+	mockStock.AssertExpectations(t)
+	mockPromotions.AssertExpectations(t)
+}
+
+func TestUpdateOrder_CancelledOrdersAreFinal(t *testing.T) {
+	ctx := context.Background()
+	mockStock := new(MockStockService)
+	mockOrders := new(MockOrderDomain)
+	mockPromotions := new(MockPromotionDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PromotionDomain: mockPromotions}
+	service := services.NewOrderService(nil, mockStock, services.NewPromotionService(store), nil, store, nil)
+
+	order := pendingOrder(300000)
+	order.OrderStatus = custom_types.OrderStatusCancelled
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+
+	// so it cannot be restocked twice
+	_, err := service.UpdateOrder(ctx, &inlineDB{}, "shop-1", 1, &dtos.UpdateOrderForm{
+		OrderStatus: null.NullValue(string(custom_types.OrderStatusPending)),
+	}, "manager@example.com")
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockStock.AssertNotCalled(t, "ApplyStockMovements", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockPromotions.AssertNotCalled(t, "ReleaseOrderPromotions", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+// transitionOrderStatus moves order to next, persists it and records who made the change.
+// The order should have been read with OrderDomain.LockOrderByID in the same transaction.
+func transitionOrderStatus(
+	ctx context.Context,
+	operations db.SQLOperations,
+	store *domain.Store,
+	order *models.Order,
+	next custom_types.OrderStatus,
+	actor string,
+	reason *string,
+) error {
+
+	if !next.IsValid() {
+		return apperr.NewBadRequest(fmt.Sprintf("invalid order status [%s]", next))
+	}
+
+	current := order.OrderStatus
+	if !current.CanTransitionTo(next) {
+		return apperr.NewErrorWithType(
+			fmt.Errorf("order [%d] cannot move from [%s] to [%s]", order.ID, current, next),
+			apperr.Conflict,
+		)
+	}
+
+	order.OrderStatus = next
+
+	err := store.OrderDomain.CreateOrder(ctx, operations, order)
+	if err != nil {
+		return err
+	}
+
+	return store.OrderStatusTransitionDomain.CreateOrderStatusTransition(ctx, operations, &models.OrderStatusTransition{
+		OrderID:    order.ID,
+		FromStatus: &current,
+		ToStatus:   next,
+		ChangedBy:  actor,
+		Reason:     reason,
+	})
+}
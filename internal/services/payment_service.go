@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+	"time"
+)
+
+// mpesaPromptExpiry is how long a pending M-Pesa payment holds its share of the bill.
+// Daraja's prompt times out on the phone well before this, so a payment still pending
+// afterwards lost its callback or was never prompted at all.
+const mpesaPromptExpiry = 3 * time.Minute
+
+type (
+	PaymentService interface {
+		RecordPayments(ctx context.Context, dB db.DB, shopID string, orderID int64, form *dtos.RecordPaymentsForm, actor string) (*models.OrderPayments, error)
+		ListPayments(ctx context.Context, dB db.DB, shopID string, orderID int64) (*models.OrderPayments, error)
+	}
+
+	paymentService struct {
+		store *domain.Store
+		now   func() time.Time
+	}
+)
+
+func NewPaymentService(
+	store *domain.Store,
+) PaymentService {
+	return &paymentService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// RecordPayments takes one or more tenders against a pending order. The order is marked
+// paid once its completed payments cover the grand total; until then it stays pending
+// with a balance. Tenders cannot cover what an M-Pesa prompt still waiting on the
+// customer is for.
+func (s *paymentService) RecordPayments(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+	form *dtos.RecordPaymentsForm,
+	actor string,
+) (*models.OrderPayments, error) {
+
+	var orderPayments *models.OrderPayments
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		order, err := s.store.OrderDomain.LockOrderByID(ctx, operations, shopID, orderID)
+		if err != nil {
+			return err
+		}
+
+		if order.OrderStatus != custom_types.OrderStatusPending {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is [%s]; only pending orders take payments", order.ID, order.OrderStatus),
+				apperr.Conflict,
+			)
+		}
+
+		payments, err := s.store.PaymentDomain.PaymentsByOrderID(ctx, operations, shopID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		err = releaseExpiredPrompts(ctx, operations, s.store, payments, s.now())
+		if err != nil {
+			return err
+		}
+
+		// an M-Pesa prompt still on the customer's phone may yet pay its part of the bill
+		awaited, err := amountAwaited(payments)
+		if err != nil {
+			return err
+		}
+
+		balance, err := balanceDue(order, payments)
+		if err != nil {
+			return err
+		}
+
+		balance, err = balance.Sub(awaited)
+		if err != nil {
+			return apperr.NewError(err)
+		}
+
+		if !awaited.IsZero() && balance.Amount <= 0 {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is waiting on an M-Pesa payment of %s", order.ID, awaited),
+				apperr.Conflict,
+			)
+		}
+
+		tendered, err := allocateTenders(balance, form.Tenders)
+		if err != nil {
+			return err
+		}
+
+		for _, payment := range tendered {
+			if payment.ProviderReference != nil {
+				_, err = s.store.PaymentDomain.PaymentByProviderReference(ctx, operations, payment.PaymentMethod, *payment.ProviderReference)
+				if err == nil {
+					return apperr.NewErrorWithType(
+						fmt.Errorf("%s reference [%s] has already been recorded", payment.PaymentMethod, *payment.ProviderReference),
+						apperr.Conflict,
+					)
+				}
+				if !apperr.IsNoRowsErr(err) {
+					return err
+				}
+			}
+
+			payment.OrderID = order.ID
+			payment.RecordedBy = actor
+
+			err = s.store.PaymentDomain.CreatePayment(ctx, operations, payment)
+			if err != nil {
+				return err
+			}
+		}
+
+		payments = append(payments, tendered...)
+
+		err = settleOrder(ctx, operations, s.store, order, payments, actor)
+		if err != nil {
+			return err
+		}
+
+		orderPayments, err = newOrderPayments(order, payments, tendered)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orderPayments, nil
+}
+
+func (s *paymentService) ListPayments(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+) (*models.OrderPayments, error) {
+
+	order, err := s.store.OrderDomain.OrderByID(ctx, dB, shopID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.store.PaymentDomain.PaymentsByOrderID(ctx, dB, shopID, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOrderPayments(order, payments, payments)
+}
+
+// allocateTenders turns the tenders handed over for an order into payments against the
+// balance still owed. Card and M-Pesa tenders are for exact amounts and are applied
+// first; cash makes up the rest, and cash beyond the balance is change.
+func allocateTenders(
+	balance custom_types.Money,
+	tenders []dtos.TenderForm,
+) ([]*models.Payment, error) {
+
+	if len(tenders) < 1 {
+		return nil, apperr.NewBadRequest("at least one tender is required")
+	}
+
+	payments := make([]*models.Payment, len(tenders))
+
+	for i, tender := range tenders {
+		if !tender.PaymentMethod.IsValid() {
+			return nil, apperr.NewBadRequest(fmt.Sprintf("invalid payment method [%s]", tender.PaymentMethod))
+		}
+
+		if tender.Amount.IsNegative() || tender.Amount.IsZero() {
+			return nil, apperr.NewBadRequest(fmt.Sprintf("%s tender must be more than zero", tender.PaymentMethod))
+		}
+
+		var providerReference *string
+		if tender.ProviderReference != nil && strings.TrimSpace(*tender.ProviderReference) != "" {
+			providerReference = null.NullValue(strings.TrimSpace(*tender.ProviderReference))
+		}
+
+		if tender.PaymentMethod == custom_types.PaymentMethodMpesa && providerReference == nil {
+			return nil, apperr.NewBadRequest("an M-Pesa tender needs its receipt number as the provider reference")
+		}
+
+		payments[i] = &models.Payment{
+			AmountTendered:    tender.Amount,
+			PaymentMethod:     tender.PaymentMethod,
+			ProviderReference: providerReference,
+			PaymentStatus:     custom_types.PaymentStatusCompleted,
+		}
+	}
+
+	remaining := balance
+
+	for _, cash := range []bool{false, true} {
+		for _, payment := range payments {
+			if (payment.PaymentMethod == custom_types.PaymentMethodCash) != cash {
+				continue
+			}
+
+			if remaining.Amount <= 0 {
+				return nil, apperr.NewBadRequest(fmt.Sprintf("the order is already covered; the %s tender of %s is not needed", payment.PaymentMethod, payment.AmountTendered))
+			}
+
+			if !cash && payment.AmountTendered.Amount > remaining.Amount {
+				return nil, apperr.NewBadRequest(fmt.Sprintf("%s tender of %s is more than the %s outstanding", payment.PaymentMethod, payment.AmountTendered, remaining))
+			}
+
+			payment.Amount = payment.AmountTendered
+			if payment.AmountTendered.Amount > remaining.Amount {
+				payment.Amount = remaining
+			}
+
+			var err error
+			payment.ChangeDue, err = payment.AmountTendered.Sub(payment.Amount)
+			if err != nil {
+				return nil, apperr.NewError(err)
+			}
+
+			remaining, err = remaining.Sub(payment.Amount)
+			if err != nil {
+				return nil, apperr.NewError(err)
+			}
+		}
+	}
+
+	return payments, nil
+}
+
+// settleOrder marks a pending order paid once its completed payments cover the grand total.
+func settleOrder(
+	ctx context.Context,
+	operations db.SQLOperations,
+	store *domain.Store,
+	order *models.Order,
+	payments []*models.Payment,
+	actor string,
+) error {
+
+	if order.OrderStatus != custom_types.OrderStatusPending {
+		return nil
+	}
+
+	balance, err := balanceDue(order, payments)
+	if err != nil {
+		return err
+	}
+
+	if balance.Amount > 0 {
+		return nil
+	}
+
+	// payment_method stays a single column for refunds and reports, so it keeps the
+	// tender that covered most of the bill
+	order.PaymentMethod = primaryPaymentMethod(payments, order.PaymentMethod)
+
+	return transitionOrderStatus(
+		ctx,
+		operations,
+		store,
+		order,
+		custom_types.OrderStatusPaid,
+		actor,
+		null.NullValue(fmt.Sprintf("paid in full by %d payment(s)", completedPayments(payments))),
+	)
+}
+
+func amountPaid(payments []*models.Payment) (custom_types.Money, error) {
+	return sumPayments(payments, custom_types.PaymentStatusCompleted)
+}
+
+// balanceDue is what is still owed on the order after its completed payments; it is
+// negative when the order has been overpaid.
+func balanceDue(order *models.Order, payments []*models.Payment) (custom_types.Money, error) {
+	paid, err := amountPaid(payments)
+	if err != nil {
+		return custom_types.Money{}, err
+	}
+
+	balance, err := order.TotalAmount.Sub(paid)
+	if err != nil {
+		return custom_types.Money{}, apperr.NewError(err)
+	}
+
+	return balance, nil
+}
+
+// releaseExpiredPrompts fails the pending M-Pesa payments older than mpesaPromptExpiry,
+// so a lost callback does not hold the order up. A callback that does arrive later still
+// records what the customer paid.
+func releaseExpiredPrompts(
+	ctx context.Context,
+	operations db.SQLOperations,
+	store *domain.Store,
+	payments []*models.Payment,
+	now time.Time,
+) error {
+
+	for _, payment := range payments {
+		if payment.PaymentStatus != custom_types.PaymentStatusPending || now.Sub(payment.CreatedAt) < mpesaPromptExpiry {
+			continue
+		}
+
+		payment.PaymentStatus = custom_types.PaymentStatusFailed
+
+		err := store.PaymentDomain.CreatePayment(ctx, operations, payment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// amountAwaited is what the order's M-Pesa prompts still waiting on a callback are for.
+func amountAwaited(payments []*models.Payment) (custom_types.Money, error) {
+	return sumPayments(payments, custom_types.PaymentStatusPending)
+}
+
+func sumPayments(payments []*models.Payment, status custom_types.PaymentStatus) (custom_types.Money, error) {
+	total := custom_types.NewMoney(0)
+
+	for _, payment := range payments {
+		if payment.PaymentStatus != status {
+			continue
+		}
+
+		var err error
+		total, err = total.Add(payment.Amount)
+		if err != nil {
+			return custom_types.Money{}, apperr.NewError(err)
+		}
+	}
+
+	return total, nil
+}
+
+func completedPayments(payments []*models.Payment) int {
+	count := 0
+
+	for _, payment := range payments {
+		if payment.PaymentStatus == custom_types.PaymentStatusCompleted {
+			count++
+		}
+	}
+
+	return count
+}
+
+// primaryPaymentMethod is the method that paid the largest share; ties go to the earlier payment.
+func primaryPaymentMethod(payments []*models.Payment, fallback custom_types.PaymentMethod) custom_types.PaymentMethod {
+	totals := make(map[custom_types.PaymentMethod]int64)
+	primary := fallback
+	var largest int64
+
+	for _, payment := range payments {
+		if payment.PaymentStatus != custom_types.PaymentStatusCompleted {
+			continue
+		}
+
+		totals[payment.PaymentMethod] += payment.Amount.Amount
+		if totals[payment.PaymentMethod] > largest {
+			largest = totals[payment.PaymentMethod]
+			primary = payment.PaymentMethod
+		}
+	}
+
+	return primary
+}
+
+func newOrderPayments(
+	order *models.Order,
+	payments []*models.Payment,
+	listed []*models.Payment,
+) (*models.OrderPayments, error) {
+
+	paid, err := amountPaid(payments)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := balanceDue(order, payments)
+	if err != nil {
+		return nil, err
+	}
+
+	if balance.IsNegative() {
+		balance = custom_types.NewMoney(0)
+	}
+
+	changeDue := custom_types.NewMoney(0)
+	for _, payment := range listed {
+		changeDue, err = changeDue.Add(payment.ChangeDue)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+	}
+
+	return &models.OrderPayments{
+		OrderID:     order.ID,
+		OrderStatus: order.OrderStatus,
+		TotalAmount: order.TotalAmount,
+		AmountPaid:  paid,
+		Balance:     balance,
+		ChangeDue:   changeDue,
+		Payments:    listed,
+	}, nil
+}
@@ -0,0 +1,386 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func tender(paymentMethod custom_types.PaymentMethod, amount int64, providerReference string) dtos.TenderForm {
+	form := dtos.TenderForm{PaymentMethod: paymentMethod, Amount: custom_types.NewMoney(amount)}
+	if providerReference != "" {
+		form.ProviderReference = null.NullValue(providerReference)
+	}
+	return form
+}
+
+func pendingOrder(total int64) *models.Order {
+	return &models.Order{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+		ShopID:               "shop-1",
+		ReferenceNumber:      "ORD-2025-000001",
+		PhoneNumber:          "0712345678",
+		TotalAmount:          custom_types.NewMoney(total),
+		OrderStatus:          custom_types.OrderStatusPending,
+		PaymentMethod:        custom_types.PaymentMethodCash,
+	}
+}
+
+func payment(id int64, paymentMethod custom_types.PaymentMethod, amount int64, paymentStatus custom_types.PaymentStatus) *models.Payment {
+	return &models.Payment{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: id},
+		OrderID:              1,
+		Amount:               custom_types.NewMoney(amount),
+		AmountTendered:       custom_types.NewMoney(amount),
+		PaymentMethod:        paymentMethod,
+		PaymentStatus:        paymentStatus,
+		Timestamps:           custom_types.Timestamps{CreatedAt: time.Now()},
+	}
+}
+
+func TestRecordPayments_CashMakesUpTheRestAndGivesChange(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, OrderStatusTransitionDomain: mockTransitions}
+	service := services.NewPaymentService(store)
+
+	order := pendingOrder(125050)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{}, nil)
+	mockPayments.On("PaymentByProviderReference", ctx, mock.Anything, custom_types.PaymentMethodCard, "AUTH123").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Return(nil).Twice()
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockTransitions.On("CreateOrderStatusTransition", ctx, mock.Anything, mock.MatchedBy(func(transition *models.OrderStatusTransition) bool {
+		return transition.ToStatus == custom_types.OrderStatusPaid && transition.ChangedBy == "cashier@example.com"
+	})).Return(nil)
+
+	// cash is listed first but card is applied first, so only cash gives change
+	orderPayments, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{
+			tender(custom_types.PaymentMethodCash, 100000, ""),
+			tender(custom_types.PaymentMethodCard, 50000, "AUTH123"),
+		},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Len(t, orderPayments.Payments, 2)
+
+	cash, card := orderPayments.Payments[0], orderPayments.Payments[1]
+	assert.Equal(t, int64(75050), cash.Amount.Amount)
+	assert.Equal(t, int64(100000), cash.AmountTendered.Amount)
+	assert.Equal(t, int64(24950), cash.ChangeDue.Amount)
+	assert.Equal(t, int64(50000), card.Amount.Amount)
+	assert.True(t, card.ChangeDue.IsZero())
+	assert.Equal(t, "AUTH123", *card.ProviderReference)
+	assert.Equal(t, "cashier@example.com", card.RecordedBy)
+
+	assert.Equal(t, custom_types.OrderStatusPaid, orderPayments.OrderStatus)
+	assert.True(t, orderPayments.Balance.IsZero())
+	assert.Equal(t, int64(24950), orderPayments.ChangeDue.Amount)
+	// cash covered most of the bill
+	assert.Equal(t, custom_types.PaymentMethodCash, order.PaymentMethod)
+
+	mockOrders.AssertExpectations(t)
+	mockPayments.AssertExpectations(t)
+	mockTransitions.AssertExpectations(t)
+}
+
+func TestRecordPayments_PartPaymentLeavesABalance(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, OrderStatusTransitionDomain: mockTransitions}
+	service := services.NewPaymentService(store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(200000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{}, nil)
+	mockPayments.On("PaymentByProviderReference", ctx, mock.Anything, custom_types.PaymentMethodMpesa, "NLJ7RT61SV").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Return(nil)
+
+	orderPayments, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodMpesa, 150000, "NLJ7RT61SV")},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.OrderStatusPending, orderPayments.OrderStatus)
+	assert.Equal(t, int64(150000), orderPayments.AmountPaid.Amount)
+	assert.Equal(t, int64(50000), orderPayments.Balance.Amount)
+	assert.True(t, orderPayments.ChangeDue.IsZero())
+	mockOrders.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+	mockTransitions.AssertNotCalled(t, "CreateOrderStatusTransition", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordPayments_PaidByTheTenderThatCoveredMost(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, OrderStatusTransitionDomain: mockTransitions}
+	service := services.NewPaymentService(store)
+
+	order := pendingOrder(200000)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{payment(1, custom_types.PaymentMethodMpesa, 150000, custom_types.PaymentStatusCompleted)}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Return(nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockTransitions.On("CreateOrderStatusTransition", ctx, mock.Anything, mock.AnythingOfType("*models.OrderStatusTransition")).
+		Return(nil)
+
+	orderPayments, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 100000, "")},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.OrderStatusPaid, orderPayments.OrderStatus)
+	assert.Equal(t, int64(200000), orderPayments.AmountPaid.Amount)
+	assert.Equal(t, int64(50000), orderPayments.ChangeDue.Amount)
+	// only the new tender is listed
+	assert.Len(t, orderPayments.Payments, 1)
+	// M-Pesa covered most of the bill
+	assert.Equal(t, custom_types.PaymentMethodMpesa, order.PaymentMethod)
+	mockTransitions.AssertExpectations(t)
+}
+
+func TestRecordPayments_RejectsBadTenders(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int64
+		payments []*models.Payment
+		tenders  []dtos.TenderForm
+	}{
+		{"no tenders", 1000, nil, nil},
+		{"unknown method", 1000, nil, []dtos.TenderForm{tender("cheque", 1000, "")}},
+		{"zero amount", 1000, nil, []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 0, "")}},
+		{"card over the balance", 1000, nil, []dtos.TenderForm{tender(custom_types.PaymentMethodCard, 1500, "")}},
+		{"M-Pesa without a receipt", 1000, nil, []dtos.TenderForm{tender(custom_types.PaymentMethodMpesa, 1000, " ")}},
+		{"cash after the balance is covered", 1000, nil, []dtos.TenderForm{
+			tender(custom_types.PaymentMethodCard, 1000, ""),
+			tender(custom_types.PaymentMethodCash, 500, ""),
+		}},
+		{"nothing owed", 1000, []*models.Payment{payment(1, custom_types.PaymentMethodCard, 1000, custom_types.PaymentStatusCompleted)}, []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 500, "")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockOrders := new(MockOrderDomain)
+			mockPayments := new(MockPaymentDomain)
+			store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+			service := services.NewPaymentService(store)
+
+			mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(pendingOrder(tt.total), nil)
+			mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(tt.payments, nil)
+
+			_, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{Tenders: tt.tenders}, "cashier@example.com")
+
+			assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+			mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRecordPayments_PaidOrdersTakeNoMoreTenders(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewPaymentService(store)
+
+	order := pendingOrder(100000)
+	order.OrderStatus = custom_types.OrderStatusPaid
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+
+	_, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 100, "")},
+	}, "cashier@example.com")
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordPayments_WaitsOnMpesaPrompts(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewPaymentService(store)
+
+	// an STK prompt for 1,000.00 is on the customer's phone
+	prompted := payment(1, custom_types.PaymentMethodMpesa, 100000, custom_types.PaymentStatusPending)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{prompted}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.AnythingOfType("*models.Payment")).
+		Return(nil)
+
+	orderPayments, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 200000, "")},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50000), orderPayments.Payments[0].Amount.Amount)
+	assert.Equal(t, int64(150000), orderPayments.Payments[0].ChangeDue.Amount)
+	assert.Equal(t, custom_types.OrderStatusPending, orderPayments.OrderStatus)
+	mockOrders.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordPayments_RefusesWhatAPromptIsFor(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewPaymentService(store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{
+			payment(1, custom_types.PaymentMethodCash, 50000, custom_types.PaymentStatusCompleted),
+			payment(2, custom_types.PaymentMethodMpesa, 100000, custom_types.PaymentStatusPending),
+		}, nil)
+
+	// the rest is what the prompt is for
+	_, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 100000, "")},
+	}, "cashier@example.com")
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordPayments_ExpiredPromptsNoLongerWait(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments, OrderStatusTransitionDomain: mockTransitions}
+	service := services.NewPaymentService(store)
+
+	// the prompt's callback never came
+	prompted := payment(1, custom_types.PaymentMethodMpesa, 150000, custom_types.PaymentStatusPending)
+	prompted.CreatedAt = time.Now().Add(-10 * time.Minute)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(150000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{prompted}, nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, prompted).
+		Return(nil)
+	mockPayments.On("CreatePayment", ctx, mock.Anything, mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.PaymentMethod == custom_types.PaymentMethodCash
+	})).Return(nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, mock.AnythingOfType("*models.Order")).
+		Return(nil)
+	mockTransitions.On("CreateOrderStatusTransition", ctx, mock.Anything, mock.AnythingOfType("*models.OrderStatusTransition")).
+		Return(nil)
+
+	orderPayments, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 150000, "")},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.PaymentStatusFailed, prompted.PaymentStatus)
+	assert.Equal(t, custom_types.OrderStatusPaid, orderPayments.OrderStatus)
+	mockPayments.AssertExpectations(t)
+}
+
+func TestRecordPayments_RejectsReusedReference(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewPaymentService(store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(100000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{}, nil)
+	mockPayments.On("PaymentByProviderReference", ctx, mock.Anything, custom_types.PaymentMethodMpesa, "NLJ7RT61SV").
+		Return(&models.Payment{OrderID: 2, ProviderReference: null.NullValue("NLJ7RT61SV")}, nil)
+
+	_, err := service.RecordPayments(ctx, &inlineDB{}, "shop-1", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodMpesa, 100000, "NLJ7RT61SV")},
+	}, "cashier@example.com")
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListPayments_PendingPaymentsDoNotCount(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewPaymentService(store)
+
+	mockOrders.On("OrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(pendingOrder(100000), nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{payment(1, custom_types.PaymentMethodMpesa, 100000, custom_types.PaymentStatusPending)}, nil)
+
+	orderPayments, err := service.ListPayments(ctx, nil, "shop-1", 1)
+
+	assert.NoError(t, err)
+	assert.True(t, orderPayments.AmountPaid.IsZero())
+	assert.Equal(t, int64(100000), orderPayments.Balance.Amount)
+	assert.Len(t, orderPayments.Payments, 1)
+}
+
+func TestPayments_OtherShopsOrders(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{OrderDomain: mockOrders, PaymentDomain: mockPayments}
+	service := services.NewPaymentService(store)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-2", int64(1)).
+		Return(nil, apperr.NewNotFound("order", "1"))
+	mockOrders.On("OrderByID", ctx, mock.Anything, "shop-2", int64(1)).
+		Return(nil, apperr.NewNotFound("order", "1"))
+
+	_, err := service.RecordPayments(ctx, &inlineDB{}, "shop-2", 1, &dtos.RecordPaymentsForm{
+		Tenders: []dtos.TenderForm{tender(custom_types.PaymentMethodCash, 100000, "")},
+	}, "cashier@example.com")
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	_, err = service.ListPayments(ctx, nil, "shop-2", 1)
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	mockPayments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything, mock.Anything)
+}
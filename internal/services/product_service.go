@@ -2,56 +2,101 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/domain"
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
 	"github/Doris-Mwito5/savannah-pos/internal/models"
-	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
 )
 
 type (
 	ProductService interface {
-		CreateProduct(ctx context.Context, dB db.DB, form *dtos.CreateProductForm) (*models.Product, error)
-		ProductByID(ctx context.Context, dB db.DB, productID int64) (*models.Product, error)
-		UpdateProduct(ctx context.Context, dB db.DB, productID int64, form *dtos.UpdateProductForm) (*models.Product, error)
-		DeleteProduct(ctx context.Context, dB db.DB, productID int64) (*models.Product, error)
+		CreateProduct(ctx context.Context, dB db.DB, shopID string, form *dtos.CreateProductForm, actor string) (*models.Product, error)
+		ProductByID(ctx context.Context, dB db.DB, shopID string, productID int64) (*models.Product, error)
+		UpdateProduct(ctx context.Context, dB db.DB, shopID string, productID int64, form *dtos.UpdateProductForm) (*models.Product, error)
+		DeleteProduct(ctx context.Context, dB db.DB, shopID string, productID int64) (*models.Product, error)
 		ListProducts(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.ProductList, error)
-		GetAveragePriceByCategory(	ctx context.Context, dB db.DB, categoryID int64) (float64, error)
+		GetAveragePriceByCategory(ctx context.Context, dB db.DB, shopID string, categoryID int64) (custom_types.Money, error)
 	}
 
 	productService struct {
-		store *domain.Store
+		stockService StockService
+		store        *domain.Store
 	}
 )
 
-func NewProductService(store *domain.Store) ProductService {
+func NewProductService(
+	stockService StockService,
+	store *domain.Store,
+) ProductService {
 	return &productService{
-		store: store,
+		stockService: stockService,
+		store:        store,
 	}
 }
 
 func (s *productService) CreateProduct(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	form *dtos.CreateProductForm,
+	actor string,
 ) (*models.Product, error) {
 	//fetch the category
-	category, err := s.store.CategoryDomain.CategoryByID(ctx, dB, form.CategoryID)
+	category, err := s.store.CategoryDomain.CategoryByID(ctx, dB, shopID, form.CategoryID)
 	if err != nil {
 		return &models.Product{}, err
 	}
 	product := &models.Product{
+		ShopID:         shopID,
 		Name:           form.Name,
 		Description:    form.Description,
 		WholesalePrice: form.WholesalePrice,
 		RetailPrice:    form.RetailPrice,
 		CategoryID:     category.ID,
 		ProductImage:   form.ProductImage,
-		Stock:          form.Stock,
 		ProductType:    form.ProductType,
+		TaxClassID:     form.TaxClassID,
+		// prices are taken to include VAT unless the form says otherwise
+		PriceIncludesTax: form.PriceIncludesTax == nil || *form.PriceIncludesTax,
 	}
 
-	err = s.store.ProductDomain.CreateProduct(ctx, dB, product)
+	err = checkTaxClass(ctx, dB, s.store, shopID, product.TaxClassID)
+	if err != nil {
+		return &models.Product{}, err
+	}
+
+	err = dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		err := s.store.ProductDomain.CreateProduct(ctx, operations, product)
+		if err != nil {
+			return err
+		}
+
+		if form.Stock == 0 {
+			return nil
+		}
+
+		// opening stock goes through the ledger like any other receipt
+		openingStock := &models.StockMovement{
+			ProductID:    product.ID,
+			MovementType: custom_types.StockMovementTypeReceived,
+			Quantity:     custom_types.StockMovementTypeReceived.SignedQuantity(form.Stock),
+			Reason:       "opening stock",
+			Actor:        actor,
+		}
+
+		err = s.stockService.ApplyStockMovements(ctx, operations, shopID, []*models.StockMovement{openingStock})
+		if err != nil {
+			return err
+		}
+
+		product.Stock = openingStock.StockAfter
+		return nil
+	})
 	if err != nil {
 		return &models.Product{}, err
 	}
@@ -62,36 +107,81 @@ func (s *productService) CreateProduct(
 func (s *productService) ProductByID(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	productID int64,
 ) (*models.Product, error) {
 
-	return s.store.ProductDomain.ProductByID(ctx, dB, productID)
+	return s.store.ProductDomain.ProductByID(ctx, dB, shopID, productID)
 }
 
 func (s *productService) UpdateProduct(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	productID int64,
 	form *dtos.UpdateProductForm,
 ) (*models.Product, error) {
 
-	product, err := s.store.ProductDomain.ProductByID(ctx, dB, productID)
+	product, err := s.store.ProductDomain.ProductByID(ctx, dB, shopID, productID)
 	if err != nil {
 		return &models.Product{}, err
 	}
 
+	if form.Name != nil {
+		name := strings.TrimSpace(*form.Name)
+		if name == "" {
+			return &models.Product{}, apperr.NewBadRequest("product name cannot be blank")
+		}
+		product.Name = name
+	}
+
+	if form.Description != nil {
+		product.Description = form.Description
+	}
+
+	if form.ProductImage != nil {
+		product.ProductImage = form.ProductImage
+	}
+
+	if form.ProductType != nil {
+		if !form.ProductType.IsValid() {
+			return &models.Product{}, apperr.NewBadRequest(fmt.Sprintf("invalid product type [%s]", *form.ProductType))
+		}
+		product.ProductType = *form.ProductType
+	}
+
+	if form.CategoryID != nil {
+		_, err = s.store.CategoryDomain.CategoryByID(ctx, dB, shopID, *form.CategoryID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return &models.Product{}, apperr.NewBadRequest(fmt.Sprintf("category [%d] does not exist", *form.CategoryID))
+			}
+			return &models.Product{}, err
+		}
+		product.CategoryID = *form.CategoryID
+	}
+
 	if form.RetailPrice != nil {
-		product.RetailPrice = null.ValueFromNull(form.RetailPrice)
+		product.RetailPrice = *form.RetailPrice
 	}
 
 	if form.WholesalePrice != nil {
-		product.WholesalePrice = null.ValueFromNull(form.WholesalePrice)
+		product.WholesalePrice = *form.WholesalePrice
 	}
 
-	if form.Stock != nil {
-		product.Stock = null.ValueFromNull(form.Stock)
+	if form.TaxClassID != nil {
+		err = checkTaxClass(ctx, dB, s.store, shopID, form.TaxClassID)
+		if err != nil {
+			return &models.Product{}, err
+		}
+		product.TaxClassID = form.TaxClassID
 	}
 
+	if form.PriceIncludesTax != nil {
+		product.PriceIncludesTax = *form.PriceIncludesTax
+	}
+
+	// stock is not set here; it only changes through stock movements
 	err = s.store.ProductDomain.CreateProduct(ctx, dB, product)
 	if err != nil {
 		return &models.Product{}, err
@@ -130,15 +220,16 @@ func (s *productService) ListProducts(
 func (s *productService) DeleteProduct(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	productID int64,
 ) (*models.Product, error) {
 
-	product, err := s.store.ProductDomain.ProductByID(ctx, dB, productID)
+	product, err := s.store.ProductDomain.ProductByID(ctx, dB, shopID, productID)
 	if err != nil {
 		return &models.Product{}, err
 	}
 
-	err = s.store.ProductDomain.DeleteProduct(ctx, dB, productID)
+	err = s.store.ProductDomain.DeleteProduct(ctx, dB, shopID, productID)
 	if err != nil {
 		return &models.Product{}, err
 	}
@@ -149,13 +240,14 @@ func (s *productService) DeleteProduct(
 func (s *productService) GetAveragePriceByCategory(
 	ctx context.Context,
 	dB db.DB,
+	shopID string,
 	categoryID int64,
-) (float64, error) {
-	
-	_, err := s.store.CategoryDomain.CategoryByID(ctx, dB, categoryID)
+) (custom_types.Money, error) {
+
+	_, err := s.store.CategoryDomain.CategoryByID(ctx, dB, shopID, categoryID)
 	if err != nil {
-		return 0, err
+		return custom_types.Money{}, err
 	}
-	
-	return s.store.ProductDomain.GetAveragePriceByCategory(ctx, dB, categoryID)
-}
\ No newline at end of file
+
+	return s.store.ProductDomain.GetAveragePriceByCategory(ctx, dB, shopID, categoryID)
+}
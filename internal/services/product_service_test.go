@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func TestUpdateProduct_AppliesTheFormsFields(t *testing.T) {
+	ctx := context.Background()
+	mockProducts := new(MockProductDomain)
+	mockCategories := new(MockCategoryDomain)
+	store := &domain.Store{ProductDomain: mockProducts, CategoryDomain: mockCategories}
+	service := services.NewProductService(nil, store)
+
+	product := goodsProduct()
+	product.Name = "Soda"
+	product.CategoryID = 1
+	product.RetailPrice = custom_types.NewMoney(6000)
+
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(10)).
+		Return(product, nil)
+	mockCategories.On("CategoryByID", ctx, mock.Anything, "shop-1", int64(2)).
+		Return(&models.Category{Name: "Repairs", ShopID: null.NullValue("shop-1")}, nil)
+	mockProducts.On("CreateProduct", ctx, mock.Anything, product).
+		Return(nil)
+
+	serviceType := custom_types.ProductTypeService
+	updated, err := service.UpdateProduct(ctx, nil, "shop-1", 10, &dtos.UpdateProductForm{
+		Name:         null.NullValue(" Phone repair "),
+		Description:  null.NullValue("Screen replacement"),
+		CategoryID:   null.NullValue(int64(2)),
+		ProductImage: null.NullValue("https://example.com/repair.png"),
+		ProductType:  &serviceType,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Phone repair", updated.Name)
+	assert.Equal(t, "Screen replacement", null.ValueFromNull(updated.Description))
+	assert.Equal(t, int64(2), updated.CategoryID)
+	assert.Equal(t, "https://example.com/repair.png", null.ValueFromNull(updated.ProductImage))
+	assert.Equal(t, custom_types.ProductTypeService, updated.ProductType)
+	// fields the form leaves out are kept
+	assert.Equal(t, int64(6000), updated.RetailPrice.Amount)
+	mockProducts.AssertExpectations(t)
+	mockCategories.AssertExpectations(t)
+}
+
+func TestUpdateProduct_Rejects(t *testing.T) {
+	badType := custom_types.ProductType("rental")
+
+	tests := []struct {
+		name string
+		form *dtos.UpdateProductForm
+	}{
+		{"blank name", &dtos.UpdateProductForm{Name: null.NullValue("  ")}},
+		{"unknown product type", &dtos.UpdateProductForm{ProductType: &badType}},
+		{"another shop's category", &dtos.UpdateProductForm{CategoryID: null.NullValue(int64(9))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockProducts := new(MockProductDomain)
+			mockCategories := new(MockCategoryDomain)
+			store := &domain.Store{ProductDomain: mockProducts, CategoryDomain: mockCategories}
+			service := services.NewProductService(nil, store)
+
+			mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(10)).
+				Return(goodsProduct(), nil)
+			mockCategories.On("CategoryByID", ctx, mock.Anything, "shop-1", int64(9)).
+				Return(nil, apperr.NewDatabaseError(sql.ErrNoRows)).Maybe()
+
+			_, err := service.UpdateProduct(ctx, nil, "shop-1", 10, tt.form)
+
+			assert.Error(t, err)
+			assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+			mockProducts.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUpdateProduct_RefusesAnotherShopsTaxClass(t *testing.T) {
+	ctx := context.Background()
+	mockProducts := new(MockProductDomain)
+	mockTaxClasses := new(MockTaxClassDomain)
+	store := &domain.Store{ProductDomain: mockProducts, TaxClassDomain: mockTaxClasses}
+	service := services.NewProductService(nil, store)
+
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(10)).
+		Return(goodsProduct(), nil)
+	// the class is another shop's, so shop-1 cannot see it
+	mockTaxClasses.On("TaxClassByID", ctx, mock.Anything, "shop-1", int64(9)).
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	_, err := service.UpdateProduct(ctx, nil, "shop-1", 10, &dtos.UpdateProductForm{TaxClassID: null.NullValue(int64(9))})
+
+	assert.Error(t, err)
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+	mockProducts.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything, mock.Anything)
+	mockTaxClasses.AssertExpectations(t)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+)
+
+// percentBasis is 100% in the basis points percentage promotions are kept in.
+const percentBasis = 10000
+
+// promotionCandidate is a promotion that may apply to an order, with the categories it
+// covers worked out beforehand when it is category-wide.
+type promotionCandidate struct {
+	promotion   *models.Promotion
+	categoryIDs map[int64]bool
+}
+
+func (c *promotionCandidate) covers(product *models.Product) bool {
+	switch {
+	case c.promotion.ProductID != nil:
+		return *c.promotion.ProductID == product.ID
+	case c.promotion.CategoryID != nil:
+		return c.categoryIDs[product.CategoryID]
+	}
+
+	return true
+}
+
+// discountOn is what the promotion takes off a line, never more than room, the part of
+// the line not already discounted.
+func (c *promotionCandidate) discountOn(
+	orderItem *models.OrderItem,
+	room custom_types.Money,
+) custom_types.Money {
+
+	discount := custom_types.Money{Currency: room.Currency}
+
+	switch c.promotion.PromotionType {
+	case custom_types.PromotionTypePercentage:
+		discount = room.MulRatio(c.promotion.PercentOff, percentBasis)
+	case custom_types.PromotionTypeFixed:
+		discount = c.promotion.AmountOff.Mul(orderItem.Quantity)
+	case custom_types.PromotionTypeBuyXGetY:
+		group := c.promotion.BuyQuantity + c.promotion.GetQuantity
+		if c.promotion.BuyQuantity > 0 && c.promotion.GetQuantity > 0 {
+			freeUnits := orderItem.Quantity / group * c.promotion.GetQuantity
+			discount = orderItem.UnitPrice.Mul(freeUnits)
+		}
+	}
+
+	if discount.Amount > room.Amount {
+		return room
+	}
+
+	return discount
+}
+
+// applyPromotions gives each order line the single best promotion that covers it and adds
+// that promotion's discount to the line's discount. Promotions do not stack on one line;
+// ties go to the candidate listed first. It reports whether each candidate was used.
+func applyPromotions(
+	orderItems []*models.OrderItem,
+	products map[int64]*models.Product,
+	candidates []*promotionCandidate,
+) (map[int64]bool, error) {
+
+	used := make(map[int64]bool, len(candidates))
+
+	for _, orderItem := range orderItems {
+		product := products[orderItem.ProductID]
+		room, err := orderItem.UnitPrice.Mul(orderItem.Quantity).Sub(orderItem.Discount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		var best *promotionCandidate
+		bestDiscount := custom_types.Money{}
+
+		for _, candidate := range candidates {
+			if !candidate.covers(product) {
+				continue
+			}
+
+			discount := candidate.discountOn(orderItem, room)
+			if discount.Amount > bestDiscount.Amount {
+				best, bestDiscount = candidate, discount
+			}
+		}
+
+		if best == nil {
+			continue
+		}
+
+		orderItem.Discount, err = orderItem.Discount.Add(bestDiscount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		orderItem.Promotions = append(orderItem.Promotions, &models.OrderItemPromotion{
+			PromotionID:   best.promotion.ID,
+			Name:          best.promotion.Name,
+			PromotionType: best.promotion.PromotionType,
+			CouponCode:    best.promotion.CouponCode,
+			Discount:      bestDiscount,
+		})
+		used[best.promotion.ID] = true
+	}
+
+	return used, nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+)
+
+func promotionProducts() map[int64]*models.Product {
+	return map[int64]*models.Product{
+		1: {SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, CategoryID: 10},
+		2: {SequentialIdentifier: custom_types.SequentialIdentifier{ID: 2}, CategoryID: 11},
+		3: {SequentialIdentifier: custom_types.SequentialIdentifier{ID: 3}, CategoryID: 20},
+	}
+}
+
+func promotion(id int64, promotionType custom_types.PromotionType) *models.Promotion {
+	return &models.Promotion{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: id},
+		Name:                 "promotion",
+		PromotionType:        promotionType,
+		IsActive:             true,
+	}
+}
+
+func TestApplyPromotions_Types(t *testing.T) {
+	percentage := promotion(1, custom_types.PromotionTypePercentage)
+	percentage.PercentOff = 1000
+	percentage.ProductID = null.NullValue(int64(1))
+
+	fixed := promotion(2, custom_types.PromotionTypeFixed)
+	fixed.AmountOff = custom_types.NewMoney(500)
+	fixed.ProductID = null.NullValue(int64(2))
+
+	buyTwoGetOne := promotion(3, custom_types.PromotionTypeBuyXGetY)
+	buyTwoGetOne.BuyQuantity = 2
+	buyTwoGetOne.GetQuantity = 1
+	buyTwoGetOne.ProductID = null.NullValue(int64(3))
+
+	orderItems := []*models.OrderItem{
+		pricingItem(1, 10000, 2, 2000), // 10% off what is left after the 20.00 manual discount
+		pricingItem(2, 300, 4, 0),      // 5.00 off each unit is capped at the 3.00 price
+		pricingItem(3, 1000, 7, 0),     // two groups of three, so two units free
+	}
+
+	used, err := applyPromotions(orderItems, promotionProducts(), []*promotionCandidate{
+		{promotion: percentage},
+		{promotion: fixed},
+		{promotion: buyTwoGetOne},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(3800), orderItems[0].Discount.Amount)
+	assert.Equal(t, int64(1200), orderItems[1].Discount.Amount)
+	assert.Equal(t, int64(2000), orderItems[2].Discount.Amount)
+
+	for i, orderItem := range orderItems {
+		assert.Len(t, orderItem.Promotions, 1)
+		assert.Equal(t, int64(i+1), orderItem.Promotions[0].PromotionID)
+	}
+
+	assert.Equal(t, map[int64]bool{1: true, 2: true, 3: true}, used)
+}
+
+func TestApplyPromotions_CategoryWide(t *testing.T) {
+	categoryWide := promotion(1, custom_types.PromotionTypePercentage)
+	categoryWide.PercentOff = 2500
+	categoryWide.CategoryID = null.NullValue(int64(10))
+
+	orderItems := []*models.OrderItem{
+		pricingItem(1, 1000, 1, 0),
+		pricingItem(2, 1000, 1, 0),
+		pricingItem(3, 1000, 1, 0),
+	}
+
+	// category 11 sits under 10 in the tree; category 20 does not
+	_, err := applyPromotions(orderItems, promotionProducts(), []*promotionCandidate{
+		{promotion: categoryWide, categoryIDs: map[int64]bool{10: true, 11: true}},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(250), orderItems[0].Discount.Amount)
+	assert.Equal(t, int64(250), orderItems[1].Discount.Amount)
+	assert.Equal(t, int64(0), orderItems[2].Discount.Amount)
+	assert.Empty(t, orderItems[2].Promotions)
+}
+
+func TestApplyPromotions_BestPromotionPerLineWithoutStacking(t *testing.T) {
+	tenPercent := promotion(1, custom_types.PromotionTypePercentage)
+	tenPercent.PercentOff = 1000
+
+	twentyPercent := promotion(2, custom_types.PromotionTypePercentage)
+	twentyPercent.PercentOff = 2000
+
+	coupon := promotion(3, custom_types.PromotionTypeFixed)
+	coupon.AmountOff = custom_types.NewMoney(200)
+	coupon.CouponCode = null.NullValue("SAVE2")
+
+	orderItems := []*models.OrderItem{
+		pricingItem(1, 1000, 1, 0),
+	}
+
+	// the coupon ties with twenty percent and is listed first, so it wins
+	used, err := applyPromotions(orderItems, promotionProducts(), []*promotionCandidate{
+		{promotion: coupon},
+		{promotion: tenPercent},
+		{promotion: twentyPercent},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(200), orderItems[0].Discount.Amount)
+	assert.Len(t, orderItems[0].Promotions, 1)
+	assert.Equal(t, "SAVE2", *orderItems[0].Promotions[0].CouponCode)
+	assert.Equal(t, map[int64]bool{3: true}, used)
+}
+
+func TestPromotionAvailableAt(t *testing.T) {
+	now := time.Date(2025, 10, 10, 12, 0, 0, 0, time.UTC)
+
+	p := promotion(1, custom_types.PromotionTypePercentage)
+	assert.True(t, p.AvailableAt(now))
+
+	p.StartsAt = null.NullValue(now.Add(time.Hour))
+	assert.False(t, p.AvailableAt(now))
+
+	p.StartsAt = nil
+	p.EndsAt = null.NullValue(now)
+	assert.False(t, p.AvailableAt(now))
+
+	p.EndsAt = nil
+	p.UsageLimit = null.NullValue(int64(5))
+	p.UsageCount = 5
+	assert.False(t, p.AvailableAt(now))
+
+	p.UsageCount = 4
+	assert.True(t, p.AvailableAt(now))
+
+	p.IsActive = false
+	assert.False(t, p.AvailableAt(now))
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+	"time"
+)
+
+type (
+	PromotionService interface {
+		CreatePromotion(ctx context.Context, dB db.DB, shopID string, form *dtos.CreatePromotionForm) (*models.Promotion, error)
+		UpdatePromotion(ctx context.Context, dB db.DB, shopID string, promotionID int64, form *dtos.UpdatePromotionForm) (*models.Promotion, error)
+		PromotionByID(ctx context.Context, dB db.DB, shopID string, promotionID int64) (*models.Promotion, error)
+		ListPromotions(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.PromotionList, error)
+		ApplyPromotions(ctx context.Context, operations db.SQLOperations, shopID string, couponCode string, orderItems []*models.OrderItem, products map[int64]*models.Product) error
+		RecordPromotions(ctx context.Context, operations db.SQLOperations, order *models.Order, orderItems []*models.OrderItem) error
+		ReleasePromotions(ctx context.Context, operations db.SQLOperations, order *models.Order) error
+	}
+
+	promotionService struct {
+		store *domain.Store
+	}
+)
+
+func NewPromotionService(
+	store *domain.Store,
+) PromotionService {
+	return &promotionService{
+		store: store,
+	}
+}
+
+func (s *promotionService) CreatePromotion(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	form *dtos.CreatePromotionForm,
+) (*models.Promotion, error) {
+
+	err := checkFormShop(shopID, form.ShopID)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion := &models.Promotion{
+		ShopID:        shopID,
+		Name:          strings.TrimSpace(form.Name),
+		PromotionType: form.PromotionType,
+		PercentOff:    form.PercentOff,
+		AmountOff:     custom_types.NewMoney(0),
+		BuyQuantity:   form.BuyQuantity,
+		GetQuantity:   form.GetQuantity,
+		ProductID:     form.ProductID,
+		CategoryID:    form.CategoryID,
+		UsageLimit:    form.UsageLimit,
+		StartsAt:      form.StartsAt,
+		EndsAt:        form.EndsAt,
+		IsActive:      true,
+	}
+
+	if form.AmountOff != nil {
+		promotion.AmountOff = *form.AmountOff
+	}
+
+	if form.CouponCode != nil {
+		couponCode := strings.TrimSpace(*form.CouponCode)
+		if couponCode == "" {
+			return nil, apperr.NewBadRequest("coupon code cannot be blank")
+		}
+		promotion.CouponCode = null.NullValue(couponCode)
+	}
+
+	err = validatePromotion(promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		err := s.checkPromotionScope(ctx, operations, promotion)
+		if err != nil {
+			return err
+		}
+
+		if promotion.CouponCode != nil {
+			_, err := s.store.PromotionDomain.PromotionByCouponCode(ctx, operations, promotion.ShopID, *promotion.CouponCode)
+			if err == nil {
+				return apperr.NewErrorWithType(
+					fmt.Errorf("shop [%s] already has coupon code [%s]", promotion.ShopID, *promotion.CouponCode),
+					apperr.Conflict,
+				)
+			}
+			if !apperr.IsNoRowsErr(err) {
+				return err
+			}
+		}
+
+		return s.store.PromotionDomain.CreatePromotion(ctx, operations, promotion)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+// UpdatePromotion changes when and how often a promotion runs. What it gives is fixed
+// once created, so orders already discounted by it keep making sense.
+func (s *promotionService) UpdatePromotion(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	promotionID int64,
+	form *dtos.UpdatePromotionForm,
+) (*models.Promotion, error) {
+
+	promotion, err := s.store.PromotionDomain.PromotionByID(ctx, dB, shopID, promotionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.Name != nil {
+		promotion.Name = strings.TrimSpace(*form.Name)
+	}
+
+	if form.UsageLimit != nil {
+		promotion.UsageLimit = form.UsageLimit
+	}
+
+	if form.StartsAt != nil {
+		promotion.StartsAt = form.StartsAt
+	}
+
+	if form.EndsAt != nil {
+		promotion.EndsAt = form.EndsAt
+	}
+
+	if form.IsActive != nil {
+		promotion.IsActive = *form.IsActive
+	}
+
+	err = validatePromotion(promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	if promotion.UsageLimit != nil && *promotion.UsageLimit < promotion.UsageCount {
+		return nil, apperr.NewBadRequest(fmt.Sprintf(
+			"usage limit cannot be below the %d time(s) the promotion has been used",
+			promotion.UsageCount,
+		))
+	}
+
+	err = s.store.PromotionDomain.CreatePromotion(ctx, dB, promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func (s *promotionService) PromotionByID(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	promotionID int64,
+) (*models.Promotion, error) {
+
+	return s.store.PromotionDomain.PromotionByID(ctx, dB, shopID, promotionID)
+}
+
+func (s *promotionService) ListPromotions(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	filter *models.Filter,
+) (*models.PromotionList, error) {
+
+	promotions, err := s.store.PromotionDomain.ListShopPromotions(ctx, dB, shopID, filter)
+	if err != nil {
+		return &models.PromotionList{}, err
+	}
+
+	count, err := s.store.PromotionDomain.ShopPromotionsCount(ctx, dB, shopID, filter)
+	if err != nil {
+		return &models.PromotionList{}, err
+	}
+
+	promotionList := &models.PromotionList{
+		Promotions: promotions,
+		Pagination: models.NewPagination(
+			count,
+			filter.Page,
+			filter.Per,
+		),
+	}
+
+	return promotionList, nil
+}
+
+// ApplyPromotions discounts orderItems with the shop's automatic promotions and, when
+// couponCode is given, that coupon. A coupon that cannot be used, or that improves on no
+// line, is rejected so the cashier knows it was not honoured.
+func (s *promotionService) ApplyPromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	couponCode string,
+	orderItems []*models.OrderItem,
+	products map[int64]*models.Product,
+) error {
+
+	now := time.Now()
+	promotions := make([]*models.Promotion, 0)
+
+	couponCode = strings.TrimSpace(couponCode)
+	if couponCode != "" {
+		coupon, err := s.store.PromotionDomain.PromotionByCouponCode(ctx, operations, shopID, couponCode)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewBadRequest(fmt.Sprintf("coupon [%s] does not exist", couponCode))
+			}
+			return err
+		}
+
+		if !coupon.AvailableAt(now) {
+			return apperr.NewBadRequest(fmt.Sprintf("coupon [%s] is not valid now", couponCode))
+		}
+
+		// listed first so it wins ties against automatic promotions
+		promotions = append(promotions, coupon)
+	}
+
+	automatic, err := s.store.PromotionDomain.AutomaticPromotions(ctx, operations, shopID, now)
+	if err != nil {
+		return err
+	}
+	promotions = append(promotions, automatic...)
+
+	candidates := make([]*promotionCandidate, 0, len(promotions))
+	for _, promotion := range promotions {
+		candidate := &promotionCandidate{promotion: promotion}
+
+		if promotion.CategoryID != nil {
+			categoryIDs, err := s.store.ProductDomain.GetCategoryHierarchy(ctx, operations, shopID, *promotion.CategoryID)
+			if err != nil {
+				return err
+			}
+
+			candidate.categoryIDs = make(map[int64]bool, len(categoryIDs))
+			for _, categoryID := range categoryIDs {
+				candidate.categoryIDs[categoryID] = true
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	used, err := applyPromotions(orderItems, products, candidates)
+	if err != nil {
+		return err
+	}
+
+	if couponCode != "" && !used[promotions[0].ID] {
+		return apperr.NewBadRequest(fmt.Sprintf("coupon [%s] gives no discount on any item in this order", couponCode))
+	}
+
+	return nil
+}
+
+// RecordPromotions counts a use of every promotion applied to the order, failing if one
+// ran out of uses meanwhile, and keeps which lines each one discounted. The order and
+// its items must already be saved.
+func (s *promotionService) RecordPromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	order *models.Order,
+	orderItems []*models.OrderItem,
+) error {
+
+	redemptions := make(map[int64]*models.PromotionRedemption)
+	orderItemPromotions := make([]*models.OrderItemPromotion, 0)
+	promotionIDs := make([]int64, 0)
+
+	for _, orderItem := range orderItems {
+		for _, orderItemPromotion := range orderItem.Promotions {
+			orderItemPromotion.OrderItemID = orderItem.ID
+			orderItemPromotions = append(orderItemPromotions, orderItemPromotion)
+
+			redemption, ok := redemptions[orderItemPromotion.PromotionID]
+			if !ok {
+				redemption = &models.PromotionRedemption{
+					PromotionID: orderItemPromotion.PromotionID,
+					OrderID:     order.ID,
+					CouponCode:  orderItemPromotion.CouponCode,
+					Discount:    custom_types.NewMoney(0),
+				}
+				redemptions[orderItemPromotion.PromotionID] = redemption
+				promotionIDs = append(promotionIDs, orderItemPromotion.PromotionID)
+			}
+
+			var err error
+			redemption.Discount, err = redemption.Discount.Add(orderItemPromotion.Discount)
+			if err != nil {
+				return apperr.NewError(err)
+			}
+		}
+	}
+
+	for _, promotionID := range promotionIDs {
+		err := s.store.PromotionDomain.RedeemPromotion(ctx, operations, redemptions[promotionID])
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.store.PromotionDomain.CreateOrderItemPromotions(ctx, operations, orderItemPromotions)
+}
+
+// ReleasePromotions gives back the uses of promotions a cancelled order took, so they can
+// go to another order.
+func (s *promotionService) ReleasePromotions(
+	ctx context.Context,
+	operations db.SQLOperations,
+	order *models.Order,
+) error {
+
+	return s.store.PromotionDomain.ReleaseOrderPromotions(ctx, operations, order.ID)
+}
+
+// checkPromotionScope makes sure the product or category a promotion is limited to exists
+// and is the shop's own.
+func (s *promotionService) checkPromotionScope(
+	ctx context.Context,
+	operations db.SQLOperations,
+	promotion *models.Promotion,
+) error {
+
+	if promotion.ProductID != nil {
+		_, err := s.store.ProductDomain.ProductByID(ctx, operations, promotion.ShopID, *promotion.ProductID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewBadRequest(fmt.Sprintf("product [%d] does not exist", *promotion.ProductID))
+			}
+			return err
+		}
+	}
+
+	if promotion.CategoryID != nil {
+		_, err := s.store.CategoryDomain.CategoryByID(ctx, operations, promotion.ShopID, *promotion.CategoryID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewBadRequest(fmt.Sprintf("category [%d] does not exist", *promotion.CategoryID))
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validatePromotion(
+	promotion *models.Promotion,
+) error {
+
+	if promotion.ShopID == "" || promotion.Name == "" {
+		return apperr.NewBadRequest("promotion shop id and name are required")
+	}
+
+	switch promotion.PromotionType {
+	case custom_types.PromotionTypePercentage:
+		if promotion.PercentOff <= 0 || promotion.PercentOff > percentBasis {
+			return apperr.NewBadRequest("percent off must be between 1 and 10000 basis points")
+		}
+	case custom_types.PromotionTypeFixed:
+		if promotion.AmountOff.Amount <= 0 {
+			return apperr.NewBadRequest("amount off must be greater than zero")
+		}
+	case custom_types.PromotionTypeBuyXGetY:
+		if promotion.BuyQuantity <= 0 || promotion.GetQuantity <= 0 {
+			return apperr.NewBadRequest("buy and get quantities must be greater than zero")
+		}
+	default:
+		return apperr.NewBadRequest(fmt.Sprintf("unknown promotion type [%s]", promotion.PromotionType))
+	}
+
+	if promotion.ProductID != nil && promotion.CategoryID != nil {
+		return apperr.NewBadRequest("a promotion covers either a product or a category, not both")
+	}
+
+	if promotion.UsageLimit != nil && *promotion.UsageLimit <= 0 {
+		return apperr.NewBadRequest("usage limit must be greater than zero")
+	}
+
+	if promotion.StartsAt != nil && promotion.EndsAt != nil && !promotion.EndsAt.After(*promotion.StartsAt) {
+		return apperr.NewBadRequest("a promotion must end after it starts")
+	}
+
+	return nil
+}
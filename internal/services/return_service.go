@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+)
+
+type (
+	ReturnService interface {
+		CreateOrderReturn(ctx context.Context, dB db.DB, shopID string, orderID int64, form *dtos.CreateOrderReturnForm, actor string) (*models.OrderReturn, error)
+		ListOrderReturns(ctx context.Context, dB db.DB, shopID string, orderID int64, filter *models.Filter) (*models.OrderReturnList, error)
+	}
+
+	returnService struct {
+		stockService              StockService
+		store                     *domain.Store
+		notificationOutboxService NotificationOutboxService
+	}
+)
+
+func NewReturnService(
+	stockService StockService,
+	store *domain.Store,
+	notificationOutboxService NotificationOutboxService,
+) ReturnService {
+	return &returnService{
+		stockService:              stockService,
+		store:                     store,
+		notificationOutboxService: notificationOutboxService,
+	}
+}
+
+// CreateOrderReturn takes items of a paid order back. The goods go back into stock,
+// the order's pricing components shrink by the returned share, and refunds are recorded
+// against the payments the order was paid with. Once every item has come back the order moves
+// to returned. Leaving form.Items empty returns everything still outstanding.
+func (s *returnService) CreateOrderReturn(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+	form *dtos.CreateOrderReturnForm,
+	actor string,
+) (*models.OrderReturn, error) {
+
+	if strings.TrimSpace(form.Reason) == "" {
+		return nil, apperr.NewBadRequest("return reason is required")
+	}
+
+	var order *models.Order
+	var orderReturn *models.OrderReturn
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		var err error
+		order, err = s.store.OrderDomain.LockOrderByID(ctx, operations, shopID, orderID)
+		if err != nil {
+			return err
+		}
+
+		if order.OrderStatus != custom_types.OrderStatusPaid {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("order [%d] is [%s]; only paid orders can be returned", order.ID, order.OrderStatus),
+				apperr.Conflict,
+			)
+		}
+
+		orderItems, err := s.store.OrderItemDomain.LockOrderItems(ctx, operations, order.ShopID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		quantities, err := returnQuantities(orderItems, form.Items)
+		if err != nil {
+			return err
+		}
+
+		orderReturn = &models.OrderReturn{
+			OrderID: order.ID,
+			Reason:  form.Reason,
+			Actor:   actor,
+			Items:   make([]*models.OrderReturnItem, 0, len(quantities)),
+		}
+
+		restockMovements := make([]*models.StockMovement, 0, len(quantities))
+		fullyReturned := true
+		orderReturn.RefundAmount = custom_types.NewMoney(0)
+
+		for _, orderItem := range orderItems {
+			quantity := quantities[orderItem.ID]
+			if quantity == 0 {
+				if orderItem.ReturnedQuantity < orderItem.Quantity {
+					fullyReturned = false
+				}
+				continue
+			}
+
+			// each pricing component of the line comes back in proportion to the units returned
+			returned, err := returnedShares(orderItem, quantity)
+			if err != nil {
+				return err
+			}
+
+			err = takeBack([]moneyPart{
+				{&order.Subtotal, returned.Subtotal},
+				{&order.LineDiscount, returned.LineDiscount},
+				{&order.Discount, returned.OrderDiscount},
+				{&order.TaxTotal, returned.TaxTotal},
+				{&order.TotalAmount, returned.GrandTotal},
+			})
+			if err != nil {
+				return err
+			}
+
+			orderReturn.Items = append(orderReturn.Items, &models.OrderReturnItem{
+				OrderItemID:  orderItem.ID,
+				ProductID:    orderItem.ProductID,
+				Quantity:     quantity,
+				RefundAmount: returned.GrandTotal,
+				TaxAmount:    returned.TaxTotal,
+			})
+			orderReturn.RefundAmount, err = orderReturn.RefundAmount.Add(returned.GrandTotal)
+			if err != nil {
+				return apperr.NewError(err)
+			}
+
+			orderItem.ReturnedQuantity += quantity
+			if orderItem.ReturnedQuantity < orderItem.Quantity {
+				fullyReturned = false
+			}
+
+			err = s.store.OrderItemDomain.UpdateReturnedQuantity(ctx, operations, orderItem)
+			if err != nil {
+				return err
+			}
+
+			product, err := s.store.ProductDomain.ProductByID(ctx, operations, order.ShopID, orderItem.ProductID)
+			if err != nil {
+				return err
+			}
+
+			// services carry no stock, so there is nothing to put back
+			if product.ProductType == custom_types.ProductTypeService {
+				continue
+			}
+
+			restockMovements = append(restockMovements, &models.StockMovement{
+				ProductID:    orderItem.ProductID,
+				MovementType: custom_types.StockMovementTypeReturn,
+				Quantity:     custom_types.StockMovementTypeReturn.SignedQuantity(quantity),
+				Reason:       fmt.Sprintf("returned on order %s: %s", order.ReferenceNumber, form.Reason),
+				Actor:        actor,
+				OrderID:      null.NullValue(order.ID),
+			})
+		}
+
+		err = s.stockService.ApplyStockMovements(ctx, operations, order.ShopID, restockMovements)
+		if err != nil {
+			return err
+		}
+
+		err = s.store.OrderReturnDomain.CreateOrderReturn(ctx, operations, orderReturn)
+		if err != nil {
+			return err
+		}
+
+		payments, err := s.store.PaymentDomain.PaymentsByOrderID(ctx, operations, order.ShopID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		refunded, err := s.store.RefundDomain.RefundsByOrderID(ctx, operations, order.ShopID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		orderReturn.Refunds, err = allocateRefunds(order, orderReturn, payments, refunded)
+		if err != nil {
+			return err
+		}
+
+		for _, refund := range orderReturn.Refunds {
+			err = s.store.RefundDomain.CreateRefund(ctx, operations, refund)
+			if err != nil {
+				return err
+			}
+		}
+
+		if fullyReturned {
+			err = transitionOrderStatus(
+				ctx,
+				operations,
+				s.store,
+				order,
+				custom_types.OrderStatusReturned,
+				actor,
+				null.NullValue(form.Reason),
+			)
+		} else {
+			err = s.store.OrderDomain.CreateOrder(ctx, operations, order)
+		}
+		if err != nil {
+			return err
+		}
+
+		return s.notificationOutboxService.EnqueueOrderReturn(ctx, operations, order, orderReturn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orderReturn, nil
+}
+
+func (s *returnService) ListOrderReturns(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+	filter *models.Filter,
+) (*models.OrderReturnList, error) {
+
+	_, err := s.store.OrderDomain.OrderByID(ctx, dB, shopID, orderID)
+	if err != nil {
+		return &models.OrderReturnList{}, err
+	}
+
+	orderReturns, err := s.store.OrderReturnDomain.ListOrderReturns(ctx, dB, shopID, orderID, filter)
+	if err != nil {
+		return &models.OrderReturnList{}, err
+	}
+
+	for _, orderReturn := range orderReturns {
+		orderReturn.Items, err = s.store.OrderReturnDomain.OrderReturnItems(ctx, dB, shopID, orderReturn.ID)
+		if err != nil {
+			return &models.OrderReturnList{}, err
+		}
+
+		orderReturn.Refunds, err = s.store.RefundDomain.RefundsByOrderReturnID(ctx, dB, shopID, orderReturn.ID)
+		if err != nil {
+			return &models.OrderReturnList{}, err
+		}
+	}
+
+	count, err := s.store.OrderReturnDomain.OrderReturnsCount(ctx, dB, shopID, orderID, filter)
+	if err != nil {
+		return &models.OrderReturnList{}, err
+	}
+
+	orderReturnList := &models.OrderReturnList{
+		OrderReturns: orderReturns,
+		Pagination: models.NewPagination(
+			count,
+			filter.Page,
+			filter.Per,
+		),
+	}
+
+	return orderReturnList, nil
+}
+
+// returnedShares is the part of each of a line's pricing components that quantity more
+// returned units take back. Shares are worked out on the running returned quantity, so
+// a line returned in several goes gives back exactly what it cost, to the cent.
+func returnedShares(
+	orderItem *models.OrderItem,
+	quantity int64,
+) (*orderPricing, error) {
+
+	before := orderItem.ReturnedQuantity
+	after := before + quantity
+
+	returned := &orderPricing{}
+	for _, part := range []moneyPart{
+		{&returned.Subtotal, orderItem.UnitPrice.Mul(orderItem.Quantity)},
+		{&returned.LineDiscount, orderItem.Discount},
+		{&returned.OrderDiscount, orderItem.OrderDiscount},
+		{&returned.TaxTotal, orderItem.TaxAmount},
+		{&returned.GrandTotal, orderItem.TotalAmount},
+	} {
+		share, err := part.amount.MulRatio(after, orderItem.Quantity).Sub(part.amount.MulRatio(before, orderItem.Quantity))
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		*part.target = share
+	}
+
+	return returned, nil
+}
+
+// takeBack subtracts each part's amount from its target.
+func takeBack(parts []moneyPart) error {
+	for _, part := range parts {
+		remaining, err := part.target.Sub(part.amount)
+		if err != nil {
+			return apperr.NewError(err)
+		}
+
+		*part.target = remaining
+	}
+
+	return nil
+}
+
+// allocateRefunds splits a return's refund across the payments the order was paid with,
+// giving no payment back more than it took. Cash goes first, since it is handed back over
+// the counter there and then; card and M-Pesa follow, latest payment first. Orders paid
+// before payments were recorded are refunded to their payment method.
+func allocateRefunds(
+	order *models.Order,
+	orderReturn *models.OrderReturn,
+	payments []*models.Payment,
+	refunded []*models.Refund,
+) ([]*models.Refund, error) {
+
+	refundedByPayment := make(map[int64]custom_types.Money, len(payments))
+	for _, refund := range refunded {
+		if refund.PaymentID == nil {
+			continue
+		}
+
+		total, err := refundedByPayment[*refund.PaymentID].Add(refund.Amount)
+		if err != nil {
+			return nil, apperr.NewError(err)
+		}
+
+		refundedByPayment[*refund.PaymentID] = total
+	}
+
+	refunds := make([]*models.Refund, 0, 1)
+	remaining := orderReturn.RefundAmount
+
+	for _, cash := range []bool{true, false} {
+		for i := len(payments) - 1; i >= 0 && remaining.Amount > 0; i-- {
+			payment := payments[i]
+			if payment.PaymentStatus != custom_types.PaymentStatusCompleted || (payment.PaymentMethod == custom_types.PaymentMethodCash) != cash {
+				continue
+			}
+
+			refundable, err := payment.Amount.Sub(refundedByPayment[payment.ID])
+			if err != nil {
+				return nil, apperr.NewError(err)
+			}
+
+			if refundable.Amount <= 0 {
+				continue
+			}
+
+			amount := remaining
+			if refundable.Amount < amount.Amount {
+				amount = refundable
+			}
+
+			refunds = append(refunds, newRefund(order, orderReturn, payment.PaymentMethod, null.NullValue(payment.ID), amount))
+			remaining, err = remaining.Sub(amount)
+			if err != nil {
+				return nil, apperr.NewError(err)
+			}
+		}
+	}
+
+	if remaining.Amount > 0 || len(refunds) == 0 {
+		refunds = append(refunds, newRefund(order, orderReturn, order.PaymentMethod, nil, remaining))
+	}
+
+	return refunds, nil
+}
+
+func newRefund(
+	order *models.Order,
+	orderReturn *models.OrderReturn,
+	paymentMethod custom_types.PaymentMethod,
+	paymentID *int64,
+	amount custom_types.Money,
+) *models.Refund {
+
+	refundStatus := custom_types.RefundStatusPending
+	if paymentMethod == custom_types.PaymentMethodCash {
+		// cash is handed back over the counter when the return is taken
+		refundStatus = custom_types.RefundStatusCompleted
+	}
+
+	return &models.Refund{
+		OrderID:       order.ID,
+		OrderReturnID: orderReturn.ID,
+		PaymentID:     paymentID,
+		Amount:        amount,
+		PaymentMethod: paymentMethod,
+		RefundStatus:  refundStatus,
+	}
+}
+
+// returnQuantities works out how many units of each order item come back, keyed by order item id.
+func returnQuantities(
+	orderItems []*models.OrderItem,
+	forms []dtos.OrderReturnItemForm,
+) (map[int64]int64, error) {
+
+	quantities := make(map[int64]int64, len(orderItems))
+
+	if len(forms) == 0 {
+		for _, orderItem := range orderItems {
+			if outstanding := orderItem.Quantity - orderItem.ReturnedQuantity; outstanding > 0 {
+				quantities[orderItem.ID] = outstanding
+			}
+		}
+	}
+
+	itemsByID := make(map[int64]*models.OrderItem, len(orderItems))
+	for _, orderItem := range orderItems {
+		itemsByID[orderItem.ID] = orderItem
+	}
+
+	for _, form := range forms {
+		orderItem, ok := itemsByID[form.OrderItemID]
+		if !ok {
+			return nil, apperr.NewBadRequest(fmt.Sprintf("order item [%d] does not belong to this order", form.OrderItemID))
+		}
+
+		if form.Quantity <= 0 {
+			return nil, apperr.NewBadRequest(fmt.Sprintf("return quantity for order item [%d] must be greater than zero", form.OrderItemID))
+		}
+
+		quantities[orderItem.ID] += form.Quantity
+
+		outstanding := orderItem.Quantity - orderItem.ReturnedQuantity
+		if quantities[orderItem.ID] > outstanding {
+			return nil, apperr.NewBadRequest(fmt.Sprintf(
+				"order item [%d] has %d unit(s) left to return, requested %d",
+				orderItem.ID,
+				outstanding,
+				quantities[orderItem.ID],
+			))
+		}
+	}
+
+	if len(quantities) == 0 {
+		return nil, apperr.NewBadRequest("nothing left to return on this order")
+	}
+
+	return quantities, nil
+}
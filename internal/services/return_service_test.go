@@ -0,0 +1,460 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+// paidOrder is order 1, paid for three units of goods (product 10) carrying a share of
+// the order discount and VAT, and one service (product 20).
+func paidOrder(paymentMethod custom_types.PaymentMethod) (*models.Order, []*models.OrderItem) {
+	order := &models.Order{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+		ShopID:               "shop-1",
+		ReferenceNumber:      "ORD-2025-000001",
+		Subtotal:             custom_types.NewMoney(35000),
+		Discount:             custom_types.NewMoney(1000),
+		TaxTotal:             custom_types.NewMoney(4690),
+		TotalAmount:          custom_types.NewMoney(34000),
+		PaymentMethod:        paymentMethod,
+		OrderStatus:          custom_types.OrderStatusPaid,
+	}
+
+	orderItems := []*models.OrderItem{
+		{
+			SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+			OrderID:              1,
+			ProductID:            10,
+			UnitPrice:            custom_types.NewMoney(10000),
+			Quantity:             3,
+			OrderDiscount:        custom_types.NewMoney(1000),
+			TaxAmount:            custom_types.NewMoney(4000),
+			TotalAmount:          custom_types.NewMoney(29000),
+		},
+		{
+			SequentialIdentifier: custom_types.SequentialIdentifier{ID: 2},
+			OrderID:              1,
+			ProductID:            20,
+			UnitPrice:            custom_types.NewMoney(5000),
+			Quantity:             1,
+			TaxAmount:            custom_types.NewMoney(690),
+			TotalAmount:          custom_types.NewMoney(5000),
+		},
+	}
+
+	return order, orderItems
+}
+
+func goodsProduct() *models.Product {
+	return &models.Product{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 10}, ShopID: "shop-1", ProductType: custom_types.ProductTypeGoods}
+}
+
+func serviceProduct() *models.Product {
+	return &models.Product{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 20}, ShopID: "shop-1", ProductType: custom_types.ProductTypeService}
+}
+
+func TestCreateOrderReturn_RestocksAndRefundsTheReturnedShare(t *testing.T) {
+	ctx := context.Background()
+	mockStock := new(MockStockService)
+	mockOutbox := new(MockNotificationOutboxService)
+	mockOrders := new(MockOrderDomain)
+	mockOrderItems := new(MockOrderItemDomain)
+	mockProducts := new(MockProductDomain)
+	mockOrderReturns := new(MockOrderReturnDomain)
+	mockRefunds := new(MockRefundDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{
+		OrderDomain:       mockOrders,
+		OrderItemDomain:   mockOrderItems,
+		ProductDomain:     mockProducts,
+		OrderReturnDomain: mockOrderReturns,
+		RefundDomain:      mockRefunds,
+		PaymentDomain:     mockPayments,
+	}
+	returnService := services.NewReturnService(mockStock, store, mockOutbox)
+
+	order, orderItems := paidOrder(custom_types.PaymentMethodMpesa)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockOrderItems.On("LockOrderItems", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(orderItems, nil)
+	mockOrderItems.On("UpdateReturnedQuantity", ctx, mock.Anything, orderItems[0]).
+		Return(nil)
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(10)).
+		Return(goodsProduct(), nil)
+	mockStock.On("ApplyStockMovements", ctx, mock.Anything, "shop-1", mock.MatchedBy(func(movements []*models.StockMovement) bool {
+		return len(movements) == 1 &&
+			movements[0].ProductID == 10 &&
+			movements[0].MovementType == custom_types.StockMovementTypeReturn &&
+			movements[0].Quantity == 1 &&
+			*movements[0].OrderID == 1 &&
+			movements[0].Reason == "returned on order ORD-2025-000001: damaged" &&
+			movements[0].Actor == "cashier@example.com"
+	})).Return(nil)
+	mockOrderReturns.On("CreateOrderReturn", ctx, mock.Anything, mock.AnythingOfType("*models.OrderReturn")).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(*models.OrderReturn).ID = 5
+		}).Return(nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{payment(3, custom_types.PaymentMethodMpesa, 34000, custom_types.PaymentStatusCompleted)}, nil)
+	mockRefunds.On("RefundsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Refund{}, nil)
+	// the money goes back the way it came, once the M-Pesa reversal is made
+	mockRefunds.On("CreateRefund", ctx, mock.Anything, mock.MatchedBy(func(refund *models.Refund) bool {
+		return refund.OrderReturnID == 5 &&
+			*refund.PaymentID == 3 &&
+			refund.Amount == custom_types.NewMoney(9667) &&
+			refund.PaymentMethod == custom_types.PaymentMethodMpesa &&
+			refund.RefundStatus == custom_types.RefundStatusPending
+	})).Return(nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockOutbox.On("EnqueueOrderReturn", ctx, mock.Anything, order, mock.AnythingOfType("*models.OrderReturn")).
+		Return(nil)
+
+	orderReturn, err := returnService.CreateOrderReturn(ctx, &inlineDB{}, "shop-1", 1, &dtos.CreateOrderReturnForm{
+		Reason: "damaged",
+		Items:  []dtos.OrderReturnItemForm{{OrderItemID: 1, Quantity: 1}},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+
+	// one of three units takes back a third of the line, VAT included
+	assert.Len(t, orderReturn.Items, 1)
+	assert.Equal(t, int64(1), orderReturn.Items[0].Quantity)
+	assert.Equal(t, custom_types.NewMoney(9667), orderReturn.Items[0].RefundAmount)
+	assert.Equal(t, custom_types.NewMoney(1333), orderReturn.Items[0].TaxAmount)
+	assert.Equal(t, custom_types.NewMoney(9667), orderReturn.RefundAmount)
+	assert.Equal(t, int64(1), orderItems[0].ReturnedQuantity)
+
+	assert.Equal(t, custom_types.OrderStatusPaid, order.OrderStatus)
+	assert.Equal(t, custom_types.NewMoney(25000), order.Subtotal)
+	assert.Equal(t, custom_types.NewMoney(667), order.Discount)
+	assert.Equal(t, custom_types.NewMoney(3357), order.TaxTotal)
+	assert.Equal(t, custom_types.NewMoney(24333), order.TotalAmount)
+
+	mockStock.AssertExpectations(t)
+	mockOrderItems.AssertExpectations(t)
+	mockOrderReturns.AssertExpectations(t)
+	mockRefunds.AssertExpectations(t)
+	mockOrders.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestCreateOrderReturn_ReturnsTheRestToTheCent(t *testing.T) {
+	ctx := context.Background()
+	mockStock := new(MockStockService)
+	mockOutbox := new(MockNotificationOutboxService)
+	mockOrders := new(MockOrderDomain)
+	mockOrderItems := new(MockOrderItemDomain)
+	mockProducts := new(MockProductDomain)
+	mockOrderReturns := new(MockOrderReturnDomain)
+	mockRefunds := new(MockRefundDomain)
+	mockPayments := new(MockPaymentDomain)
+	mockTransitions := new(MockOrderStatusTransitionDomain)
+	store := &domain.Store{
+		OrderDomain:                 mockOrders,
+		OrderItemDomain:             mockOrderItems,
+		ProductDomain:               mockProducts,
+		OrderReturnDomain:           mockOrderReturns,
+		RefundDomain:                mockRefunds,
+		PaymentDomain:               mockPayments,
+		OrderStatusTransitionDomain: mockTransitions,
+	}
+	returnService := services.NewReturnService(mockStock, store, mockOutbox)
+
+	// one of the three units has already come back
+	order, orderItems := paidOrder(custom_types.PaymentMethodMpesa)
+	orderItems[0].ReturnedQuantity = 1
+	order.Subtotal = custom_types.NewMoney(25000)
+	order.Discount = custom_types.NewMoney(667)
+	order.TaxTotal = custom_types.NewMoney(3357)
+	order.TotalAmount = custom_types.NewMoney(24333)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockOrderItems.On("LockOrderItems", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(orderItems, nil)
+	mockOrderItems.On("UpdateReturnedQuantity", ctx, mock.Anything, mock.AnythingOfType("*models.OrderItem")).
+		Return(nil).Twice()
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(10)).
+		Return(goodsProduct(), nil)
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(20)).
+		Return(serviceProduct(), nil)
+	// the service line carries no stock
+	mockStock.On("ApplyStockMovements", ctx, mock.Anything, "shop-1", mock.MatchedBy(func(movements []*models.StockMovement) bool {
+		return len(movements) == 1 && movements[0].ProductID == 10 && movements[0].Quantity == 2
+	})).Return(nil)
+	mockOrderReturns.On("CreateOrderReturn", ctx, mock.Anything, mock.AnythingOfType("*models.OrderReturn")).
+		Return(nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{payment(3, custom_types.PaymentMethodMpesa, 34000, custom_types.PaymentStatusCompleted)}, nil)
+	mockRefunds.On("RefundsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Refund{{OrderReturnID: 5, PaymentID: null.NullValue(int64(3)), Amount: custom_types.NewMoney(9667)}}, nil)
+	mockRefunds.On("CreateRefund", ctx, mock.Anything, mock.MatchedBy(func(refund *models.Refund) bool {
+		return *refund.PaymentID == 3 && refund.Amount == custom_types.NewMoney(24333)
+	})).Return(nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockTransitions.On("CreateOrderStatusTransition", ctx, mock.Anything, mock.MatchedBy(func(transition *models.OrderStatusTransition) bool {
+		return transition.ToStatus == custom_types.OrderStatusReturned && *transition.Reason == "changed their mind"
+	})).Return(nil)
+	mockOutbox.On("EnqueueOrderReturn", ctx, mock.Anything, order, mock.AnythingOfType("*models.OrderReturn")).
+		Return(nil)
+
+	// leaving the items out returns everything still outstanding
+	orderReturn, err := returnService.CreateOrderReturn(ctx, &inlineDB{}, "shop-1", 1, &dtos.CreateOrderReturnForm{
+		Reason: "changed their mind",
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Len(t, orderReturn.Items, 2)
+	assert.Equal(t, int64(2), orderReturn.Items[0].Quantity)
+	assert.Equal(t, custom_types.NewMoney(19333), orderReturn.Items[0].RefundAmount)
+	assert.Equal(t, custom_types.NewMoney(2667), orderReturn.Items[0].TaxAmount)
+	assert.Equal(t, custom_types.NewMoney(5000), orderReturn.Items[1].RefundAmount)
+	assert.Equal(t, custom_types.NewMoney(24333), orderReturn.RefundAmount)
+
+	assert.Equal(t, custom_types.OrderStatusReturned, order.OrderStatus)
+	assert.True(t, order.Subtotal.IsZero())
+	assert.True(t, order.Discount.IsZero())
+	assert.True(t, order.TaxTotal.IsZero())
+	assert.True(t, order.TotalAmount.IsZero())
+
+	mockStock.AssertExpectations(t)
+	mockOrderItems.AssertExpectations(t)
+	mockRefunds.AssertExpectations(t)
+	mockTransitions.AssertExpectations(t)
+}
+
+func TestCreateOrderReturn_OrdersWithoutPaymentsAreRefundedToTheirPaymentMethod(t *testing.T) {
+	ctx := context.Background()
+	mockStock := new(MockStockService)
+	mockOutbox := new(MockNotificationOutboxService)
+	mockOrders := new(MockOrderDomain)
+	mockOrderItems := new(MockOrderItemDomain)
+	mockProducts := new(MockProductDomain)
+	mockOrderReturns := new(MockOrderReturnDomain)
+	mockRefunds := new(MockRefundDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{
+		OrderDomain:       mockOrders,
+		OrderItemDomain:   mockOrderItems,
+		ProductDomain:     mockProducts,
+		OrderReturnDomain: mockOrderReturns,
+		RefundDomain:      mockRefunds,
+		PaymentDomain:     mockPayments,
+	}
+	returnService := services.NewReturnService(mockStock, store, mockOutbox)
+
+	order, orderItems := paidOrder(custom_types.PaymentMethodCash)
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockOrderItems.On("LockOrderItems", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(orderItems, nil)
+	mockOrderItems.On("UpdateReturnedQuantity", ctx, mock.Anything, orderItems[1]).
+		Return(nil)
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(20)).
+		Return(serviceProduct(), nil)
+	mockStock.On("ApplyStockMovements", ctx, mock.Anything, "shop-1", []*models.StockMovement{}).
+		Return(nil)
+	mockOrderReturns.On("CreateOrderReturn", ctx, mock.Anything, mock.AnythingOfType("*models.OrderReturn")).
+		Return(nil)
+	// paid before payments were recorded
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{}, nil)
+	mockRefunds.On("RefundsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Refund{}, nil)
+	// cash is handed back at once
+	mockRefunds.On("CreateRefund", ctx, mock.Anything, mock.MatchedBy(func(refund *models.Refund) bool {
+		return refund.PaymentID == nil &&
+			refund.Amount == custom_types.NewMoney(5000) &&
+			refund.PaymentMethod == custom_types.PaymentMethodCash &&
+			refund.RefundStatus == custom_types.RefundStatusCompleted
+	})).Return(nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockOutbox.On("EnqueueOrderReturn", ctx, mock.Anything, order, mock.AnythingOfType("*models.OrderReturn")).
+		Return(nil)
+
+	_, err := returnService.CreateOrderReturn(ctx, &inlineDB{}, "shop-1", 1, &dtos.CreateOrderReturnForm{
+		Reason: "damaged",
+		Items:  []dtos.OrderReturnItemForm{{OrderItemID: 2, Quantity: 1}},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	mockStock.AssertExpectations(t)
+	mockRefunds.AssertExpectations(t)
+}
+
+func TestCreateOrderReturn_SplitsTheRefundAcrossThePayments(t *testing.T) {
+	ctx := context.Background()
+	mockStock := new(MockStockService)
+	mockOutbox := new(MockNotificationOutboxService)
+	mockOrders := new(MockOrderDomain)
+	mockOrderItems := new(MockOrderItemDomain)
+	mockProducts := new(MockProductDomain)
+	mockOrderReturns := new(MockOrderReturnDomain)
+	mockRefunds := new(MockRefundDomain)
+	mockPayments := new(MockPaymentDomain)
+	store := &domain.Store{
+		OrderDomain:       mockOrders,
+		OrderItemDomain:   mockOrderItems,
+		ProductDomain:     mockProducts,
+		OrderReturnDomain: mockOrderReturns,
+		RefundDomain:      mockRefunds,
+		PaymentDomain:     mockPayments,
+	}
+	returnService := services.NewReturnService(mockStock, store, mockOutbox)
+
+	// M-Pesa first, then cash for the rest; 40.00 of the cash has already been handed back
+	order, orderItems := paidOrder(custom_types.PaymentMethodMpesa)
+	mpesa := payment(3, custom_types.PaymentMethodMpesa, 20000, custom_types.PaymentStatusCompleted)
+	failed := payment(4, custom_types.PaymentMethodCard, 14000, custom_types.PaymentStatusFailed)
+	cash := payment(5, custom_types.PaymentMethodCash, 14000, custom_types.PaymentStatusCompleted)
+
+	var refunds []*models.Refund
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockOrderItems.On("LockOrderItems", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(orderItems, nil)
+	mockOrderItems.On("UpdateReturnedQuantity", ctx, mock.Anything, orderItems[0]).
+		Return(nil)
+	mockProducts.On("ProductByID", ctx, mock.Anything, "shop-1", int64(10)).
+		Return(goodsProduct(), nil)
+	mockStock.On("ApplyStockMovements", ctx, mock.Anything, "shop-1", mock.AnythingOfType("[]*models.StockMovement")).
+		Return(nil)
+	mockOrderReturns.On("CreateOrderReturn", ctx, mock.Anything, mock.AnythingOfType("*models.OrderReturn")).
+		Return(nil)
+	mockPayments.On("PaymentsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Payment{mpesa, failed, cash}, nil)
+	mockRefunds.On("RefundsByOrderID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return([]*models.Refund{{PaymentID: null.NullValue(int64(5)), Amount: custom_types.NewMoney(4000)}}, nil)
+	mockRefunds.On("CreateRefund", ctx, mock.Anything, mock.AnythingOfType("*models.Refund")).
+		Run(func(args mock.Arguments) {
+			refunds = append(refunds, args.Get(2).(*models.Refund))
+		}).Return(nil)
+	mockOrders.On("CreateOrder", ctx, mock.Anything, order).
+		Return(nil)
+	mockOutbox.On("EnqueueOrderReturn", ctx, mock.Anything, order, mock.AnythingOfType("*models.OrderReturn")).
+		Return(nil)
+
+	orderReturn, err := returnService.CreateOrderReturn(ctx, &inlineDB{}, "shop-1", 1, &dtos.CreateOrderReturnForm{
+		Reason: "damaged",
+		Items:  []dtos.OrderReturnItemForm{{OrderItemID: 1, Quantity: 3}},
+	}, "cashier@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.NewMoney(29000), orderReturn.RefundAmount)
+	assert.Equal(t, orderReturn.Refunds, refunds)
+	assert.Len(t, refunds, 2)
+
+	// what is left of the cash goes back over the counter, and M-Pesa reverses the rest
+	assert.Equal(t, int64(5), *refunds[0].PaymentID)
+	assert.Equal(t, custom_types.NewMoney(10000), refunds[0].Amount)
+	assert.Equal(t, custom_types.PaymentMethodCash, refunds[0].PaymentMethod)
+	assert.Equal(t, custom_types.RefundStatusCompleted, refunds[0].RefundStatus)
+
+	assert.Equal(t, int64(3), *refunds[1].PaymentID)
+	assert.Equal(t, custom_types.NewMoney(19000), refunds[1].Amount)
+	assert.Equal(t, custom_types.PaymentMethodMpesa, refunds[1].PaymentMethod)
+	assert.Equal(t, custom_types.RefundStatusPending, refunds[1].RefundStatus)
+}
+
+func TestCreateOrderReturn_RejectsWhatCannotComeBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		returned int64
+		items    []dtos.OrderReturnItemForm
+	}{
+		// three were sold and two are back, so only one is left to return
+		{"more than is left", 2, []dtos.OrderReturnItemForm{{OrderItemID: 1, Quantity: 2}}},
+		{"repeated lines add up to more than is left", 2, []dtos.OrderReturnItemForm{{OrderItemID: 1, Quantity: 1}, {OrderItemID: 1, Quantity: 1}}},
+		{"item from another order", 0, []dtos.OrderReturnItemForm{{OrderItemID: 9, Quantity: 1}}},
+		{"zero quantity", 0, []dtos.OrderReturnItemForm{{OrderItemID: 1, Quantity: 0}}},
+		{"already fully returned", 3, []dtos.OrderReturnItemForm{{OrderItemID: 1, Quantity: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockOrders := new(MockOrderDomain)
+			mockOrderItems := new(MockOrderItemDomain)
+			mockOrderReturns := new(MockOrderReturnDomain)
+			store := &domain.Store{OrderDomain: mockOrders, OrderItemDomain: mockOrderItems, OrderReturnDomain: mockOrderReturns}
+			returnService := services.NewReturnService(new(MockStockService), store, new(MockNotificationOutboxService))
+
+			order, orderItems := paidOrder(custom_types.PaymentMethodCard)
+			orderItems[0].ReturnedQuantity = tt.returned
+
+			mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(order, nil)
+			mockOrderItems.On("LockOrderItems", ctx, mock.Anything, "shop-1", int64(1)).
+				Return(orderItems, nil)
+
+			_, err := returnService.CreateOrderReturn(ctx, &inlineDB{}, "shop-1", 1, &dtos.CreateOrderReturnForm{
+				Reason: "damaged",
+				Items:  tt.items,
+			}, "cashier@example.com")
+
+			assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+			assert.Equal(t, tt.returned, orderItems[0].ReturnedQuantity)
+			mockOrderItems.AssertNotCalled(t, "UpdateReturnedQuantity", mock.Anything, mock.Anything, mock.Anything)
+			mockOrderReturns.AssertNotCalled(t, "CreateOrderReturn", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateOrderReturn_NothingLeftToReturn(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockOrderItems := new(MockOrderItemDomain)
+	store := &domain.Store{OrderDomain: mockOrders, OrderItemDomain: mockOrderItems}
+	returnService := services.NewReturnService(new(MockStockService), store, new(MockNotificationOutboxService))
+
+	order, orderItems := paidOrder(custom_types.PaymentMethodCard)
+	orderItems[0].ReturnedQuantity = 3
+	orderItems[1].ReturnedQuantity = 1
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+	mockOrderItems.On("LockOrderItems", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(orderItems, nil)
+
+	_, err := returnService.CreateOrderReturn(ctx, &inlineDB{}, "shop-1", 1, &dtos.CreateOrderReturnForm{Reason: "damaged"}, "cashier@example.com")
+
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+}
+
+func TestCreateOrderReturn_OnlyPaidOrders(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockOrderItems := new(MockOrderItemDomain)
+	store := &domain.Store{OrderDomain: mockOrders, OrderItemDomain: mockOrderItems}
+	returnService := services.NewReturnService(new(MockStockService), store, new(MockNotificationOutboxService))
+
+	order, _ := paidOrder(custom_types.PaymentMethodCard)
+	order.OrderStatus = custom_types.OrderStatusPending
+
+	mockOrders.On("LockOrderByID", ctx, mock.Anything, "shop-1", int64(1)).
+		Return(order, nil)
+
+	_, err := returnService.CreateOrderReturn(ctx, &inlineDB{}, "shop-1", 1, &dtos.CreateOrderReturnForm{Reason: "damaged"}, "cashier@example.com")
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockOrderItems.AssertNotCalled(t, "LockOrderItems", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
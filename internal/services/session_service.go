@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/auth"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+	"time"
+)
+
+const (
+	sessionRevokedByLogout     = "logout"
+	sessionRevokedByTokenReuse = "refresh token reused"
+	refreshTokenBytes          = 32
+	sessionTokenType           = "Bearer"
+)
+
+type (
+	SessionService interface {
+		StartSession(ctx context.Context, dB db.DB, claims *auth.Claims) (*models.SessionTokens, error)
+		RefreshSession(ctx context.Context, dB db.DB, refreshToken string) (*models.SessionTokens, error)
+		RevokeSession(ctx context.Context, dB db.DB, sessionID int64) error
+		SessionActive(ctx context.Context, dB db.DB, sessionID int64) (bool, error)
+	}
+
+	sessionService struct {
+		jwtService      auth.JWTService
+		refreshTokenTTL time.Duration
+		store           *domain.Store
+		now             func() time.Time
+	}
+)
+
+func NewSessionService(
+	jwtService auth.JWTService,
+	refreshTokenTTL time.Duration,
+	store *domain.Store,
+) SessionService {
+	return &sessionService{
+		jwtService:      jwtService,
+		refreshTokenTTL: refreshTokenTTL,
+		store:           store,
+		now:             time.Now,
+	}
+}
+
+// StartSession records a login and hands out its first access and refresh tokens.
+func (s *sessionService) StartSession(
+	ctx context.Context,
+	dB db.DB,
+	claims *auth.Claims,
+) (*models.SessionTokens, error) {
+
+	session := &models.AuthSession{
+		Subject: claims.Sub,
+		Email:   claims.Email,
+		Name:    claims.Name,
+	}
+
+	var sessionTokens *models.SessionTokens
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		err := s.store.AuthSessionDomain.CreateAuthSession(ctx, operations, session)
+		if err != nil {
+			return err
+		}
+
+		sessionTokens, err = s.issueTokens(ctx, operations, session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionTokens, nil
+}
+
+// RefreshSession swaps a refresh token for a new access token and a new refresh token.
+// Each refresh token works once; presenting one again means it has leaked, so the whole
+// session is revoked and whoever holds its other tokens has to log in again.
+func (s *sessionService) RefreshSession(
+	ctx context.Context,
+	dB db.DB,
+	refreshToken string,
+) (*models.SessionTokens, error) {
+
+	refreshToken = strings.TrimSpace(refreshToken)
+	if refreshToken == "" {
+		return nil, apperr.NewBadRequest("refresh token is required")
+	}
+
+	var (
+		sessionTokens *models.SessionTokens
+		reused        bool
+	)
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		storedToken, err := s.store.AuthSessionDomain.LockRefreshTokenByHash(ctx, operations, hashRefreshToken(refreshToken))
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewAuthorization("invalid refresh token")
+			}
+			return err
+		}
+
+		session, err := s.store.AuthSessionDomain.LockAuthSessionByID(ctx, operations, storedToken.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if session.IsRevoked() {
+			return apperr.NewAuthorization("session has been revoked")
+		}
+
+		if storedToken.UsedAt != nil {
+			// revoke here and report it after the commit, so the revocation sticks
+			loggers.Warnf("refresh token of session [%d] was used twice; revoking the session", session.ID)
+			reused = true
+			return s.revoke(ctx, operations, session, sessionRevokedByTokenReuse)
+		}
+
+		if !s.now().Before(storedToken.ExpiresAt) {
+			return apperr.NewAuthorization("refresh token has expired")
+		}
+
+		storedToken.UsedAt = null.NullValue(s.now())
+
+		err = s.store.AuthSessionDomain.CreateRefreshToken(ctx, operations, storedToken)
+		if err != nil {
+			return err
+		}
+
+		sessionTokens, err = s.issueTokens(ctx, operations, session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused {
+		return nil, apperr.NewAuthorization("refresh token has already been used; the session has been revoked")
+	}
+
+	return sessionTokens, nil
+}
+
+// RevokeSession logs a session out. Its refresh tokens stop working at once, and so do
+// its access tokens, which are checked against the session on every request.
+func (s *sessionService) RevokeSession(
+	ctx context.Context,
+	dB db.DB,
+	sessionID int64,
+) error {
+
+	return dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		session, err := s.store.AuthSessionDomain.LockAuthSessionByID(ctx, operations, sessionID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewNotFound("session", fmt.Sprint(sessionID))
+			}
+			return err
+		}
+
+		if session.IsRevoked() {
+			return nil
+		}
+
+		return s.revoke(ctx, operations, session, sessionRevokedByLogout)
+	})
+}
+
+func (s *sessionService) SessionActive(
+	ctx context.Context,
+	dB db.DB,
+	sessionID int64,
+) (bool, error) {
+
+	session, err := s.store.AuthSessionDomain.AuthSessionByID(ctx, dB, sessionID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return !session.IsRevoked(), nil
+}
+
+func (s *sessionService) revoke(
+	ctx context.Context,
+	operations db.SQLOperations,
+	session *models.AuthSession,
+	reason string,
+) error {
+
+	session.RevokedAt = null.NullValue(s.now())
+	session.RevokedReason = null.NullValue(reason)
+
+	return s.store.AuthSessionDomain.CreateAuthSession(ctx, operations, session)
+}
+
+func (s *sessionService) issueTokens(
+	ctx context.Context,
+	operations db.SQLOperations,
+	session *models.AuthSession,
+) (*models.SessionTokens, error) {
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, apperr.NewInternal("failed to generate refresh token")
+	}
+
+	storedToken := &models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: s.now().Add(s.refreshTokenTTL),
+	}
+
+	err = s.store.AuthSessionDomain.CreateRefreshToken(ctx, operations, storedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		Email:     session.Email,
+		Name:      session.Name,
+		Sub:       session.Subject,
+		SessionID: session.ID,
+	}
+
+	accessToken, accessTokenExpiresAt, err := s.jwtService.SignToken(claims)
+	if err != nil {
+		loggers.Errorf("failed to sign access token for session [%d]: [%+v]", session.ID, err)
+		return nil, apperr.NewInternal("failed to generate token")
+	}
+
+	return &models.SessionTokens{
+		TokenType:             sessionTokenType,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessTokenExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: storedToken.ExpiresAt,
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken is what is stored and looked up, so a leaked table holds no usable tokens.
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,247 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/auth"
+	"github/Doris-Mwito5/savannah-pos/config"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func newTestJWTService(t *testing.T) auth.JWTService {
+	jwtService, err := auth.NewJWTService(&config.JWTConfig{
+		Issuer:         "savannah-pos",
+		Audience:       "savannah-pos-api",
+		SigningKeyID:   "k1",
+		Keys:           map[string]string{"k1": "test-secret"},
+		AccessTokenTTL: 15 * time.Minute,
+	})
+	assert.NoError(t, err)
+
+	return jwtService
+}
+
+func TestStartSession_Success(t *testing.T) {
+	ctx := context.Background()
+	jwtService := newTestJWTService(t)
+	mockSessions := new(MockAuthSessionDomain)
+	store := &domain.Store{AuthSessionDomain: mockSessions}
+	service := services.NewSessionService(jwtService, 24*time.Hour, store)
+
+	var storedToken *models.RefreshToken
+
+	mockSessions.On("CreateAuthSession", ctx, mock.Anything, mock.AnythingOfType("*models.AuthSession")).
+		Run(func(args mock.Arguments) { args.Get(2).(*models.AuthSession).ID = 1 }).
+		Return(nil)
+	mockSessions.On("CreateRefreshToken", ctx, mock.Anything, mock.AnythingOfType("*models.RefreshToken")).
+		Run(func(args mock.Arguments) { storedToken = args.Get(2).(*models.RefreshToken) }).
+		Return(nil)
+
+	sessionTokens, err := service.StartSession(ctx, &inlineDB{}, &auth.Claims{
+		Email: "cashier@example.com",
+		Name:  "Cashier",
+		Sub:   "google-7",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer", sessionTokens.TokenType)
+	assert.NotEmpty(t, sessionTokens.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), sessionTokens.RefreshTokenExpiresAt, time.Minute)
+
+	claims, err := jwtService.VerifyToken(ctx, sessionTokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), claims.SessionID)
+	assert.Equal(t, "google-7", claims.Sub)
+
+	// only the hash is kept
+	assert.Equal(t, int64(1), storedToken.SessionID)
+	assert.NotEmpty(t, storedToken.TokenHash)
+	assert.NotEqual(t, sessionTokens.RefreshToken, storedToken.TokenHash)
+
+	mockSessions.AssertExpectations(t)
+}
+
+func TestRefreshSession_Rotates(t *testing.T) {
+	ctx := context.Background()
+	mockSessions := new(MockAuthSessionDomain)
+	store := &domain.Store{AuthSessionDomain: mockSessions}
+	service := services.NewSessionService(newTestJWTService(t), 24*time.Hour, store)
+
+	session := &models.AuthSession{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, Subject: "google-7"}
+	storedToken := &models.RefreshToken{SessionID: 1, TokenHash: "hash-1", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockSessions.On("LockRefreshTokenByHash", ctx, mock.Anything, mock.AnythingOfType("string")).
+		Return(storedToken, nil)
+	mockSessions.On("LockAuthSessionByID", ctx, mock.Anything, int64(1)).
+		Return(session, nil)
+	mockSessions.On("CreateRefreshToken", ctx, mock.Anything, storedToken).
+		Return(nil).Once()
+	mockSessions.On("CreateRefreshToken", ctx, mock.Anything, mock.MatchedBy(func(refreshToken *models.RefreshToken) bool {
+		return refreshToken.TokenHash != "hash-1"
+	})).Return(nil).Once()
+
+	sessionTokens, err := service.RefreshSession(ctx, &inlineDB{}, "refresh-1")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, sessionTokens.AccessToken)
+	assert.NotEqual(t, "refresh-1", sessionTokens.RefreshToken)
+	assert.NotNil(t, storedToken.UsedAt)
+	mockSessions.AssertExpectations(t)
+}
+
+func TestRefreshSession_ReuseRevokesTheSession(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockSessions := new(MockAuthSessionDomain)
+	store := &domain.Store{AuthSessionDomain: mockSessions}
+	service := services.NewSessionService(newTestJWTService(t), 24*time.Hour, store)
+
+	session := &models.AuthSession{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, Subject: "google-7"}
+
+	// the token was swapped already and turns up again, e.g. replayed by whoever copied it
+	mockSessions.On("LockRefreshTokenByHash", ctx, mock.Anything, mock.AnythingOfType("string")).
+		Return(&models.RefreshToken{SessionID: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: null.NullValue(time.Now())}, nil)
+	mockSessions.On("LockAuthSessionByID", ctx, mock.Anything, int64(1)).
+		Return(session, nil)
+	mockSessions.On("CreateAuthSession", ctx, mock.Anything, session).
+		Return(nil)
+
+	_, err := service.RefreshSession(ctx, &inlineDB{}, "refresh-1")
+
+	assert.Equal(t, apperr.Authorization, apperr.NewError(err).Type)
+	assert.True(t, session.IsRevoked())
+	assert.Equal(t, "refresh token reused", *session.RevokedReason)
+	mockSessions.AssertNotCalled(t, "CreateRefreshToken", mock.Anything, mock.Anything, mock.Anything)
+	mockSessions.AssertExpectations(t)
+}
+
+func TestRefreshSession_Rejects(t *testing.T) {
+	revoked := &models.AuthSession{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 2},
+		RevokedAt:            null.NullValue(time.Now()),
+		RevokedReason:        null.NullValue("logout"),
+	}
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		storedToken  *models.RefreshToken
+		errType      apperr.Type
+	}{
+		{"no token", " ", nil, apperr.BadRequest},
+		{"unknown token", "not-a-refresh-token", nil, apperr.Authorization},
+		{"expired token", "refresh-1", &models.RefreshToken{SessionID: 1, ExpiresAt: time.Now().Add(-time.Minute)}, apperr.Authorization},
+		{"revoked session", "refresh-2", &models.RefreshToken{SessionID: 2, ExpiresAt: time.Now().Add(time.Hour)}, apperr.Authorization},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockSessions := new(MockAuthSessionDomain)
+			store := &domain.Store{AuthSessionDomain: mockSessions}
+			service := services.NewSessionService(newTestJWTService(t), 24*time.Hour, store)
+
+			if tt.storedToken != nil {
+				mockSessions.On("LockRefreshTokenByHash", ctx, mock.Anything, mock.AnythingOfType("string")).
+					Return(tt.storedToken, nil)
+			} else {
+				mockSessions.On("LockRefreshTokenByHash", ctx, mock.Anything, mock.AnythingOfType("string")).
+					Return(nil, apperr.NewDatabaseError(sql.ErrNoRows)).Maybe()
+			}
+			mockSessions.On("LockAuthSessionByID", ctx, mock.Anything, int64(1)).
+				Return(&models.AuthSession{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}}, nil).Maybe()
+			mockSessions.On("LockAuthSessionByID", ctx, mock.Anything, int64(2)).
+				Return(revoked, nil).Maybe()
+
+			_, err := service.RefreshSession(ctx, &inlineDB{}, tt.refreshToken)
+
+			assert.Equal(t, tt.errType, apperr.NewError(err).Type)
+			// refusing a token is not the same as finding it reused
+			mockSessions.AssertNotCalled(t, "CreateAuthSession", mock.Anything, mock.Anything, mock.Anything)
+			mockSessions.AssertNotCalled(t, "CreateRefreshToken", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRevokeSession_LogsOut(t *testing.T) {
+	ctx := context.Background()
+	mockSessions := new(MockAuthSessionDomain)
+	store := &domain.Store{AuthSessionDomain: mockSessions}
+	service := services.NewSessionService(newTestJWTService(t), 24*time.Hour, store)
+
+	session := &models.AuthSession{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}}
+
+	mockSessions.On("LockAuthSessionByID", ctx, mock.Anything, int64(1)).
+		Return(session, nil)
+	mockSessions.On("CreateAuthSession", ctx, mock.Anything, session).
+		Return(nil)
+
+	err := service.RevokeSession(ctx, &inlineDB{}, 1)
+
+	assert.NoError(t, err)
+	assert.True(t, session.IsRevoked())
+	assert.Equal(t, "logout", *session.RevokedReason)
+	mockSessions.AssertExpectations(t)
+}
+
+func TestRevokeSession_TwiceIsHarmless(t *testing.T) {
+	ctx := context.Background()
+	mockSessions := new(MockAuthSessionDomain)
+	store := &domain.Store{AuthSessionDomain: mockSessions}
+	service := services.NewSessionService(newTestJWTService(t), 24*time.Hour, store)
+
+	mockSessions.On("LockAuthSessionByID", ctx, mock.Anything, int64(1)).
+		Return(&models.AuthSession{
+			SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+			RevokedAt:            null.NullValue(time.Now()),
+			RevokedReason:        null.NullValue("logout"),
+		}, nil)
+
+	err := service.RevokeSession(ctx, &inlineDB{}, 1)
+
+	assert.NoError(t, err)
+	mockSessions.AssertNotCalled(t, "CreateAuthSession", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSessionActive(t *testing.T) {
+	tests := []struct {
+		name     string
+		session  *models.AuthSession
+		err      error
+		expected bool
+	}{
+		{"active", &models.AuthSession{}, nil, true},
+		{"revoked", &models.AuthSession{RevokedAt: null.NullValue(time.Now())}, nil, false},
+		{"unknown", nil, apperr.NewDatabaseError(sql.ErrNoRows), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockSessions := new(MockAuthSessionDomain)
+			store := &domain.Store{AuthSessionDomain: mockSessions}
+			service := services.NewSessionService(newTestJWTService(t), 24*time.Hour, store)
+
+			mockSessions.On("AuthSessionByID", ctx, mock.Anything, int64(1)).
+				Return(tt.session, tt.err)
+
+			active, err := service.SessionActive(ctx, nil, 1)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, active)
+		})
+	}
+}
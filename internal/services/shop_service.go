@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"regexp"
+	"strings"
+)
+
+// shopIDPattern keeps shop ids usable in URLs and headers as they are.
+var shopIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+type (
+	ShopService interface {
+		CreateShop(ctx context.Context, dB db.DB, subject string, form *dtos.CreateShopForm) (*models.Shop, error)
+		UpdateShop(ctx context.Context, dB db.DB, shopID string, form *dtos.UpdateShopForm) (*models.Shop, error)
+		ShopByID(ctx context.Context, dB db.DB, shopID string) (*models.Shop, error)
+		ListUserShops(ctx context.Context, dB db.DB, subject string) ([]*models.Shop, error)
+	}
+
+	shopService struct {
+		store *domain.Store
+	}
+)
+
+func NewShopService(
+	store *domain.Store,
+) ShopService {
+	return &shopService{
+		store: store,
+	}
+}
+
+// CreateShop opens a new shop with whoever created it as its owner.
+func (s *shopService) CreateShop(
+	ctx context.Context,
+	dB db.DB,
+	subject string,
+	form *dtos.CreateShopForm,
+) (*models.Shop, error) {
+
+	shopID := strings.ToLower(strings.TrimSpace(form.ID))
+	if !shopIDPattern.MatchString(shopID) {
+		return nil, apperr.NewBadRequest("shop id must be 2 to 63 lowercase letters, digits or hyphens")
+	}
+
+	name := strings.TrimSpace(form.Name)
+	if name == "" {
+		return nil, apperr.NewBadRequest("shop name is required")
+	}
+
+	currency, err := shopCurrency(form.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	referencePrefix, err := shopReferencePrefix(form.OrderReferencePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	shop := &models.Shop{
+		ID:                   shopID,
+		Name:                 name,
+		Currency:             currency,
+		OrderReferencePrefix: referencePrefix,
+	}
+
+	err = dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		user, err := s.store.UserDomain.LockUserBySubject(ctx, operations, subject)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return apperr.NewAuthorization("log in again before opening a shop")
+			}
+			return err
+		}
+
+		_, err = s.store.ShopDomain.ShopByID(ctx, operations, shopID)
+		if err == nil {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("shop [%s] already exists", shopID),
+				apperr.Conflict,
+			)
+		}
+		if !apperr.IsNoRowsErr(err) {
+			return err
+		}
+
+		err = s.store.ShopDomain.CreateShop(ctx, operations, shop)
+		if err != nil {
+			return err
+		}
+
+		return s.store.ShopMembershipDomain.CreateShopMembership(ctx, operations, &models.ShopMembership{
+			UserID:    user.ID,
+			ShopID:    shop.ID,
+			Role:      custom_types.StaffRoleOwner,
+			CreatedBy: user.Email,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return shop, nil
+}
+
+func (s *shopService) UpdateShop(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	form *dtos.UpdateShopForm,
+) (*models.Shop, error) {
+
+	name := strings.TrimSpace(form.Name)
+	if name == "" {
+		return nil, apperr.NewBadRequest("shop name is required")
+	}
+
+	shop, err := s.ShopByID(ctx, dB, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	shop.Name = name
+
+	shop.Currency, err = shopCurrency(form.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.OrderReferencePrefix != nil {
+		shop.OrderReferencePrefix, err = shopReferencePrefix(*form.OrderReferencePrefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.store.ShopDomain.UpdateShop(ctx, dB, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	return shop, nil
+}
+
+func (s *shopService) ShopByID(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+) (*models.Shop, error) {
+
+	shop, err := s.store.ShopDomain.ShopByID(ctx, dB, shopID)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return nil, apperr.NewNotFound("shop", shopID)
+		}
+		return nil, err
+	}
+
+	return shop, nil
+}
+
+// ListUserShops lists the shops the caller works in.
+func (s *shopService) ListUserShops(
+	ctx context.Context,
+	dB db.DB,
+	subject string,
+) ([]*models.Shop, error) {
+
+	user, err := s.store.UserDomain.UserBySubject(ctx, dB, subject)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return []*models.Shop{}, nil
+		}
+		return nil, err
+	}
+
+	return s.store.ShopDomain.ShopsByUserID(ctx, dB, user.ID)
+}
+
+// shopCurrency checks the currency a shop's form asks for. Amounts are stored without a
+// currency and are always shillings, so KES is the only one a shop can have.
+func shopCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency != "" && currency != custom_types.DefaultCurrency {
+		return "", apperr.NewBadRequest(fmt.Sprintf("unsupported currency [%s], shops trade in %s", currency, custom_types.DefaultCurrency))
+	}
+
+	return custom_types.DefaultCurrency, nil
+}
+
+// shopReferencePrefix is the order reference prefix a shop's form asks for. A blank prefix
+// is allowed and leaves the shop on the default one.
+func shopReferencePrefix(prefix string) (string, error) {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return "", nil
+	}
+
+	if !utils.ValidReferencePrefix(prefix) {
+		return "", apperr.NewBadRequest(fmt.Sprintf("invalid order reference prefix [%s], use 1 to 4 letters or digits", prefix))
+	}
+
+	return prefix, nil
+}
+
+// checkFormShop refuses a form naming a shop other than the caller's. Forms used to carry
+// the shop; it now comes from who is asking, and the field only has to agree with it.
+func checkFormShop(shopID, formShopID string) error {
+	formShopID = strings.TrimSpace(formShopID)
+	if formShopID != "" && formShopID != shopID {
+		return apperr.NewBadRequest(fmt.Sprintf("shop_id [%s] is not the shop you are working in", formShopID))
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func TestCreateShop_CreatorOwnsTheShop(t *testing.T) {
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockShops := new(MockShopDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopDomain: mockShops, ShopMembershipDomain: mockMemberships}
+	service := services.NewShopService(store)
+
+	owner := &models.User{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+		Subject:              null.NullValue("google-1"),
+		Email:                "owner@example.com",
+	}
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "google-1").
+		Return(owner, nil)
+	mockShops.On("ShopByID", ctx, mock.Anything, "duka-1").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockShops.On("CreateShop", ctx, mock.Anything, mock.AnythingOfType("*models.Shop")).
+		Return(nil)
+	mockMemberships.On("CreateShopMembership", ctx, mock.Anything, &models.ShopMembership{
+		UserID:    1,
+		ShopID:    "duka-1",
+		Role:      custom_types.StaffRoleOwner,
+		CreatedBy: "owner@example.com",
+	}).Return(nil)
+
+	shop, err := service.CreateShop(ctx, &inlineDB{}, "google-1", &dtos.CreateShopForm{ID: " Duka-1 ", Name: "Duka Moja", OrderReferencePrefix: " nbo "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "duka-1", shop.ID)
+	assert.Equal(t, "Duka Moja", shop.Name)
+	assert.Equal(t, "KES", shop.Currency)
+	assert.Equal(t, "NBO", shop.OrderReferencePrefix)
+
+	mockUsers.AssertExpectations(t)
+	mockShops.AssertExpectations(t)
+	mockMemberships.AssertExpectations(t)
+}
+
+func TestCreateShop_RefusesATakenID(t *testing.T) {
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockShops := new(MockShopDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopDomain: mockShops, ShopMembershipDomain: mockMemberships}
+	service := services.NewShopService(store)
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "google-1").
+		Return(&models.User{Email: "owner@example.com"}, nil)
+	mockShops.On("ShopByID", ctx, mock.Anything, "duka-1").
+		Return(&models.Shop{ID: "duka-1", Name: "Duka Moja"}, nil)
+
+	_, err := service.CreateShop(ctx, &inlineDB{}, "google-1", &dtos.CreateShopForm{ID: "duka-1", Name: "Duka Mbili"})
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockShops.AssertNotCalled(t, "CreateShop", mock.Anything, mock.Anything, mock.Anything)
+	mockMemberships.AssertNotCalled(t, "CreateShopMembership", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateShop_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		form    *dtos.CreateShopForm
+		errType apperr.Type
+	}{
+		{"id with spaces", "google-1", &dtos.CreateShopForm{ID: "my shop", Name: "Shop"}, apperr.BadRequest},
+		{"id too short", "google-1", &dtos.CreateShopForm{ID: "a", Name: "Shop"}, apperr.BadRequest},
+		{"no name", "google-1", &dtos.CreateShopForm{ID: "duka-1", Name: " "}, apperr.BadRequest},
+		{"bad currency", "google-1", &dtos.CreateShopForm{ID: "duka-1", Name: "Shop", Currency: "shillings"}, apperr.BadRequest},
+		{"currency other than shillings", "google-1", &dtos.CreateShopForm{ID: "duka-1", Name: "Shop", Currency: "UGX"}, apperr.BadRequest},
+		{"bad reference prefix", "google-1", &dtos.CreateShopForm{ID: "duka-1", Name: "Shop", OrderReferencePrefix: "NAIRO"}, apperr.BadRequest},
+		{"never logged in", "google-404", &dtos.CreateShopForm{ID: "duka-1", Name: "Shop"}, apperr.Authorization},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockUsers := new(MockUserDomain)
+			mockShops := new(MockShopDomain)
+			mockMemberships := new(MockShopMembershipDomain)
+			store := &domain.Store{UserDomain: mockUsers, ShopDomain: mockShops, ShopMembershipDomain: mockMemberships}
+			service := services.NewShopService(store)
+
+			mockUsers.On("LockUserBySubject", ctx, mock.Anything, "google-404").
+				Return(nil, apperr.NewDatabaseError(sql.ErrNoRows)).Maybe()
+
+			_, err := service.CreateShop(ctx, &inlineDB{}, tt.subject, tt.form)
+
+			assert.Equal(t, tt.errType, apperr.NewError(err).Type)
+			mockShops.AssertNotCalled(t, "CreateShop", mock.Anything, mock.Anything, mock.Anything)
+			mockMemberships.AssertNotCalled(t, "CreateShopMembership", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUpdateShop_KeepsWhatTheFormLeavesOut(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{ShopDomain: mockShops}
+	service := services.NewShopService(store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "duka-1").
+		Return(&models.Shop{ID: "duka-1", Name: "Duka Moja", Currency: "KES", OrderReferencePrefix: "NBO"}, nil)
+	mockShops.On("UpdateShop", ctx, mock.Anything, mock.AnythingOfType("*models.Shop")).
+		Return(nil)
+
+	shop, err := service.UpdateShop(ctx, nil, "duka-1", &dtos.UpdateShopForm{Name: "Duka Kubwa"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Duka Kubwa", shop.Name)
+	assert.Equal(t, "KES", shop.Currency)
+	assert.Equal(t, "NBO", shop.OrderReferencePrefix)
+	mockShops.AssertExpectations(t)
+}
+
+func TestUpdateShop_ChangesReferencePrefix(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{ShopDomain: mockShops}
+	service := services.NewShopService(store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "duka-1").
+		Return(&models.Shop{ID: "duka-1", Name: "Duka Moja", Currency: "KES", OrderReferencePrefix: "NBO"}, nil)
+	mockShops.On("UpdateShop", ctx, mock.Anything, mock.AnythingOfType("*models.Shop")).
+		Return(nil)
+
+	prefix := "msa"
+	shop, err := service.UpdateShop(ctx, nil, "duka-1", &dtos.UpdateShopForm{Name: "Duka Kubwa", Currency: "kes", OrderReferencePrefix: &prefix})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "KES", shop.Currency)
+	assert.Equal(t, "MSA", shop.OrderReferencePrefix)
+	mockShops.AssertExpectations(t)
+}
+
+func TestUpdateShop_RefusesAnotherCurrency(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{ShopDomain: mockShops}
+	service := services.NewShopService(store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "duka-1").
+		Return(&models.Shop{ID: "duka-1", Name: "Duka Moja", Currency: "KES"}, nil)
+
+	// amounts are stored as shillings, so relabelling them would misstate every total
+	_, err := service.UpdateShop(ctx, nil, "duka-1", &dtos.UpdateShopForm{Name: "Duka Moja", Currency: "tzs"})
+
+	assert.Error(t, err)
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+	mockShops.AssertNotCalled(t, "UpdateShop", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateShop_ClearsTheReferencePrefix(t *testing.T) {
+	ctx := context.Background()
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{ShopDomain: mockShops}
+	service := services.NewShopService(store)
+
+	mockShops.On("ShopByID", ctx, mock.Anything, "duka-1").
+		Return(&models.Shop{ID: "duka-1", Name: "Duka Moja", Currency: "KES", OrderReferencePrefix: "NBO"}, nil)
+	mockShops.On("UpdateShop", ctx, mock.Anything, mock.AnythingOfType("*models.Shop")).
+		Return(nil)
+
+	cleared := " "
+	shop, err := service.UpdateShop(ctx, nil, "duka-1", &dtos.UpdateShopForm{Name: "Duka Moja", OrderReferencePrefix: &cleared})
+
+	assert.NoError(t, err)
+	assert.Empty(t, shop.OrderReferencePrefix)
+	mockShops.AssertExpectations(t)
+}
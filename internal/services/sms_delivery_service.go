@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+)
+
+type (
+	SMSDeliveryService interface {
+		RecordDeliveryReport(ctx context.Context, dB db.DB, form *dtos.SMSDeliveryReportForm) error
+		ListOrderSMSMessages(ctx context.Context, dB db.DB, shopID string, orderID int64, filter *models.Filter) (*models.SMSMessageList, error)
+		ListCustomerSMSMessages(ctx context.Context, dB db.DB, shopID string, customerID int64, filter *models.Filter) (*models.SMSMessageList, error)
+	}
+
+	smsDeliveryService struct {
+		store *domain.Store
+	}
+)
+
+func NewSMSDeliveryService(
+	store *domain.Store,
+) SMSDeliveryService {
+	return &smsDeliveryService{
+		store: store,
+	}
+}
+
+// RecordDeliveryReport updates a sent SMS with what Africa's Talking says happened to it.
+// Reports for messages we did not send are acknowledged and dropped, since the callback
+// is set for the whole account. Once a message has a final status, later reports for it
+// are ignored.
+func (s *smsDeliveryService) RecordDeliveryReport(
+	ctx context.Context,
+	dB db.DB,
+	form *dtos.SMSDeliveryReportForm,
+) error {
+
+	messageID := strings.TrimSpace(form.ID)
+	if messageID == "" {
+		return apperr.NewBadRequest("delivery report has no message id")
+	}
+
+	status, ok := custom_types.SMSStatusFromDeliveryReport(form.Status)
+	if !ok {
+		return apperr.NewBadRequest(fmt.Sprintf("invalid delivery report status [%s]", form.Status))
+	}
+
+	return dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		smsMessage, err := s.store.SMSMessageDomain.LockSMSMessageByMessageID(ctx, operations, messageID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				loggers.Infof("ignoring delivery report for unknown sms message [%s]", messageID)
+				return nil
+			}
+			return err
+		}
+
+		if smsMessage.Status.IsFinal() {
+			loggers.Infof("ignoring repeated delivery report for sms message [%s]", messageID)
+			return nil
+		}
+
+		smsMessage.Status = status
+		if form.FailureReason != "" {
+			smsMessage.FailureReason = null.NullValue(form.FailureReason)
+		}
+
+		return s.store.SMSMessageDomain.CreateSMSMessage(ctx, operations, smsMessage)
+	})
+}
+
+func (s *smsDeliveryService) ListOrderSMSMessages(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+	filter *models.Filter,
+) (*models.SMSMessageList, error) {
+
+	_, err := s.store.OrderDomain.OrderByID(ctx, dB, shopID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.OrderID = null.NullValue(orderID)
+
+	return s.listSMSMessages(ctx, dB, shopID, filter)
+}
+
+func (s *smsDeliveryService) ListCustomerSMSMessages(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	customerID int64,
+	filter *models.Filter,
+) (*models.SMSMessageList, error) {
+
+	_, err := s.store.CustomerDomain.CustomerByID(ctx, dB, shopID, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.CustomerID = null.NullValue(customerID)
+
+	return s.listSMSMessages(ctx, dB, shopID, filter)
+}
+
+func (s *smsDeliveryService) listSMSMessages(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	filter *models.Filter,
+) (*models.SMSMessageList, error) {
+
+	if filter.Status != nil && *filter.Status != "" && !custom_types.SMSStatus(*filter.Status).IsValid() {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("invalid sms status [%s]", *filter.Status))
+	}
+
+	smsMessages, err := s.store.SMSMessageDomain.ListSMSMessages(ctx, dB, shopID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.store.SMSMessageDomain.SMSMessagesCount(ctx, dB, shopID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SMSMessageList{
+		SMSMessages: smsMessages,
+		Pagination:  models.NewPagination(count, filter.Page, filter.Per),
+	}, nil
+}
@@ -0,0 +1,208 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func TestRecordDeliveryReport(t *testing.T) {
+	loggers.InitLogger("test")
+
+	tests := []struct {
+		name          string
+		status        custom_types.SMSStatus
+		form          *dtos.SMSDeliveryReportForm
+		expected      custom_types.SMSStatus
+		failureReason string
+	}{
+		{"still on its way", custom_types.SMSStatusSent, &dtos.SMSDeliveryReportForm{ID: "ATXid_7", Status: "Buffered"}, custom_types.SMSStatusSent, ""},
+		{"delivered", custom_types.SMSStatusSent, &dtos.SMSDeliveryReportForm{ID: "ATXid_7", Status: "Success"}, custom_types.SMSStatusSuccess, ""},
+		{"failed", custom_types.SMSStatusSent, &dtos.SMSDeliveryReportForm{ID: "ATXid_7", Status: "Failed", FailureReason: "AbsentSubscriber"}, custom_types.SMSStatusFailed, "AbsentSubscriber"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockSMSMessages := new(MockSMSMessageDomain)
+			store := &domain.Store{SMSMessageDomain: mockSMSMessages}
+			service := services.NewSMSDeliveryService(store)
+
+			smsMessage := &models.SMSMessage{ShopID: "shop-1", OrderID: 7, MessageID: "ATXid_7", Status: tt.status}
+
+			mockSMSMessages.On("LockSMSMessageByMessageID", ctx, mock.Anything, "ATXid_7").
+				Return(smsMessage, nil)
+			mockSMSMessages.On("CreateSMSMessage", ctx, mock.Anything, smsMessage).
+				Return(nil)
+
+			err := service.RecordDeliveryReport(ctx, &inlineDB{}, tt.form)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, smsMessage.Status)
+			if tt.failureReason != "" {
+				assert.Equal(t, tt.failureReason, *smsMessage.FailureReason)
+			}
+			mockSMSMessages.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordDeliveryReport_LeavesAFinalStatus(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockSMSMessages := new(MockSMSMessageDomain)
+	store := &domain.Store{SMSMessageDomain: mockSMSMessages}
+	service := services.NewSMSDeliveryService(store)
+
+	smsMessage := &models.SMSMessage{ShopID: "shop-1", OrderID: 7, MessageID: "ATXid_7", Status: custom_types.SMSStatusFailed}
+
+	mockSMSMessages.On("LockSMSMessageByMessageID", ctx, mock.Anything, "ATXid_7").
+		Return(smsMessage, nil)
+
+	// a late or repeated report does not change a final status
+	err := service.RecordDeliveryReport(ctx, &inlineDB{}, &dtos.SMSDeliveryReportForm{ID: "ATXid_7", Status: "Success"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.SMSStatusFailed, smsMessage.Status)
+	mockSMSMessages.AssertNotCalled(t, "CreateSMSMessage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordDeliveryReport_AcknowledgesUnknownMessages(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockSMSMessages := new(MockSMSMessageDomain)
+	store := &domain.Store{SMSMessageDomain: mockSMSMessages}
+	service := services.NewSMSDeliveryService(store)
+
+	// reports for messages sent by something else on the account
+	mockSMSMessages.On("LockSMSMessageByMessageID", ctx, mock.Anything, "ATXid_unknown").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	err := service.RecordDeliveryReport(ctx, &inlineDB{}, &dtos.SMSDeliveryReportForm{ID: "ATXid_unknown", Status: "Success"})
+
+	assert.NoError(t, err)
+	mockSMSMessages.AssertNotCalled(t, "CreateSMSMessage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordDeliveryReport_RejectsBadReports(t *testing.T) {
+	tests := []struct {
+		name string
+		form *dtos.SMSDeliveryReportForm
+	}{
+		{"unknown status", &dtos.SMSDeliveryReportForm{ID: "ATXid_8", Status: "Delivered"}},
+		{"no message id", &dtos.SMSDeliveryReportForm{Status: "Success"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockSMSMessages := new(MockSMSMessageDomain)
+			store := &domain.Store{SMSMessageDomain: mockSMSMessages}
+			service := services.NewSMSDeliveryService(store)
+
+			err := service.RecordDeliveryReport(ctx, &inlineDB{}, tt.form)
+
+			assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+			mockSMSMessages.AssertNotCalled(t, "LockSMSMessageByMessageID", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestListOrderSMSMessages_Success(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockSMSMessages := new(MockSMSMessageDomain)
+	store := &domain.Store{OrderDomain: mockOrders, SMSMessageDomain: mockSMSMessages}
+	service := services.NewSMSDeliveryService(store)
+
+	filter := &models.Filter{Page: 1, Per: 10}
+	smsMessages := []*models.SMSMessage{{ShopID: "shop-1", OrderID: 7, MessageID: "ATXid_7"}}
+
+	mockOrders.On("OrderByID", ctx, mock.Anything, "shop-1", int64(7)).
+		Return(&models.Order{ShopID: "shop-1"}, nil)
+	mockSMSMessages.On("ListSMSMessages", ctx, mock.Anything, "shop-1", filter).
+		Return(smsMessages, nil)
+	mockSMSMessages.On("SMSMessagesCount", ctx, mock.Anything, "shop-1", filter).
+		Return(1, nil)
+
+	smsMessageList, err := service.ListOrderSMSMessages(ctx, nil, "shop-1", 7, filter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), *filter.OrderID)
+	assert.Len(t, smsMessageList.SMSMessages, 1)
+	assert.Equal(t, "ATXid_7", smsMessageList.SMSMessages[0].MessageID)
+	assert.Equal(t, 1, smsMessageList.Pagination.Count)
+	mockOrders.AssertExpectations(t)
+	mockSMSMessages.AssertExpectations(t)
+}
+
+func TestListCustomerSMSMessages_Success(t *testing.T) {
+	ctx := context.Background()
+	mockCustomers := new(MockCustomerDomain)
+	mockSMSMessages := new(MockSMSMessageDomain)
+	store := &domain.Store{CustomerDomain: mockCustomers, SMSMessageDomain: mockSMSMessages}
+	service := services.NewSMSDeliveryService(store)
+
+	filter := &models.Filter{Page: 1, Per: 10}
+	smsMessages := []*models.SMSMessage{
+		{ShopID: "shop-1", OrderID: 7, CustomerID: null.NullValue(int64(3)), MessageID: "ATXid_7"},
+		{ShopID: "shop-1", OrderID: 8, CustomerID: null.NullValue(int64(3)), MessageID: "ATXid_8"},
+	}
+
+	mockCustomers.On("CustomerByID", ctx, mock.Anything, "shop-1", int64(3)).
+		Return(&models.Customer{ShopID: "shop-1"}, nil)
+	mockSMSMessages.On("ListSMSMessages", ctx, mock.Anything, "shop-1", filter).
+		Return(smsMessages, nil)
+	mockSMSMessages.On("SMSMessagesCount", ctx, mock.Anything, "shop-1", filter).
+		Return(2, nil)
+
+	smsMessageList, err := service.ListCustomerSMSMessages(ctx, nil, "shop-1", 3, filter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), *filter.CustomerID)
+	assert.Len(t, smsMessageList.SMSMessages, 2)
+	mockCustomers.AssertExpectations(t)
+	mockSMSMessages.AssertExpectations(t)
+}
+
+func TestListSMSMessages_Rejects(t *testing.T) {
+	ctx := context.Background()
+	mockOrders := new(MockOrderDomain)
+	mockCustomers := new(MockCustomerDomain)
+	mockSMSMessages := new(MockSMSMessageDomain)
+	store := &domain.Store{OrderDomain: mockOrders, CustomerDomain: mockCustomers, SMSMessageDomain: mockSMSMessages}
+	service := services.NewSMSDeliveryService(store)
+
+	// another shop's order or customer cannot be looked into
+	mockOrders.On("OrderByID", ctx, mock.Anything, "shop-2", int64(7)).
+		Return(nil, apperr.NewNotFound("order", "7"))
+	mockCustomers.On("CustomerByID", ctx, mock.Anything, "shop-2", int64(3)).
+		Return(nil, apperr.NewNotFound("customer", "3"))
+	mockOrders.On("OrderByID", ctx, mock.Anything, "shop-1", int64(7)).
+		Return(&models.Order{ShopID: "shop-1"}, nil)
+
+	_, err := service.ListOrderSMSMessages(ctx, nil, "shop-2", 7, &models.Filter{})
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	_, err = service.ListCustomerSMSMessages(ctx, nil, "shop-2", 3, &models.Filter{})
+	assert.Equal(t, apperr.NotFound, apperr.NewError(err).Type)
+
+	_, err = service.ListOrderSMSMessages(ctx, nil, "shop-1", 7, &models.Filter{Status: null.NullValue("delivered")})
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+
+	mockSMSMessages.AssertNotCalled(t, "ListSMSMessages", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+)
+
+// staffBootstrapActor is who memberships made from STAFF_BOOTSTRAP_OWNERS are recorded against.
+const staffBootstrapActor = "bootstrap"
+
+type (
+	StaffService interface {
+		UserForLogin(ctx context.Context, dB db.DB, subject, email, name string, emailVerified bool) (*models.User, error)
+		ShopMembership(ctx context.Context, dB db.DB, subject, shopID string) (*models.ShopMembership, error)
+		AddShopMember(ctx context.Context, dB db.DB, shopID string, form *dtos.AddShopMemberForm, actor string) (*models.ShopMembership, error)
+		UpdateShopMember(ctx context.Context, dB db.DB, shopID string, userID int64, form *dtos.UpdateShopMemberForm) (*models.ShopMembership, error)
+		RemoveShopMember(ctx context.Context, dB db.DB, shopID string, userID int64) error
+		ListShopMembers(ctx context.Context, dB db.DB, shopID string) ([]*models.ShopMembership, error)
+	}
+
+	staffService struct {
+		bootstrapOwners map[string]string
+		store           *domain.Store
+	}
+)
+
+func NewStaffService(
+	bootstrapOwners map[string]string,
+	store *domain.Store,
+) StaffService {
+	return &staffService{
+		bootstrapOwners: bootstrapOwners,
+		store:           store,
+	}
+}
+
+// UserForLogin finds the user behind an identity provider login, creating one on first
+// login. Staff added by email before they ever signed in are matched by email and tied
+// to the subject from then on. Only an email the identity provider has verified is
+// trusted for that match, for STAFF_BOOTSTRAP_OWNERS, or as a new user's email; with an
+// unverified email only users who have logged in before get in.
+func (s *staffService) UserForLogin(
+	ctx context.Context,
+	dB db.DB,
+	subject string,
+	email string,
+	name string,
+	emailVerified bool,
+) (*models.User, error) {
+
+	subject = strings.TrimSpace(subject)
+	email = strings.TrimSpace(email)
+	if subject == "" || email == "" {
+		return nil, apperr.NewAuthorization("login has no subject or email")
+	}
+
+	var user *models.User
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		var err error
+
+		user, err = s.store.UserDomain.LockUserBySubject(ctx, operations, subject)
+		if err != nil && !apperr.IsNoRowsErr(err) {
+			return err
+		}
+
+		if user == nil && !emailVerified {
+			// anyone can claim an address they do not own; staff are added by email
+			loggers.Warnf("login of subject [%s] as [%s] refused; the email is not verified", subject, email)
+			return apperr.NewAuthorization("email is not verified")
+		}
+
+		if user == nil {
+			user, err = s.store.UserDomain.LockUserByEmail(ctx, operations, email)
+			if err != nil && !apperr.IsNoRowsErr(err) {
+				return err
+			}
+
+			if user != nil && user.Subject != nil {
+				// the email belongs to someone who logs in as a different subject
+				loggers.Warnf("login of subject [%s] as [%s] refused; the email belongs to user [%d]", subject, email, user.ID)
+				return apperr.NewAuthorization("email is linked to another login")
+			}
+		}
+
+		if user == nil {
+			user = &models.User{}
+		}
+
+		user.Subject = null.NullValue(subject)
+		if emailVerified {
+			user.Email = email
+		}
+		if strings.TrimSpace(name) != "" {
+			user.Name = strings.TrimSpace(name)
+		}
+
+		err = s.store.UserDomain.CreateUser(ctx, operations, user)
+		if err != nil {
+			return err
+		}
+
+		if !emailVerified {
+			return nil
+		}
+
+		return s.bootstrapOwner(ctx, operations, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ShopMembership returns the caller's role in shopID. With no shopID it picks the only
+// shop the caller works in, and asks them to choose when there is more than one.
+func (s *staffService) ShopMembership(
+	ctx context.Context,
+	dB db.DB,
+	subject string,
+	shopID string,
+) (*models.ShopMembership, error) {
+
+	user, err := s.store.UserDomain.UserBySubject(ctx, dB, subject)
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return nil, apperr.NewPermission("you are not a member of any shop's staff")
+		}
+		return nil, err
+	}
+
+	if shopID != "" {
+		membership, err := s.store.ShopMembershipDomain.ShopMembership(ctx, dB, user.ID, shopID)
+		if err != nil {
+			if apperr.IsNoRowsErr(err) {
+				return nil, apperr.NewPermission(fmt.Sprintf("you are not a member of shop [%s]'s staff", shopID))
+			}
+			return nil, err
+		}
+
+		return membership, nil
+	}
+
+	memberships, err := s.store.ShopMembershipDomain.ShopMembershipsByUserID(ctx, dB, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(memberships) {
+	case 0:
+		return nil, apperr.NewPermission("you are not a member of any shop's staff")
+	case 1:
+		return memberships[0], nil
+	}
+
+	return nil, apperr.NewBadRequest("you work in more than one shop; choose one with the X-Shop-ID header")
+}
+
+// AddShopMember gives a user a role in a shop, adding the user by email if they have not
+// logged in yet.
+func (s *staffService) AddShopMember(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	form *dtos.AddShopMemberForm,
+	actor string,
+) (*models.ShopMembership, error) {
+
+	email := strings.TrimSpace(form.Email)
+	if !strings.Contains(email, "@") {
+		return nil, apperr.NewBadRequest("a valid email is required")
+	}
+
+	if !form.Role.IsValid() {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("invalid role [%s]", form.Role))
+	}
+
+	var membership *models.ShopMembership
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		user, err := s.store.UserDomain.LockUserByEmail(ctx, operations, email)
+		if err != nil {
+			if !apperr.IsNoRowsErr(err) {
+				return err
+			}
+
+			user = &models.User{
+				Email: email,
+				Name:  strings.TrimSpace(form.Name),
+			}
+
+			err = s.store.UserDomain.CreateUser(ctx, operations, user)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = s.store.ShopMembershipDomain.ShopMembership(ctx, operations, user.ID, shopID)
+		if err == nil {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("[%s] is already a member of shop [%s]'s staff", email, shopID),
+				apperr.Conflict,
+			)
+		}
+		if !apperr.IsNoRowsErr(err) {
+			return err
+		}
+
+		membership = &models.ShopMembership{
+			UserID:    user.ID,
+			ShopID:    shopID,
+			Role:      form.Role,
+			CreatedBy: actor,
+			Email:     user.Email,
+			Name:      user.Name,
+		}
+
+		return s.store.ShopMembershipDomain.CreateShopMembership(ctx, operations, membership)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func (s *staffService) UpdateShopMember(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	userID int64,
+	form *dtos.UpdateShopMemberForm,
+) (*models.ShopMembership, error) {
+
+	if !form.Role.IsValid() {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("invalid role [%s]", form.Role))
+	}
+
+	var membership *models.ShopMembership
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		memberships, err := s.store.ShopMembershipDomain.LockShopMembershipsByShopID(ctx, operations, shopID)
+		if err != nil {
+			return err
+		}
+
+		membership, err = findShopMember(memberships, userID)
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == custom_types.StaffRoleOwner && form.Role != custom_types.StaffRoleOwner && countOwners(memberships) == 1 {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("user [%d] is shop [%s]'s only owner", userID, shopID),
+				apperr.Conflict,
+			)
+		}
+
+		membership.Role = form.Role
+
+		return s.store.ShopMembershipDomain.CreateShopMembership(ctx, operations, membership)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func (s *staffService) RemoveShopMember(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	userID int64,
+) error {
+
+	return dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+
+		memberships, err := s.store.ShopMembershipDomain.LockShopMembershipsByShopID(ctx, operations, shopID)
+		if err != nil {
+			return err
+		}
+
+		membership, err := findShopMember(memberships, userID)
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == custom_types.StaffRoleOwner && countOwners(memberships) == 1 {
+			return apperr.NewErrorWithType(
+				fmt.Errorf("user [%d] is shop [%s]'s only owner", userID, shopID),
+				apperr.Conflict,
+			)
+		}
+
+		return s.store.ShopMembershipDomain.DeleteShopMembership(ctx, operations, membership.ID)
+	})
+}
+
+func (s *staffService) ListShopMembers(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+) ([]*models.ShopMembership, error) {
+
+	return s.store.ShopMembershipDomain.ShopMembershipsByShopID(ctx, dB, shopID)
+}
+
+// bootstrapOwner makes user the owner of any shop STAFF_BOOTSTRAP_OWNERS names them for.
+func (s *staffService) bootstrapOwner(
+	ctx context.Context,
+	operations db.SQLOperations,
+	user *models.User,
+) error {
+
+	for shopID, ownerEmail := range s.bootstrapOwners {
+		if !strings.EqualFold(ownerEmail, user.Email) {
+			continue
+		}
+
+		_, err := s.store.ShopMembershipDomain.ShopMembership(ctx, operations, user.ID, shopID)
+		if err == nil {
+			continue
+		}
+		if !apperr.IsNoRowsErr(err) {
+			return err
+		}
+
+		loggers.Infof("making [%s] the owner of shop [%s]", user.Email, shopID)
+
+		err = s.store.ShopDomain.EnsureShop(ctx, operations, shopID)
+		if err != nil {
+			return err
+		}
+
+		err = s.store.ShopMembershipDomain.CreateShopMembership(ctx, operations, &models.ShopMembership{
+			UserID:    user.ID,
+			ShopID:    shopID,
+			Role:      custom_types.StaffRoleOwner,
+			CreatedBy: staffBootstrapActor,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findShopMember(
+	memberships []*models.ShopMembership,
+	userID int64,
+) (*models.ShopMembership, error) {
+
+	for _, membership := range memberships {
+		if membership.UserID == userID {
+			return membership, nil
+		}
+	}
+
+	return nil, apperr.NewNotFound("staff member", fmt.Sprint(userID))
+}
+
+func countOwners(memberships []*models.ShopMembership) int {
+	count := 0
+
+	for _, membership := range memberships {
+		if membership.Role == custom_types.StaffRoleOwner {
+			count++
+		}
+	}
+
+	return count
+}
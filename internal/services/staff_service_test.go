@@ -0,0 +1,367 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+var bootstrapOwners = map[string]string{"shop-1": "Owner@Example.com"}
+
+func TestUserForLogin_FirstLoginBootstrapsTheOwner(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopMembershipDomain: mockMemberships, ShopDomain: mockShops}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "google-1").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockUsers.On("LockUserByEmail", ctx, mock.Anything, "owner@example.com").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockUsers.On("CreateUser", ctx, mock.Anything, mock.AnythingOfType("*models.User")).
+		Run(func(args mock.Arguments) { args.Get(2).(*models.User).ID = 1 }).
+		Return(nil)
+	mockMemberships.On("ShopMembership", ctx, mock.Anything, int64(1), "shop-1").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockShops.On("EnsureShop", ctx, mock.Anything, "shop-1").
+		Return(nil)
+	mockMemberships.On("CreateShopMembership", ctx, mock.Anything, &models.ShopMembership{
+		UserID:    1,
+		ShopID:    "shop-1",
+		Role:      custom_types.StaffRoleOwner,
+		CreatedBy: "bootstrap",
+	}).Return(nil)
+
+	user, err := service.UserForLogin(ctx, &inlineDB{}, "google-1", "owner@example.com", "Owner", true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+	assert.Equal(t, "google-1", *user.Subject)
+	assert.Equal(t, "Owner", user.Name)
+
+	mockUsers.AssertExpectations(t)
+	mockMemberships.AssertExpectations(t)
+	mockShops.AssertExpectations(t)
+}
+
+func TestUserForLogin_ReturningOwnerIsNotMadeOwnerAgain(t *testing.T) {
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopMembershipDomain: mockMemberships, ShopDomain: mockShops}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	owner := &models.User{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1},
+		Subject:              null.NullValue("google-1"),
+		Email:                "owner@example.com",
+	}
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "google-1").
+		Return(owner, nil)
+	mockUsers.On("CreateUser", ctx, mock.Anything, owner).
+		Return(nil)
+	mockMemberships.On("ShopMembership", ctx, mock.Anything, int64(1), "shop-1").
+		Return(&models.ShopMembership{UserID: 1, ShopID: "shop-1", Role: custom_types.StaffRoleOwner}, nil)
+
+	_, err := service.UserForLogin(ctx, &inlineDB{}, "google-1", "owner@example.com", "Owner", true)
+
+	assert.NoError(t, err)
+	mockUsers.AssertNotCalled(t, "LockUserByEmail", mock.Anything, mock.Anything, mock.Anything)
+	mockShops.AssertNotCalled(t, "EnsureShop", mock.Anything, mock.Anything, mock.Anything)
+	mockMemberships.AssertNotCalled(t, "CreateShopMembership", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserForLogin_LinksStaffAddedByEmail(t *testing.T) {
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	store := &domain.Store{UserDomain: mockUsers}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	added := &models.User{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 2},
+		Email:                "cashier@example.com",
+	}
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "google-2").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockUsers.On("LockUserByEmail", ctx, mock.Anything, "cashier@example.com").
+		Return(added, nil)
+	mockUsers.On("CreateUser", ctx, mock.Anything, added).
+		Return(nil)
+
+	user, err := service.UserForLogin(ctx, &inlineDB{}, "google-2", "cashier@example.com", "Cashier", true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), user.ID)
+	assert.Equal(t, "google-2", *user.Subject)
+	assert.Equal(t, "Cashier", user.Name)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestUserForLogin_RefusesAnEmailLinkedToAnotherLogin(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	store := &domain.Store{UserDomain: mockUsers}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "github-9").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockUsers.On("LockUserByEmail", ctx, mock.Anything, "cashier@example.com").
+		Return(&models.User{
+			SequentialIdentifier: custom_types.SequentialIdentifier{ID: 2},
+			Subject:              null.NullValue("google-2"),
+			Email:                "cashier@example.com",
+		}, nil)
+
+	_, err := service.UserForLogin(ctx, &inlineDB{}, "github-9", "cashier@example.com", "", true)
+
+	assert.Equal(t, apperr.Authorization, apperr.NewError(err).Type)
+	mockUsers.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserForLogin_UnverifiedEmailIsNotLinkedOrMadeOwner(t *testing.T) {
+	loggers.InitLogger("test")
+
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	mockShops := new(MockShopDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopMembershipDomain: mockMemberships, ShopDomain: mockShops}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "github-9").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+
+	// the owner's email, claimed at a provider that never checked it
+	_, err := service.UserForLogin(ctx, &inlineDB{}, "github-9", "owner@example.com", "", false)
+
+	assert.Equal(t, apperr.Authorization, apperr.NewError(err).Type)
+	mockUsers.AssertNotCalled(t, "LockUserByEmail", mock.Anything, mock.Anything, mock.Anything)
+	mockUsers.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything)
+	mockMemberships.AssertNotCalled(t, "CreateShopMembership", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserForLogin_ReturningUserWithAnUnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopMembershipDomain: mockMemberships}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	cashier := &models.User{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: 2},
+		Subject:              null.NullValue("google-2"),
+		Email:                "cashier@example.com",
+	}
+
+	mockUsers.On("LockUserBySubject", ctx, mock.Anything, "google-2").
+		Return(cashier, nil)
+	mockUsers.On("CreateUser", ctx, mock.Anything, cashier).
+		Return(nil)
+
+	user, err := service.UserForLogin(ctx, &inlineDB{}, "google-2", "owner@example.com", "Cashier", false)
+
+	assert.NoError(t, err)
+	// the unverified address is not taken on, nor made the owner of shop-1
+	assert.Equal(t, "cashier@example.com", user.Email)
+	mockMemberships.AssertNotCalled(t, "ShopMembership", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockMemberships.AssertNotCalled(t, "CreateShopMembership", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddShopMember_AddsUsersWhoHaveNotLoggedIn(t *testing.T) {
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopMembershipDomain: mockMemberships}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	mockUsers.On("LockUserByEmail", ctx, mock.Anything, "cashier@example.com").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockUsers.On("CreateUser", ctx, mock.Anything, &models.User{Email: "cashier@example.com", Name: "Cashier"}).
+		Run(func(args mock.Arguments) { args.Get(2).(*models.User).ID = 2 }).
+		Return(nil)
+	mockMemberships.On("ShopMembership", ctx, mock.Anything, int64(2), "shop-1").
+		Return(nil, apperr.NewDatabaseError(sql.ErrNoRows))
+	mockMemberships.On("CreateShopMembership", ctx, mock.Anything, mock.AnythingOfType("*models.ShopMembership")).
+		Return(nil)
+
+	membership, err := service.AddShopMember(ctx, &inlineDB{}, "shop-1", &dtos.AddShopMemberForm{
+		Email: " cashier@example.com ",
+		Name:  "Cashier",
+		Role:  custom_types.StaffRoleCashier,
+	}, "owner@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), membership.UserID)
+	assert.Equal(t, "shop-1", membership.ShopID)
+	assert.Equal(t, custom_types.StaffRoleCashier, membership.Role)
+	assert.Equal(t, "owner@example.com", membership.CreatedBy)
+	mockUsers.AssertExpectations(t)
+	mockMemberships.AssertExpectations(t)
+}
+
+func TestAddShopMember_RefusesAnExistingMember(t *testing.T) {
+	ctx := context.Background()
+	mockUsers := new(MockUserDomain)
+	mockMemberships := new(MockShopMembershipDomain)
+	store := &domain.Store{UserDomain: mockUsers, ShopMembershipDomain: mockMemberships}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	mockUsers.On("LockUserByEmail", ctx, mock.Anything, "CASHIER@example.com").
+		Return(&models.User{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 2}, Email: "cashier@example.com"}, nil)
+	mockMemberships.On("ShopMembership", ctx, mock.Anything, int64(2), "shop-1").
+		Return(&models.ShopMembership{UserID: 2, ShopID: "shop-1", Role: custom_types.StaffRoleCashier}, nil)
+
+	_, err := service.AddShopMember(ctx, &inlineDB{}, "shop-1", &dtos.AddShopMemberForm{
+		Email: "CASHIER@example.com",
+		Role:  custom_types.StaffRoleViewer,
+	}, "owner@example.com")
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockMemberships.AssertNotCalled(t, "CreateShopMembership", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestShopMembership(t *testing.T) {
+	user := &models.User{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, Subject: null.NullValue("google-1")}
+	cashier := &models.ShopMembership{UserID: 1, ShopID: "shop-1", Role: custom_types.StaffRoleCashier}
+	manager := &models.ShopMembership{UserID: 1, ShopID: "shop-2", Role: custom_types.StaffRoleManager}
+
+	tests := []struct {
+		name        string
+		subject     string
+		shopID      string
+		memberships []*models.ShopMembership
+		expected    *models.ShopMembership
+		errType     apperr.Type
+	}{
+		{"never logged in", "google-404", "", nil, nil, apperr.Permission},
+		{"not in the shop", "google-1", "shop-3", nil, nil, apperr.Permission},
+		{"in no shop", "google-1", "", []*models.ShopMembership{}, nil, apperr.Permission},
+		{"the only shop", "google-1", "", []*models.ShopMembership{cashier}, cashier, ""},
+		{"two shops and none chosen", "google-1", "", []*models.ShopMembership{cashier, manager}, nil, apperr.BadRequest},
+		{"two shops and one chosen", "google-1", "shop-2", nil, manager, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockUsers := new(MockUserDomain)
+			mockMemberships := new(MockShopMembershipDomain)
+			store := &domain.Store{UserDomain: mockUsers, ShopMembershipDomain: mockMemberships}
+			service := services.NewStaffService(bootstrapOwners, store)
+
+			mockUsers.On("UserBySubject", ctx, mock.Anything, "google-1").Return(user, nil).Maybe()
+			mockUsers.On("UserBySubject", ctx, mock.Anything, "google-404").Return(nil, apperr.NewDatabaseError(sql.ErrNoRows)).Maybe()
+			mockMemberships.On("ShopMembership", ctx, mock.Anything, int64(1), "shop-2").Return(manager, nil).Maybe()
+			mockMemberships.On("ShopMembership", ctx, mock.Anything, int64(1), "shop-3").Return(nil, apperr.NewDatabaseError(sql.ErrNoRows)).Maybe()
+			mockMemberships.On("ShopMembershipsByUserID", ctx, mock.Anything, int64(1)).Return(tt.memberships, nil).Maybe()
+
+			membership, err := service.ShopMembership(ctx, nil, tt.subject, tt.shopID)
+
+			if tt.errType != "" {
+				assert.Equal(t, tt.errType, apperr.NewError(err).Type)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, membership)
+		})
+	}
+}
+
+func TestUpdateShopMember_KeepsAnOwner(t *testing.T) {
+	owner := &models.ShopMembership{UserID: 1, ShopID: "shop-1", Role: custom_types.StaffRoleOwner}
+	cashier := &models.ShopMembership{UserID: 2, ShopID: "shop-1", Role: custom_types.StaffRoleCashier}
+
+	ctx := context.Background()
+	mockMemberships := new(MockShopMembershipDomain)
+	store := &domain.Store{ShopMembershipDomain: mockMemberships}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	mockMemberships.On("LockShopMembershipsByShopID", ctx, mock.Anything, "shop-1").
+		Return([]*models.ShopMembership{owner, cashier}, nil)
+
+	_, err := service.UpdateShopMember(ctx, &inlineDB{}, "shop-1", 1, &dtos.UpdateShopMemberForm{Role: custom_types.StaffRoleManager})
+
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	mockMemberships.AssertNotCalled(t, "CreateShopMembership", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateShopMember_OneOfTwoOwnersMayStepDown(t *testing.T) {
+	owner := &models.ShopMembership{UserID: 1, ShopID: "shop-1", Role: custom_types.StaffRoleOwner}
+	partner := &models.ShopMembership{UserID: 2, ShopID: "shop-1", Role: custom_types.StaffRoleOwner}
+
+	ctx := context.Background()
+	mockMemberships := new(MockShopMembershipDomain)
+	store := &domain.Store{ShopMembershipDomain: mockMemberships}
+	service := services.NewStaffService(bootstrapOwners, store)
+
+	mockMemberships.On("LockShopMembershipsByShopID", ctx, mock.Anything, "shop-1").
+		Return([]*models.ShopMembership{owner, partner}, nil)
+	mockMemberships.On("CreateShopMembership", ctx, mock.Anything, owner).
+		Return(nil)
+
+	membership, err := service.UpdateShopMember(ctx, &inlineDB{}, "shop-1", 1, &dtos.UpdateShopMemberForm{Role: custom_types.StaffRoleManager})
+
+	assert.NoError(t, err)
+	assert.Equal(t, custom_types.StaffRoleManager, membership.Role)
+	mockMemberships.AssertExpectations(t)
+}
+
+func TestRemoveShopMember(t *testing.T) {
+	owner := &models.ShopMembership{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 10}, UserID: 1, ShopID: "shop-1", Role: custom_types.StaffRoleOwner}
+	cashier := &models.ShopMembership{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 11}, UserID: 2, ShopID: "shop-1", Role: custom_types.StaffRoleCashier}
+
+	tests := []struct {
+		name    string
+		userID  int64
+		errType apperr.Type
+	}{
+		{"the only owner", 1, apperr.Conflict},
+		{"not on the staff", 99, apperr.NotFound},
+		{"a cashier", 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockMemberships := new(MockShopMembershipDomain)
+			store := &domain.Store{ShopMembershipDomain: mockMemberships}
+			service := services.NewStaffService(bootstrapOwners, store)
+
+			mockMemberships.On("LockShopMembershipsByShopID", ctx, mock.Anything, "shop-1").
+				Return([]*models.ShopMembership{owner, cashier}, nil)
+			mockMemberships.On("DeleteShopMembership", ctx, mock.Anything, int64(11)).
+				Return(nil).Maybe()
+
+			err := service.RemoveShopMember(ctx, &inlineDB{}, "shop-1", tt.userID)
+
+			if tt.errType != "" {
+				assert.Equal(t, tt.errType, apperr.NewError(err).Type)
+				mockMemberships.AssertNotCalled(t, "DeleteShopMembership", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockMemberships.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"sort"
+	"strings"
+)
+
+type (
+	StockService interface {
+		RecordStockMovement(ctx context.Context, dB db.DB, shopID string, productID int64, form *dtos.CreateStockMovementForm, actor string) (*models.StockMovement, error)
+		ApplyStockMovements(ctx context.Context, operations db.SQLOperations, shopID string, movements []*models.StockMovement) error
+		ListProductStockMovements(ctx context.Context, dB db.DB, shopID string, productID int64, filter *models.Filter) (*models.StockMovementList, error)
+		ProductStockLevel(ctx context.Context, dB db.DB, shopID string, productID int64) (*models.StockLevel, error)
+	}
+
+	stockService struct {
+		store *domain.Store
+	}
+)
+
+func NewStockService(store *domain.Store) StockService {
+	return &stockService{
+		store: store,
+	}
+}
+
+func (s *stockService) RecordStockMovement(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	productID int64,
+	form *dtos.CreateStockMovementForm,
+	actor string,
+) (*models.StockMovement, error) {
+
+	if !form.MovementType.IsManual() {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("stock movement type [%s] cannot be recorded manually", form.MovementType))
+	}
+
+	if form.Quantity == 0 {
+		return nil, apperr.NewBadRequest("stock movement quantity cannot be zero")
+	}
+
+	if strings.TrimSpace(form.Reason) == "" {
+		return nil, apperr.NewBadRequest("stock movement reason is required")
+	}
+
+	movement := &models.StockMovement{
+		ProductID:    productID,
+		MovementType: form.MovementType,
+		Quantity:     form.MovementType.SignedQuantity(form.Quantity),
+		Reason:       form.Reason,
+		Actor:        actor,
+	}
+
+	err := dB.InTransaction(ctx, func(ctx context.Context, operations db.SQLOperations) error {
+		return s.ApplyStockMovements(ctx, operations, shopID, []*models.StockMovement{movement})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// ApplyStockMovements writes movements to the ledger and moves product stock with them.
+// Products are locked in id order, and if any movement would take stock below zero none
+// of them are applied.
+func (s *stockService) ApplyStockMovements(
+	ctx context.Context,
+	operations db.SQLOperations,
+	shopID string,
+	movements []*models.StockMovement,
+) error {
+
+	changes := make(map[int64]int64, len(movements))
+	productIDs := make([]int64, 0, len(movements))
+
+	for _, movement := range movements {
+		if _, ok := changes[movement.ProductID]; !ok {
+			productIDs = append(productIDs, movement.ProductID)
+		}
+		changes[movement.ProductID] += movement.Quantity
+	}
+
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	shortages := make([]string, 0)
+
+	for _, productID := range productIDs {
+		product, err := s.store.ProductDomain.LockProductByID(ctx, operations, shopID, productID)
+		if err != nil {
+			return err
+		}
+
+		if product.ProductType == custom_types.ProductTypeService {
+			return apperr.NewBadRequest(fmt.Sprintf("product [%d] %s is a service and does not carry stock", product.ID, product.Name))
+		}
+
+		if product.Stock+changes[productID] < 0 {
+			shortages = append(shortages, fmt.Sprintf(
+				"product [%d] %s: requested %d, available %d",
+				product.ID,
+				product.Name,
+				-changes[productID],
+				product.Stock,
+			))
+		}
+	}
+
+	if len(shortages) > 0 {
+		return apperr.NewErrorWithType(
+			fmt.Errorf("insufficient stock: %s", strings.Join(shortages, "; ")),
+			apperr.Conflict,
+		)
+	}
+
+	for _, movement := range movements {
+		stock, err := s.store.ProductDomain.AdjustStock(ctx, operations, shopID, movement.ProductID, movement.Quantity)
+		if err != nil {
+			return err
+		}
+
+		movement.StockAfter = stock
+
+		err = s.store.StockMovementDomain.CreateStockMovement(ctx, operations, movement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *stockService) ListProductStockMovements(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	productID int64,
+	filter *models.Filter,
+) (*models.StockMovementList, error) {
+
+	_, err := s.store.ProductDomain.ProductByID(ctx, dB, shopID, productID)
+	if err != nil {
+		return &models.StockMovementList{}, err
+	}
+
+	if filter.TimeFilterSet() {
+		err = filter.ConvertTime()
+		if err != nil {
+			return &models.StockMovementList{}, apperr.NewErrorWithType(err, apperr.BadRequest)
+		}
+	}
+
+	movements, err := s.store.StockMovementDomain.ListProductStockMovements(ctx, dB, shopID, productID, filter)
+	if err != nil {
+		return &models.StockMovementList{}, err
+	}
+
+	count, err := s.store.StockMovementDomain.ProductStockMovementsCount(ctx, dB, shopID, productID, filter)
+	if err != nil {
+		return &models.StockMovementList{}, err
+	}
+
+	movementList := &models.StockMovementList{
+		StockMovements: movements,
+		Pagination: models.NewPagination(
+			count,
+			filter.Page,
+			filter.Per,
+		),
+	}
+
+	return movementList, nil
+}
+
+func (s *stockService) ProductStockLevel(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	productID int64,
+) (*models.StockLevel, error) {
+
+	product, err := s.store.ProductDomain.ProductByID(ctx, dB, shopID, productID)
+	if err != nil {
+		return &models.StockLevel{}, err
+	}
+
+	ledgerStock, err := s.store.StockMovementDomain.LedgerStock(ctx, dB, shopID, productID)
+	if err != nil {
+		return &models.StockLevel{}, err
+	}
+
+	stockLevel := &models.StockLevel{
+		ProductID:   product.ID,
+		Stock:       product.Stock,
+		LedgerStock: ledgerStock,
+		InSync:      product.Stock == ledgerStock,
+	}
+
+	return stockLevel, nil
+}
@@ -0,0 +1,88 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func stockProduct(id int64, productType custom_types.ProductType, stock int64) *models.Product {
+	return &models.Product{
+		SequentialIdentifier: custom_types.SequentialIdentifier{ID: id},
+		Name:                 "product",
+		Stock:                stock,
+		ProductType:          productType,
+	}
+}
+
+func saleMovement(productID, quantity int64) *models.StockMovement {
+	return &models.StockMovement{
+		ProductID:    productID,
+		MovementType: custom_types.StockMovementTypeSale,
+		Quantity:     custom_types.StockMovementTypeSale.SignedQuantity(quantity),
+		Reason:       "sold",
+		Actor:        "cashier@example.com",
+	}
+}
+
+func TestApplyStockMovements_RecordsLedgerEntries(t *testing.T) {
+	ctx := context.Background()
+	productDomain := new(MockProductDomain)
+	movementDomain := new(MockStockMovementDomain)
+	service := services.NewStockService(&domain.Store{ProductDomain: productDomain, StockMovementDomain: movementDomain})
+
+	productDomain.On("LockProductByID", ctx, nil, "shop-1", int64(1)).Return(stockProduct(1, custom_types.ProductTypeGoods, 5), nil)
+	productDomain.On("AdjustStock", ctx, nil, "shop-1", int64(1), int64(-3)).Return(int64(2), nil)
+	productDomain.On("AdjustStock", ctx, nil, "shop-1", int64(1), int64(-1)).Return(int64(1), nil)
+	movementDomain.On("CreateStockMovement", ctx, nil, mock.AnythingOfType("*models.StockMovement")).Return(nil)
+
+	movements := []*models.StockMovement{saleMovement(1, 3), saleMovement(1, 1)}
+
+	err := service.ApplyStockMovements(ctx, nil, "shop-1", movements)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), movements[0].StockAfter)
+	assert.Equal(t, int64(1), movements[1].StockAfter)
+	productDomain.AssertNumberOfCalls(t, "LockProductByID", 1)
+	movementDomain.AssertNumberOfCalls(t, "CreateStockMovement", 2)
+}
+
+func TestApplyStockMovements_RejectsOversell(t *testing.T) {
+	ctx := context.Background()
+	productDomain := new(MockProductDomain)
+	movementDomain := new(MockStockMovementDomain)
+	service := services.NewStockService(&domain.Store{ProductDomain: productDomain, StockMovementDomain: movementDomain})
+
+	productDomain.On("LockProductByID", ctx, nil, "shop-1", int64(1)).Return(stockProduct(1, custom_types.ProductTypeGoods, 2), nil)
+	productDomain.On("LockProductByID", ctx, nil, "shop-1", int64(3)).Return(stockProduct(3, custom_types.ProductTypeGoods, 10), nil)
+
+	err := service.ApplyStockMovements(ctx, nil, "shop-1", []*models.StockMovement{saleMovement(3, 1), saleMovement(1, 3)})
+
+	assert.Error(t, err)
+	assert.Equal(t, apperr.Conflict, apperr.NewError(err).Type)
+	assert.Contains(t, err.Error(), "product [1]")
+	assert.Contains(t, err.Error(), "requested 3, available 2")
+	productDomain.AssertNotCalled(t, "AdjustStock", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	movementDomain.AssertNotCalled(t, "CreateStockMovement", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApplyStockMovements_RejectsServices(t *testing.T) {
+	ctx := context.Background()
+	productDomain := new(MockProductDomain)
+	service := services.NewStockService(&domain.Store{ProductDomain: productDomain})
+
+	productDomain.On("LockProductByID", ctx, nil, "shop-1", int64(2)).Return(stockProduct(2, custom_types.ProductTypeService, 0), nil)
+
+	err := service.ApplyStockMovements(ctx, nil, "shop-1", []*models.StockMovement{saleMovement(2, 1)})
+
+	assert.Error(t, err)
+	assert.Equal(t, apperr.BadRequest, apperr.NewError(err).Type)
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"strings"
+)
+
+type (
+	TaxService interface {
+		CreateTaxClass(ctx context.Context, dB db.DB, shopID string, form *dtos.CreateTaxClassForm) (*models.TaxClass, error)
+		ListTaxClasses(ctx context.Context, dB db.DB, shopID string) ([]*models.TaxClass, error)
+		OrderTaxSummary(ctx context.Context, dB db.DB, shopID string, orderID int64) (*models.TaxSummary, error)
+		ShopTaxSummary(ctx context.Context, dB db.DB, shopID string, filter *models.Filter) (*models.TaxSummary, error)
+	}
+
+	taxService struct {
+		store *domain.Store
+	}
+)
+
+func NewTaxService(
+	store *domain.Store,
+) TaxService {
+	return &taxService{
+		store: store,
+	}
+}
+
+// CreateTaxClass adds a tax class of the shop's own; the shared classes are left to the
+// migrations.
+func (s *taxService) CreateTaxClass(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	form *dtos.CreateTaxClassForm,
+) (*models.TaxClass, error) {
+
+	taxClass := &models.TaxClass{
+		ShopID:    null.NullValue(shopID),
+		Code:      strings.ToUpper(strings.TrimSpace(form.Code)),
+		Name:      strings.TrimSpace(form.Name),
+		Treatment: form.Treatment,
+		Rate:      form.Rate,
+	}
+
+	if taxClass.Code == "" || taxClass.Name == "" {
+		return nil, apperr.NewBadRequest("tax class code and name are required")
+	}
+
+	if !taxClass.Treatment.IsValid() {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("unknown tax treatment [%s]", taxClass.Treatment))
+	}
+
+	// zero rated and exempt goods both carry no VAT; only standard goods have a rate
+	if taxClass.Treatment == custom_types.TaxTreatmentStandard {
+		if taxClass.Rate <= 0 || taxClass.Rate >= taxRateBasis {
+			return nil, apperr.NewBadRequest("a standard rated tax class needs a rate between 1 and 9999 basis points")
+		}
+	} else if taxClass.Rate != 0 {
+		return nil, apperr.NewBadRequest(fmt.Sprintf("a [%s] tax class cannot have a rate", taxClass.Treatment))
+	}
+
+	err := s.store.TaxClassDomain.CreateTaxClass(ctx, dB, taxClass)
+	if err != nil {
+		return nil, err
+	}
+
+	return taxClass, nil
+}
+
+// ListTaxClasses lists the shared tax classes and the shop's own.
+func (s *taxService) ListTaxClasses(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+) ([]*models.TaxClass, error) {
+
+	return s.store.TaxClassDomain.ListTaxClasses(ctx, dB, shopID)
+}
+
+// OrderTaxSummary is the VAT on one order as it stands, after any returns.
+func (s *taxService) OrderTaxSummary(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	orderID int64,
+) (*models.TaxSummary, error) {
+
+	order, err := s.store.OrderDomain.OrderByID(ctx, dB, shopID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := s.store.TaxClassDomain.OrderTaxSummary(ctx, dB, shopID, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	taxSummary, err := newTaxSummary(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	taxSummary.OrderID = null.NullValue(order.ID)
+
+	return taxSummary, nil
+}
+
+// ShopTaxSummary is the VAT a shop collected over the period given by the filter's from
+// and to, for filing. Returns taken in the period reduce it.
+func (s *taxService) ShopTaxSummary(
+	ctx context.Context,
+	dB db.DB,
+	shopID string,
+	filter *models.Filter,
+) (*models.TaxSummary, error) {
+
+	if !filter.TimeFilterSet() {
+		return nil, apperr.NewBadRequest("a tax summary needs a from and to date")
+	}
+
+	err := filter.ConvertTime()
+	if err != nil {
+		return nil, apperr.NewBadRequest(err.Error())
+	}
+
+	from, to := null.ValueFromNull(filter.FromTime), null.ValueFromNull(filter.ToTime)
+	if !to.After(from) {
+		return nil, apperr.NewBadRequest("the tax summary period must end after it starts")
+	}
+
+	lines, err := s.store.TaxClassDomain.ShopTaxSummary(ctx, dB, shopID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	taxSummary, err := newTaxSummary(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	taxSummary.ShopID = null.NullValue(shopID)
+	taxSummary.From = filter.FromTime
+	taxSummary.To = filter.ToTime
+
+	return taxSummary, nil
+}
+
+// checkTaxClass makes sure a tax class given on a form exists and is shared or the
+// shop's own. A nil id is fine: the product or category then inherits its class.
+func checkTaxClass(
+	ctx context.Context,
+	operations db.SQLOperations,
+	store *domain.Store,
+	shopID string,
+	taxClassID *int64,
+) error {
+
+	if taxClassID == nil {
+		return nil
+	}
+
+	_, err := store.TaxClassDomain.TaxClassByID(ctx, operations, shopID, null.ValueFromNull(taxClassID))
+	if err != nil {
+		if apperr.IsNoRowsErr(err) {
+			return apperr.NewBadRequest(fmt.Sprintf("tax class [%d] does not exist", null.ValueFromNull(taxClassID)))
+		}
+		return err
+	}
+
+	return nil
+}
+
+func newTaxSummary(
+	lines []*models.TaxSummaryLine,
+) (*models.TaxSummary, error) {
+
+	taxSummary := &models.TaxSummary{
+		Lines:         lines,
+		TaxableAmount: custom_types.NewMoney(0),
+		TaxAmount:     custom_types.NewMoney(0),
+		GrossAmount:   custom_types.NewMoney(0),
+	}
+
+	for _, line := range lines {
+		for _, part := range []moneyPart{
+			{&taxSummary.TaxableAmount, line.TaxableAmount},
+			{&taxSummary.TaxAmount, line.TaxAmount},
+			{&taxSummary.GrossAmount, line.GrossAmount},
+		} {
+			total, err := part.target.Add(part.amount)
+			if err != nil {
+				return nil, apperr.NewError(err)
+			}
+
+			*part.target = total
+		}
+	}
+
+	return taxSummary, nil
+}
@@ -0,0 +1,39 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/null"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+func TestCreateTaxClass_BelongsToTheShop(t *testing.T) {
+	ctx := context.Background()
+	mockTaxClasses := new(MockTaxClassDomain)
+	store := &domain.Store{TaxClassDomain: mockTaxClasses}
+	service := services.NewTaxService(store)
+
+	mockTaxClasses.On("CreateTaxClass", ctx, mock.Anything, mock.MatchedBy(func(taxClass *models.TaxClass) bool {
+		return taxClass.ShopID != nil && *taxClass.ShopID == "shop-1"
+	})).Return(nil)
+
+	taxClass, err := service.CreateTaxClass(ctx, nil, "shop-1", &dtos.CreateTaxClassForm{
+		Code:      " own8 ",
+		Name:      "Own 8%",
+		Treatment: custom_types.TaxTreatmentStandard,
+		Rate:      800,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "OWN8", taxClass.Code)
+	assert.Equal(t, "shop-1", null.ValueFromNull(taxClass.ShopID))
+	mockTaxClasses.AssertExpectations(t)
+}
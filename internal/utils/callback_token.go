@@ -0,0 +1,13 @@
+package utils
+
+import "crypto/subtle"
+
+// ValidCallbackToken reports whether a provider's callback carries the token it was
+// given. With no token configured every callback is refused, since anyone could post one.
+func ValidCallbackToken(given, expected string) bool {
+	if expected == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidCallbackToken(t *testing.T) {
+	assert.True(t, ValidCallbackToken("s3cret", "s3cret"))
+	assert.False(t, ValidCallbackToken("guess", "s3cret"))
+	assert.False(t, ValidCallbackToken("", "s3cret"))
+
+	// an unconfigured token refuses everything rather than letting everything in
+	assert.False(t, ValidCallbackToken("", ""))
+	assert.False(t, ValidCallbackToken("anything", ""))
+}
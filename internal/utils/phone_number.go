@@ -1,2 +1,40 @@
 package utils
 
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatPhoneNumber puts a Kenyan phone number in the +254 form SMS providers expect and
+// send inbound messages from, so numbers typed in at the till can be matched against them.
+func FormatPhoneNumber(phone string) (string, error) {
+	cleaned := strings.ReplaceAll(phone, " ", "")
+	cleaned = strings.ReplaceAll(cleaned, "-", "")
+	cleaned = strings.ReplaceAll(cleaned, "(", "")
+	cleaned = strings.ReplaceAll(cleaned, ")", "")
+
+	if !strings.HasPrefix(cleaned, "+") {
+
+		if strings.HasPrefix(cleaned, "0") && len(cleaned) == 10 {
+			cleaned = "+254" + cleaned[1:]
+		} else if len(cleaned) == 9 {
+			cleaned = "+254" + cleaned
+		} else {
+			return "", fmt.Errorf("invalid phone number format: %s", phone)
+		}
+	}
+
+	digits := strings.TrimPrefix(cleaned, "+")
+
+	if len(digits) < 9 || len(digits) > 12 {
+		return "", fmt.Errorf("invalid phone number length: %s", phone)
+	}
+
+	for _, char := range digits {
+		if char < '0' || char > '9' {
+			return "", fmt.Errorf("invalid characters in phone number: %s", phone)
+		}
+	}
+
+	return cleaned, nil
+}
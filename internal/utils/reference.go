@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// crockfordAlphabet leaves out I, L, O and U so references survive being read out over the phone.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const (
+	// referenceBodyLength pads short sequence numbers so references keep the same shape;
+	// six characters cover just over a billion orders before the body grows.
+	referenceBodyLength = 6
+	maxReferencePrefix  = 4
+)
+
+// FormatOrderReference turns a sequence number into a reference such as "NBO-000A7K-4":
+// the shop prefix, the number in Crockford base32 and a check character.
+func FormatOrderReference(prefix string, sequence int64) string {
+	body := encodeCrockford(sequence)
+	if len(body) < referenceBodyLength {
+		body = strings.Repeat("0", referenceBodyLength-len(body)) + body
+	}
+
+	return fmt.Sprintf("%s-%s-%c", prefix, body, referenceCheckCharacter(body))
+}
+
+// ValidOrderReference reports whether ref has the shape FormatOrderReference produces
+// and its check character matches, which catches most mistyped characters.
+func ValidOrderReference(ref string) bool {
+	parts := strings.Split(ref, "-")
+	if len(parts) != 3 || !ValidReferencePrefix(parts[0]) || len(parts[2]) != 1 {
+		return false
+	}
+
+	body := parts[1]
+	if len(body) < referenceBodyLength {
+		return false
+	}
+
+	for _, char := range body {
+		if !strings.ContainsRune(crockfordAlphabet, char) {
+			return false
+		}
+	}
+
+	return parts[2][0] == referenceCheckCharacter(body)
+}
+
+// ValidReferencePrefix accepts one to four upper case letters or digits.
+func ValidReferencePrefix(prefix string) bool {
+	if len(prefix) < 1 || len(prefix) > maxReferencePrefix {
+		return false
+	}
+
+	for _, char := range prefix {
+		if (char < 'A' || char > 'Z') && (char < '0' || char > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func encodeCrockford(value int64) string {
+	if value == 0 {
+		return "0"
+	}
+
+	encoded := make([]byte, 0, 13)
+	for value > 0 {
+		encoded = append(encoded, crockfordAlphabet[value%32])
+		value /= 32
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+
+	return string(encoded)
+}
+
+// referenceCheckCharacter computes a Luhn mod 32 check character over body, which
+// detects any single wrong character and most swaps of neighbouring characters.
+func referenceCheckCharacter(body string) byte {
+	const base = 32
+
+	factor := 2
+	sum := 0
+
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(crockfordAlphabet, body[i])
+		addend = addend/base + addend%base
+		sum += addend
+
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+
+	return crockfordAlphabet[(base-sum%base)%base]
+}
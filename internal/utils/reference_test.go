@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOrderReference(t *testing.T) {
+	ref := FormatOrderReference("NBO", 1)
+
+	assert.Equal(t, "NBO-000001-", ref[:len(ref)-1])
+	assert.True(t, ValidOrderReference(ref))
+	assert.LessOrEqual(t, len(FormatOrderReference("NBOX", 1<<30-1)), 13)
+}
+
+func TestEncodeCrockford(t *testing.T) {
+	cases := map[int64]string{
+		0:         "0",
+		9:         "9",
+		10:        "A",
+		18:        "J",
+		31:        "Z",
+		32:        "10",
+		1234:      "16J",
+		1<<30 - 1: "ZZZZZZ",
+	}
+
+	for value, expected := range cases {
+		assert.Equal(t, expected, encodeCrockford(value), value)
+	}
+}
+
+func TestReferenceCheckCharacter(t *testing.T) {
+	// Luhn mod 32: doubling the last character and carrying over base 32
+	assert.Equal(t, byte('Y'), referenceCheckCharacter("000001"))
+	assert.Equal(t, byte('0'), referenceCheckCharacter("000000"))
+
+	ref := FormatOrderReference("NBO", 1)
+	assert.Equal(t, "NBO-000001-Y", ref)
+	assert.True(t, ValidOrderReference(ref))
+	assert.False(t, ValidOrderReference("NBO-000001-X"))
+}
+
+func TestValidOrderReference_RejectsMalformedReferences(t *testing.T) {
+	valid := FormatOrderReference("NBO", 1234)
+	assert.True(t, ValidOrderReference(valid))
+
+	for _, ref := range []string{
+		"",
+		"NBO-00016J",
+		"NBO00016J" + valid[len(valid)-1:],
+		"nbo-00016J-" + valid[len(valid)-1:],
+		"NBO-0016J-" + valid[len(valid)-1:],
+		"NBO-00016J-" + valid[len(valid)-1:] + "X",
+		"NBO-00016I-" + valid[len(valid)-1:],
+		"NBO-00016L-" + valid[len(valid)-1:],
+		"NBO-00016O-" + valid[len(valid)-1:],
+		"NBO-00016U-" + valid[len(valid)-1:],
+		"NBO-00016j-" + valid[len(valid)-1:],
+		"NBO-00016J-I",
+	} {
+		assert.False(t, ValidOrderReference(ref), ref)
+	}
+}
+
+func TestFormatOrderReference_DistinctSequencesGiveDistinctReferences(t *testing.T) {
+	property := func(a, b uint32) bool {
+		if a == b {
+			return true
+		}
+		return FormatOrderReference("SV", int64(a)) != FormatOrderReference("SV", int64(b))
+	}
+
+	assert.NoError(t, quick.Check(property, nil))
+}
+
+func TestValidOrderReference_DetectsSingleCharacterTypos(t *testing.T) {
+	property := func(sequence uint32, position uint8, shift uint8) bool {
+		ref := []byte(FormatOrderReference("SV", int64(sequence)))
+		if !ValidOrderReference(string(ref)) {
+			return false
+		}
+
+		// change one character of the body or the check character to another valid one
+		index := 3 + int(position)%7
+		if ref[index] == '-' {
+			index++
+		}
+		current := indexOfCrockford(ref[index])
+		ref[index] = crockfordAlphabet[(current+1+int(shift)%31)%32]
+
+		return !ValidOrderReference(string(ref))
+	}
+
+	assert.NoError(t, quick.Check(property, nil))
+}
+
+func TestValidReferencePrefix(t *testing.T) {
+	assert.True(t, ValidReferencePrefix("NBO"))
+	assert.True(t, ValidReferencePrefix("S1"))
+	assert.False(t, ValidReferencePrefix(""))
+	assert.False(t, ValidReferencePrefix("nbo"))
+	assert.False(t, ValidReferencePrefix("NAIRO"))
+	assert.False(t, ValidReferencePrefix("N-B"))
+}
+
+func indexOfCrockford(char byte) int {
+	for i := 0; i < len(crockfordAlphabet); i++ {
+		if crockfordAlphabet[i] == char {
+			return i
+		}
+	}
+	return -1
+}
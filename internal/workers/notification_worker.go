@@ -0,0 +1,101 @@
+package workers
+
+import (
+	"context"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"sync"
+	"time"
+)
+
+type (
+	// NotificationWorker delivers the notification outbox in the background.
+	NotificationWorker interface {
+		Start(ctx context.Context)
+		Shutdown(ctx context.Context) error
+	}
+
+	notificationWorker struct {
+		dB                        db.DB
+		notificationOutboxService services.NotificationOutboxService
+		pollInterval              time.Duration
+		cancel                    context.CancelFunc
+		wg                        sync.WaitGroup
+	}
+)
+
+func NewNotificationWorker(
+	dB db.DB,
+	notificationOutboxService services.NotificationOutboxService,
+	pollInterval time.Duration,
+) NotificationWorker {
+	return &notificationWorker{
+		dB:                        dB,
+		notificationOutboxService: notificationOutboxService,
+		pollInterval:              pollInterval,
+	}
+}
+
+// Start delivers everything that is due, then checks again every pollInterval until
+// ctx is done or the worker is shut down.
+func (w *notificationWorker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			w.deliverDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the worker from taking more notifications and waits for the one it is
+// sending, if any, to be recorded.
+func (w *notificationWorker) Shutdown(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+
+	w.cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *notificationWorker) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		// a notification that has been sent is always recorded, even while shutting down,
+		// so it is not sent again on the next start
+		delivered, err := w.notificationOutboxService.DeliverNext(context.WithoutCancel(ctx), w.dB)
+		if err != nil {
+			loggers.Errorf("failed to deliver notification: [%+v]", err)
+			return
+		}
+
+		if !delivered {
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package workers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+// blockingOutboxService has one notification due, and holds on to it until released.
+type blockingOutboxService struct {
+	services.NotificationOutboxService
+	started  chan struct{}
+	release  chan struct{}
+	finished bool
+}
+
+func (s *blockingOutboxService) DeliverNext(ctx context.Context, dB db.DB) (bool, error) {
+	if s.finished {
+		return false, nil
+	}
+
+	close(s.started)
+	<-s.release
+	s.finished = true
+
+	return true, ctx.Err()
+}
+
+func TestNotificationWorker_ShutdownWaitsForTheNotificationBeingSent(t *testing.T) {
+	outboxService := &blockingOutboxService{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	worker := NewNotificationWorker(nil, outboxService, time.Hour)
+	worker.Start(context.Background())
+	<-outboxService.started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- worker.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown returned while a notification was being sent")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(outboxService.release)
+	assert.NoError(t, <-shutdown)
+	assert.True(t, outboxService.finished)
+}
+
+func TestNotificationWorker_ShutdownGivesUpWhenItsContextIsDone(t *testing.T) {
+	outboxService := &blockingOutboxService{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	defer close(outboxService.release)
+
+	worker := NewNotificationWorker(nil, outboxService, time.Hour)
+	worker.Start(context.Background())
+	<-outboxService.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, worker.Shutdown(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the key of a till or script that cannot log in through the browser.
+const APIKeyHeader = "X-API-Key"
+
+func setAPIKey(c *gin.Context, apiKey *models.APIKey) {
+	c.Set("api_key", apiKey)
+}
+
+// APIKeyFromContext returns the API key the request was made with, if it was not made by a user.
+func APIKeyFromContext(c *gin.Context) (*models.APIKey, bool) {
+	value, exists := c.Get("api_key")
+	if !exists {
+		return nil, false
+	}
+
+	apiKey, ok := value.(*models.APIKey)
+	return apiKey, ok
+}
+
+// apiKeyMembership stands in for a user's shop membership when a request is made with an
+// API key: the key only works in its own shop, and only for the permissions in its scopes.
+func apiKeyMembership(
+	c *gin.Context,
+	apiKey *models.APIKey,
+	permission custom_types.Permission,
+) (*models.ShopMembership, error) {
+
+	shopID, err := shopIDFromRequest(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if shopID != "" && shopID != apiKey.ShopID {
+		return nil, apperr.NewPermission(fmt.Sprintf("api key %s is for shop [%s], not [%s]", apiKey.Prefix, apiKey.ShopID, shopID))
+	}
+
+	if !apiKey.Scopes.Has(permission) {
+		return nil, apperr.NewPermission(fmt.Sprintf("api key %s does not have the [%s] scope", apiKey.Prefix, permission))
+	}
+
+	return &models.ShopMembership{
+		ShopID:    apiKey.ShopID,
+		CreatedBy: apiKey.CreatedBy,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a route safe to retry. When the request carries an Idempotency-Key
+// header the response is stored against the key, and a retry with the same key and body
+// gets that response back instead of running the handler again. Keys are kept per shop,
+// so the middleware must run after RequirePermission. Requests without the header are
+// handled as usual.
+func Idempotency(
+	dB db.DB,
+	idempotencyService services.IdempotencyService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.HandleError(c, apperr.NewErrorWithType(err, apperr.BadRequest))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+
+		idempotencyKey, err := idempotencyService.StartRequest(
+			c.Request.Context(),
+			dB,
+			ShopIDFromContext(c),
+			key,
+			c.Request.Method+" "+c.Request.URL.Path,
+			hex.EncodeToString(hash[:]),
+		)
+		if err != nil {
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		if idempotencyKey.IsCompleted() {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(*idempotencyKey.ResponseStatus, "application/json; charset=utf-8", idempotencyKey.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyResponseWriter{
+			ResponseWriter: c.Writer,
+			body:           &bytes.Buffer{},
+		}
+		c.Writer = writer
+
+		release := func() {
+			err := idempotencyService.ReleaseRequest(c.Request.Context(), dB, idempotencyKey)
+			if err != nil {
+				loggers.Errorf("failed to release idempotency key [%s]: [%+v]", key, err)
+			}
+		}
+
+		// a panicking handler would otherwise leave the key claimed until it goes stale;
+		// the panic carries on to the recovery middleware
+		defer func() {
+			if r := recover(); r != nil {
+				release()
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		// server side failures say nothing about the request, so let the client retry it;
+		// utils.HandleError answers errors it does not recognise with 417
+		if writer.Status() >= http.StatusInternalServerError || writer.Status() == http.StatusExpectationFailed {
+			release()
+			return
+		}
+
+		err = idempotencyService.CompleteRequest(c.Request.Context(), dB, idempotencyKey, writer.Status(), writer.body.Bytes())
+		if err != nil {
+			loggers.Errorf("failed to store response for idempotency key [%s]: [%+v]", key, err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryIdempotencyKeyDomain struct {
+	mu     sync.Mutex
+	nextID int64
+	keys   map[string]*models.IdempotencyKey
+}
+
+func (d *memoryIdempotencyKeyDomain) CreateIdempotencyKey(_ context.Context, _ db.SQLOperations, idempotencyKey *models.IdempotencyKey) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.keys[idempotencyKey.ShopID+idempotencyKey.Key+idempotencyKey.RequestPath]; ok {
+		return false, nil
+	}
+
+	d.nextID++
+	idempotencyKey.ID = d.nextID
+	idempotencyKey.Touch()
+	stored := *idempotencyKey
+	d.keys[idempotencyKey.ShopID+idempotencyKey.Key+idempotencyKey.RequestPath] = &stored
+	return true, nil
+}
+
+func (d *memoryIdempotencyKeyDomain) IdempotencyKeyByKey(_ context.Context, _ db.SQLOperations, shopID, key, requestPath string) (*models.IdempotencyKey, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stored, ok := d.keys[shopID+key+requestPath]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	idempotencyKey := *stored
+	return &idempotencyKey, nil
+}
+
+func (d *memoryIdempotencyKeyDomain) CompleteIdempotencyKey(_ context.Context, _ db.SQLOperations, idempotencyKey *models.IdempotencyKey) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stored := *idempotencyKey
+	d.keys[idempotencyKey.ShopID+idempotencyKey.Key+idempotencyKey.RequestPath] = &stored
+	return nil
+}
+
+func (d *memoryIdempotencyKeyDomain) DeleteIdempotencyKey(_ context.Context, _ db.SQLOperations, idempotencyKeyID int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for k, stored := range d.keys {
+		if stored.ID == idempotencyKeyID {
+			delete(d.keys, k)
+		}
+	}
+	return nil
+}
+
+func (d *memoryIdempotencyKeyDomain) TakeOverIdempotencyKey(_ context.Context, _ db.SQLOperations, idempotencyKey *models.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, stored := range d.keys {
+		if stored.ID == idempotencyKey.ID && !stored.IsCompleted() && stored.CreatedAt.Before(staleBefore) {
+			stored.CreatedAt = time.Now()
+			idempotencyKey.CreatedAt = stored.CreatedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// age moves every unfinished claim back by the given duration.
+func (d *memoryIdempotencyKeyDomain) age(by time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, stored := range d.keys {
+		if !stored.IsCompleted() {
+			stored.CreatedAt = stored.CreatedAt.Add(-by)
+		}
+	}
+}
+
+func setupIdempotencyRouter(status int) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+
+	store := &domain.Store{IdempotencyKeyDomain: &memoryIdempotencyKeyDomain{keys: map[string]*models.IdempotencyKey{}}}
+	calls := 0
+
+	router := gin.New()
+	// stands in for RequirePermission, taking the shop from the header
+	shopMembership := func(c *gin.Context) {
+		SetShopMembership(c, &models.ShopMembership{ShopID: c.GetHeader(ShopIDHeader)})
+	}
+
+	router.POST("/v1/orders", shopMembership, Idempotency(nil, services.NewIdempotencyService(store)), func(c *gin.Context) {
+		calls++
+		c.JSON(status, gin.H{"id": calls})
+	})
+
+	return router, &calls
+}
+
+func postOrder(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	return postShopOrder(router, "1", key, body)
+}
+
+func postShopOrder(router *gin.Engine, shopID, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(body))
+	req.Header.Set(ShopIDHeader, shopID)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysOriginalResponse(t *testing.T) {
+	router, calls := setupIdempotencyRouter(http.StatusCreated)
+
+	first := postOrder(router, "till-1-0001", `{"shop_id":"1"}`)
+	replay := postOrder(router, "till-1-0001", `{"shop_id":"1"}`)
+
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, *calls)
+}
+
+func TestIdempotency_KeysArePerShop(t *testing.T) {
+	router, calls := setupIdempotencyRouter(http.StatusCreated)
+
+	first := postShopOrder(router, "1", "till-1-0001", `{"total":"100.00"}`)
+	other := postShopOrder(router, "2", "till-1-0001", `{"total":"100.00"}`)
+
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get(IdempotentReplayedHeader))
+	assert.NotEqual(t, first.Body.String(), other.Body.String())
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_RejectsDifferentBody(t *testing.T) {
+	router, calls := setupIdempotencyRouter(http.StatusCreated)
+
+	postOrder(router, "till-1-0001", `{"shop_id":"1"}`)
+	w := postOrder(router, "till-1-0001", `{"shop_id":"2"}`)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestIdempotency_ReleasesKeyOnServerError(t *testing.T) {
+	router, calls := setupIdempotencyRouter(http.StatusBadGateway)
+
+	postOrder(router, "till-1-0001", `{"shop_id":"1"}`)
+	postOrder(router, "till-1-0001", `{"shop_id":"1"}`)
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	router, calls := setupIdempotencyRouter(http.StatusCreated)
+
+	postOrder(router, "", `{"shop_id":"1"}`)
+	postOrder(router, "", `{"shop_id":"1"}`)
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_ReleasesKeyWhenTheHandlerPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &domain.Store{IdempotencyKeyDomain: &memoryIdempotencyKeyDomain{keys: map[string]*models.IdempotencyKey{}}}
+	calls := 0
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	shopMembership := func(c *gin.Context) {
+		SetShopMembership(c, &models.ShopMembership{ShopID: c.GetHeader(ShopIDHeader)})
+	}
+	router.POST("/v1/orders", shopMembership, Idempotency(nil, services.NewIdempotencyService(store)), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("till went away")
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	first := postOrder(router, "till-1-0001", `{"shop_id":"1"}`)
+	retry := postOrder(router, "till-1-0001", `{"shop_id":"1"}`)
+
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_TakesOverAStaleClaim(t *testing.T) {
+	keys := &memoryIdempotencyKeyDomain{keys: map[string]*models.IdempotencyKey{}}
+	service := services.NewIdempotencyService(&domain.Store{IdempotencyKeyDomain: keys})
+
+	// a request that claimed the key and then died without finishing
+	_, err := service.StartRequest(context.Background(), nil, "1", "till-1-0001", "POST /v1/orders", "hash")
+	assert.NoError(t, err)
+
+	_, err = service.StartRequest(context.Background(), nil, "1", "till-1-0001", "POST /v1/orders", "hash")
+	assert.Error(t, err, "a fresh claim still holds the key")
+
+	keys.age(time.Hour)
+
+	idempotencyKey, err := service.StartRequest(context.Background(), nil, "1", "till-1-0001", "POST /v1/orders", "hash")
+	assert.NoError(t, err)
+	assert.False(t, idempotencyKey.IsCompleted())
+
+	_, err = service.StartRequest(context.Background(), nil, "1", "till-1-0001", "POST /v1/orders", "hash")
+	assert.Error(t, err, "the takeover is a fresh claim")
+}
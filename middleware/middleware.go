@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"github/Doris-Mwito5/savannah-pos/auth"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
 	"log"
 	"net/http"
 	"strings"
@@ -9,10 +12,48 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(oidcSvc auth.OIDCService) gin.HandlerFunc {
+// AuthMiddleware requires a valid bearer token on every route it guards, except those
+// in publicRoutes, which are given as "METHOD /full/path" (e.g. "GET /v1/auth/login").
+// Routes are protected unless listed, so a new endpoint cannot be left open by accident.
+// Tokens are only accepted for a login session, and refused once it is revoked. Tills and
+// scripts may send an API key in the X-API-Key header instead of a bearer token.
+func AuthMiddleware(
+	dB db.DB,
+	verifier auth.TokenVerifier,
+	sessionService services.SessionService,
+	apiKeyService services.APIKeyService,
+	publicRoutes ...string,
+) gin.HandlerFunc {
+	public := make(map[string]bool, len(publicRoutes))
+	for _, route := range publicRoutes {
+		public[route] = true
+	}
+
 	return func(c *gin.Context) {
+		if public[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+
 		log.Printf("Auth middleware called for path: %s", c.FullPath())
 
+		if rawKey := c.GetHeader(APIKeyHeader); rawKey != "" {
+			apiKey, err := apiKeyService.AuthenticateAPIKey(c.Request.Context(), dB, rawKey)
+			if err != nil {
+				log.Printf("API key authentication failed: %v", err)
+				utils.HandleError(c, err)
+				c.Abort()
+				return
+			}
+
+			log.Printf("API key %s of shop %s authenticated", apiKey.Prefix, apiKey.ShopID)
+
+			setAPIKey(c, apiKey)
+
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			log.Printf("Missing Authorization header")
@@ -44,7 +85,7 @@ func AuthMiddleware(oidcSvc auth.OIDCService) gin.HandlerFunc {
 
 		log.Printf("Verifying token...")
 
-		userInfo, err := oidcSvc.VerifyToken(c.Request.Context(), token)
+		claims, err := verifier.VerifyToken(c.Request.Context(), token)
 		if err != nil {
 			log.Printf("Token verification failed: %v", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -54,19 +95,36 @@ func AuthMiddleware(oidcSvc auth.OIDCService) gin.HandlerFunc {
 			return
 		}
 
-		log.Printf("Token verified successfully for user: %s", userInfo.Email)
+		active, err := sessionService.SessionActive(c.Request.Context(), dB, claims.SessionID)
+		if err != nil {
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		if !active {
+			log.Printf("Token of revoked session %d refused", claims.SessionID)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Invalid or expired token",
+				"message": "Session has been revoked",
+			})
+			return
+		}
+
+		log.Printf("Token verified successfully for user: %s", claims.Email)
 
-		// Store user info in context so handlers can use it
-		c.Set("user", userInfo)
-		c.Set("user_email", userInfo.Email)
-		c.Set("user_sub", userInfo.Sub)
-		c.Set("user_name", userInfo.Name)
+		// Store the claims in context so handlers can use them
+		setClaims(c, claims)
 
 		c.Next()
 	}
 }
 
-func OptionalAuthMiddleware(oidcSvc auth.OIDCService) gin.HandlerFunc {
+func OptionalAuthMiddleware(
+	dB db.DB,
+	verifier auth.TokenVerifier,
+	sessionService services.SessionService,
+) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -85,22 +143,49 @@ func OptionalAuthMiddleware(oidcSvc auth.OIDCService) gin.HandlerFunc {
 			return
 		}
 
-		userInfo, err := oidcSvc.VerifyToken(c.Request.Context(), token)
+		claims, err := verifier.VerifyToken(c.Request.Context(), token)
 		if err != nil {
 			log.Printf("Optional auth failed: %v", err)
 			c.Next()
 			return
 		}
 
-		c.Set("user", userInfo)
-		c.Set("user_email", userInfo.Email)
-		c.Set("user_sub", userInfo.Sub)
-		c.Set("user_name", userInfo.Name)
+		active, err := sessionService.SessionActive(c.Request.Context(), dB, claims.SessionID)
+		if err != nil || !active {
+			log.Printf("Optional auth failed: session %d is not active", claims.SessionID)
+			c.Next()
+			return
+		}
+
+		setClaims(c, claims)
 
 		c.Next()
 	}
 }
 
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set("claims", claims)
+	c.Set("user", &auth.OIDCUserInfo{
+		Sub:   claims.Sub,
+		Email: claims.Email,
+		Name:  claims.Name,
+	})
+	c.Set("user_email", claims.Email)
+	c.Set("user_sub", claims.Sub)
+	c.Set("user_name", claims.Name)
+}
+
+// ClaimsFromContext returns the verified token claims of the caller.
+func ClaimsFromContext(c *gin.Context) (*auth.Claims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := value.(*auth.Claims)
+	return claims, ok
+}
+
 func GetUserFromContext(c *gin.Context) (*auth.OIDCUserInfo, bool) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -116,4 +201,17 @@ func min(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
+
+// ActorFromContext returns an identifier for the caller, used when recording who made a change.
+func ActorFromContext(c *gin.Context) string {
+	if apiKey, ok := APIKeyFromContext(c); ok {
+		return "api-key:" + apiKey.Prefix
+	}
+
+	if userInfo, ok := GetUserFromContext(c); ok && userInfo.Email != "" {
+		return userInfo.Email
+	}
+
+	return "anonymous"
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github/Doris-Mwito5/savannah-pos/auth"
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+)
+
+// fakeStaffService knows each subject's role per shop.
+type fakeStaffService struct {
+	services.StaffService
+	roles map[string]map[string]custom_types.StaffRole
+}
+
+func (s fakeStaffService) ShopMembership(_ context.Context, _ db.DB, subject, shopID string) (*models.ShopMembership, error) {
+	shops := s.roles[subject]
+	if shopID == "" && len(shops) == 1 {
+		for only := range shops {
+			shopID = only
+		}
+	}
+
+	role, ok := shops[shopID]
+	if !ok {
+		return nil, apperr.NewPermission("not a member")
+	}
+
+	return &models.ShopMembership{ShopID: shopID, Role: role}, nil
+}
+
+func setupPermissionRouter(subject string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	staffService := fakeStaffService{roles: map[string]map[string]custom_types.StaffRole{
+		"cashier": {"shop-1": custom_types.StaffRoleCashier},
+		"manager": {"shop-1": custom_types.StaffRoleManager, "shop-2": custom_types.StaffRoleViewer},
+	}}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if subject != "" {
+			setClaims(c, &auth.Claims{Sub: subject, Email: subject + "@example.com"})
+		}
+	})
+
+	ok := func(c *gin.Context) {
+		membership, _ := ShopMembershipFromContext(c)
+		c.JSON(http.StatusOK, gin.H{"shop_id": membership.ShopID})
+	}
+
+	router.POST("/orders", RequirePermission(nil, staffService, custom_types.PermissionCreateOrders), ok)
+	router.DELETE("/products/:id", RequirePermission(nil, staffService, custom_types.PermissionDeleteProducts), ok)
+	router.GET("/shop/:id/orders", RequirePermission(nil, staffService, custom_types.PermissionViewShop), ok)
+
+	return router
+}
+
+func callAsShop(router *gin.Engine, method, path, shopID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if shopID != "" {
+		req.Header.Set(ShopIDHeader, shopID)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequirePermission_Cashier(t *testing.T) {
+	router := setupPermissionRouter("cashier")
+
+	w := callAsShop(router, http.MethodPost, "/orders", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"shop_id":"shop-1"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, callAsShop(router, http.MethodDelete, "/products/1", "").Code)
+	assert.Equal(t, http.StatusForbidden, callAsShop(router, http.MethodPost, "/orders", "shop-2").Code)
+	assert.Equal(t, http.StatusForbidden, callAsShop(router, http.MethodGet, "/shop/shop-2/orders", "").Code)
+}
+
+func TestRequirePermission_RoleIsPerShop(t *testing.T) {
+	router := setupPermissionRouter("manager")
+
+	assert.Equal(t, http.StatusOK, callAsShop(router, http.MethodDelete, "/products/1", "shop-1").Code)
+	// a viewer in shop-2
+	assert.Equal(t, http.StatusForbidden, callAsShop(router, http.MethodDelete, "/products/1", "shop-2").Code)
+
+	w := callAsShop(router, http.MethodGet, "/shop/shop-2/orders", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"shop_id":"shop-2"}`, w.Body.String())
+
+	// the header cannot point somewhere other than the path
+	assert.Equal(t, http.StatusBadRequest, callAsShop(router, http.MethodGet, "/shop/shop-2/orders", "shop-1").Code)
+}
+
+func TestRequirePermission_NeedsAuthentication(t *testing.T) {
+	router := setupPermissionRouter("")
+
+	assert.Equal(t, http.StatusUnauthorized, callAsShop(router, http.MethodPost, "/orders", "shop-1").Code)
+}
+
+func TestRequirePermission_APIKeyIsLimitedToItsShopAndScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	loggers.InitLogger("test")
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		setAPIKey(c, &models.APIKey{
+			ShopID: "shop-1",
+			Prefix: "spos_til",
+			Scopes: custom_types.Permissions{custom_types.PermissionCreateOrders, custom_types.PermissionViewShop},
+		})
+	})
+
+	ok := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"shop_id": ShopIDFromContext(c)})
+	}
+
+	// the key needs no staff membership, so there is no staff service to ask
+	router.POST("/orders", RequirePermission(nil, nil, custom_types.PermissionCreateOrders), ok)
+	router.DELETE("/products/:id", RequirePermission(nil, nil, custom_types.PermissionDeleteProducts), ok)
+	router.GET("/shop/:id/orders", RequirePermission(nil, nil, custom_types.PermissionViewShop), ok)
+
+	w := callAsShop(router, http.MethodPost, "/orders", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"shop_id":"shop-1"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, callAsShop(router, http.MethodGet, "/shop/shop-1/orders", "").Code)
+
+	// outside its scopes
+	assert.Equal(t, http.StatusForbidden, callAsShop(router, http.MethodDelete, "/products/1", "").Code)
+	// another shop
+	assert.Equal(t, http.StatusForbidden, callAsShop(router, http.MethodPost, "/orders", "shop-2").Code)
+	assert.Equal(t, http.StatusForbidden, callAsShop(router, http.MethodGet, "/shop/shop-2/orders", "").Code)
+}
@@ -0,0 +1,23 @@
+package apikeys
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	apiKeyService services.APIKeyService,
+	staffService services.StaffService,
+) {
+	manageAPIKeys := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageAPIKeys)
+
+	r.POST("/shop/:id/api-keys", manageAPIKeys, createAPIKey(dB, apiKeyService))
+	r.GET("/shop/:id/api-keys", manageAPIKeys, listAPIKeys(dB, apiKeyService))
+	r.DELETE("/shop/:id/api-keys/:key_id", manageAPIKeys, revokeAPIKey(dB, apiKeyService))
+}
@@ -0,0 +1,84 @@
+package apikeys
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createAPIKey(
+	dB db.DB,
+	apiKeyService services.APIKeyService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.CreateAPIKeyForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		createdAPIKey, err := apiKeyService.CreateAPIKey(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, createdAPIKey)
+	}
+}
+
+func listAPIKeys(
+	dB db.DB,
+	apiKeyService services.APIKeyService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		apiKeys, err := apiKeyService.ListAPIKeys(c.Request.Context(), dB, middleware.ShopIDFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, apiKeys)
+	}
+}
+
+func revokeAPIKey(
+	dB db.DB,
+	apiKeyService services.APIKeyService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		apiKeyID, err := strconv.ParseInt(c.Param("key_id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		apiKey, err := apiKeyService.RevokeAPIKey(c.Request.Context(), dB, middleware.ShopIDFromContext(c), apiKeyID, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, apiKey)
+	}
+}
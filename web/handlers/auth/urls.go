@@ -13,12 +13,13 @@ func AddEndpoints(
 	r *gin.RouterGroup,
 	dB db.DB,
 	oidcService auth.OIDCService,
-	customerService services.CustomerService,
-	jwtSecret string,
+	staffService services.StaffService,
+	sessionService services.SessionService,
 ) {
 	r.GET("/auth/login", login(dB, oidcService))
-	r.GET("/auth/callback", callback(dB, oidcService, customerService, jwtSecret))
-	r.POST("/auth/logout", logout(dB))
+	r.GET("/auth/callback", callback(dB, oidcService, staffService, sessionService))
+	r.POST("/auth/refresh", refresh(dB, sessionService))
+	r.POST("/auth/logout", logout(dB, sessionService))
 
 	// Protected routes
 	authGroup := r.Group("/auth")
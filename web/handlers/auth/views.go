@@ -3,10 +3,10 @@ package auth
 import (
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 
 	"github/Doris-Mwito5/savannah-pos/auth"
 	"github/Doris-Mwito5/savannah-pos/internal/apperr"
@@ -15,16 +15,9 @@ import (
 	"github/Doris-Mwito5/savannah-pos/internal/models"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
 	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 )
 
-// JWT Claims structure
-type Claims struct {
-	CustomerID int64  `json:"customer_id"`
-	Email      string `json:"email"`
-	Sub        string `json:"sub"`
-	jwt.RegisteredClaims
-}
-
 func login(
 	dB db.DB,
 	oidcService auth.OIDCService,
@@ -32,7 +25,8 @@ func login(
 	return func(c *gin.Context) {
 		log.Printf("Login request received from IP: %s", c.ClientIP())
 
-		authURL, state, err := oidcService.CreateAuthURL(c)
+		// SPA and mobile clients name where they want the tokens; it must be an allowed redirect
+		authURL, state, err := oidcService.CreateAuthURL(c, c.Query("redirect_uri"))
 		if err != nil {
 			log.Printf("Failed to create auth URL: %v", err)
 			appErr := apperr.NewErrorWithType(
@@ -63,8 +57,8 @@ func login(
 func callback(
 	dB db.DB,
 	oidcService auth.OIDCService,
-	customerService services.CustomerService,
-	jwtSecret string,
+	staffService services.StaffService,
+	sessionService services.SessionService,
 ) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		code := c.Query("code")
@@ -89,20 +83,17 @@ func callback(
 			return
 		}
 
-		// Verify state parameter from cookie
-		savedState, err := c.Cookie("oauth_state")
-		if err != nil || savedState != state {
-			log.Printf("State mismatch. Expected: %s, Got: %s", savedState, state)
+		// Verify state against the signed login cookie, which is cleared once read
+		loginState, err := oidcService.LoginState(c, state)
+		if err != nil {
+			log.Printf("Invalid login state: %v", err)
 			appErr := apperr.NewBadRequest("invalid or missing state parameter")
 			utils.HandleError(c, appErr)
 			return
 		}
 
-		// Clear state cookie
-		c.SetCookie("oauth_state", "", -1, "/", "", false, true)
-
-		// Exchange code for tokens and user info
-		userInfo, err := oidcService.HandleCallback(c.Request.Context(), code, state)
+		// Exchange code for tokens (with the PKCE verifier) and check the ID token's nonce
+		userInfo, err := oidcService.HandleCallback(c.Request.Context(), code, loginState)
 		if err != nil {
 			log.Printf("Failed to handle callback: %v", err)
 			utils.HandleError(c, apperr.NewBadRequest("failed to complete authentication"))
@@ -111,68 +102,104 @@ func callback(
 
 		log.Printf("Authentication successful for user: %s", userInfo.Email)
 
-		// Try to fetch customer by email
-		customer, err := customerService.CustomerByEmail(c.Request.Context(), dB, userInfo.Email)
+		// Logins are staff; the first login creates the user
+		user, err := staffService.UserForLogin(c.Request.Context(), dB, userInfo.Sub, userInfo.Email, userInfo.Name, userInfo.EmailVerified)
 		if err != nil {
-			log.Printf("Customer not found, creating new: %s", userInfo.Email)
-
-			newCustomer := &dtos.CreateCustomerForm{
-				Email: userInfo.Email,
-				Name:  userInfo.Name,
-			}
-
-			customer, err = customerService.CreateCustomer(c.Request.Context(), dB, newCustomer)
-			if err != nil {
-				log.Printf("Failed to create customer: %v", err)
-				utils.HandleError(c, apperr.NewInternal("could not create customer"))
-				return
-			}
+			log.Printf("Failed to resolve user: %v", err)
+			utils.HandleError(c, err)
+			return
 		}
 
-		// Generate JWT token
-		token, expiresAt, err := generateJWT(customer, userInfo, jwtSecret)
+		// Start a session: a short-lived access token and a refresh token to renew it
+		sessionTokens, err := sessionService.StartSession(c.Request.Context(), dB, &auth.Claims{
+			Email: user.Email,
+			Name:  user.Name,
+			Sub:   userInfo.Sub,
+		})
 		if err != nil {
-			log.Printf("Failed to generate JWT: %v", err)
-			utils.HandleError(c, apperr.NewInternal("failed to generate token"))
+			log.Printf("Failed to start session: %v", err)
+			utils.HandleError(c, err)
+			return
+		}
+
+		if loginState.RedirectURL != "" {
+			c.Redirect(http.StatusFound, postLoginRedirect(loginState.RedirectURL, sessionTokens))
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"message":    "Authentication successful",
-			"token":      token,
-			"expires_at": expiresAt,
-			"customer":   customer,
-			"user_info":  userInfo,
+			"message":                  "Authentication successful",
+			"token":                    sessionTokens.AccessToken,
+			"expires_at":               sessionTokens.AccessTokenExpiresAt,
+			"token_type":               sessionTokens.TokenType,
+			"refresh_token":            sessionTokens.RefreshToken,
+			"refresh_token_expires_at": sessionTokens.RefreshTokenExpiresAt,
+			"user":                     user,
+			"user_info":                userInfo,
 		})
 	}
 }
 
-func generateJWT(customer *models.Customer, userInfo *auth.OIDCUserInfo, jwtSecret string) (string, time.Time, error) {
-	expiresAt := time.Now().Add(24 * time.Hour)
-
-	claims := Claims{
-		CustomerID: customer.ID,
-		Email:      userInfo.Email,
-		Sub:        userInfo.Sub,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   userInfo.Sub,
-		},
-	}
+// postLoginRedirect hands the tokens to the client in the URL fragment, which browsers
+// do not send on to the server behind the redirect.
+func postLoginRedirect(
+	redirectURL string,
+	sessionTokens *models.SessionTokens,
+) string {
+
+	fragment := url.Values{}
+	fragment.Set("access_token", sessionTokens.AccessToken)
+	fragment.Set("token_type", sessionTokens.TokenType)
+	fragment.Set("expires_at", sessionTokens.AccessTokenExpiresAt.Format(time.RFC3339))
+	fragment.Set("refresh_token", sessionTokens.RefreshToken)
+	fragment.Set("refresh_token_expires_at", sessionTokens.RefreshTokenExpiresAt.Format(time.RFC3339))
+
+	return redirectURL + "#" + fragment.Encode()
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(jwtSecret))
-	return signed, expiresAt, err
+func refresh(
+	dB db.DB,
+	sessionService services.SessionService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form dtos.RefreshTokenForm
+
+		err := c.BindJSON(&form)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		sessionTokens, err := sessionService.RefreshSession(c.Request.Context(), dB, form.RefreshToken)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, sessionTokens)
+	}
 }
 
 func logout(
 	dB db.DB,
+	sessionService services.SessionService,
 ) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		log.Printf("Logout request from IP: %s", c.ClientIP())
 
-		// In production: invalidate JWT or clear session
+		// ID tokens from the identity provider have no session here; they simply expire
+		claims, ok := middleware.ClaimsFromContext(c)
+		if ok && claims.SessionID != 0 {
+			err := sessionService.RevokeSession(c.Request.Context(), dB, claims.SessionID)
+			if err != nil {
+				utils.HandleError(c, err)
+				return
+			}
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Logged out successfully",
@@ -1,8 +1,10 @@
 package categories
 
 import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,8 +14,12 @@ func AddEndpoints(
 	r *gin.RouterGroup,
 	dB db.DB,
 	categoryService services.CategoryService,
+	staffService services.StaffService,
 ) {
-	r.POST("/categories", createCategory(dB, categoryService))
-	r.GET("/categories/:id", getCategory(dB, categoryService))
-	r.GET("/shop/:id/categories", listCategories(dB, categoryService))
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	manageCatalog := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageCatalog)
+
+	r.POST("/categories", manageCatalog, createCategory(dB, categoryService))
+	r.GET("/categories/:id", view, getCategory(dB, categoryService))
+	r.GET("/shop/:id/categories", view, listCategories(dB, categoryService))
 }
@@ -7,9 +7,9 @@ import (
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
 	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,7 +32,7 @@ func createCategory(
 			return
 		}
 
-		category, err := categoryService.CreateCategory(c.Request.Context(), dB, &req)
+		category, err := categoryService.CreateCategory(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -57,7 +57,7 @@ func getCategory(
 			utils.HandleError(c, appErr)
 			return
 		}
-		category, err := categoryService.CategoryByID(c.Request.Context(), dB, categoryID)
+		category, err := categoryService.CategoryByID(c.Request.Context(), dB, middleware.ShopIDFromContext(c), categoryID)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -73,12 +73,7 @@ func listCategories(
 ) func (c *gin.Context) {
 	return func(c *gin.Context) {
 
-		shopID := c.Param("id")
-		if len(strings.TrimSpace(shopID)) < 1{
-			appErr := apperr.NewBadRequest("shop id not set")
-			utils.HandleError(c, appErr)
-			return
-		}
+		shopID := middleware.ShopIDFromContext(c)
 
 		filter, err := ctxfilter.FilterFromContext(c)
 		if err != nil {
@@ -0,0 +1,91 @@
+package customers
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setNotificationPreference records how the customer told staff they want to hear about
+// their orders: sms, email or none.
+func setNotificationPreference(
+	dB db.DB,
+	consentService services.ConsentService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		var req dtos.NotificationPreferenceForm
+
+		err = c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		customer, err := consentService.SetNotificationPreference(c.Request.Context(), dB, middleware.ShopIDFromContext(c), customerID, &req, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, customer)
+	}
+}
+
+func listConsentChanges(
+	dB db.DB,
+	consentService services.ConsentService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		consentChangeList, err := consentService.ListConsentChanges(c.Request.Context(), dB, middleware.ShopIDFromContext(c), customerID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, consentChangeList)
+	}
+}
@@ -1,8 +1,10 @@
 package customers
 
 import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,9 +13,16 @@ func AddEndpoints(
 	r *gin.RouterGroup,
 	dB db.DB,
 	customerService services.CustomerService,
+	consentService services.ConsentService,
+	staffService services.StaffService,
 ) {
-	r.POST("/customers", createCustomer(dB, customerService))
-	r.PUT("/customers/:id", updateCustomer(dB, customerService))
-	r.GET("/customers/:id", getCustomer(dB, customerService))
-	r.GET("/shop/:id/customers", listCustomers(dB, customerService))
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	manageCustomers := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageCustomers)
+
+	r.POST("/customers", manageCustomers, createCustomer(dB, customerService))
+	r.PUT("/customers/:id", manageCustomers, updateCustomer(dB, customerService))
+	r.GET("/customers/:id", view, getCustomer(dB, customerService))
+	r.GET("/shop/:id/customers", view, listCustomers(dB, customerService))
+	r.PUT("/customers/:id/notification-preference", manageCustomers, setNotificationPreference(dB, consentService))
+	r.GET("/customers/:id/consent-changes", view, listConsentChanges(dB, consentService))
 }
@@ -7,10 +7,10 @@ import (
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
 	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,7 +36,7 @@ func createCustomer(
 		log.Printf("DEBUG: Received data: %+v", req)
 		log.Printf("DEBUG: Name: '%s', Email: '%s', CustomerType: '%v'", req.Name, req.Email, req.CustomerType)
 
-		customer, err := customerService.CreateCustomer(c.Request.Context(), dB, &req)
+		customer, err := customerService.CreateCustomer(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -75,7 +75,7 @@ func updateCustomer(
 			return
 		}
 
-		customer, err := customerService.UpdateCustomer(c.Request.Context(), dB, customerID, &req)
+		customer, err := customerService.UpdateCustomer(c.Request.Context(), dB, middleware.ShopIDFromContext(c), customerID, &req)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -102,7 +102,7 @@ func getCustomer(
 			return
 		}
 
-		customer, err := customerService.CustomerByID(c.Request.Context(), dB, customerID)
+		customer, err := customerService.CustomerByID(c.Request.Context(), dB, middleware.ShopIDFromContext(c), customerID)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -117,12 +117,7 @@ func listCustomers(
 	customerService services.CustomerService,
 ) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		shopID := c.Param("id")
-		if len(strings.TrimSpace(shopID)) < 1 {
-			appErr := apperr.NewBadRequest("shop id not set")
-			utils.HandleError(c, appErr)
-			return
-		}
+		shopID := middleware.ShopIDFromContext(c)
 
 		filter, err := ctxfilter.FilterFromContext(c)
 		if err != nil {
@@ -0,0 +1,14 @@
+package health
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+) {
+	r.GET("/health", healthCheck(dB))
+}
@@ -0,0 +1,27 @@
+package health
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthCheck reports whether the API can reach its database. It is public, so it
+// says nothing beyond up or down.
+func healthCheck(
+	dB db.DB,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		err := dB.Ping()
+		if err != nil {
+			loggers.Errorf("health check failed to ping the database: [%+v]", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
@@ -0,0 +1,30 @@
+package notifications
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	notificationOutboxService services.NotificationOutboxService,
+	notificationTemplateService services.NotificationTemplateService,
+	staffService services.StaffService,
+) {
+	manageOrders := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageOrders)
+	viewShop := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	manageShop := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageShop)
+
+	r.GET("/shop/:id/notifications", manageOrders, listNotifications(dB, notificationOutboxService))
+	r.POST("/shop/:id/notifications/:notification_id/replay", manageOrders, replayNotification(dB, notificationOutboxService))
+
+	r.GET("/shop/:id/notification-templates", viewShop, listNotificationTemplates(dB, notificationTemplateService))
+	r.PUT("/shop/:id/notification-templates", manageShop, saveNotificationTemplate(dB, notificationTemplateService))
+	r.DELETE("/shop/:id/notification-templates/:template_id", manageShop, deleteNotificationTemplate(dB, notificationTemplateService))
+	r.POST("/shop/:id/notification-templates/preview", viewShop, previewNotificationTemplate(dB, notificationTemplateService))
+}
@@ -0,0 +1,170 @@
+package notifications
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listNotifications shows a shop's outbox; ?status=dead lists the ones that need replaying.
+func listNotifications(
+	dB db.DB,
+	notificationOutboxService services.NotificationOutboxService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		notificationList, err := notificationOutboxService.ListNotifications(c.Request.Context(), dB, middleware.ShopIDFromContext(c), filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, notificationList)
+	}
+}
+
+func replayNotification(
+	dB db.DB,
+	notificationOutboxService services.NotificationOutboxService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		notificationID, err := strconv.ParseInt(c.Param("notification_id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		outboxNotification, err := notificationOutboxService.ReplayNotification(c.Request.Context(), dB, middleware.ShopIDFromContext(c), notificationID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, outboxNotification)
+	}
+}
+
+// listNotificationTemplates lists the templates the shop has replaced; anything not listed
+// is sent from the built-in templates.
+func listNotificationTemplates(
+	dB db.DB,
+	notificationTemplateService services.NotificationTemplateService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		notificationTemplates, err := notificationTemplateService.ListNotificationTemplates(c.Request.Context(), dB, middleware.ShopIDFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, notificationTemplates)
+	}
+}
+
+func saveNotificationTemplate(
+	dB db.DB,
+	notificationTemplateService services.NotificationTemplateService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var form dtos.NotificationTemplateForm
+
+		err := c.BindJSON(&form)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		notificationTemplate, err := notificationTemplateService.SaveNotificationTemplate(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &form)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, notificationTemplate)
+	}
+}
+
+func deleteNotificationTemplate(
+	dB db.DB,
+	notificationTemplateService services.NotificationTemplateService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		templateID, err := strconv.ParseInt(c.Param("template_id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		notificationTemplate, err := notificationTemplateService.DeleteNotificationTemplate(c.Request.Context(), dB, middleware.ShopIDFromContext(c), templateID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, notificationTemplate)
+	}
+}
+
+// previewNotificationTemplate renders a draft, or the template in use when no body is
+// sent, against a sample order.
+func previewNotificationTemplate(
+	dB db.DB,
+	notificationTemplateService services.NotificationTemplateService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var form dtos.PreviewNotificationTemplateForm
+
+		err := c.BindJSON(&form)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		rendered, err := notificationTemplateService.PreviewNotificationTemplate(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &form)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, rendered)
+	}
+}
@@ -0,0 +1,89 @@
+package orders
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createOrderReturn(
+	dB db.DB,
+	returnService services.ReturnService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		var req dtos.CreateOrderReturnForm
+
+		err = c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		orderReturn, err := returnService.CreateOrderReturn(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID, &req, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, orderReturn)
+	}
+}
+
+func listOrderReturns(
+	dB db.DB,
+	returnService services.ReturnService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appError := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appError)
+			return
+		}
+
+		orderReturnList, err := returnService.ListOrderReturns(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, orderReturnList)
+	}
+}
@@ -1,8 +1,10 @@
 package orders
 
 import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,10 +13,20 @@ func AddEndpoints(
 	r *gin.RouterGroup,
 	dB db.DB,
 	orderService services.OrderService,
+	returnService services.ReturnService,
+	idempotencyService services.IdempotencyService,
+	staffService services.StaffService,
 ) {
-	r.POST("/orders", createOrder(dB, orderService))
-	// r.PUT("/orders/:id", updateOrder(dB, orderService))
-	r.GET("/orders/:id", getOrder(dB, orderService))
-	r.GET("/shop/:id/orders", listOrders(dB, orderService))
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	createOrders := middleware.RequirePermission(dB, staffService, custom_types.PermissionCreateOrders)
+	manageOrders := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageOrders)
+
+	r.POST("/orders", createOrders, middleware.Idempotency(dB, idempotencyService), createOrder(dB, orderService))
+	r.PUT("/orders/:id", manageOrders, updateOrder(dB, orderService))
+	r.GET("/orders/:id", view, getOrder(dB, orderService))
+	r.GET("/orders/:id/transitions", view, listOrderStatusTransitions(dB, orderService))
+	r.POST("/orders/:id/returns", manageOrders, createOrderReturn(dB, returnService))
+	r.GET("/orders/:id/returns", view, listOrderReturns(dB, returnService))
+	r.GET("/shop/:id/orders", view, listOrders(dB, orderService))
 
 }
@@ -7,9 +7,9 @@ import (
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
 	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,7 +32,7 @@ func createOrder(
 			return
 		}
 
-		order, err := orderService.CreateOrder(c.Request.Context(), dB, &req)
+		order, err := orderService.CreateOrder(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req, middleware.ActorFromContext(c))
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -43,44 +43,44 @@ func createOrder(
 	}
 }
 
-// func updateOrder(
-// 	dB db.DB,
-// 	orderService services.OrderService,
-// ) func(c *gin.Context) {
-// 	return func(c *gin.Context) {
-
-// 		var req dtos.UpdateOrderForm
-
-// 		err := c.BindJSON(&req)
-// 		if err != nil {
-// 			appErr := apperr.NewErrorWithType(
-// 				err,
-// 				apperr.BadRequest,
-// 			)
-// 			utils.HandleError(c, appErr)
-// 			return
-// 		}
-
-// 		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
-// 		if err != nil {
-// 			appErr := apperr.NewErrorWithType(
-// 				err,
-// 				apperr.BadRequest,
-// 			)
-// 			utils.HandleError(c, appErr)
-// 			return
-// 		}
-
-// 		order, err := orderService.UpdateProduct(c.Request.Context(), dB, orderID, &req)
-// 		if err != nil {
-// 			utils.HandleError(c, err)
-// 			return
-// 		}
-
-// 		c.JSON(http.StatusOK, order)
-
-// 	}
-// }
+func updateOrder(
+	dB db.DB,
+	orderService services.OrderService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.UpdateOrderForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		order, err := orderService.UpdateOrder(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID, &req, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+
+	}
+}
 
 func getOrder(
 	dB db.DB,
@@ -98,7 +98,7 @@ func getOrder(
 			return
 		}
 
-		order, err := orderService.OrderByID(c.Request.Context(), dB, orderID)
+		order, err := orderService.OrderByID(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -113,9 +113,38 @@ func listOrders(
 	orderService services.OrderService,
 ) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		shopID := c.Param("id")
-		if len(strings.TrimSpace(shopID)) < 1 {
-			appErr := apperr.NewBadRequest("shop id not set")
+		shopID := middleware.ShopIDFromContext(c)
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appError := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appError)
+			return
+		}
+
+		orderList, err := orderService.ListShopOrders(c.Request.Context(), dB, shopID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+		}
+		c.JSON(http.StatusOK, orderList)
+	}
+}
+
+func listOrderStatusTransitions(
+	dB db.DB,
+	orderService services.OrderService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
 			utils.HandleError(c, appErr)
 			return
 		}
@@ -130,10 +159,12 @@ func listOrders(
 			return
 		}
 
-		orderList, err := orderService.ListShopOrders(c.Request.Context(), dB, shopID, filter)
+		transitionList, err := orderService.ListOrderStatusTransitions(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID, filter)
 		if err != nil {
 			utils.HandleError(c, err)
+			return
 		}
-		c.JSON(http.StatusOK, orderList)
+
+		c.JSON(http.StatusOK, transitionList)
 	}
 }
@@ -0,0 +1,34 @@
+package payments
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	paymentService services.PaymentService,
+	mpesaService services.MpesaService,
+	callbackToken string,
+	staffService services.StaffService,
+) {
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	takePayments := middleware.RequirePermission(dB, staffService, custom_types.PermissionTakePayments)
+
+	r.POST("/orders/:id/payments", takePayments, recordPayments(dB, paymentService))
+	r.GET("/orders/:id/payments", view, listPayments(dB, paymentService))
+	r.POST("/orders/:id/mpesa/stk-push", takePayments, initiateSTKPush(dB, mpesaService))
+	r.GET("/orders/:id/mpesa/stk-push", view, listSTKRequests(dB, mpesaService))
+	r.DELETE("/orders/:id/mpesa/stk-push", takePayments, cancelSTKPush(dB, mpesaService))
+	if callbackToken == "" {
+		loggers.Warn("MPESA_CALLBACK_TOKEN is not set; M-Pesa callbacks will be refused")
+	}
+
+	r.POST("/payments/mpesa/callback", mpesaCallback(dB, mpesaService, callbackToken))
+}
@@ -0,0 +1,208 @@
+package payments
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func recordPayments(
+	dB db.DB,
+	paymentService services.PaymentService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		var req dtos.RecordPaymentsForm
+
+		err = c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		orderPayments, err := paymentService.RecordPayments(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID, &req, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, orderPayments)
+	}
+}
+
+func listPayments(
+	dB db.DB,
+	paymentService services.PaymentService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		orderPayments, err := paymentService.ListPayments(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, orderPayments)
+	}
+}
+
+func initiateSTKPush(
+	dB db.DB,
+	mpesaService services.MpesaService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		var req dtos.InitiateSTKPushForm
+
+		// the body is optional; without one the order's phone number is prompted
+		if c.Request.ContentLength != 0 {
+			err = c.BindJSON(&req)
+			if err != nil {
+				appErr := apperr.NewErrorWithType(
+					err,
+					apperr.BadRequest,
+				)
+				utils.HandleError(c, appErr)
+				return
+			}
+		}
+
+		stkRequest, err := mpesaService.InitiateSTKPush(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID, &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, stkRequest)
+	}
+}
+
+func listSTKRequests(
+	dB db.DB,
+	mpesaService services.MpesaService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		stkRequests, err := mpesaService.ListSTKRequests(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, stkRequests)
+	}
+}
+
+// cancelSTKPush stops the order waiting on its M-Pesa prompt, so another tender can be taken.
+func cancelSTKPush(
+	dB db.DB,
+	mpesaService services.MpesaService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		orderPayments, err := mpesaService.CancelSTKPush(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, orderPayments)
+	}
+}
+
+// mpesaCallback receives Daraja's STK Push results. Daraja only reads ResultCode from
+// the reply, so anything other than an accepted callback is answered with an error status.
+func mpesaCallback(
+	dB db.DB,
+	mpesaService services.MpesaService,
+	callbackToken string,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		if !utils.ValidCallbackToken(c.Query("token"), callbackToken) {
+			utils.HandleError(c, apperr.NewAuthorization("invalid callback token"))
+			return
+		}
+
+		var req dtos.MpesaCallbackForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		err = mpesaService.HandleSTKCallback(c.Request.Context(), dB, &req.Body.STKCallback)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"ResultCode": 0, "ResultDesc": "Accepted"})
+	}
+}
@@ -0,0 +1,115 @@
+package products
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createStockMovement(
+	dB db.DB,
+	stockService services.StockService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.CreateStockMovementForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		movement, err := stockService.RecordStockMovement(c.Request.Context(), dB, middleware.ShopIDFromContext(c), productID, &req, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, movement)
+	}
+}
+
+func listStockMovements(
+	dB db.DB,
+	stockService services.StockService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appError := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appError)
+			return
+		}
+
+		movementList, err := stockService.ListProductStockMovements(c.Request.Context(), dB, middleware.ShopIDFromContext(c), productID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, movementList)
+	}
+}
+
+func getStockLevel(
+	dB db.DB,
+	stockService services.StockService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		stockLevel, err := stockService.ProductStockLevel(c.Request.Context(), dB, middleware.ShopIDFromContext(c), productID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, stockLevel)
+	}
+}
@@ -1,8 +1,10 @@
 package products
 
 import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,11 +13,24 @@ func AddEndpoints(
 	r *gin.RouterGroup,
 	dB db.DB,
 	productService services.ProductService,
+	stockService services.StockService,
+	staffService services.StaffService,
 ) {
-	r.POST("/products", createProduct(dB, productService))
-	r.PUT("/products/:id", updateProduct(dB, productService))
-	r.GET("/products/:id", getProduct(dB, productService))
-	r.GET("/shop/:id/products", listProducts(dB, productService))
-	r.DELETE("/products/:id", deleteProduct(dB, productService))
-	r.GET("/categories/:id/average-price", getAveragePriceByCategory(dB, productService))
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	createProducts := middleware.RequirePermission(dB, staffService, custom_types.PermissionCreateProducts)
+	// changing wholesale_price, category_id or tax_class_id needs more; updateProduct
+	// checks them against the form
+	updateProducts := middleware.RequirePermission(dB, staffService, custom_types.PermissionUpdateProducts)
+	deleteProducts := middleware.RequirePermission(dB, staffService, custom_types.PermissionDeleteProducts)
+	manageStock := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageStock)
+
+	r.POST("/products", createProducts, createProduct(dB, productService))
+	r.PUT("/products/:id", updateProducts, updateProduct(dB, productService))
+	r.GET("/products/:id", view, getProduct(dB, productService))
+	r.GET("/shop/:id/products", view, listProducts(dB, productService))
+	r.DELETE("/products/:id", deleteProducts, deleteProduct(dB, productService))
+	r.GET("/categories/:id/average-price", view, getAveragePriceByCategory(dB, productService))
+	r.POST("/products/:id/stock-movements", manageStock, createStockMovement(dB, stockService))
+	r.GET("/products/:id/stock-movements", view, listStockMovements(dB, stockService))
+	r.GET("/products/:id/stock", view, getStockLevel(dB, stockService))
 }
@@ -3,13 +3,14 @@ package products
 import (
 	"github/Doris-Mwito5/savannah-pos/internal/apperr"
 	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
 	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,7 +33,7 @@ func createProduct(
 			return
 		}
 
-		product, err := productService.CreateProduct(c.Request.Context(), dB, &req)
+		product, err := productService.CreateProduct(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req, middleware.ActorFromContext(c))
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -61,6 +62,19 @@ func updateProduct(
 			return
 		}
 
+		if req.WholesalePrice != nil && !middleware.HasPermission(c, custom_types.PermissionSetWholesalePrice) {
+			appErr := apperr.NewPermission("your role cannot change a product's wholesale price")
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		// the category and tax class decide the VAT charged on later sales
+		if (req.CategoryID != nil || req.TaxClassID != nil) && !middleware.HasPermission(c, custom_types.PermissionManageCatalog) {
+			appErr := apperr.NewPermission("your role cannot change a product's category or tax class")
+			utils.HandleError(c, appErr)
+			return
+		}
+
 		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
 			appErr := apperr.NewErrorWithType(
@@ -71,7 +85,7 @@ func updateProduct(
 			return
 		}
 
-		product, err := productService.UpdateProduct(c.Request.Context(), dB, productID, &req)
+		product, err := productService.UpdateProduct(c.Request.Context(), dB, middleware.ShopIDFromContext(c), productID, &req)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -98,7 +112,7 @@ func getProduct(
 			return
 		}
 
-		product, err := productService.ProductByID(c.Request.Context(), dB, productID)
+		product, err := productService.ProductByID(c.Request.Context(), dB, middleware.ShopIDFromContext(c), productID)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -113,12 +127,7 @@ func listProducts(
 	productService services.ProductService,
 ) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		shopID := c.Param("id")
-		if len(strings.TrimSpace(shopID)) < 1 {
-			appErr := apperr.NewBadRequest("shop id not set")
-			utils.HandleError(c, appErr)
-			return
-		}
+		shopID := middleware.ShopIDFromContext(c)
 
 		filter, err := ctxfilter.FilterFromContext(c)
 		if err != nil {
@@ -133,6 +142,7 @@ func listProducts(
 		productList, err := productService.ListProducts(c.Request.Context(), dB, shopID, filter)
 		if err != nil {
 			utils.HandleError(c, err)
+			return
 		}
 		c.JSON(http.StatusOK, productList)
 	}
@@ -153,7 +163,7 @@ func deleteProduct(
 			return
 		}
 
-		product, err := productService.DeleteProduct(c.Request.Context(), dB, productID)
+		product, err := productService.DeleteProduct(c.Request.Context(), dB, middleware.ShopIDFromContext(c), productID)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
@@ -178,7 +188,7 @@ func getAveragePriceByCategory(
 			return
 		}
 
-		averagePrice, err := productService.GetAveragePriceByCategory(c.Request.Context(), dB, categoryID)
+		averagePrice, err := productService.GetAveragePriceByCategory(c.Request.Context(), dB, middleware.ShopIDFromContext(c), categoryID)
 		if err != nil {
 			utils.HandleError(c, err)
 			return
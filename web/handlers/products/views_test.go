@@ -7,15 +7,22 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
 	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
 	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/domain"
 	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
 	"github/Doris-Mwito5/savannah-pos/internal/models"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
 )
 
 
@@ -23,22 +30,22 @@ type MockProductService struct {
 	mock.Mock
 }
 
-func (m *MockProductService) CreateProduct(ctx context.Context, dB db.DB, form *dtos.CreateProductForm) (*models.Product, error) {
-	args := m.Called(ctx, dB, form)
+func (m *MockProductService) CreateProduct(ctx context.Context, dB db.DB, shopID string, form *dtos.CreateProductForm, actor string) (*models.Product, error) {
+	args := m.Called(ctx, dB, shopID, form, actor)
 	if prod, ok := args.Get(0).(*models.Product); ok {
 		return prod, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
-func (m *MockProductService) UpdateProduct(ctx context.Context, dB db.DB, id int64, form *dtos.UpdateProductForm) (*models.Product, error) {
-	args := m.Called(ctx, dB, id, form)
+func (m *MockProductService) UpdateProduct(ctx context.Context, dB db.DB, shopID string, id int64, form *dtos.UpdateProductForm) (*models.Product, error) {
+	args := m.Called(ctx, dB, shopID, id, form)
 	if prod, ok := args.Get(0).(*models.Product); ok {
 		return prod, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
-func (m *MockProductService) ProductByID(ctx context.Context, dB db.DB, id int64) (*models.Product, error) {
-	args := m.Called(ctx, dB, id)
+func (m *MockProductService) ProductByID(ctx context.Context, dB db.DB, shopID string, id int64) (*models.Product, error) {
+	args := m.Called(ctx, dB, shopID, id)
 	if prod, ok := args.Get(0).(*models.Product); ok {
 		return prod, args.Error(1)
 	}
@@ -51,16 +58,23 @@ func (m *MockProductService) ListProducts(ctx context.Context, dB db.DB, shopID
 	}
 	return nil, args.Error(1)
 }
-func (m *MockProductService) DeleteProduct(ctx context.Context, dB db.DB, id int64) (*models.Product, error) {
-	args := m.Called(ctx, dB, id)
+func (m *MockProductService) DeleteProduct(ctx context.Context, dB db.DB, shopID string, id int64) (*models.Product, error) {
+	args := m.Called(ctx, dB, shopID, id)
 	if prod, ok := args.Get(0).(*models.Product); ok {
 		return prod, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
-func (m *MockProductService) GetAveragePriceByCategory(ctx context.Context, dB db.DB, categoryID int64) (float64, error) {
-	args := m.Called(ctx, dB, categoryID)
-	return args.Get(0).(float64), args.Error(1)
+func (m *MockProductService) GetAveragePriceByCategory(ctx context.Context, dB db.DB, shopID string, categoryID int64) (custom_types.Money, error) {
+	args := m.Called(ctx, dB, shopID, categoryID)
+	return args.Get(0).(custom_types.Money), args.Error(1)
+}
+
+// asShop stands in for RequirePermission, making the caller an owner of shopID.
+func asShop(shopID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.SetShopMembership(c, &models.ShopMembership{ShopID: shopID, Role: custom_types.StaffRoleOwner})
+	}
 }
 
 // --- Tests ---
@@ -69,12 +83,16 @@ func TestCreateProduct_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockSvc := new(MockProductService)
 	router := gin.New()
-	router.POST("/products", createProduct(nil, mockSvc))
+	router.POST("/products", asShop("shop-1"), createProduct(nil, mockSvc))
 
-	form := dtos.CreateProductForm{Name: "Product A"}
+	form := dtos.CreateProductForm{
+		Name:           "Product A",
+		WholesalePrice: custom_types.NewMoney(8000),
+		RetailPrice:    custom_types.NewMoney(12000),
+	}
 	expected := &models.Product{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, Name: "Product A"}
 
-	mockSvc.On("CreateProduct", mock.Anything, nil, &form).Return(expected, nil)
+	mockSvc.On("CreateProduct", mock.Anything, nil, "shop-1", &form, "anonymous").Return(expected, nil)
 
 	body, _ := json.Marshal(form)
 	req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
@@ -95,12 +113,12 @@ func TestUpdateProduct_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockSvc := new(MockProductService)
 	router := gin.New()
-	router.PUT("/products/:id", updateProduct(nil, mockSvc))
+	router.PUT("/products/:id", asShop("shop-1"), updateProduct(nil, mockSvc))
 
 	form := dtos.UpdateProductForm{Name: ptr("Updated")}
 	expected := &models.Product{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, Name: "Updated"}
 
-	mockSvc.On("UpdateProduct", mock.Anything, nil, int64(1), &form).Return(expected, nil)
+	mockSvc.On("UpdateProduct", mock.Anything, nil, "shop-1", int64(1), &form).Return(expected, nil)
 
 	body, _ := json.Marshal(form)
 	req := httptest.NewRequest(http.MethodPut, "/products/1", bytes.NewBuffer(body))
@@ -117,14 +135,95 @@ func TestUpdateProduct_Success(t *testing.T) {
 	mockSvc.AssertExpectations(t)
 }
 
+func TestUpdateProduct_WholesalePriceNeedsPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	form := dtos.UpdateProductForm{WholesalePrice: &custom_types.Money{Amount: 7000}}
+	body, _ := json.Marshal(form)
+
+	tests := []struct {
+		role   custom_types.StaffRole
+		status int
+	}{
+		{custom_types.StaffRoleCashier, http.StatusForbidden},
+		{custom_types.StaffRoleManager, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role.String(), func(t *testing.T) {
+			mockSvc := new(MockProductService)
+			router := gin.New()
+			router.PUT("/products/:id", func(c *gin.Context) {
+				middleware.SetShopMembership(c, &models.ShopMembership{ShopID: "shop-1", Role: tt.role})
+			}, updateProduct(nil, mockSvc))
+
+			if tt.status == http.StatusOK {
+				mockSvc.On("UpdateProduct", mock.Anything, nil, "shop-1", int64(1), mock.Anything).Return(&models.Product{}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/products/1", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateProduct_CategoryAndTaxClassNeedCatalogPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	taxClassID := int64(2)
+	categoryID := int64(3)
+
+	tests := []struct {
+		name   string
+		form   dtos.UpdateProductForm
+		role   custom_types.StaffRole
+		status int
+	}{
+		{"cashier moving category", dtos.UpdateProductForm{CategoryID: &categoryID}, custom_types.StaffRoleCashier, http.StatusForbidden},
+		{"cashier changing tax class", dtos.UpdateProductForm{TaxClassID: &taxClassID}, custom_types.StaffRoleCashier, http.StatusForbidden},
+		{"cashier changing the retail price", dtos.UpdateProductForm{RetailPrice: &custom_types.Money{Amount: 9000}}, custom_types.StaffRoleCashier, http.StatusOK},
+		{"manager changing tax class", dtos.UpdateProductForm{TaxClassID: &taxClassID}, custom_types.StaffRoleManager, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockProductService)
+			router := gin.New()
+			router.PUT("/products/:id", func(c *gin.Context) {
+				middleware.SetShopMembership(c, &models.ShopMembership{ShopID: "shop-1", Role: tt.role})
+			}, updateProduct(nil, mockSvc))
+
+			if tt.status == http.StatusOK {
+				mockSvc.On("UpdateProduct", mock.Anything, nil, "shop-1", int64(1), mock.Anything).Return(&models.Product{}, nil)
+			}
+
+			body, _ := json.Marshal(tt.form)
+			req := httptest.NewRequest(http.MethodPut, "/products/1", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
 func TestGetProduct_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockSvc := new(MockProductService)
 	router := gin.New()
-	router.GET("/products/:id", getProduct(nil, mockSvc))
+	router.GET("/products/:id", asShop("shop-1"), getProduct(nil, mockSvc))
 
 	expected := &models.Product{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, Name: "Prod X"}
-	mockSvc.On("ProductByID", mock.Anything, nil, int64(2)).Return(expected, nil)
+	mockSvc.On("ProductByID", mock.Anything, nil, "shop-1", int64(2)).Return(expected, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/products/2", nil)
 	w := httptest.NewRecorder()
@@ -139,14 +238,32 @@ func TestGetProduct_Success(t *testing.T) {
 	mockSvc.AssertExpectations(t)
 }
 
+func TestGetProduct_ScopedToCallersShop(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockSvc := new(MockProductService)
+	router := gin.New()
+	router.GET("/products/:id", asShop("shop-2"), getProduct(nil, mockSvc))
+
+	// product 2 belongs to shop-1, so shop-2's staff are told it does not exist
+	mockSvc.On("ProductByID", mock.Anything, nil, "shop-2", int64(2)).Return(nil, apperr.NewNotFound("product", "2"))
+
+	req := httptest.NewRequest(http.MethodGet, "/products/2", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockSvc.AssertExpectations(t)
+}
+
 func TestDeleteProduct_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockSvc := new(MockProductService)
 	router := gin.New()
-	router.DELETE("/products/:id", deleteProduct(nil, mockSvc))
+	router.DELETE("/products/:id", asShop("shop-1"), deleteProduct(nil, mockSvc))
 
 	expected := &models.Product{SequentialIdentifier: custom_types.SequentialIdentifier{ID: 1}, Name: "Deleted"}
-	mockSvc.On("DeleteProduct", mock.Anything, nil, int64(3)).Return(expected, nil)
+	mockSvc.On("DeleteProduct", mock.Anything, nil, "shop-1", int64(3)).Return(expected, nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/products/3", nil)
 	w := httptest.NewRecorder()
@@ -165,9 +282,9 @@ func TestGetAveragePriceByCategory_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockSvc := new(MockProductService)
 	router := gin.New()
-	router.GET("/products/category/:category_id/average", getAveragePriceByCategory(nil, mockSvc))
+	router.GET("/products/category/:category_id/average", asShop("shop-1"), getAveragePriceByCategory(nil, mockSvc))
 
-	mockSvc.On("GetAveragePriceByCategory", mock.Anything, nil, int64(5)).Return(99.5, nil)
+	mockSvc.On("GetAveragePriceByCategory", mock.Anything, nil, "shop-1", int64(5)).Return(custom_types.NewMoney(9950), nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/products/category/5/average", nil)
 	w := httptest.NewRecorder()
@@ -182,4 +299,79 @@ func TestGetAveragePriceByCategory_Success(t *testing.T) {
 	mockSvc.AssertExpectations(t)
 }
 
+// The tests below run the real service and domain against sqlmock, matching the SQL
+// exactly, so they break when a query stops fitting the schema.
+
+const selectProductsSQL = "SELECT p.id, p.shop_id, p.name, p.description, p.wholesale_price, p.retail_price, p.category_id, p.product_image, p.product_type, p.stock, p.tax_class_id, p.price_includes_tax, p.created_at, p.updated_at FROM products p"
+
+var productColumns = []string{
+	"id", "shop_id", "name", "description", "wholesale_price", "retail_price", "category_id",
+	"product_image", "product_type", "stock", "tax_class_id", "price_includes_tax", "created_at", "updated_at",
+}
+
+func setupSQLMockRouter(t *testing.T, shopID string) (*gin.Engine, sqlmock.Sqlmock) {
+	gin.SetMode(gin.TestMode)
+	loggers.InitLogger("test")
+
+	sqlDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dB := &db.AppDB{DB: sqlDB}
+	store := domain.NewStore()
+	productService := services.NewProductService(services.NewStockService(store), store)
+
+	router := gin.New()
+	router.GET("/shop/:id/products", asShop(shopID), listProducts(dB, productService))
+	router.GET("/products/:id", asShop(shopID), getProduct(dB, productService))
+
+	return router, mock
+}
+
+func TestListProducts_SQL(t *testing.T) {
+	router, mock := setupSQLMockRouter(t, "shop-1")
+	now := time.Now()
+
+	mock.ExpectQuery(selectProductsSQL+" WHERE p.shop_id = $1 ORDER BY p.id LIMIT $2 OFFSET $3").
+		WithArgs("shop-1", 5, 5).
+		WillReturnRows(sqlmock.NewRows(productColumns).
+			AddRow(6, "shop-1", "Soda", nil, "40.00", "60.00", 3, nil, []byte("goods"), 12, nil, true, now, now))
+	mock.ExpectQuery("SELECT COUNT(p.id) FROM products p WHERE p.shop_id = $1").
+		WithArgs("shop-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(6))
+
+	req := httptest.NewRequest(http.MethodGet, "/shop/shop-1/products?page=2&per=5", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var got models.ProductList
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Len(t, got.Products, 1)
+	assert.Equal(t, "shop-1", got.Products[0].ShopID)
+	assert.Equal(t, int64(6000), got.Products[0].RetailPrice.Amount)
+	assert.Equal(t, 6, got.Pagination.Count)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetProduct_SQLIsScopedToCallersShop(t *testing.T) {
+	router, mock := setupSQLMockRouter(t, "shop-2")
+
+	// product 7 is shop-1's, so nothing comes back for shop-2
+	mock.ExpectQuery(selectProductsSQL+" WHERE p.id = $1 AND p.shop_id = $2").
+		WithArgs(7, "shop-2").
+		WillReturnRows(sqlmock.NewRows(productColumns))
+
+	req := httptest.NewRequest(http.MethodGet, "/products/7", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func ptr(s string) *string { return &s }
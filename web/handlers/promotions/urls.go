@@ -0,0 +1,25 @@
+package promotions
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	promotionService services.PromotionService,
+	staffService services.StaffService,
+) {
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	manageCatalog := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageCatalog)
+
+	r.POST("/promotions", manageCatalog, createPromotion(dB, promotionService))
+	r.GET("/promotions/:id", view, getPromotion(dB, promotionService))
+	r.PUT("/promotions/:id", manageCatalog, updatePromotion(dB, promotionService))
+	r.GET("/shop/:id/promotions", view, listPromotions(dB, promotionService))
+}
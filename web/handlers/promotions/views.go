@@ -0,0 +1,135 @@
+package promotions
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createPromotion(
+	dB db.DB,
+	promotionService services.PromotionService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.CreatePromotionForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		promotion, err := promotionService.CreatePromotion(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, promotion)
+	}
+}
+
+func getPromotion(
+	dB db.DB,
+	promotionService services.PromotionService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		promotionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		promotion, err := promotionService.PromotionByID(c.Request.Context(), dB, middleware.ShopIDFromContext(c), promotionID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, promotion)
+	}
+}
+
+func updatePromotion(
+	dB db.DB,
+	promotionService services.PromotionService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.UpdatePromotionForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		promotionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		promotion, err := promotionService.UpdatePromotion(c.Request.Context(), dB, middleware.ShopIDFromContext(c), promotionID, &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, promotion)
+	}
+}
+
+func listPromotions(
+	dB db.DB,
+	promotionService services.PromotionService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		shopID := middleware.ShopIDFromContext(c)
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appError := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appError)
+			return
+		}
+
+		promotionList, err := promotionService.ListPromotions(c.Request.Context(), dB, shopID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, promotionList)
+	}
+}
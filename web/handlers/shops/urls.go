@@ -0,0 +1,26 @@
+package shops
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	shopService services.ShopService,
+	staffService services.StaffService,
+) {
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	manageShop := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageShop)
+
+	// anyone who has logged in may open a shop, and owns it from then on
+	r.POST("/shops", createShop(dB, shopService))
+	r.GET("/shops", listShops(dB, shopService))
+	r.GET("/shop/:id", view, getShop(dB, shopService))
+	r.PUT("/shop/:id", manageShop, updateShop(dB, shopService))
+}
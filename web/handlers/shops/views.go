@@ -0,0 +1,113 @@
+package shops
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createShop(
+	dB db.DB,
+	shopService services.ShopService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		claims, ok := middleware.ClaimsFromContext(c)
+		if !ok || claims.Sub == "" {
+			utils.HandleError(c, apperr.NewAuthorization("authentication required"))
+			return
+		}
+
+		var req dtos.CreateShopForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		shop, err := shopService.CreateShop(c.Request.Context(), dB, claims.Sub, &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, shop)
+	}
+}
+
+func listShops(
+	dB db.DB,
+	shopService services.ShopService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		claims, ok := middleware.ClaimsFromContext(c)
+		if !ok || claims.Sub == "" {
+			utils.HandleError(c, apperr.NewAuthorization("authentication required"))
+			return
+		}
+
+		shops, err := shopService.ListUserShops(c.Request.Context(), dB, claims.Sub)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, shops)
+	}
+}
+
+func getShop(
+	dB db.DB,
+	shopService services.ShopService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		shop, err := shopService.ShopByID(c.Request.Context(), dB, middleware.ShopIDFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, shop)
+	}
+}
+
+func updateShop(
+	dB db.DB,
+	shopService services.ShopService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.UpdateShopForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		shop, err := shopService.UpdateShop(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, shop)
+	}
+}
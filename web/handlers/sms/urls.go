@@ -0,0 +1,31 @@
+package sms
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/loggers"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	smsDeliveryService services.SMSDeliveryService,
+	consentService services.ConsentService,
+	callbackToken string,
+	staffService services.StaffService,
+) {
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+
+	r.GET("/orders/:id/sms-messages", view, listOrderSMSMessages(dB, smsDeliveryService))
+	r.GET("/customers/:id/sms-messages", view, listCustomerSMSMessages(dB, smsDeliveryService))
+	if callbackToken == "" {
+		loggers.Warn("AFRICAS_TALKING_CALLBACK_TOKEN is not set; SMS delivery reports and inbound texts will be refused")
+	}
+
+	r.POST("/sms/delivery-reports", deliveryReport(dB, smsDeliveryService, callbackToken))
+	r.POST("/sms/inbound", inboundSMS(dB, consentService, callbackToken))
+}
@@ -0,0 +1,160 @@
+package sms
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listOrderSMSMessages shows the SMS sent about an order and whether they were delivered.
+func listOrderSMSMessages(
+	dB db.DB,
+	smsDeliveryService services.SMSDeliveryService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		smsMessageList, err := smsDeliveryService.ListOrderSMSMessages(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, smsMessageList)
+	}
+}
+
+// listCustomerSMSMessages shows the SMS sent to a customer across their orders.
+func listCustomerSMSMessages(
+	dB db.DB,
+	smsDeliveryService services.SMSDeliveryService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		customerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		smsMessageList, err := smsDeliveryService.ListCustomerSMSMessages(c.Request.Context(), dB, middleware.ShopIDFromContext(c), customerID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, smsMessageList)
+	}
+}
+
+// deliveryReport receives Africa's Talking delivery reports, which are posted as form data.
+func deliveryReport(
+	dB db.DB,
+	smsDeliveryService services.SMSDeliveryService,
+	callbackToken string,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		if !utils.ValidCallbackToken(c.Query("token"), callbackToken) {
+			utils.HandleError(c, apperr.NewAuthorization("invalid callback token"))
+			return
+		}
+
+		var req dtos.SMSDeliveryReportForm
+
+		err := c.ShouldBind(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		err = smsDeliveryService.RecordDeliveryReport(c.Request.Context(), dB, &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
+
+// inboundSMS receives texts to the shop's number from Africa's Talking, posted as form
+// data, so customers can text STOP to opt out of SMS.
+func inboundSMS(
+	dB db.DB,
+	consentService services.ConsentService,
+	callbackToken string,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		if !utils.ValidCallbackToken(c.Query("token"), callbackToken) {
+			utils.HandleError(c, apperr.NewAuthorization("invalid callback token"))
+			return
+		}
+
+		var req dtos.InboundSMSForm
+
+		err := c.ShouldBind(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		err = consentService.ReceiveInboundSMS(c.Request.Context(), dB, &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,23 @@
+package staff
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	staffService services.StaffService,
+) {
+	manageStaff := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageStaff)
+
+	r.POST("/shop/:id/staff", manageStaff, addShopMember(dB, staffService))
+	r.GET("/shop/:id/staff", manageStaff, listShopMembers(dB, staffService))
+	r.PUT("/shop/:id/staff/:user_id", manageStaff, updateShopMember(dB, staffService))
+	r.DELETE("/shop/:id/staff/:user_id", manageStaff, removeShopMember(dB, staffService))
+}
@@ -0,0 +1,124 @@
+package staff
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func addShopMember(
+	dB db.DB,
+	staffService services.StaffService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.AddShopMemberForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		membership, err := staffService.AddShopMember(c.Request.Context(), dB, c.Param("id"), &req, middleware.ActorFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, membership)
+	}
+}
+
+func listShopMembers(
+	dB db.DB,
+	staffService services.StaffService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		memberships, err := staffService.ListShopMembers(c.Request.Context(), dB, c.Param("id"))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, memberships)
+	}
+}
+
+func updateShopMember(
+	dB db.DB,
+	staffService services.StaffService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.UpdateShopMemberForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		membership, err := staffService.UpdateShopMember(c.Request.Context(), dB, c.Param("id"), userID, &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, membership)
+	}
+}
+
+func removeShopMember(
+	dB db.DB,
+	staffService services.StaffService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		err = staffService.RemoveShopMember(c.Request.Context(), dB, c.Param("id"), userID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Staff member removed",
+		})
+	}
+}
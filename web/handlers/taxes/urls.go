@@ -0,0 +1,26 @@
+package taxes
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/custom_types"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddEndpoints(
+	r *gin.RouterGroup,
+	dB db.DB,
+	taxService services.TaxService,
+	staffService services.StaffService,
+) {
+	view := middleware.RequirePermission(dB, staffService, custom_types.PermissionViewShop)
+	// a shop's own tax classes decide the VAT it charges, so adding one is for owners
+	manageTaxClasses := middleware.RequirePermission(dB, staffService, custom_types.PermissionManageTaxClasses)
+
+	r.POST("/tax-classes", manageTaxClasses, createTaxClass(dB, taxService))
+	r.GET("/tax-classes", view, listTaxClasses(dB, taxService))
+	r.GET("/orders/:id/tax-summary", view, getOrderTaxSummary(dB, taxService))
+	r.GET("/shop/:id/tax-summary", view, getShopTaxSummary(dB, taxService))
+}
@@ -0,0 +1,113 @@
+package taxes
+
+import (
+	"github/Doris-Mwito5/savannah-pos/internal/apperr"
+	"github/Doris-Mwito5/savannah-pos/internal/ctxfilter"
+	"github/Doris-Mwito5/savannah-pos/internal/db"
+	"github/Doris-Mwito5/savannah-pos/internal/dtos"
+	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/utils"
+	"github/Doris-Mwito5/savannah-pos/middleware"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createTaxClass(
+	dB db.DB,
+	taxService services.TaxService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req dtos.CreateTaxClassForm
+
+		err := c.BindJSON(&req)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		taxClass, err := taxService.CreateTaxClass(c.Request.Context(), dB, middleware.ShopIDFromContext(c), &req)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, taxClass)
+	}
+}
+
+func listTaxClasses(
+	dB db.DB,
+	taxService services.TaxService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		taxClasses, err := taxService.ListTaxClasses(c.Request.Context(), dB, middleware.ShopIDFromContext(c))
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, taxClasses)
+	}
+}
+
+func getOrderTaxSummary(
+	dB db.DB,
+	taxService services.TaxService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		orderID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			appErr := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appErr)
+			return
+		}
+
+		taxSummary, err := taxService.OrderTaxSummary(c.Request.Context(), dB, middleware.ShopIDFromContext(c), orderID)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, taxSummary)
+	}
+}
+
+func getShopTaxSummary(
+	dB db.DB,
+	taxService services.TaxService,
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		shopID := middleware.ShopIDFromContext(c)
+
+		filter, err := ctxfilter.FilterFromContext(c)
+		if err != nil {
+			appError := apperr.NewErrorWithType(
+				err,
+				apperr.BadRequest,
+			)
+			utils.HandleError(c, appError)
+			return
+		}
+
+		taxSummary, err := taxService.ShopTaxSummary(c.Request.Context(), dB, shopID, filter)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, taxSummary)
+	}
+}
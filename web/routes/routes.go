@@ -13,16 +13,28 @@ import (
 	"github/Doris-Mwito5/savannah-pos/internal/notification"
 	"github/Doris-Mwito5/savannah-pos/internal/processor"
 	"github/Doris-Mwito5/savannah-pos/internal/services"
+	"github/Doris-Mwito5/savannah-pos/internal/workers"
 	"github/Doris-Mwito5/savannah-pos/middleware"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/apikeys"
 	authhandler "github/Doris-Mwito5/savannah-pos/web/handlers/auth"
 	"github/Doris-Mwito5/savannah-pos/web/handlers/categories"
 	"github/Doris-Mwito5/savannah-pos/web/handlers/customers"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/health"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/notifications"
 	"github/Doris-Mwito5/savannah-pos/web/handlers/orders"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/payments"
 	"github/Doris-Mwito5/savannah-pos/web/handlers/products"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/promotions"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/shops"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/sms"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/staff"
+	"github/Doris-Mwito5/savannah-pos/web/handlers/taxes"
 )
 
 type AppRouter struct {
 	*gin.Engine
+	// NotificationWorker delivers the notifications the services write to the outbox
+	NotificationWorker workers.NotificationWorker
 }
 
 func BuildRouter(
@@ -42,6 +54,14 @@ func BuildRouter(
 		Username: config.AppConfig.SMSService.Username, 
 		BaseURL:  config.AppConfig.SMSService.BaseURL,
 		Env:      config.AppConfig.SMSService.Env,
+		Providers: config.AppConfig.SMSService.Providers,
+		Twilio: &models.TwilioService{
+			BaseURL:    config.AppConfig.SMSService.Twilio.BaseURL,
+			AccountSID: config.AppConfig.SMSService.Twilio.AccountSID,
+			AuthToken:  config.AppConfig.SMSService.Twilio.AuthToken,
+			From:       config.AppConfig.SMSService.Twilio.From,
+		},
+		LogFile: config.AppConfig.SMSService.LogFile,
 	}
 
 	emailService := &models.EmailService{
@@ -57,15 +77,46 @@ func BuildRouter(
 	emailClient := processor.NewEmailClient(emailService)
 
 	httpClient := &http.Client{} 
-	smsClient := processor.NewSMSClient(smsService, httpClient)
+	smsClient, err := processor.NewSMSClient(smsService, httpClient)
+	if err != nil {
+		panic(err)
+	}
+
+	mpesaClient := processor.NewMpesaClient(&models.MpesaService{
+		BaseURL:        config.AppConfig.Mpesa.BaseURL,
+		ConsumerKey:    config.AppConfig.Mpesa.ConsumerKey,
+		ConsumerSecret: config.AppConfig.Mpesa.ConsumerSecret,
+		ShortCode:      config.AppConfig.Mpesa.ShortCode,
+		Passkey:        config.AppConfig.Mpesa.Passkey,
+		CallbackURL:    config.AppConfig.Mpesa.CallbackURL,
+	}, httpClient)
 	orderNotification := notification.NewOrderNotification(smsClient, emailClient)
 
 	// Instantiate other services
 	categoryService := services.NewCategoryService(domainStore)
 	customerService := services.NewCustomerService(domainStore)
-	// Pass the orderNotification to the OrderService
-	orderService := services.NewOrderService(customerService, domainStore, orderNotification)
-	productService := services.NewProductService(domainStore)
+	stockService := services.NewStockService(domainStore)
+	// orders and returns write their notifications to the outbox; the worker sends them
+	// messages are rendered from the shop's templates, in the customer's language, when they are sent
+	notificationTemplateService := services.NewNotificationTemplateService(notification.NewTemplateRenderer(), domainStore)
+	notificationOutboxService := services.NewNotificationOutboxService(orderNotification, notificationTemplateService, domainStore, config.AppConfig.Notifications.MaxAttempts)
+	orderReferenceGenerator := services.NewOrderReferenceGenerator(
+		domainStore,
+		config.AppConfig.OrderReference.DefaultPrefix,
+	)
+	promotionService := services.NewPromotionService(domainStore)
+	orderService := services.NewOrderService(customerService, stockService, promotionService, orderReferenceGenerator, domainStore, notificationOutboxService)
+	productService := services.NewProductService(stockService, domainStore)
+	returnService := services.NewReturnService(stockService, domainStore, notificationOutboxService)
+	idempotencyService := services.NewIdempotencyService(domainStore)
+	taxService := services.NewTaxService(domainStore)
+	paymentService := services.NewPaymentService(domainStore)
+	mpesaService := services.NewMpesaService(mpesaClient, domainStore)
+	staffService := services.NewStaffService(config.AppConfig.Staff.BootstrapOwners, domainStore)
+	shopService := services.NewShopService(domainStore)
+	apiKeyService := services.NewAPIKeyService(domainStore)
+	smsDeliveryService := services.NewSMSDeliveryService(domainStore)
+	consentService := services.NewConsentService(domainStore)
 
 	// OIDC Auth service (now using config from .env)
 	oidcService, err := auth.NewOIDCProvider(&config.AppConfig.OIDC)
@@ -73,27 +124,55 @@ func BuildRouter(
 		panic(err)
 	}
 
-	// AUTHENTICATION SETUP NOTE:
-	// The OIDC authentication middleware is fully implemented and ready to use.
-	// To protect API endpoints, simply uncomment the lines below:
-	//
-	// protected := baseAPIGroup.Group("/api")
-	// protected.Use(middleware.AuthMiddleware(oidcService))
-	//
-	// Then register protected endpoints with the 'protected' group instead of 'baseAPIGroup'.
-	// Currently endpoints are open for demonstration purposes.
+	// every /v1 route needs a bearer token we signed for a login session, or a shop's
+	// API key, unless it is listed here
+	jwtService, err := auth.NewJWTService(&config.AppConfig.JWT)
+	if err != nil {
+		panic(err)
+	}
 
+	sessionService := services.NewSessionService(jwtService, config.AppConfig.JWT.RefreshTokenTTL, domainStore)
+
+	tokenVerifier := auth.NewBearerVerifier(jwtService)
+	baseAPIGroup.Use(middleware.AuthMiddleware(
+		dB,
+		tokenVerifier,
+		sessionService,
+		apiKeyService,
+		"GET /v1/health",
+		"GET /v1/auth/login",
+		"GET /v1/auth/callback",
+		// the access token may have expired; the refresh token is the credential
+		"POST /v1/auth/refresh",
+		// Daraja cannot send a token; the callback is checked against MPESA_CALLBACK_TOKEN
+		"POST /v1/payments/mpesa/callback",
+		// likewise Africa's Talking; delivery reports and inbound texts are checked against AFRICAS_TALKING_CALLBACK_TOKEN
+		"POST /v1/sms/delivery-reports",
+		"POST /v1/sms/inbound",
+	))
 
 	// Register endpoints
-	authhandler.AddEndpoints(baseAPIGroup, dB, oidcService, customerService, config.AppConfig.JWTSecret)
-	categories.AddEndpoints(baseAPIGroup, dB, categoryService)
-	customers.AddEndpoints(baseAPIGroup, dB, customerService)
-	orders.AddEndpoints(baseAPIGroup, dB, orderService)
-	products.AddEndpoints(baseAPIGroup, dB, productService)
+	health.AddEndpoints(baseAPIGroup, dB)
+	notifications.AddEndpoints(baseAPIGroup, dB, notificationOutboxService, notificationTemplateService, staffService)
+	apikeys.AddEndpoints(baseAPIGroup, dB, apiKeyService, staffService)
+	authhandler.AddEndpoints(baseAPIGroup, dB, oidcService, staffService, sessionService)
+	categories.AddEndpoints(baseAPIGroup, dB, categoryService, staffService)
+	customers.AddEndpoints(baseAPIGroup, dB, customerService, consentService, staffService)
+	orders.AddEndpoints(baseAPIGroup, dB, orderService, returnService, idempotencyService, staffService)
+	payments.AddEndpoints(baseAPIGroup, dB, paymentService, mpesaService, config.AppConfig.Mpesa.CallbackToken, staffService)
+	products.AddEndpoints(baseAPIGroup, dB, productService, stockService, staffService)
+	promotions.AddEndpoints(baseAPIGroup, dB, promotionService, staffService)
+	shops.AddEndpoints(baseAPIGroup, dB, shopService, staffService)
+	sms.AddEndpoints(baseAPIGroup, dB, smsDeliveryService, consentService, config.AppConfig.SMSService.CallbackToken, staffService)
+	staff.AddEndpoints(baseAPIGroup, dB, staffService)
+	taxes.AddEndpoints(baseAPIGroup, dB, taxService, staffService)
 
 	router.NoRoute(func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error_message": "Endpoint not found"})
 	})
 
-	return &AppRouter{router}
+	return &AppRouter{
+		Engine:             router,
+		NotificationWorker: workers.NewNotificationWorker(dB, notificationOutboxService, config.AppConfig.Notifications.PollInterval),
+	}
 }